@@ -18,9 +18,13 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
@@ -50,7 +54,6 @@ import (
 	"knative.dev/pkg/signals"
 	"knative.dev/pkg/system"
 	"knative.dev/pkg/tracing"
-	tracingconfig "knative.dev/pkg/tracing/config"
 	"knative.dev/pkg/version"
 	"knative.dev/pkg/websocket"
 	activatorconfig "knative.dev/serving/pkg/activator/config"
@@ -60,6 +63,7 @@ import (
 	pkghttp "knative.dev/serving/pkg/http"
 	"knative.dev/serving/pkg/logging"
 	"knative.dev/serving/pkg/networking"
+	servingtracing "knative.dev/serving/pkg/tracing"
 )
 
 const (
@@ -67,6 +71,9 @@ const (
 
 	// The port on which autoscaler WebSocket server listens.
 	autoscalerPort = ":8080"
+
+	// The port on which the activator serves TLS, if enabled.
+	httpsPort = ":8112"
 )
 
 type config struct {
@@ -75,8 +82,57 @@ type config struct {
 
 	// These are here to allow configuring higher values of keep-alive for larger environments.
 	// TODO: run loadtests using these flags to determine optimal default values.
+	// These are applied to each revision's connection pool separately.
 	MaxIdleProxyConns        int `split_words:"true" default:"1000"`
 	MaxIdleProxyConnsPerHost int `split_words:"true" default:"100"`
+	// MaxProxyConnsPerHost caps the number of connections to a single backend, 0 means no limit.
+	MaxProxyConnsPerHost int `split_words:"true" default:"0"`
+	// ProxyIdleTimeout is the duration after which idle connections and unused
+	// revision connection pools are released.
+	ProxyIdleTimeout time.Duration `split_words:"true" default:"90s"`
+
+	// RevisionQueueDepth and RevisionQueueTimeout bound the requests waiting
+	// for a revision's capacity, e.g. during a cold start. Requests beyond
+	// the bounds are rejected with a 429. Zero means no limit. The revisions
+	// may override them with the activator-queue-depth and
+	// activator-queue-timeout annotations.
+	RevisionQueueDepth   int           `split_words:"true" default:"0"`
+	RevisionQueueTimeout time.Duration `split_words:"true" default:"0s"`
+
+	// The circuit breakers stop the activator from picking the pods failing
+	// CircuitBreakerErrorThreshold of the requests (with 5xx responses or
+	// connection errors) over CircuitBreakerWindow, for CircuitBreakerOpenDuration.
+	// Zero threshold disables them.
+	CircuitBreakerErrorThreshold float64       `split_words:"true" default:"0"`
+	CircuitBreakerMinRequests    int           `split_words:"true" default:"10"`
+	CircuitBreakerWindow         time.Duration `split_words:"true" default:"10s"`
+	CircuitBreakerOpenDuration   time.Duration `split_words:"true" default:"30s"`
+
+	// StaleEndpointTolerance is how long the pods observed being deleted are
+	// kept from receiving requests while the endpoints still list them, e.g.
+	// after a rapid scale-down. It is bounded, since the IPs may be reused.
+	// Zero disables watching the pod deletions.
+	StaleEndpointTolerance time.Duration `split_words:"true" default:"30s"`
+
+	// MaxBufferedBodyBytes bounds the memory the activator holds the request
+	// bodies in, across all the requests, to retry or to mirror them. The
+	// requests needing to be buffered beyond it are rejected with a 503, so
+	// they spill over to the other activators. Zero means no limit.
+	MaxBufferedBodyBytes int64 `split_words:"true" default:"0"`
+
+	// TLSCertFile and TLSKeyFile point to the serving certificate.
+	// When set, the activator also serves TLS, constrained as per config-network.
+	TLSCertFile string `split_words:"true"`
+	TLSKeyFile  string `split_words:"true"`
+
+	// BackendCAFile points to the CA bundle the queue-proxy certificates are
+	// validated against, when the backend TLS is enabled in config-network.
+	BackendCAFile string `split_words:"true"`
+
+	// DrainTimeout bounds how long the activator waits on termination for the
+	// in-flight and buffered requests to finish, before closing the connections.
+	// It should be kept below the pod's terminationGracePeriodSeconds.
+	DrainTimeout time.Duration `split_words:"true" default:"540s"`
 }
 
 func main() {
@@ -133,14 +189,28 @@ func main() {
 	logger.Info("Starting the knative activator")
 
 	// Start throttler.
-	throttler := activatornet.NewThrottler(ctx, env.PodIP)
+	if env.CircuitBreakerErrorThreshold < 0 || env.CircuitBreakerErrorThreshold > 1 {
+		logger.Fatalf("CIRCUIT_BREAKER_ERROR_THRESHOLD must be in [0, 1], was: %v", env.CircuitBreakerErrorThreshold)
+	}
+	throttler := activatornet.NewThrottler(ctx, env.PodIP, activatornet.QueueLimits{
+		MaxDepth: env.RevisionQueueDepth,
+		Timeout:  env.RevisionQueueTimeout,
+	}, activatornet.CircuitBreakerParams{
+		ErrorThreshold: env.CircuitBreakerErrorThreshold,
+		MinRequests:    env.CircuitBreakerMinRequests,
+		Window:         env.CircuitBreakerWindow,
+		OpenDuration:   env.CircuitBreakerOpenDuration,
+	}, env.StaleEndpointTolerance)
 	go throttler.Run(ctx)
 
-	oct := tracing.NewOpenCensusTracer(tracing.WithExporterFull(networking.ActivatorServiceName, env.PodIP, logger))
+	tracer := servingtracing.NewTracer(networking.ActivatorServiceName, env.PodIP, map[string]string{
+		servingtracing.NamespaceAttribute: system.Namespace(),
+		servingtracing.PodAttribute:       env.PodName,
+	}, logger)
 
-	tracerUpdater := configmap.TypeFilter(&tracingconfig.Config{})(func(name string, value interface{}) {
-		cfg := value.(*tracingconfig.Config)
-		if err := oct.ApplyConfig(cfg); err != nil {
+	tracerUpdater := configmap.TypeFilter(&servingtracing.Config{})(func(name string, value interface{}) {
+		cfg := value.(*servingtracing.Config)
+		if err := tracer.ApplyConfig(cfg); err != nil {
 			logger.Errorw("Unable to apply open census tracer config", zap.Error(err))
 			return
 		}
@@ -165,12 +235,24 @@ func main() {
 	concurrencyReporter := activatorhandler.NewConcurrencyReporter(ctx, env.PodName, statCh)
 	go concurrencyReporter.Run(ctx.Done())
 
-	logger.Debugf("MaxIdleProxyConns: %d, MaxIdleProxyConnsPerHost: %d", env.MaxIdleProxyConns, env.MaxIdleProxyConnsPerHost)
-	proxyTransport := pkgnet.NewAutoTransport(env.MaxIdleProxyConns, env.MaxIdleProxyConnsPerHost)
+	logger.Debugf("MaxIdleProxyConns: %d, MaxIdleProxyConnsPerHost: %d, MaxProxyConnsPerHost: %d, ProxyIdleTimeout: %v",
+		env.MaxIdleProxyConns, env.MaxIdleProxyConnsPerHost, env.MaxProxyConnsPerHost, env.ProxyIdleTimeout)
+	// Each revision gets its own connection pool, so that a misbehaving
+	// revision can't exhaust the connections used by the others.
+	proxyTransport := activatornet.NewRevisionTransports(logger, activatornet.TransportParams{
+		MaxIdleConns:        env.MaxIdleProxyConns,
+		MaxIdleConnsPerHost: env.MaxIdleProxyConnsPerHost,
+		MaxConnsPerHost:     env.MaxProxyConnsPerHost,
+		IdleTimeout:         env.ProxyIdleTimeout,
+		BackendTLS:          backendTLSConfig(logger, env.BackendCAFile),
+	})
+	go proxyTransport.Run(ctx.Done())
 
 	// Create activation handler chain
 	// Note: innermost handlers are specified first, ie. the last handler in the chain will be executed first
-	var ah http.Handler = activatorhandler.New(ctx, throttler, proxyTransport)
+	// The request bodies buffered to retry or to mirror them share the budget.
+	bufferBudget := activatorhandler.NewBufferBudget(env.MaxBufferedBodyBytes)
+	var ah http.Handler = activatorhandler.New(ctx, throttler, proxyTransport, bufferBudget)
 	ah = concurrencyReporter.Handler(ah)
 	ah = tracing.HTTPSpanMiddleware(ah)
 	ah = configStore.HTTPMiddleware(ah)
@@ -184,7 +266,11 @@ func main() {
 	// NOTE: MetricHandler is being used as the outermost handler of the meaty bits. We're not interested in measuring
 	// the healthchecks or probes.
 	ah = activatorhandler.NewMetricHandler(env.PodName, ah)
+	// gRPC health checks are answered for the inactive revisions, so keep them out of the metrics as well.
+	ah = &activatorhandler.GRPCHealthHandler{NextHandler: ah}
 	ah = activatorhandler.NewContextHandler(ctx, ah)
+	// Mirrored requests go through the whole chain above, addressed to the mirror revision.
+	ah = activatorhandler.NewMirrorHandler(ctx, bufferBudget, ah)
 
 	// Network probe handlers.
 	ah = &activatorhandler.ProbeHandler{NextHandler: ah}
@@ -215,11 +301,38 @@ func main() {
 		"profile": profiling.NewServer(profilingHandler),
 	}
 
+	if env.TLSCertFile != "" || env.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(env.TLSCertFile, env.TLSKeyFile)
+		if err != nil {
+			logger.Fatalw("Failed to load TLS certificate", zap.Error(err))
+		}
+		servers["https"] = &http.Server{
+			Addr:      httpsPort,
+			Handler:   ah,
+			TLSConfig: configStore.TLSConfig(cert),
+		}
+	}
+
 	errCh := make(chan error, len(servers))
 	for name, server := range servers {
 		go func(name string, s *http.Server) {
+			l, err := net.Listen("tcp", s.Addr)
+			if err != nil {
+				errCh <- fmt.Errorf("%s server failed to listen: %w", name, err)
+				return
+			}
+			if name != "profile" {
+				// The ingress might prepend the PROXY protocol header carrying the client's address.
+				l = pkghttp.NewProxyProtocolListener(l, configStore.ProxyProtocolTrusted)
+			}
+
+			serve := s.Serve
+			if s.TLSConfig != nil {
+				// The certificate is provided by the TLSConfig.
+				serve = func(l net.Listener) error { return s.ServeTLS(l, "", "") }
+			}
 			// Don't forward ErrServerClosed as that indicates we're already shutting down.
-			if err := s.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			if err := serve(l); err != nil && err != http.ErrServerClosed {
 				errCh <- fmt.Errorf("%s server failed: %w", name, err)
 			}
 		}(name, server)
@@ -233,6 +346,9 @@ func main() {
 		logger.Info("Received SIGTERM")
 		// Send a signal to let readiness probes start failing.
 		sigCancel()
+		// Keep serving the revisions we have requests buffered for, even
+		// once they get reassigned to the other activators.
+		throttler.Drain()
 	case err := <-errCh:
 		logger.Errorw("Failed to run HTTP server", zap.Error(err))
 	}
@@ -244,9 +360,20 @@ func main() {
 	logger.Info("Done waiting, shutting down servers.")
 
 	// Drain outstanding requests, and stop accepting new ones.
-	for _, server := range servers {
-		server.Shutdown(context.Background())
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), env.DrainTimeout)
+	defer drainCancel()
+	var wg sync.WaitGroup
+	for name, server := range servers {
+		wg.Add(1)
+		go func(name string, server *http.Server) {
+			defer wg.Done()
+			if err := server.Shutdown(drainCtx); err != nil {
+				logger.Warnw(fmt.Sprintf("Failed to drain the %s server in %v, closing it", name, env.DrainTimeout), zap.Error(err))
+				server.Close()
+			}
+		}(name, server)
 	}
+	wg.Wait()
 	logger.Info("Servers shutdown.")
 }
 
@@ -273,3 +400,23 @@ func flush(logger *zap.SugaredLogger) {
 	os.Stderr.Sync()
 	metrics.FlushExporter()
 }
+
+// backendTLSConfig returns the client TLS configuration used to talk to
+// queue-proxy, trusting the CAs from caFile, or nil if it's not set.
+func backendTLSConfig(logger *zap.SugaredLogger, caFile string) *tls.Config {
+	if caFile == "" {
+		return nil
+	}
+	ca, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		logger.Fatalw("Failed to read the backend CA file", zap.Error(err))
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		logger.Fatalf("No certificates found in the backend CA file %s", caFile)
+	}
+	return &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	}
+}
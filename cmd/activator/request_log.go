@@ -23,11 +23,18 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"knative.dev/pkg/metrics"
 	"knative.dev/serving/pkg/activator"
+	"knative.dev/serving/pkg/activator/util"
 	"knative.dev/serving/pkg/apis/serving"
 	servinglisters "knative.dev/serving/pkg/client/listers/serving/v1"
 	pkghttp "knative.dev/serving/pkg/http"
 )
 
+// activatorReqLogTemplateKey is the config-observability entry specifying the
+// request log template of the activator. It defaults to the request log template
+// shared with queue-proxy, but can also refer to the details of proxying the
+// request, i.e. the .Proxy fields.
+const activatorReqLogTemplateKey = "logging.activator-request-log-template"
+
 func updateRequestLogFromConfigMap(logger *zap.SugaredLogger, h *pkghttp.RequestLogHandler) func(configMap *corev1.ConfigMap) {
 	return func(configMap *corev1.ConfigMap) {
 		obsconfig, err := metrics.NewObservabilityConfigFromConfigMap(configMap)
@@ -39,6 +46,9 @@ func updateRequestLogFromConfigMap(logger *zap.SugaredLogger, h *pkghttp.Request
 		var newTemplate string
 		if obsconfig.EnableRequestLog {
 			newTemplate = obsconfig.RequestLogTemplate
+			if t := configMap.Data[activatorReqLogTemplateKey]; t != "" {
+				newTemplate = t
+			}
 		}
 		if err := h.SetTemplate(newTemplate); err != nil {
 			logger.Errorw("Failed to update the request log template.", zap.Error(err), "template", newTemplate)
@@ -63,10 +73,18 @@ func requestLogTemplateInputGetter(revisionLister servinglisters.RevisionLister)
 			revInfo.Service = revision.Labels[serving.ServiceLabelKey]
 		}
 
+		proxy := &pkghttp.RequestLogProxy{}
+		if stats := util.ProxyStatsFrom(req.Context()); stats != nil {
+			proxy.Dest = stats.Dest
+			proxy.QueueingDelay = stats.QueueingDelay.Seconds()
+			proxy.UpstreamLatency = stats.UpstreamLatency.Seconds()
+		}
+
 		return &pkghttp.RequestLogTemplateInput{
 			Request:  req,
 			Response: resp,
 			Revision: revInfo,
+			Proxy:    proxy,
 		}
 	}
 }
@@ -21,6 +21,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 
@@ -32,6 +33,7 @@ import (
 	"knative.dev/pkg/metrics"
 	rtesting "knative.dev/pkg/reconciler/testing"
 	"knative.dev/serving/pkg/activator"
+	"knative.dev/serving/pkg/activator/util"
 	"knative.dev/serving/pkg/apis/serving"
 	v1 "knative.dev/serving/pkg/apis/serving/v1"
 	fakeservingclient "knative.dev/serving/pkg/client/injection/client/fake"
@@ -113,6 +115,21 @@ func TestUpdateRequestLogFromConfigMap(t *testing.T) {
 			metrics.ReqLogTemplateKey: "{{.Request.URL}}\n",
 			metrics.EnableReqLogKey:   "false",
 		},
+	}, {
+		name: "activator template",
+		url:  "http://example.com/testpage",
+		data: map[string]string{
+			metrics.ReqLogTemplateKey:  "{{.Request.URL}}",
+			activatorReqLogTemplateKey: `{"url": "{{.Request.URL}}", "dest": "{{.Proxy.Dest}}"}`,
+			metrics.EnableReqLogKey:    "true",
+		},
+		want: `{"url": "http://example.com/testpage", "dest": ""}` + "\n",
+	}, {
+		name: "activator template, request logging disabled",
+		url:  "http://example.com/testpage",
+		data: map[string]string{
+			activatorReqLogTemplateKey: "{{.Request.URL}}",
+		},
 	}, {
 		name: "explicitly enable request logging with empty template",
 		url:  "http://example.com/testpage",
@@ -207,6 +224,32 @@ func TestRequestLogTemplateInputGetter(t *testing.T) {
 	}
 }
 
+func TestRequestLogTemplateInputGetterProxy(t *testing.T) {
+	getter := requestLogTemplateInputGetter(revisionLister(t, true))
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	req.Header.Set(activator.RevisionHeaderName, testRevisionName)
+	req.Header.Set(activator.RevisionHeaderNamespace, testNamespaceName)
+
+	// No stats, e.g. the request was rejected before being proxied.
+	if got, want := *getter(req, &pkghttp.RequestLogResponse{}).Proxy, (pkghttp.RequestLogProxy{}); got != want {
+		t.Errorf("Proxy = %#v, want: %#v", got, want)
+	}
+
+	req = req.WithContext(util.WithProxyStats(req.Context(), &util.ProxyStats{
+		Dest:            "10.0.0.1:8012",
+		QueueingDelay:   1500 * time.Millisecond,
+		UpstreamLatency: 250 * time.Millisecond,
+	}))
+	want := pkghttp.RequestLogProxy{
+		Dest:            "10.0.0.1:8012",
+		QueueingDelay:   1.5,
+		UpstreamLatency: 0.25,
+	}
+	if got := *getter(req, &pkghttp.RequestLogResponse{}).Proxy; got != want {
+		t.Errorf("Proxy = %#v, want: %#v", got, want)
+	}
+}
+
 func revisionLister(t *testing.T, addLabels bool) servinglisters.RevisionLister {
 	rev := &v1.Revision{
 		ObjectMeta: metav1.ObjectMeta{
@@ -27,7 +27,10 @@ import (
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
 	corev1listers "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/rest"
 
@@ -39,6 +42,7 @@ import (
 
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
+	"knative.dev/pkg/kmeta"
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/metrics"
 	"knative.dev/pkg/profiling"
@@ -48,12 +52,19 @@ import (
 	av1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
 	"knative.dev/serving/pkg/apis/serving"
 	"knative.dev/serving/pkg/autoscaler/bucket"
+	asconfig "knative.dev/serving/pkg/autoscaler/config"
+	"knative.dev/serving/pkg/autoscaler/config/autoscalerconfig"
 	asmetrics "knative.dev/serving/pkg/autoscaler/metrics"
 	"knative.dev/serving/pkg/autoscaler/scaling"
 	"knative.dev/serving/pkg/autoscaler/statforwarder"
 	"knative.dev/serving/pkg/autoscaler/statserver"
+	painformer "knative.dev/serving/pkg/client/injection/informers/autoscaling/v1alpha1/podautoscaler"
+	palisters "knative.dev/serving/pkg/client/listers/autoscaling/v1alpha1"
 	smetrics "knative.dev/serving/pkg/metrics"
 	"knative.dev/serving/pkg/reconciler/autoscaling/kpa"
+	kparesources "knative.dev/serving/pkg/reconciler/autoscaling/kpa/resources"
+	aresources "knative.dev/serving/pkg/reconciler/autoscaling/resources"
+	"knative.dev/serving/pkg/reconciler/leaderstatus"
 	"knative.dev/serving/pkg/reconciler/metric"
 	"knative.dev/serving/pkg/resources"
 )
@@ -124,7 +135,7 @@ func main() {
 	podLister := podinformer.Get(ctx).Lister()
 
 	collector := asmetrics.NewMetricCollector(
-		statsScraperFactoryFunc(podLister), logger)
+		statsScraperFactoryFunc(podLister), resourceScraperFactoryFunc(kubeClient), logger)
 
 	// Set up scalers.
 	// uniScalerFactory depends endpointsInformer to be set.
@@ -134,6 +145,7 @@ func main() {
 	controllers := []*controller.Impl{
 		kpa.NewController(ctx, cmw, multiScaler),
 		metric.NewController(ctx, cmw, collector),
+		leaderstatus.NewController(component, component+".", bucket.Prefix)(ctx, cmw),
 	}
 
 	// Start watching the configs.
@@ -158,6 +170,21 @@ func main() {
 
 	// Set up a statserver.
 	statsServer := statserver.New(statsServerAddr, statsCh, logger, f.IsBucketOwner)
+	// Serve the window data via the stats server, so that it can be reached
+	// through the bucket Services of the autoscaler owning the metric.
+	statsServer.Handle(statserver.WindowsPath,
+		statserver.NewWindowsHandler(collector, statserver.KubeAuthorizer(kubeClient), logger))
+	statsServer.Handle(statserver.SimulatePath,
+		statserver.NewSimulateHandler(collector,
+			simulationSpecsFunc(kubeClient, painformer.Get(ctx).Lister()),
+			statserver.KubeAuthorizer(kubeClient), logger))
+	// Serve the decider snapshots for the support bundles.
+	statsServer.Handle(statserver.DecidersPath,
+		statserver.NewDecidersHandler(multiScaler, statserver.KubeAuthorizer(kubeClient), logger))
+	// Release the stuck buckets of the autoscaler and the controller.
+	statsServer.Handle(statserver.ReleasePath,
+		statserver.NewReleaseHandler(kubeClient, leaderstatus.HasPrefix(component+".", bucket.Prefix, "controller."),
+			statserver.KubeLeaseAuthorizer(kubeClient), logger))
 
 	defer f.Cancel()
 
@@ -225,6 +252,43 @@ func statsScraperFactoryFunc(podLister corev1listers.PodLister) asmetrics.StatsS
 	}
 }
 
+func resourceScraperFactoryFunc(kubeClient kubernetes.Interface) asmetrics.ResourceScraperFactory {
+	return func(metric *av1alpha1.Metric) (asmetrics.ResourceScraper, error) {
+		return asmetrics.NewResourceScraper(kubeClient.Discovery().RESTClient(), metric)
+	}
+}
+
+// simulationSpecsFunc returns the SimulationSpecs built the same way the KPA
+// reconciler builds the Deciders and the Metrics, from the config-autoscaler
+// ConfigMap as is and with the proposed change applied.
+func simulationSpecsFunc(kubeClient kubernetes.Interface, paLister palisters.PodAutoscalerLister) statserver.SimulationSpecs {
+	return func(ctx context.Context, key types.NamespacedName, change map[string]string) (*statserver.SimulationSpec, *statserver.SimulationSpec, error) {
+		pa, err := paLister.PodAutoscalers(key.Namespace).Get(key.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		cm, err := kubeClient.CoreV1().ConfigMaps(system.Namespace()).Get(ctx, asconfig.ConfigName, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, err
+		}
+		current, err := asconfig.NewConfigFromMap(cm.Data)
+		if err != nil {
+			return nil, nil, err
+		}
+		proposed, err := asconfig.NewConfigFromMap(kmeta.UnionMaps(cm.Data, change))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", statserver.ErrInvalidConfig, err)
+		}
+		spec := func(cfg *autoscalerconfig.Config) *statserver.SimulationSpec {
+			return &statserver.SimulationSpec{
+				Decider:     &kparesources.MakeDecider(ctx, pa, cfg).Spec,
+				PanicWindow: aresources.MakeMetric(pa, "", cfg).Spec.PanicWindow,
+			}
+		}
+		return spec(current), spec(proposed), nil
+	}
+}
+
 func flush(logger *zap.SugaredLogger) {
 	logger.Sync()
 	metrics.FlushExporter()
@@ -17,10 +17,17 @@ limitations under the License.
 package main
 
 import (
+	"fmt"
+	"os"
+
+	"knative.dev/pkg/signals"
+	"knative.dev/serving/pkg/diag"
+
 	// The set of controllers this controller process runs.
 	"knative.dev/serving/pkg/reconciler/configuration"
 	"knative.dev/serving/pkg/reconciler/gc"
 	"knative.dev/serving/pkg/reconciler/labeler"
+	"knative.dev/serving/pkg/reconciler/leaderstatus"
 	"knative.dev/serving/pkg/reconciler/revision"
 	"knative.dev/serving/pkg/reconciler/route"
 	"knative.dev/serving/pkg/reconciler/serverlessservice"
@@ -39,8 +46,20 @@ var ctors = []injection.ControllerConstructor{
 	serverlessservice.NewController,
 	service.NewController,
 	gc.NewController,
+	leaderstatus.NewController("controller", "controller."),
 }
 
 func main() {
+	// The controller image also ships the support bundle collection, so
+	// that it can be run without extra images, e.g.
+	//   kubectl exec -n knative-serving deploy/controller -- \
+	//     /ko-app/controller diag -namespace ns -service svc -output - > bundle.tar.gz
+	if len(os.Args) > 1 && os.Args[1] == "diag" {
+		if err := diag.Main(signals.NewContext(), os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
 	sharedmain.Main("controller", ctors...)
 }
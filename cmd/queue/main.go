@@ -18,6 +18,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"net"
@@ -27,6 +28,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
@@ -47,6 +49,7 @@ import (
 	tracingconfig "knative.dev/pkg/tracing/config"
 	"knative.dev/pkg/tracing/propagation/tracecontextb3"
 	activatorutil "knative.dev/serving/pkg/activator/util"
+	"knative.dev/serving/pkg/apis/serving"
 	pkghttp "knative.dev/serving/pkg/http"
 	"knative.dev/serving/pkg/http/handler"
 	"knative.dev/serving/pkg/logging"
@@ -54,6 +57,7 @@ import (
 	"knative.dev/serving/pkg/queue"
 	"knative.dev/serving/pkg/queue/health"
 	"knative.dev/serving/pkg/queue/readiness"
+	servingtracing "knative.dev/serving/pkg/tracing"
 )
 
 const (
@@ -74,6 +78,97 @@ type config struct {
 	ServingReadinessProbe  string `split_words:"true" required:"true"`
 	EnableProfiling        bool   `split_words:"true"` // optional
 
+	CountLongLivedConnections bool `split_words:"true" default:"true"` // optional
+
+	// The response start and idle timeouts, see
+	// v1.RevisionSpec.ResponseStartTimeoutSeconds and IdleTimeoutSeconds.
+	RevisionResponseStartTimeoutSeconds int `split_words:"true"` // optional
+	RevisionIdleTimeoutSeconds          int `split_words:"true"` // optional
+
+	// The drain timeout and the pre-stop path of the user container, see
+	// v1.RevisionSpec.DrainTimeoutSeconds and serving.PreStopPathAnnotationKey.
+	RevisionDrainTimeoutSeconds int    `split_words:"true"` // optional
+	UserPrestopPath             string `split_words:"true"` // optional
+
+	// UserNamedPorts are the additional named ports of the user container,
+	// as comma separated `name=port` pairs, see config.MultiPort.
+	UserNamedPorts string `split_words:"true"` // optional
+
+	// UserUnixSocket is the unix domain socket the user container serves on,
+	// instead of the user port, see v1.Revision.UsesUnixSocket.
+	UserUnixSocket string `split_words:"true"` // optional
+
+	// UserScheme is the scheme the user container serves, https to speak TLS
+	// to it, and UserCABundlePath the CA bundle to verify its certificate
+	// against, see serving.QueueSidecarBackendSchemeAnnotationKey.
+	UserScheme       string `split_words:"true"` // optional
+	UserCABundlePath string `split_words:"true"` // optional
+
+	// ServingDebugPort is the localhost port of the debug server, serving
+	// pprof and the proxy runtime state. The server only runs when it is set.
+	ServingDebugPort int `split_words:"true"` // optional
+
+	// The timing of the aggressive readiness probe, see readiness.Timing.
+	ServingReadinessProbePeriod        time.Duration `split_words:"true"` // optional
+	ServingReadinessProbeTimeout       time.Duration `split_words:"true"` // optional
+	ServingReadinessProbeBackoffFactor float64       `split_words:"true"` // optional
+	ServingReadinessProbeMaxPeriod     time.Duration `split_words:"true"` // optional
+
+	// BackendTLSCertsDir is the directory with the certificate to serve TLS
+	// to the activator with. The TLS server is only started when it is set.
+	BackendTLSCertsDir string `split_words:"true"` // optional
+
+	// The TLS settings of the server, see networking.DataplaneTLSMinVersionKey
+	// and friends.
+	BackendTLSMinVersion   string `split_words:"true"` // optional
+	BackendTLSCipherSuites string `split_words:"true"` // optional
+	BackendTLSFIPSMode     bool   `split_words:"true"` // optional
+
+	// The request and response header manipulations, see
+	// serving.RequestHeadersSetAnnotationKey and friends.
+	ServingRequestHeadersSet     string `split_words:"true"` // optional
+	ServingRequestHeadersRemove  string `split_words:"true"` // optional
+	ServingResponseHeadersSet    string `split_words:"true"` // optional
+	ServingResponseHeadersRemove string `split_words:"true"` // optional
+
+	// How the container concurrency is enforced, see
+	// serving.ConcurrencyModeAnnotationKey and friends.
+	ServingConcurrencyMode         string        `split_words:"true"` // optional
+	ServingConcurrencyQueueDepth   int           `split_words:"true"` // optional
+	ServingConcurrencyQueueTimeout time.Duration `split_words:"true"` // optional
+
+	// The maximum size of the request bodies in bytes, see
+	// serving.MaxRequestBodySizeAnnotationKey.
+	ServingMaxRequestBodySize int64 `split_words:"true"` // optional
+
+	// The response compression, see serving.ResponseCompressionAnnotationKey
+	// and serving.ResponseCompressionTypesAnnotationKey.
+	ServingResponseCompression      string `split_words:"true"` // optional
+	ServingResponseCompressionTypes string `split_words:"true"` // optional
+
+	// The tuning of the transport to the user container, see the
+	// queueSidecar transport keys of config-deployment.
+	ServingMaxIdleConnsPerHost int           `split_words:"true"` // optional
+	ServingTLSHandshakeTimeout time.Duration `split_words:"true"` // optional
+	ServingDisableKeepAlives   bool          `split_words:"true"` // optional
+
+	// Whether to read the PROXY protocol header off the serving connections,
+	// and the proxies to read it from, see networking.DataplaneProxyProtocolKey
+	// and networking.DataplaneProxyProtocolTrustedCIDRsKey.
+	ServingProxyProtocol             bool   `split_words:"true"` // optional
+	ServingProxyProtocolTrustedCidrs string `split_words:"true"` // optional
+
+	// Whether to check the user container with the gRPC health checking
+	// protocol, and the service to check, see serving.GRPCProbeAnnotationKey.
+	ServingGRPCProbe        bool   `split_words:"true"` // optional
+	ServingGRPCProbeService string `split_words:"true"` // optional
+
+	// The concurrency state hook, see
+	// serving.ConcurrencyStateEndpointAnnotationKey.
+	ConcurrencyStateEndpoint  string `split_words:"true"` // optional
+	ConcurrencyStateTokenPath string `split_words:"true"` // optional
+	HostIP                    string `split_words:"true"` // optional
+
 	// Logging configuration
 	ServingLoggingConfig         string `split_words:"true" required:"true"`
 	ServingLoggingLevel          string `split_words:"true" required:"true"`
@@ -97,6 +192,8 @@ type config struct {
 	TracingConfigSampleRate           float64                   `split_words:"true"` // optional
 	TracingConfigZipkinEndpoint       string                    `split_words:"true"` // optional
 	TracingConfigStackdriverProjectID string                    `split_words:"true"` // optional
+	TracingConfigOtlpEndpoint         string                    `split_words:"true"` // optional
+	TracingConfigOtlpInsecure         bool                      `split_words:"true"` // optional
 }
 
 func init() {
@@ -156,27 +253,62 @@ func main() {
 	defer reportTicker.Stop()
 
 	stats := network.NewRequestStats(time.Now())
+	longLived := &queue.LongLivedConnections{Counted: env.CountLongLivedConnections}
 	go func() {
 		for now := range reportTicker.C {
 			stat := stats.Report(now)
 			promStatReporter.Report(stat)
+			promStatReporter.ReportLongLivedConnections(longLived.Open())
 			protoStatReporter.Report(stat)
 		}
 	}()
 
 	// Setup probe to run for checking user-application healthiness.
-	probe := buildProbe(logger, env.ServingReadinessProbe)
+	probe := buildProbe(logger, env)
 	healthState := &health.State{}
 
-	mainServer := buildServer(ctx, env, healthState, probe, stats, logger)
+	breaker := buildBreaker(logger, env)
+	transport := buildTransport(env, logger)
+	var transportStats *queue.TransportStats
+	if env.ServingDebugPort != 0 {
+		transportStats = queue.NewTransportStats(transport)
+		transport = transportStats
+	}
+
+	mainServer := buildServer(ctx, env, healthState, probe, stats, longLived, breaker, transport, logger)
 	servers := map[string]*http.Server{
 		"main":    mainServer,
 		"admin":   buildAdminServer(logger, healthState),
 		"metrics": buildMetricsServer(promStatReporter, protoStatReporter),
 	}
+	if env.BackendTLSCertsDir != "" {
+		tlsServer, err := buildTLSServer(env, mainServer.Handler)
+		if err != nil {
+			logger.Fatalw("Failed to parse the TLS settings", zap.Error(err))
+		}
+		servers["tls"] = tlsServer
+	}
 	if env.EnableProfiling {
 		servers["profile"] = profiling.NewServer(profiling.NewHandler(logger, true))
 	}
+	if env.ServingDebugPort != 0 {
+		servers["debug"] = &http.Server{
+			Addr:    net.JoinHostPort("127.0.0.1", strconv.Itoa(env.ServingDebugPort)),
+			Handler: queue.DebugHandler(logger, breaker, transportStats),
+		}
+	}
+
+	var proxyProtocolTrusted func(net.Addr) bool
+	if env.ServingProxyProtocol {
+		nc, err := networking.NewConfigFromMap(map[string]string{
+			networking.DataplaneProxyProtocolKey:             "true",
+			networking.DataplaneProxyProtocolTrustedCIDRsKey: env.ServingProxyProtocolTrustedCidrs,
+		})
+		if err != nil {
+			logger.Fatalw("Failed to parse the PROXY protocol settings", zap.Error(err))
+		}
+		proxyProtocolTrusted = nc.ProxyProtocolTrusted
+	}
 
 	errCh := make(chan error)
 	listenCh := make(chan struct{})
@@ -192,9 +324,19 @@ func main() {
 			if s == mainServer {
 				close(listenCh)
 			}
+			if proxyProtocolTrusted != nil && (name == "main" || name == "tls") {
+				l = pkghttp.NewProxyProtocolListener(l, proxyProtocolTrusted)
+			}
 
+			serve := s.Serve
+			if s.TLSConfig != nil {
+				serve = func(l net.Listener) error {
+					// The certificate comes from the TLSConfig.
+					return s.ServeTLS(l, "", "")
+				}
+			}
 			// Don't forward ErrServerClosed as that indicates we're already shutting down.
-			if err := s.Serve(l); err != nil && err != http.ErrServerClosed {
+			if err := serve(l); err != nil && err != http.ErrServerClosed {
 				errCh <- fmt.Errorf("%s server failed to serve: %w", name, err)
 			}
 		}(name, server)
@@ -235,13 +377,44 @@ func main() {
 			time.Sleep(pkgnet.DefaultDrainTimeout)
 
 			// Calling server.Shutdown() allows pending requests to
-			// complete, while no new work is accepted.
-			logger.Info("Shutting down main server")
-			if err := mainServer.Shutdown(context.Background()); err != nil {
-				logger.Errorw("Failed to shutdown proxy server", zap.Error(err))
+			// complete, while no new work is accepted, for up to the
+			// drain timeout.
+			// The TLS server proxies the same requests, so it is drained too.
+			drainTimeout := time.Duration(env.RevisionTimeoutSeconds) * time.Second
+			if env.RevisionDrainTimeoutSeconds > 0 {
+				drainTimeout = time.Duration(env.RevisionDrainTimeoutSeconds) * time.Second
+			}
+			drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+			defer cancel()
+			for _, name := range []string{"main", "tls"} {
+				srv, ok := servers[name]
+				if !ok {
+					continue
+				}
+				logger.Infof("Shutting down %s server", name)
+				if err := srv.Shutdown(drainCtx); err != nil {
+					logger.Errorw("Failed to shutdown proxy server", zap.String("server", name), zap.Error(err))
+				}
+				// Removing the server from the shutdown logic as we've already shut it down.
+				delete(servers, name)
+			}
+
+			// Only now let the user container know it is about to be stopped,
+			// which happens once the drain handler returns.
+			if env.UserPrestopPath != "" {
+				url := userScheme(env) + "://" + userAddress(env) + env.UserPrestopPath
+				logger.Info("Notifying the user container pre-stop path at ", url)
+				client := &http.Client{Timeout: drainTimeout}
+				switch {
+				case env.UserUnixSocket != "":
+					client.Transport = queue.NewUnixSocketTransport(env.UserUnixSocket, userAddress(env), queue.TransportOptions{MaxIdleConnsPerHost: 1})
+				case env.UserScheme == serving.BackendSchemeHTTPS:
+					client.Transport = queue.NewBackendTLSTransport(buildBackendTLSConfig(env, logger), queue.TransportOptions{MaxIdleConnsPerHost: 1})
+				}
+				if err := queue.NotifyPreStop(context.Background(), client, url); err != nil {
+					logger.Errorw("Failed to notify the user container pre-stop path", zap.Error(err))
+				}
 			}
-			// Removing the main server from the shutdown logic as we've already shut it down.
-			delete(servers, "main")
 		})
 
 		for serverName, srv := range servers {
@@ -254,50 +427,82 @@ func main() {
 	}
 }
 
-func buildProbe(logger *zap.SugaredLogger, probeJSON string) *readiness.Probe {
-	coreProbe, err := readiness.DecodeProbe(probeJSON)
+func buildProbe(logger *zap.SugaredLogger, env config) *readiness.Probe {
+	coreProbe, err := readiness.DecodeProbe(env.ServingReadinessProbe)
 	if err != nil {
 		logger.Fatalw("Queue container failed to parse readiness probe", zap.Error(err))
 	}
-	return readiness.NewProbe(coreProbe)
+	var probe *readiness.Probe
+	if env.ServingGRPCProbe {
+		probe = readiness.NewGRPCProbe(coreProbe, env.ServingGRPCProbeService)
+	} else {
+		probe = readiness.NewProbe(coreProbe)
+	}
+	probe.SetTiming(readiness.Timing{
+		Period:        env.ServingReadinessProbePeriod,
+		Timeout:       env.ServingReadinessProbeTimeout,
+		BackoffFactor: env.ServingReadinessProbeBackoffFactor,
+		MaxPeriod:     env.ServingReadinessProbeMaxPeriod,
+	})
+	if env.UserUnixSocket != "" {
+		probe.SetUnixSocket(env.UserUnixSocket)
+	}
+	return probe
 }
 
 func buildServer(ctx context.Context, env config, healthState *health.State, rp *readiness.Probe, stats *network.RequestStats,
+	longLived *queue.LongLivedConnections, breaker *queue.Breaker, transport http.RoundTripper,
 	logger *zap.SugaredLogger) *http.Server {
 	target := &url.URL{
-		Scheme: "http",
-		Host:   net.JoinHostPort("127.0.0.1", strconv.Itoa(env.UserPort)),
-	}
-
-	maxIdleConns := 1000 // TODO: somewhat arbitrary value for CC=0, needs experimental validation.
-	if env.ContainerConcurrency > 0 {
-		maxIdleConns = env.ContainerConcurrency
+		Scheme: userScheme(env),
+		Host:   userAddress(env),
 	}
 
 	httpProxy := httputil.NewSingleHostReverseProxy(target)
-	httpProxy.Transport = buildTransport(env, logger, maxIdleConns)
+	httpProxy.Transport = transport
 	httpProxy.ErrorHandler = pkgnet.ErrorHandler(logger)
 	httpProxy.BufferPool = network.NewBufferPool()
 	httpProxy.FlushInterval = network.FlushInterval
 	activatorutil.SetupHeaderPruning(httpProxy)
+	namedPorts, err := queue.ParseNamedPorts(env.UserNamedPorts)
+	if err != nil {
+		logger.Fatalw("Failed to parse the named ports", zap.Error(err))
+	}
+	if len(namedPorts) > 0 {
+		queue.SetupNamedPortRouting(httpProxy, namedPorts)
+	}
 
-	breaker := buildBreaker(logger, env)
 	metricsSupported := supportsMetrics(ctx, logger, env)
 	tracingEnabled := env.TracingConfigBackend != tracingconfig.None
+	// The timeoutSeconds bound the whole request, the response start and idle
+	// timeouts, if any, bound its silences within that.
 	timeout := time.Duration(env.RevisionTimeoutSeconds) * time.Second
+	responseStartTimeout := timeout
+	if env.RevisionResponseStartTimeoutSeconds > 0 {
+		responseStartTimeout = time.Duration(env.RevisionResponseStartTimeoutSeconds) * time.Second
+	}
+	idleTimeout := time.Duration(env.RevisionIdleTimeoutSeconds) * time.Second
 
 	// Create queue handler chain.
 	// Note: innermost handlers are specified first, ie. the last handler in the chain will be executed first.
 	var composedHandler http.Handler = httpProxy
+	composedHandler = compressionHandler(composedHandler, env)
+	composedHandler = headerHandler(logger, composedHandler, env)
+	composedHandler = queue.RouteHeaderRulesHandler(logger, composedHandler)
+	composedHandler = concurrencyStateHandler(logger, composedHandler, env)
 	if metricsSupported {
-		composedHandler = requestAppMetricsHandler(logger, composedHandler, breaker, env)
+		composedHandler = requestAppMetricsHandler(ctx, logger, composedHandler, breaker, env)
 	}
-	composedHandler = queue.ProxyHandler(breaker, stats, tracingEnabled, composedHandler)
+	composedHandler = queue.ProxyHandler(breaker, stats, longLived, tracingEnabled, composedHandler)
+	// Reject the oversized requests before they take a slot in the breaker.
+	composedHandler = pkghttp.MaxBodySizeHandler(env.ServingMaxRequestBodySize, composedHandler)
 	composedHandler = queue.ForwardedShimHandler(composedHandler)
-	composedHandler = handler.NewTimeToFirstByteTimeoutHandler(composedHandler, "request timeout", handler.StaticTimeoutFunc(timeout))
+	composedHandler = handler.NewTimeoutHandler(composedHandler, "request timeout",
+		handler.StaticTimeoutFunc(responseStartTimeout), handler.StaticTimeoutFunc(idleTimeout),
+		handler.StaticTimeoutFunc(timeout))
 
 	if metricsSupported {
-		composedHandler = requestMetricsHandler(logger, composedHandler, env)
+		composedHandler = requestMetricsHandler(ctx, logger, composedHandler, env)
 	}
 	composedHandler = tracing.HTTPSpanMiddleware(composedHandler)
 
@@ -310,22 +515,119 @@ func buildServer(ctx context.Context, env config, healthState *health.State, rp
 	return pkgnet.NewServer(":"+strconv.Itoa(env.QueueServingPort), composedHandler)
 }
 
-func buildTransport(env config, logger *zap.SugaredLogger, maxConns int) http.RoundTripper {
-	// set max-idle and max-idle-per-host to same value since we're always proxying to the same host.
-	transport := pkgnet.NewAutoTransport(maxConns /* max-idle */, maxConns /* max-idle-per-host */)
+// headerHandler wraps the handler to manipulate the request and response
+// headers as requested by the revision, if any.
+func headerHandler(logger *zap.SugaredLogger, h http.Handler, env config) http.Handler {
+	request, err := queue.ParseHeaderRules(env.ServingRequestHeadersSet, env.ServingRequestHeadersRemove)
+	if err != nil {
+		logger.Fatalw("Failed to parse the request header rules", zap.Error(err))
+	}
+	response, err := queue.ParseHeaderRules(env.ServingResponseHeadersSet, env.ServingResponseHeadersRemove)
+	if err != nil {
+		logger.Fatalw("Failed to parse the response header rules", zap.Error(err))
+	}
+	if request == nil && response == nil {
+		return h
+	}
+	return queue.HeaderHandler(request, response, h)
+}
+
+// compressionHandler wraps the handler to compress the responses,
+// if requested by the revision.
+func compressionHandler(h http.Handler, env config) http.Handler {
+	if env.ServingResponseCompression != "gzip" {
+		return h
+	}
+	return queue.CompressionHandler(queue.ParseCompressionTypes(env.ServingResponseCompressionTypes), h)
+}
+
+// concurrencyStateHandler wraps the handler to notify the concurrency state
+// endpoint of the pod becoming idle and active, if requested by the revision.
+func concurrencyStateHandler(logger *zap.SugaredLogger, h http.Handler, env config) http.Handler {
+	if env.ConcurrencyStateEndpoint == "" {
+		return h
+	}
+	hook := &queue.ConcurrencyStateHook{
+		Endpoint:  strings.ReplaceAll(env.ConcurrencyStateEndpoint, "$HOST_IP", env.HostIP),
+		TokenPath: env.ConcurrencyStateTokenPath,
+		Client:    &http.Client{Timeout: 5 * time.Second},
+	}
+	logger.Info("Notifying the concurrency state endpoint at ", hook.Endpoint)
+	return queue.ConcurrencyStateHandler(logger, h, hook.Pause, hook.Resume)
+}
+
+// buildTLSServer builds the server serving the main handler over TLS
+// to the activator, with the certificate from the BackendTLSCertsDir,
+// constrained like the other data-plane listeners.
+func buildTLSServer(env config, h http.Handler) (*http.Server, error) {
+	nc, err := networking.NewConfigFromMap(map[string]string{
+		networking.DataplaneTLSMinVersionKey:   env.BackendTLSMinVersion,
+		networking.DataplaneTLSCipherSuitesKey: env.BackendTLSCipherSuites,
+		networking.DataplaneTLSFIPSModeKey:     strconv.FormatBool(env.BackendTLSFIPSMode),
+	})
+	if err != nil {
+		return nil, err
+	}
+	s := pkgnet.NewServer(":"+strconv.Itoa(networking.BackendHTTPSPort), h)
+	s.TLSConfig = nc.DataplaneTLSConfig()
+	s.TLSConfig.GetCertificate = queue.NewCertificateReloader(env.BackendTLSCertsDir).GetCertificate
+	return s, nil
+}
+
+// transportOptions returns the options of the transport to the user container,
+// see the queueSidecar transport keys of config-deployment.
+func transportOptions(env config) queue.TransportOptions {
+	maxConns := 1000 // TODO: somewhat arbitrary value for CC=0, needs experimental validation.
+	if env.ContainerConcurrency > 0 {
+		maxConns = env.ContainerConcurrency
+	}
+	if env.ServingMaxIdleConnsPerHost > 0 {
+		maxConns = env.ServingMaxIdleConnsPerHost
+	}
+	return queue.TransportOptions{
+		MaxIdleConnsPerHost: maxConns,
+		TLSHandshakeTimeout: env.ServingTLSHandshakeTimeout,
+		DisableKeepAlives:   env.ServingDisableKeepAlives,
+	}
+}
+
+func buildTransport(env config, logger *zap.SugaredLogger) http.RoundTripper {
+	var transport http.RoundTripper
+	opts := transportOptions(env)
+	switch {
+	case env.UserUnixSocket != "":
+		transport = queue.NewUnixSocketTransport(env.UserUnixSocket, userAddress(env), opts)
+	case env.UserScheme == serving.BackendSchemeHTTPS:
+		transport = queue.NewBackendTLSTransport(buildBackendTLSConfig(env, logger), opts)
+	default:
+		transport = queue.NewTransport(opts)
+	}
 
 	if env.TracingConfigBackend == tracingconfig.None {
 		return transport
 	}
 
-	oct := tracing.NewOpenCensusTracer(tracing.WithExporterFull(env.ServingPod, env.ServingPodIP, logger))
-	oct.ApplyConfig(&tracingconfig.Config{
-		Backend:              env.TracingConfigBackend,
-		Debug:                env.TracingConfigDebug,
-		ZipkinEndpoint:       env.TracingConfigZipkinEndpoint,
-		StackdriverProjectID: env.TracingConfigStackdriverProjectID,
-		SampleRate:           env.TracingConfigSampleRate,
-	})
+	tracer := servingtracing.NewTracer(env.ServingPod, env.ServingPodIP, map[string]string{
+		servingtracing.ServiceNameAttribute: "queue-proxy",
+		servingtracing.NamespaceAttribute:   env.ServingNamespace,
+		servingtracing.PodAttribute:         env.ServingPod,
+		serving.ServiceLabelKey:             env.ServingService,
+		serving.ConfigurationLabelKey:       env.ServingConfiguration,
+		serving.RevisionLabelKey:            env.ServingRevision,
+	}, logger)
+	if err := tracer.ApplyConfig(&servingtracing.Config{
+		Config: tracingconfig.Config{
+			Backend:              env.TracingConfigBackend,
+			Debug:                env.TracingConfigDebug,
+			ZipkinEndpoint:       env.TracingConfigZipkinEndpoint,
+			StackdriverProjectID: env.TracingConfigStackdriverProjectID,
+			SampleRate:           env.TracingConfigSampleRate,
+		},
+		OTLPEndpoint: env.TracingConfigOtlpEndpoint,
+		OTLPInsecure: env.TracingConfigOtlpInsecure,
+	}); err != nil {
+		logger.Errorw("Failed to apply the tracing config", zap.Error(err))
+	}
 
 	return &ochttp.Transport{
 		Base:        transport,
@@ -333,15 +635,48 @@ func buildTransport(env config, logger *zap.SugaredLogger, maxConns int) http.Ro
 	}
 }
 
+// userAddress returns the address of the user port, which is proxied to.
+func userAddress(env config) string {
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(env.UserPort))
+}
+
+// userScheme returns the scheme the user container serves.
+func userScheme(env config) string {
+	if env.UserScheme == serving.BackendSchemeHTTPS {
+		return serving.BackendSchemeHTTPS
+	}
+	return serving.BackendSchemeHTTP
+}
+
+// buildBackendTLSConfig returns the TLS config to connect to the user
+// container serving HTTPS with, see queue.BackendTLSConfig.
+func buildBackendTLSConfig(env config, logger *zap.SugaredLogger) *tls.Config {
+	conf, err := queue.BackendTLSConfig(env.UserCABundlePath)
+	if err != nil {
+		logger.Fatalw("Failed to load the CA bundle of the user container", zap.Error(err))
+	}
+	return conf
+}
+
 func buildBreaker(logger *zap.SugaredLogger, env config) *queue.Breaker {
 	if env.ContainerConcurrency < 1 {
 		return nil
 	}
 
 	// We set the queue depth to be equal to the container concurrency * 10 to
-	// allow the autoscaler time to react.
+	// allow the autoscaler time to react, unless the revision asks otherwise.
 	queueDepth := env.ContainerConcurrency * 10
-	params := queue.BreakerParams{QueueDepth: queueDepth, MaxConcurrency: env.ContainerConcurrency, InitialCapacity: env.ContainerConcurrency}
+	params := queue.BreakerParams{MaxConcurrency: env.ContainerConcurrency, InitialCapacity: env.ContainerConcurrency}
+	switch env.ServingConcurrencyMode {
+	case serving.ConcurrencyModeSoft:
+		if env.ServingConcurrencyQueueDepth > 0 {
+			queueDepth = env.ServingConcurrencyQueueDepth
+		}
+		params.QueueTimeout = env.ServingConcurrencyQueueTimeout
+	case serving.ConcurrencyModeReject:
+		params.RejectOverflow = true
+	}
+	params.QueueDepth = queueDepth
 	logger.Infof("Queue container is starting with %#v", params)
 
 	return queue.NewBreaker(params)
@@ -406,8 +741,8 @@ func pushRequestLogHandler(logger *zap.SugaredLogger, currentHandler http.Handle
 	return handler
 }
 
-func requestMetricsHandler(logger *zap.SugaredLogger, currentHandler http.Handler, env config) http.Handler {
-	h, err := queue.NewRequestMetricsHandler(currentHandler, env.ServingNamespace,
+func requestMetricsHandler(ctx context.Context, logger *zap.SugaredLogger, currentHandler http.Handler, env config) http.Handler {
+	h, err := queue.NewRequestMetricsHandler(ctx, currentHandler, env.ServingNamespace,
 		env.ServingService, env.ServingConfiguration, env.ServingRevision, env.ServingPod)
 	if err != nil {
 		logger.Errorw("Error setting up request metrics reporter. Request metrics will be unavailable.", zap.Error(err))
@@ -416,8 +751,8 @@ func requestMetricsHandler(logger *zap.SugaredLogger, currentHandler http.Handle
 	return h
 }
 
-func requestAppMetricsHandler(logger *zap.SugaredLogger, currentHandler http.Handler, breaker *queue.Breaker, env config) http.Handler {
-	h, err := queue.NewAppRequestMetricsHandler(currentHandler, breaker, env.ServingNamespace,
+func requestAppMetricsHandler(ctx context.Context, logger *zap.SugaredLogger, currentHandler http.Handler, breaker *queue.Breaker, env config) http.Handler {
+	h, err := queue.NewAppRequestMetricsHandler(ctx, currentHandler, breaker, env.ServingNamespace,
 		env.ServingService, env.ServingConfiguration, env.ServingRevision, env.ServingPod)
 	if err != nil {
 		logger.Errorw("Error setting up app request metrics reporter. Request metrics will be unavailable.", zap.Error(err))
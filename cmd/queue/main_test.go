@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"crypto/tls"
 	"net/http"
 	"net/http/httptest"
 	"net/http/httputil"
@@ -146,7 +147,7 @@ func TestQueueTraceSpans(t *testing.T) {
 					Propagation: tracecontextb3.B3Egress,
 				}
 
-				h := queue.ProxyHandler(breaker, network.NewRequestStats(time.Now()), true /*tracingEnabled*/, proxy)
+				h := queue.ProxyHandler(breaker, network.NewRequestStats(time.Now()), nil /*longLived*/, true /*tracingEnabled*/, proxy)
 				h(writer, req)
 			} else {
 				h := health.ProbeHandler(healthState, tc.prober, true /* isAggressive*/, true /*tracingEnabled*/, nil)
@@ -188,3 +189,34 @@ func TestQueueTraceSpans(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildTLSServer(t *testing.T) {
+	s, err := buildTLSServer(config{
+		BackendTLSMinVersion:   "1.2",
+		BackendTLSCipherSuites: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		BackendTLSFIPSMode:     true,
+	}, http.NotFoundHandler())
+	if err != nil {
+		t.Fatal("buildTLSServer() =", err)
+	}
+	if got, want := s.TLSConfig.MaxVersion, uint16(tls.VersionTLS12); got != want {
+		t.Errorf("MaxVersion = %x, want: %x", got, want)
+	}
+	if got, want := s.TLSConfig.CipherSuites, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("CipherSuites = %v, want: %v", got, want)
+	}
+	if s.TLSConfig.GetCertificate == nil {
+		t.Error("GetCertificate is not set")
+	}
+
+	// The defaults apply without the settings.
+	if s, err := buildTLSServer(config{}, http.NotFoundHandler()); err != nil {
+		t.Error("buildTLSServer() =", err)
+	} else if got, want := s.TLSConfig.MinVersion, uint16(tls.VersionTLS12); got != want {
+		t.Errorf("MinVersion = %x, want: %x", got, want)
+	}
+
+	if _, err := buildTLSServer(config{BackendTLSMinVersion: "1.0"}, http.NotFoundHandler()); err == nil {
+		t.Error("buildTLSServer() = nil error for TLS 1.0")
+	}
+}
@@ -37,6 +37,7 @@ import (
 	// resource validation types
 	net "knative.dev/networking/pkg/apis/networking/v1alpha1"
 	autoscalingv1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/apis/serving"
 	servingv1 "knative.dev/serving/pkg/apis/serving/v1"
 	extravalidation "knative.dev/serving/pkg/webhook"
 
@@ -48,6 +49,7 @@ import (
 	"knative.dev/serving/pkg/deployment"
 	"knative.dev/serving/pkg/gc"
 	domainconfig "knative.dev/serving/pkg/reconciler/route/config"
+	"knative.dev/serving/pkg/tracing"
 )
 
 var types = map[schema.GroupVersionKind]resourcesemantics.GenericCRD{
@@ -104,6 +106,20 @@ func newValidationAdmissionController(ctx context.Context, cmw configmap.Watcher
 	store := defaultconfig.NewStore(logging.FromContext(ctx).Named("config-store"))
 	store.WatchConfigs(cmw)
 
+	// The revisions must not skip the verification of the image signatures,
+	// if config-deployment has public keys.
+	deploymentStore := configmap.NewUntypedStore("deployment", logging.FromContext(ctx).Named("config-store"),
+		configmap.Constructors{deployment.ConfigName: deployment.NewConfigFromConfigMap})
+	deploymentStore.WatchConfigs(cmw)
+	toContext := func(ctx context.Context) context.Context {
+		ctx = store.ToContext(ctx)
+		if cfg, ok := deploymentStore.UntypedLoad(deployment.ConfigName).(*deployment.Config); ok &&
+			cfg.DigestResolutionSignaturePublicKeys != "" {
+			ctx = serving.WithImageSignatureVerification(ctx)
+		}
+		return ctx
+	}
+
 	return validation.NewAdmissionController(ctx,
 
 		// Name of the resource webhook.
@@ -116,7 +132,7 @@ func newValidationAdmissionController(ctx context.Context, cmw configmap.Watcher
 		types,
 
 		// A function that infuses the context passed to Validate/SetDefaults with custom metadata.
-		store.ToContext,
+		toContext,
 
 		// Whether to disallow unknown fields.
 		true,
@@ -137,7 +153,7 @@ func newConfigValidationController(ctx context.Context, cmw configmap.Watcher) *
 
 		// The configmaps to validate.
 		configmap.Constructors{
-			tracingconfig.ConfigName:         tracingconfig.NewTracingConfigFromConfigMap,
+			tracingconfig.ConfigName:         tracing.NewConfigFromConfigMap,
 			autoscalerconfig.ConfigName:      autoscalerconfig.NewConfigFromConfigMap,
 			gc.ConfigName:                    gc.NewConfigFromConfigMapFunc(ctx),
 			network.ConfigName:               network.NewConfigFromConfigMap,
@@ -16,6 +16,13 @@ limitations under the License.
 
 package activator
 
+import "errors"
+
+// ErrRevisionNotAssigned is returned for the requests to a revision this
+// activator does not back, which would otherwise wait for capacity that
+// is not tracked here.
+var ErrRevisionNotAssigned = errors.New("revision is not assigned to this activator")
+
 const (
 	// Name is the name of the component.
 	Name = "activator"
@@ -23,4 +30,21 @@ const (
 	RevisionHeaderName = "Knative-Serving-Revision"
 	// RevisionHeaderNamespace is the header key for revision's namespace.
 	RevisionHeaderNamespace = "Knative-Serving-Namespace"
+	// MirrorRevisionHeaderName is the header key for the name of the revision,
+	// in the same namespace, to mirror the request to.
+	MirrorRevisionHeaderName = "Knative-Serving-Mirror-Revision"
+	// MirrorPercentHeaderName is the header key for the percentage of the
+	// requests to mirror to the revision in MirrorRevisionHeaderName.
+	MirrorPercentHeaderName = "Knative-Serving-Mirror-Percent"
+	// HintHeaderName is the response header key carrying the address the
+	// activator proxied the request to, whether the request was buffered
+	// through a cold start and how long it waited for the capacity,
+	// e.g. `dest=10.0.0.1:8012;cold-start=true;queueing-delay-ms=1500`.
+	HintHeaderName = "Knative-Serving-Activator-Hint"
+	// PortHeaderName is the header key for the name of the user container
+	// port the queue-proxy should route the request to.
+	PortHeaderName = "Knative-Serving-Port"
+	// HeaderRulesHeaderName is the header key for the JSON encoded
+	// serving.HeaderRules of the Route the queue-proxy should apply.
+	HeaderRulesHeaderName = "Knative-Serving-Header-Rules"
 )
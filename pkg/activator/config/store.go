@@ -18,17 +18,26 @@ package config
 
 import (
 	"context"
+	"crypto/tls"
+	"net"
 	"net/http"
 
+	network "knative.dev/networking/pkg"
 	"knative.dev/pkg/configmap"
 	tracingconfig "knative.dev/pkg/tracing/config"
+	asconfig "knative.dev/serving/pkg/autoscaler/config"
+	"knative.dev/serving/pkg/autoscaler/config/autoscalerconfig"
+	"knative.dev/serving/pkg/networking"
+	"knative.dev/serving/pkg/tracing"
 )
 
 type cfgKey struct{}
 
 // Config is the configuration for the activator.
 type Config struct {
-	Tracing *tracingconfig.Config
+	Tracing    *tracing.Config
+	Networking *networking.Config
+	Autoscaler *autoscalerconfig.Config
 }
 
 // FromContext obtains a Config injected into the passed context.
@@ -53,7 +62,9 @@ func NewStore(logger configmap.Logger, onAfterStore ...func(name string, value i
 			"activator",
 			logger,
 			configmap.Constructors{
-				tracingconfig.ConfigName: tracingconfig.NewTracingConfigFromConfigMap,
+				tracingconfig.ConfigName: tracing.NewConfigFromConfigMap,
+				network.ConfigName:       networking.NewConfigFromConfigMap,
+				asconfig.ConfigName:      asconfig.NewConfigFromConfigMap,
 			},
 			onAfterStore...,
 		),
@@ -68,7 +79,31 @@ func (s *Store) ToContext(ctx context.Context) context.Context {
 // Load creates a Config for this store.
 func (s *Store) Load() *Config {
 	return &Config{
-		Tracing: s.UntypedLoad(tracingconfig.ConfigName).(*tracingconfig.Config).DeepCopy(),
+		Tracing:    s.UntypedLoad(tracingconfig.ConfigName).(*tracing.Config).DeepCopy(),
+		Networking: s.UntypedLoad(network.ConfigName).(*networking.Config).DeepCopy(),
+		Autoscaler: s.UntypedLoad(asconfig.ConfigName).(*autoscalerconfig.Config).DeepCopy(),
+	}
+}
+
+// ProxyProtocolTrusted returns whether the activator's listeners should read
+// the PROXY protocol header off the connections from addr, per the current
+// network config.
+func (s *Store) ProxyProtocolTrusted(addr net.Addr) bool {
+	return s.UntypedLoad(network.ConfigName).(*networking.Config).ProxyProtocolTrusted(addr)
+}
+
+// TLSConfig returns a TLS configuration serving cert, whose version and cipher
+// suite constraints track the current network config.
+func (s *Store) TLSConfig(cert tls.Certificate) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cfg := s.Load().Networking.DataplaneTLSConfig()
+			cfg.Certificates = []tls.Certificate{cert}
+			// The per connection config replaces the server's, so enable HTTP/2 here.
+			cfg.NextProtos = []string{"h2", "http/1.1"}
+			return cfg, nil
+		},
 	}
 }
 
@@ -21,7 +21,9 @@ limitations under the License.
 package config
 
 import (
-	tracingconfig "knative.dev/pkg/tracing/config"
+	autoscalerconfig "knative.dev/serving/pkg/autoscaler/config/autoscalerconfig"
+	networking "knative.dev/serving/pkg/networking"
+	tracing "knative.dev/serving/pkg/tracing"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -29,7 +31,17 @@ func (in *Config) DeepCopyInto(out *Config) {
 	*out = *in
 	if in.Tracing != nil {
 		in, out := &in.Tracing, &out.Tracing
-		*out = new(tracingconfig.Config)
+		*out = new(tracing.Config)
+		**out = **in
+	}
+	if in.Networking != nil {
+		in, out := &in.Networking, &out.Networking
+		*out = new(networking.Config)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Autoscaler != nil {
+		in, out := &in.Autoscaler, &out.Autoscaler
+		*out = new(autoscalerconfig.Config)
 		**out = **in
 	}
 	return
@@ -0,0 +1,78 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"go.uber.org/atomic"
+)
+
+// errBufferBudgetExhausted is returned when the body of a request does not
+// fit in the BufferBudget.
+var errBufferBudgetExhausted = errors.New("activator request buffer budget exhausted")
+
+// BufferBudget bounds the bytes of the request bodies the activator holds
+// in memory across all the requests, to retry or to mirror them. The rest
+// of the request bodies are never buffered: they wait in the client's
+// connection until a backend is assigned and are then streamed through.
+type BufferBudget struct {
+	max  int64
+	used atomic.Int64
+}
+
+// NewBufferBudget returns a budget of max bytes. Non-positive max means
+// no limit.
+func NewBufferBudget(max int64) *BufferBudget {
+	return &BufferBudget{max: max}
+}
+
+// TryAcquire reserves n bytes of the budget, returning false if that
+// would exceed it.
+func (b *BufferBudget) TryAcquire(n int64) bool {
+	if b.max <= 0 {
+		b.used.Add(n)
+		return true
+	}
+	for {
+		used := b.used.Load()
+		if used+n > b.max {
+			return false
+		}
+		if b.used.CAS(used, used+n) {
+			return true
+		}
+	}
+}
+
+// Release returns n bytes to the budget.
+func (b *BufferBudget) Release(n int64) {
+	b.used.Sub(n)
+}
+
+// Used returns the number of the bytes currently reserved.
+func (b *BufferBudget) Used() int64 {
+	return b.used.Load()
+}
+
+// rejectBufferBudgetExhausted rejects the request whose body does not fit in
+// the budget with a 503, for it to spill over to the other activators.
+func rejectBufferBudgetExhausted(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", retryAfterSeconds)
+	http.Error(w, errBufferBudgetExhausted.Error(), http.StatusServiceUnavailable)
+}
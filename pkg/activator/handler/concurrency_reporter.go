@@ -23,18 +23,21 @@ import (
 	"sync"
 	"time"
 
+	"go.opencensus.io/stats"
 	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/types"
 	network "knative.dev/networking/pkg"
 	"knative.dev/pkg/logging"
 	pkgmetrics "knative.dev/pkg/metrics"
 	"knative.dev/serving/pkg/activator"
+	activatorconfig "knative.dev/serving/pkg/activator/config"
 	"knative.dev/serving/pkg/activator/util"
 	"knative.dev/serving/pkg/apis/serving"
 	asmetrics "knative.dev/serving/pkg/autoscaler/metrics"
 	revisioninformer "knative.dev/serving/pkg/client/injection/informers/serving/v1/revision"
 	servinglisters "knative.dev/serving/pkg/client/listers/serving/v1"
 	"knative.dev/serving/pkg/metrics"
+	"knative.dev/serving/pkg/networking"
 )
 
 const reportInterval = time.Second
@@ -62,6 +65,10 @@ type ConcurrencyReporter struct {
 	mux sync.RWMutex
 	// This map holds the concurrency and request count accounting across revisions.
 	stats map[types.NamespacedName]*revisionStats
+
+	llMux sync.Mutex
+	// This map holds the number of the open long-lived connections per revision.
+	longLived map[types.NamespacedName]int
 }
 
 // NewConcurrencyReporter creates a ConcurrencyReporter which listens to incoming
@@ -73,7 +80,8 @@ func NewConcurrencyReporter(ctx context.Context, podName string, statCh chan []a
 		statCh:  statCh,
 		rl:      revisioninformer.Get(ctx).Lister(),
 
-		stats: make(map[types.NamespacedName]*revisionStats),
+		stats:     make(map[types.NamespacedName]*revisionStats),
+		longLived: make(map[types.NamespacedName]int),
 	}
 }
 
@@ -86,6 +94,21 @@ func (cr *ConcurrencyReporter) handleEvent(event network.ReqEvent) {
 	stat.stats.HandleEvent(event)
 }
 
+// poke makes sure the revision is tracked, without accounting for a request.
+// This still triggers the scale-from-0 for the revisions we didn't see yet.
+func (cr *ConcurrencyReporter) poke(key types.NamespacedName) {
+	if _, msg := cr.getOrCreateStat(network.ReqEvent{Key: key, Time: time.Now()}); msg != nil {
+		cr.statCh <- []asmetrics.StatMessage{*msg}
+	}
+}
+
+// trackLongLived updates the number of open long-lived connections to the revision by delta.
+func (cr *ConcurrencyReporter) trackLongLived(key types.NamespacedName, delta int) {
+	cr.llMux.Lock()
+	defer cr.llMux.Unlock()
+	cr.longLived[key] += delta
+}
+
 // getOrCreateStat gets a stat from the state if present.
 // If absent it creates a new one and returns it, potentially returning a StatMessage too
 // to trigger an immediate scale-from-0.
@@ -165,7 +188,9 @@ func (cr *ConcurrencyReporter) computeReport(now time.Time) (msgs []asmetrics.St
 		// always a concurrency of 1 and the actual concurrency reported over
 		// the reporting period might be < 1.
 		adjustedConcurrency := math.Max(report.AverageConcurrency-firstAdj, 0)
-		adjustedCount := report.RequestCount - firstAdj
+		// The first request might have been a long-lived connection, which
+		// is not accounted for.
+		adjustedCount := math.Max(report.RequestCount-firstAdj, 0)
 		msgs = append(msgs, asmetrics.StatMessage{
 			Key: key,
 			Stat: asmetrics.Stat{
@@ -179,7 +204,21 @@ func (cr *ConcurrencyReporter) computeReport(now time.Time) (msgs []asmetrics.St
 	return msgs, toDelete
 }
 
-func (cr *ConcurrencyReporter) reportToMetricsBackend(key types.NamespacedName, concurrency float64) {
+// reportLongLived reports the number of the open long-lived connections
+// per revision to the metrics backend.
+func (cr *ConcurrencyReporter) reportLongLived() {
+	cr.llMux.Lock()
+	defer cr.llMux.Unlock()
+	for key, n := range cr.longLived {
+		cr.reportToMetricsBackend(key, longLivedConnectionsM.M(int64(n)))
+		// We've reported the last connection closing, so stop tracking.
+		if n == 0 {
+			delete(cr.longLived, key)
+		}
+	}
+}
+
+func (cr *ConcurrencyReporter) reportToMetricsBackend(key types.NamespacedName, ms ...stats.Measurement) {
 	ns := key.Namespace
 	revName := key.Name
 	revision, err := cr.rl.Revisions(ns).Get(revName)
@@ -191,7 +230,7 @@ func (cr *ConcurrencyReporter) reportToMetricsBackend(key types.NamespacedName,
 	serviceName := revision.Labels[serving.ServiceLabelKey]
 
 	reporterCtx, _ := metrics.PodRevisionContext(cr.podName, activator.Name, ns, serviceName, configurationName, revName)
-	pkgmetrics.Record(reporterCtx, requestConcurrencyM.M(concurrency))
+	pkgmetrics.RecordBatch(reporterCtx, ms...)
 }
 
 // Run runs until stopCh is closed and processes events on all incoming channels.
@@ -207,8 +246,9 @@ func (cr *ConcurrencyReporter) run(stopCh <-chan struct{}, reportCh <-chan time.
 		case now := <-reportCh:
 			msgs := cr.report(now)
 			for _, msg := range msgs {
-				cr.reportToMetricsBackend(msg.Key, msg.Stat.AverageConcurrentRequests)
+				cr.reportToMetricsBackend(msg.Key, requestConcurrencyM.M(msg.Stat.AverageConcurrentRequests))
 			}
+			cr.reportLongLived()
 			if len(msgs) > 0 {
 				cr.statCh <- msgs
 			}
@@ -223,6 +263,16 @@ func (cr *ConcurrencyReporter) run(stopCh <-chan struct{}, reportCh <-chan time.
 func (cr *ConcurrencyReporter) Handler(next http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		revisionKey := util.RevIDFrom(r.Context())
+		if networking.IsLongLivedRequest(r) {
+			cr.trackLongLived(revisionKey, 1)
+			defer cr.trackLongLived(revisionKey, -1)
+			if !activatorconfig.FromContext(r.Context()).Autoscaler.CountLongLivedConnections {
+				cr.poke(revisionKey)
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
 		cr.handleEvent(network.ReqEvent{Key: revisionKey, Type: network.ReqIn, Time: time.Now()})
 		defer func() {
 			cr.handleEvent(network.ReqEvent{Key: revisionKey, Type: network.ReqOut, Time: time.Now()})
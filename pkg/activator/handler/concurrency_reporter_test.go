@@ -28,18 +28,23 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"go.opencensus.io/resource"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	network "knative.dev/networking/pkg"
+	"knative.dev/pkg/logging"
 	"knative.dev/pkg/metrics/metricskey"
 	"knative.dev/pkg/metrics/metricstest"
 	_ "knative.dev/pkg/metrics/testing"
 	rtesting "knative.dev/pkg/reconciler/testing"
 	"knative.dev/serving/pkg/activator/util"
 	v1 "knative.dev/serving/pkg/apis/serving/v1"
+	asconfig "knative.dev/serving/pkg/autoscaler/config"
 	asmetrics "knative.dev/serving/pkg/autoscaler/metrics"
 	fakeservingclient "knative.dev/serving/pkg/client/injection/client/fake"
 	fakerevisioninformer "knative.dev/serving/pkg/client/injection/informers/serving/v1/revision/fake"
+	"knative.dev/serving/pkg/networking"
 )
 
 const (
@@ -461,6 +466,86 @@ func TestConcurrencyReporterHandler(t *testing.T) {
 	}
 }
 
+func TestConcurrencyReporterHandlerLongLived(t *testing.T) {
+	for _, counted := range []bool{true, false} {
+		t.Run(fmt.Sprint("counted=", counted), func(t *testing.T) {
+			reset()
+			cr, ctx, cancel := newTestReporter(t)
+			defer cancel()
+
+			configStore := setupConfigStore(t, logging.FromContext(ctx))
+			configStore.OnConfigChanged(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: asconfig.ConfigName},
+				Data: map[string]string{
+					"count-long-lived-connections": strconv.FormatBool(counted),
+				},
+			})
+			rCtx := util.WithRevID(configStore.ToContext(context.Background()), rev1)
+
+			closeCh := make(chan struct{})
+			handler := cr.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if networking.IsLongLivedRequest(r) {
+					<-closeCh
+				}
+			}))
+
+			// A regular request, which scales from 0.
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://example.com", nil).WithContext(rCtx))
+			<-cr.statCh
+
+			// And a websocket, which is kept open for a while.
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil).WithContext(rCtx)
+			req.Header.Set("Connection", "Upgrade")
+			req.Header.Set("Upgrade", "websocket")
+			doneCh := make(chan struct{})
+			go func() {
+				defer close(doneCh)
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			}()
+
+			if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+				cr.llMux.Lock()
+				defer cr.llMux.Unlock()
+				return cr.longLived[rev1] == 1, nil
+			}); err != nil {
+				t.Fatal("The long-lived connection was never tracked:", err)
+			}
+			cr.reportLongLived()
+			metricstest.AssertMetric(t, metricstest.IntMetric("long_lived_connections", 1, map[string]string{
+				metricskey.PodName:       activatorPodName,
+				metricskey.ContainerName: "activator",
+			}).WithResource(&resource.Resource{
+				Type: "knative_revision",
+				Labels: map[string]string{
+					metricskey.LabelRevisionName:      rev1.Name,
+					metricskey.LabelNamespaceName:     rev1.Namespace,
+					metricskey.LabelServiceName:       "service-" + rev1.Name,
+					metricskey.LabelConfigurationName: "config-" + rev1.Name,
+				},
+			}))
+
+			close(closeCh)
+			<-doneCh
+
+			// The first request is discounted via the from 0 stat.
+			wantCount := 0.
+			if counted {
+				wantCount = 1
+			}
+			msgs := cr.report(time.Now())
+			if len(msgs) != 1 || msgs[0].Stat.RequestCount != wantCount {
+				t.Errorf("Report = %#v, want RequestCount = %v", msgs, wantCount)
+			}
+
+			// The closed connection is reported once and then forgotten.
+			cr.reportLongLived()
+			if _, ok := cr.longLived[rev1]; ok {
+				t.Error("The closed long-lived connection is still tracked")
+			}
+		})
+	}
+}
+
 func TestMetricsReported(t *testing.T) {
 	reset()
 	cr, ctx, cancel := newTestReporter(t)
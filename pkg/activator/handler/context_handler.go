@@ -29,8 +29,11 @@ import (
 	"knative.dev/pkg/logging/logkey"
 	"knative.dev/serving/pkg/activator"
 	"knative.dev/serving/pkg/activator/util"
+	"knative.dev/serving/pkg/apis/serving"
+	v1 "knative.dev/serving/pkg/apis/serving/v1"
 	revisioninformer "knative.dev/serving/pkg/client/injection/informers/serving/v1/revision"
 	servinglisters "knative.dev/serving/pkg/client/listers/serving/v1"
+	pkghttp "knative.dev/serving/pkg/http"
 )
 
 // NewContextHandler creates a handler that extracts the necessary context from the request
@@ -63,14 +66,37 @@ func (h *contextHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if max := revision.MaxRequestBodySize(); max > 0 && !pkghttp.LimitRequestBody(w, r, max) {
+		return
+	}
+
 	ctx := r.Context()
 	ctx = logging.WithLogger(ctx, logger)
 	ctx = util.WithRevision(ctx, revision)
 	ctx = util.WithRevID(ctx, revID)
+	if key := sessionKey(revision, r); key != "" {
+		ctx = util.WithSessionKey(ctx, key)
+	}
+	// Filled in by the activation handler for the request logs.
+	ctx = util.WithProxyStats(ctx, &util.ProxyStats{})
 
 	h.nextHandler.ServeHTTP(w, r.WithContext(ctx))
 }
 
+// sessionKey returns the session affinity key of the request,
+// if the revision has session affinity configured.
+func sessionKey(rev *v1.Revision, r *http.Request) string {
+	if header := rev.Annotations[serving.SessionAffinityHeaderAnnotationKey]; header != "" {
+		return r.Header.Get(header)
+	}
+	if cookie := rev.Annotations[serving.SessionAffinityCookieAnnotationKey]; cookie != "" {
+		if c, err := r.Cookie(cookie); err == nil {
+			return c.Value
+		}
+	}
+	return ""
+}
+
 func sendError(err error, w http.ResponseWriter) {
 	msg := fmt.Sprint("Error getting active endpoint: ", err)
 	if k8serrors.IsNotFound(err) {
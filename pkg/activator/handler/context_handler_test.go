@@ -28,6 +28,7 @@ import (
 	rtesting "knative.dev/pkg/reconciler/testing"
 	"knative.dev/serving/pkg/activator"
 	"knative.dev/serving/pkg/activator/util"
+	"knative.dev/serving/pkg/apis/serving"
 )
 
 func TestContextHandler(t *testing.T) {
@@ -84,6 +85,83 @@ func TestContextHandlerError(t *testing.T) {
 	}
 }
 
+func TestContextHandlerMaxRequestBodySize(t *testing.T) {
+	ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
+	defer cancel()
+	revision := revision(testNamespace, testRevName)
+	revision.Annotations = map[string]string{serving.MaxRequestBodySizeAnnotationKey: "4"}
+	revisionInformer(ctx, revision)
+
+	called := false
+	handler := NewContextHandler(ctx, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "http://example.com", bytes.NewBufferString("too large"))
+	req.Header.Set(activator.RevisionHeaderNamespace, testNamespace)
+	req.Header.Set(activator.RevisionHeaderName, testRevName)
+	handler.ServeHTTP(resp, req)
+
+	if got, want := resp.Code, http.StatusRequestEntityTooLarge; got != want {
+		t.Errorf("StatusCode = %d, want %d", got, want)
+	}
+	if called {
+		t.Error("The request was passed on")
+	}
+}
+
+func TestSessionKey(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		header      string
+		cookie      string
+		want        string
+	}{{
+		name:   "no session affinity",
+		header: "abc",
+		cookie: "def",
+	}, {
+		name: "header",
+		annotations: map[string]string{
+			serving.SessionAffinityHeaderAnnotationKey: "X-Session",
+		},
+		header: "abc",
+		cookie: "def",
+		want:   "abc",
+	}, {
+		name: "cookie",
+		annotations: map[string]string{
+			serving.SessionAffinityCookieAnnotationKey: "session",
+		},
+		header: "abc",
+		cookie: "def",
+		want:   "def",
+	}, {
+		name: "missing cookie",
+		annotations: map[string]string{
+			serving.SessionAffinityCookieAnnotationKey: "session",
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rev := revision(testNamespace, testRevName)
+			rev.Annotations = test.annotations
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			if test.header != "" {
+				req.Header.Set("X-Session", test.header)
+			}
+			if test.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "session", Value: test.cookie})
+			}
+			if got := sessionKey(rev, req); got != test.want {
+				t.Errorf("sessionKey() = %q, want: %q", got, test.want)
+			}
+		})
+	}
+}
+
 func BenchmarkContextHandler(b *testing.B) {
 	tests := []struct {
 		label        string
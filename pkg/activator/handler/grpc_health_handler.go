@@ -0,0 +1,74 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"knative.dev/serving/pkg/activator/util"
+	v1 "knative.dev/serving/pkg/apis/serving/v1"
+)
+
+const (
+	// grpcHealthCheckPath is the path of the Check method of the
+	// standard gRPC health checking protocol (grpc.health.v1.Health).
+	grpcHealthCheckPath = "/grpc.health.v1.Health/Check"
+
+	grpcContentType = "application/grpc"
+)
+
+// grpcServingResponse is the length prefixed gRPC message carrying
+// the HealthCheckResponse{status: SERVING} protobuf.
+var grpcServingResponse = []byte{
+	0,          // Not compressed.
+	0, 0, 0, 2, // Message length.
+	0x08, 0x01, // Field 1 (status), varint 1 (SERVING).
+}
+
+// GRPCHealthHandler answers the gRPC health checks of the revisions that
+// are being activated, so that the health checking gRPC clients don't mark
+// them dead while they're scaling from zero. Once the revision is active,
+// the health checks are proxied to it.
+type GRPCHealthHandler struct {
+	NextHandler http.Handler
+}
+
+func (h *GRPCHealthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !isGRPCHealthCheck(r) || util.RevisionFrom(r.Context()).Status.GetCondition(v1.RevisionConditionActive).IsTrue() {
+		h.NextHandler.ServeHTTP(w, r)
+		return
+	}
+
+	// The requested service is irrelevant, since we report on behalf of the
+	// revision as a whole.
+	io.Copy(ioutil.Discard, r.Body)
+
+	w.Header().Set("Content-Type", grpcContentType)
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+	w.Write(grpcServingResponse)
+	w.Header().Set("Grpc-Status", "0") // OK.
+	w.Header().Set("Grpc-Message", "")
+}
+
+func isGRPCHealthCheck(r *http.Request) bool {
+	return r.ProtoMajor == 2 && r.Method == http.MethodPost && r.URL.Path == grpcHealthCheckPath &&
+		strings.HasPrefix(r.Header.Get("Content-Type"), grpcContentType)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"knative.dev/serving/pkg/activator/util"
+)
+
+func TestGRPCHealthHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		protoMajor int
+		active     bool
+		wantPassed bool
+	}{{
+		name:       "health check while activating",
+		path:       grpcHealthCheckPath,
+		protoMajor: 2,
+	}, {
+		name:       "health check when active",
+		path:       grpcHealthCheckPath,
+		protoMajor: 2,
+		active:     true,
+		wantPassed: true,
+	}, {
+		name:       "other gRPC method",
+		path:       "/helloworld.Greeter/SayHello",
+		protoMajor: 2,
+		wantPassed: true,
+	}, {
+		name:       "HTTP/1",
+		path:       grpcHealthCheckPath,
+		protoMajor: 1,
+		wantPassed: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			passed := false
+			handler := &GRPCHealthHandler{
+				NextHandler: http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+					passed = true
+				}),
+			}
+
+			rev := revision(testNamespace, testRevName)
+			if test.active {
+				rev.Status.MarkActiveTrue()
+			} else {
+				rev.Status.MarkActiveUnknown("Activating", "")
+			}
+			// The request is an empty HealthCheckRequest.
+			req := httptest.NewRequest(http.MethodPost, "http://example.com"+test.path,
+				bytes.NewReader([]byte{0, 0, 0, 0, 0}))
+			req.ProtoMajor = test.protoMajor
+			req.Header.Set("Content-Type", "application/grpc")
+			req = req.WithContext(util.WithRevision(req.Context(), rev))
+
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+
+			if passed != test.wantPassed {
+				t.Fatalf("Passed to the next handler = %v, want: %v", passed, test.wantPassed)
+			}
+			if passed {
+				return
+			}
+			res := resp.Result()
+			if got, want := res.Header.Get("Content-Type"), "application/grpc"; got != want {
+				t.Errorf("Content-Type = %q, want: %q", got, want)
+			}
+			if body, _ := ioutil.ReadAll(res.Body); !bytes.Equal(body, grpcServingResponse) {
+				t.Errorf("Body = %v, want: %v", body, grpcServingResponse)
+			}
+			if got, want := res.Trailer.Get("Grpc-Status"), "0"; got != want {
+				t.Errorf("Grpc-Status = %q, want: %q", got, want)
+			}
+		})
+	}
+}
@@ -18,9 +18,14 @@ package handler
 
 import (
 	"context"
+	"errors"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"go.opencensus.io/plugin/ochttp"
 	"go.opencensus.io/trace"
@@ -34,9 +39,19 @@ import (
 	"knative.dev/serving/pkg/activator"
 	activatorconfig "knative.dev/serving/pkg/activator/config"
 	"knative.dev/serving/pkg/activator/util"
+	"knative.dev/serving/pkg/networking"
 	"knative.dev/serving/pkg/queue"
 )
 
+// retryAfterSeconds is the Retry-After value sent with the requests rejected
+// because too many of them are waiting for the revision's capacity.
+const retryAfterSeconds = "1"
+
+// errDestinationFailed is reported to the Throttler, when the request failed
+// at the destination, to be accounted by the destination's circuit breaker.
+// The response has already been written by then.
+var errDestinationFailed = errors.New("request to the destination failed")
+
 // Throttler is the interface that Handler calls to Try to proxy the user request.
 type Throttler interface {
 	Try(context.Context, func(string) error) error
@@ -49,10 +64,12 @@ type activationHandler struct {
 	tracingTransport http.RoundTripper
 	throttler        Throttler
 	bufferPool       httputil.BufferPool
+	budget           *BufferBudget
 }
 
 // New constructs a new http.Handler that deals with revision activation.
-func New(ctx context.Context, t Throttler, transport http.RoundTripper) http.Handler {
+// The request bodies buffered to retry them are accounted against the budget.
+func New(ctx context.Context, t Throttler, transport http.RoundTripper, budget *BufferBudget) http.Handler {
 	return &activationHandler{
 		transport: transport,
 		tracingTransport: &ochttp.Transport{
@@ -61,6 +78,7 @@ func New(ctx context.Context, t Throttler, transport http.RoundTripper) http.Han
 		},
 		throttler:  t,
 		bufferPool: network.NewBufferPool(),
+		budget:     budget,
 	}
 }
 
@@ -72,22 +90,40 @@ func (a *activationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if tracingEnabled {
 		tryContext, trySpan = trace.StartSpan(r.Context(), "throttler_try")
 	}
+	if isHighPriority(r, activatorconfig.FromContext(r.Context()).Networking) {
+		tryContext = queue.WithHighPriority(tryContext)
+	}
 
+	stats := util.ProxyStatsFrom(r.Context())
+	tryStart := time.Now()
 	if err := a.throttler.Try(tryContext, func(dest string) error {
 		trySpan.End()
+		proxyStart := time.Now()
+		if stats != nil {
+			stats.Dest = dest
+			stats.QueueingDelay = proxyStart.Sub(tryStart)
+			defer func() {
+				stats.UpstreamLatency = time.Since(proxyStart)
+			}()
+		}
+		if nc := activatorconfig.FromContext(r.Context()).Networking; nc != nil && nc.ActivatorResponseHints {
+			// Set ahead of proxying, the proxy merges the response headers into these.
+			w.Header().Set(activator.HintHeaderName, hint(dest, stats, proxyStart.Sub(tryStart)))
+		}
 
 		proxyCtx, proxySpan := r.Context(), (*trace.Span)(nil)
 		if tracingEnabled {
 			proxyCtx, proxySpan = trace.StartSpan(r.Context(), "activator_proxy")
 		}
-		a.proxyRequest(logger, w, r.WithContext(proxyCtx), &url.URL{
-			Scheme: "http",
-			Host:   dest,
-		}, tracingEnabled)
+		failed := a.proxyRequest(logger, w, r.WithContext(proxyCtx), target(dest,
+			activatorconfig.FromContext(r.Context()).Networking), tracingEnabled)
 		proxySpan.End()
 
+		if failed {
+			return errDestinationFailed
+		}
 		return nil
-	}); err != nil {
+	}); err != nil && err != errDestinationFailed {
 		// Set error on our capacity waiting span and end it.
 		trySpan.Annotate([]trace.Attribute{trace.StringAttribute("activator.throttler.error", err.Error())}, "ThrottlerTry")
 		trySpan.End()
@@ -97,26 +133,85 @@ func (a *activationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		switch err {
 		case context.DeadlineExceeded, queue.ErrRequestQueueFull:
 			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		case queue.ErrCapacityExceeded, activator.ErrRevisionNotAssigned:
+			// Fail fast, for the upstream load balancer to fail over.
+			w.Header().Set("Retry-After", retryAfterSeconds)
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		case queue.ErrRequestQueueDepthExceeded, queue.ErrRequestQueueTimeout:
+			// Shed the load, rather than holding on to the connections.
+			w.Header().Set("Retry-After", retryAfterSeconds)
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
 		default:
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 	}
 }
 
-func (a *activationHandler) proxyRequest(logger *zap.SugaredLogger, w http.ResponseWriter, r *http.Request, target *url.URL, tracingEnabled bool) {
+// isHighPriority returns true if the request is marked with the configured
+// priority header as high priority.
+func isHighPriority(r *http.Request, nc *networking.Config) bool {
+	return nc != nil && nc.ActivatorPriorityHeader != "" &&
+		strings.EqualFold(r.Header.Get(nc.ActivatorPriorityHeader), "high")
+}
+
+// hint returns the value of the activator.HintHeaderName header.
+func hint(dest string, stats *util.ProxyStats, queueingDelay time.Duration) string {
+	coldStart := stats != nil && stats.ColdStart
+	return "dest=" + dest + ";cold-start=" + strconv.FormatBool(coldStart) +
+		";queueing-delay-ms=" + strconv.FormatInt(queueingDelay.Milliseconds(), 10)
+}
+
+// target returns the URL of the backend `dest` to proxy the request to.
+// With the backend TLS enabled the request is sent to the queue-proxy's
+// TLS port instead.
+func target(dest string, nc *networking.Config) *url.URL {
+	if nc == nil || !nc.ActivatorBackendTLS {
+		return &url.URL{Scheme: "http", Host: dest}
+	}
+	host, _, err := net.SplitHostPort(dest)
+	if err != nil {
+		// No port in the destination.
+		host = dest
+	}
+	return &url.URL{
+		Scheme: "https",
+		Host:   net.JoinHostPort(host, strconv.Itoa(networking.BackendHTTPSPort)),
+	}
+}
+
+// proxyRequest proxies the request to the target and returns whether it failed
+// at the target, i.e. the target could not be reached or responded with a 5xx.
+func (a *activationHandler) proxyRequest(logger *zap.SugaredLogger, w http.ResponseWriter, r *http.Request, target *url.URL, tracingEnabled bool) bool {
 	network.RewriteHostIn(r)
 	r.Header.Set(network.ProxyHeaderName, activator.Name)
 
 	// Set up the reverse proxy.
 	proxy := httputil.NewSingleHostReverseProxy(target)
 	proxy.BufferPool = a.bufferPool
-	proxy.Transport = a.transport
+	transport := a.transport
 	if tracingEnabled {
-		proxy.Transport = a.tracingTransport
+		transport = a.tracingTransport
 	}
+	proxy.Transport = newRetryTransport(transport, activatorconfig.FromContext(r.Context()).Networking, a.budget)
 	proxy.FlushInterval = network.FlushInterval
-	proxy.ErrorHandler = pkgnet.ErrorHandler(logger)
+	var failed bool
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		failed = resp.StatusCode >= http.StatusInternalServerError
+		return nil
+	}
+	errorHandler := pkgnet.ErrorHandler(logger)
+	proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		if errors.Is(err, errBufferBudgetExhausted) {
+			// Never reached the target.
+			rejectBufferBudgetExhausted(w)
+			return
+		}
+		// The requests canceled by the clients are not the target's fault.
+		failed = req.Context().Err() == nil
+		errorHandler(w, req, err)
+	}
 	util.SetupHeaderPruning(proxy)
 
 	proxy.ServeHTTP(w, r)
+	return failed
 }
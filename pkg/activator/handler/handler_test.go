@@ -25,6 +25,7 @@ import (
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -44,6 +45,8 @@ import (
 	"knative.dev/serving/pkg/activator/util"
 	"knative.dev/serving/pkg/apis/serving"
 	v1 "knative.dev/serving/pkg/apis/serving/v1"
+	asconfig "knative.dev/serving/pkg/autoscaler/config"
+	"knative.dev/serving/pkg/networking"
 	"knative.dev/serving/pkg/queue"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -73,14 +76,15 @@ func (ft fakeThrottler) Try(ctx context.Context, f func(string) error) error {
 
 func TestActivationHandler(t *testing.T) {
 	tests := []struct {
-		name      string
-		wantBody  string
-		wantCode  int
-		wantErr   error
-		probeErr  error
-		probeCode int
-		probeResp []string
-		throttler Throttler
+		name           string
+		wantBody       string
+		wantCode       int
+		wantRetryAfter string
+		wantErr        error
+		probeErr       error
+		probeCode      int
+		probeResp      []string
+		throttler      Throttler
 	}{{
 		name:      "active endpoint",
 		wantBody:  wantBody,
@@ -100,11 +104,39 @@ func TestActivationHandler(t *testing.T) {
 		wantErr:   nil,
 		throttler: fakeThrottler{err: context.DeadlineExceeded},
 	}, {
-		name:      "overflow",
+		name:      "overload",
 		wantBody:  "pending request queue full\n",
 		wantCode:  http.StatusServiceUnavailable,
 		wantErr:   nil,
 		throttler: fakeThrottler{err: queue.ErrRequestQueueFull},
+	}, {
+		name:           "queue depth exceeded",
+		wantBody:       "pending request queue depth exceeded\n",
+		wantCode:       http.StatusTooManyRequests,
+		wantRetryAfter: "1",
+		wantErr:        nil,
+		throttler:      fakeThrottler{err: queue.ErrRequestQueueDepthExceeded},
+	}, {
+		name:           "queue timeout",
+		wantBody:       "pending request queue timeout\n",
+		wantCode:       http.StatusTooManyRequests,
+		wantRetryAfter: "1",
+		wantErr:        nil,
+		throttler:      fakeThrottler{err: queue.ErrRequestQueueTimeout},
+	}, {
+		name:           "capacity exceeded",
+		wantBody:       "capacity exceeded\n",
+		wantCode:       http.StatusServiceUnavailable,
+		wantRetryAfter: "1",
+		wantErr:        nil,
+		throttler:      fakeThrottler{err: queue.ErrCapacityExceeded},
+	}, {
+		name:           "revision not assigned",
+		wantBody:       "revision is not assigned to this activator\n",
+		wantCode:       http.StatusServiceUnavailable,
+		wantRetryAfter: "1",
+		wantErr:        nil,
+		throttler:      fakeThrottler{err: activator.ErrRevisionNotAssigned},
 	}}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -129,7 +161,7 @@ func TestActivationHandler(t *testing.T) {
 
 			ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
 			defer cancel()
-			handler := New(ctx, test.throttler, rt)
+			handler := New(ctx, test.throttler, rt, NewBufferBudget(0))
 
 			resp := httptest.NewRecorder()
 			req := httptest.NewRequest(http.MethodPost, "http://example.com", nil)
@@ -145,6 +177,9 @@ func TestActivationHandler(t *testing.T) {
 			if resp.Code != test.wantCode {
 				t.Fatalf("Unexpected response status. Want %d, got %d", test.wantCode, resp.Code)
 			}
+			if got := resp.Header().Get("Retry-After"); got != test.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want: %q", got, test.wantRetryAfter)
+			}
 
 			gotBody, err := ioutil.ReadAll(resp.Body)
 			if err != nil {
@@ -168,7 +203,7 @@ func TestActivationHandlerProxyHeader(t *testing.T) {
 	ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
 	defer cancel()
 
-	handler := New(ctx, fakeThrottler{}, rt)
+	handler := New(ctx, fakeThrottler{}, rt, NewBufferBudget(0))
 
 	writer := httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodPost, "http://example.com", nil)
@@ -190,6 +225,253 @@ func TestActivationHandlerProxyHeader(t *testing.T) {
 	}
 }
 
+func TestActivationHandlerProxyStats(t *testing.T) {
+	rt := pkgnet.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return httptest.NewRecorder().Result(), nil
+	})
+
+	ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
+	defer cancel()
+
+	handler := New(ctx, fakeThrottler{}, rt, NewBufferBudget(0))
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	configStore := setupConfigStore(t, logging.FromContext(ctx))
+	stats := &util.ProxyStats{}
+	ctx = configStore.ToContext(req.Context())
+	ctx = util.WithRevID(ctx, types.NamespacedName{Namespace: testNamespace, Name: testRevName})
+	ctx = util.WithProxyStats(ctx, stats)
+
+	handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+
+	if got, want := stats.Dest, "10.10.10.10:1234"; got != want {
+		t.Errorf("Dest = %q, want: %q", got, want)
+	}
+	if stats.QueueingDelay < 0 || stats.UpstreamLatency <= 0 {
+		t.Errorf("QueueingDelay = %v, UpstreamLatency = %v, want them measured", stats.QueueingDelay, stats.UpstreamLatency)
+	}
+}
+
+func TestActivationHandlerResponseHints(t *testing.T) {
+	rt := pkgnet.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return httptest.NewRecorder().Result(), nil
+	})
+
+	ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
+	defer cancel()
+
+	handler := New(ctx, fakeThrottler{}, rt, NewBufferBudget(0))
+
+	configStore := setupConfigStore(t, logging.FromContext(ctx))
+	if got := sendRequest(testNamespace, testRevName, handler, configStore).Header().Get(activator.HintHeaderName); got != "" {
+		t.Errorf("Header %q = %q without the hints enabled, want: empty", activator.HintHeaderName, got)
+	}
+
+	cm := ConfigMapFromTestFile(t, network.ConfigName)
+	cm.Data[networking.ActivatorResponseHintsKey] = "true"
+	configStore.OnConfigChanged(cm)
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	ctx = configStore.ToContext(req.Context())
+	ctx = util.WithRevID(ctx, types.NamespacedName{Namespace: testNamespace, Name: testRevName})
+	ctx = util.WithProxyStats(ctx, &util.ProxyStats{ColdStart: true})
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req.WithContext(ctx))
+
+	got := resp.Header().Get(activator.HintHeaderName)
+	if want := "dest=10.10.10.10:1234;cold-start=true;queueing-delay-ms="; !strings.HasPrefix(got, want) {
+		t.Errorf("Header %q = %q, want prefix: %q", activator.HintHeaderName, got, want)
+	}
+}
+
+func TestActivationHandlerBufferBudgetExhausted(t *testing.T) {
+	tries := 0
+	rt := pkgnet.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		tries++
+		return httptest.NewRecorder().Result(), nil
+	})
+
+	ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
+	defer cancel()
+
+	budget := NewBufferBudget(4)
+	handler := New(ctx, fakeThrottler{}, rt, budget)
+
+	configStore := setupConfigStore(t, logging.FromContext(ctx))
+	cm := ConfigMapFromTestFile(t, network.ConfigName)
+	cm.Data[networking.ActivatorRetriesKey] = "1"
+	configStore.OnConfigChanged(cm)
+
+	req := httptest.NewRequest(http.MethodPut, "http://example.com", strings.NewReader("hello"))
+	ctx = configStore.ToContext(req.Context())
+	ctx = util.WithRevID(ctx, types.NamespacedName{Namespace: testNamespace, Name: testRevName})
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req.WithContext(ctx))
+
+	// The body to buffer for the retries does not fit in the budget, so the
+	// request spills over.
+	if got, want := resp.Code, http.StatusServiceUnavailable; got != want {
+		t.Errorf("StatusCode = %d, want: %d", got, want)
+	}
+	if tries != 0 {
+		t.Errorf("#tries = %d, want: 0", tries)
+	}
+	if got := budget.Used(); got != 0 {
+		t.Errorf("Budget used = %d, want: 0", got)
+	}
+}
+
+// reportingThrottler records the error the proxying reports for the dest.
+type reportingThrottler struct {
+	reported *error
+}
+
+func (rt reportingThrottler) Try(ctx context.Context, f func(string) error) error {
+	*rt.reported = f("10.10.10.10:1234")
+	return *rt.reported
+}
+
+func TestActivationHandlerDestinationFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     int
+		err      error
+		want     error
+		wantCode int
+	}{{
+		name:     "success",
+		code:     http.StatusOK,
+		wantCode: http.StatusOK,
+	}, {
+		name:     "client error",
+		code:     http.StatusNotFound,
+		wantCode: http.StatusNotFound,
+	}, {
+		name:     "server error",
+		code:     http.StatusServiceUnavailable,
+		want:     errDestinationFailed,
+		wantCode: http.StatusServiceUnavailable,
+	}, {
+		name:     "connection error",
+		err:      errors.New("connection refused"),
+		want:     errDestinationFailed,
+		wantCode: http.StatusBadGateway,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fakeRT := activatortest.FakeRoundTripper{
+				ExpectHost: "test-host",
+				RequestResponse: &activatortest.FakeResponse{
+					Err:  test.err,
+					Code: test.code,
+					Body: wantBody,
+				},
+			}
+
+			ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
+			defer cancel()
+			var reported error
+			handler := New(ctx, reportingThrottler{reported: &reported}, pkgnet.RoundTripperFunc(fakeRT.RT), NewBufferBudget(0))
+
+			resp := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "http://example.com", nil)
+			req.Host = "test-host"
+			ctx = setupConfigStore(t, logging.FromContext(ctx)).ToContext(ctx)
+			ctx = util.WithRevID(ctx, types.NamespacedName{Namespace: testNamespace, Name: testRevName})
+
+			handler.ServeHTTP(resp, req.WithContext(ctx))
+
+			if reported != test.want {
+				t.Errorf("Reported error = %v, want: %v", reported, test.want)
+			}
+			// The response of the destination is passed through as is.
+			if resp.Code != test.wantCode {
+				t.Errorf("Code = %d, want: %d", resp.Code, test.wantCode)
+			}
+		})
+	}
+}
+
+func TestIsHighPriority(t *testing.T) {
+	tests := []struct {
+		name   string
+		nc     *networking.Config
+		header string
+		want   bool
+	}{{
+		name:   "no config",
+		header: "high",
+	}, {
+		name:   "priorities disabled",
+		nc:     &networking.Config{},
+		header: "high",
+	}, {
+		name:   "high",
+		nc:     &networking.Config{ActivatorPriorityHeader: "X-Priority"},
+		header: "High",
+		want:   true,
+	}, {
+		name:   "low",
+		nc:     &networking.Config{ActivatorPriorityHeader: "X-Priority"},
+		header: "low",
+	}, {
+		name: "no header",
+		nc:   &networking.Config{ActivatorPriorityHeader: "X-Priority"},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			if test.header != "" {
+				req.Header.Set("X-Priority", test.header)
+			}
+			if got := isHighPriority(req, test.nc); got != test.want {
+				t.Errorf("isHighPriority() = %v, want: %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		dest string
+		nc   *networking.Config
+		want string
+	}{{
+		name: "no config",
+		dest: "10.10.10.10:8012",
+		want: "http://10.10.10.10:8012",
+	}, {
+		name: "backend tls disabled",
+		dest: "10.10.10.10:8012",
+		nc:   &networking.Config{},
+		want: "http://10.10.10.10:8012",
+	}, {
+		name: "backend tls",
+		dest: "10.10.10.10:8012",
+		nc:   &networking.Config{ActivatorBackendTLS: true},
+		want: "https://10.10.10.10:8112",
+	}, {
+		name: "backend tls, cluster ip",
+		dest: "10.10.10.10:80",
+		nc:   &networking.Config{ActivatorBackendTLS: true},
+		want: "https://10.10.10.10:8112",
+	}, {
+		name: "backend tls, ipv6",
+		dest: "[::1]:8013",
+		nc:   &networking.Config{ActivatorBackendTLS: true},
+		want: "https://[::1]:8112",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := target(test.dest, test.nc).String(); got != test.want {
+				t.Errorf("target() = %s, want: %s", got, test.want)
+			}
+		})
+	}
+}
+
 func TestActivationHandlerTraceSpans(t *testing.T) {
 	testcases := []struct {
 		name         string
@@ -246,7 +528,7 @@ func TestActivationHandlerTraceSpans(t *testing.T) {
 				oct.Finish()
 			}()
 
-			handler := New(ctx, fakeThrottler{}, rt)
+			handler := New(ctx, fakeThrottler{}, rt, NewBufferBudget(0))
 
 			// Set up config store to populate context.
 			configStore := setupConfigStore(t, logging.FromContext(ctx))
@@ -298,6 +580,8 @@ func setupConfigStore(t *testing.T, logger *zap.SugaredLogger) *activatorconfig.
 	configStore := activatorconfig.NewStore(logger)
 	tracingConfig := ConfigMapFromTestFile(t, tracingconfig.ConfigName)
 	configStore.OnConfigChanged(tracingConfig)
+	configStore.OnConfigChanged(ConfigMapFromTestFile(t, network.ConfigName))
+	configStore.OnConfigChanged(ConfigMapFromTestFile(t, asconfig.ConfigName))
 	return configStore
 }
 
@@ -317,7 +601,7 @@ func BenchmarkHandler(b *testing.B) {
 			}, nil
 		})
 
-		handler := New(ctx, fakeThrottler{}, rt)
+		handler := New(ctx, fakeThrottler{}, rt, NewBufferBudget(0))
 
 		request := func() *http.Request {
 			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
@@ -123,7 +123,7 @@ func TestRequestMetricHandler(t *testing.T) {
 }
 
 func reset() {
-	metricstest.Unregister(requestConcurrencyM.Name(), requestCountM.Name(), responseTimeInMsecM.Name())
+	metricstest.Unregister(requestConcurrencyM.Name(), longLivedConnectionsM.Name(), requestCountM.Name(), responseTimeInMsecM.Name())
 	register()
 }
 
@@ -30,6 +30,10 @@ var (
 		"request_concurrency",
 		"Concurrent requests that are routed to Activator",
 		stats.UnitDimensionless)
+	longLivedConnectionsM = stats.Int64(
+		"long_lived_connections",
+		"Open long-lived connections (websockets, server-sent events) that are routed to Activator",
+		stats.UnitDimensionless)
 	requestCountM = stats.Int64(
 		"request_count",
 		"The number of requests that are routed to Activator",
@@ -59,6 +63,12 @@ func register() {
 			Aggregation: view.LastValue(),
 			TagKeys:     []tag.Key{metrics.PodTagKey, metrics.ContainerTagKey},
 		},
+		&view.View{
+			Description: "Open long-lived connections (websockets, server-sent events) that are routed to Activator",
+			Measure:     longLivedConnectionsM,
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{metrics.PodTagKey, metrics.ContainerTagKey},
+		},
 		&view.View{
 			Description: "The number of requests that are routed to Activator",
 			Measure:     requestCountM,
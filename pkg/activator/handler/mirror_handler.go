@@ -0,0 +1,189 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+
+	"knative.dev/pkg/logging"
+	"knative.dev/serving/pkg/activator"
+	apiconfig "knative.dev/serving/pkg/apis/config"
+)
+
+const (
+	// maxMirrorBodyBytes is the largest request body buffered for mirroring.
+	// The requests with larger bodies are not mirrored.
+	maxMirrorBodyBytes = 1 << 20
+	// maxInflightMirrors is the number of the mirrored requests the activator
+	// keeps in flight, before it stops mirroring to protect the primary traffic.
+	maxInflightMirrors = 100
+	// mirrorTimeout bounds the lifetime of a mirrored request, which is
+	// detached from the primary request.
+	mirrorTimeout = apiconfig.DefaultMaxRevisionTimeoutSeconds * time.Second
+)
+
+// NewMirrorHandler creates a handler that mirrors a percentage of the requests,
+// as instructed by the mirror headers, to another revision in the same
+// namespace, discarding the responses of the mirror. The buffered bodies
+// of the mirrored requests are accounted against the budget, the requests
+// to mirror are rejected with a 503 once it is exhausted, so that they
+// spill over to the other activators.
+func NewMirrorHandler(ctx context.Context, budget *BufferBudget, next http.Handler) http.Handler {
+	return &mirrorHandler{
+		nextHandler: next,
+		logger:      logging.FromContext(ctx),
+		inflight:    make(chan struct{}, maxInflightMirrors),
+		budget:      budget,
+	}
+}
+
+// mirrorHandler sends copies of the requests through the rest of the handler
+// chain, addressed to the mirror revision.
+type mirrorHandler struct {
+	nextHandler http.Handler
+	logger      *zap.SugaredLogger
+	inflight    chan struct{}
+	budget      *BufferBudget
+}
+
+func (h *mirrorHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.Header.Get(activator.MirrorRevisionHeaderName)
+	percent, _ := strconv.Atoi(r.Header.Get(activator.MirrorPercentHeaderName))
+	// The user container must not see the mirror instructions.
+	r.Header.Del(activator.MirrorRevisionHeaderName)
+	r.Header.Del(activator.MirrorPercentHeaderName)
+
+	if name != "" && rand.Intn(100) < percent && r.ContentLength <= maxMirrorBodyBytes {
+		select {
+		case h.inflight <- struct{}{}:
+			reserved := bodyReservation(r)
+			if !h.budget.TryAcquire(reserved) {
+				<-h.inflight
+				h.logger.Debugw("Request buffer budget exhausted, rejecting the request to mirror", zap.String("mirror", name))
+				rejectBufferBudgetExhausted(w)
+				return
+			}
+			defer h.mirror(r, name, reserved)()
+		default:
+			h.logger.Debugw("Too many mirrored requests in flight, not mirroring", zap.String("mirror", name))
+		}
+	}
+	h.nextHandler.ServeHTTP(w, r)
+}
+
+// mirror sends the copy of the request to the revision `name` and returns the
+// function to call once the primary request is done. The caller holds a slot
+// of the in flight mirrors and `reserved` bytes of the budget.
+func (h *mirrorHandler) mirror(r *http.Request, name string, reserved int64) func() {
+	mr, buffered := h.mirrorRequest(r, name)
+	// Only hold on to what was actually buffered, until both the
+	// primary and the mirrored request are done with it.
+	h.budget.Release(reserved - buffered)
+	refs := atomic.NewInt32(1)
+	done := func() {
+		if refs.Dec() == 0 {
+			h.budget.Release(buffered)
+		}
+	}
+
+	if mr == nil {
+		<-h.inflight
+		return done
+	}
+	refs.Inc()
+	go func() {
+		defer func() { <-h.inflight }()
+		defer done()
+		ctx, cancel := context.WithTimeout(mr.Context(), mirrorTimeout)
+		defer cancel()
+		h.nextHandler.ServeHTTP(&discardResponseWriter{header: make(http.Header)}, mr.WithContext(ctx))
+	}()
+	return done
+}
+
+// bodyReservation returns the number of bytes to reserve in the budget to
+// buffer the body of the request to mirror.
+func bodyReservation(r *http.Request) int64 {
+	switch {
+	case r.Body == nil || r.Body == http.NoBody:
+		return 0
+	case r.ContentLength >= 0:
+		return r.ContentLength
+	default:
+		// Unknown length, up to the largest body that is mirrored.
+		return maxMirrorBodyBytes + 1
+	}
+}
+
+// mirrorRequest buffers the body of the request and returns its copy addressed
+// to the revision `name`, or nil if the body is too large to be mirrored,
+// along with the number of the bytes buffered.
+func (h *mirrorHandler) mirrorRequest(r *http.Request, name string) (*http.Request, int64) {
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		var err error
+		body, err = ioutil.ReadAll(io.LimitReader(r.Body, maxMirrorBodyBytes+1))
+		if err != nil || len(body) > maxMirrorBodyBytes {
+			// Serve the primary request with what was read so far.
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+			return nil, int64(len(body))
+		}
+		getBody := func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+		r.Body, _ = getBody()
+		r.GetBody = getBody
+	}
+
+	// The mirrored request must outlive the primary one.
+	mr := r.Clone(logging.WithLogger(context.Background(), h.logger))
+	mr.Header.Set(activator.RevisionHeaderName, name)
+	if body != nil {
+		mr.Body = ioutil.NopCloser(bytes.NewReader(body))
+		mr.GetBody = r.GetBody
+	}
+	return mr, int64(len(body))
+}
+
+// discardResponseWriter is the http.ResponseWriter for the mirrored requests,
+// whose responses are discarded.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *discardResponseWriter) WriteHeader(int) {}
@@ -0,0 +1,201 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"knative.dev/pkg/logging"
+	ktesting "knative.dev/pkg/logging/testing"
+	"knative.dev/serving/pkg/activator"
+)
+
+type mirroredRequest struct {
+	revision string
+	body     string
+	header   http.Header
+}
+
+func TestMirrorHandler(t *testing.T) {
+	largeBody := strings.Repeat("a", maxMirrorBodyBytes+1)
+	tests := []struct {
+		name       string
+		percent    string
+		body       string
+		wantMirror bool
+	}{{
+		name:       "mirror all",
+		percent:    "100",
+		body:       "hello",
+		wantMirror: true,
+	}, {
+		name:    "mirror none",
+		percent: "0",
+		body:    "hello",
+	}, {
+		name:    "invalid percent",
+		percent: "all",
+		body:    "hello",
+	}, {
+		name:    "body too large",
+		percent: "100",
+		body:    largeBody,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reqs := make(chan mirroredRequest, 2)
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					t.Error("Failed to read the body:", err)
+				}
+				reqs <- mirroredRequest{
+					revision: r.Header.Get(activator.RevisionHeaderName),
+					body:     string(b),
+					header:   r.Header,
+				}
+				w.Write([]byte(r.Header.Get(activator.RevisionHeaderName)))
+			})
+
+			budget := NewBufferBudget(0)
+			h := NewMirrorHandler(logging.WithLogger(context.Background(), ktesting.TestLogger(t)), budget, next)
+			req := httptest.NewRequest(http.MethodPost, "http://example.com", bytes.NewBufferString(test.body))
+			req.Header.Set(activator.RevisionHeaderNamespace, testNamespace)
+			req.Header.Set(activator.RevisionHeaderName, testRevName)
+			req.Header.Set(activator.MirrorRevisionHeaderName, "mirror")
+			req.Header.Set(activator.MirrorPercentHeaderName, test.percent)
+			resp := httptest.NewRecorder()
+			h.ServeHTTP(resp, req)
+
+			// The response is always the one of the primary.
+			if got, want := resp.Body.String(), testRevName; got != want {
+				t.Errorf("Response = %q, want: %q", got, want)
+			}
+
+			want := map[string]bool{testRevName: true}
+			if test.wantMirror {
+				want["mirror"] = true
+			}
+			for i := 0; i < len(want); i++ {
+				select {
+				case r := <-reqs:
+					if !want[r.revision] {
+						t.Errorf("Unexpected request to %q", r.revision)
+					}
+					if r.body != test.body {
+						t.Errorf("Body of the request to %q has length %d, want: %d", r.revision, len(r.body), len(test.body))
+					}
+					if r.header.Get(activator.MirrorRevisionHeaderName) != "" || r.header.Get(activator.MirrorPercentHeaderName) != "" {
+						t.Errorf("Mirror headers were not stripped from the request to %q: %v", r.revision, r.header)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatal("Timed out waiting for the requests")
+				}
+			}
+			select {
+			case r := <-reqs:
+				t.Errorf("Unexpected request to %q", r.revision)
+			case <-time.After(50 * time.Millisecond):
+			}
+			if err := wait.PollImmediate(time.Millisecond, time.Second, func() (bool, error) {
+				return budget.Used() == 0, nil
+			}); err != nil {
+				t.Errorf("Budget used = %d, want: 0", budget.Used())
+			}
+		})
+	}
+}
+
+func TestMirrorHandlerBufferBudget(t *testing.T) {
+	served := make(chan string, 2)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served <- r.Header.Get(activator.RevisionHeaderName)
+	})
+	budget := NewBufferBudget(4)
+	h := NewMirrorHandler(logging.WithLogger(context.Background(), ktesting.TestLogger(t)), budget, next)
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "http://example.com", bytes.NewBufferString(body))
+		req.Header.Set(activator.RevisionHeaderName, testRevName)
+		req.Header.Set(activator.MirrorRevisionHeaderName, "mirror")
+		req.Header.Set(activator.MirrorPercentHeaderName, "100")
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, req)
+		return resp
+	}
+
+	// The body does not fit in the budget, so the request spills over.
+	if got, want := send("hello").Code, http.StatusServiceUnavailable; got != want {
+		t.Errorf("Status = %d, want: %d", got, want)
+	}
+	select {
+	case r := <-served:
+		t.Errorf("Unexpected request to %q", r)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if got := budget.Used(); got != 0 {
+		t.Errorf("Budget used = %d, want: 0", got)
+	}
+
+	// The one that fits is served and mirrored.
+	if got, want := send("hi").Code, http.StatusOK; got != want {
+		t.Errorf("Status = %d, want: %d", got, want)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-served:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the requests")
+		}
+	}
+	if err := wait.PollImmediate(time.Millisecond, time.Second, func() (bool, error) {
+		return budget.Used() == 0, nil
+	}); err != nil {
+		t.Errorf("Budget used = %d, want: 0", budget.Used())
+	}
+}
+
+func TestBufferBudget(t *testing.T) {
+	b := NewBufferBudget(10)
+	if !b.TryAcquire(6) {
+		t.Error("TryAcquire(6) = false, want: true")
+	}
+	if b.TryAcquire(5) {
+		t.Error("TryAcquire(5) = true over the budget, want: false")
+	}
+	if !b.TryAcquire(4) {
+		t.Error("TryAcquire(4) = false, want: true")
+	}
+	b.Release(6)
+	if got, want := b.Used(), int64(4); got != want {
+		t.Errorf("Used = %d, want: %d", got, want)
+	}
+
+	unlimited := NewBufferBudget(0)
+	if !unlimited.TryAcquire(1 << 40) {
+		t.Error("TryAcquire() = false without a limit, want: true")
+	}
+}
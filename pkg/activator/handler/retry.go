@@ -0,0 +1,228 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"knative.dev/serving/pkg/networking"
+)
+
+// errPerTryTimeout is returned when an attempt got no response headers
+// within the per try timeout.
+var errPerTryTimeout = errors.New("per try timeout exceeded")
+
+// retryTransport is an http.RoundTripper that retries the requests which
+// failed to reach the revision, or got a retriable status code back,
+// as per the retry policy configured in the network config map.
+type retryTransport struct {
+	base          http.RoundTripper
+	retries       int
+	statusCodes   map[int]struct{}
+	perTryTimeout time.Duration
+	nonIdempotent bool
+	bufferSize    int64
+	budget        *BufferBudget
+}
+
+// newRetryTransport wraps base with the retry policy from cfg, buffering the
+// request bodies to replay within the budget.
+// If the policy amounts to a single attempt without a timeout,
+// base is returned as is.
+func newRetryTransport(base http.RoundTripper, cfg *networking.Config, budget *BufferBudget) http.RoundTripper {
+	if cfg == nil || cfg.ActivatorRetries == 0 && cfg.ActivatorPerTryTimeout == 0 {
+		return base
+	}
+	codes := make(map[int]struct{}, len(cfg.ActivatorRetriableStatusCodes))
+	for _, c := range cfg.ActivatorRetriableStatusCodes {
+		codes[c] = struct{}{}
+	}
+	return &retryTransport{
+		base:          base,
+		retries:       int(cfg.ActivatorRetries),
+		statusCodes:   codes,
+		perTryTimeout: cfg.ActivatorPerTryTimeout,
+		nonIdempotent: cfg.ActivatorRetryNonIdempotent,
+		bufferSize:    cfg.ActivatorRetryBufferSize,
+		budget:        budget,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rt *retryTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	retries := rt.retries
+	if !rt.nonIdempotent && !isIdempotent(r) {
+		retries = 0
+	}
+	if retries > 0 {
+		var (
+			buffered int64
+			err      error
+		)
+		if r, buffered, err = rt.replayable(r); err != nil {
+			return nil, err
+		}
+		defer rt.budget.Release(buffered)
+		// A body too large to buffer can't be sent again, so such requests
+		// get a single attempt.
+		if r.GetBody == nil && r.Body != nil && r.Body != http.NoBody {
+			retries = 0
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := rt.try(r, attempt)
+		// Don't retry if the client went away.
+		if attempt >= retries || r.Context().Err() != nil || !rt.retriable(resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}
+}
+
+// isIdempotent returns whether the request can be safely sent again, as
+// per its method, or its Idempotency-Key header, like net/http does.
+func isIdempotent(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	}
+	_, ok := r.Header["Idempotency-Key"]
+	return ok
+}
+
+// replayable buffers the body of the request, if it fits in the buffer size,
+// so that it can be sent again. Larger bodies are left to stream through.
+// The bytes buffered, which are returned, are held in the budget until the
+// caller releases them, and errBufferBudgetExhausted is returned if they do
+// not fit in it.
+func (rt *retryTransport) replayable(r *http.Request) (*http.Request, int64, error) {
+	if r.Body == nil || r.Body == http.NoBody || r.GetBody != nil {
+		return r, 0, nil
+	}
+	if r.ContentLength > rt.bufferSize {
+		return r, 0, nil
+	}
+	reserved := r.ContentLength
+	if reserved < 0 {
+		// Unknown length, up to the largest body that is buffered.
+		reserved = rt.bufferSize + 1
+	}
+	if !rt.budget.TryAcquire(reserved) {
+		return nil, 0, errBufferBudgetExhausted
+	}
+	buf, err := ioutil.ReadAll(io.LimitReader(r.Body, rt.bufferSize+1))
+	rt.budget.Release(reserved - int64(len(buf)))
+	if err != nil {
+		rt.budget.Release(int64(len(buf)))
+		return nil, 0, err
+	}
+	r = r.Clone(r.Context())
+	if int64(len(buf)) > rt.bufferSize {
+		// Stream what was read, followed by the rest of the body.
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), r.Body), r.Body}
+		return r, int64(len(buf)), nil
+	}
+	r.Body.Close()
+	r.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(buf)), nil
+	}
+	r.Body, _ = r.GetBody()
+	return r, int64(len(buf)), nil
+}
+
+func (rt *retryTransport) retriable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	_, ok := rt.statusCodes[resp.StatusCode]
+	return ok
+}
+
+func (rt *retryTransport) try(r *http.Request, attempt int) (*http.Response, error) {
+	if attempt > 0 && r.GetBody != nil {
+		body, err := r.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r = r.Clone(r.Context())
+		r.Body = body
+	}
+	if rt.perTryTimeout <= 0 {
+		return rt.base.RoundTrip(r)
+	}
+
+	// The timeout only bounds the wait for the response headers, the
+	// response may then stream for as long as it takes.
+	ctx, cancel := context.WithCancel(r.Context())
+	timer := time.AfterFunc(rt.perTryTimeout, cancel)
+	resp, err := rt.base.RoundTrip(r.WithContext(ctx))
+	if !timer.Stop() {
+		// The attempt timed out, even if the headers arrived just now.
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, errPerTryTimeout
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// Release the attempt's context once the body is closed.
+	cb := &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	if w, ok := resp.Body.(io.Writer); ok {
+		// The body of the upgraded connections must remain writable.
+		resp.Body = &cancelRWBody{cancelBody: cb, w: w}
+	} else {
+		resp.Body = cb
+	}
+	return resp, nil
+}
+
+// cancelBody cancels the attempt's context when the response body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// cancelRWBody is the cancelBody of the upgraded connections.
+type cancelRWBody struct {
+	*cancelBody
+	w io.Writer
+}
+
+func (b *cancelRWBody) Write(p []byte) (int, error) {
+	return b.w.Write(p)
+}
@@ -0,0 +1,297 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pkgnet "knative.dev/pkg/network"
+	"knative.dev/serving/pkg/networking"
+)
+
+func TestRetryTransport(t *testing.T) {
+	errDial := errors.New("connection refused")
+
+	tests := []struct {
+		name      string
+		cfg       *networking.Config
+		method    string
+		header    http.Header
+		body      string
+		responses []int // 0 means a transport error.
+		wantTries int
+		wantCode  int
+		wantErr   bool
+	}{{
+		name:      "no retries configured",
+		cfg:       &networking.Config{},
+		responses: []int{0, http.StatusOK},
+		wantTries: 1,
+		wantErr:   true,
+	}, {
+		name:      "retry connection failure",
+		cfg:       &networking.Config{ActivatorRetries: 2},
+		responses: []int{0, 0, http.StatusOK},
+		wantTries: 3,
+		wantCode:  http.StatusOK,
+	}, {
+		name:      "retries exhausted",
+		cfg:       &networking.Config{ActivatorRetries: 1},
+		responses: []int{0, 0, http.StatusOK},
+		wantTries: 2,
+		wantErr:   true,
+	}, {
+		name: "retry status code",
+		cfg: &networking.Config{
+			ActivatorRetries:              2,
+			ActivatorRetriableStatusCodes: []int{http.StatusServiceUnavailable},
+		},
+		responses: []int{http.StatusServiceUnavailable, http.StatusOK},
+		wantTries: 2,
+		wantCode:  http.StatusOK,
+	}, {
+		name: "status code not retriable",
+		cfg: &networking.Config{
+			ActivatorRetries:              2,
+			ActivatorRetriableStatusCodes: []int{http.StatusServiceUnavailable},
+		},
+		responses: []int{http.StatusBadGateway, http.StatusOK},
+		wantTries: 1,
+		wantCode:  http.StatusBadGateway,
+	}, {
+		name: "last retriable status code is returned",
+		cfg: &networking.Config{
+			ActivatorRetries:              1,
+			ActivatorRetriableStatusCodes: []int{http.StatusServiceUnavailable},
+		},
+		responses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+		wantTries: 2,
+		wantCode:  http.StatusServiceUnavailable,
+	}, {
+		name:      "non idempotent method",
+		cfg:       &networking.Config{ActivatorRetries: 2},
+		method:    http.MethodPost,
+		responses: []int{0, http.StatusOK},
+		wantTries: 1,
+		wantErr:   true,
+	}, {
+		name:      "non idempotent method with idempotency key",
+		cfg:       &networking.Config{ActivatorRetries: 2},
+		method:    http.MethodPost,
+		header:    http.Header{"Idempotency-Key": {"a-key"}},
+		responses: []int{0, http.StatusOK},
+		wantTries: 2,
+		wantCode:  http.StatusOK,
+	}, {
+		name: "non idempotent method retried when configured",
+		cfg: &networking.Config{
+			ActivatorRetries:            2,
+			ActivatorRetryNonIdempotent: true,
+			ActivatorRetryBufferSize:    1024,
+		},
+		method:    http.MethodPost,
+		body:      "♫ everything is awesome! ♫",
+		responses: []int{0, http.StatusOK},
+		wantTries: 2,
+		wantCode:  http.StatusOK,
+	}, {
+		name:      "buffered body is replayed",
+		cfg:       &networking.Config{ActivatorRetries: 2, ActivatorRetryBufferSize: 1024},
+		method:    http.MethodPut,
+		body:      "♫ everything is awesome! ♫",
+		responses: []int{0, http.StatusOK},
+		wantTries: 2,
+		wantCode:  http.StatusOK,
+	}, {
+		name:      "body too large to buffer",
+		cfg:       &networking.Config{ActivatorRetries: 2, ActivatorRetryBufferSize: 4},
+		method:    http.MethodPut,
+		body:      "♫ everything is awesome! ♫",
+		responses: []int{0, http.StatusOK},
+		wantTries: 1,
+		wantErr:   true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tries := 0
+			base := pkgnet.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				code := test.responses[tries]
+				tries++
+				// Every attempt must get the whole body.
+				if r.Body != nil {
+					if b, _ := ioutil.ReadAll(r.Body); string(b) != test.body {
+						t.Errorf("Attempt %d got body %q, want: %q", tries, b, test.body)
+					}
+				}
+				if code == 0 {
+					return nil, errDial
+				}
+				return &http.Response{
+					StatusCode: code,
+					Body:       ioutil.NopCloser(strings.NewReader("")),
+				}, nil
+			})
+
+			method := test.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "http://example.com", nil)
+			if test.body != "" {
+				req = httptest.NewRequest(method, "http://example.com", bytes.NewBufferString(test.body))
+				// Like the server side requests, the body can't be rewound.
+				req.GetBody = nil
+			}
+			for k, v := range test.header {
+				req.Header[k] = v
+			}
+			resp, err := newRetryTransport(base, test.cfg, NewBufferBudget(0)).RoundTrip(req)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("RoundTrip() = %v, wantErr: %v", err, test.wantErr)
+			}
+			if err == nil {
+				resp.Body.Close()
+				if resp.StatusCode != test.wantCode {
+					t.Errorf("StatusCode = %d, want: %d", resp.StatusCode, test.wantCode)
+				}
+			}
+			if tries != test.wantTries {
+				t.Errorf("#tries = %d, want: %d", tries, test.wantTries)
+			}
+		})
+	}
+}
+
+func TestRetryTransportBufferBudget(t *testing.T) {
+	tries := 0
+	base := pkgnet.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		tries++
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+		}, nil
+	})
+	budget := NewBufferBudget(4)
+	rt := newRetryTransport(base, &networking.Config{
+		ActivatorRetries:         1,
+		ActivatorRetryBufferSize: 1024,
+	}, budget)
+
+	send := func(body string, length int64) error {
+		req := httptest.NewRequest(http.MethodPut, "http://example.com", bytes.NewBufferString(body))
+		req.GetBody = nil
+		req.ContentLength = length
+		resp, err := rt.RoundTrip(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	for _, length := range []int64{5, -1} {
+		if err := send("hello", length); !errors.Is(err, errBufferBudgetExhausted) {
+			t.Errorf("RoundTrip() = %v, want: %v", err, errBufferBudgetExhausted)
+		}
+	}
+	if tries != 0 {
+		t.Errorf("#tries = %d, want: 0", tries)
+	}
+	if err := send("hi", 2); err != nil {
+		t.Error("RoundTrip() =", err)
+	}
+	if tries != 1 {
+		t.Errorf("#tries = %d, want: 1", tries)
+	}
+	if got := budget.Used(); got != 0 {
+		t.Errorf("Budget used = %d, want: 0", got)
+	}
+}
+
+func TestRetryTransportPerTryTimeout(t *testing.T) {
+	tries := 0
+	base := pkgnet.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		tries++
+		if tries == 1 {
+			// Hang until the attempt times out.
+			<-r.Context().Done()
+			return nil, r.Context().Err()
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(wantBody)),
+		}, nil
+	})
+
+	rt := newRetryTransport(base, &networking.Config{
+		ActivatorRetries:       1,
+		ActivatorPerTryTimeout: 10 * time.Millisecond,
+	}, NewBufferBudget(0))
+	resp, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://example.com", nil))
+	if err != nil {
+		t.Fatal("RoundTrip() =", err)
+	}
+	defer resp.Body.Close()
+	if tries != 2 {
+		t.Errorf("#tries = %d, want: 2", tries)
+	}
+	// The body must still be readable after the attempt returned.
+	if b, err := ioutil.ReadAll(resp.Body); err != nil || string(b) != wantBody {
+		t.Errorf("Body = %q, %v, want: %q", b, err, wantBody)
+	}
+}
+
+func TestRetryTransportPerTryTimeoutHeadersOnly(t *testing.T) {
+	const timeout = 10 * time.Millisecond
+	base := pkgnet.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: ioutil.NopCloser(readerFunc(func(p []byte) (int, error) {
+				// Stream for longer than the per try timeout.
+				time.Sleep(2 * timeout)
+				if err := r.Context().Err(); err != nil {
+					return 0, err
+				}
+				return copy(p, wantBody), io.EOF
+			})),
+		}, nil
+	})
+
+	rt := newRetryTransport(base, &networking.Config{ActivatorPerTryTimeout: timeout}, NewBufferBudget(0))
+	resp, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "http://example.com", nil))
+	if err != nil {
+		t.Fatal("RoundTrip() =", err)
+	}
+	defer resp.Body.Close()
+	if b, err := ioutil.ReadAll(resp.Body); err != nil || string(b) != wantBody {
+		t.Errorf("Body = %q, %v, want: %q", b, err, wantBody)
+	}
+}
+
+type readerFunc func([]byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
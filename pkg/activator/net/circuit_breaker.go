@@ -0,0 +1,134 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package net
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitBreakerParams configure the circuit breakers that stop the activator
+// from sending the requests to the destinations that keep failing them.
+type CircuitBreakerParams struct {
+	// ErrorThreshold is the ratio of the failed requests over the Window,
+	// in (0, 1], at which the destination is no longer picked.
+	// Zero disables the circuit breakers.
+	ErrorThreshold float64
+	// MinRequests is the minimum number of requests over the Window
+	// for the error ratio to be considered.
+	MinRequests int
+	// Window is the rolling window over which the error ratio is computed.
+	Window time.Duration
+	// OpenDuration is how long the destination is not picked, before the
+	// requests are let through again to probe it.
+	OpenDuration time.Duration
+}
+
+func (p CircuitBreakerParams) enabled() bool {
+	return p.ErrorThreshold > 0
+}
+
+// cbBucket counts the requests in a single second.
+type cbBucket struct {
+	sec      int64
+	requests int
+	failures int
+}
+
+// circuitBreaker tracks the outcomes of the requests to a single destination.
+// It opens once the ratio of the failed requests over the rolling window
+// reaches the threshold. After OpenDuration it is half-open, i.e. the requests
+// are let through again, and the outcome of the next one either closes it or
+// opens it again.
+type circuitBreaker struct {
+	params CircuitBreakerParams
+
+	mux      sync.Mutex
+	open     bool
+	openedAt time.Time
+	// buckets is a ring buffer of the per second counts over the window.
+	buckets []cbBucket
+}
+
+func newCircuitBreaker(params CircuitBreakerParams) *circuitBreaker {
+	n := int(params.Window / time.Second)
+	if n < 1 {
+		n = 1
+	}
+	return &circuitBreaker{
+		params:  params,
+		buckets: make([]cbBucket, n),
+	}
+}
+
+// available returns whether requests can be sent to the destination,
+// i.e. whether the breaker is closed or half-open.
+func (cb *circuitBreaker) available(now time.Time) bool {
+	cb.mux.Lock()
+	defer cb.mux.Unlock()
+	return !cb.open || now.Sub(cb.openedAt) >= cb.params.OpenDuration
+}
+
+// record accounts the outcome of a request to the destination.
+func (cb *circuitBreaker) record(now time.Time, failed bool) {
+	cb.mux.Lock()
+	defer cb.mux.Unlock()
+
+	if cb.open {
+		// This is the outcome of a half-open probe.
+		if failed {
+			cb.openedAt = now
+		} else {
+			cb.open = false
+			cb.reset()
+		}
+		return
+	}
+
+	sec := now.Unix()
+	b := &cb.buckets[int(sec%int64(len(cb.buckets)))]
+	if b.sec != sec {
+		*b = cbBucket{sec: sec}
+	}
+	b.requests++
+	if failed {
+		b.failures++
+	}
+	if !failed {
+		return
+	}
+
+	var requests, failures int
+	for _, b := range cb.buckets {
+		if sec-b.sec < int64(len(cb.buckets)) {
+			requests += b.requests
+			failures += b.failures
+		}
+	}
+	if requests >= cb.params.MinRequests &&
+		float64(failures) >= cb.params.ErrorThreshold*float64(requests) {
+		cb.open = true
+		cb.openedAt = now
+	}
+}
+
+// reset clears the counts. It expects the mux to be held.
+func (cb *circuitBreaker) reset() {
+	for i := range cb.buckets {
+		cb.buckets[i] = cbBucket{}
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package net
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	cb := newCircuitBreaker(CircuitBreakerParams{
+		ErrorThreshold: 0.5,
+		MinRequests:    4,
+		Window:         10 * time.Second,
+		OpenDuration:   30 * time.Second,
+	})
+	now := time.Unix(1600000000, 0)
+
+	// Too few requests to consider the error ratio.
+	cb.record(now, true)
+	cb.record(now, true)
+	cb.record(now.Add(time.Second), true)
+	if !cb.available(now.Add(time.Second)) {
+		t.Fatal("Circuit breaker opened before MinRequests")
+	}
+
+	// The errors slide out of the window.
+	now = now.Add(20 * time.Second)
+	cb.record(now, false)
+	cb.record(now, false)
+	cb.record(now, false)
+	cb.record(now, true)
+	if !cb.available(now) {
+		t.Fatal("Circuit breaker opened below the threshold")
+	}
+
+	// 2 out of 5 failed.
+	cb.record(now.Add(time.Second), true)
+	if !cb.available(now.Add(time.Second)) {
+		t.Fatal("Circuit breaker opened below the threshold")
+	}
+	// 3 out of 6 failed.
+	now = now.Add(2 * time.Second)
+	cb.record(now, true)
+	if cb.available(now) {
+		t.Fatal("Circuit breaker is closed at the threshold")
+	}
+	if cb.available(now.Add(29 * time.Second)) {
+		t.Fatal("Circuit breaker is closed before OpenDuration")
+	}
+
+	// Half-open, the probe fails.
+	now = now.Add(30 * time.Second)
+	if !cb.available(now) {
+		t.Fatal("Circuit breaker is not half-open after OpenDuration")
+	}
+	cb.record(now, true)
+	if cb.available(now.Add(time.Second)) {
+		t.Fatal("Circuit breaker is not open after the failed probe")
+	}
+
+	// Half-open, the probe succeeds.
+	now = now.Add(30 * time.Second)
+	cb.record(now, false)
+	if !cb.available(now) {
+		t.Fatal("Circuit breaker is not closed after the successful probe")
+	}
+	// And the counts start from scratch.
+	cb.record(now, true)
+	if !cb.available(now) {
+		t.Fatal("Circuit breaker opened before MinRequests")
+	}
+}
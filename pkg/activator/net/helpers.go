@@ -19,11 +19,18 @@ package net
 import (
 	"net"
 	"strconv"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	corev1listers "k8s.io/client-go/listers/core/v1"
 
-	"knative.dev/networking/pkg/apis/networking"
+	pkgnet "knative.dev/networking/pkg/apis/networking"
+	"knative.dev/serving/pkg/apis/serving"
+	"knative.dev/serving/pkg/networking"
 )
 
 // healthyAddresses takes an endpoints object and a port name and return the set
@@ -45,27 +52,32 @@ func healthyAddresses(endpoints *corev1.Endpoints, portName string) sets.String
 	return ready
 }
 
-// endpointsToDests takes an endpoints object and a port name and returns two sets of
-// ready and non-ready l4 dests in the endpoints object which have that port.
-func endpointsToDests(endpoints *corev1.Endpoints, portName string) (ready, notReady sets.String) {
+// endpointSliceToDests takes an endpoint slice and a port name and returns two sets of
+// ready and non-ready l4 dests in the endpoint slice which have that port.
+func endpointSliceToDests(slice *discoveryv1beta1.EndpointSlice, portName string) (ready, notReady sets.String) {
 	ready = sets.NewString()
 	notReady = sets.NewString()
+	if slice.AddressType == discoveryv1beta1.AddressTypeFQDN {
+		return ready, notReady
+	}
 
-	for _, es := range endpoints.Subsets {
-		for _, port := range es.Ports {
-			if port.Name == portName {
-				portStr := strconv.Itoa(int(port.Port))
-				for _, addr := range es.Addresses {
-					// Prefer IP as we can avoid a DNS lookup this way.
-					ready.Insert(net.JoinHostPort(addr.IP, portStr))
-				}
-				for _, addr := range es.NotReadyAddresses {
-					// Prefer IP as we can avoid a DNS lookup this way.
-					notReady.Insert(net.JoinHostPort(addr.IP, portStr))
-				}
-				break
+	for _, port := range slice.Ports {
+		if port.Name == nil || *port.Name != portName || port.Port == nil {
+			continue
+		}
+		portStr := strconv.Itoa(int(*port.Port))
+		for _, ep := range slice.Endpoints {
+			// Unknown readiness is to be interpreted as ready, while the
+			// terminating pods are reported as not ready.
+			dests := ready
+			if ep.Conditions.Ready != nil && !*ep.Conditions.Ready {
+				dests = notReady
+			}
+			for _, addr := range ep.Addresses {
+				dests.Insert(net.JoinHostPort(addr, portStr))
 			}
 		}
+		break
 	}
 
 	return ready, notReady
@@ -73,8 +85,8 @@ func endpointsToDests(endpoints *corev1.Endpoints, portName string) (ready, notR
 
 // getServicePort takes a service and a protocol and returns the port number of
 // the port named for that protocol. If the port is not found then ok is false.
-func getServicePort(protocol networking.ProtocolType, svc *corev1.Service) (port int, ok bool) {
-	wantName := networking.ServicePortName(protocol)
+func getServicePort(protocol pkgnet.ProtocolType, svc *corev1.Service) (port int, ok bool) {
+	wantName := pkgnet.ServicePortName(protocol)
 	for _, p := range svc.Spec.Ports {
 		if p.Name == wantName {
 			port, ok = int(p.Port), true
@@ -83,3 +95,36 @@ func getServicePort(protocol networking.ProtocolType, svc *corev1.Service) (port
 	}
 	return
 }
+
+// revisionEndpoints returns the endpoints of the given type for the revision,
+// or nil if they don't exist (yet).
+func revisionEndpoints(lister corev1listers.EndpointsLister, rev types.NamespacedName,
+	st networking.ServiceType) *corev1.Endpoints {
+	eps, err := lister.Endpoints(rev.Namespace).List(labels.SelectorFromSet(labels.Set{
+		serving.RevisionLabelKey:  rev.Name,
+		networking.ServiceTypeKey: string(st),
+	}))
+	if err != nil || len(eps) == 0 {
+		return nil
+	}
+	return eps[0]
+}
+
+// isAssigned returns true if the activator with the IP address `selfIP` is
+// assigned to back the revision with the public endpoints `pubEps`.
+// If the assignment is not known, all the activators back the revision.
+func isAssigned(pubEps *corev1.Endpoints, selfIP string) bool {
+	if pubEps == nil || selfIP == "" {
+		return true
+	}
+	activators := pubEps.Annotations[networking.ActivatorsAnnotationKey]
+	if activators == "" {
+		return true
+	}
+	for _, ip := range strings.Split(activators, ",") {
+		if ip == selfIP {
+			return true
+		}
+	}
+	return false
+}
@@ -21,99 +21,112 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"knative.dev/networking/pkg/apis/networking"
+	"knative.dev/pkg/ptr"
+	servingnetworking "knative.dev/serving/pkg/networking"
 )
 
-func TestEndpointsToDests(t *testing.T) {
+func TestEndpointSliceToDests(t *testing.T) {
+	httpPorts := []discoveryv1beta1.EndpointPort{{
+		Name: ptr.String(networking.ServicePortNameHTTP1),
+		Port: ptr.Int32(1234),
+	}}
 	for _, tc := range []struct {
 		name           string
-		endpoints      corev1.Endpoints
+		slice          discoveryv1beta1.EndpointSlice
 		protocol       networking.ProtocolType
 		expectReady    sets.String
 		expectNotReady sets.String
 	}{{
 		name:        "no endpoints",
-		endpoints:   corev1.Endpoints{},
+		slice:       discoveryv1beta1.EndpointSlice{},
 		expectReady: sets.NewString(),
 	}, {
 		name: "single endpoint single address",
-		endpoints: corev1.Endpoints{
-			Subsets: []corev1.EndpointSubset{{
-				Addresses: []corev1.EndpointAddress{{
-					IP: "128.0.0.1",
-				}},
-				Ports: []corev1.EndpointPort{{
-					Name: networking.ServicePortNameHTTP1,
-					Port: 1234,
-				}},
+		slice: discoveryv1beta1.EndpointSlice{
+			AddressType: discoveryv1beta1.AddressTypeIPv4,
+			Endpoints: []discoveryv1beta1.Endpoint{{
+				Addresses: []string{"128.0.0.1"},
 			}},
+			Ports: httpPorts,
 		},
 		expectReady: sets.NewString("128.0.0.1:1234"),
 	}, {
-		name: "single endpoint multiple address",
-		endpoints: corev1.Endpoints{
-			Subsets: []corev1.EndpointSubset{{
-				Addresses: []corev1.EndpointAddress{{
-					IP: "128.0.0.1",
-				}, {
-					IP: "128.0.0.2",
-				}},
-				Ports: []corev1.EndpointPort{{
-					Name: networking.ServicePortNameHTTP1,
-					Port: 1234,
-				}},
+		name: "multiple endpoints",
+		slice: discoveryv1beta1.EndpointSlice{
+			AddressType: discoveryv1beta1.AddressTypeIPv4,
+			Endpoints: []discoveryv1beta1.Endpoint{{
+				Addresses:  []string{"128.0.0.1"},
+				Conditions: discoveryv1beta1.EndpointConditions{Ready: ptr.Bool(true)},
+			}, {
+				Addresses: []string{"128.0.0.2"},
 			}},
+			Ports: httpPorts,
 		},
 		expectReady: sets.NewString("128.0.0.1:1234", "128.0.0.2:1234"),
 	}, {
-		name: "single endpoint multiple addresses, including no ready addresses",
-		endpoints: corev1.Endpoints{
-			Subsets: []corev1.EndpointSubset{{
-				Addresses: []corev1.EndpointAddress{{
-					IP: "128.0.0.1",
-				}, {
-					IP: "128.0.0.2",
-				}},
-				NotReadyAddresses: []corev1.EndpointAddress{{
-					IP: "128.0.0.3",
-				}},
-				Ports: []corev1.EndpointPort{{
-					Name: networking.ServicePortNameHTTP1,
-					Port: 1234,
-				}},
+		name: "multiple endpoints, including not ready ones",
+		slice: discoveryv1beta1.EndpointSlice{
+			AddressType: discoveryv1beta1.AddressTypeIPv4,
+			Endpoints: []discoveryv1beta1.Endpoint{{
+				Addresses:  []string{"128.0.0.1"},
+				Conditions: discoveryv1beta1.EndpointConditions{Ready: ptr.Bool(true)},
+			}, {
+				Addresses:  []string{"128.0.0.2"},
+				Conditions: discoveryv1beta1.EndpointConditions{Ready: ptr.Bool(true)},
+			}, {
+				Addresses:  []string{"128.0.0.3"},
+				Conditions: discoveryv1beta1.EndpointConditions{Ready: ptr.Bool(false)},
 			}},
+			Ports: httpPorts,
 		},
 		expectReady:    sets.NewString("128.0.0.1:1234", "128.0.0.2:1234"),
 		expectNotReady: sets.NewString("128.0.0.3:1234"),
 	}, {
-		name: "multiple endpoint filter port",
-		endpoints: corev1.Endpoints{
-			Subsets: []corev1.EndpointSubset{{
-				Addresses: []corev1.EndpointAddress{{
-					IP: "128.0.0.1",
-				}},
-				Ports: []corev1.EndpointPort{{
-					Name: networking.ServicePortNameHTTP1,
-					Port: 1234,
-				}},
-			}, {
-				Addresses: []corev1.EndpointAddress{{
-					IP: "128.0.0.2",
-				}},
-				Ports: []corev1.EndpointPort{{
-					Name: "other-protocol",
-					Port: 1234,
-				}},
+		name: "filter port",
+		slice: discoveryv1beta1.EndpointSlice{
+			AddressType: discoveryv1beta1.AddressTypeIPv4,
+			Endpoints: []discoveryv1beta1.Endpoint{{
+				Addresses: []string{"128.0.0.1"},
 			}},
+			Ports: []discoveryv1beta1.EndpointPort{{
+				Name: ptr.String("other-protocol"),
+				Port: ptr.Int32(4321),
+			}, httpPorts[0]},
 		},
 		expectReady: sets.NewString("128.0.0.1:1234"),
+	}, {
+		name: "no matching port",
+		slice: discoveryv1beta1.EndpointSlice{
+			AddressType: discoveryv1beta1.AddressTypeIPv4,
+			Endpoints: []discoveryv1beta1.Endpoint{{
+				Addresses: []string{"128.0.0.1"},
+			}},
+			Ports: []discoveryv1beta1.EndpointPort{{
+				Name: ptr.String("other-protocol"),
+				Port: ptr.Int32(1234),
+			}},
+		},
+		expectReady: sets.NewString(),
+	}, {
+		name: "FQDN addresses",
+		slice: discoveryv1beta1.EndpointSlice{
+			AddressType: discoveryv1beta1.AddressTypeFQDN,
+			Endpoints: []discoveryv1beta1.Endpoint{{
+				Addresses: []string{"foo.example.com"},
+			}},
+			Ports: httpPorts,
+		},
+		expectReady: sets.NewString(),
 	}} {
 		t.Run(tc.name, func(t *testing.T) {
 			if tc.protocol == "" {
 				tc.protocol = networking.ProtocolHTTP1
 			}
-			ready, notReady := endpointsToDests(&tc.endpoints, networking.ServicePortName(tc.protocol))
+			ready, notReady := endpointSliceToDests(&tc.slice, networking.ServicePortName(tc.protocol))
 
 			if got, want := ready, tc.expectReady; !got.Equal(want) {
 				t.Error("Got unexpected ready dests (-want, +got):", cmp.Diff(want, got))
@@ -125,6 +138,20 @@ func TestEndpointsToDests(t *testing.T) {
 	}
 }
 
+func TestMergeDests(t *testing.T) {
+	got := mergeDests(map[string]dests{
+		"a": {ready: sets.NewString("128.0.0.1:1234"), notReady: sets.NewString("128.0.0.2:1234")},
+		"b": {ready: sets.NewString("128.0.0.2:1234", "128.0.0.3:1234"), notReady: sets.NewString("128.0.0.4:1234")},
+	})
+	want := dests{
+		ready:    sets.NewString("128.0.0.1:1234", "128.0.0.2:1234", "128.0.0.3:1234"),
+		notReady: sets.NewString("128.0.0.4:1234"),
+	}
+	if !cmp.Equal(got, want, cmp.AllowUnexported(dests{})) {
+		t.Error("mergeDests (-want, +got):", cmp.Diff(want, got, cmp.AllowUnexported(dests{})))
+	}
+}
+
 func TestGetServicePort(t *testing.T) {
 	for _, tc := range []struct {
 		name     string
@@ -168,3 +195,52 @@ func TestGetServicePort(t *testing.T) {
 		})
 	}
 }
+
+func TestIsAssigned(t *testing.T) {
+	const selfIP = "10.0.0.2"
+	withActivators := func(as string) *corev1.Endpoints {
+		return &corev1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					servingnetworking.ActivatorsAnnotationKey: as,
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name   string
+		eps    *corev1.Endpoints
+		selfIP string
+		want   bool
+	}{{
+		name:   "no endpoints",
+		selfIP: selfIP,
+		want:   true,
+	}, {
+		name:   "no assignment",
+		eps:    &corev1.Endpoints{},
+		selfIP: selfIP,
+		want:   true,
+	}, {
+		name:   "assigned",
+		eps:    withActivators("10.0.0.1,10.0.0.2"),
+		selfIP: selfIP,
+		want:   true,
+	}, {
+		name:   "not assigned",
+		eps:    withActivators("10.0.0.1,10.0.0.20"),
+		selfIP: selfIP,
+		want:   false,
+	}, {
+		name: "unknown IP",
+		eps:  withActivators("10.0.0.1,10.0.0.3"),
+		want: true,
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isAssigned(tc.eps, tc.selfIP); got != tc.want {
+				t.Errorf("isAssigned = %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
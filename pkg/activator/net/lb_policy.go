@@ -20,8 +20,11 @@ package net
 
 import (
 	"context"
+	"hash/fnv"
 	"math/rand"
 	"sync"
+
+	"knative.dev/serving/pkg/activator/util"
 )
 
 // lbPolicy is a functor that selects a target pod from the list, or (noop, nil) if
@@ -111,3 +114,34 @@ func newRoundRobinPolicy() lbPolicy {
 		return noop, nil
 	}
 }
+
+// newSessionAffinityPolicy returns a load balancer policy that sends the requests
+// with the same session key to the same target, while it has capacity.
+// The target is chosen using rendezvous hashing, so that only the sessions of
+// the removed targets move when the target set changes.
+// Requests without a session key, or whose target is at capacity, are handled
+// by the fallback policy.
+func newSessionAffinityPolicy(fallback lbPolicy) lbPolicy {
+	return func(ctx context.Context, targets []*podTracker) (func(), *podTracker) {
+		key := util.SessionKeyFrom(ctx)
+		if key == "" || len(targets) == 0 {
+			return fallback(ctx, targets)
+		}
+		var (
+			pick      *podTracker
+			pickScore uint64
+		)
+		for _, t := range targets {
+			h := fnv.New64a()
+			h.Write([]byte(key))
+			h.Write([]byte(t.dest))
+			if score := h.Sum64(); pick == nil || score > pickScore {
+				pick, pickScore = t, score
+			}
+		}
+		if cb, ok := pick.Reserve(ctx); ok {
+			return cb, pick
+		}
+		return fallback(ctx, targets)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"testing"
 	"time"
 
+	"knative.dev/serving/pkg/activator/util"
 	"knative.dev/serving/pkg/queue"
 )
 
@@ -234,6 +235,62 @@ func TestRoundRobin(t *testing.T) {
 	})
 }
 
+func TestSessionAffinity(t *testing.T) {
+	t.Run("same key same target", func(t *testing.T) {
+		sap := newSessionAffinityPolicy(newRoundRobinPolicy())
+		podTrackers := makeTrackers(5, 0)
+		ctx := util.WithSessionKey(context.Background(), "session-1")
+		cb, want := sap(ctx, podTrackers)
+		t.Cleanup(cb)
+		for i := 0; i < 10; i++ {
+			cb, pt := sap(ctx, podTrackers)
+			t.Cleanup(cb)
+			if pt != want {
+				t.Fatalf("Tracker = %v, want: %v", pt, want)
+			}
+		}
+		// Removing another target must not move the session.
+		var rest []*podTracker
+		for _, pt := range podTrackers {
+			if pt != want {
+				rest = append(rest, pt)
+			}
+		}
+		rest = append(rest[1:], want)
+		cb, pt := sap(ctx, rest)
+		t.Cleanup(cb)
+		if pt != want {
+			t.Fatalf("Tracker = %v, want: %v", pt, want)
+		}
+	})
+	t.Run("no key", func(t *testing.T) {
+		sap := newSessionAffinityPolicy(newRoundRobinPolicy())
+		podTrackers := makeTrackers(3, 0)
+		for i := 0; i < 3; i++ {
+			cb, pt := sap(context.Background(), podTrackers)
+			t.Cleanup(cb)
+			if got, want := pt, podTrackers[i]; got != want {
+				t.Fatalf("Tracker = %v, want: %v", got, want)
+			}
+		}
+	})
+	t.Run("target at capacity", func(t *testing.T) {
+		sap := newSessionAffinityPolicy(newRoundRobinPolicy())
+		podTrackers := makeTrackers(2, 1)
+		ctx := util.WithSessionKey(context.Background(), "session-1")
+		cb, pinned := sap(ctx, podTrackers)
+		t.Cleanup(cb)
+		cb, pt := sap(ctx, podTrackers)
+		t.Cleanup(cb)
+		if pt == nil || pt == pinned {
+			t.Fatalf("Tracker = %v, want the other one", pt)
+		}
+		if _, pt = sap(ctx, podTrackers); pt != nil {
+			t.Fatal("Wanted nil, got: ", pt)
+		}
+	})
+}
+
 func BenchmarkPolicy(b *testing.B) {
 	for _, test := range []struct {
 		name   string
@@ -27,20 +27,24 @@ import (
 	"sync"
 	"time"
 
+	"go.uber.org/atomic"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"golang.org/x/sync/errgroup"
 
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	corev1listers "k8s.io/client-go/listers/core/v1"
+	discoverylisters "k8s.io/client-go/listers/discovery/v1beta1"
 	"k8s.io/client-go/tools/cache"
 
 	network "knative.dev/networking/pkg"
 	pkgnet "knative.dev/networking/pkg/apis/networking"
 	endpointsinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/endpoints"
+	podinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/pod"
 	serviceinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/service"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/logging"
@@ -49,6 +53,7 @@ import (
 	"knative.dev/pkg/reconciler"
 	"knative.dev/serving/pkg/apis/serving"
 	revisioninformer "knative.dev/serving/pkg/client/injection/informers/serving/v1/revision"
+	endpointsliceinformer "knative.dev/serving/pkg/client/injection/kube/informers/discovery/v1beta1/endpointslice"
 	servinglisters "knative.dev/serving/pkg/client/listers/serving/v1"
 	"knative.dev/serving/pkg/networking"
 	"knative.dev/serving/pkg/queue"
@@ -111,12 +116,21 @@ type revisionWatcher struct {
 	// podsAddressable will be set to false if we cannot
 	// probe a pod directly, but its cluster IP has been successfully probed.
 	podsAddressable bool
+
+	// deletedCh receives the IPs of the revision pods being deleted.
+	deletedCh chan string
+	// deleted are the IPs of the pods being deleted, with the time the deletion
+	// was observed. They are kept out of the dests until an endpoints update
+	// without them arrives, but at most for staleEndpointTolerance, since the
+	// IPs can be reused by the new pods.
+	deleted                map[string]time.Time
+	staleEndpointTolerance time.Duration
 }
 
 func newRevisionWatcher(ctx context.Context, rev types.NamespacedName, protocol pkgnet.ProtocolType,
 	updateCh chan<- revisionDestsUpdate, destsCh chan dests,
 	transport http.RoundTripper, serviceLister corev1listers.ServiceLister,
-	logger *zap.SugaredLogger) *revisionWatcher {
+	staleEndpointTolerance time.Duration, logger *zap.SugaredLogger) *revisionWatcher {
 	ctx, cancel := context.WithCancel(ctx)
 	return &revisionWatcher{
 		stopCh:          ctx.Done(),
@@ -130,9 +144,51 @@ func newRevisionWatcher(ctx context.Context, rev types.NamespacedName, protocol
 		serviceLister:   serviceLister,
 		podsAddressable: true, // By default we presume we can talk to pods directly.
 		logger:          logger.With(zap.Object(logkey.Key, logging.NamespacedName(rev))),
+
+		deletedCh:              make(chan string),
+		deleted:                make(map[string]time.Time),
+		staleEndpointTolerance: staleEndpointTolerance,
 	}
 }
 
+// withoutDeleted returns the dests without the addresses of the pods being
+// deleted. The deletions that the endpoints caught up with, or that were
+// observed longer than staleEndpointTolerance ago, are forgotten.
+func (rw *revisionWatcher) withoutDeleted(d dests, now time.Time) dests {
+	if len(rw.deleted) == 0 {
+		return d
+	}
+	for ip, ts := range rw.deleted {
+		if now.Sub(ts) > rw.staleEndpointTolerance {
+			delete(rw.deleted, ip)
+		}
+	}
+
+	listed := sets.NewString()
+	filter := func(src sets.String) sets.String {
+		ret := sets.NewString()
+		for dest := range src {
+			host, _, err := net.SplitHostPort(dest)
+			if err != nil {
+				host = dest
+			}
+			if _, ok := rw.deleted[host]; ok {
+				listed.Insert(host)
+				continue
+			}
+			ret.Insert(dest)
+		}
+		return ret
+	}
+	ret := dests{ready: filter(d.ready), notReady: filter(d.notReady)}
+	for ip := range rw.deleted {
+		if !listed.Has(ip) {
+			delete(rw.deleted, ip)
+		}
+	}
+	return ret
+}
+
 func (rw *revisionWatcher) getK8sPrivateService() (*corev1.Service, error) {
 	selector := labels.SelectorFromSet(labels.Set{
 		serving.RevisionLabelKey:  rw.rev.Name,
@@ -359,7 +415,14 @@ func (rw *revisionWatcher) run(probeFrequency time.Duration) {
 		case <-rw.stopCh:
 			return
 		case x := <-rw.destsCh:
-			prevDests, curDests = curDests, x
+			prevDests, curDests = curDests, rw.withoutDeleted(x, time.Now())
+		case ip := <-rw.deletedCh:
+			now := time.Now()
+			if _, ok := rw.deleted[ip]; !ok {
+				rw.deleted[ip] = now
+			}
+			rw.logger.Debugw("Dropping the pod being deleted", zap.String("IP", ip))
+			prevDests, curDests = curDests, rw.withoutDeleted(curDests, now)
 		case <-tickCh:
 		}
 
@@ -367,55 +430,103 @@ func (rw *revisionWatcher) run(probeFrequency time.Duration) {
 	}
 }
 
-// revisionBackendsManager listens to revision endpoints and keeps track of healthy
+// revisionBackendsManager listens to revision endpoint slices and keeps track of healthy
 // l4 dests which can be used to reach a revision
 type revisionBackendsManager struct {
-	ctx            context.Context
-	revisionLister servinglisters.RevisionLister
-	serviceLister  corev1listers.ServiceLister
+	ctx                 context.Context
+	revisionLister      servinglisters.RevisionLister
+	serviceLister       corev1listers.ServiceLister
+	endpointsLister     corev1listers.EndpointsLister
+	endpointSliceLister discoverylisters.EndpointSliceLister
+	selfIP              string // The IP address of this activator.
 
 	revisionWatchers    map[types.NamespacedName]*revisionWatcher
 	revisionWatchersMux sync.RWMutex
 
+	// sliceDests are the dests of the revisions' private service, per endpoint
+	// slice name, so that a slice update only recomputes that slice's dests.
+	sliceDests    map[types.NamespacedName]map[string]dests
+	sliceDestsMux sync.Mutex
+
 	updateCh       chan revisionDestsUpdate
 	transport      http.RoundTripper
 	logger         *zap.SugaredLogger
 	probeFrequency time.Duration
+
+	// staleEndpointTolerance is how long the pods observed being deleted are
+	// kept out of the revision backends, while the endpoints still list them.
+	// Zero disables watching the pods.
+	staleEndpointTolerance time.Duration
+
+	// draining is set once the activator starts shutting down, after which
+	// the revision watchers are kept even if the revisions get unassigned.
+	draining atomic.Bool
+	// unassignedGracePeriod is how long the revisions unassigned from this
+	// activator are tracked still.
+	unassignedGracePeriod time.Duration
 }
 
 // NewRevisionBackendsManager returns a new RevisionBackendsManager with default
-// probe time out.
-func newRevisionBackendsManager(ctx context.Context, tr http.RoundTripper) *revisionBackendsManager {
-	return newRevisionBackendsManagerWithProbeFrequency(ctx, tr, defaultProbeFrequency)
+// probe time out. The manager only tracks the revisions assigned to the
+// activator with the IP address selfIP.
+func newRevisionBackendsManager(ctx context.Context, tr http.RoundTripper, selfIP string,
+	staleEndpointTolerance time.Duration) *revisionBackendsManager {
+	return newRevisionBackendsManagerWithProbeFrequency(ctx, tr, selfIP, defaultProbeFrequency, staleEndpointTolerance)
 }
 
 // newRevisionBackendsManagerWithProbeFrequency creates a fully spec'd RevisionBackendsManager.
 func newRevisionBackendsManagerWithProbeFrequency(ctx context.Context, tr http.RoundTripper,
-	probeFreq time.Duration) *revisionBackendsManager {
+	selfIP string, probeFreq, staleEndpointTolerance time.Duration) *revisionBackendsManager {
+	endpointsInformer := endpointsinformer.Get(ctx)
+	endpointSliceInformer := endpointsliceinformer.Get(ctx)
 	rbm := &revisionBackendsManager{
-		ctx:              ctx,
-		revisionLister:   revisioninformer.Get(ctx).Lister(),
-		serviceLister:    serviceinformer.Get(ctx).Lister(),
-		revisionWatchers: make(map[types.NamespacedName]*revisionWatcher),
-		updateCh:         make(chan revisionDestsUpdate),
-		transport:        tr,
-		logger:           logging.FromContext(ctx),
-		probeFrequency:   probeFreq,
+		ctx:                 ctx,
+		revisionLister:      revisioninformer.Get(ctx).Lister(),
+		serviceLister:       serviceinformer.Get(ctx).Lister(),
+		endpointsLister:     endpointsInformer.Lister(),
+		endpointSliceLister: endpointSliceInformer.Lister(),
+		selfIP:              selfIP,
+		revisionWatchers:    make(map[types.NamespacedName]*revisionWatcher),
+		sliceDests:          make(map[types.NamespacedName]map[string]dests),
+		updateCh:            make(chan revisionDestsUpdate),
+		transport:           tr,
+		logger:              logging.FromContext(ctx),
+		probeFrequency:      probeFreq,
+
+		staleEndpointTolerance: staleEndpointTolerance,
+		unassignedGracePeriod:  unassignedGracePeriod,
 	}
-	endpointsInformer := endpointsinformer.Get(ctx)
+	// The revision backends are tracked through the endpoint slices of the
+	// private services, which are split up on the big revisions.
+	endpointSliceInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: reconciler.LabelExistsFilterFunc(discoveryv1beta1.LabelServiceName),
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    rbm.endpointSliceUpdated,
+			UpdateFunc: controller.PassNew(rbm.endpointSliceUpdated),
+			DeleteFunc: rbm.endpointSliceDeleted,
+		},
+	})
+	// The public endpoints carry the activators assigned to the revision.
 	endpointsInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
 		FilterFunc: reconciler.ChainFilterFuncs(
-			reconciler.LabelExistsFilterFunc(serving.RevisionUID),
-			// We are only interested in the private services, since that is
-			// what is populated by the actual revision backends.
-			reconciler.LabelFilterFunc(networking.ServiceTypeKey, string(networking.ServiceTypePrivate), false),
+			reconciler.LabelExistsFilterFunc(serving.RevisionLabelKey),
+			reconciler.LabelFilterFunc(networking.ServiceTypeKey, string(networking.ServiceTypePublic), false),
 		),
 		Handler: cache.ResourceEventHandlerFuncs{
-			AddFunc:    rbm.endpointsUpdated,
-			UpdateFunc: controller.PassNew(rbm.endpointsUpdated),
-			DeleteFunc: rbm.endpointsDeleted,
+			AddFunc:    rbm.publicEndpointsUpdated,
+			UpdateFunc: controller.PassNew(rbm.publicEndpointsUpdated),
 		},
 	})
+	if staleEndpointTolerance > 0 {
+		// The endpoints lag behind the pod deletions, so drop the pods right away.
+		podinformer.Get(ctx).Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+			FilterFunc: reconciler.LabelExistsFilterFunc(serving.RevisionLabelKey),
+			Handler: cache.ResourceEventHandlerFuncs{
+				UpdateFunc: controller.PassNew(rbm.podUpdated),
+				DeleteFunc: rbm.podDeleted,
+			},
+		})
+	}
 
 	go func() {
 		// updateCh can only be closed after revisionWatchers are done running
@@ -460,7 +571,8 @@ func (rbm *revisionBackendsManager) getOrCreateRevisionWatcher(rev types.Namespa
 		}
 
 		destsCh := make(chan dests)
-		rw := newRevisionWatcher(rbm.ctx, rev, proto, rbm.updateCh, destsCh, rbm.transport, rbm.serviceLister, rbm.logger)
+		rw := newRevisionWatcher(rbm.ctx, rev, proto, rbm.updateCh, destsCh, rbm.transport, rbm.serviceLister,
+			rbm.staleEndpointTolerance, rbm.logger)
 		rbm.revisionWatchers[rev] = rw
 		go rw.run(rbm.probeFrequency)
 		return rw, nil
@@ -469,33 +581,224 @@ func (rbm *revisionBackendsManager) getOrCreateRevisionWatcher(rev types.Namespa
 	return rwCh, nil
 }
 
-// endpointsUpdated is a handler function to be used by the Endpoints informer.
-// It updates the endpoints in the RevisionBackendsManager if the hosts changed
-func (rbm *revisionBackendsManager) endpointsUpdated(newObj interface{}) {
+// endpointSliceUpdated is a handler function to be used by the EndpointSlices informer.
+// It updates the dests of the revision with the ones of the updated slice.
+func (rbm *revisionBackendsManager) endpointSliceUpdated(newObj interface{}) {
 	// Ignore the updates when we've terminated.
 	select {
 	case <-rbm.ctx.Done():
 		return
 	default:
 	}
-	endpoints := newObj.(*corev1.Endpoints)
-	revID := types.NamespacedName{Namespace: endpoints.Namespace, Name: endpoints.Labels[serving.RevisionLabelKey]}
+	slice := newObj.(*discoveryv1beta1.EndpointSlice)
+	revID, ok := rbm.sliceRevision(slice)
+	if !ok {
+		return
+	}
 	logger := rbm.logger.With(zap.Object(logkey.Key, logging.NamespacedName(revID)))
 
-	logger.Debugf("Endpoints updated: %#v", newObj)
+	logger.Debugf("EndpointSlice updated: %#v", newObj)
+
+	// The revisions still tracked, e.g. while draining, keep being updated.
+	if !isAssigned(revisionEndpoints(rbm.endpointsLister, revID, networking.ServiceTypePublic), rbm.selfIP) &&
+		!rbm.hasRevisionWatcher(revID) {
+		logger.Debug("Revision is not assigned to this activator")
+		return
+	}
 
 	rw, err := rbm.getOrCreateRevisionWatcher(revID)
 	if err != nil {
 		logger.Errorw("Failed to get revision watcher", zap.Error(err))
 		return
 	}
-	ready, notReady := endpointsToDests(endpoints, pkgnet.ServicePortName(rw.protocol))
-	logger.Debugf("Updating Endpoints: ready backends: %d, not-ready backends: %d", len(ready), len(notReady))
+	ready, notReady := endpointSliceToDests(slice, pkgnet.ServicePortName(rw.protocol))
+	d := rbm.updateSliceDests(revID, slice.Name, dests{ready: ready, notReady: notReady})
+	logger.Debugf("Updating EndpointSlice %s: ready backends: %d, not-ready backends: %d",
+		slice.Name, len(d.ready), len(d.notReady))
+	select {
+	case <-rbm.ctx.Done():
+		return
+	case rw.destsCh <- d:
+	}
+}
+
+// sliceRevision returns the revision whose private service the endpoint slice
+// belongs to. The slices don't carry the labels of their service, so the
+// service is looked up.
+func (rbm *revisionBackendsManager) sliceRevision(slice *discoveryv1beta1.EndpointSlice) (types.NamespacedName, bool) {
+	svc, err := rbm.serviceLister.Services(slice.Namespace).Get(slice.Labels[discoveryv1beta1.LabelServiceName])
+	if err != nil || svc.Labels[networking.ServiceTypeKey] != string(networking.ServiceTypePrivate) ||
+		svc.Labels[serving.RevisionLabelKey] == "" {
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: slice.Namespace, Name: svc.Labels[serving.RevisionLabelKey]}, true
+}
+
+// updateSliceDests records the dests of the revision's endpoint slice
+// and returns the dests of all of the revision's slices.
+func (rbm *revisionBackendsManager) updateSliceDests(revID types.NamespacedName, name string, d dests) dests {
+	rbm.sliceDestsMux.Lock()
+	defer rbm.sliceDestsMux.Unlock()
+	slices, ok := rbm.sliceDests[revID]
+	if !ok {
+		slices = make(map[string]dests, 1)
+		rbm.sliceDests[revID] = slices
+	}
+	slices[name] = d
+	return mergeDests(slices)
+}
+
+// removeSliceDests forgets the dests of the deleted endpoint slice and returns
+// the revision it belonged to, along with the dests of the remaining slices.
+// The returned bool is false if the slice was not tracked.
+func (rbm *revisionBackendsManager) removeSliceDests(slice *discoveryv1beta1.EndpointSlice) (types.NamespacedName, *dests, bool) {
+	rbm.sliceDestsMux.Lock()
+	defer rbm.sliceDestsMux.Unlock()
+	for revID, slices := range rbm.sliceDests {
+		if revID.Namespace != slice.Namespace {
+			continue
+		}
+		if _, ok := slices[slice.Name]; !ok {
+			continue
+		}
+		delete(slices, slice.Name)
+		if len(slices) == 0 {
+			delete(rbm.sliceDests, revID)
+			return revID, nil, true
+		}
+		d := mergeDests(slices)
+		return revID, &d, true
+	}
+	return types.NamespacedName{}, nil, false
+}
+
+// mergeDests returns the union of the dests of the slices. The pods that are
+// ready in one slice, but not in another, e.g. while moving between the
+// slices, are deemed ready.
+func mergeDests(slices map[string]dests) dests {
+	ret := dests{ready: sets.NewString(), notReady: sets.NewString()}
+	for _, d := range slices {
+		ret.ready = ret.ready.Union(d.ready)
+		ret.notReady = ret.notReady.Union(d.notReady)
+	}
+	ret.notReady = ret.notReady.Difference(ret.ready)
+	return ret
+}
+
+// publicEndpointsUpdated is a handler function to be used by the Endpoints informer.
+// It starts or stops tracking the revision backends, when this activator
+// gets assigned to or unassigned from the revision.
+func (rbm *revisionBackendsManager) publicEndpointsUpdated(newObj interface{}) {
+	// Ignore the updates when we've terminated.
+	select {
+	case <-rbm.ctx.Done():
+		return
+	default:
+	}
+	endpoints := newObj.(*corev1.Endpoints)
+	revID := types.NamespacedName{Namespace: endpoints.Namespace, Name: endpoints.Labels[serving.RevisionLabelKey]}
+
+	if !isAssigned(endpoints, rbm.selfIP) {
+		if rbm.draining.Load() {
+			// Keep tracking the backends for the requests still buffered here.
+			return
+		}
+		if rbm.hasRevisionWatcher(revID) {
+			rbm.logger.Debugw("Revision got unassigned from this activator",
+				zap.Object(logkey.Key, logging.NamespacedName(revID)))
+			// Keep tracking the backends for the requests already routed
+			// here for a while.
+			time.AfterFunc(rbm.unassignedGracePeriod, func() { rbm.forgetUnassigned(revID) })
+		}
+		return
+	}
+
+	if rbm.hasRevisionWatcher(revID) {
+		return
+	}
+	// This activator might have just been assigned to the revision,
+	// so start tracking its current backends.
+	for _, slice := range rbm.revisionEndpointSlices(revID) {
+		rbm.endpointSliceUpdated(slice)
+	}
+}
+
+// revisionEndpointSlices returns the endpoint slices of the revision's private service.
+func (rbm *revisionBackendsManager) revisionEndpointSlices(revID types.NamespacedName) []*discoveryv1beta1.EndpointSlice {
+	svcs, err := rbm.serviceLister.Services(revID.Namespace).List(labels.SelectorFromSet(labels.Set{
+		serving.RevisionLabelKey:  revID.Name,
+		networking.ServiceTypeKey: string(networking.ServiceTypePrivate),
+	}))
+	if err != nil {
+		return nil
+	}
+	var ret []*discoveryv1beta1.EndpointSlice
+	for _, svc := range svcs {
+		slices, err := rbm.endpointSliceLister.EndpointSlices(revID.Namespace).List(labels.SelectorFromSet(labels.Set{
+			discoveryv1beta1.LabelServiceName: svc.Name,
+		}))
+		if err != nil {
+			continue
+		}
+		ret = append(ret, slices...)
+	}
+	return ret
+}
+
+// podUpdated is a handler function to be used by the Pods informer.
+// It drops the pods being terminated from the revision backends.
+func (rbm *revisionBackendsManager) podUpdated(newObj interface{}) {
+	if pod := newObj.(*corev1.Pod); pod.DeletionTimestamp != nil {
+		rbm.podDeleted(pod)
+	}
+}
+
+// podDeleted is a handler function to be used by the Pods informer.
+// It drops the deleted pod from the revision backends right away, rather
+// than when the endpoints get updated.
+func (rbm *revisionBackendsManager) podDeleted(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || pod.Status.PodIP == "" {
+		return
+	}
+	revID := types.NamespacedName{Namespace: pod.Namespace, Name: pod.Labels[serving.RevisionLabelKey]}
+	rbm.revisionWatchersMux.RLock()
+	rw, ok := rbm.revisionWatchers[revID]
+	rbm.revisionWatchersMux.RUnlock()
+	if !ok {
+		return
+	}
 	select {
 	case <-rbm.ctx.Done():
+	case <-rw.stopCh:
+	case rw.deletedCh <- pod.Status.PodIP:
+	}
+}
+
+// hasRevisionWatcher returns whether the revision backends are being tracked.
+func (rbm *revisionBackendsManager) hasRevisionWatcher(revID types.NamespacedName) bool {
+	rbm.revisionWatchersMux.RLock()
+	defer rbm.revisionWatchersMux.RUnlock()
+	_, ok := rbm.revisionWatchers[revID]
+	return ok
+}
+
+// forgetUnassigned stops tracking the revision, unless it got assigned to
+// this activator again in the meantime, or the activator is draining.
+func (rbm *revisionBackendsManager) forgetUnassigned(revID types.NamespacedName) {
+	if rbm.draining.Load() ||
+		isAssigned(revisionEndpoints(rbm.endpointsLister, revID, networking.ServiceTypePublic), rbm.selfIP) {
 		return
-	case rw.destsCh <- dests{ready: ready, notReady: notReady}:
 	}
+	rbm.revisionWatchersMux.Lock()
+	defer rbm.revisionWatchersMux.Unlock()
+	rbm.deleteRevisionWatcher(revID)
+}
+
+// drain makes the manager keep tracking the revisions it currently watches,
+// regardless of their assignment to this activator.
+func (rbm *revisionBackendsManager) drain() {
+	rbm.draining.Store(true)
 }
 
 // deleteRevisionWatcher deletes the revision watcher for rev if it exists. It expects
@@ -505,20 +808,42 @@ func (rbm *revisionBackendsManager) deleteRevisionWatcher(rev types.NamespacedNa
 		rw.cancel()
 		delete(rbm.revisionWatchers, rev)
 	}
+	rbm.sliceDestsMux.Lock()
+	defer rbm.sliceDestsMux.Unlock()
+	delete(rbm.sliceDests, rev)
 }
 
-func (rbm *revisionBackendsManager) endpointsDeleted(obj interface{}) {
+// endpointSliceDeleted is a handler function to be used by the EndpointSlices informer.
+// It stops tracking the revision once all of its slices are gone.
+func (rbm *revisionBackendsManager) endpointSliceDeleted(obj interface{}) {
 	// Ignore the updates when we've terminated.
 	select {
 	case <-rbm.ctx.Done():
 		return
 	default:
 	}
-	ep := obj.(*corev1.Endpoints)
-	revID := types.NamespacedName{Namespace: ep.Namespace, Name: ep.Labels[serving.RevisionLabelKey]}
+	slice := obj.(*discoveryv1beta1.EndpointSlice)
+	revID, d, ok := rbm.removeSliceDests(slice)
+	if !ok {
+		return
+	}
 
-	rbm.logger.Debugw("Deleting endpoint", zap.Object(logkey.Key, logging.NamespacedName(revID)))
-	rbm.revisionWatchersMux.Lock()
-	defer rbm.revisionWatchersMux.Unlock()
-	rbm.deleteRevisionWatcher(revID)
+	if d == nil {
+		rbm.logger.Debugw("Deleting endpoint slices", zap.Object(logkey.Key, logging.NamespacedName(revID)))
+		rbm.revisionWatchersMux.Lock()
+		defer rbm.revisionWatchersMux.Unlock()
+		rbm.deleteRevisionWatcher(revID)
+		return
+	}
+	rbm.revisionWatchersMux.RLock()
+	rw, ok := rbm.revisionWatchers[revID]
+	rbm.revisionWatchersMux.RUnlock()
+	if !ok {
+		return
+	}
+	select {
+	case <-rbm.ctx.Done():
+	case <-rw.stopCh:
+	case rw.destsCh <- *d:
+	}
 }
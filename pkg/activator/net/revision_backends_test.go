@@ -26,9 +26,11 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 
 	pkgnet "knative.dev/networking/pkg/apis/networking"
 	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
@@ -43,6 +45,7 @@ import (
 	v1 "knative.dev/serving/pkg/apis/serving/v1"
 	fakeservingclient "knative.dev/serving/pkg/client/injection/client/fake"
 	fakerevisioninformer "knative.dev/serving/pkg/client/injection/informers/serving/v1/revision/fake"
+	fakeendpointsliceinformer "knative.dev/serving/pkg/client/injection/kube/informers/discovery/v1beta1/endpointslice/fake"
 	"knative.dev/serving/pkg/networking"
 	"knative.dev/serving/pkg/queue"
 
@@ -423,6 +426,7 @@ func TestRevisionWatcher(t *testing.T) {
 				destsCh,
 				rt,
 				informer.Lister(),
+				0, /*staleEndpointTolerance*/
 				logger,
 			)
 			rw.clusterIPHealthy = tc.initialClusterIPState
@@ -497,29 +501,46 @@ func epSubset(port int32, portName string, ips, notReadyIps []string) *corev1.En
 	return ss
 }
 
-func ep(revL string, port int32, portName string, ips ...string) *corev1.Endpoints {
+func ep(revL string, port int32, portName string, ips ...string) *discoveryv1beta1.EndpointSlice {
 	return epNotReady(revL, port, portName, ips, nil)
 }
 
-func epNotReady(revL string, port int32, portName string, readyIps, notReadyIps []string) *corev1.Endpoints {
-	return &corev1.Endpoints{
+func epNotReady(revL string, port int32, portName string, readyIps, notReadyIps []string) *discoveryv1beta1.EndpointSlice {
+	slice := &discoveryv1beta1.EndpointSlice{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: revL + "-ep",
+			Namespace: testNamespace,
+			Name:      revL + "-ep",
 			Labels: map[string]string{
-				serving.RevisionUID:       time.Now().Format("150415.000"),
-				networking.ServiceTypeKey: string(networking.ServiceTypePrivate),
-				serving.RevisionLabelKey:  revL,
+				// The private services are named after their revisions in the tests.
+				discoveryv1beta1.LabelServiceName: revL,
 			},
 		},
-		Subsets: []corev1.EndpointSubset{*epSubset(port, portName, readyIps, notReadyIps)},
+		AddressType: discoveryv1beta1.AddressTypeIPv4,
+		Ports: []discoveryv1beta1.EndpointPort{{
+			Name: ptr.String(portName),
+			Port: ptr.Int32(port),
+		}},
+	}
+	for _, ip := range readyIps {
+		slice.Endpoints = append(slice.Endpoints, discoveryv1beta1.Endpoint{
+			Addresses:  []string{ip},
+			Conditions: discoveryv1beta1.EndpointConditions{Ready: ptr.Bool(true)},
+		})
+	}
+	for _, ip := range notReadyIps {
+		slice.Endpoints = append(slice.Endpoints, discoveryv1beta1.Endpoint{
+			Addresses:  []string{ip},
+			Conditions: discoveryv1beta1.EndpointConditions{Ready: ptr.Bool(false)},
+		})
 	}
+	return slice
 }
 
 func TestRevisionBackendManagerAddEndpoint(t *testing.T) {
 	// Make sure we wait out all the jitter in the system.
 	for _, tc := range []struct {
 		name               string
-		endpointsArr       []*corev1.Endpoints
+		endpointsArr       []*discoveryv1beta1.EndpointSlice
 		revisions          []*v1.Revision
 		services           []*corev1.Service
 		probeHostResponses map[string][]activatortest.FakeResponse
@@ -527,7 +548,7 @@ func TestRevisionBackendManagerAddEndpoint(t *testing.T) {
 		updateCnt          int
 	}{{
 		name:         "add slow healthy",
-		endpointsArr: []*corev1.Endpoints{ep(testRevision, 1234, "http", "128.0.0.1")},
+		endpointsArr: []*discoveryv1beta1.EndpointSlice{ep(testRevision, 1234, "http", "128.0.0.1")},
 		revisions: []*v1.Revision{
 			revisionCC1(types.NamespacedName{Namespace: testNamespace, Name: testRevision}, pkgnet.ProtocolHTTP1),
 		},
@@ -555,7 +576,7 @@ func TestRevisionBackendManagerAddEndpoint(t *testing.T) {
 		updateCnt: 1,
 	}, {
 		name:         "add slow ready http2",
-		endpointsArr: []*corev1.Endpoints{ep(testRevision, 1234, "http2", "128.0.0.1")},
+		endpointsArr: []*discoveryv1beta1.EndpointSlice{ep(testRevision, 1234, "http2", "128.0.0.1")},
 		revisions: []*v1.Revision{
 			revisionCC1(types.NamespacedName{Namespace: testNamespace, Name: testRevision}, pkgnet.ProtocolH2C),
 		},
@@ -583,7 +604,7 @@ func TestRevisionBackendManagerAddEndpoint(t *testing.T) {
 		updateCnt: 1,
 	}, {
 		name: "multiple revisions",
-		endpointsArr: []*corev1.Endpoints{
+		endpointsArr: []*discoveryv1beta1.EndpointSlice{
 			ep("test-revision1", 1234, "http", "128.0.0.1"),
 			ep("test-revision2", 1235, "http", "128.1.0.2"),
 		},
@@ -612,7 +633,7 @@ func TestRevisionBackendManagerAddEndpoint(t *testing.T) {
 		updateCnt: 2,
 	}, {
 		name:         "no pod addressability",
-		endpointsArr: []*corev1.Endpoints{ep(testRevision, 1234, "http", "128.0.0.1")},
+		endpointsArr: []*discoveryv1beta1.EndpointSlice{ep(testRevision, 1234, "http", "128.0.0.1")},
 		revisions: []*v1.Revision{
 			revisionCC1(types.NamespacedName{Namespace: testNamespace, Name: testRevision}, pkgnet.ProtocolHTTP1),
 		},
@@ -638,7 +659,7 @@ func TestRevisionBackendManagerAddEndpoint(t *testing.T) {
 		updateCnt: 1,
 	}, {
 		name:         "unhealthy",
-		endpointsArr: []*corev1.Endpoints{ep(testRevision, 1234, "http", "128.0.0.1")},
+		endpointsArr: []*discoveryv1beta1.EndpointSlice{ep(testRevision, 1234, "http", "128.0.0.1")},
 		revisions: []*v1.Revision{
 			revisionCC1(types.NamespacedName{Namespace: testNamespace, Name: testRevision}, pkgnet.ProtocolHTTP1),
 		},
@@ -658,7 +679,7 @@ func TestRevisionBackendManagerAddEndpoint(t *testing.T) {
 		expectDests: map[types.NamespacedName]revisionDestsUpdate{},
 	}, {
 		name:         "unready pod successfully probed",
-		endpointsArr: []*corev1.Endpoints{epNotReady(testRevision, 1234, "http", nil, []string{"128.0.0.1"})},
+		endpointsArr: []*discoveryv1beta1.EndpointSlice{epNotReady(testRevision, 1234, "http", nil, []string{"128.0.0.1"})},
 		revisions: []*v1.Revision{
 			revisionCC1(types.NamespacedName{Namespace: testNamespace, Name: testRevision}, pkgnet.ProtocolHTTP1),
 		},
@@ -692,6 +713,7 @@ func TestRevisionBackendManagerAddEndpoint(t *testing.T) {
 			ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
 
 			endpointsInformer := fakeendpointsinformer.Get(ctx)
+			endpointSliceInformer := fakeendpointsliceinformer.Get(ctx)
 			serviceInformer := fakeserviceinformer.Get(ctx)
 			revisions := fakerevisioninformer.Get(ctx)
 
@@ -706,12 +728,13 @@ func TestRevisionBackendManagerAddEndpoint(t *testing.T) {
 				serviceInformer.Informer().GetIndexer().Add(svc)
 			}
 
-			waitInformers, err := controller.RunInformers(ctx.Done(), endpointsInformer.Informer())
+			waitInformers, err := controller.RunInformers(ctx.Done(), endpointsInformer.Informer(),
+				endpointSliceInformer.Informer())
 			if err != nil {
 				t.Fatal("Failed to start informers:", err)
 			}
 
-			rbm := newRevisionBackendsManagerWithProbeFrequency(ctx, rt, probeFreq)
+			rbm := newRevisionBackendsManagerWithProbeFrequency(ctx, rt, "" /*selfIP*/, probeFreq, 0)
 			defer func() {
 				cancel()
 				waitInformers()
@@ -719,8 +742,8 @@ func TestRevisionBackendManagerAddEndpoint(t *testing.T) {
 			}()
 
 			for _, ep := range tc.endpointsArr {
-				fakekubeclient.Get(ctx).CoreV1().Endpoints(testNamespace).Create(ctx, ep, metav1.CreateOptions{})
-				endpointsInformer.Informer().GetIndexer().Add(ep)
+				fakekubeclient.Get(ctx).DiscoveryV1beta1().EndpointSlices(testNamespace).Create(ctx, ep, metav1.CreateOptions{})
+				endpointSliceInformer.Informer().GetIndexer().Add(ep)
 			}
 
 			revDests := make(map[types.NamespacedName]revisionDestsUpdate)
@@ -1155,9 +1178,9 @@ func TestRevisionDeleted(t *testing.T) {
 	si := fakeserviceinformer.Get(ctx)
 	si.Informer().GetIndexer().Add(svc)
 
-	ei := fakeendpointsinformer.Get(ctx)
+	ei := fakeendpointsliceinformer.Get(ctx)
 	ep := ep(testRevision, 1234, "http", "128.0.0.1")
-	fakekubeclient.Get(ctx).CoreV1().Endpoints(testNamespace).Create(ctx, ep, metav1.CreateOptions{})
+	fakekubeclient.Get(ctx).DiscoveryV1beta1().EndpointSlices(testNamespace).Create(ctx, ep, metav1.CreateOptions{})
 	waitInformers, err := controller.RunInformers(ctx.Done(), ei.Informer())
 	if err != nil {
 		t.Fatal("Failed to start informers:", err)
@@ -1169,7 +1192,7 @@ func TestRevisionDeleted(t *testing.T) {
 	ri.Informer().GetIndexer().Add(rev)
 
 	fakeRT := activatortest.FakeRoundTripper{}
-	rbm := newRevisionBackendsManagerWithProbeFrequency(ctx, network.RoundTripperFunc(fakeRT.RT), probeFreq)
+	rbm := newRevisionBackendsManagerWithProbeFrequency(ctx, network.RoundTripperFunc(fakeRT.RT), "" /*selfIP*/, probeFreq, 0)
 	defer func() {
 		cancel()
 		waitInformers()
@@ -1183,8 +1206,8 @@ func TestRevisionDeleted(t *testing.T) {
 	case <-time.After(updateTimeout):
 		t.Error("Timedout waiting for initial response")
 	}
-	// Now delete the endpoints.
-	fakekubeclient.Get(ctx).CoreV1().Endpoints(testNamespace).Delete(ctx, ep.Name, metav1.DeleteOptions{})
+	// Now delete the endpoint slice.
+	fakekubeclient.Get(ctx).DiscoveryV1beta1().EndpointSlices(testNamespace).Delete(ctx, ep.Name, metav1.DeleteOptions{})
 	select {
 	case r := <-rbm.updates():
 		t.Errorf("Unexpected update: %#v", r)
@@ -1193,13 +1216,202 @@ func TestRevisionDeleted(t *testing.T) {
 	}
 }
 
+func TestRevisionBackendManagerAssignment(t *testing.T) {
+	const selfIP = "10.0.0.1"
+	ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
+
+	svc := privateSKSService(
+		types.NamespacedName{Namespace: testNamespace, Name: testRevision},
+		"129.0.0.1",
+		[]corev1.ServicePort{{Name: "http", Port: 1234}},
+	)
+	fakekubeclient.Get(ctx).CoreV1().Services(testNamespace).Create(ctx, svc, metav1.CreateOptions{})
+	fakeserviceinformer.Get(ctx).Informer().GetIndexer().Add(svc)
+
+	rev := revisionCC1(types.NamespacedName{Namespace: testNamespace, Name: testRevision}, pkgnet.ProtocolHTTP1)
+	fakeservingclient.Get(ctx).ServingV1().Revisions(testNamespace).Create(ctx, rev, metav1.CreateOptions{})
+	fakerevisioninformer.Get(ctx).Informer().GetIndexer().Add(rev)
+
+	// This activator is not assigned to the revision.
+	pubEps := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testNamespace,
+			Name:      testRevision,
+			Labels: map[string]string{
+				networking.ServiceTypeKey: string(networking.ServiceTypePublic),
+				serving.RevisionLabelKey:  testRevision,
+			},
+			Annotations: map[string]string{
+				networking.ActivatorsAnnotationKey: "10.0.0.2,10.0.0.3",
+			},
+		},
+	}
+	pvtEps := ep(testRevision, 1234, "http", "128.0.0.1")
+	kc := fakekubeclient.Get(ctx)
+	kc.CoreV1().Endpoints(testNamespace).Create(ctx, pubEps, metav1.CreateOptions{})
+	kc.DiscoveryV1beta1().EndpointSlices(testNamespace).Create(ctx, pvtEps, metav1.CreateOptions{})
+
+	ei := fakeendpointsinformer.Get(ctx)
+	waitInformers, err := controller.RunInformers(ctx.Done(), ei.Informer(), fakeendpointsliceinformer.Get(ctx).Informer())
+	if err != nil {
+		t.Fatal("Failed to start informers:", err)
+	}
+
+	fakeRT := activatortest.FakeRoundTripper{
+		ExpectHost: testRevision,
+		ProbeHostResponses: map[string][]activatortest.FakeResponse{
+			"129.0.0.1:1234": {{
+				Err: errors.New("clusterIP transport error"),
+			}},
+			"128.0.0.1:1234": {{
+				Code: http.StatusOK,
+				Body: queue.Name,
+			}},
+		},
+	}
+	rbm := newRevisionBackendsManagerWithProbeFrequency(ctx, network.RoundTripperFunc(fakeRT.RT), selfIP, probeFreq, 0)
+	rbm.unassignedGracePeriod = 0
+	defer func() {
+		cancel()
+		waitInformers()
+		waitForRevisionBackedManager(t, rbm)
+	}()
+
+	select {
+	case x := <-rbm.updates():
+		t.Error("Unexpected update for the unassigned revision:", x)
+	case <-time.After(updateTimeout):
+	}
+
+	// Now assign this activator to the revision.
+	pubEps = pubEps.DeepCopy()
+	pubEps.Annotations[networking.ActivatorsAnnotationKey] = "10.0.0.1,10.0.0.2"
+	kc.CoreV1().Endpoints(testNamespace).Update(ctx, pubEps, metav1.UpdateOptions{})
+
+	select {
+	case x := <-rbm.updates():
+		if got, want := x.Dests, sets.NewString("128.0.0.1:1234"); !got.Equal(want) {
+			t.Errorf("Dests = %v, want: %v", got, want)
+		}
+	case <-time.After(updateTimeout):
+		t.Fatal("Timed out waiting for the update for the assigned revision")
+	}
+
+	// And unassign it again.
+	pubEps = pubEps.DeepCopy()
+	pubEps.Annotations[networking.ActivatorsAnnotationKey] = "10.0.0.2,10.0.0.3"
+	kc.CoreV1().Endpoints(testNamespace).Update(ctx, pubEps, metav1.UpdateOptions{})
+	if err := wait.PollImmediate(10*time.Millisecond, updateTimeout, func() (bool, error) {
+		rbm.revisionWatchersMux.RLock()
+		defer rbm.revisionWatchersMux.RUnlock()
+		return len(rbm.revisionWatchers) == 0, nil
+	}); err != nil {
+		t.Error("The revision watcher was not removed for the unassigned revision")
+	}
+}
+
+func TestRevisionBackendManagerDrain(t *testing.T) {
+	const selfIP = "10.0.0.1"
+	ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
+
+	svc := privateSKSService(
+		types.NamespacedName{Namespace: testNamespace, Name: testRevision},
+		"129.0.0.1",
+		[]corev1.ServicePort{{Name: "http", Port: 1234}},
+	)
+	fakekubeclient.Get(ctx).CoreV1().Services(testNamespace).Create(ctx, svc, metav1.CreateOptions{})
+	fakeserviceinformer.Get(ctx).Informer().GetIndexer().Add(svc)
+
+	rev := revisionCC1(types.NamespacedName{Namespace: testNamespace, Name: testRevision}, pkgnet.ProtocolHTTP1)
+	fakeservingclient.Get(ctx).ServingV1().Revisions(testNamespace).Create(ctx, rev, metav1.CreateOptions{})
+	fakerevisioninformer.Get(ctx).Informer().GetIndexer().Add(rev)
+
+	pubEps := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testNamespace,
+			Name:      testRevision,
+			Labels: map[string]string{
+				networking.ServiceTypeKey: string(networking.ServiceTypePublic),
+				serving.RevisionLabelKey:  testRevision,
+			},
+			Annotations: map[string]string{
+				networking.ActivatorsAnnotationKey: "10.0.0.1,10.0.0.2",
+			},
+		},
+	}
+	pvtEps := ep(testRevision, 1234, "http", "128.0.0.1")
+	kc := fakekubeclient.Get(ctx)
+	kc.CoreV1().Endpoints(testNamespace).Create(ctx, pubEps, metav1.CreateOptions{})
+	kc.DiscoveryV1beta1().EndpointSlices(testNamespace).Create(ctx, pvtEps, metav1.CreateOptions{})
+
+	ei := fakeendpointsinformer.Get(ctx)
+	waitInformers, err := controller.RunInformers(ctx.Done(), ei.Informer(), fakeendpointsliceinformer.Get(ctx).Informer())
+	if err != nil {
+		t.Fatal("Failed to start informers:", err)
+	}
+
+	fakeRT := activatortest.FakeRoundTripper{
+		ExpectHost: testRevision,
+		ProbeHostResponses: map[string][]activatortest.FakeResponse{
+			"129.0.0.1:1234": {{
+				Err: errors.New("clusterIP transport error"),
+			}},
+			"128.0.0.1:1234": {{
+				Code: http.StatusOK,
+				Body: queue.Name,
+			}},
+			"128.0.0.2:1234": {{
+				Code: http.StatusOK,
+				Body: queue.Name,
+			}},
+		},
+	}
+	rbm := newRevisionBackendsManagerWithProbeFrequency(ctx, network.RoundTripperFunc(fakeRT.RT), selfIP, probeFreq, 0)
+	defer func() {
+		cancel()
+		waitInformers()
+		waitForRevisionBackedManager(t, rbm)
+	}()
+
+	select {
+	case <-rbm.updates():
+	case <-time.After(updateTimeout):
+		t.Fatal("Timed out waiting for the update for the assigned revision")
+	}
+
+	rbm.drain()
+
+	// Unassign this activator from the revision.
+	pubEps = pubEps.DeepCopy()
+	pubEps.Annotations[networking.ActivatorsAnnotationKey] = "10.0.0.2,10.0.0.3"
+	kc.CoreV1().Endpoints(testNamespace).Update(ctx, pubEps, metav1.UpdateOptions{})
+	if err := wait.PollImmediate(10*time.Millisecond, updateTimeout, func() (bool, error) {
+		got, err := ei.Lister().Endpoints(testNamespace).Get(testRevision)
+		return err == nil && got.Annotations[networking.ActivatorsAnnotationKey] == "10.0.0.2,10.0.0.3", nil
+	}); err != nil {
+		t.Fatal("Timed out waiting for the public endpoints to be updated")
+	}
+
+	// The backends are still tracked while draining.
+	pvtEps = ep(testRevision, 1234, "http", "128.0.0.1", "128.0.0.2")
+	kc.DiscoveryV1beta1().EndpointSlices(testNamespace).Update(ctx, pvtEps, metav1.UpdateOptions{})
+	select {
+	case x := <-rbm.updates():
+		if got, want := x.Dests, sets.NewString("128.0.0.1:1234", "128.0.0.2:1234"); !got.Equal(want) {
+			t.Errorf("Dests = %v, want: %v", got, want)
+		}
+	case <-time.After(updateTimeout):
+		t.Fatal("Timed out waiting for the update for the draining revision")
+	}
+}
+
 func TestServiceDoesNotExist(t *testing.T) {
 	// Tests when the service is not available.
 	ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
 
-	ei := fakeendpointsinformer.Get(ctx)
+	ei := fakeendpointsliceinformer.Get(ctx)
 	eps := ep(testRevision, 1234, "http", "128.0.0.1")
-	fakekubeclient.Get(ctx).CoreV1().Endpoints(testNamespace).Create(ctx, eps, metav1.CreateOptions{})
+	fakekubeclient.Get(ctx).DiscoveryV1beta1().EndpointSlices(testNamespace).Create(ctx, eps, metav1.CreateOptions{})
 	waitInformers, err := controller.RunInformers(ctx.Done(), ei.Informer())
 	if err != nil {
 		t.Fatal("Failed to start informers:", err)
@@ -1225,7 +1437,7 @@ func TestServiceDoesNotExist(t *testing.T) {
 			}},
 		},
 	}
-	rbm := newRevisionBackendsManagerWithProbeFrequency(ctx, network.RoundTripperFunc(fakeRT.RT), probeFreq)
+	rbm := newRevisionBackendsManagerWithProbeFrequency(ctx, network.RoundTripperFunc(fakeRT.RT), "" /*selfIP*/, probeFreq, 0)
 	defer func() {
 		cancel()
 		waitInformers()
@@ -1248,9 +1460,9 @@ func TestServiceMoreThanOne(t *testing.T) {
 	// Tests when the service is not available.
 	ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
 
-	ei := fakeendpointsinformer.Get(ctx)
+	ei := fakeendpointsliceinformer.Get(ctx)
 	eps := ep(testRevision, 1234, "http", "128.0.0.1")
-	fakekubeclient.Get(ctx).CoreV1().Endpoints(testNamespace).Create(ctx, eps, metav1.CreateOptions{})
+	fakekubeclient.Get(ctx).DiscoveryV1beta1().EndpointSlices(testNamespace).Create(ctx, eps, metav1.CreateOptions{})
 	waitInformers, err := controller.RunInformers(ctx.Done(), ei.Informer())
 	if err != nil {
 		t.Fatal("Failed to start informers:", err)
@@ -1260,6 +1472,8 @@ func TestServiceMoreThanOne(t *testing.T) {
 	ri := fakerevisioninformer.Get(ctx)
 	ri.Informer().GetIndexer().Add(rev)
 
+	// Point the slice at one of them.
+	eps.Labels[discoveryv1beta1.LabelServiceName] = testRevision + "11"
 	// Now let's create two!
 	for _, num := range []string{"11", "12"} {
 		svc := privateSKSService(
@@ -1289,7 +1503,7 @@ func TestServiceMoreThanOne(t *testing.T) {
 			}},
 		},
 	}
-	rbm := newRevisionBackendsManagerWithProbeFrequency(ctx, network.RoundTripperFunc(fakeRT.RT), probeFreq)
+	rbm := newRevisionBackendsManagerWithProbeFrequency(ctx, network.RoundTripperFunc(fakeRT.RT), "" /*selfIP*/, probeFreq, 0)
 	defer func() {
 		cancel()
 		waitInformers()
@@ -1306,3 +1520,144 @@ func TestServiceMoreThanOne(t *testing.T) {
 	case <-time.After(updateTimeout):
 	}
 }
+
+func TestRevisionWatcherWithoutDeleted(t *testing.T) {
+	const tolerance = 30 * time.Second
+	now := time.Now()
+	tests := []struct {
+		name        string
+		deleted     map[string]time.Time
+		dests       dests
+		want        dests
+		wantDeleted map[string]time.Time
+	}{{
+		name: "nothing deleted",
+		dests: dests{
+			ready:    sets.NewString("128.0.0.1:1234", "128.0.0.2:1234"),
+			notReady: sets.NewString("128.0.0.3:1234"),
+		},
+		want: dests{
+			ready:    sets.NewString("128.0.0.1:1234", "128.0.0.2:1234"),
+			notReady: sets.NewString("128.0.0.3:1234"),
+		},
+		wantDeleted: map[string]time.Time{},
+	}, {
+		name:    "deleted pods dropped",
+		deleted: map[string]time.Time{"128.0.0.2": now, "128.0.0.3": now.Add(-time.Second)},
+		dests: dests{
+			ready:    sets.NewString("128.0.0.1:1234", "128.0.0.2:1234"),
+			notReady: sets.NewString("128.0.0.3:1234"),
+		},
+		want: dests{
+			ready:    sets.NewString("128.0.0.1:1234"),
+			notReady: sets.NewString(),
+		},
+		wantDeleted: map[string]time.Time{"128.0.0.2": now, "128.0.0.3": now.Add(-time.Second)},
+	}, {
+		name:    "endpoints caught up",
+		deleted: map[string]time.Time{"128.0.0.2": now},
+		dests: dests{
+			ready:    sets.NewString("128.0.0.1:1234"),
+			notReady: sets.NewString(),
+		},
+		want: dests{
+			ready:    sets.NewString("128.0.0.1:1234"),
+			notReady: sets.NewString(),
+		},
+		wantDeleted: map[string]time.Time{},
+	}, {
+		name:    "tolerance exceeded",
+		deleted: map[string]time.Time{"128.0.0.2": now.Add(-tolerance - time.Second)},
+		dests: dests{
+			ready:    sets.NewString("128.0.0.1:1234", "128.0.0.2:1234"),
+			notReady: sets.NewString(),
+		},
+		want: dests{
+			ready:    sets.NewString("128.0.0.1:1234", "128.0.0.2:1234"),
+			notReady: sets.NewString(),
+		},
+		wantDeleted: map[string]time.Time{},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rw := &revisionWatcher{
+				deleted:                map[string]time.Time{},
+				staleEndpointTolerance: tolerance,
+			}
+			for ip, ts := range tc.deleted {
+				rw.deleted[ip] = ts
+			}
+			got := rw.withoutDeleted(tc.dests, now)
+			if !cmp.Equal(got, tc.want, cmp.AllowUnexported(dests{})) {
+				t.Error("Dests mismatch (-want, +got):", cmp.Diff(tc.want, got, cmp.AllowUnexported(dests{})))
+			}
+			if !cmp.Equal(rw.deleted, tc.wantDeleted) {
+				t.Error("Deleted mismatch (-want, +got):", cmp.Diff(tc.wantDeleted, rw.deleted))
+			}
+		})
+	}
+}
+
+func TestRevisionBackendManagerSliceDests(t *testing.T) {
+	rbm := &revisionBackendsManager{
+		sliceDests: make(map[types.NamespacedName]map[string]dests),
+	}
+	revID := types.NamespacedName{Namespace: testNamespace, Name: testRevision}
+
+	rbm.updateSliceDests(revID, "slice-1", dests{
+		ready:    sets.NewString("128.0.0.1:1234"),
+		notReady: sets.NewString(),
+	})
+	got := rbm.updateSliceDests(revID, "slice-2", dests{
+		ready:    sets.NewString("128.0.0.2:1234"),
+		notReady: sets.NewString("128.0.0.3:1234"),
+	})
+	want := dests{
+		ready:    sets.NewString("128.0.0.1:1234", "128.0.0.2:1234"),
+		notReady: sets.NewString("128.0.0.3:1234"),
+	}
+	if !cmp.Equal(got, want, cmp.AllowUnexported(dests{})) {
+		t.Error("Dests mismatch (-want, +got):", cmp.Diff(want, got, cmp.AllowUnexported(dests{})))
+	}
+
+	// Only the updated slice's dests change.
+	got = rbm.updateSliceDests(revID, "slice-1", dests{
+		ready:    sets.NewString(),
+		notReady: sets.NewString("128.0.0.1:1234"),
+	})
+	want = dests{
+		ready:    sets.NewString("128.0.0.2:1234"),
+		notReady: sets.NewString("128.0.0.1:1234", "128.0.0.3:1234"),
+	}
+	if !cmp.Equal(got, want, cmp.AllowUnexported(dests{})) {
+		t.Error("Dests mismatch (-want, +got):", cmp.Diff(want, got, cmp.AllowUnexported(dests{})))
+	}
+
+	slice := func(name string) *discoveryv1beta1.EndpointSlice {
+		return &discoveryv1beta1.EndpointSlice{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: name},
+		}
+	}
+	if _, _, ok := rbm.removeSliceDests(slice("unknown")); ok {
+		t.Error("removeSliceDests found an unknown slice")
+	}
+	gotRev, gotDests, ok := rbm.removeSliceDests(slice("slice-2"))
+	if !ok || gotRev != revID || gotDests == nil {
+		t.Fatalf("removeSliceDests = %v, %v, %v, want: %v with the remaining dests", gotRev, gotDests, ok, revID)
+	}
+	want = dests{
+		ready:    sets.NewString(),
+		notReady: sets.NewString("128.0.0.1:1234"),
+	}
+	if !cmp.Equal(*gotDests, want, cmp.AllowUnexported(dests{})) {
+		t.Error("Dests mismatch (-want, +got):", cmp.Diff(want, *gotDests, cmp.AllowUnexported(dests{})))
+	}
+	// The last slice is gone.
+	if gotRev, gotDests, ok = rbm.removeSliceDests(slice("slice-1")); !ok || gotRev != revID || gotDests != nil {
+		t.Errorf("removeSliceDests = %v, %v, %v, want: %v without dests", gotRev, gotDests, ok, revID)
+	}
+	if len(rbm.sliceDests) != 0 {
+		t.Error("The revision's slices were not forgotten:", rbm.sliceDests)
+	}
+}
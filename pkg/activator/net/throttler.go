@@ -22,6 +22,7 @@ import (
 	"math/rand"
 	"sort"
 	"sync"
+	"time"
 
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
@@ -40,6 +41,7 @@ import (
 	"knative.dev/pkg/logging/logkey"
 	"knative.dev/pkg/network"
 	"knative.dev/pkg/reconciler"
+	"knative.dev/serving/pkg/activator"
 	"knative.dev/serving/pkg/activator/util"
 	"knative.dev/serving/pkg/apis/serving"
 	v1 "knative.dev/serving/pkg/apis/serving/v1"
@@ -61,8 +63,32 @@ const (
 	// requires an explicit buffer size (it's backed by a chan struct{}), but
 	// queue.MaxBreakerCapacity is math.MaxInt32.
 	revisionMaxConcurrency = queue.MaxBreakerCapacity
+
+	// unassignedGracePeriod is how long the state of a revision is kept after
+	// it got unassigned from this activator, for the requests that were
+	// already routed here to still be proxied. That is as long as the
+	// ingress takes to notice the activator is draining.
+	unassignedGracePeriod = network.DefaultDrainTimeout
 )
 
+// QueueLimits bound the requests waiting in the activator for
+// capacity of a single revision, e.g. while it is scaling from zero.
+// The revisions may override the limits, see
+// serving.ActivatorQueueDepthAnnotationKey and
+// serving.ActivatorQueueTimeoutAnnotationKey.
+type QueueLimits struct {
+	// MaxDepth is the maximum number of requests waiting for capacity.
+	// Zero means no limit.
+	MaxDepth int
+	// Timeout is the maximum time a request waits for capacity.
+	// Zero means no limit, other than the request's own deadline.
+	Timeout time.Duration
+	// RejectOverflow makes the requests fail with queue.ErrCapacityExceeded,
+	// rather than wait, when there is no capacity for them right away.
+	// It is set per revision, see serving.OverflowPolicyAnnotationKey.
+	RejectOverflow bool
+}
+
 func newPodTracker(dest string, b breaker) *podTracker {
 	tracker := &podTracker{
 		dest: dest,
@@ -76,6 +102,8 @@ func newPodTracker(dest string, b breaker) *podTracker {
 type podTracker struct {
 	dest string
 	b    breaker
+	// cb is the circuit breaker of the destination, nil if disabled.
+	cb *circuitBreaker
 
 	// weight is used for LB policy implementations.
 	weight atomic.Int32
@@ -132,6 +160,16 @@ type revisionThrottler struct {
 	containerConcurrency int
 	lbPolicy             lbPolicy
 
+	// sessionAffinity is true if the revision requested the requests of the same
+	// session to be routed to the same pod. The session keys are mapped to the
+	// pods assigned to this activator, so that the activators don't overcommit
+	// the pods; the same session thus sticks to the same pod per activator.
+	sessionAffinity bool
+
+	// standby is the number of warm standby pods of the revision.
+	// These pods receive requests only once the rest of the pods are saturated.
+	standby int
+
 	// These are used in slicing to infer which pods to assign
 	// to this activator.
 	numActivators atomic.Int32
@@ -149,6 +187,14 @@ type revisionThrottler struct {
 	// This is a breaker for the revision as a whole.
 	breaker breaker
 
+	// queueLimits bound the requests waiting for capacity and
+	// queued is the number of requests currently waiting.
+	queueLimits QueueLimits
+	queued      atomic.Int32
+
+	// cbParams configure the circuit breakers of the pod trackers.
+	cbParams CircuitBreakerParams
+
 	// This will be non-empty when we're able to use pod addressing.
 	podTrackers []*podTracker
 
@@ -156,6 +202,10 @@ type revisionThrottler struct {
 	// This is a subset of podIPTrackers.
 	assignedTrackers []*podTracker
 
+	// Standby trackers that are assigned to this Activator.
+	// This is a subset of podTrackers, disjoint with assignedTrackers.
+	standbyTrackers []*podTracker
+
 	// If we don't have a healthy clusterIPTracker this is set to nil, otherwise
 	// it is the l4dest for this revision's private clusterIP.
 	clusterIPTracker *podTracker
@@ -168,8 +218,8 @@ type revisionThrottler struct {
 }
 
 func newRevisionThrottler(revID types.NamespacedName,
-	containerConcurrency int, proto string,
-	breakerParams queue.BreakerParams,
+	containerConcurrency int, proto string, sessionAffinity bool, standby int,
+	breakerParams queue.BreakerParams, queueLimits QueueLimits, cbParams CircuitBreakerParams,
 	logger *zap.SugaredLogger) *revisionThrottler {
 	logger = logger.With(zap.Object(logkey.Key, logging.NamespacedName(revID)))
 	var (
@@ -189,10 +239,17 @@ func newRevisionThrottler(revID types.NamespacedName,
 		revBreaker = queue.NewBreaker(breakerParams)
 		lbp = newRoundRobinPolicy()
 	}
+	if sessionAffinity {
+		lbp = newSessionAffinityPolicy(lbp)
+	}
 	return &revisionThrottler{
 		revID:                revID,
 		containerConcurrency: containerConcurrency,
+		sessionAffinity:      sessionAffinity,
+		standby:              standby,
 		breaker:              revBreaker,
+		queueLimits:          queueLimits,
+		cbParams:             cbParams,
 		logger:               logger,
 		protocol:             proto,
 		activatorIndex:       *atomic.NewInt32(-1), // Start with unknown.
@@ -211,10 +268,67 @@ func (rt *revisionThrottler) acquireDest(ctx context.Context) (func(), *podTrack
 	if rt.clusterIPTracker != nil {
 		return noop, rt.clusterIPTracker
 	}
-	return rt.lbPolicy(ctx, rt.assignedTrackers)
+	cb, tracker := rt.lbPolicy(ctx, rt.availableTrackers(rt.assignedTrackers))
+	if tracker == nil && len(rt.standbyTrackers) > 0 {
+		// All the active pods are saturated, flip in the standby ones.
+		return rt.lbPolicy(ctx, rt.availableTrackers(rt.standbyTrackers))
+	}
+	return cb, tracker
+}
+
+// availableTrackers filters out the trackers whose circuit breakers are open.
+// Should all of them be open, all of them are returned, since failing the
+// requests outright is no better than trying the failing destinations.
+func (rt *revisionThrottler) availableTrackers(trackers []*podTracker) []*podTracker {
+	if !rt.cbParams.enabled() {
+		return trackers
+	}
+	now := time.Now()
+	ret := make([]*podTracker, 0, len(trackers))
+	for _, t := range trackers {
+		if t.cb == nil || t.cb.available(now) {
+			ret = append(ret, t)
+		}
+	}
+	if len(ret) == 0 {
+		return trackers
+	}
+	return ret
 }
 
 func (rt *revisionThrottler) try(ctx context.Context, function func(string) error) error {
+	if rt.queueLimits.RejectOverflow {
+		return rt.tryNow(ctx, function)
+	}
+	if stats := util.ProxyStatsFrom(ctx); stats != nil {
+		// No capacity means the revision is scaled to zero, or still activating.
+		stats.ColdStart = rt.breaker.Capacity() == 0
+	}
+	if max := rt.queueLimits.MaxDepth; max > 0 {
+		if int(rt.queued.Inc()) > max {
+			rt.queued.Dec()
+			return queue.ErrRequestQueueDepthExceeded
+		}
+	}
+	// dequeue is called once the request stops waiting for capacity.
+	var dequeued bool
+	dequeue := func() {
+		if !dequeued && rt.queueLimits.MaxDepth > 0 {
+			rt.queued.Dec()
+		}
+		dequeued = true
+	}
+	defer dequeue()
+
+	// Only the wait for capacity is bounded by the queue timeout,
+	// not the proxying of the request.
+	waitCtx := ctx
+	if rt.queueLimits.Timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, rt.queueLimits.Timeout)
+		defer cancel()
+	}
+
 	var ret error
 
 	// Retrying infinitely as long as we receive no dest. Outer semaphore and inner
@@ -223,8 +337,8 @@ func (rt *revisionThrottler) try(ctx context.Context, function func(string) erro
 	reenqueue := true
 	for reenqueue {
 		reenqueue = false
-		if err := rt.breaker.Maybe(ctx, func() {
-			cb, tracker := rt.acquireDest(ctx)
+		if err := rt.breaker.Maybe(waitCtx, func() {
+			cb, tracker := rt.acquireDest(waitCtx)
 			if tracker == nil {
 				// This can happen if individual requests raced each other or if pod
 				// capacity was decreased after passing the outer semaphore.
@@ -232,15 +346,46 @@ func (rt *revisionThrottler) try(ctx context.Context, function func(string) erro
 				return
 			}
 			defer cb()
+			dequeue()
 			// We already reserved a guaranteed spot. So just execute the passed functor.
 			ret = function(tracker.dest)
+			if tracker.cb != nil {
+				tracker.cb.record(time.Now(), ret != nil)
+			}
 		}); err != nil {
+			if waitCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+				return queue.ErrRequestQueueTimeout
+			}
 			return err
 		}
 	}
 	return ret
 }
 
+// tryNow executes function, if there is capacity for the request right away,
+// and fails with queue.ErrCapacityExceeded otherwise.
+func (rt *revisionThrottler) tryNow(ctx context.Context, function func(string) error) error {
+	// The infinite breaker always reserves, even when scaled to zero.
+	if rt.breaker.Capacity() == 0 {
+		return queue.ErrCapacityExceeded
+	}
+	release, ok := rt.breaker.Reserve(ctx)
+	if !ok {
+		return queue.ErrCapacityExceeded
+	}
+	defer release()
+	cb, tracker := rt.acquireDest(ctx)
+	if tracker == nil {
+		return queue.ErrCapacityExceeded
+	}
+	defer cb()
+	ret := function(tracker.dest)
+	if tracker.cb != nil {
+		tracker.cb.record(time.Now(), ret != nil)
+	}
+	return ret
+}
+
 func (rt *revisionThrottler) calculateCapacity(size, activatorCount int) int {
 	targetCapacity := rt.containerConcurrency * size
 
@@ -293,16 +438,21 @@ func (rt *revisionThrottler) updateCapacity(backendCount int) {
 		sort.Slice(rt.podTrackers, func(i, j int) bool {
 			return rt.podTrackers[i].dest < rt.podTrackers[j].dest
 		})
-		assigned := rt.podTrackers
+		active, standby := splitStandby(rt.podTrackers, rt.standby)
+		assigned := active
 		if rt.containerConcurrency > 0 {
 			rt.resetTrackers()
-			assigned = assignSlice(rt.podTrackers, ai, ac, rt.containerConcurrency)
+			// The standby pods are sliced like the active ones, so that
+			// the Activators don't overcommit them when they are flipped in.
+			assigned = assignSlice(active, ai, ac, rt.containerConcurrency)
+			standby = assignSlice(standby, ai, ac, rt.containerConcurrency)
 		}
-		rt.logger.Debugf("Trackers %d/%d: assignment: %v", ai, ac, assigned)
+		rt.logger.Debugf("Trackers %d/%d: assignment: %v, standby: %v", ai, ac, assigned, standby)
 		// The actual write out of the assigned trackers has to be under lock.
 		rt.mux.Lock()
 		defer rt.mux.Unlock()
 		rt.assignedTrackers = assigned
+		rt.standbyTrackers = standby
 		return len(assigned)
 	}()
 
@@ -347,6 +497,20 @@ func (rt *revisionThrottler) updateThrottlerState(backendCount int, trackers []*
 	}
 }
 
+// splitStandby splits the trackers into the active and the standby ones.
+// Since trackers are sorted by address, all the Activators agree on which pods
+// are on standby. At least one pod is always kept active.
+func splitStandby(trackers []*podTracker, standby int) (active, standbys []*podTracker) {
+	if standby <= 0 || len(trackers) <= 1 {
+		return trackers, nil
+	}
+	if standby >= len(trackers) {
+		standby = len(trackers) - 1
+	}
+	split := len(trackers) - standby
+	return trackers[:split:split], trackers[split:]
+}
+
 // pickIndices picks the indices for the slicing.
 func pickIndices(numTrackers, selfIndex, numActivators int) (beginIndex, endIndex, remnants int) {
 	if numActivators > numTrackers {
@@ -412,9 +576,11 @@ func (rt *revisionThrottler) handleUpdate(update revisionDestsUpdate) {
 		zap.String("ClusterIP", update.ClusterIPDest), zap.Object("dests", logging.StringSet(update.Dests)))
 
 	// ClusterIP is not yet ready, so we want to send requests directly to the pods.
+	// Revisions with session affinity always prefer the pods, since the ClusterIP
+	// would not let us pick the pod serving the session.
 	// NB: this will not be called in parallel, thus we can build a new podIPTrackers
 	// array before taking out a lock.
-	if update.ClusterIPDest == "" {
+	if update.ClusterIPDest == "" || rt.sessionAffinity && len(update.Dests) > 0 {
 		// Create a map for fast lookup of existing trackers.
 		trackersMap := make(map[string]*podTracker, len(rt.podTrackers))
 		for _, tracker := range rt.podTrackers {
@@ -437,6 +603,9 @@ func (rt *revisionThrottler) handleUpdate(update revisionDestsUpdate) {
 						InitialCapacity: rt.containerConcurrency, // Presume full unused capacity.
 					}))
 				}
+				if rt.cbParams.enabled() {
+					tracker.cb = newCircuitBreaker(rt.cbParams)
+				}
 			}
 			trackers = append(trackers, tracker)
 		}
@@ -455,21 +624,45 @@ type Throttler struct {
 	revisionThrottlersMutex sync.RWMutex
 	revisionLister          servinglisters.RevisionLister
 	serviceLister           corev1listers.ServiceLister
+	endpointsLister         corev1listers.EndpointsLister
 	ipAddress               string // The IP address of this activator.
+	queueLimits             QueueLimits
+	cbParams                CircuitBreakerParams
+	staleEndpointTolerance  time.Duration
 	logger                  *zap.SugaredLogger
 	epsUpdateCh             chan *corev1.Endpoints
+	// unassignedGracePeriod is how long the revisions unassigned from this
+	// activator are tracked still.
+	unassignedGracePeriod time.Duration
+
+	// draining is set once the activator starts shutting down.
+	draining atomic.Bool
+	// drainMux guards rbm, so that Drain can be called concurrently with Run.
+	drainMux sync.Mutex
+	rbm      *revisionBackendsManager
 }
 
-// NewThrottler creates a new Throttler
-func NewThrottler(ctx context.Context, ipAddr string) *Throttler {
+// NewThrottler creates a new Throttler, which applies queueLimits to
+// the requests waiting for each revision and stops picking the pods
+// failing the requests as per cbParams. The pods observed being deleted are
+// not picked for staleEndpointTolerance, while the endpoints still list them.
+func NewThrottler(ctx context.Context, ipAddr string, queueLimits QueueLimits, cbParams CircuitBreakerParams,
+	staleEndpointTolerance time.Duration) *Throttler {
 	revisionInformer := revisioninformer.Get(ctx)
+	endpointsInformer := endpointsinformer.Get(ctx)
 	t := &Throttler{
 		revisionThrottlers: make(map[types.NamespacedName]*revisionThrottler),
 		revisionLister:     revisionInformer.Lister(),
 		serviceLister:      serviceinformer.Get(ctx).Lister(),
+		endpointsLister:    endpointsInformer.Lister(),
 		ipAddress:          ipAddr,
+		queueLimits:        queueLimits,
+		cbParams:           cbParams,
 		logger:             logging.FromContext(ctx),
 		epsUpdateCh:        make(chan *corev1.Endpoints),
+
+		staleEndpointTolerance: staleEndpointTolerance,
+		unassignedGracePeriod:  unassignedGracePeriod,
 	}
 
 	// Watch revisions to create throttler with backlog immediately and delete
@@ -481,8 +674,6 @@ func NewThrottler(ctx context.Context, ipAddr string) *Throttler {
 	})
 
 	// Watch activator endpoint to maintain activator count
-	endpointsInformer := endpointsinformer.Get(ctx)
-
 	// Handles public service updates.
 	endpointsInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
 		FilterFunc: reconciler.LabelFilterFunc(networking.ServiceTypeKey,
@@ -497,7 +688,14 @@ func NewThrottler(ctx context.Context, ipAddr string) *Throttler {
 
 // Run starts the throttler and blocks until the context is done.
 func (t *Throttler) Run(ctx context.Context) {
-	rbm := newRevisionBackendsManager(ctx, network.AutoTransport)
+	rbm := newRevisionBackendsManager(ctx, network.AutoTransport, t.ipAddress, t.staleEndpointTolerance)
+	rbm.unassignedGracePeriod = t.unassignedGracePeriod
+	t.drainMux.Lock()
+	t.rbm = rbm
+	if t.draining.Load() {
+		rbm.drain()
+	}
+	t.drainMux.Unlock()
 	// Update channel is closed when ctx is done.
 	t.run(rbm.updates())
 }
@@ -517,9 +715,34 @@ func (t *Throttler) run(updateCh <-chan revisionDestsUpdate) {
 	}
 }
 
-// Try waits for capacity and then executes function, passing in a l4 dest to send a request
+// Drain makes the Throttler keep serving the revisions it currently tracks,
+// even after they get assigned to the other activators, so that the requests
+// buffered by this activator can still be proxied while it shuts down.
+func (t *Throttler) Drain() {
+	t.drainMux.Lock()
+	defer t.drainMux.Unlock()
+	t.draining.Store(true)
+	if t.rbm != nil {
+		t.rbm.drain()
+	}
+}
+
+// Try waits for capacity and then executes function, passing in a l4 dest to send a request.
+// An error returned by function is accounted as a failure of the dest and returned.
+// The requests to the revisions this activator does not back fail fast with
+// activator.ErrRevisionNotAssigned.
 func (t *Throttler) Try(ctx context.Context, function func(string) error) error {
-	rt, err := t.getOrCreateRevisionThrottler(util.RevIDFrom(ctx))
+	revID := util.RevIDFrom(ctx)
+	t.revisionThrottlersMutex.RLock()
+	_, tracked := t.revisionThrottlers[revID]
+	t.revisionThrottlersMutex.RUnlock()
+	if !tracked && !t.draining.Load() &&
+		!isAssigned(revisionEndpoints(t.endpointsLister, revID, networking.ServiceTypePublic), t.ipAddress) {
+		// Nothing tracks the backends of the revision here, so the request
+		// would wait for capacity in vain.
+		return activator.ErrRevisionNotAssigned
+	}
+	rt, err := t.getOrCreateRevisionThrottler(revID)
 	if err != nil {
 		return err
 	}
@@ -545,11 +768,23 @@ func (t *Throttler) getOrCreateRevisionThrottler(revID types.NamespacedName) (*r
 		if err != nil {
 			return nil, err
 		}
+		queueLimits := t.queueLimits
+		queueLimits.RejectOverflow = rev.RejectsOverflow()
+		if depth, ok := rev.ActivatorQueueDepth(); ok {
+			queueLimits.MaxDepth = depth
+		}
+		if timeout, ok := rev.ActivatorQueueTimeout(); ok {
+			queueLimits.Timeout = timeout
+		}
 		revThrottler = newRevisionThrottler(
 			revID,
 			int(rev.Spec.GetContainerConcurrency()),
 			pkgnet.ServicePortName(rev.GetProtocol()),
+			rev.SessionAffinityEnabled(),
+			rev.StandbyScale(),
 			queue.BreakerParams{QueueDepth: breakerQueueDepth, MaxConcurrency: revisionMaxConcurrency},
+			queueLimits,
+			t.cbParams,
 			t.logger,
 		)
 		t.revisionThrottlers[revID] = revThrottler
@@ -565,6 +800,11 @@ func (t *Throttler) revisionUpdated(obj interface{}) {
 
 	t.logger.Debug("Revision update", zap.Object(logkey.Key, logging.NamespacedName(revID)))
 
+	// Only set up the backlog for the revisions this activator backs.
+	if !isAssigned(revisionEndpoints(t.endpointsLister, revID, networking.ServiceTypePublic), t.ipAddress) {
+		return
+	}
+
 	if _, err := t.getOrCreateRevisionThrottler(revID); err != nil {
 		t.logger.Errorw("Failed to get revision throttler for revision",
 			zap.Error(err), zap.Object(logkey.Key, logging.NamespacedName(revID)))
@@ -608,6 +848,22 @@ func (t *Throttler) handlePubEpsUpdate(eps *corev1.Endpoints) {
 		return
 	}
 	rev := types.NamespacedName{Name: revN, Namespace: eps.Namespace}
+	if !isAssigned(eps, t.ipAddress) {
+		if t.draining.Load() {
+			// Keep the state, since there might still be requests for the
+			// revision buffered in this activator.
+			return
+		}
+		t.revisionThrottlersMutex.RLock()
+		_, ok := t.revisionThrottlers[rev]
+		t.revisionThrottlersMutex.RUnlock()
+		if ok {
+			// This activator does not back the revision anymore, but the
+			// requests already routed here are still served for a while.
+			time.AfterFunc(t.unassignedGracePeriod, func() { t.forgetUnassigned(rev) })
+		}
+		return
+	}
 	if rt, err := t.getOrCreateRevisionThrottler(rev); err != nil {
 		logger := t.logger.With(zap.Object(logkey.Key, logging.NamespacedName(rev)))
 		if k8serrors.IsNotFound(err) {
@@ -620,6 +876,18 @@ func (t *Throttler) handlePubEpsUpdate(eps *corev1.Endpoints) {
 	}
 }
 
+// forgetUnassigned drops the state of the revision, unless it got assigned to
+// this activator again in the meantime, or the activator is draining.
+func (t *Throttler) forgetUnassigned(rev types.NamespacedName) {
+	if t.draining.Load() ||
+		isAssigned(revisionEndpoints(t.endpointsLister, rev, networking.ServiceTypePublic), t.ipAddress) {
+		return
+	}
+	t.revisionThrottlersMutex.Lock()
+	defer t.revisionThrottlersMutex.Unlock()
+	delete(t.revisionThrottlers, rev)
+}
+
 func (rt *revisionThrottler) handlePubEpsUpdate(eps *corev1.Endpoints, selfIP string) {
 	// NB: this is guaranteed to be executed on a single thread.
 	epSet := healthyAddresses(eps, rt.protocol)
@@ -41,6 +41,7 @@ import (
 	. "knative.dev/pkg/logging/testing"
 	rtesting "knative.dev/pkg/reconciler/testing"
 	_ "knative.dev/pkg/system/testing"
+	"knative.dev/serving/pkg/activator"
 	"knative.dev/serving/pkg/activator/util"
 	"knative.dev/serving/pkg/apis/serving"
 	v1 "knative.dev/serving/pkg/apis/serving/v1"
@@ -63,7 +64,7 @@ type tryResult struct {
 }
 
 func newTestThrottler(ctx context.Context) *Throttler {
-	return NewThrottler(ctx, "10.10.10.10")
+	return NewThrottler(ctx, "10.10.10.10", QueueLimits{}, CircuitBreakerParams{}, 0)
 }
 
 func TestThrottlerUpdateCapacity(t *testing.T) {
@@ -431,7 +432,7 @@ func TestThrottlerSuccesses(t *testing.T) {
 
 			updateCh := make(chan revisionDestsUpdate)
 
-			throttler := NewThrottler(ctx, "130.0.0.2")
+			throttler := NewThrottler(ctx, "130.0.0.2", QueueLimits{}, CircuitBreakerParams{}, 0)
 			var grp errgroup.Group
 			grp.Go(func() error { throttler.run(updateCh); return nil })
 			// Ensure the throttler stopped before we leave the test, so that
@@ -540,7 +541,7 @@ func TestPodAssignmentFinite(t *testing.T) {
 	defer cancel()
 
 	throttler := newTestThrottler(ctx)
-	rt := newRevisionThrottler(revName, 42 /*cc*/, pkgnet.ServicePortNameHTTP1, testBreakerParams, logger)
+	rt := newRevisionThrottler(revName, 42 /*cc*/, pkgnet.ServicePortNameHTTP1, false /*sessionAffinity*/, 0 /*standby*/, testBreakerParams, QueueLimits{}, CircuitBreakerParams{}, logger)
 	rt.numActivators.Store(4)
 	rt.activatorIndex.Store(0)
 	throttler.revisionThrottlers[revName] = rt
@@ -586,6 +587,173 @@ func TestPodAssignmentFinite(t *testing.T) {
 	}
 }
 
+func TestThrottlerSessionAffinitySlicing(t *testing.T) {
+	revName := types.NamespacedName{Namespace: testNamespace, Name: testRevision}
+
+	ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
+	defer cancel()
+
+	throttler := newTestThrottler(ctx)
+	rt := newRevisionThrottler(revName, 42 /*cc*/, pkgnet.ServicePortNameHTTP1, true /*sessionAffinity*/, 0 /*standby*/, testBreakerParams, QueueLimits{}, CircuitBreakerParams{}, TestLogger(t))
+	rt.numActivators.Store(4)
+	rt.activatorIndex.Store(0)
+	throttler.revisionThrottlers[revName] = rt
+
+	// The pods are preferred to the ClusterIP, but still sliced.
+	throttler.handleUpdate(revisionDestsUpdate{
+		Rev:           revName,
+		ClusterIPDest: "129.0.0.1:1234",
+		Dests:         sets.NewString("ip4", "ip3", "ip5", "ip2", "ip1", "ip0"),
+	})
+	assigned := trackerDestSet(rt.assignedTrackers)
+	if want := sets.NewString("ip0", "ip4", "ip5"); !assigned.Equal(want) {
+		t.Errorf("Assigned trackers = %v, want: %v, diff: %s", assigned, want, cmp.Diff(want, assigned))
+	}
+	if got, want := rt.breaker.Capacity(), 6*42/4; got != want {
+		t.Errorf("TotalCapacity = %d, want: %d", got, want)
+	}
+
+	// The sessions stick to the assigned pods.
+	for _, key := range []string{"alice", "bob", "carol"} {
+		dests := sets.NewString()
+		for i := 0; i < 5; i++ {
+			cb, tracker := rt.lbPolicy(util.WithSessionKey(ctx, key), rt.assignedTrackers)
+			cb()
+			dests.Insert(tracker.dest)
+		}
+		if dests.Len() != 1 || !assigned.HasAll(dests.List()...) {
+			t.Errorf("Session %q was routed to %v, want one of %v", key, dests, assigned)
+		}
+	}
+}
+
+func TestStandbyTrackers(t *testing.T) {
+	revName := types.NamespacedName{Namespace: testNamespace, Name: testRevision}
+
+	ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
+	defer cancel()
+
+	throttler := newTestThrottler(ctx)
+	rt := newRevisionThrottler(revName, 1 /*cc*/, pkgnet.ServicePortNameHTTP1, false /*sessionAffinity*/, 1 /*standby*/, testBreakerParams, QueueLimits{}, CircuitBreakerParams{}, TestLogger(t))
+	rt.numActivators.Store(1)
+	rt.activatorIndex.Store(0)
+	throttler.revisionThrottlers[revName] = rt
+
+	throttler.handleUpdate(revisionDestsUpdate{
+		Rev:   revName,
+		Dests: sets.NewString("ip1", "ip0"),
+	})
+	if got, want := trackerDestSet(rt.assignedTrackers), sets.NewString("ip0"); !got.Equal(want) {
+		t.Errorf("Assigned trackers = %v, want: %v", got, want)
+	}
+	if got, want := trackerDestSet(rt.standbyTrackers), sets.NewString("ip1"); !got.Equal(want) {
+		t.Errorf("Standby trackers = %v, want: %v", got, want)
+	}
+	// Standby pods still count towards the capacity.
+	if got, want := rt.breaker.Capacity(), 2; got != want {
+		t.Errorf("TotalCapacity = %d, want: %d", got, want)
+	}
+
+	// The active pod is used first.
+	cb, tracker := rt.acquireDest(context.Background())
+	if tracker == nil || tracker.dest != "ip0" {
+		t.Fatalf("First dest = %v, want: ip0", tracker)
+	}
+	defer cb()
+	// Once it's saturated, the standby one is flipped in.
+	cb, tracker = rt.acquireDest(context.Background())
+	if tracker == nil || tracker.dest != "ip1" {
+		t.Fatalf("Second dest = %v, want: ip1", tracker)
+	}
+	defer cb()
+	if _, tracker := rt.acquireDest(context.Background()); tracker != nil {
+		t.Errorf("Third dest = %v, want: nil", tracker)
+	}
+}
+
+func TestStandbyTrackersSliced(t *testing.T) {
+	revName := types.NamespacedName{Namespace: testNamespace, Name: testRevision}
+
+	ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
+	defer cancel()
+
+	for ai, want := range []struct {
+		assigned, standby sets.String
+	}{{
+		assigned: sets.NewString("ip0"),
+		standby:  sets.NewString("ip2"),
+	}, {
+		assigned: sets.NewString("ip1"),
+		standby:  sets.NewString("ip3"),
+	}} {
+		throttler := newTestThrottler(ctx)
+		rt := newRevisionThrottler(revName, 1 /*cc*/, pkgnet.ServicePortNameHTTP1, false /*sessionAffinity*/, 2 /*standby*/, testBreakerParams, QueueLimits{}, CircuitBreakerParams{}, TestLogger(t))
+		rt.numActivators.Store(2)
+		rt.activatorIndex.Store(int32(ai))
+		throttler.revisionThrottlers[revName] = rt
+
+		throttler.handleUpdate(revisionDestsUpdate{
+			Rev:   revName,
+			Dests: sets.NewString("ip0", "ip1", "ip2", "ip3"),
+		})
+		if got := trackerDestSet(rt.assignedTrackers); !got.Equal(want.assigned) {
+			t.Errorf("Activator %d: assigned trackers = %v, want: %v", ai, got, want.assigned)
+		}
+		if got := trackerDestSet(rt.standbyTrackers); !got.Equal(want.standby) {
+			t.Errorf("Activator %d: standby trackers = %v, want: %v", ai, got, want.standby)
+		}
+		// Each activator gets its share of all the pods.
+		if got, want := rt.breaker.Capacity(), 2; got != want {
+			t.Errorf("Activator %d: capacity = %d, want: %d", ai, got, want)
+		}
+	}
+}
+
+func TestSplitStandby(t *testing.T) {
+	trackers := []*podTracker{{dest: "ip0"}, {dest: "ip1"}, {dest: "ip2"}}
+	tests := []struct {
+		name        string
+		trackers    []*podTracker
+		standby     int
+		wantActive  sets.String
+		wantStandby sets.String
+	}{{
+		name:        "no standby",
+		trackers:    trackers,
+		wantActive:  sets.NewString("ip0", "ip1", "ip2"),
+		wantStandby: sets.NewString(),
+	}, {
+		name:        "one standby",
+		trackers:    trackers,
+		standby:     1,
+		wantActive:  sets.NewString("ip0", "ip1"),
+		wantStandby: sets.NewString("ip2"),
+	}, {
+		name:        "keep one active",
+		trackers:    trackers,
+		standby:     5,
+		wantActive:  sets.NewString("ip0"),
+		wantStandby: sets.NewString("ip1", "ip2"),
+	}, {
+		name:        "single pod",
+		trackers:    trackers[:1],
+		standby:     1,
+		wantActive:  sets.NewString("ip0"),
+		wantStandby: sets.NewString(),
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			active, standby := splitStandby(tc.trackers, tc.standby)
+			if got := trackerDestSet(active); !got.Equal(tc.wantActive) {
+				t.Errorf("Active = %v, want: %v", got, tc.wantActive)
+			}
+			if got := trackerDestSet(standby); !got.Equal(tc.wantStandby) {
+				t.Errorf("Standby = %v, want: %v", got, tc.wantStandby)
+			}
+		})
+	}
+}
+
 func TestPodAssignmentInfinite(t *testing.T) {
 	logger := TestLogger(t)
 	revName := types.NamespacedName{Namespace: testNamespace, Name: testRevision}
@@ -594,7 +762,7 @@ func TestPodAssignmentInfinite(t *testing.T) {
 	defer cancel()
 
 	throttler := newTestThrottler(ctx)
-	rt := newRevisionThrottler(revName, 0 /*cc*/, pkgnet.ServicePortNameHTTP1, testBreakerParams, logger)
+	rt := newRevisionThrottler(revName, 0 /*cc*/, pkgnet.ServicePortNameHTTP1, false /*sessionAffinity*/, 0 /*standby*/, testBreakerParams, QueueLimits{}, CircuitBreakerParams{}, logger)
 	throttler.revisionThrottlers[revName] = rt
 
 	update := revisionDestsUpdate{
@@ -632,6 +800,60 @@ func TestPodAssignmentInfinite(t *testing.T) {
 	}
 }
 
+func TestRevisionThrottlerCircuitBreaker(t *testing.T) {
+	revName := types.NamespacedName{Namespace: testNamespace, Name: testRevision}
+
+	ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
+	defer cancel()
+
+	throttler := newTestThrottler(ctx)
+	rt := newRevisionThrottler(revName, 0 /*cc*/, pkgnet.ServicePortNameHTTP1, false /*sessionAffinity*/, 0 /*standby*/, testBreakerParams, QueueLimits{},
+		CircuitBreakerParams{
+			ErrorThreshold: 0.5,
+			MinRequests:    2,
+			Window:         time.Minute,
+			OpenDuration:   time.Hour,
+		}, TestLogger(t))
+	throttler.revisionThrottlers[revName] = rt
+	throttler.handleUpdate(revisionDestsUpdate{
+		Rev:   revName,
+		Dests: sets.NewString("good", "bad"),
+	})
+
+	failing := sets.NewString("bad")
+	try := func() string {
+		var got string
+		rt.try(context.Background(), func(dest string) error {
+			got = dest
+			if failing.Has(dest) {
+				return errors.New("backend failure")
+			}
+			return nil
+		})
+		return got
+	}
+
+	// Send requests until the bad pod is picked twice and trips its breaker.
+	for bad := 0; bad < 2; {
+		if try() == "bad" {
+			bad++
+		}
+	}
+	for i := 0; i < 10; i++ {
+		if got := try(); got != "good" {
+			t.Fatalf("Request went to %q, want: good", got)
+		}
+	}
+
+	// Once all the pods are failing the requests are still sent to them.
+	failing.Insert("good")
+	try()
+	try()
+	if got := try(); got == "" {
+		t.Error("Request was not sent, with all the circuit breakers open")
+	}
+}
+
 func TestActivatorsIndexUpdate(t *testing.T) {
 	ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
 
@@ -653,7 +875,7 @@ func TestActivatorsIndexUpdate(t *testing.T) {
 
 	updateCh := make(chan revisionDestsUpdate)
 
-	throttler := NewThrottler(ctx, "130.0.0.2")
+	throttler := NewThrottler(ctx, "130.0.0.2", QueueLimits{}, CircuitBreakerParams{}, 0)
 	var grp errgroup.Group
 	grp.Go(func() error { throttler.run(updateCh); return nil })
 	// Ensure the throttler stopped before we leave the test, so that
@@ -728,6 +950,177 @@ func TestActivatorsIndexUpdate(t *testing.T) {
 	}
 }
 
+func TestThrottlerUnassignedRevision(t *testing.T) {
+	ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
+
+	fake := fakekubeclient.Get(ctx)
+	endpoints := fakeendpointsinformer.Get(ctx)
+	servfake := fakeservingclient.Get(ctx)
+	revisions := revisioninformer.Get(ctx)
+
+	waitInformers, err := controller.RunInformers(ctx.Done(), endpoints.Informer(), revisions.Informer())
+	if err != nil {
+		t.Fatal("Failed to start informers:", err)
+	}
+
+	revID := types.NamespacedName{Namespace: testNamespace, Name: testRevision}
+	rev := revisionCC1(revID, pkgnet.ProtocolH2C)
+	servfake.ServingV1().Revisions(rev.Namespace).Create(ctx, rev, metav1.CreateOptions{})
+	revisions.Informer().GetIndexer().Add(rev)
+
+	updateCh := make(chan revisionDestsUpdate)
+
+	throttler := NewThrottler(ctx, "130.0.0.2", QueueLimits{}, CircuitBreakerParams{}, 0)
+	throttler.unassignedGracePeriod = 0
+	var grp errgroup.Group
+	grp.Go(func() error { throttler.run(updateCh); return nil })
+	defer func() {
+		close(updateCh)
+		grp.Wait()
+		cancel()
+		waitInformers()
+	}()
+
+	if _, err := throttler.getOrCreateRevisionThrottler(revID); err != nil {
+		t.Fatal("RevisionThrottler can't be found:", err)
+	}
+
+	// The revision is assigned to other activators.
+	publicEp := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testRevision,
+			Namespace: testNamespace,
+			Labels: map[string]string{
+				networking.ServiceTypeKey: string(networking.ServiceTypePublic),
+				serving.RevisionLabelKey:  testRevision,
+			},
+			Annotations: map[string]string{
+				networking.ActivatorsAnnotationKey: "130.0.0.1,130.0.0.3",
+			},
+		},
+		Subsets: []corev1.EndpointSubset{
+			*epSubset(8013, "http2", []string{"130.0.0.1", "130.0.0.3"}, nil),
+		},
+	}
+	fake.CoreV1().Endpoints(testNamespace).Create(ctx, publicEp, metav1.CreateOptions{})
+	endpoints.Informer().GetIndexer().Add(publicEp)
+
+	// Verify the state for the revision is dropped.
+	if err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		throttler.revisionThrottlersMutex.RLock()
+		defer throttler.revisionThrottlersMutex.RUnlock()
+		_, ok := throttler.revisionThrottlers[revID]
+		return !ok, nil
+	}); err != nil {
+		t.Fatal("Timed out waiting for the revision throttler to be removed")
+	}
+
+	// And the revision updates don't recreate it.
+	throttler.revisionUpdated(rev)
+	throttler.revisionThrottlersMutex.RLock()
+	_, ok := throttler.revisionThrottlers[revID]
+	throttler.revisionThrottlersMutex.RUnlock()
+	if ok {
+		t.Error("Revision throttler was created for the unassigned revision")
+	}
+
+	// The requests to it fail fast, rather than waiting for capacity.
+	tryCtx := util.WithRevID(ctx, revID)
+	if err := throttler.Try(tryCtx, func(string) error { return nil }); err != activator.ErrRevisionNotAssigned {
+		t.Errorf("Try() = %v, want: %v", err, activator.ErrRevisionNotAssigned)
+	}
+}
+
+func TestThrottlerUnassignedRevisionGracePeriod(t *testing.T) {
+	ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
+	defer cancel()
+
+	revID := types.NamespacedName{Namespace: testNamespace, Name: testRevision}
+	rev := revisionCC1(revID, pkgnet.ProtocolH2C)
+	fakeservingclient.Get(ctx).ServingV1().Revisions(rev.Namespace).Create(ctx, rev, metav1.CreateOptions{})
+	revisioninformer.Get(ctx).Informer().GetIndexer().Add(rev)
+
+	throttler := NewThrottler(ctx, "130.0.0.2", QueueLimits{}, CircuitBreakerParams{}, 0)
+	throttler.unassignedGracePeriod = 200 * time.Millisecond
+	want, err := throttler.getOrCreateRevisionThrottler(revID)
+	if err != nil {
+		t.Fatal("RevisionThrottler can't be found:", err)
+	}
+
+	// The revision gets reassigned to the other activators.
+	pubEps := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testRevision,
+			Namespace: testNamespace,
+			Labels: map[string]string{
+				networking.ServiceTypeKey: string(networking.ServiceTypePublic),
+				serving.RevisionLabelKey:  testRevision,
+			},
+			Annotations: map[string]string{
+				networking.ActivatorsAnnotationKey: "130.0.0.1,130.0.0.3",
+			},
+		},
+	}
+	fakeendpointsinformer.Get(ctx).Informer().GetIndexer().Add(pubEps)
+	throttler.handlePubEpsUpdate(pubEps)
+
+	// The requests already routed here keep using the same throttler.
+	got, err := throttler.getOrCreateRevisionThrottler(revID)
+	if err != nil {
+		t.Fatal("RevisionThrottler can't be found:", err)
+	}
+	if got != want {
+		t.Error("Revision throttler was replaced within the grace period")
+	}
+
+	// Until the grace period is over.
+	if err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		throttler.revisionThrottlersMutex.RLock()
+		defer throttler.revisionThrottlersMutex.RUnlock()
+		_, ok := throttler.revisionThrottlers[revID]
+		return !ok, nil
+	}); err != nil {
+		t.Fatal("Timed out waiting for the revision throttler to be removed")
+	}
+}
+
+func TestThrottlerDrainKeepsUnassignedRevision(t *testing.T) {
+	ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
+	defer cancel()
+
+	revID := types.NamespacedName{Namespace: testNamespace, Name: testRevision}
+	rev := revisionCC1(revID, pkgnet.ProtocolH2C)
+	fakeservingclient.Get(ctx).ServingV1().Revisions(rev.Namespace).Create(ctx, rev, metav1.CreateOptions{})
+	revisioninformer.Get(ctx).Informer().GetIndexer().Add(rev)
+
+	throttler := NewThrottler(ctx, "130.0.0.2", QueueLimits{}, CircuitBreakerParams{}, 0)
+	if _, err := throttler.getOrCreateRevisionThrottler(revID); err != nil {
+		t.Fatal("RevisionThrottler can't be found:", err)
+	}
+	throttler.Drain()
+
+	// The revision gets reassigned to the other activators.
+	throttler.handlePubEpsUpdate(&corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      testRevision,
+			Namespace: testNamespace,
+			Labels: map[string]string{
+				networking.ServiceTypeKey: string(networking.ServiceTypePublic),
+				serving.RevisionLabelKey:  testRevision,
+			},
+			Annotations: map[string]string{
+				networking.ActivatorsAnnotationKey: "130.0.0.1,130.0.0.3",
+			},
+		},
+	})
+
+	throttler.revisionThrottlersMutex.RLock()
+	defer throttler.revisionThrottlersMutex.RUnlock()
+	if _, ok := throttler.revisionThrottlers[revID]; !ok {
+		t.Error("Revision throttler was removed while draining")
+	}
+}
+
 func TestMultipleActivators(t *testing.T) {
 	ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
 
@@ -748,7 +1141,7 @@ func TestMultipleActivators(t *testing.T) {
 
 	updateCh := make(chan revisionDestsUpdate)
 
-	throttler := NewThrottler(ctx, "130.0.0.2")
+	throttler := NewThrottler(ctx, "130.0.0.2", QueueLimits{}, CircuitBreakerParams{}, 0)
 	var grp errgroup.Group
 	grp.Go(func() error { throttler.run(updateCh); return nil })
 	// Ensure the throttler stopped before we leave the test, so that
@@ -825,12 +1218,146 @@ func TestMultipleActivators(t *testing.T) {
 func TestInfiniteBreakerCreation(t *testing.T) {
 	// This test verifies that we use infiniteBreaker when CC==0.
 	tttl := newRevisionThrottler(types.NamespacedName{Namespace: "a", Name: "b"}, 0, /*cc*/
-		pkgnet.ServicePortNameHTTP1, queue.BreakerParams{}, TestLogger(t))
+		pkgnet.ServicePortNameHTTP1, false /*sessionAffinity*/, 0 /*standby*/, queue.BreakerParams{}, QueueLimits{}, CircuitBreakerParams{}, TestLogger(t))
 	if _, ok := tttl.breaker.(*infiniteBreaker); !ok {
 		t.Errorf("The type of revisionBreaker = %T, want %T", tttl, (*infiniteBreaker)(nil))
 	}
 }
 
+func TestRevisionThrottlerQueueLimits(t *testing.T) {
+	rt := newRevisionThrottler(types.NamespacedName{Namespace: "a", Name: "b"}, 0, /*cc*/
+		pkgnet.ServicePortNameHTTP1, false /*sessionAffinity*/, 0 /*standby*/, queue.BreakerParams{},
+		QueueLimits{MaxDepth: 2, Timeout: 100 * time.Millisecond}, CircuitBreakerParams{}, TestLogger(t))
+
+	// No capacity, as during a cold start, so the first two requests queue up.
+	errCh := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			errCh <- rt.try(context.Background(), func(string) error { return nil })
+		}()
+	}
+	if err := wait.PollImmediate(time.Millisecond, time.Second, func() (bool, error) {
+		return rt.queued.Load() == 2, nil
+	}); err != nil {
+		t.Fatal("Requests did not queue up:", err)
+	}
+
+	// The queue is full, so the next one is rejected immediately.
+	if err := rt.try(context.Background(), func(string) error { return nil }); err != queue.ErrRequestQueueDepthExceeded {
+		t.Errorf("try() = %v, want: %v", err, queue.ErrRequestQueueDepthExceeded)
+	}
+
+	// The queued ones time out.
+	for i := 0; i < 2; i++ {
+		if err := <-errCh; err != queue.ErrRequestQueueTimeout {
+			t.Errorf("try() = %v, want: %v", err, queue.ErrRequestQueueTimeout)
+		}
+	}
+	if got := rt.queued.Load(); got != 0 {
+		t.Errorf("#queued = %d, want: 0", got)
+	}
+
+	// The request's own deadline is not reported as a queue timeout.
+	stats := &util.ProxyStats{}
+	ctx, cancel := context.WithTimeout(util.WithProxyStats(context.Background(), stats), 10*time.Millisecond)
+	defer cancel()
+	if err := rt.try(ctx, func(string) error { return nil }); err != context.DeadlineExceeded {
+		t.Errorf("try() = %v, want: %v", err, context.DeadlineExceeded)
+	}
+	if !stats.ColdStart {
+		t.Error("ColdStart = false without capacity, want: true")
+	}
+
+	// With capacity the request is no longer queued while it is proxied.
+	rt.updateThrottlerState(1, nil /*trackers*/, newPodTracker("10.0.0.1:1234", nil))
+	stats = &util.ProxyStats{}
+	if err := rt.try(util.WithProxyStats(context.Background(), stats), func(string) error {
+		if got := rt.queued.Load(); got != 0 {
+			t.Errorf("#queued while proxying = %d, want: 0", got)
+		}
+		return nil
+	}); err != nil {
+		t.Error("try() =", err)
+	}
+	if stats.ColdStart {
+		t.Error("ColdStart = true with capacity, want: false")
+	}
+}
+
+func TestThrottlerRevisionQueueLimits(t *testing.T) {
+	ctx, cancel, _ := rtesting.SetupFakeContextWithCancel(t)
+	defer cancel()
+
+	defaults := QueueLimits{MaxDepth: 10, Timeout: time.Second}
+	throttler := NewThrottler(ctx, "130.0.0.2", defaults, CircuitBreakerParams{}, 0)
+
+	plain := types.NamespacedName{Namespace: testNamespace, Name: "plain"}
+	overridden := types.NamespacedName{Namespace: testNamespace, Name: "overridden"}
+	for _, revID := range []types.NamespacedName{plain, overridden} {
+		rev := revisionCC1(revID, pkgnet.ProtocolHTTP1)
+		if revID == overridden {
+			rev.Annotations = map[string]string{
+				serving.ActivatorQueueDepthAnnotationKey:   "0",
+				serving.ActivatorQueueTimeoutAnnotationKey: "30s",
+			}
+		}
+		fakeservingclient.Get(ctx).ServingV1().Revisions(rev.Namespace).Create(ctx, rev, metav1.CreateOptions{})
+		revisioninformer.Get(ctx).Informer().GetIndexer().Add(rev)
+	}
+
+	for revID, want := range map[types.NamespacedName]QueueLimits{
+		plain:      defaults,
+		overridden: {MaxDepth: 0, Timeout: 30 * time.Second},
+	} {
+		rt, err := throttler.getOrCreateRevisionThrottler(revID)
+		if err != nil {
+			t.Fatal("RevisionThrottler can't be found:", err)
+		}
+		if got := rt.queueLimits; got != want {
+			t.Errorf("%s queue limits = %+v, want: %+v", revID.Name, got, want)
+		}
+	}
+}
+
+func TestRevisionThrottlerRejectOverflow(t *testing.T) {
+	rt := newRevisionThrottler(types.NamespacedName{Namespace: "a", Name: "b"}, 1, /*cc*/
+		pkgnet.ServicePortNameHTTP1, false /*sessionAffinity*/, 0 /*standby*/, testBreakerParams,
+		QueueLimits{RejectOverflow: true}, CircuitBreakerParams{}, TestLogger(t))
+	rt.numActivators.Store(1)
+	rt.activatorIndex.Store(0)
+
+	// No capacity, as during a cold start, so the request is rejected right away.
+	if err := rt.try(context.Background(), func(string) error { return nil }); err != queue.ErrCapacityExceeded {
+		t.Errorf("try() = %v, want: %v", err, queue.ErrCapacityExceeded)
+	}
+
+	rt.updateThrottlerState(1, nil /*trackers*/, newPodTracker("10.0.0.1:1234", nil))
+	proxying, done := make(chan struct{}), make(chan struct{})
+	errCh := make(chan error)
+	go func() {
+		errCh <- rt.try(context.Background(), func(dest string) error {
+			close(proxying)
+			<-done
+			return nil
+		})
+	}()
+	<-proxying
+
+	// The single slot is taken, so the next request is rejected, rather than queued.
+	if err := rt.try(context.Background(), func(string) error { return nil }); err != queue.ErrCapacityExceeded {
+		t.Errorf("try() = %v, want: %v", err, queue.ErrCapacityExceeded)
+	}
+
+	close(done)
+	if err := <-errCh; err != nil {
+		t.Error("try() =", err)
+	}
+	// The slot is free again.
+	if err := rt.try(context.Background(), func(string) error { return nil }); err != nil {
+		t.Error("try() =", err)
+	}
+}
+
 func (t *Throttler) try(ctx context.Context, requests int, try func(string) error) chan tryResult {
 	resultChan := make(chan tryResult)
 
@@ -0,0 +1,277 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package net
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/types"
+
+	pkgnetapi "knative.dev/networking/pkg/apis/networking"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/logging/logkey"
+	pkgnet "knative.dev/pkg/network"
+	"knative.dev/serving/pkg/activator/util"
+	"knative.dev/serving/pkg/networking"
+)
+
+// TransportParams are the parameters of the per revision transports
+// used to proxy the requests to the revision backends.
+type TransportParams struct {
+	// MaxIdleConns is the maximum number of idle connections kept
+	// across all the backends of a single revision.
+	MaxIdleConns int
+	// MaxIdleConnsPerHost is the maximum number of idle connections
+	// kept to a single backend of a revision.
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost caps the number of connections (active and idle)
+	// to a single backend of a revision. Zero means no limit.
+	MaxConnsPerHost int
+	// IdleTimeout is the duration after which idle connections are closed.
+	// Transports of revisions which did not receive any requests
+	// for that long are released altogether.
+	IdleTimeout time.Duration
+	// BackendTLS is the client TLS configuration, with the root CAs of the
+	// backend certificates, used to proxy the requests to the queue-proxy over
+	// TLS. The server name is set per revision. Nil disables the backend TLS.
+	BackendTLS *tls.Config
+}
+
+// idleCloser is implemented by the transports that can release
+// their idle connections.
+type idleCloser interface {
+	CloseIdleConnections()
+}
+
+// revisionTransport is the transport dedicated to a single revision.
+type revisionTransport struct {
+	http.RoundTripper
+	h1 *http.Transport
+	h2 http.RoundTripper
+	// tls is nil, unless the backend TLS is configured.
+	tls *http.Transport
+
+	// lastUsed is the unix nano timestamp of when the transport was created,
+	// or the last request proxied through it started or finished.
+	lastUsed atomic.Int64
+	// inFlight is the number of requests being proxied through this
+	// transport, whose response bodies are not closed yet. This includes
+	// the long running streams and the upgraded connections.
+	inFlight atomic.Int32
+}
+
+// done records the end of a request proxied through the transport.
+func (rt *revisionTransport) done() {
+	rt.lastUsed.Store(time.Now().UnixNano())
+	rt.inFlight.Dec()
+}
+
+// trackedBody calls done once, when the response body is closed.
+type trackedBody struct {
+	io.ReadCloser
+	once sync.Once
+	rt   *revisionTransport
+}
+
+func (b *trackedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.rt.done)
+	return err
+}
+
+// trackedRWBody is the trackedBody of the upgraded connections, which
+// httputil.ReverseProxy requires to be an io.ReadWriteCloser.
+type trackedRWBody struct {
+	*trackedBody
+	w io.Writer
+}
+
+func (b *trackedRWBody) Write(p []byte) (int, error) {
+	return b.w.Write(p)
+}
+
+func trackBody(body io.ReadCloser, rt *revisionTransport) io.ReadCloser {
+	tb := &trackedBody{ReadCloser: body, rt: rt}
+	if w, ok := body.(io.Writer); ok {
+		return &trackedRWBody{trackedBody: tb, w: w}
+	}
+	return tb
+}
+
+func (rt *revisionTransport) closeIdleConnections() {
+	rt.h1.CloseIdleConnections()
+	if rt.tls != nil {
+		rt.tls.CloseIdleConnections()
+	}
+	if c, ok := rt.h2.(idleCloser); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+// RevisionTransports is an http.RoundTripper which proxies each request
+// over a connection pool dedicated to the request's revision, so that
+// a misbehaving revision can't exhaust the sockets used by all the others.
+// Pools that have not been used for longer than the idle timeout are reaped.
+type RevisionTransports struct {
+	params TransportParams
+	logger *zap.SugaredLogger
+
+	mux        sync.RWMutex
+	transports map[types.NamespacedName]*revisionTransport
+}
+
+var _ http.RoundTripper = (*RevisionTransports)(nil)
+
+// NewRevisionTransports creates a new RevisionTransports.
+func NewRevisionTransports(logger *zap.SugaredLogger, params TransportParams) *RevisionTransports {
+	return &RevisionTransports{
+		params:     params,
+		logger:     logger,
+		transports: make(map[types.NamespacedName]*revisionTransport),
+	}
+}
+
+func (rts *RevisionTransports) newRevisionTransport(revID types.NamespacedName) *revisionTransport {
+	h1 := http.DefaultTransport.(*http.Transport).Clone()
+	h1.DialContext = pkgnet.DialWithBackOff
+	h1.MaxIdleConns = rts.params.MaxIdleConns
+	h1.MaxIdleConnsPerHost = rts.params.MaxIdleConnsPerHost
+	h1.MaxConnsPerHost = rts.params.MaxConnsPerHost
+	h1.IdleConnTimeout = rts.params.IdleTimeout
+	h1.ForceAttemptHTTP2 = false
+
+	var tlsTransport *http.Transport
+	if rts.params.BackendTLS != nil {
+		tlsTransport = h1.Clone()
+		tlsTransport.TLSClientConfig = rts.params.BackendTLS.Clone()
+		// The backend certificates are issued per namespace, so this
+		// makes sure we talk to the pods of the revision's namespace.
+		tlsTransport.TLSClientConfig.ServerName = networking.BackendCertSAN(revID.Namespace)
+		// Both HTTP/1 and HTTP/2 are negotiated via ALPN.
+		tlsTransport.ForceAttemptHTTP2 = true
+	}
+
+	h2 := pkgnet.NewH2CTransport()
+	rt := &revisionTransport{
+		RoundTripper: pkgnet.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Scheme == "https" && tlsTransport != nil {
+				return tlsTransport.RoundTrip(r)
+			}
+			if useH2C(r) {
+				return h2.RoundTrip(r)
+			}
+			return h1.RoundTrip(r)
+		}),
+		h1:  h1,
+		h2:  h2,
+		tls: tlsTransport,
+	}
+	rt.lastUsed.Store(time.Now().UnixNano())
+	return rt
+}
+
+// useH2C returns true if the request must be proxied over h2c. The protocol
+// declared by the revision takes precedence over the one of the request, which
+// might have been downgraded to HTTP/1.1 on its way to the activator, except
+// for the protocol upgrades, e.g. websockets, which only work over HTTP/1.1.
+func useH2C(r *http.Request) bool {
+	rev := util.RevisionFrom(r.Context())
+	if rev == nil {
+		return r.ProtoMajor == 2
+	}
+	return rev.GetProtocol() == pkgnetapi.ProtocolH2C && r.Header.Get("Upgrade") == ""
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rts *RevisionTransports) RoundTrip(r *http.Request) (*http.Response, error) {
+	rt := rts.acquire(util.RevIDFrom(r.Context()))
+	rt.lastUsed.Store(time.Now().UnixNano())
+	resp, err := rt.RoundTrip(r)
+	if err != nil {
+		rt.done()
+		return nil, err
+	}
+	resp.Body = trackBody(resp.Body, rt)
+	return resp, nil
+}
+
+// acquire returns the transport of the revision, creating it if needed,
+// with the request accounted in flight. The request is accounted while the
+// lock is held, so that the transport isn't reaped in between.
+func (rts *RevisionTransports) acquire(revID types.NamespacedName) *revisionTransport {
+	// This is in the request path, so optimize for the case where
+	// the transport already exists.
+	rts.mux.RLock()
+	rt, ok := rts.transports[revID]
+	if ok {
+		rt.inFlight.Inc()
+	}
+	rts.mux.RUnlock()
+	if ok {
+		return rt
+	}
+
+	rts.mux.Lock()
+	defer rts.mux.Unlock()
+	if rt, ok = rts.transports[revID]; !ok {
+		rt = rts.newRevisionTransport(revID)
+		rts.transports[revID] = rt
+	}
+	rt.inFlight.Inc()
+	return rt
+}
+
+// Run periodically reaps the transports of the revisions that have been
+// idle for longer than the idle timeout, until stopCh is closed.
+func (rts *RevisionTransports) Run(stopCh <-chan struct{}) {
+	if rts.params.IdleTimeout <= 0 {
+		return
+	}
+	ticker := time.NewTicker(rts.params.IdleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case now := <-ticker.C:
+			rts.reap(now)
+		}
+	}
+}
+
+// reap releases the transports which were not used since
+// now - IdleTimeout, and have no requests in flight.
+func (rts *RevisionTransports) reap(now time.Time) {
+	cutoff := now.Add(-rts.params.IdleTimeout).UnixNano()
+
+	rts.mux.Lock()
+	defer rts.mux.Unlock()
+	for revID, rt := range rts.transports {
+		if rt.inFlight.Load() == 0 && rt.lastUsed.Load() < cutoff {
+			rts.logger.Debugw("Releasing idle revision transport",
+				zap.Object(logkey.Key, logging.NamespacedName(revID)))
+			rt.closeIdleConnections()
+			delete(rts.transports, revID)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package net
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	pkgnet "knative.dev/pkg/network"
+	"knative.dev/pkg/webhook/certificates/resources"
+	"knative.dev/serving/pkg/activator/util"
+	v1 "knative.dev/serving/pkg/apis/serving/v1"
+	"knative.dev/serving/pkg/networking"
+
+	. "knative.dev/pkg/logging/testing"
+)
+
+func TestRevisionTransports(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	rts := NewRevisionTransports(TestLogger(t), TransportParams{
+		MaxIdleConns:        10,
+		MaxIdleConnsPerHost: 1,
+		MaxConnsPerHost:     2,
+		IdleTimeout:         time.Minute,
+	})
+
+	send := func(revID types.NamespacedName) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, server.URL, nil)
+		req.RequestURI = ""
+		req = req.WithContext(util.WithRevID(req.Context(), revID))
+		resp, err := rts.RoundTrip(req)
+		if err != nil {
+			t.Fatal("RoundTrip() =", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("StatusCode = %d, want: %d", resp.StatusCode, http.StatusOK)
+		}
+	}
+
+	rev1 := types.NamespacedName{Namespace: testNamespace, Name: "rev-1"}
+	rev2 := types.NamespacedName{Namespace: testNamespace, Name: "rev-2"}
+	send(rev1)
+	send(rev1)
+	send(rev2)
+
+	if got, want := len(rts.transports), 2; got != want {
+		t.Fatalf("#transports = %d, want: %d", got, want)
+	}
+	if rts.transports[rev1] == rts.transports[rev2] {
+		t.Error("Revisions must not share a transport")
+	}
+	h1 := rts.transports[rev1].h1
+	if h1.MaxConnsPerHost != 2 || h1.MaxIdleConnsPerHost != 1 || h1.IdleConnTimeout != time.Minute {
+		t.Errorf("Transport not configured from params: MaxConnsPerHost = %d, MaxIdleConnsPerHost = %d, IdleConnTimeout = %v",
+			h1.MaxConnsPerHost, h1.MaxIdleConnsPerHost, h1.IdleConnTimeout)
+	}
+
+	// Nothing is idle for long enough yet.
+	rts.reap(time.Now())
+	if got, want := len(rts.transports), 2; got != want {
+		t.Fatalf("#transports after reap = %d, want: %d", got, want)
+	}
+
+	// Make rev1 recently used, so only rev2 is reaped.
+	rts.transports[rev2].lastUsed.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	rts.reap(time.Now())
+	if _, ok := rts.transports[rev2]; ok {
+		t.Error("Idle transport for rev-2 was not reaped")
+	}
+	if _, ok := rts.transports[rev1]; !ok {
+		t.Error("Active transport for rev-1 was reaped")
+	}
+
+	// A reaped revision gets a fresh transport on demand.
+	send(rev2)
+	if _, ok := rts.transports[rev2]; !ok {
+		t.Error("Transport for rev-2 was not recreated")
+	}
+}
+
+func TestRevisionTransportsInFlight(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	rts := NewRevisionTransports(TestLogger(t), TransportParams{IdleTimeout: time.Minute})
+	rev := types.NamespacedName{Namespace: testNamespace, Name: "rev"}
+
+	// A freshly created transport is not idle.
+	rts.acquire(rev).done()
+	rts.reap(time.Now())
+	if _, ok := rts.transports[rev]; !ok {
+		t.Fatal("Fresh transport was reaped")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, server.URL, nil)
+	req.RequestURI = ""
+	req = req.WithContext(util.WithRevID(req.Context(), rev))
+	resp, err := rts.RoundTrip(req)
+	if err != nil {
+		t.Fatal("RoundTrip() =", err)
+	}
+
+	// The response is still streaming, long past the idle timeout.
+	rt := rts.transports[rev]
+	rt.lastUsed.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	rts.reap(time.Now())
+	if _, ok := rts.transports[rev]; !ok {
+		t.Fatal("Transport with a request in flight was reaped")
+	}
+
+	resp.Body.Close()
+	resp.Body.Close()
+	if got := rt.inFlight.Load(); got != 0 {
+		t.Errorf("inFlight = %d, want: 0", got)
+	}
+	if time.Since(time.Unix(0, rt.lastUsed.Load())) > time.Minute {
+		t.Error("lastUsed was not updated when the body was closed")
+	}
+	rt.lastUsed.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	rts.reap(time.Now())
+	if _, ok := rts.transports[rev]; ok {
+		t.Error("Idle transport was not reaped")
+	}
+}
+
+func TestRevisionTransportsRunStops(t *testing.T) {
+	rts := NewRevisionTransports(TestLogger(t), TransportParams{IdleTimeout: 10 * time.Millisecond})
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rts.Run(stopCh)
+	}()
+	close(stopCh)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after stop")
+	}
+}
+
+func TestRevisionTransportsProtocol(t *testing.T) {
+	server := httptest.NewServer(pkgnet.NewServer(":0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proto", r.Proto)
+	})).Handler)
+	defer server.Close()
+
+	rts := NewRevisionTransports(TestLogger(t), TransportParams{})
+	h2cRev := &v1.Revision{
+		Spec: v1.RevisionSpec{
+			PodSpec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Ports: []corev1.ContainerPort{{Name: "h2c"}},
+				}},
+			},
+		},
+	}
+	http1Rev := &v1.Revision{}
+
+	tests := []struct {
+		name       string
+		rev        *v1.Revision
+		protoMajor int
+		upgrade    bool
+		want       string
+	}{{
+		name:       "h2c revision, downgraded request",
+		rev:        h2cRev,
+		protoMajor: 1,
+		want:       "HTTP/2.0",
+	}, {
+		name:       "h2c revision, upgrade request",
+		rev:        h2cRev,
+		protoMajor: 1,
+		upgrade:    true,
+		want:       "HTTP/1.1",
+	}, {
+		name:       "http1 revision",
+		rev:        http1Rev,
+		protoMajor: 2,
+		want:       "HTTP/1.1",
+	}, {
+		name:       "no revision, http2 request",
+		protoMajor: 2,
+		want:       "HTTP/2.0",
+	}, {
+		name:       "no revision, http1 request",
+		protoMajor: 1,
+		want:       "HTTP/1.1",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, server.URL, nil)
+			req.RequestURI = ""
+			req.ProtoMajor = test.protoMajor
+			if test.upgrade {
+				req.Header.Set("Upgrade", "websocket")
+			}
+			ctx := util.WithRevID(req.Context(), types.NamespacedName{Namespace: testNamespace, Name: "rev"})
+			if test.rev != nil {
+				ctx = util.WithRevision(ctx, test.rev)
+			}
+			resp, err := rts.RoundTrip(req.WithContext(ctx))
+			if err != nil {
+				t.Fatal("RoundTrip() =", err)
+			}
+			resp.Body.Close()
+			if got := resp.Header.Get("X-Proto"); got != test.want {
+				t.Errorf("Proto = %s, want: %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRevisionTransportsBackendTLS(t *testing.T) {
+	key, cert, ca, err := resources.CreateCerts(context.Background(),
+		networking.BackendCertSAN(testNamespace), "backend", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal("CreateCerts() =", err)
+	}
+	serverCert, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		t.Fatal("X509KeyPair() =", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{serverCert}}
+	server.StartTLS()
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca)
+	rts := NewRevisionTransports(TestLogger(t), TransportParams{
+		BackendTLS: &tls.Config{RootCAs: pool},
+	})
+
+	send := func(revID types.NamespacedName) error {
+		req := httptest.NewRequest(http.MethodGet, server.URL, nil)
+		req.RequestURI = ""
+		req = req.WithContext(util.WithRevID(req.Context(), revID))
+		resp, err := rts.RoundTrip(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	if err := send(types.NamespacedName{Namespace: testNamespace, Name: "rev"}); err != nil {
+		t.Error("RoundTrip() =", err)
+	}
+	// The certificate is not valid for the revisions in the other namespaces.
+	if err := send(types.NamespacedName{Namespace: "other-namespace", Name: "rev"}); err == nil {
+		t.Error("RoundTrip() succeeded, want certificate validation error")
+	}
+}
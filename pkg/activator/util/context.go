@@ -23,24 +23,43 @@ package util
 
 import (
 	"context"
+	"time"
 
 	"k8s.io/apimachinery/pkg/types"
 	v1 "knative.dev/serving/pkg/apis/serving/v1"
 )
 
 type (
-	revisionKey struct{}
-	revIDKey    struct{}
+	revisionKey   struct{}
+	revIDKey      struct{}
+	sessionKeyKey struct{}
+	proxyStatsKey struct{}
 )
 
+// ProxyStats are the details of proxying a request to the revision,
+// collected for the request logs.
+type ProxyStats struct {
+	// Dest is the address of the pod the request was proxied to.
+	Dest string
+	// QueueingDelay is the time the request waited for the capacity.
+	QueueingDelay time.Duration
+	// ColdStart is set if the revision had no capacity when the request
+	// arrived, so the request was buffered while the revision activated.
+	ColdStart bool
+	// UpstreamLatency is the time it took to proxy the request.
+	UpstreamLatency time.Duration
+}
+
 // WithRevision attaches the Revision object to the context.
 func WithRevision(ctx context.Context, rev *v1.Revision) context.Context {
 	return context.WithValue(ctx, revisionKey{}, rev)
 }
 
-// RevisionFrom retrieves the Revision object from the context.
+// RevisionFrom retrieves the Revision object from the context,
+// or nil if there is none.
 func RevisionFrom(ctx context.Context) *v1.Revision {
-	return ctx.Value(revisionKey{}).(*v1.Revision)
+	rev, _ := ctx.Value(revisionKey{}).(*v1.Revision)
+	return rev
 }
 
 // WithRevID attaches the the revisionID to the context.
@@ -52,3 +71,27 @@ func WithRevID(ctx context.Context, revID types.NamespacedName) context.Context
 func RevIDFrom(ctx context.Context) types.NamespacedName {
 	return ctx.Value(revIDKey{}).(types.NamespacedName)
 }
+
+// WithSessionKey attaches the session affinity key of the request to the context.
+func WithSessionKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, sessionKeyKey{}, key)
+}
+
+// SessionKeyFrom retrieves the session affinity key from the context,
+// or empty string if the request has none.
+func SessionKeyFrom(ctx context.Context) string {
+	key, _ := ctx.Value(sessionKeyKey{}).(string)
+	return key
+}
+
+// WithProxyStats attaches the ProxyStats to fill in to the context.
+func WithProxyStats(ctx context.Context, stats *ProxyStats) context.Context {
+	return context.WithValue(ctx, proxyStatsKey{}, stats)
+}
+
+// ProxyStatsFrom retrieves the ProxyStats from the context,
+// or nil if there are none.
+func ProxyStatsFrom(ctx context.Context) *ProxyStats {
+	stats, _ := ctx.Value(proxyStatsKey{}).(*ProxyStats)
+	return stats
+}
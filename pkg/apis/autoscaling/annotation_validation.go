@@ -57,7 +57,10 @@ func ValidateAnnotations(ctx context.Context, config *autoscalerconfig.Config, a
 		Also(validateLastPodRetention(anns)).
 		Also(validateScaleDownDelay(anns)).
 		Also(validateMetric(anns)).
-		Also(validateInitialScale(config, anns))
+		Also(validateInitialScale(config, anns)).
+		Also(validateSLO(anns)).
+		Also(validateStandbyScale(anns)).
+		Also(validateActivatorBypass(anns))
 }
 
 func validateClass(annotations map[string]string) *apis.FieldError {
@@ -125,6 +128,57 @@ func validateScaleDownDelay(annotations map[string]string) *apis.FieldError {
 	return errs
 }
 
+func validateSLO(annotations map[string]string) (errs *apis.FieldError) {
+	if v, ok := annotations[SLOLatencyP99AnnotationKey]; ok {
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			errs = errs.Also(apis.ErrInvalidValue(v, SLOLatencyP99AnnotationKey))
+		}
+	}
+	if v, ok := annotations[SLOMaxErrorRatePercentageAnnotationKey]; ok {
+		if fv, err := strconv.ParseFloat(v, 64); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(v, SLOMaxErrorRatePercentageAnnotationKey))
+		} else if fv < 0 || fv > 100 {
+			errs = errs.Also(apis.ErrOutOfBoundsValue(v, 0, 100, SLOMaxErrorRatePercentageAnnotationKey))
+		}
+	}
+	return errs
+}
+
+func validateStandbyScale(annotations map[string]string) *apis.FieldError {
+	_, errs := getIntGE0(annotations, StandbyScaleAnnotationKey)
+	return errs
+}
+
+func validateActivatorBypass(annotations map[string]string) *apis.FieldError {
+	v, ok := annotations[ActivatorBypassAnnotationKey]
+	if !ok {
+		return nil
+	}
+	bypass, err := strconv.ParseBool(v)
+	if err != nil {
+		return apis.ErrInvalidValue(v, ActivatorBypassAnnotationKey)
+	}
+	if !bypass {
+		return nil
+	}
+	var errs *apis.FieldError
+	if tbc, ok := annotations[TargetBurstCapacityKey]; ok {
+		if fv, err := strconv.ParseFloat(tbc, 64); err == nil && fv != 0 {
+			errs = errs.Also(apis.ErrGeneric(
+				fmt.Sprintf("%s must be 0 with %s", TargetBurstCapacityKey, ActivatorBypassAnnotationKey),
+				TargetBurstCapacityKey, ActivatorBypassAnnotationKey))
+		}
+	}
+	if ss, ok := annotations[StandbyScaleAnnotationKey]; ok {
+		if iv, err := strconv.ParseInt(ss, 10, 32); err == nil && iv != 0 {
+			errs = errs.Also(apis.ErrGeneric(
+				fmt.Sprintf("%s must be 0 with %s", StandbyScaleAnnotationKey, ActivatorBypassAnnotationKey),
+				StandbyScaleAnnotationKey, ActivatorBypassAnnotationKey))
+		}
+	}
+	return errs
+}
+
 func validateLastPodRetention(annotations map[string]string) *apis.FieldError {
 	if w, ok := annotations[ScaleToZeroPodRetentionPeriodKey]; ok {
 		if d, err := time.ParseDuration(w); err != nil {
@@ -195,6 +249,12 @@ func validateMetric(annotations map[string]string) *apis.FieldError {
 			switch metric {
 			case Concurrency, RPS:
 				return nil
+			case CPU, Memory:
+				// There is no sensible default for the resource usage.
+				if _, ok := annotations[TargetAnnotationKey]; !ok {
+					return apis.ErrMissingField(TargetAnnotationKey)
+				}
+				return nil
 			}
 		case HPA:
 			switch metric {
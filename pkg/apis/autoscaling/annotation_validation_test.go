@@ -66,6 +66,37 @@ func TestValidateAnnotations(t *testing.T) {
 		name:        "maxScale is -1",
 		annotations: map[string]string{MaxScaleAnnotationKey: "-1"},
 		expectErr:   "expected 0 <= -1 <= 2147483647: " + MaxScaleAnnotationKey,
+	}, {
+		name:        "activatorBypass is true",
+		annotations: map[string]string{ActivatorBypassAnnotationKey: "true", TargetBurstCapacityKey: "0"},
+	}, {
+		name:        "activatorBypass is false with tbc",
+		annotations: map[string]string{ActivatorBypassAnnotationKey: "false", TargetBurstCapacityKey: "-1"},
+	}, {
+		name:        "activatorBypass is sure",
+		annotations: map[string]string{ActivatorBypassAnnotationKey: "sure"},
+		expectErr:   "invalid value: sure: " + ActivatorBypassAnnotationKey,
+	}, {
+		name:        "activatorBypass with tbc",
+		annotations: map[string]string{ActivatorBypassAnnotationKey: "true", TargetBurstCapacityKey: "-1"},
+		expectErr: TargetBurstCapacityKey + " must be 0 with " + ActivatorBypassAnnotationKey + ": " +
+			ActivatorBypassAnnotationKey + ", " + TargetBurstCapacityKey,
+	}, {
+		name:        "activatorBypass with standbyScale",
+		annotations: map[string]string{ActivatorBypassAnnotationKey: "true", StandbyScaleAnnotationKey: "2"},
+		expectErr: StandbyScaleAnnotationKey + " must be 0 with " + ActivatorBypassAnnotationKey + ": " +
+			ActivatorBypassAnnotationKey + ", " + StandbyScaleAnnotationKey,
+	}, {
+		name:        "standbyScale is 2",
+		annotations: map[string]string{StandbyScaleAnnotationKey: "2"},
+	}, {
+		name:        "standbyScale is -1",
+		annotations: map[string]string{StandbyScaleAnnotationKey: "-1"},
+		expectErr:   "expected 0 <= -1 <= 2147483647: " + StandbyScaleAnnotationKey,
+	}, {
+		name:        "standbyScale is foo",
+		annotations: map[string]string{StandbyScaleAnnotationKey: "foo"},
+		expectErr:   "invalid value: foo: " + StandbyScaleAnnotationKey,
 	}, {
 		name:        "minScale is foo",
 		annotations: map[string]string{MinScaleAnnotationKey: "foo"},
@@ -318,8 +349,22 @@ func TestValidateAnnotations(t *testing.T) {
 		},
 	}, {
 		name:        "invalid metric for default class(KPA)",
+		annotations: map[string]string{MetricAnnotationKey: "metrics"},
+		expectErr:   "invalid value: metrics: " + MetricAnnotationKey,
+	}, {
+		name:        "metric CPU for class KPA without target",
 		annotations: map[string]string{MetricAnnotationKey: CPU},
-		expectErr:   "invalid value: cpu: " + MetricAnnotationKey,
+		expectErr:   "missing field(s): " + TargetAnnotationKey,
+	}, {
+		name:        "valid class KPA with metric CPU",
+		annotations: map[string]string{MetricAnnotationKey: CPU, TargetAnnotationKey: "500"},
+	}, {
+		name:        "valid class KPA with metric Memory",
+		annotations: map[string]string{ClassAnnotationKey: KPA, MetricAnnotationKey: Memory, TargetAnnotationKey: "256"},
+	}, {
+		name:        "invalid metric Memory for HPA class",
+		annotations: map[string]string{ClassAnnotationKey: HPA, MetricAnnotationKey: Memory},
+		expectErr:   "invalid value: memory: " + MetricAnnotationKey,
 	}, {
 		name:        "invalid metric for HPA class",
 		annotations: map[string]string{MetricAnnotationKey: "metrics", ClassAnnotationKey: HPA},
@@ -353,6 +398,28 @@ func TestValidateAnnotations(t *testing.T) {
 		name:        "initial scale non-parseable",
 		annotations: map[string]string{InitialScaleAnnotationKey: "invalid"},
 		expectErr:   "invalid value: invalid: autoscaling.knative.dev/initialScale",
+	}, {
+		name: "valid SLO",
+		annotations: map[string]string{
+			SLOLatencyP99AnnotationKey:             "250ms",
+			SLOMaxErrorRatePercentageAnnotationKey: "0.1",
+		},
+	}, {
+		name:        "invalid SLO latency",
+		annotations: map[string]string{SLOLatencyP99AnnotationKey: "fast"},
+		expectErr:   "invalid value: fast: " + SLOLatencyP99AnnotationKey,
+	}, {
+		name:        "zero SLO latency",
+		annotations: map[string]string{SLOLatencyP99AnnotationKey: "0s"},
+		expectErr:   "invalid value: 0s: " + SLOLatencyP99AnnotationKey,
+	}, {
+		name:        "invalid SLO error rate",
+		annotations: map[string]string{SLOMaxErrorRatePercentageAnnotationKey: "none"},
+		expectErr:   "invalid value: none: " + SLOMaxErrorRatePercentageAnnotationKey,
+	}, {
+		name:        "SLO error rate out of bounds",
+		annotations: map[string]string{SLOMaxErrorRatePercentageAnnotationKey: "101"},
+		expectErr:   "expected 0 <= 101 <= 100: " + SLOMaxErrorRatePercentageAnnotationKey,
 	}}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
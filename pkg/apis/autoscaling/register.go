@@ -45,6 +45,12 @@ const (
 	// the PodAutoscaler should provision. For example,
 	//   autoscaling.knative.dev/maxScale: "10"
 	MaxScaleAnnotationKey = GroupName + "/maxScale"
+	// StandbyScaleAnnotationKey is the annotation to specify the number of warm
+	// standby Pods the PodAutoscaler should provision on top of the desired scale.
+	// Standby Pods are kept running, but don't receive requests until the rest
+	// of the Pods are saturated. For example,
+	//   autoscaling.knative.dev/standbyScale: "2"
+	StandbyScaleAnnotationKey = GroupName + "/standbyScale"
 
 	// InitialScaleAnnotationKey is the annotation to specify the initial scale of
 	// a revision when a service is initially deployed. This number can be set to 0 iff
@@ -61,10 +67,27 @@ const (
 	// Concurrency is the number of requests in-flight at any given time.
 	Concurrency = "concurrency"
 	// CPU is the amount of the requested cpu actually being consumed by the Pod.
+	// For the KPA class, it is the cpu used by the Pod, in millicores.
 	CPU = "cpu"
+	// Memory is the memory used by the Pod, in MiB. Only supported by the KPA class.
+	Memory = "memory"
 	// RPS is the requests per second reaching the Pod.
 	RPS = "rps"
 
+	// ActivatorMetricMergeSum adds up the activator-reported metrics and the
+	// metrics scraped from the Pods, after subtracting the proxied requests
+	// from the latter.
+	ActivatorMetricMergeSum = "sum"
+	// ActivatorMetricMergeActivator uses only the activator-reported metrics
+	// while the activators report them and the scraped metrics otherwise.
+	ActivatorMetricMergeActivator = "activator"
+	// ActivatorMetricMergeScrape uses only the scraped metrics while the scrapes
+	// succeed and the activator-reported metrics otherwise.
+	ActivatorMetricMergeScrape = "scrape"
+	// ActivatorMetricMergeWeighted blends the activator-reported and the
+	// scraped metrics using the activator metric weight, while both are reported.
+	ActivatorMetricMergeWeighted = "weighted"
+
 	// TargetAnnotationKey is the annotation to specify what metric value the
 	// PodAutoscaler should attempt to maintain. For example,
 	//   autoscaling.knative.dev/metric: cpu
@@ -117,6 +140,18 @@ const (
 	// <0 && != -1 -- an error.
 	TargetBurstCapacityKey = GroupName + "/targetBurstCapacity"
 
+	// ActivatorBypassAnnotationKey is the annotation to take the activator
+	// out of the request path for good once the revision is scaled up, e.g.
+	//   autoscaling.knative.dev/activatorBypass: "true"
+	// The activator then only buffers the requests while the revision is
+	// scaled to zero: the target burst capacity is treated as 0 and the
+	// revision is kept in the Serve mode regardless of the excess burst
+	// capacity. This trades the overflow protection for the latency, so
+	// it can't be combined with a non-zero targetBurstCapacity or with
+	// the standbyScale, which both need the activator in the path.
+	// The session affinity still keeps the activator in the path.
+	ActivatorBypassAnnotationKey = GroupName + "/activatorBypass"
+
 	// PanicWindowPercentageAnnotationKey is the annotation to
 	// specify the time interval over which to calculate the average
 	// metric during a spike. Where a spike is defined as the metric
@@ -172,4 +207,15 @@ const (
 	// PanicThresholdPercentageMax is the counterpart to the PanicThresholdPercentageMin
 	// but bounding from above.
 	PanicThresholdPercentageMax = 1000.0
+
+	// SLOLatencyP99AnnotationKey is the annotation to declare the target
+	// 99th percentile request latency of the revision, e.g. "250ms".
+	// SLO annotations are hints: they are recorded on the PodAutoscaler and
+	// exported as metrics, but they don't affect the scaling decisions.
+	SLOLatencyP99AnnotationKey = GroupName + "/sloLatencyP99"
+
+	// SLOMaxErrorRatePercentageAnnotationKey is the annotation to declare the
+	// maximum percentage of the requests to the revision that may fail.
+	// It is in the 0 <= rate <= 100 range.
+	SLOMaxErrorRatePercentageAnnotationKey = GroupName + "/sloMaxErrorRatePercentage"
 )
@@ -65,6 +65,14 @@ type MetricSpec struct {
 	PanicWindow time.Duration `json:"panicWindow"`
 	// ScrapeTarget is the K8s service that publishes the metric endpoint.
 	ScrapeTarget string `json:"scrapeTarget"`
+	// ActivatorMergePolicy determines how the metrics reported by the activators
+	// are merged with the scraped ones. Empty means they are summed up.
+	// +optional
+	ActivatorMergePolicy string `json:"activatorMergePolicy,omitempty"`
+	// ActivatorWeightPercentage is the weight of the activator-reported metrics
+	// for the weighted merge policy.
+	// +optional
+	ActivatorWeightPercentage float64 `json:"activatorWeightPercentage,omitempty"`
 }
 
 // MetricStatus reflects the status of metric collection for this specific entity.
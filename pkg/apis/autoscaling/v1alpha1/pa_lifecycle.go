@@ -26,6 +26,7 @@ import (
 
 	"knative.dev/pkg/apis"
 	"knative.dev/serving/pkg/apis/autoscaling"
+	"knative.dev/serving/pkg/apis/serving"
 	"knative.dev/serving/pkg/autoscaler/config/autoscalerconfig"
 )
 
@@ -144,6 +145,13 @@ func (pa *PodAutoscaler) ScaleDownDelay() (time.Duration, bool) {
 	return pa.annotationDuration(autoscaling.ScaleDownDelayAnnotationKey)
 }
 
+// ProgressDeadline returns the progress deadline annotation of the revision,
+// or false if not present.
+func (pa *PodAutoscaler) ProgressDeadline() (time.Duration, bool) {
+	// The value is validated in the webhook.
+	return pa.annotationDuration(serving.ProgressDeadlineAnnotationKey)
+}
+
 // PanicWindowPercentage returns the panic window annotation value, or false if not present.
 func (pa *PodAutoscaler) PanicWindowPercentage() (percentage float64, ok bool) {
 	// The value is validated in the webhook.
@@ -156,12 +164,39 @@ func (pa *PodAutoscaler) PanicThresholdPercentage() (percentage float64, ok bool
 	return pa.annotationFloat64(autoscaling.PanicThresholdPercentageAnnotationKey)
 }
 
+// SLOLatencyP99 returns the target P99 latency annotation value, or false if not present.
+func (pa *PodAutoscaler) SLOLatencyP99() (time.Duration, bool) {
+	// The value is validated in the webhook.
+	return pa.annotationDuration(autoscaling.SLOLatencyP99AnnotationKey)
+}
+
+// SLOMaxErrorRatePercentage returns the max error rate annotation value, or false if not present.
+func (pa *PodAutoscaler) SLOMaxErrorRatePercentage() (float64, bool) {
+	// The value is validated in the webhook.
+	return pa.annotationFloat64(autoscaling.SLOMaxErrorRatePercentageAnnotationKey)
+}
+
 // InitialScale returns the initial scale on the revision if present, or false if not present.
 func (pa *PodAutoscaler) InitialScale() (int32, bool) {
 	// The value is validated in the webhook.
 	return pa.annotationInt32(autoscaling.InitialScaleAnnotationKey)
 }
 
+// StandbyScale returns the number of warm standby pods requested for the
+// revision, or false if not present.
+func (pa *PodAutoscaler) StandbyScale() (int32, bool) {
+	// The value is validated in the webhook.
+	return pa.annotationInt32(autoscaling.StandbyScaleAnnotationKey)
+}
+
+// ActivatorBypass returns true if the revision asked to keep the activator
+// out of the request path once it is scaled up.
+func (pa *PodAutoscaler) ActivatorBypass() bool {
+	// The value is validated in the webhook.
+	b, _ := strconv.ParseBool(pa.Annotations[autoscaling.ActivatorBypassAnnotationKey])
+	return b
+}
+
 // IsReady returns true if the Status condition PodAutoscalerConditionReady
 // is true and the latest spec has been observed.
 func (pa *PodAutoscaler) IsReady() bool {
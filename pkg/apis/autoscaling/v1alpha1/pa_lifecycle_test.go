@@ -30,6 +30,7 @@ import (
 	apistest "knative.dev/pkg/apis/testing"
 	"knative.dev/pkg/ptr"
 	"knative.dev/serving/pkg/apis/autoscaling"
+	"knative.dev/serving/pkg/apis/serving"
 	"knative.dev/serving/pkg/autoscaler/config/autoscalerconfig"
 )
 
@@ -821,6 +822,42 @@ func TestWindowAnnotation(t *testing.T) {
 	}
 }
 
+func TestProgressDeadlineAnnotation(t *testing.T) {
+	cases := []struct {
+		name         string
+		pa           *PodAutoscaler
+		wantDeadline time.Duration
+		wantOK       bool
+	}{{
+		name: "not present",
+		pa:   pa(map[string]string{}),
+	}, {
+		name: "present",
+		pa: pa(map[string]string{
+			serving.ProgressDeadlineAnnotationKey: "10m",
+		}),
+		wantDeadline: 10 * time.Minute,
+		wantOK:       true,
+	}, {
+		name: "invalid",
+		pa: pa(map[string]string{
+			serving.ProgressDeadlineAnnotationKey: "forever",
+		}),
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotDeadline, gotOK := tc.pa.ProgressDeadline()
+			if gotDeadline != tc.wantDeadline {
+				t.Errorf("ProgressDeadline = %v, want: %v", gotDeadline, tc.wantDeadline)
+			}
+			if gotOK != tc.wantOK {
+				t.Errorf("OK = %v, want: %v", gotOK, tc.wantOK)
+			}
+		})
+	}
+}
+
 func TestPanicWindowPercentageAnnotation(t *testing.T) {
 	cases := []struct {
 		name           string
@@ -934,6 +971,27 @@ func TestPanicThresholdPercentage(t *testing.T) {
 	}
 }
 
+func TestSLO(t *testing.T) {
+	p := pa(map[string]string{})
+	if _, ok := p.SLOLatencyP99(); ok {
+		t.Error("SLOLatencyP99 present, want absent")
+	}
+	if _, ok := p.SLOMaxErrorRatePercentage(); ok {
+		t.Error("SLOMaxErrorRatePercentage present, want absent")
+	}
+
+	p = pa(map[string]string{
+		autoscaling.SLOLatencyP99AnnotationKey:             "250ms",
+		autoscaling.SLOMaxErrorRatePercentageAnnotationKey: "0.5",
+	})
+	if got, ok := p.SLOLatencyP99(); !ok || got != 250*time.Millisecond {
+		t.Errorf("SLOLatencyP99 = %v, %v, want: 250ms, true", got, ok)
+	}
+	if got, ok := p.SLOMaxErrorRatePercentage(); !ok || got != 0.5 {
+		t.Errorf("SLOMaxErrorRatePercentage = %v, %v, want: 0.5, true", got, ok)
+	}
+}
+
 func pa(annotations map[string]string) *PodAutoscaler {
 	return &PodAutoscaler{
 		ObjectMeta: metav1.ObjectMeta{
@@ -1177,6 +1235,30 @@ func TestInitialScale(t *testing.T) {
 	}
 }
 
+func TestStandbyScale(t *testing.T) {
+	if got, ok := pa(map[string]string{}).StandbyScale(); ok {
+		t.Errorf("StandbyScale = %v, want absent", got)
+	}
+	p := pa(map[string]string{
+		autoscaling.StandbyScaleAnnotationKey: "3",
+	})
+	if got, ok := p.StandbyScale(); !ok || got != 3 {
+		t.Errorf("StandbyScale = %v, %v, want: 3, true", got, ok)
+	}
+}
+
+func TestActivatorBypass(t *testing.T) {
+	if pa(map[string]string{}).ActivatorBypass() {
+		t.Error("ActivatorBypass = true, want: false")
+	}
+	if pa(map[string]string{autoscaling.ActivatorBypassAnnotationKey: "false"}).ActivatorBypass() {
+		t.Error("ActivatorBypass = true, want: false")
+	}
+	if !pa(map[string]string{autoscaling.ActivatorBypassAnnotationKey: "true"}).ActivatorBypass() {
+		t.Error("ActivatorBypass = false, want: true")
+	}
+}
+
 func TestIsScaleTargetInitialized(t *testing.T) {
 	p := PodAutoscaler{}
 	if got, want := p.Status.IsScaleTargetInitialized(), false; got != want {
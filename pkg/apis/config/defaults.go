@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math"
+	"strconv"
 	"strings"
 	"text/template"
 
@@ -29,6 +30,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	"knative.dev/pkg/apis"
 	cm "knative.dev/pkg/configmap"
@@ -61,6 +64,10 @@ const (
 	DefaultAllowContainerConcurrencyZero = true
 )
 
+// defaultGPUResourceNames are the extended resources making a revision a GPU
+// revision, unless configured otherwise.
+var defaultGPUResourceNames = sets.NewString("nvidia.com/gpu", "amd.com/gpu")
+
 var (
 	templateCache *lru.Cache
 
@@ -102,6 +109,56 @@ func asTriState(key string, target **bool, defValue *bool) cm.ParseFunc {
 	}
 }
 
+// asOptionalInt64 parses the integer of the key, if present, into target.
+func asOptionalInt64(key string, target **int64) cm.ParseFunc {
+	return func(data map[string]string) error {
+		if raw, ok := data[key]; ok {
+			v, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return fmt.Errorf("failed to parse %q: %w", key, err)
+			}
+			*target = &v
+		}
+		return nil
+	}
+}
+
+// asResourceList parses the comma separated name=quantity pairs of the key,
+// if present, into target.
+func asResourceList(key string, target *corev1.ResourceList) cm.ParseFunc {
+	return func(data map[string]string) error {
+		raw := strings.TrimSpace(data[key])
+		if raw == "" {
+			return nil
+		}
+		rl := corev1.ResourceList{}
+		for _, pair := range strings.Split(raw, ",") {
+			kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("failed to parse %q: %q is not a name=quantity pair", key, pair)
+			}
+			q, err := resource.ParseQuantity(strings.TrimSpace(kv[1]))
+			if err != nil {
+				return fmt.Errorf("failed to parse %q: %w", key, err)
+			}
+			rl[corev1.ResourceName(strings.TrimSpace(kv[0]))] = q
+		}
+		*target = rl
+		return nil
+	}
+}
+
+// isExtendedResourceName returns whether the resource name is one of an
+// extended resource, i.e. a domain-prefixed name outside of kubernetes.io.
+func isExtendedResourceName(name corev1.ResourceName) bool {
+	s := string(name)
+	if !strings.Contains(s, "/") || strings.HasPrefix(s, corev1.ResourceDefaultNamespacePrefix) ||
+		strings.HasPrefix(s, corev1.DefaultResourceRequestsPrefix) {
+		return false
+	}
+	return len(validation.IsQualifiedName(s)) == 0
+}
+
 // NewDefaultsConfigFromMap creates a Defaults from the supplied Map.
 func NewDefaultsConfigFromMap(data map[string]string) (*Defaults, error) {
 	nc := defaultDefaultsConfig()
@@ -111,11 +168,16 @@ func NewDefaultsConfigFromMap(data map[string]string) (*Defaults, error) {
 
 		cm.AsBool("allow-container-concurrency-zero", &nc.AllowContainerConcurrencyZero),
 		asTriState("enable-service-links", &nc.EnableServiceLinks, nil),
+		asTriState("automount-service-account-token", &nc.AutomountServiceAccountToken, nil),
 
 		cm.AsInt64("revision-timeout-seconds", &nc.RevisionTimeoutSeconds),
 		cm.AsInt64("max-revision-timeout-seconds", &nc.MaxRevisionTimeoutSeconds),
+		cm.AsInt64("max-termination-grace-period-seconds", &nc.MaxTerminationGracePeriodSeconds),
 		cm.AsInt64("container-concurrency", &nc.ContainerConcurrency),
 		cm.AsInt64("container-concurrency-max-limit", &nc.ContainerConcurrencyMaxLimit),
+		cm.AsInt64("max-container-count", &nc.MaxContainerCount),
+		asOptionalInt64("gpu-container-concurrency", &nc.GPUContainerConcurrency),
+		cm.AsStringSet("gpu-resource-names", &nc.GPUResourceNames),
 
 		cm.AsQuantity("revision-cpu-request", &nc.RevisionCPURequest),
 		cm.AsQuantity("revision-memory-request", &nc.RevisionMemoryRequest),
@@ -123,6 +185,13 @@ func NewDefaultsConfigFromMap(data map[string]string) (*Defaults, error) {
 		cm.AsQuantity("revision-cpu-limit", &nc.RevisionCPULimit),
 		cm.AsQuantity("revision-memory-limit", &nc.RevisionMemoryLimit),
 		cm.AsQuantity("revision-ephemeral-storage-limit", &nc.RevisionEphemeralStorageLimit),
+		asResourceList("revision-extended-resource-limits", &nc.RevisionExtendedResourceLimits),
+
+		cm.AsStringSet("propagate-labels", &nc.PropagateLabels),
+		cm.AsStringSet("propagate-labels-exclude", &nc.PropagateLabelsExclude),
+		cm.AsStringSet("propagate-annotations", &nc.PropagateAnnotations),
+		cm.AsStringSet("propagate-annotations-exclude", &nc.PropagateAnnotationsExclude),
+		cm.AsQuantity("max-image-size", &nc.MaxImageSize),
 	); err != nil {
 		return nil, err
 	}
@@ -130,6 +199,10 @@ func NewDefaultsConfigFromMap(data map[string]string) (*Defaults, error) {
 	if nc.RevisionTimeoutSeconds > nc.MaxRevisionTimeoutSeconds {
 		return nil, fmt.Errorf("revision-timeout-seconds (%d) cannot be greater than max-revision-timeout-seconds (%d)", nc.RevisionTimeoutSeconds, nc.MaxRevisionTimeoutSeconds)
 	}
+	if nc.MaxTerminationGracePeriodSeconds < 0 {
+		return nil, apis.ErrOutOfBoundsValue(
+			nc.MaxTerminationGracePeriodSeconds, 0, math.MaxInt64, "max-termination-grace-period-seconds")
+	}
 	if nc.ContainerConcurrencyMaxLimit < 1 {
 		return nil, apis.ErrOutOfBoundsValue(
 			nc.ContainerConcurrencyMaxLimit, 1, math.MaxInt32, "container-concurrency-max-limit")
@@ -138,6 +211,45 @@ func NewDefaultsConfigFromMap(data map[string]string) (*Defaults, error) {
 		return nil, apis.ErrOutOfBoundsValue(
 			nc.ContainerConcurrency, 0, nc.ContainerConcurrencyMaxLimit, "container-concurrency")
 	}
+	if cc := nc.GPUContainerConcurrency; cc != nil && (*cc < 0 || *cc > nc.ContainerConcurrencyMaxLimit) {
+		return nil, apis.ErrOutOfBoundsValue(
+			*cc, 0, nc.ContainerConcurrencyMaxLimit, "gpu-container-concurrency")
+	}
+	for _, name := range nc.GPUResourceNames.List() {
+		if !isExtendedResourceName(corev1.ResourceName(name)) {
+			return nil, fmt.Errorf("gpu-resource-names: %q is not an extended resource name", name)
+		}
+	}
+	for name, q := range nc.RevisionExtendedResourceLimits {
+		if !isExtendedResourceName(name) {
+			return nil, fmt.Errorf("revision-extended-resource-limits: %q is not an extended resource name", name)
+		}
+		// Extended resources cannot be overcommitted, so only whole units count.
+		if q.Sign() <= 0 || q.MilliValue()%1000 != 0 {
+			return nil, fmt.Errorf("revision-extended-resource-limits: %s must be a positive integer, was %s", name, q.String())
+		}
+	}
+	if nc.MaxContainerCount < 0 {
+		return nil, apis.ErrOutOfBoundsValue(
+			nc.MaxContainerCount, 0, math.MaxInt32, "max-container-count")
+	}
+	if nc.MaxImageSize != nil && nc.MaxImageSize.Sign() < 0 {
+		return nil, fmt.Errorf("max-image-size (%s) cannot be negative", nc.MaxImageSize)
+	}
+	for _, r := range []struct {
+		name           string
+		request, limit *resource.Quantity
+	}{
+		{"cpu", nc.RevisionCPURequest, nc.RevisionCPULimit},
+		{"memory", nc.RevisionMemoryRequest, nc.RevisionMemoryLimit},
+		{"ephemeral-storage", nc.RevisionEphemeralStorageRequest, nc.RevisionEphemeralStorageLimit},
+	} {
+		// The pods of the user containers defaulted to both would be rejected.
+		if r.request != nil && r.limit != nil && r.request.Cmp(*r.limit) > 0 {
+			return nil, fmt.Errorf("revision-%[1]s-request (%[2]s) cannot be greater than revision-%[1]s-limit (%[3]s)",
+				r.name, r.request, r.limit)
+		}
+	}
 
 	tmpl, err := template.New("user-container").Parse(nc.UserContainerNameTemplate)
 	if err != nil {
@@ -164,6 +276,10 @@ type Defaults struct {
 	// RevisionTimeoutSeconds must be less than this value.
 	MaxRevisionTimeoutSeconds int64
 
+	// MaxTerminationGracePeriodSeconds is the maximum termination grace period
+	// a revision may specify. Zero means MaxRevisionTimeoutSeconds.
+	MaxTerminationGracePeriodSeconds int64
+
 	UserContainerNameTemplate string
 
 	ContainerConcurrency int64
@@ -180,12 +296,117 @@ type Defaults struct {
 	// See: https://github.com/knative/serving/issues/8498 for details.
 	EnableServiceLinks *bool
 
+	// AutomountServiceAccountToken permits defaulting of the
+	// `automountServiceAccountToken` pod spec field, e.g. to not mount the
+	// tokens of the Kubernetes API into pods which don't need them.
+	AutomountServiceAccountToken *bool
+
+	// MaxContainerCount is the maximum number of containers a revision
+	// may specify. Zero means there is no limit.
+	MaxContainerCount int64
+
+	// MaxImageSize is the maximum total size of the layers of a resolved
+	// container image. Nil means there is no limit.
+	MaxImageSize *resource.Quantity
+
 	RevisionCPURequest              *resource.Quantity
 	RevisionCPULimit                *resource.Quantity
 	RevisionMemoryRequest           *resource.Quantity
 	RevisionMemoryLimit             *resource.Quantity
 	RevisionEphemeralStorageRequest *resource.Quantity
 	RevisionEphemeralStorageLimit   *resource.Quantity
+
+	// RevisionExtendedResourceLimits are the default limits of extended
+	// resources, e.g. nvidia.com/gpu, of the serving container of revisions.
+	// Kubernetes defaults their requests to them.
+	RevisionExtendedResourceLimits corev1.ResourceList
+
+	// GPUResourceNames are the extended resources whose requests or limits
+	// make a revision a GPU revision. Nil means nvidia.com/gpu and amd.com/gpu.
+	GPUResourceNames sets.String
+
+	// GPUContainerConcurrency is the default container concurrency of GPU
+	// revisions. Nil means ContainerConcurrency is used.
+	GPUContainerConcurrency *int64
+
+	// PropagateLabels and PropagateAnnotations are the patterns, with `*`
+	// wildcards, of the keys of the labels and annotations of Configurations
+	// propagated to their Revisions, and so to their Deployments and Pods,
+	// unless they match the patterns of PropagateLabelsExclude and
+	// PropagateAnnotationsExclude.
+	PropagateLabels             sets.String
+	PropagateLabelsExclude      sets.String
+	PropagateAnnotations        sets.String
+	PropagateAnnotationsExclude sets.String
+}
+
+// TerminationGracePeriodSecondsLimit returns the maximum termination grace
+// period a revision may specify.
+func (d *Defaults) TerminationGracePeriodSecondsLimit() int64 {
+	if d.MaxTerminationGracePeriodSeconds > 0 {
+		return d.MaxTerminationGracePeriodSeconds
+	}
+	return d.MaxRevisionTimeoutSeconds
+}
+
+// IsGPUResource returns whether the resource makes a revision a GPU revision.
+func (d *Defaults) IsGPUResource(name corev1.ResourceName) bool {
+	if d.GPUResourceNames == nil {
+		return defaultGPUResourceNames.Has(string(name))
+	}
+	return d.GPUResourceNames.Has(string(name))
+}
+
+// PropagatesLabel returns whether the label of the given key of Configurations
+// propagates to their Revisions.
+func (d *Defaults) PropagatesLabel(key string) bool {
+	return propagates(d.PropagateLabels, d.PropagateLabelsExclude, key)
+}
+
+// PropagatesAnnotation returns whether the annotation of the given key of
+// Configurations propagates to their Revisions.
+func (d *Defaults) PropagatesAnnotation(key string) bool {
+	return propagates(d.PropagateAnnotations, d.PropagateAnnotationsExclude, key)
+}
+
+// propagates returns whether the key matches one of the included patterns and
+// none of the excluded ones. The keys of Knative are never propagated, as
+// they are managed for each resource.
+func propagates(include, exclude sets.String, key string) bool {
+	if strings.Contains(key, "knative.dev/") {
+		return false
+	}
+	return matchesAny(include, key) && !matchesAny(exclude, key)
+}
+
+// matchesAny returns whether the key matches one of the patterns, whose `*`
+// match any sequence of characters.
+func matchesAny(patterns sets.String, key string) bool {
+	for pattern := range patterns {
+		if globMatch(pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func globMatch(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, parts[len(parts)-1])
 }
 
 // UserContainerName returns the name of the user container based on the context.
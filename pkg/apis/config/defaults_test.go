@@ -24,6 +24,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/ptr"
 	"knative.dev/pkg/system"
@@ -50,7 +51,13 @@ func TestDefaultsConfigurationFromFile(t *testing.T) {
 	got.RevisionCPULimit, got.RevisionCPURequest = nil, nil
 	got.RevisionMemoryLimit, got.RevisionMemoryRequest = nil, nil
 	got.RevisionEphemeralStorageLimit, got.RevisionEphemeralStorageRequest = nil, nil
+	got.MaxImageSize = nil
+	got.RevisionExtendedResourceLimits, got.GPUContainerConcurrency = nil, nil
+	got.PropagateLabels, got.PropagateLabelsExclude = nil, nil
+	got.PropagateAnnotations, got.PropagateAnnotationsExclude = nil, nil
 	want := defaultDefaultsConfig()
+	// The example spells out the default GPU resource names.
+	want.GPUResourceNames = defaultGPUResourceNames
 	if diff := cmp.Diff(want, got); diff != "" {
 		t.Error("Example does not represent default config: diff(-want,+got)\n", diff)
 	}
@@ -58,6 +65,7 @@ func TestDefaultsConfigurationFromFile(t *testing.T) {
 
 func TestDefaultsConfiguration(t *testing.T) {
 	oneTwoThree := resource.MustParse("123m")
+	oneGi := resource.MustParse("1Gi")
 
 	configTests := []struct {
 		name         string
@@ -89,6 +97,145 @@ func TestDefaultsConfiguration(t *testing.T) {
 			"allow-container-concurrency-zero": "false",
 			"enable-service-links":             "true",
 		},
+	}, {
+		name:    "container and image limits",
+		wantErr: false,
+		wantDefaults: &Defaults{
+			RevisionTimeoutSeconds:        DefaultRevisionTimeoutSeconds,
+			MaxRevisionTimeoutSeconds:     DefaultMaxRevisionTimeoutSeconds,
+			UserContainerNameTemplate:     DefaultUserContainerName,
+			ContainerConcurrencyMaxLimit:  DefaultMaxRevisionContainerConcurrency,
+			AllowContainerConcurrencyZero: DefaultAllowContainerConcurrencyZero,
+			EnableServiceLinks:            ptr.Bool(false),
+			MaxContainerCount:             3,
+			MaxImageSize:                  &oneGi,
+		},
+		data: map[string]string{
+			"max-container-count": "3",
+			"max-image-size":      "1Gi",
+		},
+	}, {
+		name:    "max termination grace period",
+		wantErr: false,
+		wantDefaults: &Defaults{
+			RevisionTimeoutSeconds:           DefaultRevisionTimeoutSeconds,
+			MaxRevisionTimeoutSeconds:        DefaultMaxRevisionTimeoutSeconds,
+			MaxTerminationGracePeriodSeconds: 3600,
+			UserContainerNameTemplate:        DefaultUserContainerName,
+			ContainerConcurrencyMaxLimit:     DefaultMaxRevisionContainerConcurrency,
+			AllowContainerConcurrencyZero:    DefaultAllowContainerConcurrencyZero,
+			EnableServiceLinks:               ptr.Bool(false),
+		},
+		data: map[string]string{
+			"max-termination-grace-period-seconds": "3600",
+		},
+	}, {
+		name:    "max termination grace period is negative",
+		wantErr: true,
+		data: map[string]string{
+			"max-termination-grace-period-seconds": "-1",
+		},
+	}, {
+		name:    "max container count is negative",
+		wantErr: true,
+		data: map[string]string{
+			"max-container-count": "-1",
+		},
+	}, {
+		name:    "max image size is negative",
+		wantErr: true,
+		data: map[string]string{
+			"max-image-size": "-1Gi",
+		},
+	}, {
+		name:    "ephemeral storage request greater than limit",
+		wantErr: true,
+		data: map[string]string{
+			"revision-ephemeral-storage-request": "1G",
+			"revision-ephemeral-storage-limit":   "500M",
+		},
+	}, {
+		name:    "cpu request greater than limit",
+		wantErr: true,
+		data: map[string]string{
+			"revision-cpu-request": "2",
+			"revision-cpu-limit":   "1",
+		},
+	}, {
+		name:    "gpu revisions",
+		wantErr: false,
+		wantDefaults: &Defaults{
+			RevisionTimeoutSeconds:         DefaultRevisionTimeoutSeconds,
+			MaxRevisionTimeoutSeconds:      DefaultMaxRevisionTimeoutSeconds,
+			UserContainerNameTemplate:      DefaultUserContainerName,
+			ContainerConcurrencyMaxLimit:   DefaultMaxRevisionContainerConcurrency,
+			AllowContainerConcurrencyZero:  DefaultAllowContainerConcurrencyZero,
+			EnableServiceLinks:             ptr.Bool(false),
+			RevisionExtendedResourceLimits: corev1.ResourceList{"example.com/tpu": resource.MustParse("2")},
+			GPUResourceNames:               sets.NewString("example.com/tpu"),
+			GPUContainerConcurrency:        ptr.Int64(1),
+		},
+		data: map[string]string{
+			"revision-extended-resource-limits": "example.com/tpu=2",
+			"gpu-resource-names":                "example.com/tpu",
+			"gpu-container-concurrency":         "1",
+		},
+	}, {
+		name:    "propagated labels and annotations",
+		wantErr: false,
+		wantDefaults: &Defaults{
+			RevisionTimeoutSeconds:        DefaultRevisionTimeoutSeconds,
+			MaxRevisionTimeoutSeconds:     DefaultMaxRevisionTimeoutSeconds,
+			UserContainerNameTemplate:     DefaultUserContainerName,
+			ContainerConcurrencyMaxLimit:  DefaultMaxRevisionContainerConcurrency,
+			AllowContainerConcurrencyZero: DefaultAllowContainerConcurrencyZero,
+			EnableServiceLinks:            ptr.Bool(false),
+			PropagateLabels:               sets.NewString("example.com/*", "team"),
+			PropagateLabelsExclude:        sets.NewString("example.com/internal-*"),
+			PropagateAnnotations:          sets.NewString("*"),
+		},
+		data: map[string]string{
+			"propagate-labels":         "example.com/*,team",
+			"propagate-labels-exclude": "example.com/internal-*",
+			"propagate-annotations":    "*",
+		},
+	}, {
+		name:    "gpu container concurrency above the max limit",
+		wantErr: true,
+		data: map[string]string{
+			"gpu-container-concurrency":       "11",
+			"container-concurrency-max-limit": "10",
+		},
+	}, {
+		name:    "gpu container concurrency negative",
+		wantErr: true,
+		data: map[string]string{
+			"gpu-container-concurrency": "-1",
+		},
+	}, {
+		name:    "gpu resource name not extended",
+		wantErr: true,
+		data: map[string]string{
+			"gpu-resource-names": "cpu",
+		},
+	}, {
+		name:    "extended resource limit not extended",
+		wantErr: true,
+		data: map[string]string{
+			"revision-extended-resource-limits": "memory=1Gi",
+		},
+	}, {
+		name:    "extended resource limit fractional",
+		wantErr: true,
+		data: map[string]string{
+			"revision-extended-resource-limits": "nvidia.com/gpu=500m",
+		},
+	}, {
+		name:    "extended resource limit not a pair",
+		wantErr: true,
+		data: map[string]string{
+			"revision-extended-resource-limits": "nvidia.com/gpu",
+		},
 	}, {
 		name:    "service links false",
 		wantErr: false,
@@ -117,6 +264,21 @@ func TestDefaultsConfiguration(t *testing.T) {
 		data: map[string]string{
 			"enable-service-links": "default",
 		},
+	}, {
+		name:    "automount service account token false",
+		wantErr: false,
+		wantDefaults: &Defaults{
+			RevisionTimeoutSeconds:        DefaultRevisionTimeoutSeconds,
+			MaxRevisionTimeoutSeconds:     DefaultMaxRevisionTimeoutSeconds,
+			UserContainerNameTemplate:     DefaultUserContainerName,
+			ContainerConcurrencyMaxLimit:  DefaultMaxRevisionContainerConcurrency,
+			AllowContainerConcurrencyZero: true,
+			EnableServiceLinks:            ptr.Bool(false),
+			AutomountServiceAccountToken:  ptr.Bool(false),
+		},
+		data: map[string]string{
+			"automount-service-account-token": "false",
+		},
 	}, {
 		name:    "invalid allow container concurrency zero flag value",
 		wantErr: true,
@@ -265,3 +427,34 @@ func TestTemplating(t *testing.T) {
 		}
 	})
 }
+
+func TestPropagates(t *testing.T) {
+	d := &Defaults{
+		PropagateLabels:        sets.NewString("example.com/*", "team", "*-owner"),
+		PropagateLabelsExclude: sets.NewString("example.com/internal-*"),
+		PropagateAnnotations:   sets.NewString("*"),
+	}
+
+	for key, want := range map[string]bool{
+		"example.com/app":           true,
+		"example.com/internal-cost": false,
+		"team":                      true,
+		"teams":                     false,
+		"billing-owner":             true,
+		"other.com/app":             false,
+		"serving.knative.dev/route": false,
+	} {
+		if got := d.PropagatesLabel(key); got != want {
+			t.Errorf("PropagatesLabel(%q) = %v, want: %v", key, got, want)
+		}
+	}
+	if !d.PropagatesAnnotation("example.com/any") {
+		t.Error("PropagatesAnnotation(example.com/any) = false, want: true")
+	}
+	if d.PropagatesAnnotation("serving.knative.dev/creator") {
+		t.Error("PropagatesAnnotation(serving.knative.dev/creator) = true, want: false")
+	}
+	if (&Defaults{}).PropagatesLabel("team") {
+		t.Error("PropagatesLabel(team) = true without patterns, want: false")
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	cm "knative.dev/pkg/configmap"
 )
 
@@ -41,16 +42,35 @@ const (
 
 func defaultFeaturesConfig() *Features {
 	return &Features{
-		MultiContainer:          Enabled,
-		PodSpecAffinity:         Disabled,
-		PodSpecDryRun:           Allowed,
-		PodSpecFieldRef:         Disabled,
-		PodSpecNodeSelector:     Disabled,
-		PodSpecRuntimeClassName: Disabled,
-		PodSpecSecurityContext:  Disabled,
-		PodSpecTolerations:      Disabled,
-		ResponsiveRevisionGC:    Enabled,
-		TagHeaderBasedRouting:   Disabled,
+		DebugContainers:              Disabled,
+		ImageDigestPinning:           Disabled,
+		ImageMetadata:                Disabled,
+		MultiContainer:               Enabled,
+		MultiContainerProbing:        Disabled,
+		MultiPort:                    Disabled,
+		PodSpecAffinity:              Disabled,
+		PodSpecDNSConfig:             Disabled,
+		PodSpecDNSPolicy:             Disabled,
+		PodSpecDryRun:                Allowed,
+		PodSpecFieldRef:              Disabled,
+		PodSpecHostAliases:           Disabled,
+		PodSpecInitContainers:        Disabled,
+		PodSpecLifecycle:             Disabled,
+		PodSpecNodeSelector:          Disabled,
+		PodSpecPersistentVolumeClaim: Disabled,
+		PodSpecPriorityClassName:     Disabled,
+		PodSpecRuntimeClassName:      Disabled,
+		PodSpecSecurityContext:       Disabled,
+		PodSpecShareProcessNamespace: Disabled,
+		PodSpecTolerations:           Disabled,
+		PodSpecVolumesEmptyDir:       Disabled,
+		ProbePassthrough:             Disabled,
+		ResourceRecommendations:      Disabled,
+		ResponsiveRevisionGC:         Enabled,
+		SecurePodDefaults:            Disabled,
+		TagHeaderBasedRouting:        Disabled,
+		TrafficHeaderMatching:        Disabled,
+		TrafficPathMatching:          Disabled,
 	}
 }
 
@@ -59,16 +79,37 @@ func NewFeaturesConfigFromMap(data map[string]string) (*Features, error) {
 	nc := defaultFeaturesConfig()
 
 	if err := cm.Parse(data,
+		asFlag("debug-containers", &nc.DebugContainers),
+		asFlag("image-digest-pinning", &nc.ImageDigestPinning),
+		asNameSet("image-registries-allowlist", &nc.ImageRegistriesAllowlist),
+		asFlag("image-metadata", &nc.ImageMetadata),
 		asFlag("multi-container", &nc.MultiContainer),
+		asFlag("multi-container-probing", &nc.MultiContainerProbing),
+		asFlag("multi-port", &nc.MultiPort),
 		asFlag("kubernetes.podspec-affinity", &nc.PodSpecAffinity),
+		asFlag("kubernetes.podspec-dnsconfig", &nc.PodSpecDNSConfig),
+		asFlag("kubernetes.podspec-dnspolicy", &nc.PodSpecDNSPolicy),
 		asFlag("kubernetes.podspec-dryrun", &nc.PodSpecDryRun),
 		asFlag("kubernetes.podspec-fieldref", &nc.PodSpecFieldRef),
+		asFlag("kubernetes.podspec-hostaliases", &nc.PodSpecHostAliases),
+		asFlag("kubernetes.podspec-init-containers", &nc.PodSpecInitContainers),
+		asFlag("kubernetes.podspec-lifecycle", &nc.PodSpecLifecycle),
 		asFlag("kubernetes.podspec-nodeselector", &nc.PodSpecNodeSelector),
+		asFlag("kubernetes.podspec-persistent-volume-claim", &nc.PodSpecPersistentVolumeClaim),
+		asFlag("kubernetes.podspec-priorityclassname", &nc.PodSpecPriorityClassName),
 		asFlag("kubernetes.podspec-runtimeclassname", &nc.PodSpecRuntimeClassName),
+		asNameSet("kubernetes.podspec-runtimeclassname-allowlist", &nc.PodSpecRuntimeClassNameAllowlist),
 		asFlag("kubernetes.podspec-securitycontext", &nc.PodSpecSecurityContext),
+		asFlag("kubernetes.podspec-shareprocessnamespace", &nc.PodSpecShareProcessNamespace),
 		asFlag("kubernetes.podspec-tolerations", &nc.PodSpecTolerations),
+		asFlag("kubernetes.podspec-volumes-emptydir", &nc.PodSpecVolumesEmptyDir),
+		asFlag("probe-passthrough", &nc.ProbePassthrough),
+		asFlag("resource-recommendations", &nc.ResourceRecommendations),
 		asFlag("responsive-revision-gc", &nc.ResponsiveRevisionGC),
-		asFlag("tag-header-based-routing", &nc.TagHeaderBasedRouting)); err != nil {
+		asFlag("secure-pod-defaults", &nc.SecurePodDefaults),
+		asFlag("tag-header-based-routing", &nc.TagHeaderBasedRouting),
+		asFlag("traffic-header-matching", &nc.TrafficHeaderMatching),
+		asFlag("traffic-path-matching", &nc.TrafficPathMatching)); err != nil {
 		return nil, err
 	}
 	return nc, nil
@@ -81,16 +122,63 @@ func NewFeaturesConfigFromConfigMap(config *corev1.ConfigMap) (*Features, error)
 
 // Features specifies which features are allowed by the webhook.
 type Features struct {
-	MultiContainer          Flag
-	PodSpecAffinity         Flag
-	PodSpecDryRun           Flag
-	PodSpecFieldRef         Flag
-	PodSpecNodeSelector     Flag
-	PodSpecRuntimeClassName Flag
-	PodSpecSecurityContext  Flag
-	PodSpecTolerations      Flag
-	ResponsiveRevisionGC    Flag
-	TagHeaderBasedRouting   Flag
+	DebugContainers              Flag
+	ImageDigestPinning           Flag
+	ImageMetadata                Flag
+	MultiContainer               Flag
+	MultiContainerProbing        Flag
+	MultiPort                    Flag
+	PodSpecAffinity              Flag
+	PodSpecDNSConfig             Flag
+	PodSpecDNSPolicy             Flag
+	PodSpecDryRun                Flag
+	PodSpecFieldRef              Flag
+	PodSpecHostAliases           Flag
+	PodSpecInitContainers        Flag
+	PodSpecLifecycle             Flag
+	PodSpecNodeSelector          Flag
+	PodSpecPersistentVolumeClaim Flag
+	PodSpecPriorityClassName     Flag
+	PodSpecRuntimeClassName      Flag
+	PodSpecSecurityContext       Flag
+	PodSpecShareProcessNamespace Flag
+	PodSpecTolerations           Flag
+	PodSpecVolumesEmptyDir       Flag
+	ProbePassthrough             Flag
+	ResourceRecommendations      Flag
+	ResponsiveRevisionGC         Flag
+	SecurePodDefaults            Flag
+	TagHeaderBasedRouting        Flag
+	TrafficHeaderMatching        Flag
+	TrafficPathMatching          Flag
+
+	// PodSpecRuntimeClassNameAllowlist are the runtimeClassNames the
+	// revisions may select, any if empty.
+	PodSpecRuntimeClassNameAllowlist sets.String
+
+	// ImageRegistriesAllowlist are the prefixes of the repositories, e.g.
+	// "gcr.io/my-project/", the images of the revisions must be pulled from,
+	// any if empty.
+	ImageRegistriesAllowlist sets.String
+}
+
+// asNameSet parses the comma separated names at key into the target, if it
+// exists, leaving it nil if there are none.
+func asNameSet(key string, target *sets.String) cm.ParseFunc {
+	return func(data map[string]string) error {
+		if raw, ok := data[key]; ok {
+			names := sets.NewString()
+			for _, name := range strings.Split(raw, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					names.Insert(name)
+				}
+			}
+			if names.Len() > 0 {
+				*target = names
+			}
+		}
+		return nil
+	}
 }
 
 // asFlag parses the value at key as a Flag into the target, if it exists.
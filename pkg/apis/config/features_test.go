@@ -22,6 +22,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	. "knative.dev/pkg/configmap/testing"
 	_ "knative.dev/pkg/system/testing"
 )
@@ -59,26 +60,116 @@ func TestFeaturesConfiguration(t *testing.T) {
 		name:    "features Enabled",
 		wantErr: false,
 		wantFeatures: defaultWith(&Features{
-			MultiContainer:          Enabled,
-			PodSpecAffinity:         Enabled,
-			PodSpecDryRun:           Enabled,
-			PodSpecNodeSelector:     Enabled,
-			PodSpecRuntimeClassName: Enabled,
-			PodSpecSecurityContext:  Enabled,
-			PodSpecTolerations:      Enabled,
-			ResponsiveRevisionGC:    Enabled,
-			TagHeaderBasedRouting:   Enabled,
+			DebugContainers:              Enabled,
+			ImageMetadata:                Enabled,
+			MultiContainer:               Enabled,
+			MultiContainerProbing:        Enabled,
+			MultiPort:                    Enabled,
+			PodSpecAffinity:              Enabled,
+			PodSpecDNSConfig:             Enabled,
+			PodSpecDNSPolicy:             Enabled,
+			PodSpecDryRun:                Enabled,
+			PodSpecHostAliases:           Enabled,
+			PodSpecInitContainers:        Enabled,
+			PodSpecLifecycle:             Enabled,
+			PodSpecNodeSelector:          Enabled,
+			PodSpecPersistentVolumeClaim: Enabled,
+			PodSpecPriorityClassName:     Enabled,
+			PodSpecRuntimeClassName:      Enabled,
+			PodSpecSecurityContext:       Enabled,
+			PodSpecShareProcessNamespace: Enabled,
+			PodSpecTolerations:           Enabled,
+			PodSpecVolumesEmptyDir:       Enabled,
+			ProbePassthrough:             Enabled,
+			ResourceRecommendations:      Enabled,
+			ResponsiveRevisionGC:         Enabled,
+			SecurePodDefaults:            Enabled,
+			TagHeaderBasedRouting:        Enabled,
+			TrafficHeaderMatching:        Enabled,
+			TrafficPathMatching:          Enabled,
 		}),
 		data: map[string]string{
-			"multi-container":                     "Enabled",
-			"kubernetes.podspec-affinity":         "Enabled",
-			"kubernetes.podspec-dryrun":           "Enabled",
-			"kubernetes.podspec-nodeselector":     "Enabled",
-			"kubernetes.podspec-runtimeclassname": "Enabled",
-			"kubernetes.podspec-securitycontext":  "Enabled",
-			"kubernetes.podspec-tolerations":      "Enabled",
-			"responsive-revision-gc":              "Enabled",
-			"tag-header-based-routing":            "Enabled",
+			"debug-containers":                           "Enabled",
+			"image-metadata":                             "Enabled",
+			"multi-container":                            "Enabled",
+			"multi-container-probing":                    "Enabled",
+			"multi-port":                                 "Enabled",
+			"kubernetes.podspec-affinity":                "Enabled",
+			"kubernetes.podspec-dnsconfig":               "Enabled",
+			"kubernetes.podspec-dnspolicy":               "Enabled",
+			"kubernetes.podspec-dryrun":                  "Enabled",
+			"kubernetes.podspec-hostaliases":             "Enabled",
+			"kubernetes.podspec-init-containers":         "Enabled",
+			"kubernetes.podspec-lifecycle":               "Enabled",
+			"kubernetes.podspec-nodeselector":            "Enabled",
+			"kubernetes.podspec-persistent-volume-claim": "Enabled",
+			"kubernetes.podspec-priorityclassname":       "Enabled",
+			"kubernetes.podspec-runtimeclassname":        "Enabled",
+			"kubernetes.podspec-securitycontext":         "Enabled",
+			"kubernetes.podspec-shareprocessnamespace":   "Enabled",
+			"kubernetes.podspec-tolerations":             "Enabled",
+			"kubernetes.podspec-volumes-emptydir":        "Enabled",
+			"probe-passthrough":                          "Enabled",
+			"resource-recommendations":                   "Enabled",
+			"responsive-revision-gc":                     "Enabled",
+			"secure-pod-defaults":                        "Enabled",
+			"tag-header-based-routing":                   "Enabled",
+			"traffic-header-matching":                    "Enabled",
+			"traffic-path-matching":                      "Enabled",
+		},
+	}, {
+		name:    "image-metadata Enabled",
+		wantErr: false,
+		wantFeatures: defaultWith(&Features{
+			ImageMetadata: Enabled,
+		}),
+		data: map[string]string{
+			"image-metadata": "Enabled",
+		},
+	}, {
+		name:    "resource-recommendations Enabled",
+		wantErr: false,
+		wantFeatures: defaultWith(&Features{
+			ResourceRecommendations: Enabled,
+		}),
+		data: map[string]string{
+			"resource-recommendations": "Enabled",
+		},
+	}, {
+		name:    "multi-port Enabled",
+		wantErr: false,
+		wantFeatures: defaultWith(&Features{
+			MultiPort: Enabled,
+		}),
+		data: map[string]string{
+			"multi-port": "Enabled",
+		},
+	}, {
+		name:    "traffic-header-matching Enabled",
+		wantErr: false,
+		wantFeatures: defaultWith(&Features{
+			TrafficHeaderMatching: Enabled,
+		}),
+		data: map[string]string{
+			"traffic-header-matching": "Enabled",
+		},
+	}, {
+		name:    "traffic-path-matching Enabled",
+		wantErr: false,
+		wantFeatures: defaultWith(&Features{
+			TrafficPathMatching: Enabled,
+		}),
+		data: map[string]string{
+			"traffic-path-matching": "Enabled",
+		},
+	}, {
+		name:    "probe-passthrough Allowed",
+		wantErr: false,
+		wantFeatures: defaultWith(&Features{
+			ProbePassthrough: Allowed,
+		}),
+		data: map[string]string{
+			"probe-passthrough": "Allowed",
 		},
 	}, {
 		name:    "multi-container Allowed",
@@ -188,6 +279,60 @@ func TestFeaturesConfiguration(t *testing.T) {
 		data: map[string]string{
 			"kubernetes.podspec-nodeselector": "Disabled",
 		},
+	}, {
+		name:    "kubernetes.podspec-dnsconfig Allowed",
+		wantErr: false,
+		wantFeatures: defaultWith(&Features{
+			PodSpecDNSConfig: Allowed,
+		}),
+		data: map[string]string{
+			"kubernetes.podspec-dnsconfig": "Allowed",
+		},
+	}, {
+		name:    "kubernetes.podspec-dnspolicy Allowed",
+		wantErr: false,
+		wantFeatures: defaultWith(&Features{
+			PodSpecDNSPolicy: Allowed,
+		}),
+		data: map[string]string{
+			"kubernetes.podspec-dnspolicy": "Allowed",
+		},
+	}, {
+		name:    "kubernetes.podspec-hostaliases Allowed",
+		wantErr: false,
+		wantFeatures: defaultWith(&Features{
+			PodSpecHostAliases: Allowed,
+		}),
+		data: map[string]string{
+			"kubernetes.podspec-hostaliases": "Allowed",
+		},
+	}, {
+		name:    "kubernetes.podspec-lifecycle Allowed",
+		wantErr: false,
+		wantFeatures: defaultWith(&Features{
+			PodSpecLifecycle: Allowed,
+		}),
+		data: map[string]string{
+			"kubernetes.podspec-lifecycle": "Allowed",
+		},
+	}, {
+		name:    "kubernetes.podspec-shareprocessnamespace Allowed",
+		wantErr: false,
+		wantFeatures: defaultWith(&Features{
+			PodSpecShareProcessNamespace: Allowed,
+		}),
+		data: map[string]string{
+			"kubernetes.podspec-shareprocessnamespace": "Allowed",
+		},
+	}, {
+		name:    "kubernetes.podspec-priorityclassname Allowed",
+		wantErr: false,
+		wantFeatures: defaultWith(&Features{
+			PodSpecPriorityClassName: Allowed,
+		}),
+		data: map[string]string{
+			"kubernetes.podspec-priorityclassname": "Allowed",
+		},
 	}, {
 		name:    "kubernetes.podspec-runtimeclassname Allowed",
 		wantErr: false,
@@ -242,6 +387,76 @@ func TestFeaturesConfiguration(t *testing.T) {
 		data: map[string]string{
 			"kubernetes.podspec-tolerations": "Disabled",
 		},
+	}, {
+		name:    "kubernetes.podspec-init-containers Allowed",
+		wantErr: false,
+		wantFeatures: defaultWith(&Features{
+			PodSpecInitContainers: Allowed,
+		}),
+		data: map[string]string{
+			"kubernetes.podspec-init-containers": "Allowed",
+		},
+	}, {
+		name:    "kubernetes.podspec-volumes-emptydir Allowed",
+		wantErr: false,
+		wantFeatures: defaultWith(&Features{
+			PodSpecVolumesEmptyDir: Allowed,
+		}),
+		data: map[string]string{
+			"kubernetes.podspec-volumes-emptydir": "Allowed",
+		},
+	}, {
+		name:    "kubernetes.podspec-persistent-volume-claim Allowed",
+		wantErr: false,
+		wantFeatures: defaultWith(&Features{
+			PodSpecPersistentVolumeClaim: Allowed,
+		}),
+		data: map[string]string{
+			"kubernetes.podspec-persistent-volume-claim": "Allowed",
+		},
+	}, {
+		name:    "kubernetes.podspec-runtimeclassname-allowlist",
+		wantErr: false,
+		wantFeatures: defaultWith(&Features{
+			PodSpecRuntimeClassNameAllowlist: sets.NewString("gvisor", "kata"),
+		}),
+		data: map[string]string{
+			"kubernetes.podspec-runtimeclassname-allowlist": "gvisor, kata,",
+		},
+	}, {
+		name:         "kubernetes.podspec-runtimeclassname-allowlist empty",
+		wantErr:      false,
+		wantFeatures: defaultWith(&Features{}),
+		data: map[string]string{
+			"kubernetes.podspec-runtimeclassname-allowlist": "",
+		},
+	}, {
+		name:    "image-digest-pinning Enabled",
+		wantErr: false,
+		wantFeatures: defaultWith(&Features{
+			ImageDigestPinning: Enabled,
+		}),
+		data: map[string]string{
+			"image-digest-pinning": "Enabled",
+		},
+	}, {
+		name:    "image-registries-allowlist",
+		wantErr: false,
+		wantFeatures: defaultWith(&Features{
+			ImageRegistriesAllowlist: sets.NewString("gcr.io/my-project/", "registry.example.com/"),
+		}),
+		data: map[string]string{
+			"image-registries-allowlist": "gcr.io/my-project/, registry.example.com/",
+		},
+	}, {
+		name:    "secure-pod-defaults Allowed",
+		wantErr: false,
+		wantFeatures: defaultWith(&Features{
+			SecurePodDefaults: Allowed,
+		}),
+		data: map[string]string{
+			"secure-pod-defaults": "Allowed",
+		},
 	}, {
 		name:    "responsive-revision-gc Allowed",
 		wantErr: false,
@@ -322,8 +537,8 @@ func defaultWith(p *Features) *Features {
 	pType := reflect.ValueOf(p).Elem()
 	fType := reflect.ValueOf(f).Elem()
 	for i := 0; i < pType.NumField(); i++ {
-		if pType.Field(i).Interface().(Flag) != "" {
-			fType.Field(i).Set(pType.Field(i))
+		if v := pType.Field(i); !v.IsZero() {
+			fType.Field(i).Set(v)
 		}
 	}
 	return f
@@ -21,6 +21,8 @@ limitations under the License.
 package config
 
 import (
+	v1 "k8s.io/api/core/v1"
+	sets "k8s.io/apimachinery/pkg/util/sets"
 	autoscalerconfig "knative.dev/serving/pkg/autoscaler/config/autoscalerconfig"
 )
 
@@ -35,7 +37,7 @@ func (in *Config) DeepCopyInto(out *Config) {
 	if in.Features != nil {
 		in, out := &in.Features, &out.Features
 		*out = new(Features)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.Autoscaler != nil {
 		in, out := &in.Autoscaler, &out.Autoscaler
@@ -63,6 +65,16 @@ func (in *Defaults) DeepCopyInto(out *Defaults) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.AutomountServiceAccountToken != nil {
+		in, out := &in.AutomountServiceAccountToken, &out.AutomountServiceAccountToken
+		*out = new(bool)
+		**out = **in
+	}
+	if in.MaxImageSize != nil {
+		in, out := &in.MaxImageSize, &out.MaxImageSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
 	if in.RevisionCPURequest != nil {
 		in, out := &in.RevisionCPURequest, &out.RevisionCPURequest
 		x := (*in).DeepCopy()
@@ -93,6 +105,53 @@ func (in *Defaults) DeepCopyInto(out *Defaults) {
 		x := (*in).DeepCopy()
 		*out = &x
 	}
+	if in.RevisionExtendedResourceLimits != nil {
+		in, out := &in.RevisionExtendedResourceLimits, &out.RevisionExtendedResourceLimits
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.GPUResourceNames != nil {
+		in, out := &in.GPUResourceNames, &out.GPUResourceNames
+		*out = make(sets.String, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.GPUContainerConcurrency != nil {
+		in, out := &in.GPUContainerConcurrency, &out.GPUContainerConcurrency
+		*out = new(int64)
+		**out = **in
+	}
+	if in.PropagateLabels != nil {
+		in, out := &in.PropagateLabels, &out.PropagateLabels
+		*out = make(sets.String, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PropagateLabelsExclude != nil {
+		in, out := &in.PropagateLabelsExclude, &out.PropagateLabelsExclude
+		*out = make(sets.String, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PropagateAnnotations != nil {
+		in, out := &in.PropagateAnnotations, &out.PropagateAnnotations
+		*out = make(sets.String, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PropagateAnnotationsExclude != nil {
+		in, out := &in.PropagateAnnotationsExclude, &out.PropagateAnnotationsExclude
+		*out = make(sets.String, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -109,6 +168,20 @@ func (in *Defaults) DeepCopy() *Defaults {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Features) DeepCopyInto(out *Features) {
 	*out = *in
+	if in.PodSpecRuntimeClassNameAllowlist != nil {
+		in, out := &in.PodSpecRuntimeClassNameAllowlist, &out.PodSpecRuntimeClassNameAllowlist
+		*out = make(sets.String, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ImageRegistriesAllowlist != nil {
+		in, out := &in.ImageRegistriesAllowlist, &out.ImageRegistriesAllowlist
+		*out = make(sets.String, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -0,0 +1,86 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serving
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// ParseDeploymentStrategy parses and validates the JSON update strategy of
+// the revision deployments, as in config-deployment or
+// DeploymentStrategyAnnotationKey, e.g. {"type": "Recreate"} to not surge
+// pods with scarce resources during the updates.
+func ParseDeploymentStrategy(s string) (*appsv1.DeploymentStrategy, error) {
+	ret := &appsv1.DeploymentStrategy{}
+	if err := json.Unmarshal([]byte(s), ret); err != nil {
+		return nil, err
+	}
+	switch ret.Type {
+	case appsv1.RecreateDeploymentStrategyType:
+		if ret.RollingUpdate != nil {
+			return nil, fmt.Errorf("rollingUpdate is only allowed with type %s", appsv1.RollingUpdateDeploymentStrategyType)
+		}
+	case appsv1.RollingUpdateDeploymentStrategyType:
+		if ru := ret.RollingUpdate; ru != nil {
+			surge, err := rollingUpdateValue(ru.MaxSurge, false)
+			if err != nil {
+				return nil, fmt.Errorf("invalid maxSurge: %w", err)
+			}
+			unavailable, err := rollingUpdateValue(ru.MaxUnavailable, true)
+			if err != nil {
+				return nil, fmt.Errorf("invalid maxUnavailable: %w", err)
+			}
+			if surge == 0 && unavailable == 0 {
+				return nil, errors.New("maxSurge and maxUnavailable cannot both be 0")
+			}
+		}
+	default:
+		return nil, fmt.Errorf("type must be %s or %s, was %q",
+			appsv1.RecreateDeploymentStrategyType, appsv1.RollingUpdateDeploymentStrategyType, ret.Type)
+	}
+	return ret, nil
+}
+
+// rollingUpdateValue returns the number or the percentage of v, checking that
+// it is not negative, nor above 100% if it is a percentage and capped.
+// Unset values are reported as not 0, as Kubernetes defaults them to 25%.
+func rollingUpdateValue(v *intstr.IntOrString, capped bool) (int, error) {
+	if v == nil {
+		return 1, nil
+	}
+	if v.Type == intstr.Int {
+		if v.IntVal < 0 {
+			return 0, fmt.Errorf("%d cannot be negative", v.IntVal)
+		}
+		return int(v.IntVal), nil
+	}
+	p, err := strconv.Atoi(strings.TrimSuffix(v.StrVal, "%"))
+	if err != nil || !strings.HasSuffix(v.StrVal, "%") {
+		return 0, fmt.Errorf("%q must be a number or a percentage", v.StrVal)
+	}
+	if p < 0 || (capped && p > 100) {
+		return 0, fmt.Errorf("%q must be a percentage between 0%% and 100%%", v.StrVal)
+	}
+	return p, nil
+}
@@ -43,11 +43,12 @@ func VolumeMask(in *corev1.Volume) *corev1.Volume {
 // VolumeSourceMask performs a _shallow_ copy of the Kubernetes VolumeSource object to a new
 // Kubernetes VolumeSource object bringing over only the fields allowed in the Knative API. This
 // does not validate the contents or the bounds of the provided fields.
-func VolumeSourceMask(in *corev1.VolumeSource) *corev1.VolumeSource {
+func VolumeSourceMask(ctx context.Context, in *corev1.VolumeSource) *corev1.VolumeSource {
 	if in == nil {
 		return nil
 	}
 
+	cfg := config.FromContextOrDefaults(ctx)
 	out := new(corev1.VolumeSource)
 
 	// Allowed fields
@@ -55,6 +56,14 @@ func VolumeSourceMask(in *corev1.VolumeSource) *corev1.VolumeSource {
 	out.ConfigMap = in.ConfigMap
 	out.Projected = in.Projected
 
+	// Feature fields
+	if cfg.Features.PodSpecVolumesEmptyDir != config.Disabled {
+		out.EmptyDir = in.EmptyDir
+	}
+	if cfg.Features.PodSpecPersistentVolumeClaim != config.Disabled {
+		out.PersistentVolumeClaim = in.PersistentVolumeClaim
+	}
+
 	// Too many disallowed fields to list
 
 	return out
@@ -171,6 +180,8 @@ func PodSpecMask(ctx context.Context, in *corev1.PodSpec) *corev1.PodSpec {
 	out.Volumes = in.Volumes
 	out.ImagePullSecrets = in.ImagePullSecrets
 	out.EnableServiceLinks = in.EnableServiceLinks
+	out.AutomountServiceAccountToken = in.AutomountServiceAccountToken
+	out.TerminationGracePeriodSeconds = in.TerminationGracePeriodSeconds
 
 	// Feature fields
 	if cfg.Features.PodSpecAffinity != config.Disabled {
@@ -188,27 +199,35 @@ func PodSpecMask(ctx context.Context, in *corev1.PodSpec) *corev1.PodSpec {
 	if cfg.Features.PodSpecSecurityContext != config.Disabled {
 		out.SecurityContext = in.SecurityContext
 	}
+	if cfg.Features.PodSpecInitContainers != config.Disabled {
+		out.InitContainers = in.InitContainers
+	}
+	if cfg.Features.PodSpecDNSPolicy != config.Disabled {
+		out.DNSPolicy = in.DNSPolicy
+	}
+	if cfg.Features.PodSpecDNSConfig != config.Disabled {
+		out.DNSConfig = in.DNSConfig
+	}
+	if cfg.Features.PodSpecHostAliases != config.Disabled {
+		out.HostAliases = in.HostAliases
+	}
+	if cfg.Features.PodSpecShareProcessNamespace != config.Disabled {
+		out.ShareProcessNamespace = in.ShareProcessNamespace
+	}
 
 	// Disallowed fields
 	// This list is unnecessary, but added here for clarity
-	out.InitContainers = nil
 	out.RestartPolicy = ""
-	out.TerminationGracePeriodSeconds = nil
 	out.ActiveDeadlineSeconds = nil
-	out.DNSPolicy = ""
-	out.AutomountServiceAccountToken = nil
 	out.NodeName = ""
 	out.HostNetwork = false
 	out.HostPID = false
 	out.HostIPC = false
-	out.ShareProcessNamespace = nil
 	out.Hostname = ""
 	out.Subdomain = ""
 	out.SchedulerName = ""
-	out.HostAliases = nil
 	out.PriorityClassName = ""
 	out.Priority = nil
-	out.DNSConfig = nil
 	out.ReadinessGates = nil
 
 	return out
@@ -217,7 +236,7 @@ func PodSpecMask(ctx context.Context, in *corev1.PodSpec) *corev1.PodSpec {
 // ContainerMask performs a _shallow_ copy of the Kubernetes Container object to a new
 // Kubernetes Container object bringing over only the fields allowed in the Knative API. This
 // does not validate the contents or the bounds of the provided fields.
-func ContainerMask(in *corev1.Container) *corev1.Container {
+func ContainerMask(ctx context.Context, in *corev1.Container) *corev1.Container {
 	if in == nil {
 		return nil
 	}
@@ -238,13 +257,18 @@ func ContainerMask(in *corev1.Container) *corev1.Container {
 	out.ReadinessProbe = in.ReadinessProbe
 	out.Resources = in.Resources
 	out.SecurityContext = in.SecurityContext
+	out.StartupProbe = in.StartupProbe
 	out.TerminationMessagePath = in.TerminationMessagePath
 	out.TerminationMessagePolicy = in.TerminationMessagePolicy
 	out.VolumeMounts = in.VolumeMounts
 
+	// Feature fields
+	if config.FromContextOrDefaults(ctx).Features.PodSpecLifecycle != config.Disabled {
+		out.Lifecycle = in.Lifecycle
+	}
+
 	// Disallowed fields
 	// This list is unnecessary, but added here for clarity
-	out.Lifecycle = nil
 	out.Stdin = false
 	out.StdinOnce = false
 	out.TTY = false
@@ -592,16 +616,21 @@ func SecurityContextMask(ctx context.Context, in *corev1.SecurityContext) *corev
 	// Allowed fields
 	out.RunAsUser = in.RunAsUser
 
-	if config.FromContextOrDefaults(ctx).Features.PodSpecSecurityContext != config.Disabled {
+	features := config.FromContextOrDefaults(ctx).Features
+	if features.PodSpecSecurityContext != config.Disabled {
 		out.RunAsGroup = in.RunAsGroup
 		out.RunAsNonRoot = in.RunAsNonRoot
 	}
+	if spd := features.SecurePodDefaults; spd == config.Enabled || spd == config.Allowed {
+		// The fields opting out of the secure pod defaults.
+		out.RunAsNonRoot = in.RunAsNonRoot
+		out.ReadOnlyRootFilesystem = in.ReadOnlyRootFilesystem
+		out.Capabilities = in.Capabilities
+	}
 	// Disallowed
 	// This list is unnecessary, but added here for clarity
-	out.Capabilities = nil
 	out.Privileged = nil
 	out.SELinuxOptions = nil
-	out.ReadOnlyRootFilesystem = nil
 	out.AllowPrivilegeEscalation = nil
 	out.ProcMount = nil
 
@@ -60,9 +60,10 @@ func TestVolumeSourceMask(t *testing.T) {
 		Secret:    &corev1.SecretVolumeSource{},
 		ConfigMap: &corev1.ConfigMapVolumeSource{},
 		NFS:       &corev1.NFSVolumeSource{},
+		EmptyDir:  &corev1.EmptyDirVolumeSource{},
 	}
 
-	got := VolumeSourceMask(in)
+	got := VolumeSourceMask(context.Background(), in)
 
 	if &want == &got {
 		t.Error("Input and output share addresses. Want different addresses")
@@ -74,9 +75,20 @@ func TestVolumeSourceMask(t *testing.T) {
 		t.Error("VolumeSourceMask (-want, +got):", diff)
 	}
 
-	if got = VolumeSourceMask(nil); got != nil {
+	if got = VolumeSourceMask(context.Background(), nil); got != nil {
 		t.Errorf("VolumeSourceMask(nil) = %v, want: nil", got)
 	}
+
+	// The emptyDir is kept with the feature.
+	cfg := config.FromContextOrDefaults(context.Background())
+	cfg.Features.PodSpecVolumesEmptyDir = config.Enabled
+	want.EmptyDir = in.EmptyDir
+	got = VolumeSourceMask(config.ToContext(context.Background(), cfg), in)
+	if diff, err := kmp.SafeDiff(want, got); err != nil {
+		t.Error("Got error comparing output, err =", err)
+	} else if diff != "" {
+		t.Error("VolumeSourceMask (-want, +got):", diff)
+	}
 }
 
 func TestPodSpecMask(t *testing.T) {
@@ -96,6 +108,8 @@ func TestPodSpecMask(t *testing.T) {
 				},
 			},
 		}},
+		TerminationGracePeriodSeconds: ptr.Int64(900),
+		AutomountServiceAccountToken:  ptr.Bool(false),
 	}
 	in := &corev1.PodSpec{
 		ServiceAccountName: "default",
@@ -113,6 +127,8 @@ func TestPodSpecMask(t *testing.T) {
 				},
 			},
 		}},
+		TerminationGracePeriodSeconds: ptr.Int64(900),
+		AutomountServiceAccountToken:  ptr.Bool(false),
 		// Stripped out.
 		InitContainers: []corev1.Container{{
 			Image: "busybox",
@@ -151,6 +167,7 @@ func TestContainerMask(t *testing.T) {
 		ReadinessProbe:           &corev1.Probe{},
 		Resources:                corev1.ResourceRequirements{},
 		SecurityContext:          &corev1.SecurityContext{},
+		StartupProbe:             &corev1.Probe{},
 		TerminationMessagePath:   "/",
 		TerminationMessagePolicy: corev1.TerminationMessageReadFile,
 		VolumeMounts:             []corev1.VolumeMount{{}},
@@ -167,16 +184,18 @@ func TestContainerMask(t *testing.T) {
 		ReadinessProbe:           &corev1.Probe{},
 		Resources:                corev1.ResourceRequirements{},
 		SecurityContext:          &corev1.SecurityContext{},
+		StartupProbe:             &corev1.Probe{},
 		TerminationMessagePath:   "/",
 		TerminationMessagePolicy: corev1.TerminationMessageReadFile,
 		VolumeMounts:             []corev1.VolumeMount{{}},
 		Name:                     "foo",
+		Lifecycle:                &corev1.Lifecycle{},
 		Stdin:                    true,
 		StdinOnce:                true,
 		TTY:                      true,
 	}
 
-	got := ContainerMask(in)
+	got := ContainerMask(context.Background(), in)
 
 	if &want == &got {
 		t.Error("Input and output share addresses. Want different addresses")
@@ -188,9 +207,20 @@ func TestContainerMask(t *testing.T) {
 		t.Error("ContainerMask (-want, +got):", diff)
 	}
 
-	if got = ContainerMask(nil); got != nil {
+	if got = ContainerMask(context.Background(), nil); got != nil {
 		t.Errorf("ContainerMask(nil) = %v, want: nil", got)
 	}
+
+	// The lifecycle is kept with the feature.
+	cfg := config.FromContextOrDefaults(context.Background())
+	cfg.Features.PodSpecLifecycle = config.Enabled
+	want.Lifecycle = in.Lifecycle
+	got = ContainerMask(config.ToContext(context.Background(), cfg), in)
+	if diff, err := kmp.SafeDiff(want, got); err != nil {
+		t.Error("Got error comparing output, err =", err)
+	} else if diff != "" {
+		t.Error("ContainerMask (-want, +got):", diff)
+	}
 }
 
 func TestVolumeMountMask(t *testing.T) {
@@ -790,3 +820,45 @@ func TestSecurityContextMask_FeatureEnabled(t *testing.T) {
 		t.Error("SecurityContextMask (-want, +got):", diff)
 	}
 }
+
+func TestSecurityContextMask_SecurePodDefaults(t *testing.T) {
+	mtype := corev1.UnmaskedProcMount
+	want := &corev1.SecurityContext{
+		Capabilities: &corev1.Capabilities{
+			Add: []corev1.Capability{"NET_ADMIN"},
+		},
+		ReadOnlyRootFilesystem: ptr.Bool(false),
+		RunAsNonRoot:           ptr.Bool(false),
+		RunAsUser:              ptr.Int64(1),
+	}
+	in := &corev1.SecurityContext{
+		AllowPrivilegeEscalation: ptr.Bool(true),
+		Capabilities: &corev1.Capabilities{
+			Add: []corev1.Capability{"NET_ADMIN"},
+		},
+		Privileged:             ptr.Bool(true),
+		ProcMount:              &mtype,
+		ReadOnlyRootFilesystem: ptr.Bool(false),
+		RunAsGroup:             ptr.Int64(2),
+		RunAsNonRoot:           ptr.Bool(false),
+		RunAsUser:              ptr.Int64(1),
+		SELinuxOptions:         &corev1.SELinuxOptions{},
+	}
+
+	ctx := config.ToContext(context.Background(),
+		&config.Config{
+			Features: &config.Features{
+				PodSpecSecurityContext: config.Disabled,
+				SecurePodDefaults:      config.Allowed,
+			},
+		},
+	)
+
+	got := SecurityContextMask(ctx, in)
+
+	if diff, err := kmp.SafeDiff(want, got); err != nil {
+		t.Error("Got error comparing output, err =", err)
+	} else if diff != "" {
+		t.Error("SecurityContextMask (-want, +got):", diff)
+	}
+}
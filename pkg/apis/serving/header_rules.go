@@ -0,0 +1,55 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serving
+
+import "encoding/json"
+
+// HeaderRulesAllTargets is the key of the rules in HeaderRulesAnnotationKey
+// applying to all the traffic of the Route, save the tags with rules of their own.
+const HeaderRulesAllTargets = "*"
+
+// HeaderOperations are the manipulations of the request or the response headers.
+type HeaderOperations struct {
+	// Set are the headers to set, replacing their existing values.
+	Set map[string]string `json:"set,omitempty"`
+	// Add are the headers to add, keeping their existing values.
+	Add map[string]string `json:"add,omitempty"`
+	// Remove are the headers to strip.
+	Remove []string `json:"remove,omitempty"`
+}
+
+// HeaderRules are the manipulations of the request and the response headers
+// of the traffic of a Route, as specified by HeaderRulesAnnotationKey.
+type HeaderRules struct {
+	Request  HeaderOperations `json:"request,omitempty"`
+	Response HeaderOperations `json:"response,omitempty"`
+}
+
+// ParseHeaderRulesAnnotation returns the header rules keyed by the traffic tag,
+// or HeaderRulesAllTargets, as specified by HeaderRulesAnnotationKey, or nil
+// if the annotation is not set.
+func ParseHeaderRulesAnnotation(annotations map[string]string) (map[string]HeaderRules, error) {
+	v, ok := annotations[HeaderRulesAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+	var ret map[string]HeaderRules
+	if err := json.Unmarshal([]byte(v), &ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -0,0 +1,33 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serving
+
+import "strings"
+
+// ParseHostsAnnotation returns the custom hosts specified by HostsAnnotationKey,
+// or nil if the annotation is not set.
+func ParseHostsAnnotation(annotations map[string]string) []string {
+	v, ok := annotations[HostsAnnotationKey]
+	if !ok {
+		return nil
+	}
+	hosts := strings.Split(v, ",")
+	for i := range hosts {
+		hosts[i] = strings.TrimSpace(hosts[i])
+	}
+	return hosts
+}
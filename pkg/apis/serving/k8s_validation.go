@@ -26,6 +26,7 @@ import (
 	"github.com/google/go-containerregistry/pkg/name"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"knative.dev/pkg/apis"
@@ -37,6 +38,13 @@ import (
 const (
 	minUserID, maxUserID   = 0, math.MaxInt32
 	minGroupID, maxGroupID = 0, math.MaxInt32
+
+	// The limits of the dnsConfig of the K8s API server.
+	maxDNSNameservers, maxDNSSearches = 3, 6
+
+	// userPortName is the name the serving port is given on the pod,
+	// see v1.UserPortName.
+	userPortName = "user-port"
 )
 
 var (
@@ -58,6 +66,7 @@ var (
 		"K_SERVICE",
 		"K_CONFIGURATION",
 		"K_REVISION",
+		"K_UNIX_SOCKET",
 	)
 
 	reservedPorts = sets.NewInt32(
@@ -77,16 +86,47 @@ var (
 	validPortNames = sets.NewString(
 		"h2c",
 		"http1",
+		UnixSocketPortName,
 		"",
 	)
 )
 
-// ValidateVolumes validates the Volumes of a PodSpec.
-func ValidateVolumes(vs []corev1.Volume, mountedVolumes sets.String) (sets.String, *apis.FieldError) {
-	volumes := make(sets.String, len(vs))
+// hasServingPort returns true if the container declares the port the requests
+// are served on, as opposed to the named non-serving ports sidecars may declare.
+func hasServingPort(container *corev1.Container) bool {
+	for _, p := range container.Ports {
+		if validPortNames.Has(p.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ServingContainerIndex returns the index of the container serving the requests:
+// the only container, or the one declaring the serving port, falling back
+// to the first one declaring any ports. -1 is returned if there is none.
+func ServingContainerIndex(containers []corev1.Container) int {
+	if len(containers) == 1 {
+		return 0
+	}
+	fallback := -1
+	for i := range containers {
+		if hasServingPort(&containers[i]) {
+			return i
+		}
+		if fallback < 0 && len(containers[i].Ports) != 0 {
+			fallback = i
+		}
+	}
+	return fallback
+}
+
+// ValidateVolumes validates the Volumes of a PodSpec, returning them by name.
+func ValidateVolumes(ctx context.Context, vs []corev1.Volume, mountedVolumes sets.String) (map[string]corev1.Volume, *apis.FieldError) {
+	volumes := make(map[string]corev1.Volume, len(vs))
 	var errs *apis.FieldError
 	for i, volume := range vs {
-		if volumes.Has(volume.Name) {
+		if _, ok := volumes[volume.Name]; ok {
 			errs = errs.Also((&apis.FieldError{
 				Message: fmt.Sprintf("duplicate volume name %q", volume.Name),
 				Paths:   []string{"name"},
@@ -98,13 +138,13 @@ func ValidateVolumes(vs []corev1.Volume, mountedVolumes sets.String) (sets.Strin
 				Paths:   []string{"name"},
 			}).ViaIndex(i))
 		}
-		errs = errs.Also(validateVolume(volume).ViaIndex(i))
-		volumes.Insert(volume.Name)
+		errs = errs.Also(validateVolume(ctx, volume).ViaIndex(i))
+		volumes[volume.Name] = volume
 	}
 	return volumes, errs
 }
 
-func validateVolume(volume corev1.Volume) *apis.FieldError {
+func validateVolume(ctx context.Context, volume corev1.Volume) *apis.FieldError {
 	errs := apis.CheckDisallowedFields(volume, *VolumeMask(&volume))
 	if volume.Name == "" {
 		errs = apis.ErrMissingField("name")
@@ -113,7 +153,7 @@ func validateVolume(volume corev1.Volume) *apis.FieldError {
 	}
 
 	vs := volume.VolumeSource
-	errs = errs.Also(apis.CheckDisallowedFields(vs, *VolumeSourceMask(&vs)))
+	errs = errs.Also(apis.CheckDisallowedFields(vs, *VolumeSourceMask(ctx, &vs)))
 	specified := []string{}
 	if vs.Secret != nil {
 		specified = append(specified, "secret")
@@ -133,8 +173,26 @@ func validateVolume(volume corev1.Volume) *apis.FieldError {
 			errs = errs.Also(validateProjectedVolumeSource(proj).ViaFieldIndex("projected", i))
 		}
 	}
+	oneOf := []string{"secret", "configMap", "projected"}
+	features := config.FromContextOrDefaults(ctx).Features
+	if features.PodSpecVolumesEmptyDir != config.Disabled {
+		oneOf = append(oneOf, "emptyDir")
+		if vs.EmptyDir != nil {
+			specified = append(specified, "emptyDir")
+			errs = errs.Also(validateEmptyDir(vs.EmptyDir).ViaField("emptyDir"))
+		}
+	}
+	if features.PodSpecPersistentVolumeClaim != config.Disabled {
+		oneOf = append(oneOf, "persistentVolumeClaim")
+		if vs.PersistentVolumeClaim != nil {
+			specified = append(specified, "persistentVolumeClaim")
+			if vs.PersistentVolumeClaim.ClaimName == "" {
+				errs = errs.Also(apis.ErrMissingField("persistentVolumeClaim.claimName"))
+			}
+		}
+	}
 	if len(specified) == 0 {
-		errs = errs.Also(apis.ErrMissingOneOf("secret", "configMap", "projected"))
+		errs = errs.Also(apis.ErrMissingOneOf(oneOf...))
 	} else if len(specified) > 1 {
 		errs = errs.Also(apis.ErrMultipleOneOf(specified...))
 	}
@@ -142,6 +200,20 @@ func validateVolume(volume corev1.Volume) *apis.FieldError {
 	return errs
 }
 
+// validateEmptyDir validates the scratch space of an emptyDir volume.
+func validateEmptyDir(ed *corev1.EmptyDirVolumeSource) *apis.FieldError {
+	var errs *apis.FieldError
+	switch ed.Medium {
+	case corev1.StorageMediumDefault, corev1.StorageMediumMemory:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(ed.Medium, "medium"))
+	}
+	if ed.SizeLimit != nil && ed.SizeLimit.Sign() < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(ed.SizeLimit.String(), "sizeLimit"))
+	}
+	return errs
+}
+
 func validateProjectedVolumeSource(vp corev1.VolumeProjection) *apis.FieldError {
 	errs := apis.CheckDisallowedFields(vp, *VolumeProjectionMask(&vp))
 	specified := make([]string, 0, 1) // Most of the time there will be a success with a single element.
@@ -288,8 +360,14 @@ func ValidatePodSpec(ctx context.Context, ps corev1.PodSpec) *apis.FieldError {
 	errs := apis.CheckDisallowedFields(ps, *PodSpecMask(ctx, &ps))
 
 	errs = errs.Also(ValidatePodSecurityContext(ctx, ps.SecurityContext).ViaField("securityContext"))
-
-	volumes, err := ValidateVolumes(ps.Volumes, AllMountedVolumes(ps.Containers))
+	errs = errs.Also(validateNodeSelector(ps.NodeSelector).ViaField("nodeSelector"))
+	errs = errs.Also(validateRuntimeClassName(ctx, ps.RuntimeClassName).ViaField("runtimeClassName"))
+	errs = errs.Also(validateTolerations(ps.Tolerations).ViaField("tolerations"))
+	errs = errs.Also(validateDNS(ps.DNSPolicy, ps.DNSConfig))
+	errs = errs.Also(validateHostAliases(ps.HostAliases).ViaField("hostAliases"))
+
+	mountedVolumes := AllMountedVolumes(ps.Containers).Union(AllMountedVolumes(ps.InitContainers))
+	volumes, err := ValidateVolumes(ctx, ps.Volumes, mountedVolumes)
 	if err != nil {
 		errs = errs.Also(err.ViaField("volumes"))
 	}
@@ -303,25 +381,241 @@ func ValidatePodSpec(ctx context.Context, ps corev1.PodSpec) *apis.FieldError {
 	default:
 		errs = errs.Also(validateContainers(ctx, ps.Containers, volumes))
 	}
+	if config.FromContextOrDefaults(ctx).Features.PodSpecInitContainers != config.Disabled {
+		errs = errs.Also(validateInitContainers(ctx, ps.InitContainers, ps.Containers, volumes))
+	}
+	if max := config.FromContextOrDefaults(ctx).Defaults.MaxContainerCount; max > 0 && int64(len(ps.Containers)) > max {
+		errs = errs.Also(&apis.FieldError{
+			Message: fmt.Sprintf("found %d containers, but at most %d are allowed", len(ps.Containers), max),
+			Paths:   []string{"containers"},
+		})
+	}
 	if ps.ServiceAccountName != "" {
 		for range validation.IsDNS1123Subdomain(ps.ServiceAccountName) {
 			errs = errs.Also(apis.ErrInvalidValue("serviceAccountName", ps.ServiceAccountName))
 		}
 	}
+	errs = errs.Also(validateImagePullSecrets(ps.ImagePullSecrets).ViaField("imagePullSecrets"))
 	return errs
 }
 
-func validateContainers(ctx context.Context, containers []corev1.Container, volumes sets.String) (errs *apis.FieldError) {
+// validateImagePullSecrets validates the secrets used, along with those of the
+// service account, to pull the images and resolve their digests.
+func validateImagePullSecrets(secrets []corev1.LocalObjectReference) (errs *apis.FieldError) {
+	names := make(sets.String, len(secrets))
+	for i, s := range secrets {
+		switch {
+		case s.Name == "":
+			errs = errs.Also(apis.ErrMissingField("name").ViaIndex(i))
+		case len(validation.IsDNS1123Subdomain(s.Name)) != 0, names.Has(s.Name):
+			errs = errs.Also(apis.ErrInvalidValue(s.Name, "name").ViaIndex(i))
+		}
+		names.Insert(s.Name)
+	}
+	return errs
+}
+
+// validateNodeSelector validates the labels the nodes of the pods must have.
+func validateNodeSelector(selector map[string]string) (errs *apis.FieldError) {
+	for k, v := range selector {
+		if len(validation.IsQualifiedName(k)) != 0 {
+			errs = errs.Also(apis.ErrInvalidKeyName(k, apis.CurrentField))
+		} else if len(validation.IsValidLabelValue(v)) != 0 {
+			errs = errs.Also(apis.ErrInvalidValue(v, k))
+		}
+	}
+	return errs
+}
+
+// validateRuntimeClassName validates the runtimeClassName against the
+// allowlist of config-features, if any.
+func validateRuntimeClassName(ctx context.Context, name *string) *apis.FieldError {
+	if name == nil {
+		return nil
+	}
+	if len(validation.IsDNS1123Subdomain(*name)) != 0 {
+		return apis.ErrInvalidValue(*name, apis.CurrentField)
+	}
+	allowed := config.FromContextOrDefaults(ctx).Features.PodSpecRuntimeClassNameAllowlist
+	if allowed.Len() > 0 && !allowed.Has(*name) {
+		return &apis.FieldError{
+			Message: fmt.Sprintf("runtimeClassName %q is not allowed, must be one of: %s",
+				*name, strings.Join(allowed.List(), ", ")),
+			Paths: []string{apis.CurrentField},
+		}
+	}
+	return nil
+}
+
+// validateImagePolicy validates the image reference against the digest
+// pinning and the registries allowlist of config-features.
+func validateImagePolicy(ctx context.Context, ref name.Reference) (errs *apis.FieldError) {
+	features := config.FromContextOrDefaults(ctx).Features
+	if _, ok := ref.(name.Digest); !ok && features.ImageDigestPinning == config.Enabled {
+		errs = errs.Also(&apis.FieldError{
+			Message: fmt.Sprintf("image %q must be specified by digest", ref.String()),
+			Paths:   []string{apis.CurrentField},
+		})
+	}
+	if allowed := features.ImageRegistriesAllowlist; !IsImageRepositoryAllowed(allowed, ref) {
+		repo := ref.Context().Name()
+		errs = errs.Also(&apis.FieldError{
+			Message: fmt.Sprintf("image repository %q is not allowed, must start with one of: %s",
+				repo, strings.Join(allowed.List(), ", ")),
+			Paths: []string{apis.CurrentField},
+		})
+	}
+	return errs
+}
+
+// IsImageRepositoryAllowed returns whether the repository of the image starts
+// with one of the allowed prefixes, if any.
+func IsImageRepositoryAllowed(allowed sets.String, ref name.Reference) bool {
+	if allowed.Len() == 0 {
+		return true
+	}
+	repo := ref.Context().Name()
+	for prefix := range allowed {
+		if strings.HasPrefix(repo, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateDNS validates the dnsPolicy and the dnsConfig of the pod spec,
+// like the K8s API server would, to fail at admission rather than rollout.
+func validateDNS(policy corev1.DNSPolicy, dc *corev1.PodDNSConfig) (errs *apis.FieldError) {
+	switch policy {
+	case "", corev1.DNSClusterFirst, corev1.DNSDefault:
+	case corev1.DNSNone:
+		if dc == nil || len(dc.Nameservers) == 0 {
+			errs = errs.Also(&apis.FieldError{
+				Message: "at least one nameserver is required when dnsPolicy is None",
+				Paths:   []string{"dnsConfig.nameservers"},
+			})
+		}
+	default:
+		// ClusterFirstWithHostNet is moot without hostNetwork.
+		errs = errs.Also(apis.ErrInvalidValue(policy, "dnsPolicy"))
+	}
+	if dc == nil {
+		return errs
+	}
+
+	if len(dc.Nameservers) > maxDNSNameservers {
+		errs = errs.Also(&apis.FieldError{
+			Message: fmt.Sprintf("must not have more than %d nameservers", maxDNSNameservers),
+			Paths:   []string{"dnsConfig.nameservers"},
+		})
+	}
+	for i, ns := range dc.Nameservers {
+		if len(validation.IsValidIP(ns)) != 0 {
+			errs = errs.Also(apis.ErrInvalidArrayValue(ns, "dnsConfig.nameservers", i))
+		}
+	}
+	if len(dc.Searches) > maxDNSSearches {
+		errs = errs.Also(&apis.FieldError{
+			Message: fmt.Sprintf("must not have more than %d search paths", maxDNSSearches),
+			Paths:   []string{"dnsConfig.searches"},
+		})
+	}
+	for i, s := range dc.Searches {
+		// The search paths may be fully qualified.
+		if len(validation.IsDNS1123Subdomain(strings.TrimSuffix(s, "."))) != 0 {
+			errs = errs.Also(apis.ErrInvalidArrayValue(s, "dnsConfig.searches", i))
+		}
+	}
+	for i, o := range dc.Options {
+		if o.Name == "" {
+			errs = errs.Also(apis.ErrMissingField("name").ViaFieldIndex("dnsConfig.options", i))
+		}
+	}
+	return errs
+}
+
+// validateHostAliases validates the IPs and the hostnames of the entries added
+// to the pods' /etc/hosts.
+func validateHostAliases(aliases []corev1.HostAlias) (errs *apis.FieldError) {
+	for i, ha := range aliases {
+		var aerrs *apis.FieldError
+		if len(validation.IsValidIP(ha.IP)) != 0 {
+			aerrs = aerrs.Also(apis.ErrInvalidValue(ha.IP, "ip"))
+		}
+		if len(ha.Hostnames) == 0 {
+			aerrs = aerrs.Also(apis.ErrMissingField("hostnames"))
+		}
+		for j, h := range ha.Hostnames {
+			if len(validation.IsDNS1123Subdomain(h)) != 0 {
+				aerrs = aerrs.Also(apis.ErrInvalidArrayValue(h, "hostnames", j))
+			}
+		}
+		errs = errs.Also(aerrs.ViaIndex(i))
+	}
+	return errs
+}
+
+// validateTolerations validates the taints of the nodes the pods tolerate.
+// The affinity isn't validated beyond the field mask, it's left to the
+// K8s API server, see the PodSpecDryRun feature.
+func validateTolerations(tolerations []corev1.Toleration) (errs *apis.FieldError) {
+	for i, t := range tolerations {
+		var terrs *apis.FieldError
+		if t.Key != "" && len(validation.IsQualifiedName(t.Key)) != 0 {
+			terrs = terrs.Also(apis.ErrInvalidValue(t.Key, "key"))
+		}
+		switch t.Operator {
+		case corev1.TolerationOpEqual, "":
+			if t.Key == "" {
+				terrs = terrs.Also(&apis.FieldError{
+					Message: "operator must be Exists when key is empty",
+					Paths:   []string{"operator"},
+				})
+			}
+		case corev1.TolerationOpExists:
+			if t.Value != "" {
+				terrs = terrs.Also(&apis.FieldError{
+					Message: "value must be empty when operator is Exists",
+					Paths:   []string{"value"},
+				})
+			}
+		default:
+			terrs = terrs.Also(apis.ErrInvalidValue(t.Operator, "operator"))
+		}
+		switch t.Effect {
+		case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute, "":
+		default:
+			terrs = terrs.Also(apis.ErrInvalidValue(t.Effect, "effect"))
+		}
+		if t.TolerationSeconds != nil && t.Effect != corev1.TaintEffectNoExecute {
+			terrs = terrs.Also(&apis.FieldError{
+				Message: "effect must be NoExecute when tolerationSeconds is set",
+				Paths:   []string{"effect"},
+			})
+		}
+		errs = errs.Also(terrs.ViaIndex(i))
+	}
+	return errs
+}
+
+func validateContainers(ctx context.Context, containers []corev1.Container, volumes map[string]corev1.Volume) (errs *apis.FieldError) {
 	features := config.FromContextOrDefaults(ctx).Features
 	if features.MultiContainer != config.Enabled {
 		return errs.Also(&apis.FieldError{Message: fmt.Sprintf("multi-container is off, "+
 			"but found %d containers", len(containers))})
 	}
-	errs = errs.Also(validateContainersPorts(containers).ViaField("containers"))
+	// With the multi container probing the sidecars can declare readiness
+	// probes and non-serving ports, so the serving container is the one
+	// with the serving port.
+	isServing := func(c *corev1.Container) bool { return len(c.Ports) != 0 }
+	if features.MultiContainerProbing == config.Enabled {
+		isServing = hasServingPort
+	}
+	errs = errs.Also(validateContainersPorts(containers, isServing, features.MultiPort == config.Enabled).ViaField("containers"))
 	for i := range containers {
 		// Probes are not allowed on other than serving container,
 		// ref: http://bit.ly/probes-condition
-		if len(containers[i].Ports) == 0 {
+		if !isServing(&containers[i]) {
 			errs = errs.Also(validateSidecarContainer(WithinSidecarContainer(ctx), containers[i], volumes).ViaFieldIndex("containers", i))
 		} else {
 			errs = errs.Also(ValidateContainer(WithinUserContainer(ctx), containers[i], volumes).ViaFieldIndex("containers", i))
@@ -330,6 +624,50 @@ func validateContainers(ctx context.Context, containers []corev1.Container, volu
 	return errs
 }
 
+// validateInitContainers validates the init containers, which run to
+// completion before the containers start, and share their names' namespace.
+func validateInitContainers(ctx context.Context, initContainers, containers []corev1.Container, volumes map[string]corev1.Volume) (errs *apis.FieldError) {
+	names := make(sets.String, len(containers)+len(initContainers))
+	for i := range containers {
+		names.Insert(containers[i].Name)
+	}
+	for i := range initContainers {
+		c := initContainers[i]
+		if c.Name == "" {
+			errs = errs.Also(apis.ErrMissingField("name").ViaFieldIndex("initContainers", i))
+		} else if names.Has(c.Name) {
+			errs = errs.Also(&apis.FieldError{
+				Message: fmt.Sprintf("duplicate container name %q", c.Name),
+				Paths:   []string{"name"},
+			}).ViaFieldIndex("initContainers", i)
+		}
+		names.Insert(c.Name)
+		errs = errs.Also(validateInitContainer(WithinSidecarContainer(ctx), c, volumes).ViaFieldIndex("initContainers", i))
+	}
+	return errs
+}
+
+// validateInitContainer validates fields for an init container, which can't
+// be probed nor serve, as it exits before the pod becomes ready.
+func validateInitContainer(ctx context.Context, container corev1.Container, volumes map[string]corev1.Volume) (errs *apis.FieldError) {
+	if container.LivenessProbe != nil {
+		errs = errs.Also(apis.ErrDisallowedFields("livenessProbe"))
+	}
+	if container.ReadinessProbe != nil {
+		errs = errs.Also(apis.ErrDisallowedFields("readinessProbe"))
+	}
+	if container.StartupProbe != nil {
+		errs = errs.Also(apis.ErrDisallowedFields("startupProbe"))
+	}
+	if len(container.Ports) != 0 {
+		errs = errs.Also(apis.ErrDisallowedFields("ports"))
+	}
+	if container.Lifecycle != nil {
+		errs = errs.Also(apis.ErrDisallowedFields("lifecycle"))
+	}
+	return errs.Also(validate(ctx, container, volumes))
+}
+
 // AllMountedVolumes returns all the mounted volumes in all the containers.
 func AllMountedVolumes(containers []corev1.Container) sets.String {
 	volumeNames := sets.NewString()
@@ -342,10 +680,17 @@ func AllMountedVolumes(containers []corev1.Container) sets.String {
 }
 
 // validateContainersPorts validates port when specified multiple containers
-func validateContainersPorts(containers []corev1.Container) *apis.FieldError {
+func validateContainersPorts(containers []corev1.Container, isServing func(*corev1.Container) bool, multiPort bool) *apis.FieldError {
 	var count int
 	for i := range containers {
-		count += len(containers[i].Ports)
+		if isServing(&containers[i]) {
+			n := len(containers[i].Ports)
+			// The additional named ports are validated with the container.
+			if multiPort && n > 1 {
+				n = 1
+			}
+			count += n
+		}
 	}
 	// When no container ports are specified.
 	if count == 0 {
@@ -359,7 +704,14 @@ func validateContainersPorts(containers []corev1.Container) *apis.FieldError {
 }
 
 // validateSidecarContainer validate fields for non serving containers
-func validateSidecarContainer(ctx context.Context, container corev1.Container, volumes sets.String) (errs *apis.FieldError) {
+func validateSidecarContainer(ctx context.Context, container corev1.Container, volumes map[string]corev1.Volume) (errs *apis.FieldError) {
+	if config.FromContextOrDefaults(ctx).Features.MultiContainerProbing == config.Enabled {
+		errs = errs.Also(validateSidecarProbe(container.LivenessProbe, container.Ports).ViaField("livenessProbe"))
+		errs = errs.Also(validateSidecarProbe(container.ReadinessProbe, container.Ports).ViaField("readinessProbe"))
+		errs = errs.Also(validateSidecarProbe(container.StartupProbe, container.Ports).ViaField("startupProbe"))
+		errs = errs.Also(validateSidecarPorts(container.Ports).ViaField("ports"))
+		return errs.Also(validate(ctx, container, volumes))
+	}
 	if container.LivenessProbe != nil {
 		errs = errs.Also(apis.CheckDisallowedFields(*container.LivenessProbe,
 			*ProbeMask(&corev1.Probe{})).ViaField("livenessProbe"))
@@ -368,37 +720,169 @@ func validateSidecarContainer(ctx context.Context, container corev1.Container, v
 		errs = errs.Also(apis.CheckDisallowedFields(*container.ReadinessProbe,
 			*ProbeMask(&corev1.Probe{})).ViaField("readinessProbe"))
 	}
+	if container.StartupProbe != nil {
+		errs = errs.Also(apis.CheckDisallowedFields(*container.StartupProbe,
+			*ProbeMask(&corev1.Probe{})).ViaField("startupProbe"))
+	}
 	return errs.Also(validate(ctx, container, volumes))
 }
 
+// validateSidecarProbe validates a probe of a sidecar container.
+// Unlike the serving container ones, these are executed by the kubelet as is,
+// so they must specify the port to probe, which can't be one of queue-proxy.
+func validateSidecarProbe(p *corev1.Probe, ports []corev1.ContainerPort) *apis.FieldError {
+	if p == nil {
+		return nil
+	}
+	errs := apis.CheckDisallowedFields(*p, *ProbeMask(p))
+
+	h := p.Handler
+	errs = errs.Also(apis.CheckDisallowedFields(h, *HandlerMask(&h)))
+
+	var handlers []string
+	if h.HTTPGet != nil {
+		handlers = append(handlers, "httpGet")
+		errs = errs.Also(validateHandlerPort(h.HTTPGet.Port, ports).ViaField("httpGet"))
+	}
+	if h.TCPSocket != nil {
+		handlers = append(handlers, "tcpSocket")
+		errs = errs.Also(validateHandlerPort(h.TCPSocket.Port, ports).ViaField("tcpSocket"))
+	}
+	if h.Exec != nil {
+		handlers = append(handlers, "exec")
+		errs = errs.Also(apis.CheckDisallowedFields(*h.Exec, *ExecActionMask(h.Exec))).ViaField("exec")
+	}
+
+	if len(handlers) == 0 {
+		errs = errs.Also(apis.ErrMissingOneOf("httpGet", "tcpSocket", "exec"))
+	} else if len(handlers) > 1 {
+		errs = errs.Also(apis.ErrMultipleOneOf(handlers...))
+	}
+	return errs
+}
+
+// validateHandlerPort validates the port a probe or hook run by the kubelet
+// connects to, which, if named, the container must declare.
+func validateHandlerPort(port intstr.IntOrString, ports []corev1.ContainerPort) *apis.FieldError {
+	switch {
+	case port.Type == intstr.String && port.StrVal != "":
+		for _, p := range ports {
+			if p.Name == port.StrVal {
+				return nil
+			}
+		}
+		return &apis.FieldError{
+			Message: fmt.Sprintf("port %q is not declared by the container", port.StrVal),
+			Paths:   []string{"port"},
+		}
+	case port.IntValue() == 0:
+		return apis.ErrMissingField("port")
+	case reservedPorts.Has(int32(port.IntValue())):
+		return apis.ErrInvalidValue(port.IntValue(), "port")
+	}
+	return nil
+}
+
+// validateSidecarPorts validates the non-serving ports of a sidecar container.
+func validateSidecarPorts(ports []corev1.ContainerPort) (errs *apis.FieldError) {
+	for i := range ports {
+		p := &ports[i]
+		errs = errs.Also(apis.CheckDisallowedFields(*p, *ContainerPortMask(p)).ViaIndex(i))
+		if p.Protocol != "" && p.Protocol != corev1.ProtocolTCP {
+			errs = errs.Also(apis.ErrInvalidValue(p.Protocol, "protocol").ViaIndex(i))
+		}
+		if reservedPorts.Has(p.ContainerPort) {
+			errs = errs.Also(apis.ErrInvalidValue(p.ContainerPort, "containerPort").ViaIndex(i))
+		}
+		if p.ContainerPort < 1 || p.ContainerPort > 65535 {
+			errs = errs.Also(apis.ErrOutOfBoundsValue(p.ContainerPort, 1, 65535, "containerPort").ViaIndex(i))
+		}
+		// Unreachable via validateContainersPorts, but here for completeness.
+		if validPortNames.Has(p.Name) {
+			errs = errs.Also(apis.ErrInvalidValue(p.Name, "name").ViaIndex(i))
+		}
+	}
+	return errs
+}
+
 // ValidateContainer validate fields for serving containers
-func ValidateContainer(ctx context.Context, container corev1.Container, volumes sets.String) (errs *apis.FieldError) {
-	// Single container cannot have multiple ports
-	errs = errs.Also(portValidation(container.Ports).ViaField("ports"))
+func ValidateContainer(ctx context.Context, container corev1.Container, volumes map[string]corev1.Volume) (errs *apis.FieldError) {
+	// Single container cannot have multiple ports, unless they are named
+	errs = errs.Also(portValidation(ctx, container.Ports).ViaField("ports"))
 	// Liveness Probes
 	errs = errs.Also(validateProbe(container.LivenessProbe).ViaField("livenessProbe"))
+	// Startup Probes
+	errs = errs.Also(validateProbe(container.StartupProbe).ViaField("startupProbe"))
 	// Readiness Probes
 	errs = errs.Also(validateReadinessProbe(container.ReadinessProbe).ViaField("readinessProbe"))
+	if len(container.Ports) > 0 && container.Ports[0].Name == UnixSocketPortName {
+		// The kubelet can't connect to the unix socket, while queue-proxy
+		// runs the readiness probe against it.
+		errs = errs.Also(validateUnixSocketProbe(container.LivenessProbe).ViaField("livenessProbe"))
+		errs = errs.Also(validateUnixSocketProbe(container.StartupProbe).ViaField("startupProbe"))
+	}
 	return errs.Also(validate(ctx, container, volumes))
 }
 
-func portValidation(containerPorts []corev1.ContainerPort) *apis.FieldError {
-	if len(containerPorts) > 1 {
+// validateUnixSocketProbe validates that the probe run by the kubelet doesn't
+// connect to the serving port, when the user container listens on a unix socket.
+func validateUnixSocketProbe(p *corev1.Probe) *apis.FieldError {
+	if p == nil || p.TCPSocket == nil {
+		return nil
+	}
+	return apis.ErrGeneric("tcpSocket probes are not supported with a unix socket serving port", "tcpSocket")
+}
+
+func portValidation(ctx context.Context, containerPorts []corev1.ContainerPort) *apis.FieldError {
+	if len(containerPorts) <= 1 {
+		return nil
+	}
+	if config.FromContextOrDefaults(ctx).Features.MultiPort != config.Enabled {
 		return &apis.FieldError{
 			Message: "More than one container port is set",
 			Paths:   []string{apis.CurrentField},
 			Details: "Only a single port is allowed",
 		}
 	}
-	return nil
+	return validateNamedPorts(containerPorts)
 }
 
-func validate(ctx context.Context, container corev1.Container, volumes sets.String) *apis.FieldError {
+// validateNamedPorts validates the ports following the serving port, which
+// the traffic targets of the routes select by name.
+func validateNamedPorts(ports []corev1.ContainerPort) (errs *apis.FieldError) {
+	names := sets.NewString()
+	numbers := sets.NewInt32(ports[0].ContainerPort)
+	for i := 1; i < len(ports); i++ {
+		p := &ports[i]
+		errs = errs.Also(apis.CheckDisallowedFields(*p, *ContainerPortMask(p)).ViaIndex(i))
+		if p.Protocol != "" && p.Protocol != corev1.ProtocolTCP {
+			errs = errs.Also(apis.ErrInvalidValue(p.Protocol, "protocol").ViaIndex(i))
+		}
+		if reservedPorts.Has(p.ContainerPort) || numbers.Has(p.ContainerPort) {
+			errs = errs.Also(apis.ErrInvalidValue(p.ContainerPort, "containerPort").ViaIndex(i))
+		}
+		if p.ContainerPort < 1 || p.ContainerPort > 65535 {
+			errs = errs.Also(apis.ErrOutOfBoundsValue(p.ContainerPort, 1, 65535, "containerPort").ViaIndex(i))
+		}
+		switch {
+		case p.Name == "":
+			errs = errs.Also(apis.ErrMissingField("name").ViaIndex(i))
+		case len(validation.IsValidPortName(p.Name)) != 0, validPortNames.Has(p.Name),
+			p.Name == userPortName, names.Has(p.Name):
+			errs = errs.Also(apis.ErrInvalidValue(p.Name, "name").ViaIndex(i))
+		}
+		names.Insert(p.Name)
+		numbers.Insert(p.ContainerPort)
+	}
+	return errs
+}
+
+func validate(ctx context.Context, container corev1.Container, volumes map[string]corev1.Volume) *apis.FieldError {
 	if equality.Semantic.DeepEqual(container, corev1.Container{}) {
 		return apis.ErrMissingField(apis.CurrentField)
 	}
 
-	errs := apis.CheckDisallowedFields(container, *ContainerMask(&container))
+	errs := apis.CheckDisallowedFields(container, *ContainerMask(ctx, &container))
 
 	if reservedContainerNames.Has(container.Name) {
 		errs = errs.Also(&apis.FieldError{
@@ -414,20 +898,40 @@ func validate(ctx context.Context, container corev1.Container, volumes sets.Stri
 	// Image
 	if container.Image == "" {
 		errs = errs.Also(apis.ErrMissingField("image"))
-	} else if _, err := name.ParseReference(container.Image, name.WeakValidation); err != nil {
+	} else if ref, err := name.ParseReference(container.Image, name.WeakValidation); err != nil {
 		fe := &apis.FieldError{
 			Message: "Failed to parse image reference",
 			Paths:   []string{"image"},
 			Details: fmt.Sprintf("image: %q, error: %v", container.Image, err),
 		}
 		errs = errs.Also(fe)
+	} else {
+		errs = errs.Also(validateImagePolicy(ctx, ref).ViaField("image"))
+	}
+	// ImagePullPolicy
+	switch container.ImagePullPolicy {
+	case corev1.PullAlways, corev1.PullIfNotPresent, "":
+	case corev1.PullNever:
+		// The images that are never pulled cannot be verified to be the signed ones.
+		if IsImageSignatureVerified(ctx) {
+			errs = errs.Also(&apis.FieldError{
+				Message: "imagePullPolicy Never is not allowed while the image signatures are verified",
+				Paths:   []string{"imagePullPolicy"},
+			})
+		}
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(container.ImagePullPolicy, "imagePullPolicy"))
+	}
+	// Ports, the sidecar ones are validated separately.
+	if !IsInSidecarContainer(ctx) {
+		errs = errs.Also(validateContainerPorts(container.Ports).ViaField("ports"))
 	}
-	// Ports
-	errs = errs.Also(validateContainerPorts(container.Ports).ViaField("ports"))
 	// Resources
 	errs = errs.Also(validateResources(&container.Resources).ViaField("resources"))
 	// SecurityContext
 	errs = errs.Also(validateSecurityContext(ctx, container.SecurityContext).ViaField("securityContext"))
+	// Lifecycle
+	errs = errs.Also(validateLifecycle(ctx, container).ViaField("lifecycle"))
 	// TerminationMessagePolicy
 	switch container.TerminationMessagePolicy {
 	case corev1.TerminationMessageReadFile, corev1.TerminationMessageFallbackToLogsOnError, "":
@@ -440,6 +944,119 @@ func validate(ctx context.Context, container corev1.Container, volumes sets.Stri
 	return errs
 }
 
+// validateLifecycle validates the lifecycle hooks of a container. The postStart
+// hook is run by the kubelet as is, while the preStop one of the serving
+// container is called by queue-proxy once the requests in flight are drained,
+// since the kubelet runs the drain hook of queue-proxy instead.
+func validateLifecycle(ctx context.Context, container corev1.Container) *apis.FieldError {
+	lc := container.Lifecycle
+	if lc == nil {
+		return nil
+	}
+	var errs *apis.FieldError
+	if lc.PostStart != nil {
+		ports := container.Ports
+		if !IsInSidecarContainer(ctx) && len(ports) > 0 {
+			// The serving port is renamed, so it can only be targeted by number.
+			ports = ports[1:]
+		}
+		errs = validateLifecycleHandler(*lc.PostStart, ports).ViaField("postStart")
+	}
+	switch {
+	case lc.PreStop == nil:
+	case IsInSidecarContainer(ctx):
+		errs = errs.Also(&apis.FieldError{
+			Message: "preStop hooks are only supported on the serving container",
+			Paths:   []string{"preStop"},
+			Details: "the sidecars are stopped once queue-proxy drained the requests in flight",
+		})
+	default:
+		errs = errs.Also(validatePreStopHandler(*lc.PreStop, container.Ports).ViaField("preStop"))
+	}
+	return errs
+}
+
+// validateLifecycleHandler validates a hook run by the kubelet.
+func validateLifecycleHandler(h corev1.Handler, ports []corev1.ContainerPort) *apis.FieldError {
+	errs := apis.CheckDisallowedFields(h, *HandlerMask(&h))
+
+	var handlers []string
+	if h.Exec != nil {
+		handlers = append(handlers, "exec")
+		errs = errs.Also(apis.CheckDisallowedFields(*h.Exec, *ExecActionMask(h.Exec)).ViaField("exec"))
+		if len(h.Exec.Command) == 0 {
+			errs = errs.Also(apis.ErrMissingField("exec.command"))
+		}
+	}
+	if h.HTTPGet != nil {
+		handlers = append(handlers, "httpGet")
+		errs = errs.Also(validateHandlerPort(h.HTTPGet.Port, ports).ViaField("httpGet"))
+	}
+	if h.TCPSocket != nil {
+		// The kubelet fails such hooks, and so the containers, at runtime.
+		handlers = append(handlers, "tcpSocket")
+		errs = errs.Also(apis.ErrGeneric("tcpSocket hooks are not supported", "tcpSocket"))
+	}
+
+	if len(handlers) == 0 {
+		errs = errs.Also(apis.ErrMissingOneOf("exec", "httpGet"))
+	} else if len(handlers) > 1 {
+		errs = errs.Also(apis.ErrMultipleOneOf(handlers...))
+	}
+	return errs
+}
+
+// validatePreStopHandler validates the preStop hook of the serving container,
+// which must be a GET request on the user port as queue-proxy sends it.
+func validatePreStopHandler(h corev1.Handler, ports []corev1.ContainerPort) *apis.FieldError {
+	if h.HTTPGet == nil || h.Exec != nil || h.TCPSocket != nil {
+		return &apis.FieldError{
+			Message: "the preStop hook of the serving container must be an httpGet of the user port",
+			Paths:   []string{apis.CurrentField},
+			Details: "queue-proxy sends it once the requests in flight are drained",
+		}
+	}
+
+	g := h.HTTPGet
+	var errs *apis.FieldError
+	switch {
+	case g.Path == "":
+		errs = errs.Also(apis.ErrMissingField("path"))
+	case !isPreStopPath(g.Path):
+		errs = errs.Also(apis.ErrInvalidValue(g.Path, "path"))
+	}
+	if g.Host != "" {
+		errs = errs.Also(apis.ErrDisallowedFields("host"))
+	}
+	if len(g.HTTPHeaders) != 0 {
+		errs = errs.Also(apis.ErrDisallowedFields("httpHeaders"))
+	}
+	if g.Scheme != "" && g.Scheme != corev1.URISchemeHTTP {
+		errs = errs.Also(apis.ErrInvalidValue(g.Scheme, "scheme"))
+	}
+	if !isUserPort(g.Port, ports) {
+		errs = errs.Also(&apis.FieldError{
+			Message: fmt.Sprintf("port %s is not the user port", g.Port.String()),
+			Paths:   []string{"port"},
+		})
+	}
+	return errs.ViaField("httpGet")
+}
+
+// isUserPort returns whether the port is unset or the serving one.
+func isUserPort(port intstr.IntOrString, ports []corev1.ContainerPort) bool {
+	switch {
+	case port.Type == intstr.String && port.StrVal == "", port.Type == intstr.Int && port.IntVal == 0:
+		return true
+	case len(ports) == 0:
+		return false
+	case port.Type == intstr.String:
+		return port.StrVal == ports[0].Name
+	default:
+		return port.IntVal == ports[0].ContainerPort
+	}
+}
+
 func validateResources(resources *corev1.ResourceRequirements) *apis.FieldError {
 	if resources == nil {
 		return nil
@@ -466,10 +1083,32 @@ func validateSecurityContext(ctx context.Context, sc *corev1.SecurityContext) *a
 			errs = errs.Also(apis.ErrOutOfBoundsValue(gid, minGroupID, maxGroupID, "runAsGroup"))
 		}
 	}
+
+	if sc.Capabilities != nil {
+		errs = errs.Also(validateCapabilities(sc.Capabilities.Add).ViaField("capabilities.add"))
+		errs = errs.Also(validateCapabilities(sc.Capabilities.Drop).ViaField("capabilities.drop"))
+	}
+	return errs
+}
+
+// validateCapabilities validates that the capabilities are named like
+// NET_BIND_SERVICE, without the CAP_ prefix, or ALL.
+func validateCapabilities(caps []corev1.Capability) (errs *apis.FieldError) {
+	for i, c := range caps {
+		if !isCapabilityName(string(c)) {
+			errs = errs.Also(apis.ErrInvalidArrayValue(c, apis.CurrentField, i))
+		}
+	}
 	return errs
 }
 
-func validateVolumeMounts(mounts []corev1.VolumeMount, volumes sets.String) *apis.FieldError {
+func isCapabilityName(c string) bool {
+	return c != "" && !strings.HasPrefix(c, "CAP_") && strings.IndexFunc(c, func(r rune) bool {
+		return (r < 'A' || r > 'Z') && r != '_'
+	}) < 0
+}
+
+func validateVolumeMounts(mounts []corev1.VolumeMount, volumes map[string]corev1.Volume) *apis.FieldError {
 	var errs *apis.FieldError
 	// Check that volume mounts match names in "volumes", that "volumes" has 100%
 	// coverage, and the field restrictions.
@@ -479,7 +1118,8 @@ func validateVolumeMounts(mounts []corev1.VolumeMount, volumes sets.String) *api
 		vm := mounts[i]
 		errs = errs.Also(apis.CheckDisallowedFields(vm, *VolumeMountMask(&vm)).ViaIndex(i))
 		// This effectively checks that Name is non-empty because Volume name must be non-empty.
-		if !volumes.Has(vm.Name) {
+		volume, ok := volumes[vm.Name]
+		if !ok {
 			errs = errs.Also((&apis.FieldError{
 				Message: "volumeMount has no matching volume",
 				Paths:   []string{"name"},
@@ -502,7 +1142,11 @@ func validateVolumeMounts(mounts []corev1.VolumeMount, volumes sets.String) *api
 		}
 		seenMountPath.Insert(filepath.Clean(vm.MountPath))
 
-		if !vm.ReadOnly {
+		// The scratch space of emptyDir volumes and the claims which aren't
+		// read-only are writable.
+		writable := volume.EmptyDir != nil ||
+			(volume.PersistentVolumeClaim != nil && !volume.PersistentVolumeClaim.ReadOnly)
+		if !vm.ReadOnly && !writable {
 			errs = errs.Also(apis.ErrMissingField("readOnly").ViaIndex(i))
 		}
 
@@ -721,3 +1365,17 @@ func WithinSidecarContainer(ctx context.Context) context.Context {
 func IsInSidecarContainer(ctx context.Context) bool {
 	return ctx.Value(sidecarContainer{}) != nil
 }
+
+// This is attached to contexts when the signatures of the images are verified.
+type imageSignatureVerification struct{}
+
+// WithImageSignatureVerification notes on the context that the signatures of
+// the images are verified, i.e. config-deployment has signature public keys.
+func WithImageSignatureVerification(ctx context.Context) context.Context {
+	return context.WithValue(ctx, imageSignatureVerification{}, struct{}{})
+}
+
+// IsImageSignatureVerified checks if the signatures of the images are verified.
+func IsImageSignatureVerified(ctx context.Context) bool {
+	return ctx.Value(imageSignatureVerification{}) != nil
+}
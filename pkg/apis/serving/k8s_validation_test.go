@@ -41,6 +41,20 @@ func withMultiContainerDisabled() configOption {
 	}
 }
 
+func withMultiContainerProbingEnabled() configOption {
+	return func(cfg *config.Config) *config.Config {
+		cfg.Features.MultiContainerProbing = config.Enabled
+		return cfg
+	}
+}
+
+func withMultiPortEnabled() configOption {
+	return func(cfg *config.Config) *config.Config {
+		cfg.Features.MultiPort = config.Enabled
+		return cfg
+	}
+}
+
 func withPodSpecFieldRefEnabled() configOption {
 	return func(cfg *config.Config) *config.Config {
 		cfg.Features.PodSpecFieldRef = config.Enabled
@@ -76,6 +90,74 @@ func withPodSpecRuntimeClassNameEnabled() configOption {
 	}
 }
 
+func withPodSpecInitContainersEnabled() configOption {
+	return func(cfg *config.Config) *config.Config {
+		cfg.Features.PodSpecInitContainers = config.Enabled
+		return cfg
+	}
+}
+
+func quantityPtr(s string) *resource.Quantity {
+	q := resource.MustParse(s)
+	return &q
+}
+
+func withPodSpecVolumesEmptyDirEnabled() configOption {
+	return func(cfg *config.Config) *config.Config {
+		cfg.Features.PodSpecVolumesEmptyDir = config.Enabled
+		return cfg
+	}
+}
+
+func withPodSpecPersistentVolumeClaimEnabled() configOption {
+	return func(cfg *config.Config) *config.Config {
+		cfg.Features.PodSpecPersistentVolumeClaim = config.Enabled
+		return cfg
+	}
+}
+
+func withSecurePodDefaultsEnabled() configOption {
+	return func(cfg *config.Config) *config.Config {
+		cfg.Features.SecurePodDefaults = config.Enabled
+		return cfg
+	}
+}
+
+func withPodSpecDNSPolicyEnabled() configOption {
+	return func(cfg *config.Config) *config.Config {
+		cfg.Features.PodSpecDNSPolicy = config.Enabled
+		return cfg
+	}
+}
+
+func withPodSpecDNSConfigEnabled() configOption {
+	return func(cfg *config.Config) *config.Config {
+		cfg.Features.PodSpecDNSConfig = config.Enabled
+		return cfg
+	}
+}
+
+func withPodSpecHostAliasesEnabled() configOption {
+	return func(cfg *config.Config) *config.Config {
+		cfg.Features.PodSpecHostAliases = config.Enabled
+		return cfg
+	}
+}
+
+func withPodSpecShareProcessNamespaceEnabled() configOption {
+	return func(cfg *config.Config) *config.Config {
+		cfg.Features.PodSpecShareProcessNamespace = config.Enabled
+		return cfg
+	}
+}
+
+func withPodSpecLifecycleEnabled() configOption {
+	return func(cfg *config.Config) *config.Config {
+		cfg.Features.PodSpecLifecycle = config.Enabled
+		return cfg
+	}
+}
+
 func withPodSpecSecurityContextEnabled() configOption {
 	return func(cfg *config.Config) *config.Config {
 		cfg.Features.PodSpecSecurityContext = config.Enabled
@@ -83,6 +165,13 @@ func withPodSpecSecurityContextEnabled() configOption {
 	}
 }
 
+func withMaxContainerCount(max int64) configOption {
+	return func(cfg *config.Config) *config.Config {
+		cfg.Defaults.MaxContainerCount = max
+		return cfg
+	}
+}
+
 func TestPodSpecValidation(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -234,6 +323,46 @@ func TestPodSpecValidation(t *testing.T) {
 			ServiceAccountName: "foo@bar.baz",
 		},
 		want: apis.ErrInvalidValue("serviceAccountName", "foo@bar.baz"),
+	}, {
+		name: "with image pull policy and secrets",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image:           "private.example.com/busybox",
+				ImagePullPolicy: corev1.PullAlways,
+			}},
+			ImagePullSecrets: []corev1.LocalObjectReference{{
+				Name: "registry-a",
+			}, {
+				Name: "registry-b",
+			}},
+		},
+		want: nil,
+	}, {
+		name: "bad image pull policy",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image:           "busybox",
+				ImagePullPolicy: "Sometimes",
+			}},
+		},
+		want: apis.ErrInvalidValue("Sometimes", "containers[0].imagePullPolicy"),
+	}, {
+		name: "bad image pull secrets",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+			}},
+			ImagePullSecrets: []corev1.LocalObjectReference{{
+				Name: "registry",
+			}, {}, {
+				Name: "Not_A_Secret",
+			}, {
+				Name: "registry",
+			}},
+		},
+		want: apis.ErrMissingField("imagePullSecrets[1].name").Also(
+			apis.ErrInvalidValue("Not_A_Secret", "imagePullSecrets[2].name")).Also(
+			apis.ErrInvalidValue("registry", "imagePullSecrets[3].name")),
 	}}
 
 	for _, test := range tests {
@@ -254,6 +383,27 @@ func TestPodSpecValidation(t *testing.T) {
 	}
 }
 
+func TestPodSpecNeverPulledImageSignatureVerification(t *testing.T) {
+	ps := corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Image:           "busybox",
+			ImagePullPolicy: corev1.PullNever,
+		}},
+	}
+	if got := ValidatePodSpec(context.Background(), ps); got != nil {
+		t.Error("ValidatePodSpec() =", got)
+	}
+
+	want := &apis.FieldError{
+		Message: "imagePullPolicy Never is not allowed while the image signatures are verified",
+		Paths:   []string{"containers[0].imagePullPolicy"},
+	}
+	got := ValidatePodSpec(WithImageSignatureVerification(context.Background()), ps)
+	if diff := cmp.Diff(want.Error(), got.Error()); diff != "" {
+		t.Errorf("ValidatePodSpec (-want, +got): \n%s", diff)
+	}
+}
+
 func TestPodSpecMultiContainerValidation(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -319,6 +469,38 @@ func TestPodSpecMultiContainerValidation(t *testing.T) {
 			}},
 		},
 		want: apis.ErrMissingField("containers.ports"),
+	}, {
+		name: "max container count: within the limit",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 8888,
+				}},
+			}, {
+				Image: "helloworld",
+			}},
+		},
+		cfgOpts: []configOption{withMaxContainerCount(2)},
+	}, {
+		name: "max container count: above the limit",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 8888,
+				}},
+			}, {
+				Image: "helloworld",
+			}, {
+				Image: "sidecar",
+			}},
+		},
+		cfgOpts: []configOption{withMaxContainerCount(2)},
+		want: &apis.FieldError{
+			Message: "found 3 containers, but at most 2 are allowed",
+			Paths:   []string{"containers"},
+		},
 	}, {
 		name: "flag enabled: multiple containers with multiple port",
 		ps: corev1.PodSpec{
@@ -528,8 +710,193 @@ func TestPodSpecMultiContainerValidation(t *testing.T) {
 		want: &apis.FieldError{
 			Message: `volume with name "the-name2" not mounted`,
 			Paths:   []string{"volumes[1].name"},
-		}},
-	}
+		}}, {
+		name: "probing disabled: sidecar with readiness probe",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{ContainerPort: 8888}},
+			}, {
+				Image: "envoy",
+				ReadinessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{Path: "/ready", Port: intstr.FromInt(9901)},
+					},
+				},
+			}},
+		},
+		want: apis.ErrDisallowedFields("containers[1].readinessProbe"),
+	}, {
+		name: "probing disabled: sidecar with port",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{ContainerPort: 8888}},
+			}, {
+				Image: "envoy",
+				Ports: []corev1.ContainerPort{{ContainerPort: 9901}},
+			}},
+		},
+		want: apis.ErrMultipleOneOf("containers.ports"),
+	}, {
+		name: "probing enabled: sidecar with readiness probe and port",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{ContainerPort: 8888}},
+			}, {
+				Image: "envoy",
+				Ports: []corev1.ContainerPort{{Name: "admin", ContainerPort: 9901}},
+				ReadinessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{Path: "/ready", Port: intstr.FromInt(9901)},
+					},
+					PeriodSeconds: 5,
+				},
+			}},
+		},
+		cfgOpts: []configOption{withMultiContainerProbingEnabled()},
+	}, {
+		name: "probing disabled: sidecar liveness and startup probes",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{ContainerPort: 8888}},
+			}, {
+				Image: "envoy",
+				LivenessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(9901)},
+					},
+				},
+				StartupProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(9901)},
+					},
+				},
+			}},
+		},
+		want: apis.ErrDisallowedFields("containers[1].livenessProbe").Also(
+			apis.ErrDisallowedFields("containers[1].startupProbe")),
+	}, {
+		name: "probing enabled: sidecar liveness and startup probes",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{ContainerPort: 8888}},
+			}, {
+				Image: "envoy",
+				Ports: []corev1.ContainerPort{{Name: "admin", ContainerPort: 9901}},
+				LivenessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: intstr.FromString("admin")},
+					},
+				},
+				StartupProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(9901)},
+					},
+					FailureThreshold: 30,
+				},
+			}},
+		},
+		cfgOpts: []configOption{withMultiContainerProbingEnabled()},
+	}, {
+		name: "probing enabled: sidecar probes of queue-proxy and undeclared ports",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{ContainerPort: 8888}},
+			}, {
+				Image: "envoy",
+				LivenessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: intstr.FromString("admin")},
+					},
+				},
+				StartupProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(8012)},
+					},
+				},
+			}},
+		},
+		cfgOpts: []configOption{withMultiContainerProbingEnabled()},
+		want: (&apis.FieldError{
+			Message: `port "admin" is not declared by the container`,
+			Paths:   []string{"containers[1].livenessProbe.httpGet.port"},
+		}).Also(apis.ErrInvalidValue(8012, "containers[1].startupProbe.tcpSocket.port")),
+	}, {
+		name: "probing enabled: sidecar probe without handler",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{ContainerPort: 8888}},
+			}, {
+				Image:          "envoy",
+				ReadinessProbe: &corev1.Probe{},
+			}},
+		},
+		cfgOpts: []configOption{withMultiContainerProbingEnabled()},
+		want:    apis.ErrMissingOneOf("containers[1].readinessProbe.exec", "containers[1].readinessProbe.httpGet", "containers[1].readinessProbe.tcpSocket"),
+	}, {
+		name: "probing enabled: sidecar probe without port",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{ContainerPort: 8888}},
+			}, {
+				Image: "envoy",
+				ReadinessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						TCPSocket: &corev1.TCPSocketAction{},
+					},
+				},
+			}},
+		},
+		cfgOpts: []configOption{withMultiContainerProbingEnabled()},
+		want:    apis.ErrMissingField("containers[1].readinessProbe.tcpSocket.port"),
+	}, {
+		name: "probing enabled: sidecar with reserved port",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{ContainerPort: 8888}},
+			}, {
+				Image: "envoy",
+				Ports: []corev1.ContainerPort{{Name: "admin", ContainerPort: 8012, Protocol: corev1.ProtocolUDP}},
+			}},
+		},
+		cfgOpts: []configOption{withMultiContainerProbingEnabled()},
+		want: apis.ErrInvalidValue(8012, "containers[1].ports[0].containerPort").Also(
+			apis.ErrInvalidValue(corev1.ProtocolUDP, "containers[1].ports[0].protocol")),
+	}, {
+		name: "probing enabled: two serving ports",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{ContainerPort: 8888}},
+			}, {
+				Image: "envoy",
+				Ports: []corev1.ContainerPort{{Name: "h2c", ContainerPort: 9901}},
+			}},
+		},
+		cfgOpts: []configOption{withMultiContainerProbingEnabled()},
+		want:    apis.ErrMultipleOneOf("containers.ports"),
+	}, {
+		name: "probing enabled: no serving port",
+		ps: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{Name: "metrics", ContainerPort: 8888}},
+			}, {
+				Image: "envoy",
+				Ports: []corev1.ContainerPort{{Name: "admin", ContainerPort: 9901}},
+			}},
+		},
+		cfgOpts: []configOption{withMultiContainerProbingEnabled()},
+		want:    apis.ErrMissingField("containers.ports"),
+	}}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -615,78 +982,709 @@ func TestPodSpecFeatureValidation(t *testing.T) {
 			Message: "must not set the field(s)",
 			Paths:   []string{"runtimeClassName"},
 		},
-		cfgOpts: []configOption{withPodSpecRuntimeClassNameEnabled()},
+		cfgOpts: []configOption{withPodSpecRuntimeClassNameEnabled()},
+	}, {
+		name: "PodSpecSecurityContext",
+		featureSpec: corev1.PodSpec{
+			SecurityContext: &corev1.PodSecurityContext{},
+		},
+		err: &apis.FieldError{
+			Message: "must not set the field(s)",
+			Paths:   []string{"securityContext"},
+		},
+		cfgOpts: []configOption{withPodSpecSecurityContextEnabled()},
+	}, {
+		name: "InitContainers",
+		featureSpec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{
+				Name:  "migrate",
+				Image: "busybox",
+			}},
+		},
+		err: &apis.FieldError{
+			Message: "must not set the field(s)",
+			Paths:   []string{"initContainers"},
+		},
+		cfgOpts: []configOption{withPodSpecInitContainersEnabled()},
+	}, {
+		name: "DNSPolicy",
+		featureSpec: corev1.PodSpec{
+			DNSPolicy: corev1.DNSDefault,
+		},
+		err: &apis.FieldError{
+			Message: "must not set the field(s)",
+			Paths:   []string{"dnsPolicy"},
+		},
+		cfgOpts: []configOption{withPodSpecDNSPolicyEnabled()},
+	}, {
+		name: "DNSConfig",
+		featureSpec: corev1.PodSpec{
+			DNSConfig: &corev1.PodDNSConfig{
+				Options: []corev1.PodDNSConfigOption{{
+					Name:  "ndots",
+					Value: ptr.String("1"),
+				}},
+			},
+		},
+		err: &apis.FieldError{
+			Message: "must not set the field(s)",
+			Paths:   []string{"dnsConfig"},
+		},
+		cfgOpts: []configOption{withPodSpecDNSConfigEnabled()},
+	}, {
+		name: "HostAliases",
+		featureSpec: corev1.PodSpec{
+			HostAliases: []corev1.HostAlias{{
+				IP:        "10.1.2.3",
+				Hostnames: []string{"legacy.example.com"},
+			}},
+		},
+		err: &apis.FieldError{
+			Message: "must not set the field(s)",
+			Paths:   []string{"hostAliases"},
+		},
+		cfgOpts: []configOption{withPodSpecHostAliasesEnabled()},
+	}, {
+		name: "ShareProcessNamespace",
+		featureSpec: corev1.PodSpec{
+			ShareProcessNamespace: ptr.Bool(true),
+		},
+		err: &apis.FieldError{
+			Message: "must not set the field(s)",
+			Paths:   []string{"shareProcessNamespace"},
+		},
+		cfgOpts: []configOption{withPodSpecShareProcessNamespaceEnabled()},
+	}}
+
+	featureTests := []struct {
+		nameTemplate       string
+		enableFeature      bool
+		includeFeatureSpec bool
+		wantError          bool
+	}{{
+		nameTemplate:       "flag disabled: %s not present",
+		enableFeature:      false,
+		includeFeatureSpec: false,
+		wantError:          false,
+	}, {
+		nameTemplate:       "flag disabled: %s present",
+		enableFeature:      false,
+		includeFeatureSpec: true,
+		wantError:          true,
+	}, {
+		nameTemplate:       "flag enabled: %s not present",
+		enableFeature:      true,
+		includeFeatureSpec: false,
+		wantError:          false,
+	}, {
+		nameTemplate:       "flag enabled: %s present",
+		enableFeature:      true,
+		includeFeatureSpec: true,
+		wantError:          false,
+	}}
+
+	for _, featureData := range featureData {
+		for _, test := range featureTests {
+			t.Run(fmt.Sprintf(test.nameTemplate, featureData.name), func(t *testing.T) {
+				ctx := context.Background()
+				obj := corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Image: "busybox",
+					}},
+				}
+				want := &apis.FieldError{}
+				if test.wantError {
+					want = featureData.err
+				}
+				if test.enableFeature {
+					cfg := config.FromContextOrDefaults(ctx)
+					for _, opt := range featureData.cfgOpts {
+						cfg = opt(cfg)
+					}
+					ctx = config.ToContext(ctx, cfg)
+				}
+				if test.includeFeatureSpec {
+					obj = featureData.featureSpec
+					obj.Containers = []corev1.Container{{
+						Image: "busybox",
+					}}
+				}
+				got := ValidatePodSpec(ctx, obj)
+				if diff := cmp.Diff(want.Error(), got.Error()); diff != "" {
+					t.Errorf("ValidatePodSpec (-want, +got): \n%s", diff)
+				}
+			})
+		}
+	}
+}
+
+func TestPodSpecPlacementValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		ps      corev1.PodSpec
+		wantErr *apis.FieldError
+	}{{
+		name: "valid",
+		ps: corev1.PodSpec{
+			NodeSelector: map[string]string{
+				"cloud.google.com/gke-accelerator": "nvidia-tesla-t4",
+			},
+			Tolerations: []corev1.Toleration{{
+				Key:      "nvidia.com/gpu",
+				Operator: corev1.TolerationOpExists,
+				Effect:   corev1.TaintEffectNoSchedule,
+			}, {
+				Key:               "spot",
+				Value:             "true",
+				Effect:            corev1.TaintEffectNoExecute,
+				TolerationSeconds: ptr.Int64(30),
+			}, {
+				Operator: corev1.TolerationOpExists,
+			}},
+		},
+	}, {
+		name: "invalid node selector",
+		ps: corev1.PodSpec{
+			NodeSelector: map[string]string{
+				"not a key":          "v",
+				"kubernetes.io/arch": "not a value",
+			},
+		},
+		wantErr: apis.ErrInvalidKeyName("not a key", "nodeSelector").Also(
+			apis.ErrInvalidValue("not a value", "nodeSelector.kubernetes.io/arch")),
+	}, {
+		name: "invalid tolerations",
+		ps: corev1.PodSpec{
+			Tolerations: []corev1.Toleration{{
+				Value: "v",
+			}, {
+				Key:      "k",
+				Operator: corev1.TolerationOpExists,
+				Value:    "v",
+				Effect:   "Never",
+			}, {
+				Key:               "k",
+				Operator:          "In",
+				TolerationSeconds: ptr.Int64(30),
+			}},
+		},
+		wantErr: (&apis.FieldError{
+			Message: "operator must be Exists when key is empty",
+			Paths:   []string{"tolerations[0].operator"},
+		}).Also(&apis.FieldError{
+			Message: "value must be empty when operator is Exists",
+			Paths:   []string{"tolerations[1].value"},
+		}).Also(apis.ErrInvalidValue("Never", "tolerations[1].effect")).
+			Also(apis.ErrInvalidValue("In", "tolerations[2].operator")).
+			Also(&apis.FieldError{
+				Message: "effect must be NoExecute when tolerationSeconds is set",
+				Paths:   []string{"tolerations[2].effect"},
+			}),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := config.FromContextOrDefaults(context.Background())
+			cfg = withPodSpecNodeSelectorEnabled()(withPodSpecTolerationsEnabled()(cfg))
+			ctx := config.ToContext(context.Background(), cfg)
+			test.ps.Containers = []corev1.Container{{
+				Image: "busybox",
+			}}
+			got := ValidatePodSpec(ctx, test.ps)
+			if got, want := got.Error(), test.wantErr.Error(); got != want {
+				t.Errorf("ValidatePodSpec =\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestPodSpecDNSValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		ps      corev1.PodSpec
+		wantErr *apis.FieldError
+	}{{
+		name: "valid",
+		ps: corev1.PodSpec{
+			DNSPolicy: corev1.DNSNone,
+			DNSConfig: &corev1.PodDNSConfig{
+				Nameservers: []string{"10.0.0.10", "fd00::10"},
+				Searches:    []string{"svc.cluster.local.", "example.com"},
+				Options: []corev1.PodDNSConfigOption{{
+					Name:  "ndots",
+					Value: ptr.String("1"),
+				}, {
+					Name: "single-request-reopen",
+				}},
+			},
+		},
+	}, {
+		name: "invalid dns policy",
+		ps: corev1.PodSpec{
+			DNSPolicy: corev1.DNSClusterFirstWithHostNet,
+		},
+		wantErr: apis.ErrInvalidValue(corev1.DNSClusterFirstWithHostNet, "dnsPolicy"),
+	}, {
+		name: "none without nameservers",
+		ps: corev1.PodSpec{
+			DNSPolicy: corev1.DNSNone,
+		},
+		wantErr: &apis.FieldError{
+			Message: "at least one nameserver is required when dnsPolicy is None",
+			Paths:   []string{"dnsConfig.nameservers"},
+		},
+	}, {
+		name: "invalid dns config",
+		ps: corev1.PodSpec{
+			DNSConfig: &corev1.PodDNSConfig{
+				Nameservers: []string{"10.0.0.10", "10.0.0.11", "10.0.0.12", "dns.local"},
+				Searches:    []string{"a", "b", "c", "d", "e", "f", "Not_A_Domain"},
+				Options: []corev1.PodDNSConfigOption{{
+					Value: ptr.String("1"),
+				}},
+			},
+		},
+		wantErr: (&apis.FieldError{
+			Message: "must not have more than 3 nameservers",
+			Paths:   []string{"dnsConfig.nameservers"},
+		}).Also(apis.ErrInvalidArrayValue("dns.local", "dnsConfig.nameservers", 3)).
+			Also(&apis.FieldError{
+				Message: "must not have more than 6 search paths",
+				Paths:   []string{"dnsConfig.searches"},
+			}).
+			Also(apis.ErrInvalidArrayValue("Not_A_Domain", "dnsConfig.searches", 6)).
+			Also(apis.ErrMissingField("dnsConfig.options[0].name")),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := config.FromContextOrDefaults(context.Background())
+			cfg = withPodSpecDNSPolicyEnabled()(withPodSpecDNSConfigEnabled()(cfg))
+			ctx := config.ToContext(context.Background(), cfg)
+			test.ps.Containers = []corev1.Container{{
+				Image: "busybox",
+			}}
+			got := ValidatePodSpec(ctx, test.ps)
+			if got, want := got.Error(), test.wantErr.Error(); got != want {
+				t.Errorf("ValidatePodSpec =\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestPodSpecHostAliasesValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		aliases []corev1.HostAlias
+		wantErr *apis.FieldError
+	}{{
+		name: "valid",
+		aliases: []corev1.HostAlias{{
+			IP:        "10.1.2.3",
+			Hostnames: []string{"legacy.example.com", "legacy"},
+		}, {
+			IP:        "fd00::1",
+			Hostnames: []string{"ipv6.example.com"},
+		}},
+	}, {
+		name: "invalid",
+		aliases: []corev1.HostAlias{{
+			IP:        "10.1.2.3",
+			Hostnames: []string{"legacy.example.com"},
+		}, {
+			IP: "legacy.example.com",
+		}, {
+			IP:        "10.1.2.4",
+			Hostnames: []string{"ok.example.com", "Not_A_Host"},
+		}},
+		wantErr: apis.ErrInvalidValue("legacy.example.com", "hostAliases[1].ip").Also(
+			apis.ErrMissingField("hostAliases[1].hostnames")).Also(
+			apis.ErrInvalidArrayValue("Not_A_Host", "hostAliases[2].hostnames", 1)),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := config.ToContext(context.Background(),
+				withPodSpecHostAliasesEnabled()(config.FromContextOrDefaults(context.Background())))
+			ps := corev1.PodSpec{
+				HostAliases: test.aliases,
+				Containers: []corev1.Container{{
+					Image: "busybox",
+				}},
+			}
+			got := ValidatePodSpec(ctx, ps)
+			if got, want := got.Error(), test.wantErr.Error(); got != want {
+				t.Errorf("ValidatePodSpec =\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestPodSpecLifecycleValidation(t *testing.T) {
+	postStart := &corev1.Handler{
+		Exec: &corev1.ExecAction{Command: []string{"warmup"}},
+	}
+	preStop := &corev1.Handler{
+		HTTPGet: &corev1.HTTPGetAction{Path: "/shutdown"},
+	}
+	tests := []struct {
+		name      string
+		disabled  bool
+		lifecycle *corev1.Lifecycle
+		sidecar   *corev1.Lifecycle
+		wantErr   *apis.FieldError
+	}{{
+		name:     "flag disabled",
+		disabled: true,
+		lifecycle: &corev1.Lifecycle{
+			PostStart: postStart,
+		},
+		wantErr: apis.ErrDisallowedFields("containers[0].lifecycle"),
+	}, {
+		name: "valid",
+		lifecycle: &corev1.Lifecycle{
+			PostStart: postStart,
+			PreStop:   preStop,
+		},
+		sidecar: &corev1.Lifecycle{
+			PostStart: &corev1.Handler{
+				HTTPGet: &corev1.HTTPGetAction{Port: intstr.FromString("admin"), Path: "/ready"},
+			},
+		},
+	}, {
+		name: "preStop on the user port",
+		lifecycle: &corev1.Lifecycle{
+			PreStop: &corev1.Handler{
+				HTTPGet: &corev1.HTTPGetAction{
+					Port:   intstr.FromInt(8888),
+					Path:   "/shutdown?graceful=true",
+					Scheme: corev1.URISchemeHTTP,
+				},
+			},
+		},
+	}, {
+		name: "invalid postStart",
+		lifecycle: &corev1.Lifecycle{
+			PostStart: &corev1.Handler{
+				Exec:      &corev1.ExecAction{},
+				TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(8888)},
+			},
+		},
+		sidecar: &corev1.Lifecycle{
+			PostStart: &corev1.Handler{
+				HTTPGet: &corev1.HTTPGetAction{Port: intstr.FromInt(8012)},
+			},
+		},
+		wantErr: apis.ErrMissingField("containers[0].lifecycle.postStart.exec.command").Also(
+			apis.ErrGeneric("tcpSocket hooks are not supported", "containers[0].lifecycle.postStart.tcpSocket")).Also(
+			apis.ErrMultipleOneOf("containers[0].lifecycle.postStart.exec", "containers[0].lifecycle.postStart.tcpSocket")).Also(
+			apis.ErrInvalidValue(8012, "containers[1].lifecycle.postStart.httpGet.port")),
+	}, {
+		name: "exec preStop",
+		lifecycle: &corev1.Lifecycle{
+			PreStop: postStart,
+		},
+		wantErr: &apis.FieldError{
+			Message: "the preStop hook of the serving container must be an httpGet of the user port",
+			Paths:   []string{"containers[0].lifecycle.preStop"},
+			Details: "queue-proxy sends it once the requests in flight are drained",
+		},
+	}, {
+		name: "invalid httpGet preStop",
+		lifecycle: &corev1.Lifecycle{
+			PreStop: &corev1.Handler{
+				HTTPGet: &corev1.HTTPGetAction{
+					Host:        "example.com",
+					Port:        intstr.FromInt(8012),
+					Path:        "shutdown",
+					Scheme:      corev1.URISchemeHTTPS,
+					HTTPHeaders: []corev1.HTTPHeader{{Name: "foo", Value: "bar"}},
+				},
+			},
+		},
+		wantErr: apis.ErrInvalidValue("shutdown", "containers[0].lifecycle.preStop.httpGet.path").Also(
+			apis.ErrDisallowedFields("containers[0].lifecycle.preStop.httpGet.host")).Also(
+			apis.ErrDisallowedFields("containers[0].lifecycle.preStop.httpGet.httpHeaders")).Also(
+			apis.ErrInvalidValue(corev1.URISchemeHTTPS, "containers[0].lifecycle.preStop.httpGet.scheme")).Also(
+			&apis.FieldError{
+				Message: "port 8012 is not the user port",
+				Paths:   []string{"containers[0].lifecycle.preStop.httpGet.port"},
+			}),
+	}, {
+		name: "sidecar preStop",
+		sidecar: &corev1.Lifecycle{
+			PreStop: preStop,
+		},
+		wantErr: &apis.FieldError{
+			Message: "preStop hooks are only supported on the serving container",
+			Paths:   []string{"containers[1].lifecycle.preStop"},
+			Details: "the sidecars are stopped once queue-proxy drained the requests in flight",
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := config.FromContextOrDefaults(context.Background())
+			if !test.disabled {
+				cfg = withPodSpecLifecycleEnabled()(cfg)
+			}
+			// The sidecar declares the port its postStart hook targets.
+			ctx := config.ToContext(context.Background(), withMultiContainerProbingEnabled()(cfg))
+			ps := corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Image:     "busybox",
+					Ports:     []corev1.ContainerPort{{ContainerPort: 8888}},
+					Lifecycle: test.lifecycle,
+				}, {
+					Name:      "sidecar",
+					Image:     "envoy",
+					Ports:     []corev1.ContainerPort{{Name: "admin", ContainerPort: 9901}},
+					Lifecycle: test.sidecar,
+				}},
+			}
+			got := ValidatePodSpec(ctx, ps)
+			if got, want := got.Error(), test.wantErr.Error(); got != want {
+				t.Errorf("ValidatePodSpec =\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestPodSpecRuntimeClassNameValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		allowlist sets.String
+		rcn       *string
+		wantErr   *apis.FieldError
+	}{{
+		name: "unset",
+	}, {
+		name: "any allowed",
+		rcn:  ptr.String("gvisor"),
+	}, {
+		name:      "allowed",
+		allowlist: sets.NewString("gvisor", "kata"),
+		rcn:       ptr.String("kata"),
+	}, {
+		name:      "not allowed",
+		allowlist: sets.NewString("gvisor", "kata"),
+		rcn:       ptr.String("runc"),
+		wantErr: &apis.FieldError{
+			Message: `runtimeClassName "runc" is not allowed, must be one of: gvisor, kata`,
+			Paths:   []string{"runtimeClassName"},
+		},
+	}, {
+		name:    "invalid name",
+		rcn:     ptr.String("Not_Valid"),
+		wantErr: apis.ErrInvalidValue("Not_Valid", "runtimeClassName"),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := withPodSpecRuntimeClassNameEnabled()(config.FromContextOrDefaults(context.Background()))
+			cfg.Features.PodSpecRuntimeClassNameAllowlist = test.allowlist
+			ctx := config.ToContext(context.Background(), cfg)
+			ps := corev1.PodSpec{
+				RuntimeClassName: test.rcn,
+				Containers: []corev1.Container{{
+					Image: "busybox",
+				}},
+			}
+			got := ValidatePodSpec(ctx, ps)
+			if got, want := got.Error(), test.wantErr.Error(); got != want {
+				t.Errorf("ValidatePodSpec =\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestContainerImagePolicyValidation(t *testing.T) {
+	const digest = "sha256:deadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef"
+	tests := []struct {
+		name      string
+		pinning   config.Flag
+		allowlist sets.String
+		image     string
+		wantErr   *apis.FieldError
+	}{{
+		name:  "no policy",
+		image: "busybox",
+	}, {
+		name:    "pinned by digest",
+		pinning: config.Enabled,
+		image:   "gcr.io/my-project/app@" + digest,
+	}, {
+		name:    "tag with pinning",
+		pinning: config.Enabled,
+		image:   "gcr.io/my-project/app:v1",
+		wantErr: &apis.FieldError{
+			Message: `image "gcr.io/my-project/app:v1" must be specified by digest`,
+			Paths:   []string{"containers[0].image"},
+		},
+	}, {
+		name:      "allowed registry",
+		allowlist: sets.NewString("registry.example.com/", "gcr.io/my-project/"),
+		image:     "gcr.io/my-project/app:v1",
+	}, {
+		name:      "docker hub image not allowed",
+		allowlist: sets.NewString("gcr.io/my-project/"),
+		image:     "busybox",
+		wantErr: &apis.FieldError{
+			Message: `image repository "index.docker.io/library/busybox" is not allowed, must start with one of: gcr.io/my-project/`,
+			Paths:   []string{"containers[0].image"},
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := config.FromContextOrDefaults(context.Background())
+			if test.pinning != "" {
+				cfg.Features.ImageDigestPinning = test.pinning
+			}
+			cfg.Features.ImageRegistriesAllowlist = test.allowlist
+			ctx := config.ToContext(context.Background(), cfg)
+			ps := corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Image: test.image,
+				}},
+			}
+			got := ValidatePodSpec(ctx, ps)
+			if got, want := got.Error(), test.wantErr.Error(); got != want {
+				t.Errorf("ValidatePodSpec =\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestPodSpecInitContainerValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		ps      corev1.PodSpec
+		wantErr *apis.FieldError
+	}{{
+		name: "valid with volume",
+		ps: corev1.PodSpec{
+			InitContainers: []corev1.Container{{
+				Name:  "download",
+				Image: "busybox",
+				VolumeMounts: []corev1.VolumeMount{{
+					Name:      "assets",
+					MountPath: "/assets",
+					ReadOnly:  true,
+				}},
+			}},
+			Containers: []corev1.Container{{
+				Name:  "user-container",
+				Image: "busybox",
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "assets",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{SecretName: "assets"},
+				},
+			}},
+		},
+	}, {
+		name: "missing name",
+		ps: corev1.PodSpec{
+			InitContainers: []corev1.Container{{
+				Image: "busybox",
+			}},
+			Containers: []corev1.Container{{
+				Image: "busybox",
+			}},
+		},
+		wantErr: apis.ErrMissingField("initContainers[0].name"),
+	}, {
+		name: "duplicate name",
+		ps: corev1.PodSpec{
+			InitContainers: []corev1.Container{{
+				Name:  "user-container",
+				Image: "busybox",
+			}},
+			Containers: []corev1.Container{{
+				Name:  "user-container",
+				Image: "busybox",
+			}},
+		},
+		wantErr: &apis.FieldError{
+			Message: `duplicate container name "user-container"`,
+			Paths:   []string{"initContainers[0].name"},
+		},
+	}, {
+		name: "reserved name",
+		ps: corev1.PodSpec{
+			InitContainers: []corev1.Container{{
+				Name:  "queue-proxy",
+				Image: "busybox",
+			}},
+			Containers: []corev1.Container{{
+				Image: "busybox",
+			}},
+		},
+		wantErr: &apis.FieldError{
+			Message: `"queue-proxy" is a reserved container name`,
+			Paths:   []string{"initContainers[0].name"},
+		},
+	}, {
+		name: "probes and ports",
+		ps: corev1.PodSpec{
+			InitContainers: []corev1.Container{{
+				Name:  "migrate",
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 8888,
+				}},
+				ReadinessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						TCPSocket: &corev1.TCPSocketAction{},
+					},
+				},
+			}},
+			Containers: []corev1.Container{{
+				Image: "busybox",
+			}},
+		},
+		wantErr: apis.ErrDisallowedFields("initContainers[0].ports", "initContainers[0].readinessProbe"),
 	}, {
-		name: "PodSpecSecurityContext",
-		featureSpec: corev1.PodSpec{
-			SecurityContext: &corev1.PodSecurityContext{},
-		},
-		err: &apis.FieldError{
-			Message: "must not set the field(s)",
-			Paths:   []string{"securityContext"},
+		name: "lifecycle",
+		ps: corev1.PodSpec{
+			InitContainers: []corev1.Container{{
+				Name:  "migrate",
+				Image: "busybox",
+				Lifecycle: &corev1.Lifecycle{
+					PostStart: &corev1.Handler{
+						Exec: &corev1.ExecAction{Command: []string{"true"}},
+					},
+				},
+			}},
+			Containers: []corev1.Container{{
+				Image: "busybox",
+			}},
 		},
-		cfgOpts: []configOption{withPodSpecSecurityContextEnabled()},
-	}}
-
-	featureTests := []struct {
-		nameTemplate       string
-		enableFeature      bool
-		includeFeatureSpec bool
-		wantError          bool
-	}{{
-		nameTemplate:       "flag disabled: %s not present",
-		enableFeature:      false,
-		includeFeatureSpec: false,
-		wantError:          false,
-	}, {
-		nameTemplate:       "flag disabled: %s present",
-		enableFeature:      false,
-		includeFeatureSpec: true,
-		wantError:          true,
-	}, {
-		nameTemplate:       "flag enabled: %s not present",
-		enableFeature:      true,
-		includeFeatureSpec: false,
-		wantError:          false,
+		wantErr: apis.ErrDisallowedFields("initContainers[0].lifecycle"),
 	}, {
-		nameTemplate:       "flag enabled: %s present",
-		enableFeature:      true,
-		includeFeatureSpec: true,
-		wantError:          false,
+		name: "missing image",
+		ps: corev1.PodSpec{
+			InitContainers: []corev1.Container{{
+				Name: "migrate",
+			}},
+			Containers: []corev1.Container{{
+				Image: "busybox",
+			}},
+		},
+		wantErr: apis.ErrMissingField("initContainers[0].image"),
 	}}
 
-	for _, featureData := range featureData {
-		for _, test := range featureTests {
-			t.Run(fmt.Sprintf(test.nameTemplate, featureData.name), func(t *testing.T) {
-				ctx := context.Background()
-				obj := corev1.PodSpec{
-					Containers: []corev1.Container{{
-						Image: "busybox",
-					}},
-				}
-				want := &apis.FieldError{}
-				if test.wantError {
-					want = featureData.err
-				}
-				if test.enableFeature {
-					cfg := config.FromContextOrDefaults(ctx)
-					for _, opt := range featureData.cfgOpts {
-						cfg = opt(cfg)
-					}
-					ctx = config.ToContext(ctx, cfg)
-				}
-				if test.includeFeatureSpec {
-					obj = featureData.featureSpec
-					obj.Containers = []corev1.Container{{
-						Image: "busybox",
-					}}
-				}
-				got := ValidatePodSpec(ctx, obj)
-				if diff := cmp.Diff(want.Error(), got.Error()); diff != "" {
-					t.Errorf("ValidatePodSpec (-want, +got): \n%s", diff)
-				}
-			})
-		}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := config.ToContext(context.Background(),
+				withPodSpecInitContainersEnabled()(config.FromContextOrDefaults(context.Background())))
+			got := ValidatePodSpec(ctx, test.ps)
+			if got, want := got.Error(), test.wantErr.Error(); got != want {
+				t.Errorf("ValidatePodSpec =\n%s\nwant:\n%s", got, want)
+			}
+		})
 	}
 }
 
@@ -810,7 +1808,7 @@ func TestContainerValidation(t *testing.T) {
 		name    string
 		c       corev1.Container
 		want    *apis.FieldError
-		volumes sets.String
+		volumes map[string]corev1.Volume
 		cfgOpts []configOption
 	}{{
 		name: "empty container",
@@ -888,6 +1886,43 @@ func TestContainerValidation(t *testing.T) {
 			}},
 		},
 		want: nil,
+	}, {
+		name: "has valid user port unix",
+		c: corev1.Container{
+			Image: "foo",
+			Ports: []corev1.ContainerPort{{
+				Name: "unix",
+			}},
+			ReadinessProbe: &corev1.Probe{
+				SuccessThreshold: 1,
+				Handler: corev1.Handler{
+					TCPSocket: &corev1.TCPSocketAction{},
+				},
+			},
+			LivenessProbe: &corev1.Probe{
+				Handler: corev1.Handler{
+					HTTPGet: &corev1.HTTPGetAction{
+						Path: "/healthz",
+					},
+				},
+			},
+		},
+		want: nil,
+	}, {
+		name: "unix user port with tcp liveness probe",
+		c: corev1.Container{
+			Image: "foo",
+			Ports: []corev1.ContainerPort{{
+				Name: "unix",
+			}},
+			LivenessProbe: &corev1.Probe{
+				Handler: corev1.Handler{
+					TCPSocket: &corev1.TCPSocketAction{},
+				},
+			},
+		},
+		want: apis.ErrGeneric("tcpSocket probes are not supported with a unix socket serving port",
+			"livenessProbe.tcpSocket"),
 	}, {
 		name: "has more than one ports with valid names",
 		c: corev1.Container{
@@ -903,6 +1938,50 @@ func TestContainerValidation(t *testing.T) {
 			Paths:   []string{"ports"},
 			Details: "Only a single port is allowed",
 		},
+	}, {
+		name: "has named ports with multi-port enabled",
+		c: corev1.Container{
+			Image: "foo",
+			Ports: []corev1.ContainerPort{{
+				ContainerPort: 8080,
+			}, {
+				Name:          "admin",
+				ContainerPort: 8181,
+			}, {
+				Name:          "data",
+				ContainerPort: 8282,
+			}},
+		},
+		cfgOpts: []configOption{withMultiPortEnabled()},
+		want:    nil,
+	}, {
+		name: "has invalid named ports with multi-port enabled",
+		c: corev1.Container{
+			Image: "foo",
+			Ports: []corev1.ContainerPort{{
+				ContainerPort: 8080,
+			}, {
+				ContainerPort: 8181,
+			}, {
+				Name:          "h2c",
+				ContainerPort: 8080,
+			}, {
+				Name:          "admin",
+				ContainerPort: 8022,
+				Protocol:      corev1.ProtocolUDP,
+			}, {
+				Name:          "admin",
+				ContainerPort: 8282,
+			}},
+		},
+		cfgOpts: []configOption{withMultiPortEnabled()},
+		want: apis.ErrMissingField("ports[1].name").Also(
+			apis.ErrInvalidValue(8080, "ports[2].containerPort"),
+			apis.ErrInvalidValue("h2c", "ports[2].name"),
+			apis.ErrInvalidValue(corev1.ProtocolUDP, "ports[3].protocol"),
+			apis.ErrInvalidValue(8022, "ports[3].containerPort"),
+			apis.ErrInvalidValue("admin", "ports[4].name"),
+		),
 	}, {
 		name: "has container port value too large",
 		c: corev1.Container{
@@ -1046,7 +2125,56 @@ func TestContainerValidation(t *testing.T) {
 				ReadOnly:  true,
 			}},
 		},
-		volumes: sets.NewString("the-name"),
+		volumes: map[string]corev1.Volume{"the-name": {}},
+	}, {
+		name: "has writable emptyDir volumeMount",
+		c: corev1.Container{
+			Image: "foo",
+			VolumeMounts: []corev1.VolumeMount{{
+				MountPath: "/scratch",
+				Name:      "scratch",
+			}},
+		},
+		volumes: map[string]corev1.Volume{"scratch": {
+			Name: "scratch",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		}},
+	}, {
+		name: "has writable persistentVolumeClaim volumeMount",
+		c: corev1.Container{
+			Image: "foo",
+			VolumeMounts: []corev1.VolumeMount{{
+				MountPath: "/cache",
+				Name:      "cache",
+			}},
+		},
+		volumes: map[string]corev1.Volume{"cache": {
+			Name: "cache",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "cache"},
+			},
+		}},
+	}, {
+		name: "has writable volumeMount of read-only persistentVolumeClaim",
+		c: corev1.Container{
+			Image: "foo",
+			VolumeMounts: []corev1.VolumeMount{{
+				MountPath: "/cache",
+				Name:      "cache",
+			}},
+		},
+		volumes: map[string]corev1.Volume{"cache": {
+			Name: "cache",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: "cache",
+					ReadOnly:  true,
+				},
+			},
+		}},
+		want: apis.ErrMissingField("volumeMounts[0].readOnly"),
 	}, {
 		name: "has known volumeMounts, but at reserved path",
 		c: corev1.Container{
@@ -1057,7 +2185,7 @@ func TestContainerValidation(t *testing.T) {
 				ReadOnly:  true,
 			}},
 		},
-		volumes: sets.NewString("the-name"),
+		volumes: map[string]corev1.Volume{"the-name": {}},
 		want: (&apis.FieldError{
 			Message: `mountPath "/var/log" is a reserved path`,
 			Paths:   []string{"mountPath"},
@@ -1072,7 +2200,7 @@ func TestContainerValidation(t *testing.T) {
 				ReadOnly:  true,
 			}},
 		},
-		volumes: sets.NewString("the-name"),
+		volumes: map[string]corev1.Volume{"the-name": {}},
 		want:    apis.ErrInvalidValue("not/absolute", "volumeMounts[0].mountPath"),
 	}, {
 		name: "has lifecycle",
@@ -1095,7 +2223,7 @@ func TestContainerValidation(t *testing.T) {
 				ReadOnly:  true,
 			}},
 		},
-		volumes: sets.NewString("the-name"),
+		volumes: map[string]corev1.Volume{"the-name": {}},
 	}, {
 		name: "valid with probes (no port)",
 		c: corev1.Container{
@@ -1303,6 +2431,44 @@ func TestContainerValidation(t *testing.T) {
 			},
 		},
 		want: apis.ErrOutOfBoundsValue(-10, 0, math.MaxInt32, "securityContext.runAsGroup"),
+	}, {
+		name: "disallowed capabilities",
+		c: corev1.Container{
+			Image: "foo",
+			SecurityContext: &corev1.SecurityContext{
+				Capabilities: &corev1.Capabilities{},
+			},
+		},
+		want: apis.ErrDisallowedFields("securityContext.capabilities"),
+	}, {
+		name:    "secure pod defaults opt-out",
+		cfgOpts: []configOption{withSecurePodDefaultsEnabled()},
+		c: corev1.Container{
+			Image: "foo",
+			SecurityContext: &corev1.SecurityContext{
+				RunAsNonRoot:           ptr.Bool(false),
+				ReadOnlyRootFilesystem: ptr.Bool(false),
+				Capabilities: &corev1.Capabilities{
+					Add:  []corev1.Capability{"NET_BIND_SERVICE"},
+					Drop: []corev1.Capability{"ALL"},
+				},
+			},
+		},
+	}, {
+		name:    "invalid capabilities",
+		cfgOpts: []configOption{withSecurePodDefaultsEnabled()},
+		c: corev1.Container{
+			Image: "foo",
+			SecurityContext: &corev1.SecurityContext{
+				Capabilities: &corev1.Capabilities{
+					Add:  []corev1.Capability{"CAP_NET_ADMIN", "net_raw"},
+					Drop: []corev1.Capability{""},
+				},
+			},
+		},
+		want: apis.ErrInvalidArrayValue("CAP_NET_ADMIN", "securityContext.capabilities.add", 0).Also(
+			apis.ErrInvalidArrayValue("net_raw", "securityContext.capabilities.add", 1)).Also(
+			apis.ErrInvalidArrayValue("", "securityContext.capabilities.drop", 0)),
 	}, {
 		name: "envFrom - None of",
 		c: corev1.Container{
@@ -1413,6 +2579,28 @@ func TestContainerValidation(t *testing.T) {
 			},
 		},
 		want: apis.ErrDisallowedFields("livenessProbe.tcpSocket.port"),
+	}, {
+		name: "valid startup http probe",
+		c: corev1.Container{
+			Image: "foo",
+			StartupProbe: &corev1.Probe{
+				Handler: corev1.Handler{
+					HTTPGet: &corev1.HTTPGetAction{
+						Path: "/started",
+					},
+				},
+				PeriodSeconds:    10,
+				FailureThreshold: 30,
+			},
+		},
+		want: nil,
+	}, {
+		name: "invalid startup probe (no handler)",
+		c: corev1.Container{
+			Image:        "foo",
+			StartupProbe: &corev1.Probe{},
+		},
+		want: apis.ErrMissingOneOf("startupProbe.httpGet", "startupProbe.tcpSocket", "startupProbe.exec"),
 	}, {
 		name: "disallowed container fields",
 		c: corev1.Container{
@@ -1462,9 +2650,10 @@ func TestContainerValidation(t *testing.T) {
 
 func TestVolumeValidation(t *testing.T) {
 	tests := []struct {
-		name string
-		v    corev1.Volume
-		want *apis.FieldError
+		name    string
+		v       corev1.Volume
+		cfgOpts []configOption
+		want    *apis.FieldError
 	}{{
 		name: "just name",
 		v: corev1.Volume{
@@ -1713,11 +2902,86 @@ func TestVolumeValidation(t *testing.T) {
 			},
 		},
 		want: apis.ErrMissingField("projected[0].serviceAccountToken.path"),
+	}, {
+		name: "emptyDir with size limit",
+		v: corev1.Volume{
+			Name: "scratch",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{
+					Medium:    corev1.StorageMediumMemory,
+					SizeLimit: quantityPtr("64Mi"),
+				},
+			},
+		},
+		cfgOpts: []configOption{withPodSpecVolumesEmptyDirEnabled()},
+	}, {
+		name: "emptyDir invalid medium and size limit",
+		v: corev1.Volume{
+			Name: "scratch",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{
+					Medium:    "HugePages",
+					SizeLimit: quantityPtr("-1Mi"),
+				},
+			},
+		},
+		cfgOpts: []configOption{withPodSpecVolumesEmptyDirEnabled()},
+		want: apis.ErrInvalidValue("HugePages", "emptyDir.medium").Also(
+			apis.ErrInvalidValue("-1Mi", "emptyDir.sizeLimit")),
+	}, {
+		name: "emptyDir and secret",
+		v: corev1.Volume{
+			Name: "scratch",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: "foo",
+				},
+			},
+		},
+		cfgOpts: []configOption{withPodSpecVolumesEmptyDirEnabled()},
+		want:    apis.ErrMultipleOneOf("secret", "emptyDir"),
+	}, {
+		name: "no volume source with emptyDir enabled",
+		v: corev1.Volume{
+			Name: "scratch",
+		},
+		cfgOpts: []configOption{withPodSpecVolumesEmptyDirEnabled()},
+		want:    apis.ErrMissingOneOf("secret", "configMap", "projected", "emptyDir"),
+	}, {
+		name: "persistentVolumeClaim",
+		v: corev1.Volume{
+			Name: "cache",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: "cache",
+				},
+			},
+		},
+		cfgOpts: []configOption{withPodSpecPersistentVolumeClaimEnabled()},
+	}, {
+		name: "persistentVolumeClaim without claimName",
+		v: corev1.Volume{
+			Name: "cache",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{},
+			},
+		},
+		cfgOpts: []configOption{withPodSpecPersistentVolumeClaimEnabled()},
+		want:    apis.ErrMissingField("persistentVolumeClaim.claimName"),
 	}}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			got := validateVolume(test.v)
+			ctx := context.Background()
+			if test.cfgOpts != nil {
+				cfg := config.FromContextOrDefaults(ctx)
+				for _, opt := range test.cfgOpts {
+					cfg = opt(cfg)
+				}
+				ctx = config.ToContext(ctx, cfg)
+			}
+			got := validateVolume(ctx, test.v)
 			if diff := cmp.Diff(test.want.Error(), got.Error()); diff != "" {
 				t.Errorf("validateVolume (-want, +got): \n%s", diff)
 			}
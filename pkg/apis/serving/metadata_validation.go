@@ -18,14 +18,23 @@ package serving
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"mime"
+	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/api/validation"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
 
 	"knative.dev/pkg/apis"
 	"knative.dev/serving/pkg/apis/autoscaling"
@@ -41,6 +50,47 @@ var (
 		ForceUpgradeAnnotationKey,
 		RevisionPreservedAnnotationKey,
 		RoutesAnnotationKey,
+		SessionAffinityHeaderAnnotationKey,
+		SessionAffinityCookieAnnotationKey,
+		RequestHeadersSetAnnotationKey,
+		RequestHeadersRemoveAnnotationKey,
+		ResponseHeadersSetAnnotationKey,
+		ConcurrencyStateEndpointAnnotationKey,
+		PreStopPathAnnotationKey,
+		RegistriesSkippingTagResolvingAnnotationKey,
+		OverflowPolicyAnnotationKey,
+		ActivatorQueueDepthAnnotationKey,
+		ActivatorQueueTimeoutAnnotationKey,
+		ConcurrencyModeAnnotationKey,
+		ConcurrencyQueueDepthAnnotationKey,
+		ConcurrencyQueueTimeoutAnnotationKey,
+		MaxRequestBodySizeAnnotationKey,
+		GRPCProbeAnnotationKey,
+		MirrorAnnotationKey,
+		HeaderRulesAnnotationKey,
+		ResponseHeadersRemoveAnnotationKey,
+		RequestLogAnnotationKey,
+		RequestLogTemplateAnnotationKey,
+		LogSidecarAnnotationKey,
+		EndToEndReadinessAnnotationKey,
+		RollbackOnFailureAnnotationKey,
+		HostsAnnotationKey,
+		ResponseCompressionAnnotationKey,
+		ResponseCompressionTypesAnnotationKey,
+		QueueSidecarDebugPortAnnotationKey,
+		QueueSidecarProbePeriodAnnotationKey,
+		QueueSidecarProbeTimeoutAnnotationKey,
+		QueueSidecarProbeBackoffFactorAnnotationKey,
+		QueueSidecarProbeMaxPeriodAnnotationKey,
+		QueueSidecarBackendSchemeAnnotationKey,
+		QueueSidecarBackendCASecretAnnotationKey,
+		SharedPersistentVolumeClaimsAnnotationKey,
+		TopologySpreadConstraintsAnnotationKey,
+		PriorityClassNameAnnotationKey,
+		DeploymentStrategyAnnotationKey,
+		ProgressDeadlineAnnotationKey,
+		DebugPodAnnotationKey,
+		DebugImageAnnotationKey,
 	)
 )
 
@@ -76,24 +126,668 @@ func ValidateHasNoAutoscalingAnnotation(annotations map[string]string) (errs *ap
 }
 
 // ValidateQueueSidecarAnnotation validates QueueSideCarResourcePercentageAnnotation
+// and the queue-proxy resource annotations.
 func ValidateQueueSidecarAnnotation(annotations map[string]string) *apis.FieldError {
 	if len(annotations) == 0 {
 		return nil
 	}
+	errs := validateQueueSidecarResourceAnnotations(annotations)
 	v, ok := annotations[QueueSideCarResourcePercentageAnnotation]
 	if !ok {
-		return nil
+		return errs
 	}
 	value, err := strconv.ParseFloat(v, 64)
 	if err != nil {
-		return apis.ErrInvalidValue(v, apis.CurrentField).ViaKey(QueueSideCarResourcePercentageAnnotation)
+		return errs.Also(apis.ErrInvalidValue(v, apis.CurrentField).ViaKey(QueueSideCarResourcePercentageAnnotation))
 	}
 	if value < 0.1 || value > 100 {
-		return apis.ErrOutOfBoundsValue(value, 0.1, 100.0, apis.CurrentField).ViaKey(QueueSideCarResourcePercentageAnnotation)
+		return errs.Also(apis.ErrOutOfBoundsValue(value, 0.1, 100.0, apis.CurrentField).ViaKey(QueueSideCarResourcePercentageAnnotation))
+	}
+	return errs
+}
+
+// queueSidecarResourceAnnotationKeys are the request and limit annotation
+// keys of each of the queue-proxy resources.
+var queueSidecarResourceAnnotationKeys = [][2]string{
+	{QueueSidecarCPUResourceRequestAnnotationKey, QueueSidecarCPUResourceLimitAnnotationKey},
+	{QueueSidecarMemoryResourceRequestAnnotationKey, QueueSidecarMemoryResourceLimitAnnotationKey},
+	{QueueSidecarEphemeralStorageResourceRequestAnnotationKey, QueueSidecarEphemeralStorageResourceLimitAnnotationKey},
+}
+
+// validateQueueSidecarResourceAnnotations validates that the queue-proxy
+// resource annotations are non-negative quantities, and that the requests
+// don't exceed the limits.
+func validateQueueSidecarResourceAnnotations(annotations map[string]string) (errs *apis.FieldError) {
+	for _, keys := range queueSidecarResourceAnnotationKeys {
+		var quantities [2]*resource.Quantity
+		for i, key := range keys {
+			v, ok := annotations[key]
+			if !ok {
+				continue
+			}
+			q, err := resource.ParseQuantity(v)
+			if err != nil || q.Sign() < 0 {
+				errs = errs.Also(apis.ErrInvalidValue(v, apis.CurrentField).ViaKey(key))
+				continue
+			}
+			quantities[i] = &q
+		}
+		if request, limit := quantities[0], quantities[1]; request != nil && limit != nil && request.Cmp(*limit) > 0 {
+			errs = errs.Also(apis.ErrGeneric(fmt.Sprintf("request %s must be less than or equal to limit %s",
+				request, limit), keys[0], keys[1]))
+		}
+	}
+	return errs
+}
+
+// ValidateSessionAffinityAnnotations validates SessionAffinityHeaderAnnotationKey
+// and SessionAffinityCookieAnnotationKey.
+func ValidateSessionAffinityAnnotations(annotations map[string]string) (errs *apis.FieldError) {
+	header, hasHeader := annotations[SessionAffinityHeaderAnnotationKey]
+	cookie, hasCookie := annotations[SessionAffinityCookieAnnotationKey]
+	if hasHeader && hasCookie {
+		return apis.ErrMultipleOneOf(SessionAffinityHeaderAnnotationKey, SessionAffinityCookieAnnotationKey)
+	}
+	if hasHeader && len(k8svalidation.IsHTTPHeaderName(header)) != 0 {
+		errs = errs.Also(apis.ErrInvalidValue(header, SessionAffinityHeaderAnnotationKey))
+	}
+	if hasCookie && len(k8svalidation.IsHTTPHeaderName(cookie)) != 0 {
+		errs = errs.Also(apis.ErrInvalidValue(cookie, SessionAffinityCookieAnnotationKey))
+	}
+	return errs
+}
+
+// ValidateHeaderAnnotations validates the annotations specifying the
+// request and response headers manipulated by queue-proxy.
+func ValidateHeaderAnnotations(annotations map[string]string) (errs *apis.FieldError) {
+	for _, key := range []string{RequestHeadersSetAnnotationKey, ResponseHeadersSetAnnotationKey} {
+		v, ok := annotations[key]
+		if !ok {
+			continue
+		}
+		var headers map[string]string
+		if err := json.Unmarshal([]byte(v), &headers); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(v, key))
+			continue
+		}
+		for name, value := range headers {
+			if len(k8svalidation.IsHTTPHeaderName(name)) != 0 {
+				errs = errs.Also(apis.ErrInvalidKeyName(name, key))
+			} else if strings.ContainsAny(value, "\r\n\x00") {
+				errs = errs.Also(apis.ErrInvalidValue(value, key+"."+name))
+			}
+		}
+	}
+	for _, key := range []string{RequestHeadersRemoveAnnotationKey, ResponseHeadersRemoveAnnotationKey} {
+		v, ok := annotations[key]
+		if !ok {
+			continue
+		}
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); len(k8svalidation.IsHTTPHeaderName(name)) != 0 {
+				errs = errs.Also(apis.ErrInvalidValue(name, key))
+			}
+		}
+	}
+	return errs
+}
+
+// ValidateConcurrencyStateEndpointAnnotation validates
+// ConcurrencyStateEndpointAnnotationKey.
+func ValidateConcurrencyStateEndpointAnnotation(annotations map[string]string) *apis.FieldError {
+	v, ok := annotations[ConcurrencyStateEndpointAnnotationKey]
+	if !ok {
+		return nil
+	}
+	u, err := url.Parse(strings.ReplaceAll(v, "$HOST_IP", "127.0.0.1"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return apis.ErrInvalidValue(v, ConcurrencyStateEndpointAnnotationKey)
+	}
+	return nil
+}
+
+// ValidatePreStopPathAnnotation validates PreStopPathAnnotationKey against the
+// lifecycle of the serving container, as both set the path queue-proxy calls.
+func ValidatePreStopPathAnnotation(annotations map[string]string, lc *corev1.Lifecycle) *apis.FieldError {
+	v, ok := annotations[PreStopPathAnnotationKey]
+	if !ok {
+		return nil
+	}
+	if !isPreStopPath(v) {
+		return apis.ErrInvalidValue(v, PreStopPathAnnotationKey)
+	}
+	if lc != nil && lc.PreStop != nil {
+		return apis.ErrGeneric("cannot be set along with the preStop hook of the serving container", PreStopPathAnnotationKey)
+	}
+	return nil
+}
+
+// ValidateRegistriesSkippingTagResolvingAnnotation validates
+// RegistriesSkippingTagResolvingAnnotationKey, which is not allowed while the
+// image signatures are verified.
+func ValidateRegistriesSkippingTagResolvingAnnotation(ctx context.Context, annotations map[string]string) *apis.FieldError {
+	v, ok := annotations[RegistriesSkippingTagResolvingAnnotationKey]
+	if !ok {
+		return nil
+	}
+	if IsImageSignatureVerified(ctx) {
+		return apis.ErrGeneric("the tag resolution cannot be skipped while the image signatures are verified",
+			RegistriesSkippingTagResolvingAnnotationKey)
+	}
+	for _, r := range strings.Split(v, ",") {
+		r = strings.TrimSpace(r)
+		if r == AllRegistries {
+			continue
+		}
+		if _, err := name.NewRegistry(r, name.StrictValidation); err != nil {
+			return apis.ErrInvalidValue(v, RegistriesSkippingTagResolvingAnnotationKey)
+		}
 	}
 	return nil
 }
 
+// isPreStopPath returns whether the path is an absolute one on the user port.
+func isPreStopPath(v string) bool {
+	u, err := url.Parse(v)
+	return err == nil && strings.HasPrefix(v, "/") && u.Host == ""
+}
+
+// ValidateOverflowPolicyAnnotation validates OverflowPolicyAnnotationKey.
+func ValidateOverflowPolicyAnnotation(annotations map[string]string) *apis.FieldError {
+	if v, ok := annotations[OverflowPolicyAnnotationKey]; ok && v != OverflowPolicyQueue && v != OverflowPolicyReject {
+		return apis.ErrInvalidValue(v, OverflowPolicyAnnotationKey)
+	}
+	return nil
+}
+
+// ValidateActivatorQueueAnnotations validates ActivatorQueueDepthAnnotationKey
+// and ActivatorQueueTimeoutAnnotationKey.
+func ValidateActivatorQueueAnnotations(annotations map[string]string) (errs *apis.FieldError) {
+	if v, ok := annotations[ActivatorQueueDepthAnnotationKey]; ok {
+		if depth, err := strconv.Atoi(v); err != nil || depth < 0 {
+			errs = errs.Also(apis.ErrInvalidValue(v, ActivatorQueueDepthAnnotationKey))
+		}
+	}
+	if v, ok := annotations[ActivatorQueueTimeoutAnnotationKey]; ok {
+		if timeout, err := time.ParseDuration(v); err != nil || timeout < 0 {
+			errs = errs.Also(apis.ErrInvalidValue(v, ActivatorQueueTimeoutAnnotationKey))
+		}
+	}
+	return errs
+}
+
+// ValidateConcurrencyModeAnnotations validates ConcurrencyModeAnnotationKey,
+// ConcurrencyQueueDepthAnnotationKey and ConcurrencyQueueTimeoutAnnotationKey.
+func ValidateConcurrencyModeAnnotations(annotations map[string]string) *apis.FieldError {
+	var errs *apis.FieldError
+	mode, ok := annotations[ConcurrencyModeAnnotationKey]
+	if ok && mode != ConcurrencyModeHard && mode != ConcurrencyModeSoft && mode != ConcurrencyModeReject {
+		errs = errs.Also(apis.ErrInvalidValue(mode, ConcurrencyModeAnnotationKey))
+	}
+	if v, ok := annotations[ConcurrencyQueueDepthAnnotationKey]; ok {
+		if depth, err := strconv.Atoi(v); err != nil || depth <= 0 {
+			errs = errs.Also(apis.ErrInvalidValue(v, ConcurrencyQueueDepthAnnotationKey))
+		} else if mode != ConcurrencyModeSoft {
+			errs = errs.Also(&apis.FieldError{
+				Message: "only supported in the " + ConcurrencyModeSoft + " concurrency mode",
+				Paths:   []string{ConcurrencyQueueDepthAnnotationKey},
+			})
+		}
+	}
+	if v, ok := annotations[ConcurrencyQueueTimeoutAnnotationKey]; ok {
+		if timeout, err := time.ParseDuration(v); err != nil || timeout <= 0 {
+			errs = errs.Also(apis.ErrInvalidValue(v, ConcurrencyQueueTimeoutAnnotationKey))
+		} else if mode != ConcurrencyModeSoft {
+			errs = errs.Also(&apis.FieldError{
+				Message: "only supported in the " + ConcurrencyModeSoft + " concurrency mode",
+				Paths:   []string{ConcurrencyQueueTimeoutAnnotationKey},
+			})
+		}
+	}
+	return errs
+}
+
+// ValidateMaxRequestBodySizeAnnotation validates MaxRequestBodySizeAnnotationKey.
+func ValidateMaxRequestBodySizeAnnotation(annotations map[string]string) *apis.FieldError {
+	v, ok := annotations[MaxRequestBodySizeAnnotationKey]
+	if !ok {
+		return nil
+	}
+	if q, err := resource.ParseQuantity(v); err != nil || q.Sign() <= 0 {
+		return apis.ErrInvalidValue(v, MaxRequestBodySizeAnnotationKey)
+	}
+	return nil
+}
+
+// grpcServiceName matches the fully qualified gRPC service names,
+// e.g. `grpc.health.v1.Health`.
+var grpcServiceName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// ValidateGRPCProbeAnnotation validates GRPCProbeAnnotationKey against the
+// readiness probe of the serving container, which must be a TCP one.
+func ValidateGRPCProbeAnnotation(annotations map[string]string, rp *corev1.Probe) *apis.FieldError {
+	v, ok := annotations[GRPCProbeAnnotationKey]
+	if !ok {
+		return nil
+	}
+	if v != "" && !grpcServiceName.MatchString(v) {
+		return apis.ErrInvalidValue(v, GRPCProbeAnnotationKey)
+	}
+	if rp != nil && rp.TCPSocket == nil {
+		return apis.ErrGeneric("the gRPC probe requires a tcpSocket readiness probe", GRPCProbeAnnotationKey)
+	}
+	return nil
+}
+
+// ValidateRequestLogAnnotations validates RequestLogAnnotationKey and
+// RequestLogTemplateAnnotationKey.
+func ValidateRequestLogAnnotations(annotations map[string]string) (errs *apis.FieldError) {
+	if v, ok := annotations[RequestLogAnnotationKey]; ok {
+		if _, err := strconv.ParseBool(v); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(v, RequestLogAnnotationKey))
+		}
+	}
+	if v, ok := annotations[RequestLogTemplateAnnotationKey]; ok {
+		if msgs := k8svalidation.IsConfigMapKey(v); len(msgs) > 0 {
+			errs = errs.Also(apis.ErrInvalidValue(v, RequestLogTemplateAnnotationKey))
+		}
+	}
+	return errs
+}
+
+// ValidateResponseCompressionAnnotations validates ResponseCompressionAnnotationKey
+// and ResponseCompressionTypesAnnotationKey.
+func ValidateResponseCompressionAnnotations(annotations map[string]string) (errs *apis.FieldError) {
+	if v, ok := annotations[ResponseCompressionAnnotationKey]; ok && v != "gzip" {
+		errs = errs.Also(apis.ErrInvalidValue(v, ResponseCompressionAnnotationKey))
+	}
+	v, ok := annotations[ResponseCompressionTypesAnnotationKey]
+	if !ok {
+		return errs
+	}
+	for _, t := range strings.Split(v, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if mt, params, err := mime.ParseMediaType(t); err != nil || len(params) > 0 || !strings.Contains(mt, "/") {
+			errs = errs.Also(apis.ErrInvalidValue(v, ResponseCompressionTypesAnnotationKey))
+			break
+		}
+	}
+	if _, ok := annotations[ResponseCompressionAnnotationKey]; !ok {
+		errs = errs.Also(apis.ErrGeneric("requires "+ResponseCompressionAnnotationKey, ResponseCompressionTypesAnnotationKey))
+	}
+	return errs
+}
+
+// ValidateQueueSidecarDebugPortAnnotation validates QueueSidecarDebugPortAnnotationKey.
+// The port may not be one of the reserved ports, nor one of the container ports.
+func ValidateQueueSidecarDebugPortAnnotation(annotations map[string]string, containers []corev1.Container) *apis.FieldError {
+	v, ok := annotations[QueueSidecarDebugPortAnnotationKey]
+	if !ok {
+		return nil
+	}
+	port, err := strconv.ParseInt(v, 10, 32)
+	if err != nil || port < 1 || port > 65535 || reservedPorts.Has(int32(port)) {
+		return apis.ErrInvalidValue(v, QueueSidecarDebugPortAnnotationKey)
+	}
+	for _, c := range containers {
+		for _, p := range c.Ports {
+			if p.ContainerPort == int32(port) {
+				return apis.ErrGeneric("port is used by container "+c.Name, QueueSidecarDebugPortAnnotationKey)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateQueueSidecarProbeAnnotations validates the queue-proxy readiness
+// probe timing annotations.
+func ValidateQueueSidecarProbeAnnotations(annotations map[string]string) (errs *apis.FieldError) {
+	durations := make(map[string]time.Duration, 3)
+	for _, k := range []string{
+		QueueSidecarProbePeriodAnnotationKey,
+		QueueSidecarProbeTimeoutAnnotationKey,
+		QueueSidecarProbeMaxPeriodAnnotationKey,
+	} {
+		v, ok := annotations[k]
+		if !ok {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			errs = errs.Also(apis.ErrInvalidValue(v, k))
+			continue
+		}
+		durations[k] = d
+	}
+	if v, ok := annotations[QueueSidecarProbeBackoffFactorAnnotationKey]; ok {
+		if f, err := strconv.ParseFloat(v, 64); err != nil || f < 1 {
+			errs = errs.Also(apis.ErrInvalidValue(v, QueueSidecarProbeBackoffFactorAnnotationKey))
+		}
+	}
+	period, hasPeriod := durations[QueueSidecarProbePeriodAnnotationKey]
+	if maxPeriod, ok := durations[QueueSidecarProbeMaxPeriodAnnotationKey]; ok && hasPeriod && maxPeriod < period {
+		errs = errs.Also(&apis.FieldError{
+			Message: "may not be less than " + QueueSidecarProbePeriodAnnotationKey,
+			Paths:   []string{QueueSidecarProbeMaxPeriodAnnotationKey},
+		})
+	}
+	return errs
+}
+
+// ValidateQueueSidecarBackendAnnotations validates
+// QueueSidecarBackendSchemeAnnotationKey and QueueSidecarBackendCASecretAnnotationKey
+// against the ports of the serving container. HTTPS isn't supported with a
+// unix socket serving port, nor with the gRPC probe.
+func ValidateQueueSidecarBackendAnnotations(annotations map[string]string, ports []corev1.ContainerPort) (errs *apis.FieldError) {
+	scheme, ok := annotations[QueueSidecarBackendSchemeAnnotationKey]
+	if ok && scheme != BackendSchemeHTTP && scheme != BackendSchemeHTTPS {
+		errs = errs.Also(apis.ErrInvalidValue(scheme, QueueSidecarBackendSchemeAnnotationKey))
+	}
+	if scheme == BackendSchemeHTTPS {
+		if len(ports) > 0 && ports[0].Name == UnixSocketPortName {
+			errs = errs.Also(apis.ErrGeneric("https is not supported with a unix socket serving port",
+				QueueSidecarBackendSchemeAnnotationKey))
+		}
+		if _, ok := annotations[GRPCProbeAnnotationKey]; ok {
+			errs = errs.Also(apis.ErrGeneric("https is not supported with the gRPC probe",
+				QueueSidecarBackendSchemeAnnotationKey))
+		}
+	}
+	if v, ok := annotations[QueueSidecarBackendCASecretAnnotationKey]; ok {
+		if len(k8svalidation.IsDNS1123Subdomain(v)) > 0 {
+			errs = errs.Also(apis.ErrInvalidValue(v, QueueSidecarBackendCASecretAnnotationKey))
+		} else if scheme != BackendSchemeHTTPS {
+			errs = errs.Also(&apis.FieldError{
+				Message: "only supported with the " + BackendSchemeHTTPS + " backend scheme",
+				Paths:   []string{QueueSidecarBackendCASecretAnnotationKey},
+			})
+		}
+	}
+	return errs
+}
+
+// ValidateSharedPersistentVolumeClaimsAnnotation validates
+// SharedPersistentVolumeClaimsAnnotationKey. Without it, the writable
+// persistentVolumeClaim volumes of the pod spec are assumed ReadWriteOnce,
+// so the maxScale of the Revision must be set to 1, and its minScale cannot
+// exceed 1.
+func ValidateSharedPersistentVolumeClaimsAnnotation(annotations map[string]string, ps corev1.PodSpec) *apis.FieldError {
+	v, ok := annotations[SharedPersistentVolumeClaimsAnnotationKey]
+	if ok && v != "true" && v != "false" {
+		return apis.ErrInvalidValue(v, SharedPersistentVolumeClaimsAnnotationKey)
+	}
+	if v == "true" || !hasWritablePersistentVolumeClaim(ps) {
+		return nil
+	}
+	var errs *apis.FieldError
+	// Invalid scales are reported by the autoscaling annotations validation.
+	if scale, err := strconv.Atoi(annotations[autoscaling.MinScaleAnnotationKey]); err == nil && scale > 1 {
+		errs = errs.Also(&apis.FieldError{
+			Message: fmt.Sprintf("cannot exceed 1 with writable persistentVolumeClaim volumes, "+
+				"unless %s is true", SharedPersistentVolumeClaimsAnnotationKey),
+			Paths: []string{autoscaling.MinScaleAnnotationKey},
+		})
+	}
+	// A missing or zero maxScale means the Revision is not bounded.
+	v, ok = annotations[autoscaling.MaxScaleAnnotationKey]
+	if scale, err := strconv.Atoi(v); !ok || err == nil && scale != 1 {
+		errs = errs.Also(&apis.FieldError{
+			Message: fmt.Sprintf("must be set to 1 with writable persistentVolumeClaim volumes, "+
+				"unless %s is true", SharedPersistentVolumeClaimsAnnotationKey),
+			Paths: []string{autoscaling.MaxScaleAnnotationKey},
+		})
+	}
+	return errs
+}
+
+// hasWritablePersistentVolumeClaim returns true if a container of the pod
+// spec mounts a persistentVolumeClaim volume writable.
+func hasWritablePersistentVolumeClaim(ps corev1.PodSpec) bool {
+	claims := sets.NewString()
+	for _, v := range ps.Volumes {
+		if pvc := v.PersistentVolumeClaim; pvc != nil && !pvc.ReadOnly {
+			claims.Insert(v.Name)
+		}
+	}
+	for _, cs := range [][]corev1.Container{ps.Containers, ps.InitContainers} {
+		for _, c := range cs {
+			for _, vm := range c.VolumeMounts {
+				if !vm.ReadOnly && claims.Has(vm.Name) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// ValidateTopologySpreadConstraintsAnnotation validates
+// TopologySpreadConstraintsAnnotationKey.
+func ValidateTopologySpreadConstraintsAnnotation(annotations map[string]string) *apis.FieldError {
+	v, ok := annotations[TopologySpreadConstraintsAnnotationKey]
+	if !ok {
+		return nil
+	}
+	if _, err := ParseTopologySpreadConstraints(v); err != nil {
+		fe := apis.ErrInvalidValue(v, TopologySpreadConstraintsAnnotationKey)
+		fe.Details = err.Error()
+		return fe
+	}
+	return nil
+}
+
+// ValidatePriorityClassNameAnnotation validates PriorityClassNameAnnotationKey.
+func ValidatePriorityClassNameAnnotation(annotations map[string]string) *apis.FieldError {
+	if v, ok := annotations[PriorityClassNameAnnotationKey]; ok && len(k8svalidation.IsDNS1123Subdomain(v)) != 0 {
+		return apis.ErrInvalidValue(v, PriorityClassNameAnnotationKey)
+	}
+	return nil
+}
+
+// ValidateDeploymentStrategyAnnotation validates DeploymentStrategyAnnotationKey.
+func ValidateDeploymentStrategyAnnotation(annotations map[string]string) *apis.FieldError {
+	v, ok := annotations[DeploymentStrategyAnnotationKey]
+	if !ok {
+		return nil
+	}
+	if _, err := ParseDeploymentStrategy(v); err != nil {
+		fe := apis.ErrInvalidValue(v, DeploymentStrategyAnnotationKey)
+		fe.Details = err.Error()
+		return fe
+	}
+	return nil
+}
+
+// ValidateProgressDeadlineAnnotation validates ProgressDeadlineAnnotationKey.
+func ValidateProgressDeadlineAnnotation(annotations map[string]string) *apis.FieldError {
+	v, ok := annotations[ProgressDeadlineAnnotationKey]
+	if !ok {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return apis.ErrInvalidValue(v, ProgressDeadlineAnnotationKey)
+	}
+	if d.Truncate(time.Second) != d {
+		fe := apis.ErrInvalidValue(v, ProgressDeadlineAnnotationKey)
+		fe.Details = "must be rounded to a whole second"
+		return fe
+	}
+	return nil
+}
+
+// ValidateDebugContainerAnnotations validates DebugPodAnnotationKey and
+// DebugImageAnnotationKey, which are only allowed with the debug-containers
+// feature enabled.
+func ValidateDebugContainerAnnotations(ctx context.Context, annotations map[string]string) (errs *apis.FieldError) {
+	pod, hasPod := annotations[DebugPodAnnotationKey]
+	image, hasImage := annotations[DebugImageAnnotationKey]
+	if !hasPod && !hasImage {
+		return nil
+	}
+	if config.FromContextOrDefaults(ctx).Features.DebugContainers != config.Enabled {
+		if hasPod {
+			errs = errs.Also(apis.ErrInvalidKeyName(DebugPodAnnotationKey, apis.CurrentField,
+				"debug containers are not enabled"))
+		}
+		if hasImage {
+			errs = errs.Also(apis.ErrInvalidKeyName(DebugImageAnnotationKey, apis.CurrentField,
+				"debug containers are not enabled"))
+		}
+		return errs
+	}
+	switch {
+	case !hasPod:
+		errs = errs.Also(apis.ErrMissingField(DebugPodAnnotationKey))
+	case len(k8svalidation.IsDNS1123Subdomain(pod)) != 0:
+		errs = errs.Also(apis.ErrInvalidValue(pod, DebugPodAnnotationKey))
+	}
+	switch {
+	case !hasImage:
+		errs = errs.Also(apis.ErrMissingField(DebugImageAnnotationKey))
+	case strings.TrimSpace(image) == "":
+		errs = errs.Also(apis.ErrInvalidValue(image, DebugImageAnnotationKey))
+	default:
+		// The debug image is subject to the same policy as the images of the
+		// containers.
+		if ref, err := name.ParseReference(image, name.WeakValidation); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(image, DebugImageAnnotationKey))
+		} else {
+			errs = errs.Also(validateImagePolicy(ctx, ref).ViaField(DebugImageAnnotationKey))
+		}
+	}
+	return errs
+}
+
+// ValidateEndToEndReadinessAnnotation validates EndToEndReadinessAnnotationKey.
+func ValidateEndToEndReadinessAnnotation(annotations map[string]string) *apis.FieldError {
+	if v, ok := annotations[EndToEndReadinessAnnotationKey]; ok && v != "true" {
+		return apis.ErrInvalidValue(v, EndToEndReadinessAnnotationKey)
+	}
+	return nil
+}
+
+// ValidateRollbackOnFailureAnnotation validates RollbackOnFailureAnnotationKey.
+func ValidateRollbackOnFailureAnnotation(annotations map[string]string) *apis.FieldError {
+	if v, ok := annotations[RollbackOnFailureAnnotationKey]; ok && v != "true" {
+		return apis.ErrInvalidValue(v, RollbackOnFailureAnnotationKey)
+	}
+	return nil
+}
+
+// ValidateLogSidecarAnnotation validates LogSidecarAnnotationKey.
+func ValidateLogSidecarAnnotation(annotations map[string]string) *apis.FieldError {
+	if v, ok := annotations[LogSidecarAnnotationKey]; ok && v != "true" {
+		return apis.ErrInvalidValue(v, LogSidecarAnnotationKey)
+	}
+	return nil
+}
+
+// ValidateHostsAnnotation validates HostsAnnotationKey. Whether the hosts are
+// allowed is only known to the Route reconciler.
+func ValidateHostsAnnotation(annotations map[string]string) (errs *apis.FieldError) {
+	for _, host := range ParseHostsAnnotation(annotations) {
+		if msgs := k8svalidation.IsDNS1123Subdomain(host); len(msgs) > 0 {
+			errs = errs.Also(apis.ErrInvalidValue(host, HostsAnnotationKey))
+		}
+	}
+	return errs
+}
+
+// ValidateMirrorAnnotation validates MirrorAnnotationKey.
+func ValidateMirrorAnnotation(annotations map[string]string) (errs *apis.FieldError) {
+	v, ok := annotations[MirrorAnnotationKey]
+	if !ok {
+		return nil
+	}
+	mirrors, err := ParseMirrorAnnotation(annotations)
+	if err != nil {
+		return apis.ErrInvalidValue(v, MirrorAnnotationKey)
+	}
+	for tag, m := range mirrors {
+		if msgs := k8svalidation.IsDNS1035Label(tag); len(msgs) > 0 {
+			errs = errs.Also(apis.ErrInvalidKeyName(tag, MirrorAnnotationKey, msgs...))
+		}
+		if msgs := k8svalidation.IsDNS1123Subdomain(m.RevisionName); len(msgs) > 0 {
+			errs = errs.Also(apis.ErrInvalidValue(m.RevisionName, MirrorAnnotationKey+"."+tag+".revisionName"))
+		}
+		if m.Percent < 1 || m.Percent > 100 {
+			errs = errs.Also(apis.ErrOutOfBoundsValue(m.Percent, 1, 100, MirrorAnnotationKey+"."+tag+".percent"))
+		}
+	}
+	return errs
+}
+
+// ValidateTagVisibilityAnnotation validates TagVisibilityAnnotationKey.
+func ValidateTagVisibilityAnnotation(annotations map[string]string) (errs *apis.FieldError) {
+	v, ok := annotations[TagVisibilityAnnotationKey]
+	if !ok {
+		return nil
+	}
+	visibility, err := ParseTagVisibilityAnnotation(annotations)
+	if err != nil {
+		return apis.ErrInvalidValue(v, TagVisibilityAnnotationKey)
+	}
+	for tag, vis := range visibility {
+		if msgs := k8svalidation.IsDNS1035Label(tag); len(msgs) > 0 {
+			errs = errs.Also(apis.ErrInvalidKeyName(tag, TagVisibilityAnnotationKey, msgs...))
+		}
+		if vis != VisibilityClusterLocal {
+			errs = errs.Also(apis.ErrInvalidValue(vis, TagVisibilityAnnotationKey+"."+tag))
+		}
+	}
+	return errs
+}
+
+// ValidateHeaderRulesAnnotation validates HeaderRulesAnnotationKey.
+func ValidateHeaderRulesAnnotation(annotations map[string]string) (errs *apis.FieldError) {
+	v, ok := annotations[HeaderRulesAnnotationKey]
+	if !ok {
+		return nil
+	}
+	rules, err := ParseHeaderRulesAnnotation(annotations)
+	if err != nil {
+		return apis.ErrInvalidValue(v, HeaderRulesAnnotationKey)
+	}
+	for tag, r := range rules {
+		if tag != HeaderRulesAllTargets {
+			if msgs := k8svalidation.IsDNS1035Label(tag); len(msgs) > 0 {
+				errs = errs.Also(apis.ErrInvalidKeyName(tag, HeaderRulesAnnotationKey, msgs...))
+				continue
+			}
+		}
+		errs = errs.Also(validateHeaderOperations(r.Request, HeaderRulesAnnotationKey+"."+tag+".request"))
+		errs = errs.Also(validateHeaderOperations(r.Response, HeaderRulesAnnotationKey+"."+tag+".response"))
+	}
+	return errs
+}
+
+// validateHeaderOperations validates the header names and values of ops.
+func validateHeaderOperations(ops HeaderOperations, path string) (errs *apis.FieldError) {
+	invalidName := func(name string) bool {
+		return len(k8svalidation.IsHTTPHeaderName(name)) != 0 ||
+			strings.HasPrefix(strings.ToLower(name), "knative-")
+	}
+	for field, headers := range map[string]map[string]string{"set": ops.Set, "add": ops.Add} {
+		for name, value := range headers {
+			if invalidName(name) {
+				errs = errs.Also(apis.ErrInvalidKeyName(name, path+"."+field))
+			} else if strings.ContainsAny(value, "\r\n\x00") {
+				errs = errs.Also(apis.ErrInvalidValue(value, path+"."+field+"."+name))
+			}
+		}
+	}
+	for i, name := range ops.Remove {
+		if invalidName(name) {
+			errs = errs.Also(apis.ErrInvalidArrayValue(name, path+".remove", i))
+		}
+	}
+	return errs
+}
+
 // ValidateTimeoutSeconds validates timeout by comparing MaxRevisionTimeoutSeconds
 func ValidateTimeoutSeconds(ctx context.Context, timeoutSeconds int64) *apis.FieldError {
 	if timeoutSeconds != 0 {
@@ -107,6 +801,49 @@ func ValidateTimeoutSeconds(ctx context.Context, timeoutSeconds int64) *apis.Fie
 	return nil
 }
 
+// ValidateResponseStartTimeoutSeconds validates the response start timeout
+// by comparing it with the timeout, if set, and MaxRevisionTimeoutSeconds.
+func ValidateResponseStartTimeoutSeconds(ctx context.Context, responseStartTimeoutSeconds int64, timeoutSeconds *int64) *apis.FieldError {
+	max := config.FromContextOrDefaults(ctx).Defaults.MaxRevisionTimeoutSeconds
+	if timeoutSeconds != nil && *timeoutSeconds > 0 && *timeoutSeconds < max {
+		max = *timeoutSeconds
+	}
+	if responseStartTimeoutSeconds > max || responseStartTimeoutSeconds < 0 {
+		return apis.ErrOutOfBoundsValue(responseStartTimeoutSeconds, 0, max, "responseStartTimeoutSeconds")
+	}
+	return nil
+}
+
+// ValidateIdleTimeoutSeconds validates the idle timeout by comparing it
+// with MaxRevisionTimeoutSeconds.
+func ValidateIdleTimeoutSeconds(ctx context.Context, idleTimeoutSeconds int64) *apis.FieldError {
+	max := config.FromContextOrDefaults(ctx).Defaults.MaxRevisionTimeoutSeconds
+	if idleTimeoutSeconds > max || idleTimeoutSeconds < 0 {
+		return apis.ErrOutOfBoundsValue(idleTimeoutSeconds, 0, max, "idleTimeoutSeconds")
+	}
+	return nil
+}
+
+// ValidateDrainTimeoutSeconds validates the drain timeout by comparing it
+// with MaxRevisionTimeoutSeconds.
+func ValidateDrainTimeoutSeconds(ctx context.Context, drainTimeoutSeconds int64) *apis.FieldError {
+	max := config.FromContextOrDefaults(ctx).Defaults.MaxRevisionTimeoutSeconds
+	if drainTimeoutSeconds > max || drainTimeoutSeconds < 0 {
+		return apis.ErrOutOfBoundsValue(drainTimeoutSeconds, 0, max, "drainTimeoutSeconds")
+	}
+	return nil
+}
+
+// ValidateTerminationGracePeriodSeconds validates the termination grace
+// period by comparing it with MaxTerminationGracePeriodSeconds.
+func ValidateTerminationGracePeriodSeconds(ctx context.Context, terminationGracePeriodSeconds int64) *apis.FieldError {
+	max := config.FromContextOrDefaults(ctx).Defaults.TerminationGracePeriodSecondsLimit()
+	if terminationGracePeriodSeconds > max || terminationGracePeriodSeconds < 0 {
+		return apis.ErrOutOfBoundsValue(terminationGracePeriodSeconds, 0, max, "terminationGracePeriodSeconds")
+	}
+	return nil
+}
+
 // ValidateContainerConcurrency function validates the ContainerConcurrency field
 // TODO(#5007): Move this to autoscaling.
 func ValidateContainerConcurrency(ctx context.Context, containerConcurrency *int64) *apis.FieldError {
@@ -26,6 +26,8 @@ import (
 	authv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
 	network "knative.dev/networking/pkg"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/ptr"
@@ -299,6 +301,41 @@ func TestValidateQueueSidecarAnnotation(t *testing.T) {
 		annotation: map[string]string{
 			QueueSideCarResourcePercentageAnnotation: "100",
 		},
+	}, {
+		name: "valid queue sidecar resources",
+		annotation: map[string]string{
+			QueueSidecarCPUResourceRequestAnnotationKey:    "500m",
+			QueueSidecarCPUResourceLimitAnnotationKey:      "1",
+			QueueSidecarMemoryResourceLimitAnnotationKey:   "200Mi",
+			QueueSideCarResourcePercentageAnnotation:       "10",
+			QueueSidecarMemoryResourceRequestAnnotationKey: "100Mi",
+		},
+	}, {
+		name: "invalid queue sidecar resource",
+		annotation: map[string]string{
+			QueueSidecarEphemeralStorageResourceRequestAnnotationKey: "lots",
+		},
+		expectErr: &apis.FieldError{
+			Message: "invalid value: lots",
+			Paths:   []string{fmt.Sprintf("[%s]", QueueSidecarEphemeralStorageResourceRequestAnnotationKey)},
+		},
+	}, {
+		name: "negative queue sidecar resource",
+		annotation: map[string]string{
+			QueueSidecarCPUResourceLimitAnnotationKey: "-1",
+		},
+		expectErr: &apis.FieldError{
+			Message: "invalid value: -1",
+			Paths:   []string{fmt.Sprintf("[%s]", QueueSidecarCPUResourceLimitAnnotationKey)},
+		},
+	}, {
+		name: "queue sidecar request exceeds limit",
+		annotation: map[string]string{
+			QueueSidecarCPUResourceRequestAnnotationKey: "2",
+			QueueSidecarCPUResourceLimitAnnotationKey:   "1",
+		},
+		expectErr: apis.ErrGeneric("request 2 must be less than or equal to limit 1",
+			QueueSidecarCPUResourceRequestAnnotationKey, QueueSidecarCPUResourceLimitAnnotationKey),
 	}}
 
 	for _, c := range cases {
@@ -311,6 +348,1332 @@ func TestValidateQueueSidecarAnnotation(t *testing.T) {
 	}
 }
 
+func TestValidateSessionAffinityAnnotations(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no session affinity",
+		annotation: map[string]string{},
+	}, {
+		name: "header",
+		annotation: map[string]string{
+			SessionAffinityHeaderAnnotationKey: "X-Session-Id",
+		},
+	}, {
+		name: "cookie",
+		annotation: map[string]string{
+			SessionAffinityCookieAnnotationKey: "session",
+		},
+	}, {
+		name: "both",
+		annotation: map[string]string{
+			SessionAffinityHeaderAnnotationKey: "X-Session-Id",
+			SessionAffinityCookieAnnotationKey: "session",
+		},
+		expectErr: apis.ErrMultipleOneOf(SessionAffinityHeaderAnnotationKey, SessionAffinityCookieAnnotationKey),
+	}, {
+		name: "invalid header",
+		annotation: map[string]string{
+			SessionAffinityHeaderAnnotationKey: "X Session",
+		},
+		expectErr: apis.ErrInvalidValue("X Session", SessionAffinityHeaderAnnotationKey),
+	}, {
+		name: "empty cookie",
+		annotation: map[string]string{
+			SessionAffinityCookieAnnotationKey: "",
+		},
+		expectErr: apis.ErrInvalidValue("", SessionAffinityCookieAnnotationKey),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateSessionAffinityAnnotations(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateHeaderAnnotations(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no header annotations",
+		annotation: map[string]string{},
+	}, {
+		name: "valid",
+		annotation: map[string]string{
+			RequestHeadersSetAnnotationKey:     `{"X-Env": "prod"}`,
+			RequestHeadersRemoveAnnotationKey:  "X-Internal, X-Debug",
+			ResponseHeadersSetAnnotationKey:    `{"Strict-Transport-Security": "max-age=31536000; includeSubDomains"}`,
+			ResponseHeadersRemoveAnnotationKey: "Server",
+		},
+	}, {
+		name: "malformed json",
+		annotation: map[string]string{
+			ResponseHeadersSetAnnotationKey: "Server: none",
+		},
+		expectErr: apis.ErrInvalidValue("Server: none", ResponseHeadersSetAnnotationKey),
+	}, {
+		name: "invalid header name",
+		annotation: map[string]string{
+			RequestHeadersSetAnnotationKey: `{"X Env": "prod"}`,
+		},
+		expectErr: apis.ErrInvalidKeyName("X Env", RequestHeadersSetAnnotationKey),
+	}, {
+		name: "invalid header value",
+		annotation: map[string]string{
+			RequestHeadersSetAnnotationKey: `{"X-Env": "prod\r\nX-Evil: 1"}`,
+		},
+		expectErr: apis.ErrInvalidValue("prod\r\nX-Evil: 1", RequestHeadersSetAnnotationKey+".X-Env"),
+	}, {
+		name: "invalid header to remove",
+		annotation: map[string]string{
+			ResponseHeadersRemoveAnnotationKey: "Server,,X-Powered-By",
+		},
+		expectErr: apis.ErrInvalidValue("", ResponseHeadersRemoveAnnotationKey),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateHeaderAnnotations(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateConcurrencyStateEndpointAnnotation(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name: "valid",
+		annotation: map[string]string{
+			ConcurrencyStateEndpointAnnotationKey: "http://$HOST_IP:9696/state",
+		},
+	}, {
+		name: "not http",
+		annotation: map[string]string{
+			ConcurrencyStateEndpointAnnotationKey: "unix:///var/run/state.sock",
+		},
+		expectErr: apis.ErrInvalidValue("unix:///var/run/state.sock", ConcurrencyStateEndpointAnnotationKey),
+	}, {
+		name: "no host",
+		annotation: map[string]string{
+			ConcurrencyStateEndpointAnnotationKey: "/state",
+		},
+		expectErr: apis.ErrInvalidValue("/state", ConcurrencyStateEndpointAnnotationKey),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateConcurrencyStateEndpointAnnotation(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateRegistriesSkippingTagResolvingAnnotation(t *testing.T) {
+	cases := []struct {
+		name       string
+		ctx        context.Context
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name:       "registries",
+		annotation: map[string]string{RegistriesSkippingTagResolvingAnnotationKey: "registry.internal:5000, mirror.internal"},
+	}, {
+		name:       "all registries",
+		annotation: map[string]string{RegistriesSkippingTagResolvingAnnotationKey: AllRegistries},
+	}, {
+		name:       "empty registry",
+		annotation: map[string]string{RegistriesSkippingTagResolvingAnnotationKey: "registry.internal,,"},
+		expectErr:  apis.ErrInvalidValue("registry.internal,,", RegistriesSkippingTagResolvingAnnotationKey),
+	}, {
+		name:       "repository",
+		annotation: map[string]string{RegistriesSkippingTagResolvingAnnotationKey: "registry.internal/team"},
+		expectErr:  apis.ErrInvalidValue("registry.internal/team", RegistriesSkippingTagResolvingAnnotationKey),
+	}, {
+		name:       "signatures verified",
+		ctx:        WithImageSignatureVerification(context.Background()),
+		annotation: map[string]string{RegistriesSkippingTagResolvingAnnotationKey: AllRegistries},
+		expectErr: apis.ErrGeneric("the tag resolution cannot be skipped while the image signatures are verified",
+			RegistriesSkippingTagResolvingAnnotationKey),
+	}, {
+		name:       "signatures verified without annotation",
+		ctx:        WithImageSignatureVerification(context.Background()),
+		annotation: map[string]string{},
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := c.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			err := ValidateRegistriesSkippingTagResolvingAnnotation(ctx, c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidatePreStopPathAnnotation(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		lifecycle  *corev1.Lifecycle
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name:       "valid",
+		annotation: map[string]string{PreStopPathAnnotationKey: "/shutdown?graceful=true"},
+	}, {
+		name:       "relative",
+		annotation: map[string]string{PreStopPathAnnotationKey: "shutdown"},
+		expectErr:  apis.ErrInvalidValue("shutdown", PreStopPathAnnotationKey),
+	}, {
+		name:       "with host",
+		annotation: map[string]string{PreStopPathAnnotationKey: "//example.com/shutdown"},
+		expectErr:  apis.ErrInvalidValue("//example.com/shutdown", PreStopPathAnnotationKey),
+	}, {
+		name:       "with postStart hook",
+		annotation: map[string]string{PreStopPathAnnotationKey: "/shutdown"},
+		lifecycle: &corev1.Lifecycle{
+			PostStart: &corev1.Handler{Exec: &corev1.ExecAction{Command: []string{"warmup"}}},
+		},
+	}, {
+		name:       "with preStop hook",
+		annotation: map[string]string{PreStopPathAnnotationKey: "/shutdown"},
+		lifecycle: &corev1.Lifecycle{
+			PreStop: &corev1.Handler{HTTPGet: &corev1.HTTPGetAction{Path: "/quit"}},
+		},
+		expectErr: apis.ErrGeneric("cannot be set along with the preStop hook of the serving container", PreStopPathAnnotationKey),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidatePreStopPathAnnotation(c.annotation, c.lifecycle)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateConcurrencyModeAnnotations(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name:       "reject",
+		annotation: map[string]string{ConcurrencyModeAnnotationKey: ConcurrencyModeReject},
+	}, {
+		name: "soft with queue depth and timeout",
+		annotation: map[string]string{
+			ConcurrencyModeAnnotationKey:         ConcurrencyModeSoft,
+			ConcurrencyQueueDepthAnnotationKey:   "50",
+			ConcurrencyQueueTimeoutAnnotationKey: "5s",
+		},
+	}, {
+		name:       "invalid mode",
+		annotation: map[string]string{ConcurrencyModeAnnotationKey: "lenient"},
+		expectErr:  apis.ErrInvalidValue("lenient", ConcurrencyModeAnnotationKey),
+	}, {
+		name: "invalid queue depth and timeout",
+		annotation: map[string]string{
+			ConcurrencyModeAnnotationKey:         ConcurrencyModeSoft,
+			ConcurrencyQueueDepthAnnotationKey:   "0",
+			ConcurrencyQueueTimeoutAnnotationKey: "5",
+		},
+		expectErr: apis.ErrInvalidValue("0", ConcurrencyQueueDepthAnnotationKey).Also(
+			apis.ErrInvalidValue("5", ConcurrencyQueueTimeoutAnnotationKey)),
+	}, {
+		name: "queue depth not in soft mode",
+		annotation: map[string]string{
+			ConcurrencyModeAnnotationKey:       ConcurrencyModeReject,
+			ConcurrencyQueueDepthAnnotationKey: "50",
+		},
+		expectErr: &apis.FieldError{
+			Message: "only supported in the soft concurrency mode",
+			Paths:   []string{ConcurrencyQueueDepthAnnotationKey},
+		},
+	}, {
+		name:       "queue timeout without mode",
+		annotation: map[string]string{ConcurrencyQueueTimeoutAnnotationKey: "5s"},
+		expectErr: &apis.FieldError{
+			Message: "only supported in the soft concurrency mode",
+			Paths:   []string{ConcurrencyQueueTimeoutAnnotationKey},
+		},
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateConcurrencyModeAnnotations(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateOverflowPolicyAnnotation(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name:       "queue",
+		annotation: map[string]string{OverflowPolicyAnnotationKey: OverflowPolicyQueue},
+	}, {
+		name:       "reject",
+		annotation: map[string]string{OverflowPolicyAnnotationKey: OverflowPolicyReject},
+	}, {
+		name:       "invalid",
+		annotation: map[string]string{OverflowPolicyAnnotationKey: "drop"},
+		expectErr:  apis.ErrInvalidValue("drop", OverflowPolicyAnnotationKey),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateOverflowPolicyAnnotation(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateActivatorQueueAnnotations(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name: "valid",
+		annotation: map[string]string{
+			ActivatorQueueDepthAnnotationKey:   "100",
+			ActivatorQueueTimeoutAnnotationKey: "10s",
+		},
+	}, {
+		name: "zero",
+		annotation: map[string]string{
+			ActivatorQueueDepthAnnotationKey:   "0",
+			ActivatorQueueTimeoutAnnotationKey: "0s",
+		},
+	}, {
+		name:       "negative depth",
+		annotation: map[string]string{ActivatorQueueDepthAnnotationKey: "-1"},
+		expectErr:  apis.ErrInvalidValue("-1", ActivatorQueueDepthAnnotationKey),
+	}, {
+		name:       "invalid depth",
+		annotation: map[string]string{ActivatorQueueDepthAnnotationKey: "lots"},
+		expectErr:  apis.ErrInvalidValue("lots", ActivatorQueueDepthAnnotationKey),
+	}, {
+		name:       "negative timeout",
+		annotation: map[string]string{ActivatorQueueTimeoutAnnotationKey: "-1s"},
+		expectErr:  apis.ErrInvalidValue("-1s", ActivatorQueueTimeoutAnnotationKey),
+	}, {
+		name:       "invalid timeout",
+		annotation: map[string]string{ActivatorQueueTimeoutAnnotationKey: "10"},
+		expectErr:  apis.ErrInvalidValue("10", ActivatorQueueTimeoutAnnotationKey),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateActivatorQueueAnnotations(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateHostsAnnotation(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name:       "valid",
+		annotation: map[string]string{HostsAnnotationKey: "www.example.org, example.org"},
+	}, {
+		name:       "invalid host",
+		annotation: map[string]string{HostsAnnotationKey: "www.example.org,*.example.org"},
+		expectErr:  apis.ErrInvalidValue("*.example.org", HostsAnnotationKey),
+	}, {
+		name:       "empty host",
+		annotation: map[string]string{HostsAnnotationKey: "www.example.org,"},
+		expectErr:  apis.ErrInvalidValue("", HostsAnnotationKey),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateHostsAnnotation(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateTagVisibilityAnnotation(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name:       "valid",
+		annotation: map[string]string{TagVisibilityAnnotationKey: `{"canary": "cluster-local"}`},
+	}, {
+		name:       "not json",
+		annotation: map[string]string{TagVisibilityAnnotationKey: "cluster-local"},
+		expectErr:  apis.ErrInvalidValue("cluster-local", TagVisibilityAnnotationKey),
+	}, {
+		name:       "invalid tag",
+		annotation: map[string]string{TagVisibilityAnnotationKey: `{"Canary": "cluster-local"}`},
+		expectErr: apis.ErrInvalidKeyName("Canary", TagVisibilityAnnotationKey,
+			k8svalidation.IsDNS1035Label("Canary")...),
+	}, {
+		name:       "invalid visibility",
+		annotation: map[string]string{TagVisibilityAnnotationKey: `{"canary": "public"}`},
+		expectErr:  apis.ErrInvalidValue("public", TagVisibilityAnnotationKey+".canary"),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateTagVisibilityAnnotation(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateMirrorAnnotation(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name: "valid",
+		annotation: map[string]string{
+			MirrorAnnotationKey: `{"candidate": {"revisionName": "foo-00002", "percent": 10}}`,
+		},
+	}, {
+		name:       "not json",
+		annotation: map[string]string{MirrorAnnotationKey: "foo-00002"},
+		expectErr:  apis.ErrInvalidValue("foo-00002", MirrorAnnotationKey),
+	}, {
+		name: "invalid tag",
+		annotation: map[string]string{
+			MirrorAnnotationKey: `{"Candidate": {"revisionName": "foo-00002", "percent": 10}}`,
+		},
+		expectErr: apis.ErrInvalidKeyName("Candidate", MirrorAnnotationKey,
+			k8svalidation.IsDNS1035Label("Candidate")...),
+	}, {
+		name: "invalid revision name",
+		annotation: map[string]string{
+			MirrorAnnotationKey: `{"candidate": {"revisionName": "Foo_2", "percent": 10}}`,
+		},
+		expectErr: apis.ErrInvalidValue("Foo_2", MirrorAnnotationKey+".candidate.revisionName"),
+	}, {
+		name: "percent out of bounds",
+		annotation: map[string]string{
+			MirrorAnnotationKey: `{"candidate": {"revisionName": "foo-00002", "percent": 0}}`,
+		},
+		expectErr: apis.ErrOutOfBoundsValue(0, 1, 100, MirrorAnnotationKey+".candidate.percent"),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateMirrorAnnotation(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateHeaderRulesAnnotation(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name: "valid",
+		annotation: map[string]string{
+			HeaderRulesAnnotationKey: `{
+				"*": {"response": {"set": {"Strict-Transport-Security": "max-age=31536000"}}},
+				"candidate": {"request": {"add": {"X-Candidate": "true"}, "remove": ["X-Internal"]}}
+			}`,
+		},
+	}, {
+		name:       "not json",
+		annotation: map[string]string{HeaderRulesAnnotationKey: "X-Foo: bar"},
+		expectErr:  apis.ErrInvalidValue("X-Foo: bar", HeaderRulesAnnotationKey),
+	}, {
+		name:       "invalid tag",
+		annotation: map[string]string{HeaderRulesAnnotationKey: `{"Candidate": {}}`},
+		expectErr: apis.ErrInvalidKeyName("Candidate", HeaderRulesAnnotationKey,
+			k8svalidation.IsDNS1035Label("Candidate")...),
+	}, {
+		name: "invalid header name",
+		annotation: map[string]string{
+			HeaderRulesAnnotationKey: `{"*": {"request": {"set": {"X Foo": "bar"}}}}`,
+		},
+		expectErr: apis.ErrInvalidKeyName("X Foo", HeaderRulesAnnotationKey+".*.request.set"),
+	}, {
+		name: "invalid header value",
+		annotation: map[string]string{
+			HeaderRulesAnnotationKey: `{"*": {"response": {"add": {"X-Foo": "bar\r\nX-Bar: baz"}}}}`,
+		},
+		expectErr: apis.ErrInvalidValue("bar\r\nX-Bar: baz", HeaderRulesAnnotationKey+".*.response.add.X-Foo"),
+	}, {
+		name: "knative header",
+		annotation: map[string]string{
+			HeaderRulesAnnotationKey: `{"candidate": {"request": {"remove": ["X-Foo", "Knative-Serving-Revision"]}}}`,
+		},
+		expectErr: apis.ErrInvalidArrayValue("Knative-Serving-Revision", HeaderRulesAnnotationKey+".candidate.request.remove", 1),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateHeaderRulesAnnotation(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateMaxRequestBodySizeAnnotation(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name:       "quantity",
+		annotation: map[string]string{MaxRequestBodySizeAnnotationKey: "10Mi"},
+	}, {
+		name:       "bytes",
+		annotation: map[string]string{MaxRequestBodySizeAnnotationKey: "1024"},
+	}, {
+		name:       "zero",
+		annotation: map[string]string{MaxRequestBodySizeAnnotationKey: "0"},
+		expectErr:  apis.ErrInvalidValue("0", MaxRequestBodySizeAnnotationKey),
+	}, {
+		name:       "invalid",
+		annotation: map[string]string{MaxRequestBodySizeAnnotationKey: "ten megs"},
+		expectErr:  apis.ErrInvalidValue("ten megs", MaxRequestBodySizeAnnotationKey),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateMaxRequestBodySizeAnnotation(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateQueueSidecarDebugPortAnnotation(t *testing.T) {
+	containers := []corev1.Container{{
+		Name: "user",
+		Ports: []corev1.ContainerPort{{
+			ContainerPort: 8080,
+		}},
+	}}
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name:       "valid port",
+		annotation: map[string]string{QueueSidecarDebugPortAnnotationKey: "8090"},
+	}, {
+		name:       "not a number",
+		annotation: map[string]string{QueueSidecarDebugPortAnnotationKey: "debug"},
+		expectErr:  apis.ErrInvalidValue("debug", QueueSidecarDebugPortAnnotationKey),
+	}, {
+		name:       "out of range",
+		annotation: map[string]string{QueueSidecarDebugPortAnnotationKey: "65536"},
+		expectErr:  apis.ErrInvalidValue("65536", QueueSidecarDebugPortAnnotationKey),
+	}, {
+		name:       "reserved port",
+		annotation: map[string]string{QueueSidecarDebugPortAnnotationKey: "8022"},
+		expectErr:  apis.ErrInvalidValue("8022", QueueSidecarDebugPortAnnotationKey),
+	}, {
+		name:       "container port",
+		annotation: map[string]string{QueueSidecarDebugPortAnnotationKey: "8080"},
+		expectErr:  apis.ErrGeneric("port is used by container user", QueueSidecarDebugPortAnnotationKey),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateQueueSidecarDebugPortAnnotation(c.annotation, containers)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateQueueSidecarProbeAnnotations(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name: "all valid",
+		annotation: map[string]string{
+			QueueSidecarProbePeriodAnnotationKey:        "200ms",
+			QueueSidecarProbeTimeoutAnnotationKey:       "1s",
+			QueueSidecarProbeBackoffFactorAnnotationKey: "1.5",
+			QueueSidecarProbeMaxPeriodAnnotationKey:     "5s",
+		},
+	}, {
+		name:       "invalid period",
+		annotation: map[string]string{QueueSidecarProbePeriodAnnotationKey: "soon"},
+		expectErr:  apis.ErrInvalidValue("soon", QueueSidecarProbePeriodAnnotationKey),
+	}, {
+		name:       "non-positive timeout",
+		annotation: map[string]string{QueueSidecarProbeTimeoutAnnotationKey: "0s"},
+		expectErr:  apis.ErrInvalidValue("0s", QueueSidecarProbeTimeoutAnnotationKey),
+	}, {
+		name:       "backoff factor below 1",
+		annotation: map[string]string{QueueSidecarProbeBackoffFactorAnnotationKey: "0.5"},
+		expectErr:  apis.ErrInvalidValue("0.5", QueueSidecarProbeBackoffFactorAnnotationKey),
+	}, {
+		name: "max period less than period",
+		annotation: map[string]string{
+			QueueSidecarProbePeriodAnnotationKey:    "2s",
+			QueueSidecarProbeMaxPeriodAnnotationKey: "1s",
+		},
+		expectErr: &apis.FieldError{
+			Message: "may not be less than " + QueueSidecarProbePeriodAnnotationKey,
+			Paths:   []string{QueueSidecarProbeMaxPeriodAnnotationKey},
+		},
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateQueueSidecarProbeAnnotations(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateQueueSidecarBackendAnnotations(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		ports      []corev1.ContainerPort
+		expectErr  *apis.FieldError
+	}{{
+		name: "empty",
+	}, {
+		name:       "http",
+		annotation: map[string]string{QueueSidecarBackendSchemeAnnotationKey: "http"},
+	}, {
+		name: "https with ca secret",
+		annotation: map[string]string{
+			QueueSidecarBackendSchemeAnnotationKey:   "https",
+			QueueSidecarBackendCASecretAnnotationKey: "my-ca",
+		},
+	}, {
+		name:       "invalid scheme",
+		annotation: map[string]string{QueueSidecarBackendSchemeAnnotationKey: "HTTPS"},
+		expectErr:  apis.ErrInvalidValue("HTTPS", QueueSidecarBackendSchemeAnnotationKey),
+	}, {
+		name:       "https with unix socket",
+		annotation: map[string]string{QueueSidecarBackendSchemeAnnotationKey: "https"},
+		ports:      []corev1.ContainerPort{{Name: UnixSocketPortName}},
+		expectErr: apis.ErrGeneric("https is not supported with a unix socket serving port",
+			QueueSidecarBackendSchemeAnnotationKey),
+	}, {
+		name: "https with grpc probe",
+		annotation: map[string]string{
+			QueueSidecarBackendSchemeAnnotationKey: "https",
+			GRPCProbeAnnotationKey:                 "",
+		},
+		expectErr: apis.ErrGeneric("https is not supported with the gRPC probe",
+			QueueSidecarBackendSchemeAnnotationKey),
+	}, {
+		name: "invalid ca secret",
+		annotation: map[string]string{
+			QueueSidecarBackendSchemeAnnotationKey:   "https",
+			QueueSidecarBackendCASecretAnnotationKey: "My_CA",
+		},
+		expectErr: apis.ErrInvalidValue("My_CA", QueueSidecarBackendCASecretAnnotationKey),
+	}, {
+		name:       "ca secret without https",
+		annotation: map[string]string{QueueSidecarBackendCASecretAnnotationKey: "my-ca"},
+		expectErr: &apis.FieldError{
+			Message: "only supported with the https backend scheme",
+			Paths:   []string{QueueSidecarBackendCASecretAnnotationKey},
+		},
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateQueueSidecarBackendAnnotations(c.annotation, c.ports)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateSharedPersistentVolumeClaimsAnnotation(t *testing.T) {
+	claim := func(readOnly bool) corev1.PodSpec {
+		return corev1.PodSpec{
+			Containers: []corev1.Container{{
+				VolumeMounts: []corev1.VolumeMount{{
+					Name:      "cache",
+					MountPath: "/cache",
+				}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "cache",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: "cache",
+						ReadOnly:  readOnly,
+					},
+				},
+			}},
+		}
+	}
+	minScaleErr := &apis.FieldError{
+		Message: "cannot exceed 1 with writable persistentVolumeClaim volumes, " +
+			"unless serving.knative.dev/shared-persistent-volume-claims is true",
+		Paths: []string{autoscaling.MinScaleAnnotationKey},
+	}
+	maxScaleErr := &apis.FieldError{
+		Message: "must be set to 1 with writable persistentVolumeClaim volumes, " +
+			"unless serving.knative.dev/shared-persistent-volume-claims is true",
+		Paths: []string{autoscaling.MaxScaleAnnotationKey},
+	}
+
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		ps         corev1.PodSpec
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no claims",
+		annotation: map[string]string{autoscaling.MinScaleAnnotationKey: "3"},
+	}, {
+		name:       "single pod",
+		annotation: map[string]string{autoscaling.MaxScaleAnnotationKey: "1"},
+		ps:         claim(false),
+	}, {
+		name: "scaled",
+		annotation: map[string]string{
+			autoscaling.MinScaleAnnotationKey: "2",
+			autoscaling.MaxScaleAnnotationKey: "5",
+		},
+		ps:        claim(false),
+		expectErr: minScaleErr.Also(maxScaleErr),
+	}, {
+		name:      "unbounded",
+		ps:        claim(false),
+		expectErr: maxScaleErr,
+	}, {
+		name:       "unbounded shared false",
+		annotation: map[string]string{SharedPersistentVolumeClaimsAnnotationKey: "false"},
+		ps:         claim(false),
+		expectErr:  maxScaleErr,
+	}, {
+		name:       "explicitly unbounded",
+		annotation: map[string]string{autoscaling.MaxScaleAnnotationKey: "0"},
+		ps:         claim(false),
+		expectErr:  maxScaleErr,
+	}, {
+		name:       "unbounded shared",
+		annotation: map[string]string{SharedPersistentVolumeClaimsAnnotationKey: "true"},
+		ps:         claim(false),
+	}, {
+		name: "scaled shared",
+		annotation: map[string]string{
+			autoscaling.MaxScaleAnnotationKey:         "5",
+			SharedPersistentVolumeClaimsAnnotationKey: "true",
+		},
+		ps: claim(false),
+	}, {
+		name:       "scaled read-only claim",
+		annotation: map[string]string{autoscaling.MaxScaleAnnotationKey: "5"},
+		ps:         claim(true),
+	}, {
+		name:       "invalid",
+		annotation: map[string]string{SharedPersistentVolumeClaimsAnnotationKey: "yes"},
+		expectErr:  apis.ErrInvalidValue("yes", SharedPersistentVolumeClaimsAnnotationKey),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateSharedPersistentVolumeClaimsAnnotation(c.annotation, c.ps)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateTopologySpreadConstraintsAnnotation(t *testing.T) {
+	invalid := func(v, details string) *apis.FieldError {
+		fe := apis.ErrInvalidValue(v, TopologySpreadConstraintsAnnotationKey)
+		fe.Details = details
+		return fe
+	}
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name:       "empty list",
+		annotation: map[string]string{TopologySpreadConstraintsAnnotationKey: "[]"},
+	}, {
+		name: "valid",
+		annotation: map[string]string{TopologySpreadConstraintsAnnotationKey: `[{"maxSkew": 1, ` +
+			`"topologyKey": "topology.kubernetes.io/zone", "whenUnsatisfiable": "ScheduleAnyway"}]`},
+	}, {
+		name:       "not JSON",
+		annotation: map[string]string{TopologySpreadConstraintsAnnotationKey: "zone"},
+		expectErr:  invalid("zone", "invalid character 'z' looking for beginning of value"),
+	}, {
+		name: "no max skew",
+		annotation: map[string]string{TopologySpreadConstraintsAnnotationKey: `[{"topologyKey": "zone", ` +
+			`"whenUnsatisfiable": "ScheduleAnyway"}]`},
+		expectErr: invalid(`[{"topologyKey": "zone", "whenUnsatisfiable": "ScheduleAnyway"}]`,
+			"constraint 0: maxSkew must be at least 1, was 0"),
+	}, {
+		name:       "no topology key",
+		annotation: map[string]string{TopologySpreadConstraintsAnnotationKey: `[{"maxSkew": 1, "whenUnsatisfiable": "ScheduleAnyway"}]`},
+		expectErr: invalid(`[{"maxSkew": 1, "whenUnsatisfiable": "ScheduleAnyway"}]`,
+			`constraint 0: invalid topologyKey "": [name part must be non-empty name part must consist of alphanumeric `+
+				`characters, '-', '_' or '.', and must start and end with an alphanumeric character `+
+				`(e.g. 'MyName',  or 'my.name',  or '123-abc', regex used for validation is '([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]')]`),
+	}, {
+		name:       "invalid when unsatisfiable",
+		annotation: map[string]string{TopologySpreadConstraintsAnnotationKey: `[{"maxSkew": 1, "topologyKey": "zone"}]`},
+		expectErr: invalid(`[{"maxSkew": 1, "topologyKey": "zone"}]`,
+			`constraint 0: whenUnsatisfiable must be DoNotSchedule or ScheduleAnyway, was ""`),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateTopologySpreadConstraintsAnnotation(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidatePriorityClassNameAnnotation(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name:       "valid",
+		annotation: map[string]string{PriorityClassNameAnnotationKey: "serving-critical"},
+	}, {
+		name:       "empty",
+		annotation: map[string]string{PriorityClassNameAnnotationKey: ""},
+		expectErr:  apis.ErrInvalidValue("", PriorityClassNameAnnotationKey),
+	}, {
+		name:       "invalid",
+		annotation: map[string]string{PriorityClassNameAnnotationKey: "Serving_Critical"},
+		expectErr:  apis.ErrInvalidValue("Serving_Critical", PriorityClassNameAnnotationKey),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidatePriorityClassNameAnnotation(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateDeploymentStrategyAnnotation(t *testing.T) {
+	invalid := func(v, details string) *apis.FieldError {
+		fe := apis.ErrInvalidValue(v, DeploymentStrategyAnnotationKey)
+		fe.Details = details
+		return fe
+	}
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name:       "recreate",
+		annotation: map[string]string{DeploymentStrategyAnnotationKey: `{"type": "Recreate"}`},
+	}, {
+		name:       "rolling update",
+		annotation: map[string]string{DeploymentStrategyAnnotationKey: `{"type": "RollingUpdate"}`},
+	}, {
+		name: "rolling update without surge",
+		annotation: map[string]string{DeploymentStrategyAnnotationKey: `{"type": "RollingUpdate", ` +
+			`"rollingUpdate": {"maxSurge": 0, "maxUnavailable": "50%"}}`},
+	}, {
+		name:       "not JSON",
+		annotation: map[string]string{DeploymentStrategyAnnotationKey: "Recreate"},
+		expectErr:  invalid("Recreate", "invalid character 'R' looking for beginning of value"),
+	}, {
+		name:       "no type",
+		annotation: map[string]string{DeploymentStrategyAnnotationKey: `{}`},
+		expectErr:  invalid(`{}`, `type must be Recreate or RollingUpdate, was ""`),
+	}, {
+		name: "recreate with rolling update",
+		annotation: map[string]string{DeploymentStrategyAnnotationKey: `{"type": "Recreate", ` +
+			`"rollingUpdate": {"maxSurge": 1}}`},
+		expectErr: invalid(`{"type": "Recreate", "rollingUpdate": {"maxSurge": 1}}`,
+			"rollingUpdate is only allowed with type RollingUpdate"),
+	}, {
+		name: "negative surge",
+		annotation: map[string]string{DeploymentStrategyAnnotationKey: `{"type": "RollingUpdate", ` +
+			`"rollingUpdate": {"maxSurge": -1}}`},
+		expectErr: invalid(`{"type": "RollingUpdate", "rollingUpdate": {"maxSurge": -1}}`,
+			"invalid maxSurge: -1 cannot be negative"),
+	}, {
+		name: "unavailable above 100%",
+		annotation: map[string]string{DeploymentStrategyAnnotationKey: `{"type": "RollingUpdate", ` +
+			`"rollingUpdate": {"maxUnavailable": "150%"}}`},
+		expectErr: invalid(`{"type": "RollingUpdate", "rollingUpdate": {"maxUnavailable": "150%"}}`,
+			`invalid maxUnavailable: "150%" must be a percentage between 0% and 100%`),
+	}, {
+		name: "not a percentage",
+		annotation: map[string]string{DeploymentStrategyAnnotationKey: `{"type": "RollingUpdate", ` +
+			`"rollingUpdate": {"maxSurge": "one"}}`},
+		expectErr: invalid(`{"type": "RollingUpdate", "rollingUpdate": {"maxSurge": "one"}}`,
+			`invalid maxSurge: "one" must be a number or a percentage`),
+	}, {
+		name: "no progress",
+		annotation: map[string]string{DeploymentStrategyAnnotationKey: `{"type": "RollingUpdate", ` +
+			`"rollingUpdate": {"maxSurge": 0, "maxUnavailable": "0%"}}`},
+		expectErr: invalid(`{"type": "RollingUpdate", "rollingUpdate": {"maxSurge": 0, "maxUnavailable": "0%"}}`,
+			"maxSurge and maxUnavailable cannot both be 0"),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateDeploymentStrategyAnnotation(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateProgressDeadlineAnnotation(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name:       "valid",
+		annotation: map[string]string{ProgressDeadlineAnnotationKey: "10m"},
+	}, {
+		name:       "zero",
+		annotation: map[string]string{ProgressDeadlineAnnotationKey: "0s"},
+		expectErr:  apis.ErrInvalidValue("0s", ProgressDeadlineAnnotationKey),
+	}, {
+		name:       "not a duration",
+		annotation: map[string]string{ProgressDeadlineAnnotationKey: "600"},
+		expectErr:  apis.ErrInvalidValue("600", ProgressDeadlineAnnotationKey),
+	}, {
+		name:       "fractional seconds",
+		annotation: map[string]string{ProgressDeadlineAnnotationKey: "90.5s"},
+		expectErr: func() *apis.FieldError {
+			fe := apis.ErrInvalidValue("90.5s", ProgressDeadlineAnnotationKey)
+			fe.Details = "must be rounded to a whole second"
+			return fe
+		}(),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateProgressDeadlineAnnotation(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateDebugContainerAnnotations(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		disabled   bool
+		allowlist  sets.String
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name: "valid",
+		annotation: map[string]string{
+			DebugPodAnnotationKey:   "foo-00001-deployment-5d8f7c9b6-x2x4z",
+			DebugImageAnnotationKey: "busybox",
+		},
+	}, {
+		name: "disabled",
+		annotation: map[string]string{
+			DebugPodAnnotationKey:   "foo-00001-deployment-5d8f7c9b6-x2x4z",
+			DebugImageAnnotationKey: "busybox",
+		},
+		disabled: true,
+		expectErr: apis.ErrInvalidKeyName(DebugPodAnnotationKey, apis.CurrentField, "debug containers are not enabled").Also(
+			apis.ErrInvalidKeyName(DebugImageAnnotationKey, apis.CurrentField, "debug containers are not enabled")),
+	}, {
+		name:       "pod without image",
+		annotation: map[string]string{DebugPodAnnotationKey: "foo-00001-deployment-5d8f7c9b6-x2x4z"},
+		expectErr:  apis.ErrMissingField(DebugImageAnnotationKey),
+	}, {
+		name:       "image without pod",
+		annotation: map[string]string{DebugImageAnnotationKey: "busybox"},
+		expectErr:  apis.ErrMissingField(DebugPodAnnotationKey),
+	}, {
+		name: "invalid pod name",
+		annotation: map[string]string{
+			DebugPodAnnotationKey:   "Not_A_Pod",
+			DebugImageAnnotationKey: "busybox",
+		},
+		expectErr: apis.ErrInvalidValue("Not_A_Pod", DebugPodAnnotationKey),
+	}, {
+		name: "empty image",
+		annotation: map[string]string{
+			DebugPodAnnotationKey:   "foo-00001-deployment-5d8f7c9b6-x2x4z",
+			DebugImageAnnotationKey: " ",
+		},
+		expectErr: apis.ErrInvalidValue(" ", DebugImageAnnotationKey),
+	}, {
+		name: "invalid image",
+		annotation: map[string]string{
+			DebugPodAnnotationKey:   "foo-00001-deployment-5d8f7c9b6-x2x4z",
+			DebugImageAnnotationKey: "Not:An:Image",
+		},
+		expectErr: apis.ErrInvalidValue("Not:An:Image", DebugImageAnnotationKey),
+	}, {
+		name: "allowed image",
+		annotation: map[string]string{
+			DebugPodAnnotationKey:   "foo-00001-deployment-5d8f7c9b6-x2x4z",
+			DebugImageAnnotationKey: "registry.example.com/busybox",
+		},
+		allowlist: sets.NewString("registry.example.com/"),
+	}, {
+		name: "image not allowed",
+		annotation: map[string]string{
+			DebugPodAnnotationKey:   "foo-00001-deployment-5d8f7c9b6-x2x4z",
+			DebugImageAnnotationKey: "busybox",
+		},
+		allowlist: sets.NewString("registry.example.com/"),
+		expectErr: &apis.FieldError{
+			Message: `image repository "index.docker.io/library/busybox" is not allowed, must start with one of: registry.example.com/`,
+			Paths:   []string{DebugImageAnnotationKey},
+		},
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			flag := config.Enabled
+			if c.disabled {
+				flag = config.Disabled
+			}
+			ctx := config.ToContext(context.Background(), &config.Config{
+				Features: &config.Features{DebugContainers: flag, ImageRegistriesAllowlist: c.allowlist},
+			})
+			err := ValidateDebugContainerAnnotations(ctx, c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateRequestLogAnnotations(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name: "disabled with template",
+		annotation: map[string]string{
+			RequestLogAnnotationKey:         "false",
+			RequestLogTemplateAnnotationKey: "minimal",
+		},
+	}, {
+		name:       "invalid bool",
+		annotation: map[string]string{RequestLogAnnotationKey: "sometimes"},
+		expectErr:  apis.ErrInvalidValue("sometimes", RequestLogAnnotationKey),
+	}, {
+		name:       "invalid template name",
+		annotation: map[string]string{RequestLogTemplateAnnotationKey: "not a key"},
+		expectErr:  apis.ErrInvalidValue("not a key", RequestLogTemplateAnnotationKey),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateRequestLogAnnotations(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateResponseCompressionAnnotations(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name: "gzip with types",
+		annotation: map[string]string{
+			ResponseCompressionAnnotationKey:      "gzip",
+			ResponseCompressionTypesAnnotationKey: "application/json, text/*",
+		},
+	}, {
+		name:       "unsupported encoding",
+		annotation: map[string]string{ResponseCompressionAnnotationKey: "br"},
+		expectErr:  apis.ErrInvalidValue("br", ResponseCompressionAnnotationKey),
+	}, {
+		name: "invalid type",
+		annotation: map[string]string{
+			ResponseCompressionAnnotationKey:      "gzip",
+			ResponseCompressionTypesAnnotationKey: "application/json; charset=utf-8",
+		},
+		expectErr: apis.ErrInvalidValue("application/json; charset=utf-8", ResponseCompressionTypesAnnotationKey),
+	}, {
+		name:       "types without compression",
+		annotation: map[string]string{ResponseCompressionTypesAnnotationKey: "text/*"},
+		expectErr:  apis.ErrGeneric("requires "+ResponseCompressionAnnotationKey, ResponseCompressionTypesAnnotationKey),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateResponseCompressionAnnotations(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateEndToEndReadinessAnnotation(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name:       "enabled",
+		annotation: map[string]string{EndToEndReadinessAnnotationKey: "true"},
+	}, {
+		name:       "invalid",
+		annotation: map[string]string{EndToEndReadinessAnnotationKey: "false"},
+		expectErr:  apis.ErrInvalidValue("false", EndToEndReadinessAnnotationKey),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateEndToEndReadinessAnnotation(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateRollbackOnFailureAnnotation(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name:       "enabled",
+		annotation: map[string]string{RollbackOnFailureAnnotationKey: "true"},
+	}, {
+		name:       "invalid",
+		annotation: map[string]string{RollbackOnFailureAnnotationKey: "false"},
+		expectErr:  apis.ErrInvalidValue("false", RollbackOnFailureAnnotationKey),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateRollbackOnFailureAnnotation(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateLogSidecarAnnotation(t *testing.T) {
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+	}, {
+		name:       "enabled",
+		annotation: map[string]string{LogSidecarAnnotationKey: "true"},
+	}, {
+		name:       "invalid",
+		annotation: map[string]string{LogSidecarAnnotationKey: "yes"},
+		expectErr:  apis.ErrInvalidValue("yes", LogSidecarAnnotationKey),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateLogSidecarAnnotation(c.annotation)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateGRPCProbeAnnotation(t *testing.T) {
+	tcpProbe := &corev1.Probe{
+		Handler: corev1.Handler{
+			TCPSocket: &corev1.TCPSocketAction{},
+		},
+	}
+	cases := []struct {
+		name       string
+		annotation map[string]string
+		probe      *corev1.Probe
+		expectErr  *apis.FieldError
+	}{{
+		name:       "no annotation",
+		annotation: map[string]string{},
+		probe: &corev1.Probe{
+			Handler: corev1.Handler{
+				Exec: &corev1.ExecAction{Command: []string{"true"}},
+			},
+		},
+	}, {
+		name:       "server health",
+		annotation: map[string]string{GRPCProbeAnnotationKey: ""},
+		probe:      tcpProbe,
+	}, {
+		name:       "service health",
+		annotation: map[string]string{GRPCProbeAnnotationKey: "grpc.health.v1.Health"},
+		probe:      tcpProbe,
+	}, {
+		name:       "defaulted probe",
+		annotation: map[string]string{GRPCProbeAnnotationKey: "Ping"},
+	}, {
+		name:       "invalid service",
+		annotation: map[string]string{GRPCProbeAnnotationKey: "not a service"},
+		probe:      tcpProbe,
+		expectErr:  apis.ErrInvalidValue("not a service", GRPCProbeAnnotationKey),
+	}, {
+		name:       "http probe",
+		annotation: map[string]string{GRPCProbeAnnotationKey: ""},
+		probe: &corev1.Probe{
+			Handler: corev1.Handler{
+				HTTPGet: &corev1.HTTPGetAction{Path: "/"},
+			},
+		},
+		expectErr: apis.ErrGeneric("the gRPC probe requires a tcpSocket readiness probe", GRPCProbeAnnotationKey),
+	}}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateGRPCProbeAnnotation(c.annotation, c.probe)
+			if got, want := err.Error(), c.expectErr.Error(); got != want {
+				t.Errorf("\nGot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
 func TestValidateTimeoutSecond(t *testing.T) {
 	cases := []struct {
 		name      string
@@ -0,0 +1,42 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serving
+
+import "encoding/json"
+
+// MirrorTarget is the revision the activator mirrors a percentage of the
+// requests of a traffic tag to, as specified by MirrorAnnotationKey.
+type MirrorTarget struct {
+	// RevisionName is the name of the revision receiving the mirrored requests.
+	RevisionName string `json:"revisionName"`
+	// Percent is the percentage of the requests to mirror, in [1, 100].
+	Percent int `json:"percent"`
+}
+
+// ParseMirrorAnnotation returns the mirror targets keyed by the traffic tag,
+// as specified by MirrorAnnotationKey, or nil if the annotation is not set.
+func ParseMirrorAnnotation(annotations map[string]string) (map[string]MirrorTarget, error) {
+	v, ok := annotations[MirrorAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+	var ret map[string]MirrorTarget
+	if err := json.Unmarshal([]byte(v), &ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
@@ -96,6 +96,286 @@ const (
 	// It has to be in [0.1,100]
 	QueueSideCarResourcePercentageAnnotation = "queue.sidecar." + GroupName + "/resourcePercentage"
 
+	// The queue-proxy resource annotations override, per Revision, the
+	// resources from the config-deployment ConfigMap and the ones derived
+	// from QueueSideCarResourcePercentageAnnotation. The values are quantities.
+	QueueSidecarCPUResourceRequestAnnotationKey              = "queue.sidecar." + GroupName + "/cpu-resource-request"
+	QueueSidecarCPUResourceLimitAnnotationKey                = "queue.sidecar." + GroupName + "/cpu-resource-limit"
+	QueueSidecarMemoryResourceRequestAnnotationKey           = "queue.sidecar." + GroupName + "/memory-resource-request"
+	QueueSidecarMemoryResourceLimitAnnotationKey             = "queue.sidecar." + GroupName + "/memory-resource-limit"
+	QueueSidecarEphemeralStorageResourceRequestAnnotationKey = "queue.sidecar." + GroupName + "/ephemeral-storage-resource-request"
+	QueueSidecarEphemeralStorageResourceLimitAnnotationKey   = "queue.sidecar." + GroupName + "/ephemeral-storage-resource-limit"
+
+	// QueueSidecarDebugPortAnnotationKey is the annotation on the Revision
+	// opting into the queue-proxy debug endpoint, serving pprof and the
+	// proxy runtime state on the given port of the pod's localhost only.
+	QueueSidecarDebugPortAnnotationKey = "queue.sidecar." + GroupName + "/debug-port"
+
+	// The queue-proxy readiness probe annotations override, per Revision,
+	// the timing of the aggressive probing of the user container from the
+	// config-deployment ConfigMap. The period, the timeout and the max period
+	// are durations, the backoff factor is a number no less than 1.
+	QueueSidecarProbePeriodAnnotationKey        = "queue.sidecar." + GroupName + "/probe-period"
+	QueueSidecarProbeTimeoutAnnotationKey       = "queue.sidecar." + GroupName + "/probe-timeout"
+	QueueSidecarProbeBackoffFactorAnnotationKey = "queue.sidecar." + GroupName + "/probe-backoff-factor"
+	QueueSidecarProbeMaxPeriodAnnotationKey     = "queue.sidecar." + GroupName + "/probe-max-period"
+
+	// QueueSidecarBackendSchemeAnnotationKey is the annotation on the Revision
+	// declaring the scheme the user container serves, either BackendSchemeHTTP,
+	// the default, or BackendSchemeHTTPS, for queue-proxy to speak TLS to it.
+	QueueSidecarBackendSchemeAnnotationKey = "queue.sidecar." + GroupName + "/backend-scheme"
+
+	// QueueSidecarBackendCASecretAnnotationKey is the annotation on the Revision
+	// naming the Secret, in its namespace, whose ca.crt key holds the CA bundle
+	// queue-proxy verifies the certificate of the user container serving HTTPS
+	// against. The certificate isn't verified without it.
+	QueueSidecarBackendCASecretAnnotationKey = "queue.sidecar." + GroupName + "/backend-ca-secret"
+
+	// SharedPersistentVolumeClaimsAnnotationKey is the annotation on the
+	// Revision declaring, when "true", that its writable persistentVolumeClaim
+	// volumes support ReadWriteMany, so it can scale beyond a single pod.
+	// They are otherwise assumed ReadWriteOnce.
+	SharedPersistentVolumeClaimsAnnotationKey = GroupName + "/shared-persistent-volume-claims"
+
+	// TopologySpreadConstraintsAnnotationKey is the annotation on the Revision
+	// with the JSON list of the topology spread constraints of its pods,
+	// overriding the default ones of config-deployment, e.g. "[]" to pack
+	// them. See ParseTopologySpreadConstraints.
+	TopologySpreadConstraintsAnnotationKey = GroupName + "/topology-spread-constraints"
+
+	// PriorityClassNameAnnotationKey is the annotation on the Revision with
+	// the name of the PriorityClass of its pods, overriding the default one
+	// of config-deployment, when the kubernetes.podspec-priorityclassname
+	// feature is on.
+	PriorityClassNameAnnotationKey = GroupName + "/priority-class-name"
+
+	// DeploymentStrategyAnnotationKey is the annotation on the Revision with
+	// the JSON update strategy of its deployment, overriding the default one
+	// of config-deployment. See ParseDeploymentStrategy.
+	DeploymentStrategyAnnotationKey = GroupName + "/deployment-strategy"
+
+	// ProgressDeadlineAnnotationKey is the annotation on the Revision with the
+	// duration, in whole seconds, its deployment may take to make progress,
+	// e.g. pulling large images, overriding the default one of
+	// config-deployment. It also bounds the activation of the revision.
+	ProgressDeadlineAnnotationKey = GroupName + "/progress-deadline"
+
+	// DebugPodAnnotationKey is the annotation on the Revision naming one of
+	// its running pods the Revision reconciler attaches an ephemeral debug
+	// container to, running the image of DebugImageAnnotationKey.
+	DebugPodAnnotationKey = GroupName + "/debug-pod"
+
+	// DebugImageAnnotationKey is the annotation on the Revision with the
+	// image of the ephemeral debug container, which is allowed, resolved and
+	// verified like the images of the containers. See DebugPodAnnotationKey.
+	DebugImageAnnotationKey = GroupName + "/debug-image"
+
+	// SessionAffinityHeaderAnnotationKey is the annotation on the Revision specifying
+	// the name of the request header carrying the session key. Requests with the same
+	// session key are routed to the same pod by the activator, while possible.
+	// The affinity only holds while the activator is in the request path, and
+	// each activator maps the sessions to its own share of the pods.
+	SessionAffinityHeaderAnnotationKey = GroupName + "/session-affinity-header"
+
+	// SessionAffinityCookieAnnotationKey is the annotation on the Revision specifying
+	// the name of the cookie carrying the session key. It is mutually exclusive with
+	// SessionAffinityHeaderAnnotationKey.
+	SessionAffinityCookieAnnotationKey = GroupName + "/session-affinity-cookie"
+
+	// RequestHeadersSetAnnotationKey is the annotation on the Revision specifying
+	// the headers queue-proxy sets on the requests before passing them to the
+	// user container, as a JSON object mapping the header names to their values,
+	// e.g. `{"X-Env": "prod"}`. The existing values of the headers are replaced.
+	RequestHeadersSetAnnotationKey = GroupName + "/request-headers-set"
+
+	// RequestHeadersRemoveAnnotationKey is the annotation on the Revision specifying
+	// the comma separated list of headers queue-proxy strips from the requests
+	// before passing them to the user container.
+	RequestHeadersRemoveAnnotationKey = GroupName + "/request-headers-remove"
+
+	// ResponseHeadersSetAnnotationKey is the annotation on the Revision specifying
+	// the headers queue-proxy sets on the responses of the user container, in the
+	// same format as RequestHeadersSetAnnotationKey,
+	// e.g. `{"Strict-Transport-Security": "max-age=31536000"}`.
+	ResponseHeadersSetAnnotationKey = GroupName + "/response-headers-set"
+
+	// ResponseHeadersRemoveAnnotationKey is the annotation on the Revision specifying
+	// the comma separated list of headers queue-proxy strips from the responses
+	// of the user container.
+	ResponseHeadersRemoveAnnotationKey = GroupName + "/response-headers-remove"
+
+	// ConcurrencyStateEndpointAnnotationKey is the annotation on the Revision
+	// specifying the URL queue-proxy notifies when the pod transitions between
+	// having zero and non-zero requests in flight. `$HOST_IP` in the URL is
+	// replaced with the IP of the node the pod runs on.
+	ConcurrencyStateEndpointAnnotationKey = GroupName + "/concurrency-state-endpoint"
+
+	// PreStopPathAnnotationKey is the annotation on the Revision specifying
+	// the path on the user port queue-proxy sends a GET request to once the
+	// requests in flight are drained on termination, before the user
+	// container is stopped, e.g. `/shutdown`.
+	PreStopPathAnnotationKey = GroupName + "/prestop-path"
+
+	// RegistriesSkippingTagResolvingAnnotationKey is the annotation on the
+	// Revision specifying the comma separated list of registries whose image
+	// tags aren't resolved to digests, in addition to the registries of the
+	// config-deployment ConfigMap, e.g. for air-gapped registries without
+	// reliable HEAD support. AllRegistries skips the resolution of every tag.
+	RegistriesSkippingTagResolvingAnnotationKey = GroupName + "/registries-skipping-tag-resolving"
+	// AllRegistries matches the registries of all the images.
+	AllRegistries = "*"
+
+	// OverflowPolicyAnnotationKey is the annotation on the Revision specifying
+	// what the activator does with the requests exceeding the capacity of the
+	// revision: either OverflowPolicyQueue or OverflowPolicyReject.
+	OverflowPolicyAnnotationKey = GroupName + "/overflow-policy"
+	// OverflowPolicyQueue makes the activator queue the requests until there
+	// is capacity for them. This is the default.
+	OverflowPolicyQueue = "queue"
+	// OverflowPolicyReject makes the activator reject the requests right away
+	// with a 503, e.g. for the upstream load balancer to fail over to another
+	// region, rather than accumulating latency.
+	OverflowPolicyReject = "reject"
+
+	// ActivatorQueueDepthAnnotationKey is the annotation on the Revision
+	// specifying the maximum number of requests waiting in each activator for
+	// capacity of the revision, e.g. `100`, overriding the activator's
+	// default. The requests beyond it are rejected with a 429. Zero means
+	// no limit.
+	ActivatorQueueDepthAnnotationKey = GroupName + "/activator-queue-depth"
+
+	// ActivatorQueueTimeoutAnnotationKey is the annotation on the Revision
+	// specifying the maximum time a request waits in the activator for
+	// capacity of the revision, as a duration, e.g. `10s`, overriding the
+	// activator's default. The requests waiting longer are rejected with
+	// a 429. Zero means no limit.
+	ActivatorQueueTimeoutAnnotationKey = GroupName + "/activator-queue-timeout"
+
+	// ConcurrencyModeAnnotationKey is the annotation on the Revision specifying
+	// how queue-proxy enforces the container concurrency: ConcurrencyModeHard,
+	// ConcurrencyModeSoft or ConcurrencyModeReject.
+	ConcurrencyModeAnnotationKey = GroupName + "/concurrency-mode"
+	// ConcurrencyModeHard makes queue-proxy queue the requests exceeding the
+	// container concurrency, up to ten times the container concurrency,
+	// until there is capacity for them. This is the default.
+	ConcurrencyModeHard = "hard"
+	// ConcurrencyModeSoft makes queue-proxy queue the requests exceeding the
+	// container concurrency up to the depth and for up to the timeout given by
+	// ConcurrencyQueueDepthAnnotationKey and ConcurrencyQueueTimeoutAnnotationKey,
+	// and fail the rest with a 503.
+	ConcurrencyModeSoft = "soft"
+	// ConcurrencyModeReject makes queue-proxy reject the requests exceeding
+	// the container concurrency right away with a 429.
+	ConcurrencyModeReject = "reject"
+
+	// ConcurrencyQueueDepthAnnotationKey is the annotation on the Revision
+	// specifying the maximum number of requests queue-proxy queues in the
+	// ConcurrencyModeSoft, e.g. `50`.
+	ConcurrencyQueueDepthAnnotationKey = GroupName + "/concurrency-queue-depth"
+
+	// ConcurrencyQueueTimeoutAnnotationKey is the annotation on the Revision
+	// specifying the maximum time a request is queued by queue-proxy in the
+	// ConcurrencyModeSoft, as a duration, e.g. `5s`.
+	ConcurrencyQueueTimeoutAnnotationKey = GroupName + "/concurrency-queue-timeout"
+
+	// MaxRequestBodySizeAnnotationKey is the annotation on the Revision specifying
+	// the maximum size of the request bodies, as a quantity, e.g. `10Mi`.
+	// The larger requests are rejected with a 413 by the activator and
+	// queue-proxy, before they reach the user container.
+	MaxRequestBodySizeAnnotationKey = GroupName + "/max-request-body-size"
+
+	// GRPCProbeAnnotationKey is the annotation on the Revision making
+	// queue-proxy check the readiness of the user container with the standard
+	// gRPC health checking protocol, on the port of its TCP readiness probe.
+	// The value is the name of the gRPC service to check, or empty for the
+	// health of the server as a whole.
+	GRPCProbeAnnotationKey = GroupName + "/grpc-probe"
+
+	// ProbePassthroughAnnotationKey is the annotation on the Revision opting into
+	// keeping its TCP and HTTPS probes verbatim, when the probe-passthrough
+	// feature is Allowed. The only supported value is "enabled".
+	ProbePassthroughAnnotationKey = "features.knative.dev/probe-passthrough"
+
+	// MirrorAnnotationKey is the annotation on the Route specifying, per
+	// traffic tag, the revision the activator mirrors a percentage of the
+	// tag's requests to. The value is a JSON object mapping the tags to
+	// MirrorTarget, e.g. `{"candidate": {"revisionName": "foo-00002", "percent": 10}}`.
+	// The responses of the mirror are discarded. The KIngress has no notion of
+	// mirroring, so the mirroring is always emulated by the activator, and
+	// only happens while the activator is in the request path. It takes
+	// precedence over the Mirror of the traffic targets of the tag.
+	MirrorAnnotationKey = GroupName + "/mirror"
+
+	// HeaderRulesAnnotationKey is the annotation on the Route specifying, per
+	// traffic tag, the manipulations of the request and the response headers.
+	// The value is a JSON object mapping the tags, or HeaderRulesAllTargets, to
+	// HeaderRules, e.g. `{"*": {"response": {"set": {"Strict-Transport-Security": "max-age=31536000"}}}}`.
+	// The request headers to set are set by the Ingress, the other manipulations
+	// are applied by queue-proxy. The Knative-* headers may not be manipulated.
+	HeaderRulesAnnotationKey = GroupName + "/header-rules"
+
+	// TagVisibilityAnnotationKey is the annotation on the Route, or the Service,
+	// specifying the visibility of its traffic tags, like the visibility label
+	// of the same key does for the whole Route. The value is a JSON object
+	// mapping the tags to the label value, e.g. `{"canary": "cluster-local"}`.
+	// The cluster-local tags are then only reachable from within the cluster.
+	TagVisibilityAnnotationKey = "networking.knative.dev/visibility"
+
+	// HostsAnnotationKey is the annotation on the Route, or the Service,
+	// requesting custom external hosts for its default traffic target, in
+	// addition to the one derived from the domain template, as a comma
+	// separated list, e.g. `www.example.org,example.org`. The hosts must be
+	// allowed by the custom-domains-allowlist of config-domain. A host
+	// requested by several Routes is kept by the one claiming it first.
+	HostsAnnotationKey = GroupName + "/hosts"
+
+	// HostsClaimedAnnotationKey is the annotation on the Route status recording
+	// when the Route claimed each of the custom hosts it serves, as a JSON object
+	// mapping the hosts to the times, e.g. `{"www.example.org": "2021-01-02T15:04:05Z"}`.
+	// The earliest claim of a host takes precedence over the others.
+	HostsClaimedAnnotationKey = GroupName + "/hosts-claimed"
+
+	// RequestLogAnnotationKey is the annotation on the Revision overriding
+	// whether queue-proxy writes the request logs of the revision, either
+	// "true" or "false". By default logging.enable-request-log of
+	// config-observability applies.
+	RequestLogAnnotationKey = GroupName + "/request-log"
+
+	// RequestLogTemplateAnnotationKey is the annotation on the Revision selecting
+	// the named request log template of config-observability queue-proxy uses,
+	// i.e. the name in a `logging.request-log-template.<name>` entry.
+	RequestLogTemplateAnnotationKey = GroupName + "/request-log-template"
+
+	// LogSidecarAnnotationKey is the annotation on the Revision opting into
+	// the injection of the log sidecar defined by logging.sidecar-template of
+	// config-observability, which reads the files the containers write under
+	// /var/log. The only supported value is "true".
+	LogSidecarAnnotationKey = GroupName + "/log-sidecar"
+
+	// ResponseCompressionAnnotationKey is the annotation on the Revision enabling
+	// the compression of the responses by queue-proxy, for the user containers
+	// not compressing them on their own. The only supported encoding is "gzip".
+	ResponseCompressionAnnotationKey = GroupName + "/response-compression"
+
+	// ResponseCompressionTypesAnnotationKey is the annotation on the Revision
+	// specifying the comma separated list of the media types of the responses
+	// queue-proxy compresses, e.g. `application/json,text/*`. By default the
+	// JSON, JavaScript, XML and text responses are compressed.
+	ResponseCompressionTypesAnnotationKey = GroupName + "/response-compression-types"
+
+	// EndToEndReadinessAnnotationKey is the annotation on the Service opting
+	// into reporting Ready only once its URL actually responds, rather than
+	// once its Configuration and Route report Ready. The only supported
+	// value is "true".
+	EndToEndReadinessAnnotationKey = GroupName + "/end-to-end-readiness"
+
+	// RollbackOnFailureAnnotationKey is the annotation on the Route, or the
+	// Service, opting into routing the traffic of its configuration targets
+	// to the previous ready Revision of the Configuration while the latest
+	// ready one has failed, e.g. crashing after its rollout. The only
+	// supported value is "true".
+	RollbackOnFailureAnnotationKey = GroupName + "/rollback-on-failure"
+
 	// VisibilityLabelKeyObsolete is the obsolete VisibilityLabelKey.
 	// This will move over to VisibilityLabelKey in networking repo..
 	VisibilityLabelKeyObsolete = "serving.knative.dev/visibility"
@@ -104,6 +384,20 @@ const (
 	// that will result to the Route/KService getting a cluster local
 	// domain suffix.
 	VisibilityClusterLocal = "cluster-local"
+
+	// UnixSocketPortName is the name of the serving container port declaring
+	// that the user container listens on the unix domain socket at the path in
+	// the K_UNIX_SOCKET environment variable, rather than on the port.
+	UnixSocketPortName = "unix"
+
+	// BackendSchemeHTTP and BackendSchemeHTTPS are the values of
+	// QueueSidecarBackendSchemeAnnotationKey.
+	BackendSchemeHTTP  = "http"
+	BackendSchemeHTTPS = "https"
+
+	// BackendCASecretKey is the key of the CA bundle in the Secret named by
+	// QueueSidecarBackendCASecretAnnotationKey.
+	BackendCASecretKey = "ca.crt"
 )
 
 var (
@@ -0,0 +1,33 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serving
+
+import "encoding/json"
+
+// ParseTagVisibilityAnnotation returns the visibility keyed by the traffic tag,
+// as specified by TagVisibilityAnnotationKey, or nil if the annotation is not set.
+func ParseTagVisibilityAnnotation(annotations map[string]string) (map[string]string, error) {
+	v, ok := annotations[TagVisibilityAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+	var ret map[string]string
+	if err := json.Unmarshal([]byte(v), &ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
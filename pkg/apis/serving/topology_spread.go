@@ -0,0 +1,51 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serving
+
+import (
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ParseTopologySpreadConstraints parses and validates the JSON list of the
+// topology spread constraints of the revision pods, as in config-deployment
+// or TopologySpreadConstraintsAnnotationKey. The constraints without a
+// labelSelector spread the pods of their revision.
+func ParseTopologySpreadConstraints(s string) ([]corev1.TopologySpreadConstraint, error) {
+	var ret []corev1.TopologySpreadConstraint
+	if err := json.Unmarshal([]byte(s), &ret); err != nil {
+		return nil, err
+	}
+	for i, c := range ret {
+		if c.MaxSkew < 1 {
+			return nil, fmt.Errorf("constraint %d: maxSkew must be at least 1, was %d", i, c.MaxSkew)
+		}
+		if errs := validation.IsQualifiedName(c.TopologyKey); len(errs) > 0 {
+			return nil, fmt.Errorf("constraint %d: invalid topologyKey %q: %v", i, c.TopologyKey, errs)
+		}
+		switch c.WhenUnsatisfiable {
+		case corev1.DoNotSchedule, corev1.ScheduleAnyway:
+		default:
+			return nil, fmt.Errorf("constraint %d: whenUnsatisfiable must be %s or %s, was %q",
+				i, corev1.DoNotSchedule, corev1.ScheduleAnyway, c.WhenUnsatisfiable)
+		}
+	}
+	return ret, nil
+}
@@ -53,11 +53,6 @@ func (rs *RevisionSpec) SetDefaults(ctx context.Context) {
 		rs.TimeoutSeconds = ptr.Int64(cfg.Defaults.RevisionTimeoutSeconds)
 	}
 
-	// Default ContainerConcurrency based on our configmap.
-	if rs.ContainerConcurrency == nil {
-		rs.ContainerConcurrency = ptr.Int64(cfg.Defaults.ContainerConcurrency)
-	}
-
 	// Avoid clashes with user-supplied names when generating defaults.
 	userContainerNames := make(sets.String, len(rs.PodSpec.Containers))
 	for idx := range rs.PodSpec.Containers {
@@ -89,6 +84,31 @@ func (rs *RevisionSpec) SetDefaults(ctx context.Context) {
 
 		rs.applyDefault(ctx, &rs.PodSpec.Containers[idx], cfg)
 	}
+
+	// Default ContainerConcurrency based on our configmap, once the
+	// resources of the containers are defaulted.
+	if rs.ContainerConcurrency == nil {
+		if cc := cfg.Defaults.GPUContainerConcurrency; cc != nil && rs.usesGPU(cfg) {
+			rs.ContainerConcurrency = ptr.Int64(*cc)
+		} else {
+			rs.ContainerConcurrency = ptr.Int64(cfg.Defaults.ContainerConcurrency)
+		}
+	}
+}
+
+// usesGPU returns whether any container requests or limits one of the GPU
+// resources.
+func (rs *RevisionSpec) usesGPU(cfg *config.Config) bool {
+	for _, c := range rs.PodSpec.Containers {
+		for _, rl := range []corev1.ResourceList{c.Resources.Requests, c.Resources.Limits} {
+			for name := range rl {
+				if cfg.Defaults.IsGPUResource(name) {
+					return true
+				}
+			}
+		}
+	}
+	return false
 }
 
 func (rs *RevisionSpec) applyDefault(ctx context.Context, container *corev1.Container, cfg *config.Config) {
@@ -127,14 +147,27 @@ func (rs *RevisionSpec) applyDefault(ctx context.Context, container *corev1.Cont
 
 	// If there are multiple containers then default probes will be applied to the container where user specified PORT
 	// default probes will not be applied for non serving containers
-	if len(rs.PodSpec.Containers) == 1 || len(container.Ports) != 0 {
+	if container == rs.GetContainer() {
 		rs.applyProbes(container)
+
+		// The extended resources, like GPUs, are only defaulted for the serving
+		// container, which would otherwise share them with the sidecars.
+		for name, limit := range cfg.Defaults.RevisionExtendedResourceLimits {
+			_, hasRequest := container.Resources.Requests[name]
+			if _, ok := container.Resources.Limits[name]; !ok && !hasRequest {
+				container.Resources.Limits[name] = limit
+			}
+		}
 	}
 
 	if rs.PodSpec.EnableServiceLinks == nil && apis.IsInCreate(ctx) {
 		rs.PodSpec.EnableServiceLinks = cfg.Defaults.EnableServiceLinks
 	}
 
+	if rs.PodSpec.AutomountServiceAccountToken == nil && apis.IsInCreate(ctx) {
+		rs.PodSpec.AutomountServiceAccountToken = cfg.Defaults.AutomountServiceAccountToken
+	}
+
 	vms := container.VolumeMounts
 	for i := range vms {
 		vms[i].ReadOnly = true
@@ -22,9 +22,11 @@ import (
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/clock"
 	net "knative.dev/networking/pkg/apis/networking"
 	"knative.dev/pkg/kmeta"
+	"knative.dev/serving/pkg/apis/autoscaling"
 	"knative.dev/serving/pkg/apis/serving"
 )
 
@@ -106,15 +108,8 @@ func (e LastPinnedParseError) Error() string {
 // if there are multiple containers it returns the container which has Ports
 // as guaranteed by validation.
 func (rs *RevisionSpec) GetContainer() *corev1.Container {
-	switch {
-	case len(rs.Containers) == 1:
-		return &rs.Containers[0]
-	case len(rs.Containers) > 1:
-		for i := range rs.Containers {
-			if len(rs.Containers[i].Ports) != 0 {
-				return &rs.Containers[i]
-			}
-		}
+	if i := serving.ServingContainerIndex(rs.Containers); i >= 0 {
+		return &rs.Containers[i]
 	}
 	// Should be unreachable post-validation, but here to ease testing.
 	return &corev1.Container{}
@@ -167,6 +162,13 @@ func (r *Revision) IsReachable() bool {
 		RoutingState(r.Labels[serving.RoutingStateLabelKey]) == RoutingStateActive
 }
 
+// UsesUnixSocket returns true if the user container listens on a unix domain
+// socket, rather than on the serving port, see serving.UnixSocketPortName.
+func (r *Revision) UsesUnixSocket() bool {
+	ports := r.Spec.GetContainer().Ports
+	return len(ports) > 0 && ports[0].Name == serving.UnixSocketPortName
+}
+
 // GetProtocol returns the app level network protocol.
 func (r *Revision) GetProtocol() (p net.ProtocolType) {
 	p = net.ProtocolHTTP1
@@ -183,6 +185,115 @@ func (r *Revision) GetProtocol() (p net.ProtocolType) {
 	return
 }
 
+// SessionAffinityEnabled returns true if the revision requested the requests
+// of the same session to be routed to the same pod.
+func (r *Revision) SessionAffinityEnabled() bool {
+	return r.Annotations[serving.SessionAffinityHeaderAnnotationKey] != "" ||
+		r.Annotations[serving.SessionAffinityCookieAnnotationKey] != ""
+}
+
+// StandbyScale returns the number of warm standby pods requested
+// for the revision, or 0 if none were requested.
+func (r *Revision) StandbyScale() int {
+	// The value is validated in the webhook.
+	v, _ := strconv.Atoi(r.Annotations[autoscaling.StandbyScaleAnnotationKey])
+	return v
+}
+
+// RejectsOverflow returns true if the activator should reject the requests
+// exceeding the capacity of the revision, rather than queue them.
+func (r *Revision) RejectsOverflow() bool {
+	return r.Annotations[serving.OverflowPolicyAnnotationKey] == serving.OverflowPolicyReject
+}
+
+// ActivatorQueueDepth returns the maximum number of requests waiting in the
+// activator for capacity of the revision, and whether the revision
+// overrides the activator's default.
+func (r *Revision) ActivatorQueueDepth() (int, bool) {
+	v, ok := r.Annotations[serving.ActivatorQueueDepthAnnotationKey]
+	if !ok {
+		return 0, false
+	}
+	// The value is validated in the webhook.
+	depth, err := strconv.Atoi(v)
+	return depth, err == nil
+}
+
+// ActivatorQueueTimeout returns the maximum time a request waits in the
+// activator for capacity of the revision, and whether the revision
+// overrides the activator's default.
+func (r *Revision) ActivatorQueueTimeout() (time.Duration, bool) {
+	v, ok := r.Annotations[serving.ActivatorQueueTimeoutAnnotationKey]
+	if !ok {
+		return 0, false
+	}
+	// The value is validated in the webhook.
+	timeout, err := time.ParseDuration(v)
+	return timeout, err == nil
+}
+
+// MaxRequestBodySize returns the maximum size of the request bodies
+// in bytes, or 0 if it is not limited.
+func (r *Revision) MaxRequestBodySize() int64 {
+	// The value is validated in the webhook.
+	q, err := resource.ParseQuantity(r.Annotations[serving.MaxRequestBodySizeAnnotationKey])
+	if err != nil {
+		return 0
+	}
+	return q.Value()
+}
+
+// GRPCProbeService returns the name of the gRPC service queue-proxy checks
+// the health of, and whether the gRPC probe is enabled at all.
+func (r *Revision) GRPCProbeService() (string, bool) {
+	service, ok := r.Annotations[serving.GRPCProbeAnnotationKey]
+	return service, ok
+}
+
+// RequestLogEnabled returns whether queue-proxy writes the request logs
+// of the revision, and whether the revision overrides the cluster setting.
+func (r *Revision) RequestLogEnabled() (enabled, ok bool) {
+	v, ok := r.Annotations[serving.RequestLogAnnotationKey]
+	if !ok {
+		return false, false
+	}
+	// The value is validated in the webhook.
+	enabled, err := strconv.ParseBool(v)
+	return enabled, err == nil
+}
+
+// LogSidecarEnabled returns whether the revision opts into the injection of
+// the log sidecar.
+func (r *Revision) LogSidecarEnabled() bool {
+	return r.Annotations[serving.LogSidecarAnnotationKey] == "true"
+}
+
+// RequestLogTemplateName returns the name of the request log template
+// the revision selects, or empty for the default one.
+func (r *Revision) RequestLogTemplateName() string {
+	return r.Annotations[serving.RequestLogTemplateAnnotationKey]
+}
+
+// ProgressDeadline returns the progress deadline of the revision deployment,
+// and whether the revision overrides the one of config-deployment.
+func (r *Revision) ProgressDeadline() (time.Duration, bool) {
+	v, ok := r.Annotations[serving.ProgressDeadlineAnnotationKey]
+	if !ok {
+		return 0, false
+	}
+	// The value is validated in the webhook.
+	d, err := time.ParseDuration(v)
+	return d, err == nil
+}
+
+// DebugContainer returns the name of the pod of the revision to attach an
+// ephemeral debug container to, the image of the debug container, and
+// whether one is requested at all.
+func (r *Revision) DebugContainer() (pod, image string, ok bool) {
+	pod, image = r.Annotations[serving.DebugPodAnnotationKey], r.Annotations[serving.DebugImageAnnotationKey]
+	return pod, image, pod != "" && image != ""
+}
+
 // SetLastPinned sets the revision's last pinned annotations
 // to be the specified time.
 func (r *Revision) SetLastPinned(t time.Time) {
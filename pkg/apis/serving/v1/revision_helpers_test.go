@@ -265,6 +265,51 @@ func TestRevisionGetProtocol(t *testing.T) {
 	}
 }
 
+func TestRevisionUsesUnixSocket(t *testing.T) {
+	for name, want := range map[string]bool{
+		"":     false,
+		"h2c":  false,
+		"unix": true,
+	} {
+		r := &Revision{
+			Spec: RevisionSpec{
+				PodSpec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Ports: []corev1.ContainerPort{{Name: name}},
+					}},
+				},
+			},
+		}
+		if got := r.UsesUnixSocket(); got != want {
+			t.Errorf("UsesUnixSocket(%q) = %v, want: %v", name, got, want)
+		}
+	}
+	if (&Revision{}).UsesUnixSocket() {
+		t.Error("UsesUnixSocket() = true without ports, want: false")
+	}
+}
+
+func TestRevisionActivatorQueueLimits(t *testing.T) {
+	r := &Revision{}
+	if _, ok := r.ActivatorQueueDepth(); ok {
+		t.Error("ActivatorQueueDepth() is set without the annotation")
+	}
+	if _, ok := r.ActivatorQueueTimeout(); ok {
+		t.Error("ActivatorQueueTimeout() is set without the annotation")
+	}
+
+	r.Annotations = map[string]string{
+		serving.ActivatorQueueDepthAnnotationKey:   "100",
+		serving.ActivatorQueueTimeoutAnnotationKey: "10s",
+	}
+	if got, ok := r.ActivatorQueueDepth(); !ok || got != 100 {
+		t.Errorf("ActivatorQueueDepth() = %d, %v, want: 100, true", got, ok)
+	}
+	if got, ok := r.ActivatorQueueTimeout(); !ok || got != 10*time.Second {
+		t.Errorf("ActivatorQueueTimeout() = %v, %v, want: 10s, true", got, ok)
+	}
+}
+
 func TestGetContainer(t *testing.T) {
 	cases := []struct {
 		name   string
@@ -311,6 +356,33 @@ func TestGetContainer(t *testing.T) {
 				ContainerPort: 8888,
 			}},
 		},
+	}, {
+		name: "get serving container info when the sidecar has ports",
+		status: RevisionSpec{
+			PodSpec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name:  "sidecar",
+					Image: "envoy",
+					Ports: []corev1.ContainerPort{{
+						Name:          "admin",
+						ContainerPort: 9901,
+					}},
+				}, {
+					Name:  "servingContainer",
+					Image: "servingImage",
+					Ports: []corev1.ContainerPort{{
+						ContainerPort: 8888,
+					}},
+				}},
+			},
+		},
+		want: &corev1.Container{
+			Name:  "servingContainer",
+			Image: "servingImage",
+			Ports: []corev1.ContainerPort{{
+				ContainerPort: 8888,
+			}},
+		},
 	}, {
 		name: "get empty container when passed multiple containers without the container port",
 		status: RevisionSpec{
@@ -18,6 +18,7 @@ package v1
 
 import (
 	"fmt"
+	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -38,6 +39,11 @@ const (
 	// as unknown if the digests for the container images are being resolved.
 	ReasonResolvingDigests = "ResolvingDigests"
 
+	// ReasonImageSignatureInvalid defines the reason for marking container
+	// healthiness status as false if a container image isn't signed by one of
+	// the public keys of the digest resolution.
+	ReasonImageSignatureInvalid = "ImageSignatureInvalid"
+
 	// ReasonDeploying defines the reason for marking revision availability status as
 	// unknown if the revision is still deploying.
 	ReasonDeploying = "Deploying"
@@ -49,6 +55,20 @@ const (
 	// ReasonProgressDeadlineExceeded defines the reason for marking revision availability
 	// status as false if progress has exceeded the deadline.
 	ReasonProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+
+	// ReasonQuotaExceeded defines the reason for marking revision availability
+	// status as false if its pods exceed a ResourceQuota of the namespace.
+	ReasonQuotaExceeded = "QuotaExceeded"
+
+	// ReasonExceedsNodeAllocatable defines the reason for marking revision
+	// availability status as false if no node has enough allocatable resources
+	// for its pods.
+	ReasonExceedsNodeAllocatable = "ExceedsNodeAllocatable"
+
+	// ReasonBlockedByPolicy defines the reason for marking revision availability
+	// status as false if its pods are rejected by a policy of the cluster, like
+	// a PodSecurityPolicy, a LimitRange or an admission webhook.
+	ReasonBlockedByPolicy = "BlockedByPolicy"
 )
 
 var revisionCondSet = apis.NewLivingConditionSet(
@@ -238,8 +258,44 @@ func RevisionContainerExitingMessage(message string) string {
 	return fmt.Sprint("Container failed with: ", message)
 }
 
+// RevisionContainerTerminatedMessage constructs the status message of a
+// container of the revision terminating, with the message it terminated with.
+func RevisionContainerTerminatedMessage(container, reason string, exitCode, restarts int32, message string) string {
+	details := fmt.Sprintf("exit code %d, %d restarts", exitCode, restarts)
+	if reason != "" {
+		details = reason + ", " + details
+	}
+	return fmt.Sprintf("Container %q terminated (%s): %s", container, details, message)
+}
+
+// RevisionQuotaExceededMessage constructs the status message if the pods of
+// the revision exceed the given ResourceQuota for the given resources.
+func RevisionQuotaExceededMessage(quota string, resources []string, message string) string {
+	return fmt.Sprintf("The pods exceed the ResourceQuota %q for %s, raise the quota or lower the resources of the revision: %s",
+		quota, strings.Join(resources, ", "), message)
+}
+
+// RevisionExceedsNodeAllocatableMessage constructs the status message if no
+// node has enough of the given allocatable resources for the pods of the revision.
+func RevisionExceedsNodeAllocatableMessage(resources []string, message string) string {
+	return fmt.Sprintf("No node has enough allocatable %s for the pods, lower the requests of the revision or add larger nodes: %s",
+		strings.Join(resources, ", "), message)
+}
+
+// RevisionBlockedByPolicyMessage constructs the status message if the pods of
+// the revision are rejected by a policy of the cluster.
+func RevisionBlockedByPolicyMessage(message string) string {
+	return "The pods are rejected by a policy of the cluster, adjust the revision or the policy: " + message
+}
+
 // RevisionContainerMissingMessage constructs the status message if a given image
 // cannot be pulled correctly.
 func RevisionContainerMissingMessage(image string, message string) string {
 	return fmt.Sprintf("Unable to fetch image %q: %s", image, message)
 }
+
+// RevisionImageSignatureInvalidMessage constructs the status message if the
+// signature of a given image is missing or cannot be verified.
+func RevisionImageSignatureInvalidMessage(image string, message string) string {
+	return fmt.Sprintf("Unable to verify the signature of image %q: %s", image, message)
+}
@@ -87,6 +87,27 @@ type RevisionSpec struct {
 	// be provided.
 	// +optional
 	TimeoutSeconds *int64 `json:"timeoutSeconds,omitempty"`
+
+	// ResponseStartTimeoutSeconds holds the max duration the instance is
+	// allowed for starting to respond to a request, i.e. writing the first
+	// byte of the response. It must not exceed TimeoutSeconds, which is used
+	// if unspecified.
+	// +optional
+	ResponseStartTimeoutSeconds *int64 `json:"responseStartTimeoutSeconds,omitempty"`
+
+	// IdleTimeoutSeconds holds the max duration the instance is allowed to
+	// not write to a response it started, e.g. between the events of
+	// a stream, before the response is terminated. If unspecified or zero,
+	// the responses may be idle until TimeoutSeconds, which bounds them as
+	// a whole however much they are written to.
+	// +optional
+	IdleTimeoutSeconds *int64 `json:"idleTimeoutSeconds,omitempty"`
+
+	// DrainTimeoutSeconds holds the max duration the instance is allowed,
+	// once asked to terminate, to finish the requests in flight before
+	// the user container is stopped. If unspecified, TimeoutSeconds is used.
+	// +optional
+	DrainTimeoutSeconds *int64 `json:"drainTimeoutSeconds,omitempty"`
 }
 
 const (
@@ -153,12 +174,48 @@ type RevisionStatus struct {
 	// ref: http://bit.ly/image-digests
 	// +optional
 	ContainerStatuses []ContainerStatus `json:"containerStatuses,omitempty"`
+
+	// InitContainerStatuses is a slice of images present in
+	// .Spec.InitContainers[*].Image to their respective digests and their
+	// container name, resolved like the ContainerStatuses.
+	// +optional
+	InitContainerStatuses []ContainerStatus `json:"initContainerStatuses,omitempty"`
 }
 
 // ContainerStatus holds the information of container name and image digest value
 type ContainerStatus struct {
 	Name        string `json:"name,omitempty"`
 	ImageDigest string `json:"imageDigest,omitempty"`
+
+	// TagResolutionSkipped is true when the image is used as is, rather than
+	// resolved to a digest, as its registry skips the tag resolution or it is
+	// never pulled.
+	// +optional
+	TagResolutionSkipped bool `json:"tagResolutionSkipped,omitempty"`
+
+	// ImageSize is the total size in bytes of the config and the layers of the
+	// resolved image, as per its manifest.
+	// Only reported when the image-metadata feature is enabled.
+	// +optional
+	ImageSize int64 `json:"imageSize,omitempty"`
+
+	// ImageCreated is the time the resolved image was created, as per its config.
+	// Only reported when the image-metadata feature is enabled.
+	// +optional
+	ImageCreated *metav1.Time `json:"imageCreated,omitempty"`
+
+	// RecommendedRequests are the cpu and memory requests recommended for
+	// the container, from the peak usage observed while the revision serves,
+	// to right-size its resources in the next Configuration update.
+	// Only reported when the resource-recommendations feature is enabled.
+	// +optional
+	RecommendedRequests corev1.ResourceList `json:"recommendedRequests,omitempty"`
+
+	// LastTerminationReason is the reason, e.g. OOMKilled or Error, of the
+	// latest termination of the container across the pods of the revision,
+	// as of the last time the revision had no available pods.
+	// +optional
+	LastTerminationReason string `json:"lastTerminationReason,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -32,6 +32,7 @@ func (r *Revision) Validate(ctx context.Context) *apis.FieldError {
 	errs := serving.ValidateObjectMetadata(ctx, r.GetObjectMeta()).Also(
 		r.ValidateLabels().ViaField("labels")).ViaField("metadata")
 	errs = errs.Also(r.Status.Validate(apis.WithinStatus(ctx)).ViaField("status"))
+	errs = errs.Also(serving.ValidateDebugContainerAnnotations(ctx, r.Annotations).ViaField("metadata.annotations"))
 
 	if apis.IsInUpdate(ctx) {
 		original := apis.GetBaseline(ctx).(*Revision)
@@ -65,6 +66,32 @@ func (rts *RevisionTemplateSpec) Validate(ctx context.Context) *apis.FieldError
 	// it follows the requirements on the name.
 	errs = errs.Also(serving.ValidateRevisionName(ctx, rts.Name, rts.GenerateName))
 	errs = errs.Also(serving.ValidateQueueSidecarAnnotation(rts.Annotations).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateSessionAffinityAnnotations(rts.Annotations).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateHeaderAnnotations(rts.Annotations).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateConcurrencyStateEndpointAnnotation(rts.Annotations).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidatePreStopPathAnnotation(rts.Annotations,
+		rts.Spec.GetContainer().Lifecycle).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateOverflowPolicyAnnotation(rts.Annotations).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateActivatorQueueAnnotations(rts.Annotations).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateRegistriesSkippingTagResolvingAnnotation(ctx, rts.Annotations).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateConcurrencyModeAnnotations(rts.Annotations).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateMaxRequestBodySizeAnnotation(rts.Annotations).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateGRPCProbeAnnotation(rts.Annotations,
+		rts.Spec.GetContainer().ReadinessProbe).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateRequestLogAnnotations(rts.Annotations).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateLogSidecarAnnotation(rts.Annotations).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateResponseCompressionAnnotations(rts.Annotations).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateQueueSidecarProbeAnnotations(rts.Annotations).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateQueueSidecarDebugPortAnnotation(rts.Annotations,
+		rts.Spec.Containers).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateQueueSidecarBackendAnnotations(rts.Annotations,
+		rts.Spec.GetContainer().Ports).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateSharedPersistentVolumeClaimsAnnotation(rts.Annotations,
+		rts.Spec.PodSpec).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateTopologySpreadConstraintsAnnotation(rts.Annotations).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidatePriorityClassNameAnnotation(rts.Annotations).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateDeploymentStrategyAnnotation(rts.Annotations).ViaField("metadata.annotations"))
+	errs = errs.Also(serving.ValidateProgressDeadlineAnnotation(rts.Annotations).ViaField("metadata.annotations"))
 	return errs
 }
 
@@ -106,6 +133,22 @@ func (rs *RevisionSpec) Validate(ctx context.Context) *apis.FieldError {
 		errs = errs.Also(serving.ValidateTimeoutSeconds(ctx, *rs.TimeoutSeconds))
 	}
 
+	if rs.ResponseStartTimeoutSeconds != nil {
+		errs = errs.Also(serving.ValidateResponseStartTimeoutSeconds(ctx, *rs.ResponseStartTimeoutSeconds, rs.TimeoutSeconds))
+	}
+
+	if rs.IdleTimeoutSeconds != nil {
+		errs = errs.Also(serving.ValidateIdleTimeoutSeconds(ctx, *rs.IdleTimeoutSeconds))
+	}
+
+	if rs.DrainTimeoutSeconds != nil {
+		errs = errs.Also(serving.ValidateDrainTimeoutSeconds(ctx, *rs.DrainTimeoutSeconds))
+	}
+
+	if rs.TerminationGracePeriodSeconds != nil {
+		errs = errs.Also(serving.ValidateTerminationGracePeriodSeconds(ctx, *rs.TerminationGracePeriodSeconds))
+	}
+
 	if rs.ContainerConcurrency != nil {
 		errs = errs.Also(serving.ValidateContainerConcurrency(ctx, rs.ContainerConcurrency).ViaField("containerConcurrency"))
 	}
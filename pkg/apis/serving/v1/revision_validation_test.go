@@ -464,6 +464,120 @@ func TestRevisionSpecValidation(t *testing.T) {
 		want: apis.ErrOutOfBoundsValue(
 			-30, 0, config.DefaultMaxRevisionTimeoutSeconds,
 			"timeoutSeconds"),
+	}, {
+		name: "valid response start, idle and drain timeouts",
+		rs: &RevisionSpec{
+			PodSpec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Image: "helloworld",
+				}},
+			},
+			TimeoutSeconds:              ptr.Int64(300),
+			ResponseStartTimeoutSeconds: ptr.Int64(30),
+			IdleTimeoutSeconds:          ptr.Int64(600),
+			DrainTimeoutSeconds:         ptr.Int64(450),
+		},
+		want: nil,
+	}, {
+		name: "response start timeout exceeds timeout",
+		rs: &RevisionSpec{
+			PodSpec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Image: "helloworld",
+				}},
+			},
+			TimeoutSeconds:              ptr.Int64(100),
+			ResponseStartTimeoutSeconds: ptr.Int64(101),
+		},
+		want: apis.ErrOutOfBoundsValue(101, 0, 100, "responseStartTimeoutSeconds"),
+	}, {
+		name: "response start timeout exceeds max timeout",
+		rs: &RevisionSpec{
+			PodSpec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Image: "helloworld",
+				}},
+			},
+			ResponseStartTimeoutSeconds: ptr.Int64(6000),
+		},
+		want: apis.ErrOutOfBoundsValue(
+			6000, 0, config.DefaultMaxRevisionTimeoutSeconds,
+			"responseStartTimeoutSeconds"),
+	}, {
+		name: "negative idle timeout",
+		rs: &RevisionSpec{
+			PodSpec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Image: "helloworld",
+				}},
+			},
+			IdleTimeoutSeconds: ptr.Int64(-1),
+		},
+		want: apis.ErrOutOfBoundsValue(
+			-1, 0, config.DefaultMaxRevisionTimeoutSeconds,
+			"idleTimeoutSeconds"),
+	}, {
+		name: "drain timeout exceeds max timeout",
+		rs: &RevisionSpec{
+			PodSpec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Image: "helloworld",
+				}},
+			},
+			DrainTimeoutSeconds: ptr.Int64(config.DefaultMaxRevisionTimeoutSeconds + 1),
+		},
+		want: apis.ErrOutOfBoundsValue(
+			config.DefaultMaxRevisionTimeoutSeconds+1, 0, config.DefaultMaxRevisionTimeoutSeconds,
+			"drainTimeoutSeconds"),
+	}, {
+		name: "valid termination grace period",
+		rs: &RevisionSpec{
+			PodSpec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Image: "helloworld",
+				}},
+				TerminationGracePeriodSeconds: ptr.Int64(config.DefaultMaxRevisionTimeoutSeconds),
+			},
+		},
+		want: nil,
+	}, {
+		name: "termination grace period exceeds max",
+		rs: &RevisionSpec{
+			PodSpec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Image: "helloworld",
+				}},
+				TerminationGracePeriodSeconds: ptr.Int64(config.DefaultMaxRevisionTimeoutSeconds + 1),
+			},
+		},
+		want: apis.ErrOutOfBoundsValue(
+			config.DefaultMaxRevisionTimeoutSeconds+1, 0, config.DefaultMaxRevisionTimeoutSeconds,
+			"terminationGracePeriodSeconds"),
+	}, {
+		name: "termination grace period within operator max",
+		rs: &RevisionSpec{
+			PodSpec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Image: "helloworld",
+				}},
+				TerminationGracePeriodSeconds: ptr.Int64(3601),
+			},
+		},
+		wc: func(ctx context.Context) context.Context {
+			s := config.NewStore(logtesting.TestLogger(t))
+			s.OnConfigChanged(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: autoscalerconfig.ConfigName}})
+			s.OnConfigChanged(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: config.FeaturesConfigName}})
+			s.OnConfigChanged(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: config.DefaultsConfigName,
+				},
+				Data: map[string]string{
+					"max-termination-grace-period-seconds": "3600",
+				},
+			})
+			return s.ToContext(ctx)
+		},
+		want: apis.ErrOutOfBoundsValue(3601, 0, 3600, "terminationGracePeriodSeconds"),
 	}}
 
 	for _, test := range tests {
@@ -24,6 +24,7 @@ import (
 
 	"knative.dev/networking/pkg/apis/networking/v1alpha1"
 	"knative.dev/pkg/apis"
+	"knative.dev/serving/pkg/apis/serving"
 )
 
 var routeCondSet = apis.NewLivingConditionSet(
@@ -58,6 +59,13 @@ func (r *Route) IsFailed() bool {
 		rs.GetCondition(RouteConditionReady).IsFalse()
 }
 
+// RollbackOnFailure returns true if the traffic of the configuration targets
+// of the route goes to the previous ready Revision of the Configuration
+// while its latest ready one has failed.
+func (r *Route) RollbackOnFailure() bool {
+	return r.Annotations[serving.RollbackOnFailureAnnotationKey] == "true"
+}
+
 // InitializeConditions sets the initial values to the conditions.
 func (rs *RouteStatus) InitializeConditions() {
 	routeCondSet.Manage(rs).InitializeConditions()
@@ -70,6 +78,20 @@ func (rs *RouteStatus) MarkServiceNotOwned(name string) {
 		fmt.Sprintf("There is an existing placeholder Service %q that we do not own.", name))
 }
 
+// MarkCustomHostNotAllowed changes the IngressReady condition to be false to
+// reflect that the custom host is not allowed by config-domain.
+func (rs *RouteStatus) MarkCustomHostNotAllowed(host string) {
+	routeCondSet.Manage(rs).MarkFalse(RouteConditionIngressReady, "CustomHostNotAllowed",
+		"The custom host %q is not allowed by the custom-domains-allowlist of config-domain.", host)
+}
+
+// MarkCustomHostClaimed changes the IngressReady condition to be false to
+// reflect that the custom host is already claimed by another Route.
+func (rs *RouteStatus) MarkCustomHostClaimed(host, route string) {
+	routeCondSet.Manage(rs).MarkFalse(RouteConditionIngressReady, "CustomHostClaimed",
+		"The custom host %q is already claimed by the Route %q.", host, route)
+}
+
 // MarkIngressNotConfigured changes the IngressReady condition to be unknown to reflect
 // that the Ingress does not yet have a Status
 func (rs *RouteStatus) MarkIngressNotConfigured() {
@@ -128,6 +150,14 @@ func (rs *RouteStatus) MarkMissingTrafficTarget(kind, name string) {
 		"%s %q referenced in traffic not found.", kind, name)
 }
 
+// MarkMissingPort marks the RouteConditionAllTrafficAssigned condition to
+// indicate the Revision does not declare the port referenced in traffic.
+func (rs *RouteStatus) MarkMissingPort(revision, port string) {
+	routeCondSet.Manage(rs).MarkFalse(RouteConditionAllTrafficAssigned,
+		"PortMissing",
+		"Revision %q does not declare the port %q referenced in traffic.", revision, port)
+}
+
 // MarkCertificateProvisionFailed marks the
 // RouteConditionCertificateProvisioned condition to indicate that the
 // Certificate provisioning failed.
@@ -172,6 +202,24 @@ const (
 	TLSNotEnabledForClusterLocalMessage = "TLS is not enabled for cluster-local"
 )
 
+// MarkRolloutRolledBack sets RouteConditionRolloutRolledBack to true, with
+// the message describing the rolled back configuration targets.
+func (rs *RouteStatus) MarkRolloutRolledBack(msg string) {
+	routeCondSet.Manage(rs).SetCondition(apis.Condition{
+		Type:     RouteConditionRolloutRolledBack,
+		Status:   corev1.ConditionTrue,
+		Severity: apis.ConditionSeverityWarning,
+		Reason:   "LatestReadyRevisionFailed",
+		Message:  msg,
+	})
+}
+
+// ClearRolloutRolledBack removes RouteConditionRolloutRolledBack, once no
+// configuration target is rolled back anymore.
+func (rs *RouteStatus) ClearRolloutRolledBack() {
+	routeCondSet.Manage(rs).ClearCondition(RouteConditionRolloutRolledBack)
+}
+
 // MarkTLSNotEnabled sets RouteConditionCertificateProvisioned to true when
 // certificate config such as autoTLS is not enabled or private cluster-local service.
 func (rs *RouteStatus) MarkTLSNotEnabled(msg string) {
@@ -26,6 +26,7 @@ import (
 	"knative.dev/pkg/apis/duck"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
 	apistest "knative.dev/pkg/apis/testing"
+	"knative.dev/serving/pkg/apis/serving"
 )
 
 func TestRouteDuckTypes(t *testing.T) {
@@ -479,3 +480,50 @@ func TestIngressNotConfigured(t *testing.T) {
 
 	apistest.CheckConditionOngoing(r, RouteConditionIngressReady, t)
 }
+
+func TestRouteRolloutRolledBack(t *testing.T) {
+	r := &RouteStatus{}
+	r.InitializeConditions()
+	r.MarkTrafficAssigned()
+	r.MarkRolloutRolledBack("rolled back")
+
+	apistest.CheckConditionSucceeded(r, RouteConditionRolloutRolledBack, t)
+	apistest.CheckConditionSucceeded(r, RouteConditionAllTrafficAssigned, t)
+	if c := r.GetCondition(RouteConditionRolloutRolledBack); c.Severity != apis.ConditionSeverityWarning {
+		t.Errorf("Severity = %q, want: %q", c.Severity, apis.ConditionSeverityWarning)
+	}
+
+	r.ClearRolloutRolledBack()
+	if c := r.GetCondition(RouteConditionRolloutRolledBack); c != nil {
+		t.Errorf("RolloutRolledBack condition = %v, want: nil", c)
+	}
+}
+
+func TestRouteRollbackOnFailure(t *testing.T) {
+	r := &Route{}
+	if r.RollbackOnFailure() {
+		t.Error("RollbackOnFailure() = true without the annotation")
+	}
+	r.Annotations = map[string]string{serving.RollbackOnFailureAnnotationKey: "true"}
+	if !r.RollbackOnFailure() {
+		t.Error("RollbackOnFailure() = false with the annotation")
+	}
+}
+
+func TestCustomHostNotAllowed(t *testing.T) {
+	r := &RouteStatus{}
+	r.InitializeConditions()
+	r.MarkCustomHostNotAllowed("www.example.org")
+
+	apistest.CheckConditionFailed(r, RouteConditionIngressReady, t)
+	apistest.CheckConditionFailed(r, RouteConditionReady, t)
+}
+
+func TestCustomHostClaimed(t *testing.T) {
+	r := &RouteStatus{}
+	r.InitializeConditions()
+	r.MarkCustomHostClaimed("www.example.org", "other/owner")
+
+	apistest.CheckConditionFailed(r, RouteConditionIngressReady, t)
+	apistest.CheckConditionFailed(r, RouteConditionReady, t)
+}
@@ -103,6 +103,30 @@ type TrafficTarget struct {
 	// +optional
 	Percent *int64 `json:"percent,omitempty"`
 
+	// Port is optionally used to send the traffic of the dedicated url of this
+	// tagged target to the named port of the Revision's container, rather than
+	// to its serving port.
+	// +optional
+	Port string `json:"port,omitempty"`
+
+	// Headers optionally routes the requests carrying all of these headers,
+	// with exactly these values, to this target, regardless of the
+	// percentage split of the other requests.
+	// +optional
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// PathPrefix optionally routes the requests whose path starts with this
+	// prefix to this target, regardless of the percentage split of the other
+	// requests. The longest matching prefix wins. The prefix is matched
+	// literally, on the path segment boundaries.
+	// +optional
+	PathPrefix string `json:"pathPrefix,omitempty"`
+
+	// Mirror optionally sends a copy of a percentage of the requests routed
+	// to this target to another revision, discarding its responses.
+	// +optional
+	Mirror *TrafficMirror `json:"mirror,omitempty"`
+
 	// URL displays the URL for accessing named traffic targets. URL is displayed in
 	// status, and is disallowed on spec. URL must contain a scheme (e.g. http://) and
 	// a hostname, but may not contain anything else (e.g. basic auth, url path, etc.)
@@ -110,6 +134,19 @@ type TrafficTarget struct {
 	URL *apis.URL `json:"url,omitempty"`
 }
 
+// TrafficMirror is the revision receiving a copy of a percentage of the
+// requests routed to a TrafficTarget. The KIngress has no notion of
+// mirroring, so it is emulated by the activator, and only happens while
+// the activator is in the request path.
+type TrafficMirror struct {
+	// RevisionName of the revision receiving the copies of the requests.
+	RevisionName string `json:"revisionName"`
+
+	// Percent of the requests routed to the target to mirror, between 1
+	// and 100.
+	Percent int64 `json:"percent"`
+}
+
 // RouteSpec holds the desired state of the Route (from the client).
 type RouteSpec struct {
 	// Traffic specifies how to distribute traffic over a collection of
@@ -135,6 +172,12 @@ const (
 	// RouteConditionCertificateProvisioned is set to False when the
 	// Knative Certificates fail to be provisioned for the Route.
 	RouteConditionCertificateProvisioned apis.ConditionType = "CertificateProvisioned"
+
+	// RouteConditionRolloutRolledBack is set to True when the traffic of a
+	// configuration target is routed to the previous ready Revision of the
+	// Configuration, as its latest ready one failed. It does not affect the
+	// readiness of the Route.
+	RouteConditionRolloutRolledBack apis.ConditionType = "RolloutRolledBack"
 )
 
 // IsRouteCondition returns true if the ConditionType is a route condition type
@@ -144,7 +187,8 @@ func IsRouteCondition(t apis.ConditionType) bool {
 		RouteConditionReady,
 		RouteConditionAllTrafficAssigned,
 		RouteConditionIngressReady,
-		RouteConditionCertificateProvisioned:
+		RouteConditionCertificateProvisioned,
+		RouteConditionRolloutRolledBack:
 		return true
 	}
 	return false
@@ -19,18 +19,25 @@ package v1
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/util/validation"
 	network "knative.dev/networking/pkg"
 	"knative.dev/pkg/apis"
+	"knative.dev/serving/pkg/apis/config"
 	"knative.dev/serving/pkg/apis/serving"
 )
 
 // Validate makes sure that Route is properly configured.
 func (r *Route) Validate(ctx context.Context) *apis.FieldError {
 	errs := serving.ValidateObjectMetadata(ctx, r.GetObjectMeta()).Also(
-		r.validateLabels().ViaField("labels")).ViaField("metadata")
+		r.validateLabels().ViaField("labels")).Also(
+		serving.ValidateMirrorAnnotation(r.GetAnnotations()).ViaField("annotations")).Also(
+		serving.ValidateHeaderRulesAnnotation(r.GetAnnotations()).ViaField("annotations")).Also(
+		serving.ValidateTagVisibilityAnnotation(r.GetAnnotations()).ViaField("annotations")).Also(
+		serving.ValidateHostsAnnotation(r.GetAnnotations()).ViaField("annotations")).Also(
+		serving.ValidateRollbackOnFailureAnnotation(r.GetAnnotations()).ViaField("annotations")).ViaField("metadata")
 	errs = errs.Also(r.Spec.Validate(apis.WithinSpec(ctx)).ViaField("spec"))
 	errs = errs.Also(r.Status.Validate(apis.WithinStatus(ctx)).ViaField("status"))
 
@@ -51,6 +58,8 @@ func validateTrafficList(ctx context.Context, traffic []TrafficTarget) *apis.Fie
 
 	// Track the targets of named TrafficTarget entries (to detect duplicates).
 	trafficMap := make(map[string]int)
+	// Track the matches of the path prefixed entries (to detect overlaps).
+	matchMap := make(map[string]int)
 
 	sum := int64(0)
 	for i, tt := range traffic {
@@ -60,6 +69,23 @@ func validateTrafficList(ctx context.Context, traffic []TrafficTarget) *apis.Fie
 			sum += *tt.Percent
 		}
 
+		if tt.PathPrefix != "" {
+			// Prefixes only differing by a trailing slash match the same requests.
+			// Maps are printed sorted by key.
+			match := fmt.Sprint(strings.TrimSuffix(tt.PathPrefix, "/"), tt.Headers)
+			if idx, ok := matchMap[match]; ok {
+				errs = errs.Also(&apis.FieldError{
+					Message: fmt.Sprintf("Overlapping definitions for path prefix %q", tt.PathPrefix),
+					Paths: []string{
+						fmt.Sprintf("[%d].pathPrefix", i),
+						fmt.Sprintf("[%d].pathPrefix", idx),
+					},
+				})
+			} else {
+				matchMap[match] = i
+			}
+		}
+
 		if tt.Tag == "" {
 			continue
 		}
@@ -102,6 +128,10 @@ func (tt *TrafficTarget) Validate(ctx context.Context) *apis.FieldError {
 	errs := tt.validateLatestRevision(ctx)
 	errs = tt.validateRevisionAndConfiguration(ctx, errs)
 	errs = tt.validateTrafficPercentage(errs)
+	errs = tt.validatePort(ctx, errs)
+	errs = tt.validateHeaders(ctx, errs)
+	errs = tt.validatePathPrefix(ctx, errs)
+	errs = tt.validateMirror(errs)
 	return tt.validateURL(ctx, errs)
 }
 
@@ -171,6 +201,74 @@ func (tt *TrafficTarget) validateLatestRevision(ctx context.Context) *apis.Field
 	return nil
 }
 
+func (tt *TrafficTarget) validatePort(ctx context.Context, errs *apis.FieldError) *apis.FieldError {
+	if tt.Port == "" {
+		return errs
+	}
+	if apis.IsInSpec(ctx) && config.FromContextOrDefaults(ctx).Features.MultiPort != config.Enabled {
+		return errs.Also(apis.ErrDisallowedFields("port"))
+	}
+	// Only the dedicated url of the target can select the port.
+	if tt.Tag == "" {
+		errs = errs.Also(apis.ErrGeneric("may not set port without tag", "port"))
+	}
+	if len(validation.IsValidPortName(tt.Port)) != 0 {
+		errs = errs.Also(apis.ErrInvalidValue(tt.Port, "port"))
+	}
+	return errs
+}
+
+func (tt *TrafficTarget) validateHeaders(ctx context.Context, errs *apis.FieldError) *apis.FieldError {
+	if len(tt.Headers) == 0 {
+		return errs
+	}
+	if apis.IsInSpec(ctx) && config.FromContextOrDefaults(ctx).Features.TrafficHeaderMatching != config.Enabled {
+		return errs.Also(apis.ErrDisallowedFields("headers"))
+	}
+	for k, v := range tt.Headers {
+		if len(validation.IsHTTPHeaderName(k)) != 0 {
+			errs = errs.Also(apis.ErrInvalidKeyName(k, "headers"))
+		} else if v == "" {
+			errs = errs.Also(apis.ErrInvalidValue(v, apis.CurrentField).ViaKey(k).ViaField("headers"))
+		}
+	}
+	return errs
+}
+
+func (tt *TrafficTarget) validatePathPrefix(ctx context.Context, errs *apis.FieldError) *apis.FieldError {
+	if tt.PathPrefix == "" {
+		return errs
+	}
+	if apis.IsInSpec(ctx) && config.FromContextOrDefaults(ctx).Features.TrafficPathMatching != config.Enabled {
+		return errs.Also(apis.ErrDisallowedFields("pathPrefix"))
+	}
+	// The prefix must be a plain absolute path, other than the root one,
+	// which would match all the requests. The regexp metacharacters are
+	// allowed, since the prefix is escaped in the KIngress paths.
+	if u, err := url.Parse(tt.PathPrefix); err != nil || u.Path != tt.PathPrefix ||
+		!strings.HasPrefix(tt.PathPrefix, "/") || tt.PathPrefix == "/" {
+		errs = errs.Also(apis.ErrInvalidValue(tt.PathPrefix, "pathPrefix"))
+	}
+	return errs
+}
+
+func (tt *TrafficTarget) validateMirror(errs *apis.FieldError) *apis.FieldError {
+	if tt.Mirror == nil {
+		return errs
+	}
+	if tt.Mirror.RevisionName == "" {
+		errs = errs.Also(apis.ErrMissingField("mirror.revisionName"))
+	} else if el := validation.IsQualifiedName(tt.Mirror.RevisionName); len(el) > 0 {
+		errs = errs.Also(apis.ErrInvalidKeyName(
+			tt.Mirror.RevisionName, "mirror.revisionName", el...))
+	}
+	if tt.Mirror.Percent < 1 || tt.Mirror.Percent > 100 {
+		errs = errs.Also(apis.ErrOutOfBoundsValue(
+			tt.Mirror.Percent, 1, 100, "mirror.percent"))
+	}
+	return errs
+}
+
 func (tt *TrafficTarget) validateURL(ctx context.Context, errs *apis.FieldError) *apis.FieldError {
 	// Check that we set the URL appropriately.
 	if tt.URL.String() != "" {
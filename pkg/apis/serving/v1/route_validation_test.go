@@ -26,6 +26,7 @@ import (
 	network "knative.dev/networking/pkg"
 	"knative.dev/pkg/apis"
 	"knative.dev/pkg/ptr"
+	"knative.dev/serving/pkg/apis/config"
 	"knative.dev/serving/pkg/apis/serving"
 )
 
@@ -278,6 +279,142 @@ func TestTrafficTargetValidation(t *testing.T) {
 		},
 		wc:   apis.WithinSpec,
 		want: apis.ErrDisallowedFields("url"),
+	}, {
+		name: "valid port",
+		tt: &TrafficTarget{
+			Tag:          "admin",
+			RevisionName: "bar",
+			Port:         "admin",
+		},
+		wc:   withMultiPort,
+		want: nil,
+	}, {
+		name: "port with multi-port disabled",
+		tt: &TrafficTarget{
+			Tag:          "admin",
+			RevisionName: "bar",
+			Port:         "admin",
+		},
+		wc:   apis.WithinSpec,
+		want: apis.ErrDisallowedFields("port"),
+	}, {
+		name: "invalid port without tag",
+		tt: &TrafficTarget{
+			RevisionName: "bar",
+			Percent:      ptr.Int64(100),
+			Port:         "Admin_Port",
+		},
+		wc: withMultiPort,
+		want: apis.ErrGeneric("may not set port without tag", "port").Also(
+			apis.ErrInvalidValue("Admin_Port", "port")),
+	}, {
+		name: "valid headers",
+		tt: &TrafficTarget{
+			RevisionName: "bar",
+			Headers:      map[string]string{"X-Canary": "true"},
+		},
+		wc:   withTrafficHeaderMatching,
+		want: nil,
+	}, {
+		name: "headers with traffic-header-matching disabled",
+		tt: &TrafficTarget{
+			RevisionName: "bar",
+			Headers:      map[string]string{"X-Canary": "true"},
+		},
+		wc:   apis.WithinSpec,
+		want: apis.ErrDisallowedFields("headers"),
+	}, {
+		name: "invalid headers",
+		tt: &TrafficTarget{
+			RevisionName: "bar",
+			Headers:      map[string]string{"X Canary": "true", "X-Cohort": ""},
+		},
+		wc: withTrafficHeaderMatching,
+		want: apis.ErrInvalidKeyName("X Canary", "headers").Also(
+			apis.ErrInvalidValue("", "headers[X-Cohort]")),
+	}, {
+		name: "valid path prefix",
+		tt: &TrafficTarget{
+			RevisionName: "bar",
+			PathPrefix:   "/api",
+		},
+		wc:   withTrafficPathMatching,
+		want: nil,
+	}, {
+		// The prefix is escaped in the KIngress paths.
+		name: "path prefix with regexp metacharacters",
+		tt: &TrafficTarget{
+			RevisionName: "bar",
+			PathPrefix:   "/v1.0/(beta)*",
+		},
+		wc:   withTrafficPathMatching,
+		want: nil,
+	}, {
+		name: "path prefix with traffic-path-matching disabled",
+		tt: &TrafficTarget{
+			RevisionName: "bar",
+			PathPrefix:   "/api",
+		},
+		wc:   apis.WithinSpec,
+		want: apis.ErrDisallowedFields("pathPrefix"),
+	}, {
+		name: "relative path prefix",
+		tt: &TrafficTarget{
+			RevisionName: "bar",
+			PathPrefix:   "api",
+		},
+		wc:   withTrafficPathMatching,
+		want: apis.ErrInvalidValue("api", "pathPrefix"),
+	}, {
+		name: "path prefix with query",
+		tt: &TrafficTarget{
+			RevisionName: "bar",
+			PathPrefix:   "/api?v=1",
+		},
+		wc:   withTrafficPathMatching,
+		want: apis.ErrInvalidValue("/api?v=1", "pathPrefix"),
+	}, {
+		name: "root path prefix",
+		tt: &TrafficTarget{
+			RevisionName: "bar",
+			PathPrefix:   "/",
+		},
+		wc:   withTrafficPathMatching,
+		want: apis.ErrInvalidValue("/", "pathPrefix"),
+	}, {
+		name: "valid mirror",
+		tt: &TrafficTarget{
+			RevisionName: "bar",
+			Percent:      ptr.Int64(100),
+			Mirror:       &TrafficMirror{RevisionName: "baz", Percent: 10},
+		},
+		wc:   apis.WithinSpec,
+		want: nil,
+	}, {
+		name: "mirror without revision",
+		tt: &TrafficTarget{
+			RevisionName: "bar",
+			Mirror:       &TrafficMirror{Percent: 10},
+		},
+		wc:   apis.WithinSpec,
+		want: apis.ErrMissingField("mirror.revisionName"),
+	}, {
+		name: "mirror with invalid revision",
+		tt: &TrafficTarget{
+			RevisionName: "bar",
+			Mirror:       &TrafficMirror{RevisionName: "b az", Percent: 10},
+		},
+		wc: apis.WithinSpec,
+		want: apis.ErrInvalidKeyName(
+			"b az", "mirror.revisionName", "name part must consist of alphanumeric characters, '-', '_' or '.', and must start and end with an alphanumeric character (e.g. 'MyName',  or 'my.name',  or '123-abc', regex used for validation is '([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]')"),
+	}, {
+		name: "mirror percent out of bounds",
+		tt: &TrafficTarget{
+			RevisionName: "bar",
+			Mirror:       &TrafficMirror{RevisionName: "baz"},
+		},
+		wc:   apis.WithinSpec,
+		want: apis.ErrOutOfBoundsValue(0, 1, 100, "mirror.percent"),
 	}}
 
 	for _, test := range tests {
@@ -295,6 +432,61 @@ func TestTrafficTargetValidation(t *testing.T) {
 	}
 }
 
+func withMultiPort(ctx context.Context) context.Context {
+	return config.ToContext(apis.WithinSpec(ctx), &config.Config{
+		Features: &config.Features{MultiPort: config.Enabled},
+	})
+}
+
+func withTrafficHeaderMatching(ctx context.Context) context.Context {
+	return config.ToContext(apis.WithinSpec(ctx), &config.Config{
+		Features: &config.Features{TrafficHeaderMatching: config.Enabled},
+	})
+}
+
+func withTrafficPathMatching(ctx context.Context) context.Context {
+	return config.ToContext(apis.WithinSpec(ctx), &config.Config{
+		Features: &config.Features{TrafficPathMatching: config.Enabled},
+	})
+}
+
+func TestTrafficListPathPrefixOverlap(t *testing.T) {
+	traffic := []TrafficTarget{{
+		RevisionName: "foo",
+		Percent:      ptr.Int64(100),
+	}, {
+		RevisionName: "bar",
+		PathPrefix:   "/api",
+	}, {
+		RevisionName: "baz",
+		PathPrefix:   "/api/",
+	}, {
+		// Nested prefixes don't overlap, the longest wins.
+		RevisionName: "baz",
+		PathPrefix:   "/api/v2",
+	}}
+	want := &apis.FieldError{
+		Message: `Overlapping definitions for path prefix "/api/"`,
+		Paths:   []string{"[2].pathPrefix", "[1].pathPrefix"},
+	}
+	got := validateTrafficList(withTrafficPathMatching(context.Background()), traffic)
+	if !cmp.Equal(want.Error(), got.Error()) {
+		t.Errorf("validateTrafficList (-want, +got) = %v", cmp.Diff(want.Error(), got.Error()))
+	}
+
+	// The same prefix with distinct headers matches distinct requests.
+	traffic[2].Headers = map[string]string{"X-Canary": "true"}
+	ctx := config.ToContext(apis.WithinSpec(context.Background()), &config.Config{
+		Features: &config.Features{
+			TrafficHeaderMatching: config.Enabled,
+			TrafficPathMatching:   config.Enabled,
+		},
+	})
+	if err := validateTrafficList(ctx, traffic); err != nil {
+		t.Error("validateTrafficList =", err)
+	}
+}
+
 func TestRouteValidation(t *testing.T) {
 	tests := []struct {
 		name string
@@ -510,6 +702,61 @@ func TestRouteValidation(t *testing.T) {
 			Message: "invalid value: not a DNS 1035 label: [a DNS-1035 label must consist of lower case alphanumeric characters or '-', start with an alphabetic character, and end with an alphanumeric character (e.g. 'my-name',  or 'abc-123', regex used for validation is '[a-z]([-a-z0-9]*[a-z0-9])?')]",
 			Paths:   []string{"spec.traffic.tag[0]"},
 		},
+	}, {
+		name: "valid mirror",
+		r: &Route{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "valid",
+				Annotations: map[string]string{
+					serving.MirrorAnnotationKey: `{"bar": {"revisionName": "baz", "percent": 10}}`,
+				},
+			},
+			Spec: RouteSpec{
+				Traffic: []TrafficTarget{{
+					Tag:          "bar",
+					RevisionName: "foo",
+					Percent:      ptr.Int64(100),
+				}},
+			},
+		},
+	}, {
+		name: "invalid mirror percent",
+		r: &Route{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "valid",
+				Annotations: map[string]string{
+					serving.MirrorAnnotationKey: `{"bar": {"revisionName": "baz", "percent": 200}}`,
+				},
+			},
+			Spec: RouteSpec{
+				Traffic: []TrafficTarget{{
+					Tag:          "bar",
+					RevisionName: "foo",
+					Percent:      ptr.Int64(100),
+				}},
+			},
+		},
+		want: apis.ErrOutOfBoundsValue(200, 1, 100,
+			serving.MirrorAnnotationKey+".bar.percent").ViaField("metadata.annotations"),
+	}, {
+		name: "invalid header rules",
+		r: &Route{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "valid",
+				Annotations: map[string]string{
+					serving.HeaderRulesAnnotationKey: `{"bar": {"response": {"remove": ["Knative-Foo"]}}}`,
+				},
+			},
+			Spec: RouteSpec{
+				Traffic: []TrafficTarget{{
+					Tag:          "bar",
+					RevisionName: "foo",
+					Percent:      ptr.Int64(100),
+				}},
+			},
+		},
+		want: apis.ErrInvalidArrayValue("Knative-Foo",
+			serving.HeaderRulesAnnotationKey+".bar.response.remove", 0).ViaField("metadata.annotations"),
 	}}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
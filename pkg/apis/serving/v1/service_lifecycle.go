@@ -23,6 +23,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"knative.dev/pkg/apis"
+	"knative.dev/serving/pkg/apis/serving"
 )
 
 const (
@@ -60,6 +61,12 @@ func (s *Service) IsFailed() bool {
 		ss.GetCondition(ServiceConditionReady).IsFalse()
 }
 
+// EndToEndReadiness returns true if the service reports Ready only once
+// its URL actually responds.
+func (s *Service) EndToEndReadiness() bool {
+	return s.Annotations[serving.EndToEndReadinessAnnotationKey] == "true"
+}
+
 // InitializeConditions sets the initial values to the conditions.
 func (ss *ServiceStatus) InitializeConditions() {
 	serviceCondSet.Manage(ss).InitializeConditions()
@@ -119,6 +126,13 @@ func (ss *ServiceStatus) MarkRouteNotYetReady() {
 	serviceCondSet.Manage(ss).MarkUnknown(ServiceConditionRoutesReady, trafficNotMigratedReason, trafficNotMigratedMessage)
 }
 
+// MarkRouteNotYetResponding marks the service `RoutesReady` condition to the
+// `Unknown` state until the url responds to the end-to-end readiness probe.
+func (ss *ServiceStatus) MarkRouteNotYetResponding(url string) {
+	serviceCondSet.Manage(ss).MarkUnknown(ServiceConditionRoutesReady,
+		"NotResponding", "Waiting for %s to respond.", url)
+}
+
 // MarkRouteNotReconciled notes that the Route controller has not yet
 // caught up to the desired changes we have specified.
 func (ss *ServiceStatus) MarkRouteNotReconciled() {
@@ -317,6 +317,21 @@ func TestMarkRouteNotYetReady(t *testing.T) {
 	}
 }
 
+func TestMarkRouteNotYetResponding(t *testing.T) {
+	svc := &ServiceStatus{}
+	svc.InitializeConditions()
+	svc.MarkRouteNotYetResponding("http://foo.bar.example.com")
+	apistest.CheckConditionOngoing(svc, ServiceConditionReady, t)
+	apistest.CheckConditionOngoing(svc, ServiceConditionRoutesReady, t)
+	dt := svc.GetCondition(ServiceConditionRoutesReady)
+	if got, want := dt.Reason, "NotResponding"; got != want {
+		t.Errorf("Condition Reason: got: %s, want: %s", got, want)
+	}
+	if got, want := dt.Message, "Waiting for http://foo.bar.example.com to respond."; got != want {
+		t.Errorf("Condition Message: got: %s, want: %s", got, want)
+	}
+}
+
 func TestMarkRouteNotReconciled(t *testing.T) {
 	svc := &ServiceStatus{}
 	svc.InitializeConditions()
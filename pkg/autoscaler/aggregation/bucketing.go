@@ -136,6 +136,41 @@ func (t *TimedFloat64Buckets) WindowAverage(now time.Time) float64 {
 	}
 }
 
+// Bucket is the value recorded in the bucket starting at Time.
+type Bucket struct {
+	Time  time.Time
+	Value float64
+}
+
+// Buckets returns the buckets holding the data within the window as of now,
+// oldest first. Consistently with WindowAverage, the gaps between the writes
+// are returned as zero buckets, while the time since the last write is not
+// represented at all.
+func (t *TimedFloat64Buckets) Buckets(now time.Time) []Bucket {
+	now = now.Truncate(t.granularity)
+	t.bucketsMutex.RLock()
+	defer t.bucketsMutex.RUnlock()
+	if t.lastWrite.IsZero() || now.Sub(t.lastWrite) >= t.window {
+		return nil
+	}
+	span := time.Duration(len(t.buckets)-1) * t.granularity
+	start := t.firstWrite
+	if ws := now.Add(-span); ws.After(start) {
+		start = ws
+	}
+	if ws := t.lastWrite.Add(-span); ws.After(start) {
+		start = ws
+	}
+	ret := make([]Bucket, 0, len(t.buckets))
+	for tm := start; !tm.After(t.lastWrite); tm = tm.Add(t.granularity) {
+		ret = append(ret, Bucket{
+			Time:  tm,
+			Value: t.buckets[t.timeToIndex(tm)%len(t.buckets)],
+		})
+	}
+	return ret
+}
+
 // timeToIndex converts time to an integer that can be used for modulo
 // operations to find the index in the bucket list.
 // bucketMutex needs to be held.
@@ -216,6 +216,42 @@ func TestTimedFloat64BucketsHoles(t *testing.T) {
 	}
 }
 
+func TestTimedFloat64BucketsBuckets(t *testing.T) {
+	now := time.Now().Truncate(granularity)
+	buckets := NewTimedFloat64Buckets(5*time.Second, granularity)
+	if got := buckets.Buckets(now); got != nil {
+		t.Errorf("Buckets = %v, want: nil", got)
+	}
+
+	buckets.Record(now, 1)
+	buckets.Record(now.Add(time.Second), 2)
+	// Skip the third second.
+	buckets.Record(now.Add(3*time.Second), 4)
+
+	bs := func(vals ...float64) []Bucket {
+		ret := make([]Bucket, len(vals))
+		for i, v := range vals {
+			ret[i] = Bucket{Time: now.Add(time.Duration(i) * time.Second), Value: v}
+		}
+		return ret
+	}
+	if got, want := buckets.Buckets(now.Add(3*time.Second)), bs(1, 2, 0, 4); !cmp.Equal(got, want) {
+		t.Errorf("Buckets = %v, want: %v, diff(-want,+got): %s", got, want, cmp.Diff(want, got))
+	}
+	// The time after the last write is not represented.
+	if got, want := buckets.Buckets(now.Add(4*time.Second)), bs(1, 2, 0, 4); !cmp.Equal(got, want) {
+		t.Errorf("Buckets = %v, want: %v, diff(-want,+got): %s", got, want, cmp.Diff(want, got))
+	}
+	// The buckets that slid out of the window are dropped.
+	if got, want := buckets.Buckets(now.Add(6*time.Second)), bs(1, 2, 0, 4)[2:]; !cmp.Equal(got, want) {
+		t.Errorf("Buckets = %v, want: %v, diff(-want,+got): %s", got, want, cmp.Diff(want, got))
+	}
+	// Nothing within the window.
+	if got := buckets.Buckets(now.Add(8 * time.Second)); got != nil {
+		t.Errorf("Buckets = %v, want: nil", got)
+	}
+}
+
 func TestTimedFloat64BucketsWindowUpdate(t *testing.T) {
 	startTime := time.Now()
 	buckets := NewTimedFloat64Buckets(5*time.Second, granularity)
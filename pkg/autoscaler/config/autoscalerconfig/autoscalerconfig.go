@@ -84,5 +84,20 @@ type Config struct {
 	// add an additional delay to the very last pod, if required.
 	ScaleDownDelay time.Duration
 
+	// CountLongLivedConnections determines whether the long-lived connections,
+	// i.e. websockets and server-sent event streams, count towards the request
+	// concurrency the revisions are scaled on. These connections are mostly
+	// idle, so counting them may pin the scale of the chat and streaming
+	// workloads high. They are tracked separately regardless.
+	CountLongLivedConnections bool
+
+	// ActivatorMetricMergePolicy determines how the metrics reported by the
+	// activators are merged with the metrics scraped from the pods, while the
+	// activators are in the request path. One of sum, activator, scrape or weighted.
+	ActivatorMetricMergePolicy string
+	// ActivatorMetricWeightPercentage is the weight of the activator-reported
+	// metrics, in percent, when the merge policy is weighted.
+	ActivatorMetricWeightPercentage float64
+
 	PodAutoscalerClass string
 }
@@ -62,6 +62,10 @@ func defaultConfig() *autoscalerconfig.Config {
 		InitialScale:                  1,
 		MaxScale:                      0,
 		MaxScaleLimit:                 0,
+		CountLongLivedConnections:     true,
+
+		ActivatorMetricMergePolicy:      autoscaling.ActivatorMetricMergeSum,
+		ActivatorMetricWeightPercentage: 50,
 	}
 }
 
@@ -71,9 +75,11 @@ func NewConfigFromMap(data map[string]string) (*autoscalerconfig.Config, error)
 
 	if err := cm.Parse(data,
 		cm.AsString("pod-autoscaler-class", &lc.PodAutoscalerClass),
+		cm.AsString("activator-metric-merge-policy", &lc.ActivatorMetricMergePolicy),
 
 		cm.AsBool("enable-scale-to-zero", &lc.EnableScaleToZero),
 		cm.AsBool("allow-zero-initial-scale", &lc.AllowZeroInitialScale),
+		cm.AsBool("count-long-lived-connections", &lc.CountLongLivedConnections),
 
 		cm.AsFloat64("max-scale-up-rate", &lc.MaxScaleUpRate),
 		cm.AsFloat64("max-scale-down-rate", &lc.MaxScaleDownRate),
@@ -84,6 +90,7 @@ func NewConfigFromMap(data map[string]string) (*autoscalerconfig.Config, error)
 		cm.AsFloat64("panic-window-percentage", &lc.PanicWindowPercentage),
 		cm.AsFloat64("activator-capacity", &lc.ActivatorCapacity),
 		cm.AsFloat64("panic-threshold-percentage", &lc.PanicThresholdPercentage),
+		cm.AsFloat64("activator-metric-weight-percentage", &lc.ActivatorMetricWeightPercentage),
 
 		cm.AsInt32("initial-scale", &lc.InitialScale),
 		cm.AsInt32("max-scale", &lc.MaxScale),
@@ -183,6 +190,19 @@ func validate(lc *autoscalerconfig.Config) (*autoscalerconfig.Config, error) {
 	if lc.MaxScaleLimit < 0 {
 		return nil, fmt.Errorf("max-scale-limit = %v, must be at least 0", lc.MaxScaleLimit)
 	}
+
+	switch lc.ActivatorMetricMergePolicy {
+	case autoscaling.ActivatorMetricMergeSum, autoscaling.ActivatorMetricMergeActivator,
+		autoscaling.ActivatorMetricMergeScrape, autoscaling.ActivatorMetricMergeWeighted:
+	default:
+		return nil, fmt.Errorf("activator-metric-merge-policy = %q, must be one of %s, %s, %s or %s",
+			lc.ActivatorMetricMergePolicy, autoscaling.ActivatorMetricMergeSum, autoscaling.ActivatorMetricMergeActivator,
+			autoscaling.ActivatorMetricMergeScrape, autoscaling.ActivatorMetricMergeWeighted)
+	}
+
+	if lc.ActivatorMetricWeightPercentage < 0 || lc.ActivatorMetricWeightPercentage > 100 {
+		return nil, fmt.Errorf("activator-metric-weight-percentage = %v, must be in [0, 100] interval", lc.ActivatorMetricWeightPercentage)
+	}
 	return lc, nil
 }
 
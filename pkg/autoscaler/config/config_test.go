@@ -24,6 +24,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 
 	. "knative.dev/pkg/configmap/testing"
+	"knative.dev/serving/pkg/apis/autoscaling"
 	"knative.dev/serving/pkg/autoscaler/config/autoscalerconfig"
 )
 
@@ -64,6 +65,9 @@ func TestNewConfig(t *testing.T) {
 			"pod-autoscaler-class":                    "some.class",
 			"activator-capacity":                      "905",
 			"scale-to-zero-pod-retention-period":      "2m3s",
+			"count-long-lived-connections":            "false",
+			"activator-metric-merge-policy":           "weighted",
+			"activator-metric-weight-percentage":      "30",
 		},
 		want: func() *autoscalerconfig.Config {
 			c := defaultConfig()
@@ -78,6 +82,9 @@ func TestNewConfig(t *testing.T) {
 			c.ActivatorCapacity = 905
 			c.PodAutoscalerClass = "some.class"
 			c.ScaleToZeroPodRetentionPeriod = 2*time.Minute + 3*time.Second
+			c.CountLongLivedConnections = false
+			c.ActivatorMetricMergePolicy = autoscaling.ActivatorMetricMergeWeighted
+			c.ActivatorMetricWeightPercentage = 30
 			return c
 		}(),
 	}, {
@@ -157,6 +164,19 @@ func TestNewConfig(t *testing.T) {
 			"max-scale-up-rate": "not a float",
 		},
 		wantErr: true,
+	}, {
+		name: "invalid activator metric merge policy",
+		input: map[string]string{
+			"activator-metric-merge-policy": "average",
+		},
+		wantErr: true,
+	}, {
+		name: "activator metric weight too big",
+		input: map[string]string{
+			"activator-metric-merge-policy":      "weighted",
+			"activator-metric-weight-percentage": "101",
+		},
+		wantErr: true,
 	}, {
 		name: "invalid scale-down-delay",
 		input: map[string]string{
@@ -26,6 +26,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/clock"
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/logging/logkey"
+	"knative.dev/serving/pkg/apis/autoscaling"
 	av1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
 	"knative.dev/serving/pkg/autoscaler/aggregation"
 	"knative.dev/serving/pkg/autoscaler/config"
@@ -35,6 +36,19 @@ const (
 	// scrapeTickInterval is the interval of time between triggering StatsScraper.Scrape()
 	// to get metrics across all pods of a revision.
 	scrapeTickInterval = time.Second
+
+	// resourceScrapeInterval is the interval of time between triggering
+	// ResourceScraper.Scrape(). The metrics-server refreshes the usage far less
+	// often than every second, so the last usage is recorded at every tick.
+	resourceScrapeInterval = 10 * time.Second
+
+	// HistoryWindow is how long the collected concurrency and RPS are kept
+	// around for replaying them, e.g. to simulate configuration changes.
+	HistoryWindow = time.Hour
+
+	// sourceFreshness is how long the stats from a source, i.e. the activators
+	// or the scraper, are considered current for merging them with the other.
+	sourceFreshness = 3 * scrapeTickInterval
 )
 
 var (
@@ -80,14 +94,19 @@ type MetricClient interface {
 	// StableAndPanicRPS returns both the stable and the panic RPS
 	// for the given replica as of the given time.
 	StableAndPanicRPS(key types.NamespacedName, now time.Time) (float64, float64, error)
+
+	// StableAndPanicResourceUsage returns both the stable and the panic
+	// usage of the resource the given replica scales on as of the given time.
+	StableAndPanicResourceUsage(key types.NamespacedName, now time.Time) (float64, float64, error)
 }
 
 // MetricCollector manages collection of metrics for many entities.
 type MetricCollector struct {
 	logger *zap.SugaredLogger
 
-	statsScraperFactory StatsScraperFactory
-	clock               clock.Clock
+	statsScraperFactory    StatsScraperFactory
+	resourceScraperFactory ResourceScraperFactory
+	clock                  clock.Clock
 
 	collectionsMutex sync.RWMutex
 	collections      map[types.NamespacedName]*collection
@@ -99,13 +118,16 @@ type MetricCollector struct {
 var _ Collector = (*MetricCollector)(nil)
 var _ MetricClient = (*MetricCollector)(nil)
 
-// NewMetricCollector creates a new metric collector.
-func NewMetricCollector(statsScraperFactory StatsScraperFactory, logger *zap.SugaredLogger) *MetricCollector {
+// NewMetricCollector creates a new metric collector. The resourceScraperFactory
+// may be nil, if scaling on the resource usage is not supported.
+func NewMetricCollector(statsScraperFactory StatsScraperFactory, resourceScraperFactory ResourceScraperFactory,
+	logger *zap.SugaredLogger) *MetricCollector {
 	return &MetricCollector{
-		logger:              logger,
-		collections:         make(map[types.NamespacedName]*collection),
-		statsScraperFactory: statsScraperFactory,
-		clock:               clock.RealClock{},
+		logger:                 logger,
+		collections:            make(map[types.NamespacedName]*collection),
+		statsScraperFactory:    statsScraperFactory,
+		resourceScraperFactory: resourceScraperFactory,
+		clock:                  clock.RealClock{},
 	}
 }
 
@@ -120,6 +142,12 @@ func (c *MetricCollector) CreateOrUpdate(metric *av1alpha1.Metric) error {
 	if err != nil {
 		return err
 	}
+	var resourceScraper ResourceScraper
+	if c.resourceScraperFactory != nil {
+		if resourceScraper, err = c.resourceScraperFactory(metric); err != nil {
+			return err
+		}
+	}
 	key := types.NamespacedName{Namespace: metric.Namespace, Name: metric.Name}
 
 	c.collectionsMutex.Lock()
@@ -128,11 +156,12 @@ func (c *MetricCollector) CreateOrUpdate(metric *av1alpha1.Metric) error {
 	collection, exists := c.collections[key]
 	if exists {
 		collection.updateScraper(scraper)
+		collection.updateResourceScraper(resourceScraper)
 		collection.updateMetric(metric)
 		return collection.lastError()
 	}
 
-	c.collections[key] = newCollection(metric, scraper, c.clock, c.Inform, logger)
+	c.collections[key] = newCollection(metric, scraper, resourceScraper, c.clock, c.Inform, logger)
 	return nil
 }
 
@@ -155,7 +184,7 @@ func (c *MetricCollector) Record(key types.NamespacedName, now time.Time, stat S
 	defer c.collectionsMutex.RUnlock()
 
 	if collection, exists := c.collections[key]; exists {
-		collection.record(now, stat)
+		collection.recordActivatorStat(now, stat)
 	}
 }
 
@@ -218,6 +247,96 @@ func (c *MetricCollector) StableAndPanicRPS(key types.NamespacedName, now time.T
 		nil
 }
 
+// StableAndPanicResourceUsage returns both the stable and the panic usage of
+// the resource the metric scales on, i.e. cpu or memory.
+// It may truncate metric buckets as a side-effect.
+func (c *MetricCollector) StableAndPanicResourceUsage(key types.NamespacedName, now time.Time) (float64, float64, error) {
+	c.collectionsMutex.RLock()
+	defer c.collectionsMutex.RUnlock()
+
+	collection, exists := c.collections[key]
+	if !exists {
+		return 0, 0, ErrNotCollecting
+	}
+
+	if collection.resourceBuckets.IsEmpty(now) && collection.getResourceScraper() != nil {
+		return 0, 0, ErrNoData
+	}
+	return collection.resourceBuckets.WindowAverage(now),
+		collection.resourcePanicBuckets.WindowAverage(now),
+		nil
+}
+
+// WindowData is the data in a single aggregation window of a metric.
+type WindowData struct {
+	// Average is the window average, as used for the scaling decisions.
+	Average float64
+	// Buckets are the buckets within the window, oldest first.
+	Buckets []aggregation.Bucket
+}
+
+// WindowsSnapshot is the data in the stable and panic windows of a metric.
+type WindowsSnapshot struct {
+	StableWindow     time.Duration
+	PanicWindow      time.Duration
+	Concurrency      WindowData
+	PanicConcurrency WindowData
+	RPS              WindowData
+	PanicRPS         WindowData
+}
+
+// WindowsSnapshot returns the data in the stable and panic windows of the
+// metric as of the given time, i.e. what the autoscaler sees.
+func (c *MetricCollector) WindowsSnapshot(key types.NamespacedName, now time.Time) (*WindowsSnapshot, error) {
+	c.collectionsMutex.RLock()
+	defer c.collectionsMutex.RUnlock()
+
+	collection, exists := c.collections[key]
+	if !exists {
+		return nil, ErrNotCollecting
+	}
+
+	metric := collection.currentMetric()
+	data := func(b *aggregation.TimedFloat64Buckets) WindowData {
+		return WindowData{
+			Average: b.WindowAverage(now),
+			Buckets: b.Buckets(now),
+		}
+	}
+	return &WindowsSnapshot{
+		StableWindow:     metric.Spec.StableWindow,
+		PanicWindow:      metric.Spec.PanicWindow,
+		Concurrency:      data(collection.concurrencyBuckets),
+		PanicConcurrency: data(collection.concurrencyPanicBuckets),
+		RPS:              data(collection.rpsBuckets),
+		PanicRPS:         data(collection.rpsPanicBuckets),
+	}, nil
+}
+
+// History is the data of a metric collected over the last HistoryWindow.
+type History struct {
+	// Concurrency and RPS are the buckets oldest first, as recorded before
+	// the window aggregation.
+	Concurrency []aggregation.Bucket
+	RPS         []aggregation.Bucket
+}
+
+// History returns the data of the metric collected over the HistoryWindow
+// before the given time.
+func (c *MetricCollector) History(key types.NamespacedName, now time.Time) (*History, error) {
+	c.collectionsMutex.RLock()
+	defer c.collectionsMutex.RUnlock()
+
+	collection, exists := c.collections[key]
+	if !exists {
+		return nil, ErrNotCollecting
+	}
+	return &History{
+		Concurrency: collection.concurrencyHistory.Buckets(now),
+		RPS:         collection.rpsHistory.Buckets(now),
+	}, nil
+}
+
 // collection represents the collection of metrics for one specific entity.
 type collection struct {
 	// mux guards access to all of the collection's state.
@@ -230,12 +349,23 @@ type collection struct {
 	concurrencyPanicBuckets *aggregation.TimedFloat64Buckets
 	rpsBuckets              *aggregation.TimedFloat64Buckets
 	rpsPanicBuckets         *aggregation.TimedFloat64Buckets
+	resourceBuckets         *aggregation.TimedFloat64Buckets
+	resourcePanicBuckets    *aggregation.TimedFloat64Buckets
+	// The history is kept independently of the windows, which are resized
+	// with the metric.
+	concurrencyHistory *aggregation.TimedFloat64Buckets
+	rpsHistory         *aggregation.TimedFloat64Buckets
 
 	// Fields relevant for metric scraping specifically.
-	scraper StatsScraper
-	lastErr error
-	grp     sync.WaitGroup
-	stopCh  chan struct{}
+	scraper         StatsScraper
+	resourceScraper ResourceScraper
+	lastErr         error
+	lastScrape      time.Time
+	// activatorStats are the last stats reported by each activator, keyed by
+	// the activator pod name.
+	activatorStats map[string]timedStat
+	grp            sync.WaitGroup
+	stopCh         chan struct{}
 }
 
 func (c *collection) updateScraper(ss StatsScraper) {
@@ -250,10 +380,23 @@ func (c *collection) getScraper() StatsScraper {
 	return c.scraper
 }
 
+func (c *collection) updateResourceScraper(rs ResourceScraper) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.resourceScraper = rs
+}
+
+func (c *collection) getResourceScraper() ResourceScraper {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	return c.resourceScraper
+}
+
 // newCollection creates a new collection, which uses the given scraper to
-// collect stats every scrapeTickInterval.
-func newCollection(metric *av1alpha1.Metric, scraper StatsScraper, clock clock.Clock,
-	callback func(types.NamespacedName), logger *zap.SugaredLogger) *collection {
+// collect stats every scrapeTickInterval. The resource scraper, if any, is
+// used to collect the resource usage every resourceScrapeInterval.
+func newCollection(metric *av1alpha1.Metric, scraper StatsScraper, resourceScraper ResourceScraper,
+	clock clock.Clock, callback func(types.NamespacedName), logger *zap.SugaredLogger) *collection {
 	c := &collection{
 		metric: metric,
 		concurrencyBuckets: aggregation.NewTimedFloat64Buckets(
@@ -264,7 +407,17 @@ func newCollection(metric *av1alpha1.Metric, scraper StatsScraper, clock clock.C
 			metric.Spec.StableWindow, config.BucketSize),
 		rpsPanicBuckets: aggregation.NewTimedFloat64Buckets(
 			metric.Spec.PanicWindow, config.BucketSize),
-		scraper: scraper,
+		resourceBuckets: aggregation.NewTimedFloat64Buckets(
+			metric.Spec.StableWindow, config.BucketSize),
+		resourcePanicBuckets: aggregation.NewTimedFloat64Buckets(
+			metric.Spec.PanicWindow, config.BucketSize),
+		concurrencyHistory: aggregation.NewTimedFloat64Buckets(
+			HistoryWindow, config.BucketSize),
+		rpsHistory: aggregation.NewTimedFloat64Buckets(
+			HistoryWindow, config.BucketSize),
+		scraper:         scraper,
+		resourceScraper: resourceScraper,
+		activatorStats:  make(map[string]timedStat),
 
 		stopCh: make(chan struct{}),
 	}
@@ -278,11 +431,33 @@ func newCollection(metric *av1alpha1.Metric, scraper StatsScraper, clock clock.C
 
 		scrapeTicker := clock.NewTicker(scrapeTickInterval)
 		defer scrapeTicker.Stop()
+		var (
+			usage         float64
+			haveUsage     bool
+			lastUsageTime time.Time
+		)
 		for {
 			select {
 			case <-c.stopCh:
 				return
 			case <-scrapeTicker.C():
+				if rs := c.getResourceScraper(); rs == nil {
+					haveUsage = false
+				} else {
+					now := clock.Now()
+					if now.Sub(lastUsageTime) >= resourceScrapeInterval {
+						lastUsageTime = now
+						if u, err := rs.Scrape(); err != nil {
+							logger.Errorw("Failed to scrape the resource usage", zap.Error(err))
+						} else {
+							usage, haveUsage = u, true
+						}
+					}
+					if haveUsage {
+						c.recordResourceUsage(now, usage)
+					}
+				}
+
 				scraper := c.getScraper()
 				if scraper == nil {
 					// Don't scrape empty target service.
@@ -300,7 +475,7 @@ func newCollection(metric *av1alpha1.Metric, scraper StatsScraper, clock clock.C
 					callback(key)
 				}
 				if stat != emptyStat {
-					c.record(clock.Now(), stat)
+					c.recordScrapedStat(clock.Now(), stat)
 				}
 			}
 		}
@@ -325,6 +500,8 @@ func (c *collection) updateMetric(metric *av1alpha1.Metric) {
 	c.concurrencyPanicBuckets.ResizeWindow(metric.Spec.PanicWindow)
 	c.rpsBuckets.ResizeWindow(metric.Spec.StableWindow)
 	c.rpsPanicBuckets.ResizeWindow(metric.Spec.PanicWindow)
+	c.resourceBuckets.ResizeWindow(metric.Spec.StableWindow)
+	c.resourcePanicBuckets.ResizeWindow(metric.Spec.PanicWindow)
 }
 
 // currentMetric safely returns the current metric stored in the collection.
@@ -355,16 +532,101 @@ func (c *collection) lastError() error {
 	return c.lastErr
 }
 
+// timedStat is a stat along with the time it was recorded at.
+type timedStat struct {
+	time time.Time
+	stat Stat
+}
+
+// mergePolicy returns the activator metric merge policy of the current metric
+// and the weight of the activator-reported metrics, as a fraction.
+func (c *collection) mergePolicy() (string, float64) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	return c.metric.Spec.ActivatorMergePolicy, c.metric.Spec.ActivatorWeightPercentage / 100
+}
+
+// recordActivatorStat records a stat reported by an activator, merging it
+// with the scraped stats according to the merge policy.
+func (c *collection) recordActivatorStat(now time.Time, stat Stat) {
+	policy, _ := c.mergePolicy()
+
+	c.mux.Lock()
+	c.activatorStats[stat.PodName] = timedStat{time: now, stat: stat}
+	scraping := now.Sub(c.lastScrape) < sourceFreshness
+	c.mux.Unlock()
+
+	// While the pods are being scraped, the scrape and the weighted
+	// policies account for the activator stats when recording the scraped ones.
+	if scraping && (policy == autoscaling.ActivatorMetricMergeScrape ||
+		policy == autoscaling.ActivatorMetricMergeWeighted) {
+		return
+	}
+	c.record(now, stat)
+}
+
+// recordScrapedStat records a stat scraped from the pods, merging it with
+// the activator-reported stats according to the merge policy.
+func (c *collection) recordScrapedStat(now time.Time, stat Stat) {
+	policy, weight := c.mergePolicy()
+
+	c.mux.Lock()
+	c.lastScrape = now
+	var (
+		activator       Stat
+		activatorActive bool
+	)
+	for pod, ts := range c.activatorStats {
+		if now.Sub(ts.time) >= sourceFreshness {
+			delete(c.activatorStats, pod)
+			continue
+		}
+		activator.add(ts.stat)
+		activatorActive = true
+	}
+	c.mux.Unlock()
+
+	switch {
+	case !activatorActive:
+		c.record(now, stat)
+	case policy == autoscaling.ActivatorMetricMergeActivator:
+		// The activator-reported stats are recorded as they arrive.
+	case policy == autoscaling.ActivatorMetricMergeScrape:
+		// The scraped stats include the proxied requests, which are
+		// not counted at the activator under this policy.
+		c.recordValues(now, stat.AverageConcurrentRequests, stat.RequestCount)
+	case policy == autoscaling.ActivatorMetricMergeWeighted:
+		c.recordValues(now,
+			weight*activator.AverageConcurrentRequests+(1-weight)*stat.AverageConcurrentRequests,
+			weight*activator.RequestCount+(1-weight)*stat.RequestCount)
+	default:
+		c.record(now, stat)
+	}
+}
+
 // record adds a stat to the current collection.
 func (c *collection) record(now time.Time, stat Stat) {
 	// Proxied requests have been counted at the activator. Subtract
 	// them to avoid double counting.
-	concur := stat.AverageConcurrentRequests - stat.AverageProxiedConcurrentRequests
+	c.recordValues(now,
+		stat.AverageConcurrentRequests-stat.AverageProxiedConcurrentRequests,
+		stat.RequestCount-stat.ProxiedRequestCount)
+}
+
+// recordValues adds the given concurrency and RPS to the current collection.
+func (c *collection) recordValues(now time.Time, concur, rps float64) {
 	c.concurrencyBuckets.Record(now, concur)
 	c.concurrencyPanicBuckets.Record(now, concur)
-	rps := stat.RequestCount - stat.ProxiedRequestCount
+	c.concurrencyHistory.Record(now, concur)
 	c.rpsBuckets.Record(now, rps)
 	c.rpsPanicBuckets.Record(now, rps)
+	c.rpsHistory.Record(now, rps)
+}
+
+// recordResourceUsage records the resource usage across all the pods.
+func (c *collection) recordResourceUsage(now time.Time, usage float64) {
+	c.resourceBuckets.Record(now, usage)
+	c.resourcePanicBuckets.Record(now, usage)
 }
 
 // add adds the stats from `src` to `dst`.
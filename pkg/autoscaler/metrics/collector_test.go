@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"go.uber.org/atomic"
 	"go.uber.org/zap"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -31,6 +32,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 
 	. "knative.dev/pkg/logging/testing"
+	"knative.dev/serving/pkg/apis/autoscaling"
 	av1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
 	"knative.dev/serving/pkg/apis/serving"
 	"knative.dev/serving/pkg/autoscaler/aggregation"
@@ -75,7 +77,7 @@ func TestMetricCollectorCRUD(t *testing.T) {
 		want := errors.New("factory failure")
 		failingFactory := scraperFactory(nil, want)
 
-		coll := NewMetricCollector(failingFactory, logger)
+		coll := NewMetricCollector(failingFactory, nil, logger)
 		got := coll.CreateOrUpdate(&defaultMetric)
 
 		if got != want {
@@ -85,7 +87,7 @@ func TestMetricCollectorCRUD(t *testing.T) {
 
 	t.Run("full crud", func(t *testing.T) {
 		key := types.NamespacedName{Namespace: defaultMetric.Namespace, Name: defaultMetric.Name}
-		coll := NewMetricCollector(factory, logger)
+		coll := NewMetricCollector(factory, nil, logger)
 		if err := coll.CreateOrUpdate(&defaultMetric); err != nil {
 			t.Errorf("CreateOrUpdate() = %v, want no error", err)
 		}
@@ -149,7 +151,7 @@ func TestMetricCollectorScraperMovingTime(t *testing.T) {
 	}
 	factory := scraperFactory(scraper, nil)
 
-	coll := NewMetricCollector(factory, logger)
+	coll := NewMetricCollector(factory, nil, logger)
 	coll.clock = fc
 	coll.CreateOrUpdate(&defaultMetric)
 
@@ -193,6 +195,71 @@ func TestMetricCollectorScraperMovingTime(t *testing.T) {
 	}
 }
 
+func TestMetricCollectorResourceUsage(t *testing.T) {
+	logger := TestLogger(t)
+
+	mtp := &fake.ManualTickProvider{
+		Channel: make(chan time.Time),
+	}
+	now := time.Now()
+	fc := fake.Clock{
+		FakeClock: clock.NewFakeClock(now),
+		TP:        mtp,
+	}
+	metricKey := types.NamespacedName{Namespace: defaultNamespace, Name: defaultName}
+	scrapes := atomic.NewInt32(0)
+	usage := atomic.NewFloat64(100)
+	rs := testResourceScraper(func() (float64, error) {
+		scrapes.Inc()
+		return usage.Load(), nil
+	})
+
+	coll := NewMetricCollector(scraperFactory(nil, nil), func(*av1alpha1.Metric) (ResourceScraper, error) {
+		return rs, nil
+	}, logger)
+	coll.clock = fc
+	coll.CreateOrUpdate(&defaultMetric)
+
+	if _, _, err := coll.StableAndPanicResourceUsage(metricKey, now); err != ErrNoData {
+		t.Errorf("StableAndPanicResourceUsage() = %v, want: %v", err, ErrNoData)
+	}
+
+	// The usage is scraped once, but recorded at every tick.
+	for i := 0; i < 3; i++ {
+		now = now.Add(time.Second)
+		fc.SetTime(now)
+		mtp.Channel <- now
+	}
+	var stable, panicUsage float64
+	wait.PollImmediate(10*time.Millisecond, 2*time.Second, func() (bool, error) {
+		stable, panicUsage, _ = coll.StableAndPanicResourceUsage(metricKey, now)
+		return stable == 100 && panicUsage == 100, nil
+	})
+	if stable != 100 || panicUsage != 100 {
+		t.Errorf("StableAndPanicResourceUsage() = (%v, %v), want: (100, 100)", stable, panicUsage)
+	}
+	if got, want := scrapes.Load(), int32(1); got != want {
+		t.Errorf("Scrapes = %d, want: %d", got, want)
+	}
+
+	usage.Store(300)
+	now = now.Add(resourceScrapeInterval)
+	fc.SetTime(now)
+	mtp.Channel <- now
+	wait.PollImmediate(10*time.Millisecond, 2*time.Second, func() (bool, error) {
+		return scrapes.Load() == 2, nil
+	})
+	if got, want := scrapes.Load(), int32(2); got != want {
+		t.Errorf("Scrapes = %d, want: %d", got, want)
+	}
+}
+
+type testResourceScraper func() (float64, error)
+
+func (s testResourceScraper) Scrape() (float64, error) {
+	return s()
+}
+
 func TestMetricCollectorScraper(t *testing.T) {
 	logger := TestLogger(t)
 
@@ -225,7 +292,7 @@ func TestMetricCollectorScraper(t *testing.T) {
 	}
 	factory := scraperFactory(scraper, nil)
 
-	coll := NewMetricCollector(factory, logger)
+	coll := NewMetricCollector(factory, nil, logger)
 	coll.clock = fc
 	coll.CreateOrUpdate(&defaultMetric)
 
@@ -312,7 +379,7 @@ func TestMetricCollectorNoScraper(t *testing.T) {
 	}
 	factory := scraperFactory(nil, nil)
 
-	coll := NewMetricCollector(factory, logger)
+	coll := NewMetricCollector(factory, nil, logger)
 	coll.clock = fc
 
 	noTargetMetric := defaultMetric
@@ -386,7 +453,7 @@ func TestMetricCollectorNoDataError(t *testing.T) {
 		},
 	}
 	factory := scraperFactory(scraper, nil)
-	coll := NewMetricCollector(factory, logger)
+	coll := NewMetricCollector(factory, nil, logger)
 
 	coll.CreateOrUpdate(&defaultMetric)
 	// Verify correct error is returned if ScrapeTarget is set
@@ -426,7 +493,7 @@ func TestMetricCollectorRecord(t *testing.T) {
 	}
 	factory := scraperFactory(scraper, nil)
 
-	coll := NewMetricCollector(factory, logger)
+	coll := NewMetricCollector(factory, nil, logger)
 	mtp := &fake.ManualTickProvider{
 		Channel: make(chan time.Time),
 	}
@@ -471,6 +538,142 @@ func TestMetricCollectorRecord(t *testing.T) {
 	}
 }
 
+func TestMetricCollectorWindowsSnapshot(t *testing.T) {
+	logger := TestLogger(t)
+
+	now := time.Now().Truncate(config.BucketSize)
+	metricKey := types.NamespacedName{Namespace: defaultNamespace, Name: defaultName}
+	coll := NewMetricCollector(scraperFactory(&testScraper{
+		s: func() (Stat, error) {
+			return emptyStat, nil
+		},
+	}, nil), nil, logger)
+	coll.clock = fake.Clock{
+		FakeClock: clock.NewFakeClock(now),
+		TP:        &fake.ManualTickProvider{Channel: make(chan time.Time)},
+	}
+
+	if _, err := coll.WindowsSnapshot(metricKey, now); err != ErrNotCollecting {
+		t.Errorf("WindowsSnapshot() = %v, want: %v", err, ErrNotCollecting)
+	}
+
+	coll.CreateOrUpdate(&defaultMetric)
+	coll.Record(metricKey, now.Add(-10*time.Second), Stat{
+		PodName:                   "testPod",
+		AverageConcurrentRequests: 4,
+		RequestCount:              8,
+	})
+	coll.Record(metricKey, now, Stat{
+		PodName:                   "testPod",
+		AverageConcurrentRequests: 2,
+		RequestCount:              4,
+	})
+
+	got, err := coll.WindowsSnapshot(metricKey, now)
+	if err != nil {
+		t.Fatal("WindowsSnapshot() =", err)
+	}
+	stable := func(first, last float64) []aggregation.Bucket {
+		ret := make([]aggregation.Bucket, 11)
+		for i := range ret {
+			ret[i].Time = now.Add(time.Duration(i-10) * time.Second)
+		}
+		ret[0].Value, ret[10].Value = first, last
+		return ret
+	}
+	want := &WindowsSnapshot{
+		StableWindow: defaultMetric.Spec.StableWindow,
+		PanicWindow:  defaultMetric.Spec.PanicWindow,
+		Concurrency: WindowData{
+			Average: 6. / 11,
+			Buckets: stable(4, 2),
+		},
+		// The gap is longer than the panic window, so the panic
+		// window only has the latest data.
+		PanicConcurrency: WindowData{
+			Average: 2,
+			Buckets: stable(4, 2)[10:],
+		},
+		RPS: WindowData{
+			Average: 12. / 11,
+			Buckets: stable(8, 4),
+		},
+		PanicRPS: WindowData{
+			Average: 4,
+			Buckets: stable(8, 4)[10:],
+		},
+	}
+	if !cmp.Equal(got, want, cmp.Comparer(func(a, b float64) bool {
+		return math.Abs(a-b) < 0.001
+	})) {
+		t.Errorf("WindowsSnapshot() = %v, want: %v, diff(-want,+got): %s", got, want, cmp.Diff(want, got))
+	}
+}
+
+func TestMetricCollectorHistory(t *testing.T) {
+	logger := TestLogger(t)
+
+	now := time.Now().Truncate(config.BucketSize)
+	metricKey := types.NamespacedName{Namespace: defaultNamespace, Name: defaultName}
+	coll := NewMetricCollector(scraperFactory(&testScraper{
+		s: func() (Stat, error) {
+			return emptyStat, nil
+		},
+	}, nil), nil, logger)
+	coll.clock = fake.Clock{
+		FakeClock: clock.NewFakeClock(now),
+		TP:        &fake.ManualTickProvider{Channel: make(chan time.Time)},
+	}
+
+	if _, err := coll.History(metricKey, now); err != ErrNotCollecting {
+		t.Errorf("History() = %v, want: %v", err, ErrNotCollecting)
+	}
+
+	coll.CreateOrUpdate(&defaultMetric)
+	// Way older than the stable window, but within the history.
+	coll.Record(metricKey, now.Add(-30*time.Minute), Stat{
+		PodName:                   "testPod",
+		AverageConcurrentRequests: 4,
+		RequestCount:              8,
+	})
+	coll.Record(metricKey, now, Stat{
+		PodName:                   "testPod",
+		AverageConcurrentRequests: 2,
+		RequestCount:              4,
+	})
+
+	got, err := coll.History(metricKey, now)
+	if err != nil {
+		t.Fatal("History() =", err)
+	}
+	for _, tc := range []struct {
+		name        string
+		buckets     []aggregation.Bucket
+		first, last float64
+	}{{
+		name:    "concurrency",
+		buckets: got.Concurrency,
+		first:   4,
+		last:    2,
+	}, {
+		name:    "rps",
+		buckets: got.RPS,
+		first:   8,
+		last:    4,
+	}} {
+		if got, want := len(tc.buckets), 30*60+1; got != want {
+			t.Fatalf("len(%s) = %d, want: %d", tc.name, got, want)
+		}
+		first, last := tc.buckets[0], tc.buckets[len(tc.buckets)-1]
+		if !first.Time.Equal(now.Add(-30*time.Minute)) || first.Value != tc.first {
+			t.Errorf("First %s bucket = %v, want: %v at %v", tc.name, first, tc.first, now.Add(-30*time.Minute))
+		}
+		if !last.Time.Equal(now) || last.Value != tc.last {
+			t.Errorf("Last %s bucket = %v, want: %v at %v", tc.name, last, tc.last, now)
+		}
+	}
+}
+
 func TestDoubleWatch(t *testing.T) {
 	defer func() {
 		if x := recover(); x == nil {
@@ -479,7 +682,7 @@ func TestDoubleWatch(t *testing.T) {
 	}()
 	logger := TestLogger(t)
 	factory := scraperFactory(nil, nil)
-	coll := NewMetricCollector(factory, logger)
+	coll := NewMetricCollector(factory, nil, logger)
 	coll.Watch(func(types.NamespacedName) {})
 	coll.Watch(func(types.NamespacedName) {})
 }
@@ -542,7 +745,7 @@ func TestMetricCollectorError(t *testing.T) {
 				FakeClock: clock.NewFakeClock(now),
 				TP:        mtp,
 			}
-			coll := NewMetricCollector(factory, logger)
+			coll := NewMetricCollector(factory, nil, logger)
 			coll.clock = fc
 
 			watchCh := make(chan types.NamespacedName)
@@ -596,6 +799,8 @@ func TestMetricCollectorAggregate(t *testing.T) {
 		concurrencyPanicBuckets: aggregation.NewTimedFloat64Buckets(m.Spec.PanicWindow, config.BucketSize),
 		rpsBuckets:              aggregation.NewTimedFloat64Buckets(m.Spec.StableWindow, config.BucketSize),
 		rpsPanicBuckets:         aggregation.NewTimedFloat64Buckets(m.Spec.PanicWindow, config.BucketSize),
+		concurrencyHistory:      aggregation.NewTimedFloat64Buckets(HistoryWindow, config.BucketSize),
+		rpsHistory:              aggregation.NewTimedFloat64Buckets(HistoryWindow, config.BucketSize),
 	}
 	now := time.Now()
 	for i := time.Duration(0); i < 10; i++ {
@@ -618,3 +823,91 @@ func TestMetricCollectorAggregate(t *testing.T) {
 		t.Errorf("Stable Concurrency = %f, want: %f", got, want)
 	}
 }
+
+func TestMetricCollectorActivatorMergePolicy(t *testing.T) {
+	activatorStat := Stat{
+		PodName:                   "activator",
+		AverageConcurrentRequests: 10,
+		RequestCount:              10,
+	}
+	scrapedStat := Stat{
+		PodName:                          scraperPodName,
+		AverageConcurrentRequests:        8,
+		AverageProxiedConcurrentRequests: 6,
+		RequestCount:                     8,
+		ProxiedRequestCount:              6,
+	}
+
+	tests := []struct {
+		name          string
+		policy        string
+		activatorBack time.Duration
+		want          float64
+	}{{
+		name: "default",
+		want: 12,
+	}, {
+		name:   "sum",
+		policy: autoscaling.ActivatorMetricMergeSum,
+		want:   12,
+	}, {
+		name:   "activator",
+		policy: autoscaling.ActivatorMetricMergeActivator,
+		want:   10,
+	}, {
+		name:          "activator, stale activator stats",
+		policy:        autoscaling.ActivatorMetricMergeActivator,
+		activatorBack: sourceFreshness,
+		want:          2,
+	}, {
+		name:   "scrape",
+		policy: autoscaling.ActivatorMetricMergeScrape,
+		want:   8,
+	}, {
+		name:   "weighted",
+		policy: autoscaling.ActivatorMetricMergeWeighted,
+		want:   0.3*10 + 0.7*8,
+	}, {
+		name:          "weighted, stale activator stats",
+		policy:        autoscaling.ActivatorMetricMergeWeighted,
+		activatorBack: sourceFreshness,
+		want:          2,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := defaultMetric
+			m.Spec.PanicWindow = config.BucketSize
+			m.Spec.ActivatorMergePolicy = test.policy
+			m.Spec.ActivatorWeightPercentage = 30
+			c := &collection{
+				metric:                  &m,
+				concurrencyBuckets:      aggregation.NewTimedFloat64Buckets(m.Spec.StableWindow, config.BucketSize),
+				concurrencyPanicBuckets: aggregation.NewTimedFloat64Buckets(m.Spec.PanicWindow, config.BucketSize),
+				rpsBuckets:              aggregation.NewTimedFloat64Buckets(m.Spec.StableWindow, config.BucketSize),
+				rpsPanicBuckets:         aggregation.NewTimedFloat64Buckets(m.Spec.PanicWindow, config.BucketSize),
+				concurrencyHistory:      aggregation.NewTimedFloat64Buckets(HistoryWindow, config.BucketSize),
+				rpsHistory:              aggregation.NewTimedFloat64Buckets(HistoryWindow, config.BucketSize),
+				activatorStats:          make(map[string]timedStat),
+			}
+
+			now := time.Now().Truncate(config.BucketSize)
+			// An earlier scrape, outside of the panic window.
+			c.recordScrapedStat(now.Add(-time.Second), scrapedStat)
+			if test.activatorBack == 0 {
+				c.recordActivatorStat(now, activatorStat)
+			} else {
+				c.activatorStats[activatorStat.PodName] = timedStat{time: now.Add(-test.activatorBack), stat: activatorStat}
+			}
+			c.recordScrapedStat(now, scrapedStat)
+
+			const tolerance = 0.001
+			if got := c.concurrencyPanicBuckets.WindowAverage(now); math.Abs(got-test.want) > tolerance {
+				t.Errorf("Concurrency = %v, want: %v", got, test.want)
+			}
+			if got := c.rpsPanicBuckets.WindowAverage(now); math.Abs(got-test.want) > tolerance {
+				t.Errorf("RPS = %v, want: %v", got, test.want)
+			}
+		})
+	}
+}
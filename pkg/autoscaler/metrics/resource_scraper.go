@@ -0,0 +1,118 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/rest"
+	"knative.dev/serving/pkg/apis/autoscaling"
+	av1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/apis/serving"
+)
+
+// ResourceScraper defines the interface for collecting the resource usage
+// of the pods of a Revision.
+type ResourceScraper interface {
+	// Scrape returns the total usage of the resource across the pods.
+	Scrape() (float64, error)
+}
+
+// ResourceScraperFactory creates a ResourceScraper for a given Metric.
+// It returns nil, if the resource usage is not needed for the Metric.
+type ResourceScraperFactory func(*av1alpha1.Metric) (ResourceScraper, error)
+
+// podMetricsList is the subset of the metrics.k8s.io PodMetricsList
+// the scraper needs.
+type podMetricsList struct {
+	Items []struct {
+		Containers []struct {
+			Usage corev1.ResourceList `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+// resourceScraper reads the resource usage of the pods of a Revision from
+// the metrics API, as served by the metrics-server.
+type resourceScraper struct {
+	client    rest.Interface
+	namespace string
+	selector  string
+	resource  corev1.ResourceName
+}
+
+// NewResourceScraper creates a new ResourceScraper for the Revision which
+// the given Metric is responsible for, if the Metric scales on cpu or memory.
+// The client must talk to the API server, e.g. the discovery REST client.
+func NewResourceScraper(client rest.Interface, metric *av1alpha1.Metric) (ResourceScraper, error) {
+	var resource corev1.ResourceName
+	switch metric.Annotations[autoscaling.MetricAnnotationKey] {
+	case autoscaling.CPU:
+		resource = corev1.ResourceCPU
+	case autoscaling.Memory:
+		resource = corev1.ResourceMemory
+	default:
+		return nil, nil
+	}
+	revisionName := metric.Labels[serving.RevisionLabelKey]
+	if revisionName == "" {
+		return nil, fmt.Errorf("label %q not found or empty in Metric %s", serving.RevisionLabelKey, metric.Name)
+	}
+	return &resourceScraper{
+		client:    client,
+		namespace: metric.Namespace,
+		selector:  labels.SelectorFromSet(labels.Set{serving.RevisionLabelKey: revisionName}).String(),
+		resource:  resource,
+	}, nil
+}
+
+// Scrape returns the cpu usage in millicores, or the memory usage in MiB,
+// summed over all the containers of all the pods.
+func (s *resourceScraper) Scrape() (float64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), httpClientTimeout)
+	defer cancel()
+	raw, err := s.client.Get().
+		AbsPath("/apis/metrics.k8s.io/v1beta1/namespaces", s.namespace, "pods").
+		Param("labelSelector", s.selector).
+		DoRaw(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the pod metrics: %w", err)
+	}
+	var list podMetricsList
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return 0, fmt.Errorf("failed to parse the pod metrics: %w", err)
+	}
+	total := 0.
+	for _, pod := range list.Items {
+		for _, c := range pod.Containers {
+			q, ok := c.Usage[s.resource]
+			if !ok {
+				continue
+			}
+			if s.resource == corev1.ResourceCPU {
+				total += float64(q.MilliValue())
+			} else {
+				total += float64(q.Value()) / (1 << 20)
+			}
+		}
+	}
+	return total, nil
+}
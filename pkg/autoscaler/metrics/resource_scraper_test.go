@@ -0,0 +1,115 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"knative.dev/serving/pkg/apis/autoscaling"
+	av1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/apis/serving"
+)
+
+const testPodMetrics = `{
+  "kind": "PodMetricsList",
+  "apiVersion": "metrics.k8s.io/v1beta1",
+  "items": [{
+    "metadata": {"name": "pod-1", "namespace": "putting-fun-into-namespaces"},
+    "containers": [
+      {"name": "user-container", "usage": {"cpu": "250m", "memory": "64Mi"}},
+      {"name": "queue-proxy", "usage": {"cpu": "5m", "memory": "16Mi"}}
+    ]
+  }, {
+    "metadata": {"name": "pod-2", "namespace": "putting-fun-into-namespaces"},
+    "containers": [
+      {"name": "user-container", "usage": {"cpu": "1", "memory": "128Mi"}}
+    ]
+  }]
+}`
+
+func TestResourceScraper(t *testing.T) {
+	var gotPath, gotSelector string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotSelector = r.URL.Query().Get("labelSelector")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(testPodMetrics))
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	client, err := rest.NewRESTClient(u, "", rest.ClientContentConfig{}, nil, srv.Client())
+	if err != nil {
+		t.Fatal("NewRESTClient() =", err)
+	}
+
+	metric := func(m string) *av1alpha1.Metric {
+		return &av1alpha1.Metric{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   testNamespace,
+				Name:        testRevision,
+				Labels:      map[string]string{serving.RevisionLabelKey: testRevision},
+				Annotations: map[string]string{autoscaling.MetricAnnotationKey: m},
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		metric string
+		want   float64
+	}{{
+		metric: autoscaling.CPU,
+		want:   1255,
+	}, {
+		metric: autoscaling.Memory,
+		want:   208,
+	}} {
+		t.Run(tc.metric, func(t *testing.T) {
+			s, err := NewResourceScraper(client, metric(tc.metric))
+			if err != nil {
+				t.Fatal("NewResourceScraper() =", err)
+			}
+			got, err := s.Scrape()
+			if err != nil {
+				t.Fatal("Scrape() =", err)
+			}
+			if got != tc.want {
+				t.Errorf("Scrape() = %v, want: %v", got, tc.want)
+			}
+			if want := "/apis/metrics.k8s.io/v1beta1/namespaces/" + testNamespace + "/pods"; gotPath != want {
+				t.Errorf("Path = %q, want: %q", gotPath, want)
+			}
+			if want := serving.RevisionLabelKey + "=" + testRevision; gotSelector != want {
+				t.Errorf("LabelSelector = %q, want: %q", gotSelector, want)
+			}
+		})
+	}
+
+	if s, err := NewResourceScraper(client, metric(autoscaling.Concurrency)); s != nil || err != nil {
+		t.Errorf("NewResourceScraper(concurrency) = (%v, %v), want: (nil, nil)", s, err)
+	}
+	m := metric(autoscaling.CPU)
+	m.Labels = nil
+	if _, err := NewResourceScraper(client, m); err == nil {
+		t.Error("NewResourceScraper() = nil, wanted an error without the revision label")
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"sync"
 	"time"
 
+	"go.opencensus.io/stats"
 	"go.uber.org/zap"
 
 	"knative.dev/pkg/logging"
@@ -61,6 +62,10 @@ type autoscaler struct {
 	// specMux guards the current DeciderSpec.
 	specMux     sync.RWMutex
 	deciderSpec *DeciderSpec
+
+	// simulated autoscalers replay the recorded metrics and do not report
+	// their decisions.
+	simulated bool
 }
 
 // New creates a new instance of default autoscaler implementation.
@@ -137,6 +142,29 @@ func (a *autoscaler) Update(deciderSpec *DeciderSpec) error {
 // Scale calculates the desired scale based on current statistics given the current time.
 // desiredPodCount is the calculated pod count the autoscaler would like to set.
 // validScale signifies whether the desiredPodCount should be applied or not.
+// resourceUsage returns both the stable and the panic resource usage, gated
+// on the concurrency: with no requests the usage is reported as 0, so that the
+// revision still scales to zero, and with requests as at least the target, so
+// that it scales from zero when the activator reports requests.
+func (a *autoscaler) resourceUsage(key types.NamespacedName, now time.Time, target float64) (float64, float64, error) {
+	stableConcurrency, panicConcurrency, err := a.metricClient.StableAndPanicConcurrency(key, now)
+	if err != nil {
+		return 0, 0, err
+	}
+	stableUsage, panicUsage, err := a.metricClient.StableAndPanicResourceUsage(key, now)
+	// There is no usage to report with no pods.
+	if err != nil && err != metrics.ErrNoData {
+		return 0, 0, err
+	}
+	gate := func(usage, concurrency float64) float64 {
+		if concurrency == 0 {
+			return 0
+		}
+		return math.Max(usage, target)
+	}
+	return gate(stableUsage, stableConcurrency), gate(panicUsage, panicConcurrency), nil
+}
+
 // Scale is not thread safe in regards to panic state, but it's thread safe in
 // regards to acquiring the decider spec.
 func (a *autoscaler) Scale(ctx context.Context, now time.Time) ScaleResult {
@@ -159,12 +187,14 @@ func (a *autoscaler) Scale(ctx context.Context, now time.Time) ScaleResult {
 	switch spec.ScalingMetric {
 	case autoscaling.RPS:
 		observedStableValue, observedPanicValue, err = a.metricClient.StableAndPanicRPS(metricKey, now)
-		pkgmetrics.RecordBatch(a.reporterCtx, stableRPSM.M(observedStableValue), panicRPSM.M(observedStableValue),
+		a.record(stableRPSM.M(observedStableValue), panicRPSM.M(observedStableValue),
 			targetRPSM.M(spec.TargetValue))
+	case autoscaling.CPU, autoscaling.Memory:
+		observedStableValue, observedPanicValue, err = a.resourceUsage(metricKey, now, spec.TargetValue)
 	default:
 		metricName = autoscaling.Concurrency // concurrency is used by default
 		observedStableValue, observedPanicValue, err = a.metricClient.StableAndPanicConcurrency(metricKey, now)
-		pkgmetrics.RecordBatch(a.reporterCtx, stableRequestConcurrencyM.M(observedStableValue),
+		a.record(stableRequestConcurrencyM.M(observedStableValue),
 			panicRequestConcurrencyM.M(observedPanicValue), targetRequestConcurrencyM.M(spec.TargetValue))
 	}
 
@@ -212,7 +242,7 @@ func (a *autoscaler) Scale(ctx context.Context, now time.Time) ScaleResult {
 		// Begin panicking when we cross the threshold in the panic window.
 		logger.Info("PANICKING.")
 		a.panicTime = now
-		pkgmetrics.Record(a.reporterCtx, panicM.M(1))
+		a.record(panicM.M(1))
 	} else if isOverPanicThreshold {
 		// If we're still over panic threshold right now — extend the panic window.
 		a.panicTime = now
@@ -221,7 +251,7 @@ func (a *autoscaler) Scale(ctx context.Context, now time.Time) ScaleResult {
 		logger.Info("Un-panicking.")
 		a.panicTime = time.Time{}
 		a.maxPanicPods = 0
-		pkgmetrics.Record(a.reporterCtx, panicM.M(0))
+		a.record(panicM.M(0))
 	}
 
 	desiredPodCount := desiredStablePodCount
@@ -294,7 +324,7 @@ func (a *autoscaler) Scale(ctx context.Context, now time.Time) ScaleResult {
 		originalReadyPodsCount, a.deciderSpec.TotalValue, observedStableValue,
 		observedPanicValue, a.deciderSpec.TargetBurstCapacity, excessBCF, numAct)
 
-	pkgmetrics.RecordBatch(a.reporterCtx, excessBurstCapacityM.M(excessBCF),
+	a.record(excessBurstCapacityM.M(excessBCF),
 		desiredPodCountM.M(int64(desiredPodCount)))
 
 	return ScaleResult{
@@ -305,6 +335,13 @@ func (a *autoscaler) Scale(ctx context.Context, now time.Time) ScaleResult {
 	}
 }
 
+// record reports the measurements, unless the autoscaler is simulated.
+func (a *autoscaler) record(mss ...stats.Measurement) {
+	if !a.simulated {
+		pkgmetrics.RecordBatch(a.reporterCtx, mss...)
+	}
+}
+
 func (a *autoscaler) currentSpec() *DeciderSpec {
 	a.specMux.RLock()
 	defer a.specMux.RUnlock()
@@ -349,6 +349,30 @@ func TestAutoscalerStableModeNoTrafficScaleToZero(t *testing.T) {
 	expectScale(t, a, time.Now(), ScaleResult{0, expectedEBC(10, 75, 0, 1), na, true})
 }
 
+func TestAutoscalerResourceUsage(t *testing.T) {
+	metrics := &metricClient{
+		StableConcurrency: 1,
+		PanicConcurrency:  1,
+		StableUsage:       1200,
+		PanicUsage:        1200,
+	}
+	a, pc := newTestAutoscalerWithScalingMetric(t, 500, 0, metrics, "cpu", false /*startInPanic*/)
+	pc.readyCount = 2
+	// With no target burst capacity the activator is not kept in the path.
+	expectScale(t, a, time.Now(), ScaleResult{3, 0, MinActivators, true})
+
+	// The idle pods still use some cpu, but with no requests we scale to zero.
+	metrics.SetStableAndPanicConcurrency(0, 0)
+	metrics.StableUsage, metrics.PanicUsage = 10, 10
+	expectScale(t, a, time.Now(), ScaleResult{0, 0, MinActivators, true})
+
+	// With no pods there is no usage, but the activator reported requests.
+	pc.readyCount = 0
+	metrics.SetStableAndPanicConcurrency(1, 1)
+	metrics.StableUsage, metrics.PanicUsage = 0, 0
+	expectScale(t, a, time.Now(), ScaleResult{1, 0, MinActivators, true})
+}
+
 // QPS is increasing exponentially. Each scaling event bring concurrency
 // back to the target level (1.0) but then traffic continues to increase.
 // At 1296 QPS traffic stabilizes.
@@ -731,6 +755,8 @@ type metricClient struct {
 	PanicConcurrency  float64
 	StableRPS         float64
 	PanicRPS          float64
+	StableUsage       float64
+	PanicUsage        float64
 	ErrF              func(key types.NamespacedName, now time.Time) error
 }
 
@@ -758,3 +784,13 @@ func (mc *metricClient) StableAndPanicRPS(key types.NamespacedName, now time.Tim
 	}
 	return mc.StableRPS, mc.PanicRPS, err
 }
+
+// StableAndPanicResourceUsage returns stable/panic resource usage stored in the object
+// and the result of Errf as the error.
+func (mc *metricClient) StableAndPanicResourceUsage(key types.NamespacedName, now time.Time) (float64, float64, error) {
+	var err error
+	if mc.ErrF != nil {
+		err = mc.ErrF(key, now)
+	}
+	return mc.StableUsage, mc.PanicUsage, err
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scaling
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/types"
+
+	"knative.dev/pkg/logging"
+	"knative.dev/serving/pkg/apis/autoscaling"
+	"knative.dev/serving/pkg/autoscaler/aggregation"
+	"knative.dev/serving/pkg/autoscaler/aggregation/max"
+	"knative.dev/serving/pkg/autoscaler/config"
+	"knative.dev/serving/pkg/autoscaler/metrics"
+)
+
+// ErrSimulationUnsupported is returned when simulating the scaling on
+// a metric that is not kept in the metrics history.
+var ErrSimulationUnsupported = errors.New("only concurrency and rps based scaling can be simulated")
+
+// SimulatedScale is the desired scale the simulated autoscaler decided on.
+type SimulatedScale struct {
+	Time            time.Time
+	DesiredPodCount int32
+}
+
+// Simulate replays the history of a metric through an autoscaler configured
+// with the given spec, aggregating the metric over the spec's stable window
+// and the given panic window, and returns the desired scale at every tick.
+// The simulation presumes that the desired pods become ready right away and
+// does not apply the min and max scale bounds.
+func Simulate(history *metrics.History, spec *DeciderSpec, panicWindow time.Duration) ([]SimulatedScale, error) {
+	var series []aggregation.Bucket
+	switch spec.ScalingMetric {
+	case autoscaling.CPU, autoscaling.Memory:
+		return nil, ErrSimulationUnsupported
+	case autoscaling.RPS:
+		series = history.RPS
+	default:
+		series = history.Concurrency
+	}
+	if len(series) == 0 {
+		return nil, nil
+	}
+
+	replay := &replayMetricClient{
+		stableBuckets: aggregation.NewTimedFloat64Buckets(spec.StableWindow, config.BucketSize),
+		panicBuckets:  aggregation.NewTimedFloat64Buckets(panicWindow, config.BucketSize),
+	}
+	pods := &simulatedPodCounter{}
+	var delayer *max.TimeWindow
+	if spec.ScaleDownDelay > 0 {
+		delayer = max.NewTimeWindow(spec.ScaleDownDelay, tickInterval)
+	}
+	a := &autoscaler{
+		metricClient: replay,
+		podCounter:   pods,
+		deciderSpec:  spec,
+		delayWindow:  delayer,
+		simulated:    true,
+	}
+
+	ctx := logging.WithLogger(context.Background(), zap.NewNop().Sugar())
+	end := series[len(series)-1].Time
+	ret := make([]SimulatedScale, 0, int(end.Sub(series[0].Time)/tickInterval)+1)
+	for now, next := series[0].Time, 0; !now.After(end); now = now.Add(tickInterval) {
+		for ; next < len(series) && !series[next].Time.After(now); next++ {
+			replay.stableBuckets.Record(series[next].Time, series[next].Value)
+			replay.panicBuckets.Record(series[next].Time, series[next].Value)
+		}
+		if sr := a.Scale(ctx, now); sr.ScaleValid {
+			pods.ready = int(sr.DesiredPodCount)
+		}
+		ret = append(ret, SimulatedScale{Time: now, DesiredPodCount: int32(pods.ready)})
+	}
+	return ret, nil
+}
+
+// replayMetricClient serves the window averages of the replayed metric,
+// be it concurrency or RPS.
+type replayMetricClient struct {
+	stableBuckets, panicBuckets *aggregation.TimedFloat64Buckets
+}
+
+var _ metrics.MetricClient = (*replayMetricClient)(nil)
+
+func (c *replayMetricClient) StableAndPanicConcurrency(_ types.NamespacedName, now time.Time) (float64, float64, error) {
+	return c.stableBuckets.WindowAverage(now), c.panicBuckets.WindowAverage(now), nil
+}
+
+func (c *replayMetricClient) StableAndPanicRPS(_ types.NamespacedName, now time.Time) (float64, float64, error) {
+	return c.stableBuckets.WindowAverage(now), c.panicBuckets.WindowAverage(now), nil
+}
+
+func (c *replayMetricClient) StableAndPanicResourceUsage(types.NamespacedName, time.Time) (float64, float64, error) {
+	return 0, 0, ErrSimulationUnsupported
+}
+
+// simulatedPodCounter presumes the desired pods are ready.
+type simulatedPodCounter struct {
+	ready int
+}
+
+func (c *simulatedPodCounter) ReadyCount() (int, error) {
+	return c.ready, nil
+}
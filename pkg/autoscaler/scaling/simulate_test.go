@@ -0,0 +1,104 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scaling
+
+import (
+	"testing"
+	"time"
+
+	"knative.dev/serving/pkg/apis/autoscaling"
+	"knative.dev/serving/pkg/autoscaler/aggregation"
+	"knative.dev/serving/pkg/autoscaler/metrics"
+)
+
+func TestSimulate(t *testing.T) {
+	start := time.Now().Truncate(time.Second)
+	// Five minutes of steady load.
+	concurrency := make([]aggregation.Bucket, 5*60)
+	rps := make([]aggregation.Bucket, len(concurrency))
+	for i := range concurrency {
+		concurrency[i] = aggregation.Bucket{Time: start.Add(time.Duration(i) * time.Second), Value: 10}
+		rps[i] = aggregation.Bucket{Time: concurrency[i].Time, Value: 100}
+	}
+	history := &metrics.History{Concurrency: concurrency, RPS: rps}
+
+	spec := func(metric string, target float64) *DeciderSpec {
+		return &DeciderSpec{
+			MaxScaleUpRate:   10,
+			MaxScaleDownRate: 2,
+			ScalingMetric:    metric,
+			TargetValue:      target,
+			TotalValue:       target,
+			PanicThreshold:   2,
+			StableWindow:     time.Minute,
+			Reachable:        true,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		history *metrics.History
+		spec    *DeciderSpec
+		want    int32
+		wantErr error
+	}{{
+		name:    "concurrency",
+		history: history,
+		spec:    spec(autoscaling.Concurrency, 1),
+		want:    10,
+	}, {
+		name:    "higher concurrency target",
+		history: history,
+		spec:    spec(autoscaling.Concurrency, 2),
+		want:    5,
+	}, {
+		name:    "rps",
+		history: history,
+		spec:    spec(autoscaling.RPS, 25),
+		want:    4,
+	}, {
+		name:    "cpu",
+		history: history,
+		spec:    spec(autoscaling.CPU, 100),
+		wantErr: ErrSimulationUnsupported,
+	}, {
+		name:    "no history",
+		history: &metrics.History{},
+		spec:    spec(autoscaling.Concurrency, 1),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Simulate(test.history, test.spec, 6*time.Second)
+			if err != test.wantErr {
+				t.Fatalf("Simulate() error = %v, want: %v", err, test.wantErr)
+			}
+			if len(test.history.Concurrency) == 0 || test.wantErr != nil {
+				if len(got) != 0 {
+					t.Errorf("Simulate() = %v, want no scales", got)
+				}
+				return
+			}
+			if want := len(concurrency) / int(tickInterval/time.Second); len(got) != want {
+				t.Errorf("len(Simulate()) = %d, want: %d", len(got), want)
+			}
+			if got := got[len(got)-1].DesiredPodCount; got != test.want {
+				t.Errorf("Final DesiredPodCount = %d, want: %d", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+
+	"knative.dev/serving/pkg/autoscaler/scaling"
+)
+
+// DecidersPath is the path prefix of the endpoint serving the snapshots of
+// the deciders, as DecidersPath + "<namespace>/<name>".
+const DecidersPath = "/deciders/"
+
+// DeciderSource provides the deciders of the PodAutoscalers.
+type DeciderSource interface {
+	Get(ctx context.Context, namespace, name string) (*scaling.Decider, error)
+}
+
+// deciderJSON is the JSON representation of a scaling.Decider.
+type deciderJSON struct {
+	Namespace string                `json:"namespace"`
+	Name      string                `json:"name"`
+	Time      int64                 `json:"time"`
+	Spec      scaling.DeciderSpec   `json:"spec"`
+	Status    scaling.DeciderStatus `json:"status"`
+}
+
+// NewDecidersHandler returns the handler serving the snapshots of the
+// deciders of this autoscaler, the parameters and the latest decisions of
+// the autoscaling of the revisions, for the support bundles.
+// Only the requests allowed by authz are served.
+func NewDecidersHandler(src DeciderSource, authz Authorizer, logger *zap.SugaredLogger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		key, ok := keyFromPath(r.URL.Path, DecidersPath)
+		if !ok {
+			http.Error(w, "expected path "+DecidersPath+"<namespace>/<name>", http.StatusNotFound)
+			return
+		}
+
+		if ok, err := authz(r, key); err != nil {
+			logger.Errorw("Failed to authorize the deciders request", zap.Error(err))
+			http.Error(w, "failed to authorize the request", http.StatusInternalServerError)
+			return
+		} else if !ok {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		decider, err := src.Get(r.Context(), key.Namespace, key.Name)
+		if apierrs.IsNotFound(err) {
+			// The decider might be run by another autoscaler bucket.
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(deciderJSON{
+			Namespace: key.Namespace,
+			Name:      key.Name,
+			Time:      time.Now().Unix(),
+			Spec:      decider.Spec,
+			Status:    decider.Status,
+		})
+	})
+}
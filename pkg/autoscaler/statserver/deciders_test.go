@@ -0,0 +1,134 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	. "knative.dev/pkg/logging/testing"
+	"knative.dev/serving/pkg/autoscaler/scaling"
+)
+
+type fakeDeciderSource map[types.NamespacedName]*scaling.Decider
+
+func (f fakeDeciderSource) Get(_ context.Context, namespace, name string) (*scaling.Decider, error) {
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	if d, ok := f[key]; ok {
+		return d, nil
+	}
+	return nil, apierrs.NewNotFound(schema.GroupResource{Resource: "Deciders"}, key.String())
+}
+
+func TestDecidersHandler(t *testing.T) {
+	key := types.NamespacedName{Namespace: "ns", Name: "rev"}
+	src := fakeDeciderSource{
+		key: {
+			Spec: scaling.DeciderSpec{
+				ScalingMetric: "concurrency",
+				TargetValue:   7,
+				TotalValue:    10,
+			},
+			Status: scaling.DeciderStatus{
+				DesiredScale:        3,
+				ExcessBurstCapacity: -2,
+				NumActivators:       2,
+			},
+		},
+	}
+	allowAll := func(*http.Request, types.NamespacedName) (bool, error) { return true, nil }
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		authz    Authorizer
+		wantCode int
+		want     *deciderJSON
+	}{{
+		name:     "ok",
+		path:     DecidersPath + "ns/rev",
+		authz:    allowAll,
+		wantCode: http.StatusOK,
+		want: &deciderJSON{
+			Namespace: "ns",
+			Name:      "rev",
+			Spec:      src[key].Spec,
+			Status:    src[key].Status,
+		},
+	}, {
+		name:     "not decided here",
+		path:     DecidersPath + "ns/other",
+		authz:    allowAll,
+		wantCode: http.StatusNotFound,
+	}, {
+		name:     "malformed path",
+		path:     DecidersPath + "ns",
+		authz:    allowAll,
+		wantCode: http.StatusNotFound,
+	}, {
+		name:     "wrong method",
+		method:   http.MethodPost,
+		path:     DecidersPath + "ns/rev",
+		authz:    allowAll,
+		wantCode: http.StatusMethodNotAllowed,
+	}, {
+		name:     "forbidden",
+		path:     DecidersPath + "ns/rev",
+		authz:    func(*http.Request, types.NamespacedName) (bool, error) { return false, nil },
+		wantCode: http.StatusForbidden,
+	}, {
+		name:     "authorizer error",
+		path:     DecidersPath + "ns/rev",
+		authz:    func(*http.Request, types.NamespacedName) (bool, error) { return false, errors.New("boom") },
+		wantCode: http.StatusInternalServerError,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			rec := httptest.NewRecorder()
+			NewDecidersHandler(src, tc.authz, TestLogger(t)).ServeHTTP(rec, httptest.NewRequest(method, tc.path, nil))
+			if rec.Code != tc.wantCode {
+				t.Fatalf("Code = %d, want: %d, body: %s", rec.Code, tc.wantCode, rec.Body.String())
+			}
+			if tc.want == nil {
+				return
+			}
+			got := &deciderJSON{}
+			if err := json.Unmarshal(rec.Body.Bytes(), got); err != nil {
+				t.Fatal("Failed to unmarshal the response:", err)
+			}
+			got.Time = 0
+			if !cmp.Equal(got, tc.want) {
+				t.Error("Response (-want,+got):", cmp.Diff(tc.want, got))
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"knative.dev/pkg/system"
+)
+
+// ReleasePath is the path prefix of the endpoint forcing a new election of a
+// bucket by deleting its Lease, as ReleasePath + "<lease name>". This drains
+// the leaders that got stuck without restarting every replica.
+// The request must be a POST.
+const ReleasePath = "/buckets/release/"
+
+// releaseJSON is the JSON representation of a released bucket.
+type releaseJSON struct {
+	Bucket string `json:"bucket"`
+	// Holder is the identity of the holder the bucket was released from.
+	Holder string `json:"holder"`
+}
+
+// NewReleaseHandler returns the handler releasing the buckets, out of the
+// Leases in the system namespace accepted by isBucket.
+// Only the requests allowed by authz are served.
+func NewReleaseHandler(kc kubernetes.Interface, isBucket func(name string) bool, authz Authorizer, logger *zap.SugaredLogger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		name := strings.TrimPrefix(r.URL.Path, ReleasePath)
+		if name == "" || strings.Contains(name, "/") || !isBucket(name) {
+			http.Error(w, "expected path "+ReleasePath+"<bucket>", http.StatusNotFound)
+			return
+		}
+		key := types.NamespacedName{Namespace: system.Namespace(), Name: name}
+
+		if ok, err := authz(r, key); err != nil {
+			logger.Errorw("Failed to authorize the release request", zap.Error(err))
+			http.Error(w, "failed to authorize the request", http.StatusInternalServerError)
+			return
+		} else if !ok {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		leases := kc.CoordinationV1().Leases(key.Namespace)
+		lease, err := leases.Get(r.Context(), key.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			http.Error(w, "bucket not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Only delete the Lease we read, rather than one the bucket was
+		// already elected again with.
+		if err := leases.Delete(r.Context(), key.Name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{
+				UID:             &lease.UID,
+				ResourceVersion: &lease.ResourceVersion,
+			},
+		}); apierrors.IsConflict(err) {
+			http.Error(w, "the bucket changed hands, retry", http.StatusConflict)
+			return
+		} else if err != nil && !apierrors.IsNotFound(err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		resp := releaseJSON{Bucket: key.Name}
+		if lease.Spec.HolderIdentity != nil {
+			resp.Holder = *lease.Spec.HolderIdentity
+		}
+		logger.Infow("Released bucket", zap.String("bucket", resp.Bucket), zap.String("holder", resp.Holder))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
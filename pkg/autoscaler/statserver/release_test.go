@@ -0,0 +1,167 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	authnv1 "k8s.io/api/authentication/v1"
+	authzv1 "k8s.io/api/authorization/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	fakek8s "k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
+	"knative.dev/pkg/ptr"
+	"knative.dev/pkg/system"
+
+	. "knative.dev/pkg/logging/testing"
+	_ "knative.dev/pkg/system/testing"
+)
+
+func TestReleaseHandler(t *testing.T) {
+	const bkt = "autoscaler-bucket-00-of-01"
+	isBucket := func(name string) bool { return strings.HasPrefix(name, "autoscaler-bucket") }
+	allow := func(*http.Request, types.NamespacedName) (bool, error) { return true, nil }
+	deny := func(*http.Request, types.NamespacedName) (bool, error) { return false, nil }
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		authz      Authorizer
+		lease      bool
+		wantStatus int
+		want       *releaseJSON
+	}{{
+		name:       "not a post",
+		method:     http.MethodGet,
+		path:       ReleasePath + bkt,
+		authz:      allow,
+		lease:      true,
+		wantStatus: http.StatusMethodNotAllowed,
+	}, {
+		name:       "not a bucket",
+		method:     http.MethodPost,
+		path:       ReleasePath + "some-lease",
+		authz:      allow,
+		wantStatus: http.StatusNotFound,
+	}, {
+		name:       "forbidden",
+		method:     http.MethodPost,
+		path:       ReleasePath + bkt,
+		authz:      deny,
+		lease:      true,
+		wantStatus: http.StatusForbidden,
+	}, {
+		name:       "no lease",
+		method:     http.MethodPost,
+		path:       ReleasePath + bkt,
+		authz:      allow,
+		wantStatus: http.StatusNotFound,
+	}, {
+		name:       "released",
+		method:     http.MethodPost,
+		path:       ReleasePath + bkt,
+		authz:      allow,
+		lease:      true,
+		wantStatus: http.StatusOK,
+		want: &releaseJSON{
+			Bucket: bkt,
+			Holder: "autoscaler-1_10.0.0.1",
+		},
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			kc := fakek8s.NewSimpleClientset()
+			if tc.lease {
+				kc.CoordinationV1().Leases(system.Namespace()).Create(context.Background(), &coordinationv1.Lease{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      bkt,
+						Namespace: system.Namespace(),
+					},
+					Spec: coordinationv1.LeaseSpec{
+						HolderIdentity: ptr.String("autoscaler-1_10.0.0.1"),
+					},
+				}, metav1.CreateOptions{})
+			}
+
+			rec := httptest.NewRecorder()
+			NewReleaseHandler(kc, isBucket, tc.authz, TestLogger(t)).ServeHTTP(rec,
+				httptest.NewRequest(tc.method, tc.path, nil))
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("Status = %d, want: %d, body: %s", rec.Code, tc.wantStatus, rec.Body)
+			}
+			_, err := kc.CoordinationV1().Leases(system.Namespace()).Get(context.Background(), bkt, metav1.GetOptions{})
+			if tc.want == nil {
+				if tc.lease && err != nil {
+					t.Error("The lease was deleted:", err)
+				}
+				return
+			}
+			if !apierrors.IsNotFound(err) {
+				t.Error("The lease was not deleted:", err)
+			}
+			var got releaseJSON
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal("Failed to unmarshal the response:", err)
+			}
+			if !cmp.Equal(&got, tc.want) {
+				t.Errorf("Response (-want, +got) =\n%s", cmp.Diff(tc.want, &got))
+			}
+		})
+	}
+}
+
+func TestKubeLeaseAuthorizer(t *testing.T) {
+	key := types.NamespacedName{Namespace: system.Namespace(), Name: "autoscaler-bucket-00-of-01"}
+	kc := fakek8s.NewSimpleClientset()
+	kc.PrependReactor("create", "tokenreviews", func(a clientgotesting.Action) (bool, runtime.Object, error) {
+		tr := a.(clientgotesting.CreateAction).GetObject().(*authnv1.TokenReview)
+		tr.Status.Authenticated = true
+		tr.Status.User = authnv1.UserInfo{Username: "admin"}
+		return true, tr, nil
+	})
+	kc.PrependReactor("create", "subjectaccessreviews", func(a clientgotesting.Action) (bool, runtime.Object, error) {
+		sar := a.(clientgotesting.CreateAction).GetObject().(*authzv1.SubjectAccessReview)
+		want := &authzv1.ResourceAttributes{
+			Namespace: key.Namespace,
+			Name:      key.Name,
+			Verb:      "delete",
+			Group:     coordinationv1.GroupName,
+			Resource:  "leases",
+		}
+		sar.Status.Allowed = cmp.Equal(sar.Spec.ResourceAttributes, want)
+		return true, sar, nil
+	})
+
+	r := httptest.NewRequest(http.MethodPost, ReleasePath+key.Name, nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	if got, err := KubeLeaseAuthorizer(kc)(r, key); err != nil || !got {
+		t.Errorf("KubeLeaseAuthorizer() = %v, %v, want: true", got, err)
+	}
+}
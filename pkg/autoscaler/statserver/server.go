@@ -44,6 +44,7 @@ var isBucketHost = bucket.IsBucketHost
 type Server struct {
 	addr        string
 	wsSrv       http.Server
+	mux         *http.ServeMux
 	servingCh   chan struct{}
 	stopCh      chan struct{}
 	statsCh     chan<- metrics.StatMessage
@@ -64,16 +65,21 @@ func New(statsServerAddr string, statsCh chan<- metrics.StatMessage, logger *zap
 		logger:      logger.Named("stats-websocket-server").With("address", statsServerAddr),
 	}
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", svr.Handler)
+	svr.mux = http.NewServeMux()
+	svr.mux.HandleFunc("/", svr.Handler)
 	svr.wsSrv = http.Server{
 		Addr:      statsServerAddr,
-		Handler:   mux,
+		Handler:   svr.mux,
 		ConnState: svr.onConnStateChange,
 	}
 	return &svr
 }
 
+// Handle registers an additional handler for the given pattern.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
 func (s *Server) onConnStateChange(conn net.Conn, state http.ConnState) {
 	if state == http.StateNew {
 		tcpConn := conn.(*net.TCPConn)
@@ -0,0 +1,184 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	"knative.dev/serving/pkg/autoscaler/metrics"
+	"knative.dev/serving/pkg/autoscaler/scaling"
+)
+
+// SimulatePath is the path prefix of the endpoint simulating how the
+// revision would have scaled over the metrics history under a proposed
+// change of the autoscaler configuration, as SimulatePath + "<namespace>/<name>".
+// The change is POSTed as a JSON object of the changed config-autoscaler keys.
+const SimulatePath = "/simulate/"
+
+// maxSimulateBodyBytes bounds the size of the proposed configuration change.
+const maxSimulateBodyBytes = 64 << 10
+
+// ErrInvalidConfig is returned by SimulationSpecs when the autoscaler
+// configuration with the proposed change applied is invalid.
+var ErrInvalidConfig = errors.New("invalid autoscaler configuration")
+
+// HistorySource provides the history of the metrics.
+type HistorySource interface {
+	History(key types.NamespacedName, now time.Time) (*metrics.History, error)
+}
+
+// SimulationSpec is the configuration the revision is scaled with.
+type SimulationSpec struct {
+	Decider     *scaling.DeciderSpec
+	PanicWindow time.Duration
+}
+
+// SimulationSpecs returns the configuration the revision is scaled with
+// currently and the one it would be scaled with after the proposed change
+// to the autoscaler configuration.
+type SimulationSpecs func(ctx context.Context, key types.NamespacedName, change map[string]string) (current, proposed *SimulationSpec, err error)
+
+// simulationSummaryJSON summarizes how the desired scale would differ.
+type simulationSummaryJSON struct {
+	CurrentMax      int32   `json:"currentMax"`
+	ProposedMax     int32   `json:"proposedMax"`
+	CurrentAverage  float64 `json:"currentAverage"`
+	ProposedAverage float64 `json:"proposedAverage"`
+	// DifferingTicks is the number of the ticks the desired scales differ at.
+	DifferingTicks int `json:"differingTicks"`
+}
+
+// simulationJSON is the JSON representation of the simulation results.
+type simulationJSON struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Current and Proposed are the [unix time in seconds, desired scale] pairs.
+	Current  [][2]int64            `json:"current"`
+	Proposed [][2]int64            `json:"proposed"`
+	Summary  simulationSummaryJSON `json:"summary"`
+}
+
+func makeSimulationJSON(key types.NamespacedName, current, proposed []scaling.SimulatedScale) simulationJSON {
+	ret := simulationJSON{
+		Namespace: key.Namespace,
+		Name:      key.Name,
+		Current:   make([][2]int64, 0, len(current)),
+		Proposed:  make([][2]int64, 0, len(proposed)),
+	}
+	var currentSum, proposedSum int64
+	for i := range current {
+		c, p := current[i], proposed[i]
+		ret.Current = append(ret.Current, [2]int64{c.Time.Unix(), int64(c.DesiredPodCount)})
+		ret.Proposed = append(ret.Proposed, [2]int64{p.Time.Unix(), int64(p.DesiredPodCount)})
+		if c.DesiredPodCount > ret.Summary.CurrentMax {
+			ret.Summary.CurrentMax = c.DesiredPodCount
+		}
+		if p.DesiredPodCount > ret.Summary.ProposedMax {
+			ret.Summary.ProposedMax = p.DesiredPodCount
+		}
+		if c.DesiredPodCount != p.DesiredPodCount {
+			ret.Summary.DifferingTicks++
+		}
+		currentSum += int64(c.DesiredPodCount)
+		proposedSum += int64(p.DesiredPodCount)
+	}
+	if len(current) > 0 {
+		ret.Summary.CurrentAverage = float64(currentSum) / float64(len(current))
+		ret.Summary.ProposedAverage = float64(proposedSum) / float64(len(proposed))
+	}
+	return ret
+}
+
+// NewSimulateHandler returns the handler replaying the metrics history of
+// the revisions collected by this autoscaler under both the current and the
+// proposed autoscaler configuration, for the operators to validate window
+// and target changes before rolling them out.
+// Only the requests allowed by authz are served.
+func NewSimulateHandler(src HistorySource, specs SimulationSpecs, authz Authorizer, logger *zap.SugaredLogger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		key, ok := keyFromPath(r.URL.Path, SimulatePath)
+		if !ok {
+			http.Error(w, "expected path "+SimulatePath+"<namespace>/<name>", http.StatusNotFound)
+			return
+		}
+
+		if ok, err := authz(r, key); err != nil {
+			logger.Errorw("Failed to authorize the simulate request", zap.Error(err))
+			http.Error(w, "failed to authorize the request", http.StatusInternalServerError)
+			return
+		} else if !ok {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		var change map[string]string
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSimulateBodyBytes)).Decode(&change); err != nil {
+			http.Error(w, "expected a JSON object of the changed config-autoscaler keys: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		history, err := src.History(key, time.Now())
+		if errors.Is(err, metrics.ErrNotCollecting) {
+			// The metric might be collected by another autoscaler bucket.
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		current, proposed, err := specs(r.Context(), key, change)
+		switch {
+		case errors.Is(err, ErrInvalidConfig):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case apierrors.IsNotFound(err):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case err != nil:
+			logger.Errorw("Failed to compute the simulation specs", zap.Error(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		currentScales, err := scaling.Simulate(history, current.Decider, current.PanicWindow)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		proposedScales, err := scaling.Simulate(history, proposed.Decider, proposed.PanicWindow)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(makeSimulationJSON(key, currentScales, proposedScales))
+	})
+}
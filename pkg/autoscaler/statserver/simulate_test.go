@@ -0,0 +1,166 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	. "knative.dev/pkg/logging/testing"
+	"knative.dev/serving/pkg/autoscaler/aggregation"
+	"knative.dev/serving/pkg/autoscaler/metrics"
+	"knative.dev/serving/pkg/autoscaler/scaling"
+)
+
+type fakeHistorySource map[types.NamespacedName]*metrics.History
+
+func (f fakeHistorySource) History(key types.NamespacedName, _ time.Time) (*metrics.History, error) {
+	if h, ok := f[key]; ok {
+		return h, nil
+	}
+	return nil, metrics.ErrNotCollecting
+}
+
+func TestSimulateHandler(t *testing.T) {
+	key := types.NamespacedName{Namespace: "ns", Name: "rev"}
+	start := time.Unix(1600000000, 0)
+	concurrency := make([]aggregation.Bucket, 5*60)
+	for i := range concurrency {
+		concurrency[i] = aggregation.Bucket{Time: start.Add(time.Duration(i) * time.Second), Value: 10}
+	}
+	src := fakeHistorySource{key: {Concurrency: concurrency}}
+	allowAll := func(*http.Request, types.NamespacedName) (bool, error) { return true, nil }
+
+	spec := func(target float64) *SimulationSpec {
+		return &SimulationSpec{
+			Decider: &scaling.DeciderSpec{
+				MaxScaleUpRate:   10,
+				MaxScaleDownRate: 2,
+				TargetValue:      target,
+				TotalValue:       target,
+				PanicThreshold:   2,
+				StableWindow:     time.Minute,
+				Reachable:        true,
+			},
+			PanicWindow: 6 * time.Second,
+		}
+	}
+	// The proposed target is taken from the change.
+	specs := func(_ context.Context, _ types.NamespacedName, change map[string]string) (*SimulationSpec, *SimulationSpec, error) {
+		target, err := strconv.ParseFloat(change["container-concurrency-target-default"], 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+		return spec(1), spec(target), nil
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		authz    Authorizer
+		wantCode int
+		want     *simulationSummaryJSON
+	}{{
+		name:     "ok",
+		path:     SimulatePath + "ns/rev",
+		body:     `{"container-concurrency-target-default": "2"}`,
+		authz:    allowAll,
+		wantCode: http.StatusOK,
+		want: &simulationSummaryJSON{
+			CurrentMax:  10,
+			ProposedMax: 5,
+		},
+	}, {
+		name:     "invalid config",
+		path:     SimulatePath + "ns/rev",
+		body:     `{"container-concurrency-target-default": "many"}`,
+		authz:    allowAll,
+		wantCode: http.StatusBadRequest,
+	}, {
+		name:     "malformed body",
+		path:     SimulatePath + "ns/rev",
+		body:     `["stable-window"]`,
+		authz:    allowAll,
+		wantCode: http.StatusBadRequest,
+	}, {
+		name:     "not collected here",
+		path:     SimulatePath + "ns/other",
+		body:     `{}`,
+		authz:    allowAll,
+		wantCode: http.StatusNotFound,
+	}, {
+		name:     "malformed path",
+		path:     SimulatePath + "ns",
+		body:     `{}`,
+		authz:    allowAll,
+		wantCode: http.StatusNotFound,
+	}, {
+		name:     "wrong method",
+		method:   http.MethodGet,
+		path:     SimulatePath + "ns/rev",
+		authz:    allowAll,
+		wantCode: http.StatusMethodNotAllowed,
+	}, {
+		name:     "forbidden",
+		path:     SimulatePath + "ns/rev",
+		body:     `{}`,
+		authz:    func(*http.Request, types.NamespacedName) (bool, error) { return false, nil },
+		wantCode: http.StatusForbidden,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			rec := httptest.NewRecorder()
+			NewSimulateHandler(src, specs, tc.authz, TestLogger(t)).ServeHTTP(rec,
+				httptest.NewRequest(method, tc.path, strings.NewReader(tc.body)))
+			if rec.Code != tc.wantCode {
+				t.Fatalf("Code = %d, want: %d, body: %s", rec.Code, tc.wantCode, rec.Body.String())
+			}
+			if tc.want == nil {
+				return
+			}
+			got := &simulationJSON{}
+			if err := json.Unmarshal(rec.Body.Bytes(), got); err != nil {
+				t.Fatal("Failed to unmarshal the response:", err)
+			}
+			if len(got.Current) == 0 || len(got.Current) != len(got.Proposed) {
+				t.Errorf("len(Current) = %d, len(Proposed) = %d, want equal and non zero", len(got.Current), len(got.Proposed))
+			}
+			if got.Summary.CurrentMax != tc.want.CurrentMax || got.Summary.ProposedMax != tc.want.ProposedMax {
+				t.Errorf("Summary = %+v, want max scales: %d and %d", got.Summary, tc.want.CurrentMax, tc.want.ProposedMax)
+			}
+			if got.Summary.DifferingTicks == 0 {
+				t.Error("DifferingTicks = 0, want the scales to differ")
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	authnv1 "k8s.io/api/authentication/v1"
+	authzv1 "k8s.io/api/authorization/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"knative.dev/serving/pkg/apis/autoscaling"
+	"knative.dev/serving/pkg/autoscaler/metrics"
+)
+
+// WindowsPath is the path prefix of the endpoint serving the stable and
+// panic window data of the metrics, as WindowsPath + "<namespace>/<name>".
+const WindowsPath = "/windows/"
+
+// WindowsSource provides the window data of the metrics.
+type WindowsSource interface {
+	WindowsSnapshot(key types.NamespacedName, now time.Time) (*metrics.WindowsSnapshot, error)
+}
+
+// Authorizer returns whether the request is allowed to access the object with the key,
+// e.g. read the data of the metric.
+type Authorizer func(r *http.Request, key types.NamespacedName) (bool, error)
+
+// windowJSON is the JSON representation of a single window.
+type windowJSON struct {
+	Average float64 `json:"average"`
+	// Buckets are the [unix time in seconds, value] pairs, oldest first.
+	Buckets [][2]float64 `json:"buckets"`
+}
+
+// seriesJSON is the JSON representation of both windows of a metric.
+type seriesJSON struct {
+	Stable windowJSON `json:"stable"`
+	Panic  windowJSON `json:"panic"`
+}
+
+// windowsJSON is the JSON representation of a metrics.WindowsSnapshot.
+type windowsJSON struct {
+	Namespace           string     `json:"namespace"`
+	Name                string     `json:"name"`
+	Time                int64      `json:"time"`
+	StableWindowSeconds float64    `json:"stableWindowSeconds"`
+	PanicWindowSeconds  float64    `json:"panicWindowSeconds"`
+	Concurrency         seriesJSON `json:"concurrency"`
+	RPS                 seriesJSON `json:"rps"`
+}
+
+func makeWindowJSON(wd metrics.WindowData) windowJSON {
+	ret := windowJSON{
+		Average: wd.Average,
+		Buckets: make([][2]float64, 0, len(wd.Buckets)),
+	}
+	for _, b := range wd.Buckets {
+		ret.Buckets = append(ret.Buckets, [2]float64{float64(b.Time.Unix()), b.Value})
+	}
+	return ret
+}
+
+// NewWindowsHandler returns the handler serving the stable and panic window
+// data of the metrics collected by this autoscaler, for the dashboards to
+// render what the autoscaler saw next to what it decided.
+// Only the requests allowed by authz are served.
+func NewWindowsHandler(src WindowsSource, authz Authorizer, logger *zap.SugaredLogger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		key, ok := keyFromPath(r.URL.Path, WindowsPath)
+		if !ok {
+			http.Error(w, "expected path "+WindowsPath+"<namespace>/<name>", http.StatusNotFound)
+			return
+		}
+
+		if ok, err := authz(r, key); err != nil {
+			logger.Errorw("Failed to authorize the windows request", zap.Error(err))
+			http.Error(w, "failed to authorize the request", http.StatusInternalServerError)
+			return
+		} else if !ok {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		now := time.Now()
+		snap, err := src.WindowsSnapshot(key, now)
+		if errors.Is(err, metrics.ErrNotCollecting) {
+			// The metric might be collected by another autoscaler bucket.
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(windowsJSON{
+			Namespace:           key.Namespace,
+			Name:                key.Name,
+			Time:                now.Unix(),
+			StableWindowSeconds: snap.StableWindow.Seconds(),
+			PanicWindowSeconds:  snap.PanicWindow.Seconds(),
+			Concurrency: seriesJSON{
+				Stable: makeWindowJSON(snap.Concurrency),
+				Panic:  makeWindowJSON(snap.PanicConcurrency),
+			},
+			RPS: seriesJSON{
+				Stable: makeWindowJSON(snap.RPS),
+				Panic:  makeWindowJSON(snap.PanicRPS),
+			},
+		})
+	})
+}
+
+// keyFromPath returns the key of the metric from the path prefix + "<namespace>/<name>".
+func keyFromPath(path, prefix string) (types.NamespacedName, bool) {
+	parts := strings.Split(strings.TrimPrefix(path, prefix), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: parts[0], Name: parts[1]}, true
+}
+
+// KubeAuthorizer returns an Authorizer that authenticates the bearer token of
+// the request via the TokenReview API and allows the users that may get the
+// Metric resource via the SubjectAccessReview API.
+func KubeAuthorizer(kc kubernetes.Interface) Authorizer {
+	return kubeAuthorizer(kc, func(key types.NamespacedName) *authzv1.ResourceAttributes {
+		return &authzv1.ResourceAttributes{
+			Namespace: key.Namespace,
+			Name:      key.Name,
+			Verb:      "get",
+			Group:     autoscaling.InternalGroupName,
+			Resource:  "metrics",
+		}
+	})
+}
+
+// KubeLeaseAuthorizer returns an Authorizer like KubeAuthorizer, which allows
+// the users that may delete the Lease resource.
+func KubeLeaseAuthorizer(kc kubernetes.Interface) Authorizer {
+	return kubeAuthorizer(kc, func(key types.NamespacedName) *authzv1.ResourceAttributes {
+		return &authzv1.ResourceAttributes{
+			Namespace: key.Namespace,
+			Name:      key.Name,
+			Verb:      "delete",
+			Group:     coordinationv1.GroupName,
+			Resource:  "leases",
+		}
+	})
+}
+
+func kubeAuthorizer(kc kubernetes.Interface, attrs func(types.NamespacedName) *authzv1.ResourceAttributes) Authorizer {
+	return func(r *http.Request, key types.NamespacedName) (bool, error) {
+		const prefix = "Bearer "
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, prefix) {
+			return false, nil
+		}
+		tr, err := kc.AuthenticationV1().TokenReviews().Create(r.Context(), &authnv1.TokenReview{
+			Spec: authnv1.TokenReviewSpec{
+				Token: strings.TrimPrefix(auth, prefix),
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return false, err
+		}
+		if !tr.Status.Authenticated {
+			return false, nil
+		}
+
+		user := tr.Status.User
+		extra := make(map[string]authzv1.ExtraValue, len(user.Extra))
+		for k, v := range user.Extra {
+			extra[k] = authzv1.ExtraValue(v)
+		}
+		sar, err := kc.AuthorizationV1().SubjectAccessReviews().Create(r.Context(), &authzv1.SubjectAccessReview{
+			Spec: authzv1.SubjectAccessReviewSpec{
+				User:               user.Username,
+				Groups:             user.Groups,
+				UID:                user.UID,
+				Extra:              extra,
+				ResourceAttributes: attrs(key),
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return false, err
+		}
+		return sar.Status.Allowed, nil
+	}
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	authnv1 "k8s.io/api/authentication/v1"
+	authzv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	fakek8s "k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
+
+	. "knative.dev/pkg/logging/testing"
+	"knative.dev/serving/pkg/apis/autoscaling"
+	"knative.dev/serving/pkg/autoscaler/aggregation"
+	"knative.dev/serving/pkg/autoscaler/metrics"
+)
+
+type fakeWindowsSource map[types.NamespacedName]*metrics.WindowsSnapshot
+
+func (f fakeWindowsSource) WindowsSnapshot(key types.NamespacedName, _ time.Time) (*metrics.WindowsSnapshot, error) {
+	if s, ok := f[key]; ok {
+		return s, nil
+	}
+	return nil, metrics.ErrNotCollecting
+}
+
+func TestWindowsHandler(t *testing.T) {
+	key := types.NamespacedName{Namespace: "ns", Name: "rev"}
+	ts := time.Unix(1600000000, 0)
+	src := fakeWindowsSource{
+		key: {
+			StableWindow: 60 * time.Second,
+			PanicWindow:  6 * time.Second,
+			Concurrency: metrics.WindowData{
+				Average: 1.5,
+				Buckets: []aggregation.Bucket{{Time: ts, Value: 1}, {Time: ts.Add(time.Second), Value: 2}},
+			},
+			PanicConcurrency: metrics.WindowData{
+				Average: 2,
+				Buckets: []aggregation.Bucket{{Time: ts.Add(time.Second), Value: 2}},
+			},
+		},
+	}
+	allowAll := func(*http.Request, types.NamespacedName) (bool, error) { return true, nil }
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		authz    Authorizer
+		wantCode int
+		want     *windowsJSON
+	}{{
+		name:     "ok",
+		path:     WindowsPath + "ns/rev",
+		authz:    allowAll,
+		wantCode: http.StatusOK,
+		want: &windowsJSON{
+			Namespace:           "ns",
+			Name:                "rev",
+			StableWindowSeconds: 60,
+			PanicWindowSeconds:  6,
+			Concurrency: seriesJSON{
+				Stable: windowJSON{Average: 1.5, Buckets: [][2]float64{{1600000000, 1}, {1600000001, 2}}},
+				Panic:  windowJSON{Average: 2, Buckets: [][2]float64{{1600000001, 2}}},
+			},
+			RPS: seriesJSON{
+				Stable: windowJSON{Buckets: [][2]float64{}},
+				Panic:  windowJSON{Buckets: [][2]float64{}},
+			},
+		},
+	}, {
+		name:     "not collected here",
+		path:     WindowsPath + "ns/other",
+		authz:    allowAll,
+		wantCode: http.StatusNotFound,
+	}, {
+		name:     "malformed path",
+		path:     WindowsPath + "ns",
+		authz:    allowAll,
+		wantCode: http.StatusNotFound,
+	}, {
+		name:     "wrong method",
+		method:   http.MethodPost,
+		path:     WindowsPath + "ns/rev",
+		authz:    allowAll,
+		wantCode: http.StatusMethodNotAllowed,
+	}, {
+		name:     "forbidden",
+		path:     WindowsPath + "ns/rev",
+		authz:    func(*http.Request, types.NamespacedName) (bool, error) { return false, nil },
+		wantCode: http.StatusForbidden,
+	}, {
+		name:     "authorizer error",
+		path:     WindowsPath + "ns/rev",
+		authz:    func(*http.Request, types.NamespacedName) (bool, error) { return false, errors.New("boom") },
+		wantCode: http.StatusInternalServerError,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			rec := httptest.NewRecorder()
+			NewWindowsHandler(src, tc.authz, TestLogger(t)).ServeHTTP(rec, httptest.NewRequest(method, tc.path, nil))
+			if rec.Code != tc.wantCode {
+				t.Fatalf("Code = %d, want: %d, body: %s", rec.Code, tc.wantCode, rec.Body.String())
+			}
+			if tc.want == nil {
+				return
+			}
+			got := &windowsJSON{}
+			if err := json.Unmarshal(rec.Body.Bytes(), got); err != nil {
+				t.Fatal("Failed to unmarshal the response:", err)
+			}
+			got.Time = 0
+			if !cmp.Equal(got, tc.want) {
+				t.Error("Response (-want,+got):", cmp.Diff(tc.want, got))
+			}
+		})
+	}
+}
+
+func TestKubeAuthorizer(t *testing.T) {
+	key := types.NamespacedName{Namespace: "ns", Name: "rev"}
+	tests := []struct {
+		name          string
+		header        string
+		authenticated bool
+		allowed       bool
+		want          bool
+	}{{
+		name: "no token",
+	}, {
+		name:   "not authenticated",
+		header: "Bearer secret",
+	}, {
+		name:          "not allowed",
+		header:        "Bearer secret",
+		authenticated: true,
+	}, {
+		name:          "allowed",
+		header:        "Bearer secret",
+		authenticated: true,
+		allowed:       true,
+		want:          true,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			kc := fakek8s.NewSimpleClientset()
+			kc.PrependReactor("create", "tokenreviews", func(a clientgotesting.Action) (bool, runtime.Object, error) {
+				tr := a.(clientgotesting.CreateAction).GetObject().(*authnv1.TokenReview)
+				if tr.Spec.Token != "secret" {
+					t.Errorf("Token = %q, want: secret", tr.Spec.Token)
+				}
+				tr.Status.Authenticated = tc.authenticated
+				tr.Status.User = authnv1.UserInfo{Username: "dashboard"}
+				return true, tr, nil
+			})
+			kc.PrependReactor("create", "subjectaccessreviews", func(a clientgotesting.Action) (bool, runtime.Object, error) {
+				sar := a.(clientgotesting.CreateAction).GetObject().(*authzv1.SubjectAccessReview)
+				want := &authzv1.ResourceAttributes{
+					Namespace: key.Namespace,
+					Name:      key.Name,
+					Verb:      "get",
+					Group:     autoscaling.InternalGroupName,
+					Resource:  "metrics",
+				}
+				if !cmp.Equal(sar.Spec.ResourceAttributes, want) || sar.Spec.User != "dashboard" {
+					t.Errorf("Unexpected SubjectAccessReview: %#v", sar.Spec)
+				}
+				sar.Status.Allowed = tc.allowed
+				return true, sar, nil
+			})
+
+			r := httptest.NewRequest(http.MethodGet, WindowsPath+"ns/rev", nil)
+			if tc.header != "" {
+				r.Header.Set("Authorization", tc.header)
+			}
+			got, err := KubeAuthorizer(kc)(r, key)
+			if err != nil {
+				t.Fatal("KubeAuthorizer() =", err)
+			}
+			if got != tc.want {
+				t.Errorf("KubeAuthorizer() = %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
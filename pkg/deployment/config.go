@@ -17,15 +17,33 @@ limitations under the License.
 package deployment
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	cm "knative.dev/pkg/configmap"
+	"knative.dev/serving/pkg/apis/serving"
 )
 
 const (
@@ -48,6 +66,36 @@ const (
 	// digestResolutionTimeoutDefault is the default digest resolution timeout.
 	digestResolutionTimeoutDefault = 10 * time.Second
 
+	// digestResolutionCABundlePathKey is the key to configure the path of a
+	// PEM bundle, mounted into the controller from a Secret or a ConfigMap,
+	// of the additional CAs the digest resolution trusts.
+	digestResolutionCABundlePathKey = "digestResolutionCABundlePath"
+
+	// digestResolutionProxyKey is the key to configure the URL of the proxy
+	// the digest resolution goes through, instead of the one of the
+	// HTTPS_PROXY and NO_PROXY environment variables of the controller.
+	digestResolutionProxyKey = "digestResolutionProxy"
+
+	// digestResolutionRetriesKey and digestResolutionBackoffKey are the keys
+	// to configure how many times, and after how long initially, the digest
+	// resolution is retried on transient registry errors.
+	digestResolutionRetriesKey = "digestResolutionRetries"
+	digestResolutionBackoffKey = "digestResolutionBackoff"
+
+	// digestResolutionRetriesDefault and digestResolutionBackoffDefault are
+	// the default retries of the digest resolution, doubling the backoff.
+	digestResolutionRetriesDefault = 2
+	digestResolutionBackoffDefault = 500 * time.Millisecond
+
+	// digestResolutionCacheTTLKey is the key to configure how long the
+	// resolved digests are reused for the same image tag and credentials.
+	digestResolutionCacheTTLKey = "digestResolutionCacheTTL"
+
+	// digestResolutionSignaturePublicKeysKey is the key to configure the PEM
+	// public keys, one of which must have signed the cosign signature of the
+	// resolved images.
+	digestResolutionSignaturePublicKeysKey = "digestResolutionSignaturePublicKeys"
+
 	// registriesSkippingTagResolvingKey is the config map key for the set of registries
 	// (e.g. ko.local) where tags should not be resolved to digests.
 	registriesSkippingTagResolvingKey = "registriesSkippingTagResolving"
@@ -61,8 +109,169 @@ const (
 	queueSidecarCPULimitKey              = "queueSidecarCPULimit"
 	queueSidecarMemoryLimitKey           = "queueSidecarMemoryLimit"
 	queueSidecarEphemeralStorageLimitKey = "queueSidecarEphemeralStorageLimit"
+
+	// queueSidecar readiness probe timing keys.
+	queueSidecarProbePeriodKey        = "queueSidecarProbePeriod"
+	queueSidecarProbeTimeoutKey       = "queueSidecarProbeTimeout"
+	queueSidecarProbeBackoffFactorKey = "queueSidecarProbeBackoffFactor"
+	queueSidecarProbeMaxPeriodKey     = "queueSidecarProbeMaxPeriod"
+
+	// The queue sidecar readiness probe timing defaults.
+	queueSidecarProbePeriodDefault        = 50 * time.Millisecond
+	queueSidecarProbeTimeoutDefault       = 100 * time.Millisecond
+	queueSidecarProbeBackoffFactorDefault = 1.0
+	queueSidecarProbeMaxPeriodDefault     = time.Second
+
+	// queueSidecar transport keys, tuning the connections the queue sidecar
+	// keeps to the user container.
+	queueSidecarMaxIdleConnsPerHostKey = "queueSidecarMaxIdleConnsPerHost"
+	queueSidecarTLSHandshakeTimeoutKey = "queueSidecarTLSHandshakeTimeout"
+	queueSidecarDisableKeepAlivesKey   = "queueSidecarDisableKeepAlives"
+
+	// queueSidecarEnvKey is the config map key for the JSON object of the
+	// extra environment variables of the queue sidecar, mapping their names
+	// to templates of their values, see QueueSidecarTemplateData.
+	queueSidecarEnvKey = "queueSidecarEnv"
+
+	// queueSidecarLabelsKey is the config map key for the JSON object of the
+	// extra labels of the pods running the queue sidecar, mapping their keys
+	// to templates of their values, see QueueSidecarTemplateData.
+	queueSidecarLabelsKey = "queueSidecarLabels"
+
+	// topologySpreadConstraintsKey is the config map key for the JSON list of
+	// the default topology spread constraints of the revision pods, see
+	// serving.ParseTopologySpreadConstraints.
+	topologySpreadConstraintsKey = "topologySpreadConstraints"
+
+	// priorityClassNameKey is the config map key for the default
+	// priorityClassName of the revision pods.
+	priorityClassNameKey = "priorityClassName"
+
+	// deploymentStrategyKey is the config map key for the JSON default update
+	// strategy of the revision deployments, see serving.ParseDeploymentStrategy.
+	deploymentStrategyKey = "deploymentStrategy"
+
+	// podDisruptionBudgetMaxUnavailableKey is the config map key for the
+	// maxUnavailable, a number or a percentage, of the pod disruption budgets
+	// of the revisions with a minScale of at least 2.
+	podDisruptionBudgetMaxUnavailableKey = "podDisruptionBudgetMaxUnavailable"
 )
 
+// QueueSidecarTemplateData is the data the templates of the queue sidecar's
+// extra environment variables and labels are executed with.
+type QueueSidecarTemplateData struct {
+	Namespace     string
+	Revision      string
+	Configuration string
+	Service       string
+}
+
+// ExecuteQueueSidecarTemplate executes the template of an extra environment
+// variable or label of the queue sidecar with the given data.
+func ExecuteQueueSidecarTemplate(tmpl string, data QueueSidecarTemplateData) (string, error) {
+	t, err := template.New("queueSidecar").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// asTemplateMap parses the JSON object of the key, if present, into target,
+// checking that its values are valid templates and its keys pass validate.
+func asTemplateMap(key string, target *map[string]string, validate func(string) []string) cm.ParseFunc {
+	return func(data map[string]string) error {
+		raw, ok := data[key]
+		if !ok {
+			return nil
+		}
+		m := make(map[string]string)
+		if err := json.Unmarshal([]byte(raw), &m); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", key, err)
+		}
+		for k, v := range m {
+			if errs := validate(k); len(errs) > 0 {
+				return fmt.Errorf("invalid name %q in %s: %s", k, key, strings.Join(errs, "; "))
+			}
+			t, err := template.New(k).Option("missingkey=error").Parse(v)
+			if err != nil {
+				return fmt.Errorf("invalid template for %q in %s: %w", k, key, err)
+			}
+			if err := t.Execute(ioutil.Discard, QueueSidecarTemplateData{}); err != nil {
+				return fmt.Errorf("invalid template for %q in %s: %w", k, key, err)
+			}
+		}
+		if len(m) > 0 {
+			*target = m
+		}
+		return nil
+	}
+}
+
+// asTopologySpreadConstraints parses the JSON list of the key, if present,
+// into target.
+func asTopologySpreadConstraints(key string, target *[]corev1.TopologySpreadConstraint) cm.ParseFunc {
+	return func(data map[string]string) error {
+		raw, ok := data[key]
+		if !ok {
+			return nil
+		}
+		tscs, err := serving.ParseTopologySpreadConstraints(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", key, err)
+		}
+		if len(tscs) > 0 {
+			*target = tscs
+		}
+		return nil
+	}
+}
+
+// asDeploymentStrategy parses the JSON strategy of the key, if present and not
+// empty, into target.
+func asDeploymentStrategy(key string, target **appsv1.DeploymentStrategy) cm.ParseFunc {
+	return func(data map[string]string) error {
+		raw := strings.TrimSpace(data[key])
+		if raw == "" {
+			return nil
+		}
+		ds, err := serving.ParseDeploymentStrategy(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", key, err)
+		}
+		*target = ds
+		return nil
+	}
+}
+
+// asMaxUnavailable parses the number or percentage of the key, if present and
+// not empty, into target. Numbers must be positive, and percentages between 1%
+// and 99%, for the budget to both allow and limit disruptions.
+func asMaxUnavailable(key string, target **intstr.IntOrString) cm.ParseFunc {
+	return func(data map[string]string) error {
+		raw := strings.TrimSpace(data[key])
+		if raw == "" {
+			return nil
+		}
+		v := intstr.Parse(raw)
+		if v.Type == intstr.Int {
+			if v.IntVal < 1 {
+				return fmt.Errorf("%s must be positive, was %q", key, raw)
+			}
+		} else {
+			p, err := strconv.Atoi(strings.TrimSuffix(raw, "%"))
+			if err != nil || !strings.HasSuffix(raw, "%") || p < 1 || p > 99 {
+				return fmt.Errorf("%s must be a positive number or a percentage between 1%% and 99%%, was %q", key, raw)
+			}
+		}
+		*target = &v
+		return nil
+	}
+}
+
 var (
 	// QueueSidecarCPURequestDefault is the default request.cpu to set for the
 	// queue sidecar. It is set at 25m for backwards-compatibility since this was
@@ -74,8 +283,14 @@ func defaultConfig() *Config {
 	return &Config{
 		ProgressDeadline:               ProgressDeadlineDefault,
 		DigestResolutionTimeout:        digestResolutionTimeoutDefault,
+		DigestResolutionRetries:        digestResolutionRetriesDefault,
+		DigestResolutionBackoff:        digestResolutionBackoffDefault,
 		RegistriesSkippingTagResolving: sets.NewString("kind.local", "ko.local", "dev.local"),
 		QueueSidecarCPURequest:         &QueueSidecarCPURequestDefault,
+		QueueSidecarProbePeriod:        queueSidecarProbePeriodDefault,
+		QueueSidecarProbeTimeout:       queueSidecarProbeTimeoutDefault,
+		QueueSidecarProbeBackoffFactor: queueSidecarProbeBackoffFactorDefault,
+		QueueSidecarProbeMaxPeriod:     queueSidecarProbeMaxPeriodDefault,
 	}
 }
 
@@ -87,6 +302,12 @@ func NewConfigFromMap(configMap map[string]string) (*Config, error) {
 		cm.AsString(QueueSidecarImageKey, &nc.QueueSidecarImage),
 		cm.AsDuration(ProgressDeadlineKey, &nc.ProgressDeadline),
 		cm.AsDuration(digestResolutionTimeoutKey, &nc.DigestResolutionTimeout),
+		cm.AsString(digestResolutionCABundlePathKey, &nc.DigestResolutionCABundlePath),
+		cm.AsString(digestResolutionProxyKey, &nc.DigestResolutionProxy),
+		cm.AsInt32(digestResolutionRetriesKey, &nc.DigestResolutionRetries),
+		cm.AsDuration(digestResolutionBackoffKey, &nc.DigestResolutionBackoff),
+		cm.AsDuration(digestResolutionCacheTTLKey, &nc.DigestResolutionCacheTTL),
+		cm.AsString(digestResolutionSignaturePublicKeysKey, &nc.DigestResolutionSignaturePublicKeys),
 		cm.AsStringSet(registriesSkippingTagResolvingKey, &nc.RegistriesSkippingTagResolving),
 
 		cm.AsQuantity(queueSidecarCPURequestKey, &nc.QueueSidecarCPURequest),
@@ -95,6 +316,23 @@ func NewConfigFromMap(configMap map[string]string) (*Config, error) {
 		cm.AsQuantity(queueSidecarCPULimitKey, &nc.QueueSidecarCPULimit),
 		cm.AsQuantity(queueSidecarMemoryLimitKey, &nc.QueueSidecarMemoryLimit),
 		cm.AsQuantity(queueSidecarEphemeralStorageLimitKey, &nc.QueueSidecarEphemeralStorageLimit),
+
+		cm.AsDuration(queueSidecarProbePeriodKey, &nc.QueueSidecarProbePeriod),
+		cm.AsDuration(queueSidecarProbeTimeoutKey, &nc.QueueSidecarProbeTimeout),
+		cm.AsFloat64(queueSidecarProbeBackoffFactorKey, &nc.QueueSidecarProbeBackoffFactor),
+		cm.AsDuration(queueSidecarProbeMaxPeriodKey, &nc.QueueSidecarProbeMaxPeriod),
+
+		cm.AsInt32(queueSidecarMaxIdleConnsPerHostKey, &nc.QueueSidecarMaxIdleConnsPerHost),
+		cm.AsDuration(queueSidecarTLSHandshakeTimeoutKey, &nc.QueueSidecarTLSHandshakeTimeout),
+		cm.AsBool(queueSidecarDisableKeepAlivesKey, &nc.QueueSidecarDisableKeepAlives),
+
+		asTemplateMap(queueSidecarEnvKey, &nc.QueueSidecarEnv, validation.IsEnvVarName),
+		asTemplateMap(queueSidecarLabelsKey, &nc.QueueSidecarLabels, validation.IsQualifiedName),
+
+		asTopologySpreadConstraints(topologySpreadConstraintsKey, &nc.TopologySpreadConstraints),
+		cm.AsString(priorityClassNameKey, &nc.PriorityClassName),
+		asDeploymentStrategy(deploymentStrategyKey, &nc.DeploymentStrategy),
+		asMaxUnavailable(podDisruptionBudgetMaxUnavailableKey, &nc.PodDisruptionBudgetMaxUnavailable),
 	); err != nil {
 		return nil, err
 	}
@@ -115,6 +353,64 @@ func NewConfigFromMap(configMap map[string]string) (*Config, error) {
 		return nil, fmt.Errorf("digestResolutionTimeout cannot be a non-positive duration, was %v", nc.DigestResolutionTimeout)
 	}
 
+	if nc.DigestResolutionCABundlePath != "" && !filepath.IsAbs(nc.DigestResolutionCABundlePath) {
+		return nil, fmt.Errorf("%s must be an absolute path, was %q", digestResolutionCABundlePathKey, nc.DigestResolutionCABundlePath)
+	}
+
+	if nc.DigestResolutionProxy != "" {
+		if u, err := url.Parse(nc.DigestResolutionProxy); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("%s must be an http or https URL, was %q", digestResolutionProxyKey, nc.DigestResolutionProxy)
+		}
+	}
+
+	if nc.DigestResolutionSignaturePublicKeys != "" {
+		if _, err := ParseSignaturePublicKeys(nc.DigestResolutionSignaturePublicKeys); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", digestResolutionSignaturePublicKeysKey, err)
+		}
+	}
+
+	if nc.DigestResolutionRetries < 0 {
+		return nil, fmt.Errorf("%s cannot be negative, was %d", digestResolutionRetriesKey, nc.DigestResolutionRetries)
+	}
+
+	if nc.DigestResolutionBackoff <= 0 {
+		return nil, fmt.Errorf("%s cannot be a non-positive duration, was %v", digestResolutionBackoffKey, nc.DigestResolutionBackoff)
+	}
+
+	if nc.DigestResolutionCacheTTL < 0 {
+		return nil, fmt.Errorf("%s cannot be a negative duration, was %v", digestResolutionCacheTTLKey, nc.DigestResolutionCacheTTL)
+	}
+
+	if nc.QueueSidecarProbePeriod <= 0 {
+		return nil, fmt.Errorf("%s cannot be a non-positive duration, was %v", queueSidecarProbePeriodKey, nc.QueueSidecarProbePeriod)
+	}
+
+	if nc.QueueSidecarProbeTimeout <= 0 {
+		return nil, fmt.Errorf("%s cannot be a non-positive duration, was %v", queueSidecarProbeTimeoutKey, nc.QueueSidecarProbeTimeout)
+	}
+
+	if nc.QueueSidecarProbeBackoffFactor < 1 {
+		return nil, fmt.Errorf("%s must be at least 1, was %v", queueSidecarProbeBackoffFactorKey, nc.QueueSidecarProbeBackoffFactor)
+	}
+
+	if nc.QueueSidecarProbeMaxPeriod < nc.QueueSidecarProbePeriod {
+		return nil, fmt.Errorf("%s cannot be less than %s, was %v", queueSidecarProbeMaxPeriodKey, queueSidecarProbePeriodKey, nc.QueueSidecarProbeMaxPeriod)
+	}
+
+	if nc.QueueSidecarMaxIdleConnsPerHost < 0 {
+		return nil, fmt.Errorf("%s cannot be negative, was %d", queueSidecarMaxIdleConnsPerHostKey, nc.QueueSidecarMaxIdleConnsPerHost)
+	}
+
+	if nc.QueueSidecarTLSHandshakeTimeout < 0 {
+		return nil, fmt.Errorf("%s cannot be a negative duration, was %v", queueSidecarTLSHandshakeTimeoutKey, nc.QueueSidecarTLSHandshakeTimeout)
+	}
+
+	if nc.PriorityClassName != "" {
+		if errs := validation.IsDNS1123Subdomain(nc.PriorityClassName); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid %s %q: %s", priorityClassNameKey, nc.PriorityClassName, strings.Join(errs, "; "))
+		}
+	}
+
 	return nc, nil
 }
 
@@ -135,6 +431,33 @@ type Config struct {
 	// DigestResolutionTimeout is the maximum time allowed for image digest resolution.
 	DigestResolutionTimeout time.Duration
 
+	// DigestResolutionCABundlePath is the path of the PEM bundle of the
+	// additional CAs trusted by the image digest resolution.
+	DigestResolutionCABundlePath string
+
+	// DigestResolutionProxy is the URL of the proxy the image digest
+	// resolution goes through, if set.
+	DigestResolutionProxy string
+
+	// DigestResolutionRetries is how many times the image digest resolution
+	// is retried on transient registry errors, within the timeout.
+	DigestResolutionRetries int32
+
+	// DigestResolutionBackoff is the initial delay between the retries of the
+	// image digest resolution, doubled after each of them.
+	DigestResolutionBackoff time.Duration
+
+	// DigestResolutionCacheTTL is how long the digest resolved for an image
+	// tag is reused by the revisions with the same credentials, so that a
+	// tag pushed again in the meantime still resolves to the previous digest.
+	// Zero disables the cache.
+	DigestResolutionCacheTTL time.Duration
+
+	// DigestResolutionSignaturePublicKeys are the PEM public keys, one of
+	// which must have signed the cosign signature of the images resolved to
+	// digests, if set. See ParseSignaturePublicKeys.
+	DigestResolutionSignaturePublicKeys string
+
 	// ProgressDeadline is the time in seconds we wait for the deployment to
 	// be ready before considering it failed.
 	ProgressDeadline time.Duration
@@ -158,4 +481,85 @@ type Config struct {
 	// QueueSidecarEphemeralStorageLimit is the Ephemeral Storage Limit to set
 	// for the queue proxy sidecar container.
 	QueueSidecarEphemeralStorageLimit *resource.Quantity
+
+	// QueueSidecarProbePeriod is the initial interval between the attempts
+	// of the queue proxy's aggressive readiness probe of the user container.
+	QueueSidecarProbePeriod time.Duration
+
+	// QueueSidecarProbeTimeout is the timeout of a single attempt of the
+	// queue proxy's aggressive readiness probe.
+	QueueSidecarProbeTimeout time.Duration
+
+	// QueueSidecarProbeBackoffFactor multiplies the interval between the
+	// attempts of the aggressive readiness probe after each failed one.
+	QueueSidecarProbeBackoffFactor float64
+
+	// QueueSidecarProbeMaxPeriod caps the interval between the attempts
+	// of the aggressive readiness probe.
+	QueueSidecarProbeMaxPeriod time.Duration
+
+	// QueueSidecarMaxIdleConnsPerHost bounds the idle connections the queue
+	// proxy keeps to the user container. Zero derives it from the revision's
+	// container concurrency.
+	QueueSidecarMaxIdleConnsPerHost int32
+
+	// QueueSidecarTLSHandshakeTimeout bounds the TLS handshakes of the queue
+	// proxy with a user container serving HTTPS. Zero keeps the Go default.
+	QueueSidecarTLSHandshakeTimeout time.Duration
+
+	// QueueSidecarDisableKeepAlives makes the queue proxy open a new
+	// connection to the user container for every request.
+	QueueSidecarDisableKeepAlives bool
+
+	// QueueSidecarEnv maps the names of the extra environment variables of
+	// the queue proxy sidecar container to the templates of their values.
+	QueueSidecarEnv map[string]string
+
+	// QueueSidecarLabels maps the keys of the extra labels of the revision
+	// pods, which run the queue proxy sidecar, to the templates of their values.
+	QueueSidecarLabels map[string]string
+
+	// TopologySpreadConstraints are the default topology spread constraints
+	// of the revision pods, e.g. to spread them across zones.
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint
+
+	// PriorityClassName is the default priorityClassName of the revision pods,
+	// set when the kubernetes.podspec-priorityclassname feature is on.
+	PriorityClassName string
+
+	// DeploymentStrategy is the default update strategy of the revision
+	// deployments, the Kubernetes default one if nil.
+	DeploymentStrategy *appsv1.DeploymentStrategy
+
+	// PodDisruptionBudgetMaxUnavailable is the maxUnavailable of the pod
+	// disruption budgets of the revisions with a minScale of at least 2,
+	// which get none if nil.
+	PodDisruptionBudgetMaxUnavailable *intstr.IntOrString
+}
+
+// ParseSignaturePublicKeys parses the PEM encoded PKIX public keys, ECDSA,
+// RSA or Ed25519 ones, of DigestResolutionSignaturePublicKeys.
+func ParseSignaturePublicKeys(keys string) ([]crypto.PublicKey, error) {
+	var ret []crypto.PublicKey
+	rest := []byte(keys)
+	for {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		switch key.(type) {
+		case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		default:
+			return nil, fmt.Errorf("unsupported public key type %T", key)
+		}
+		ret = append(ret, key)
+	}
+	if len(ret) == 0 || len(bytes.TrimSpace(rest)) != 0 {
+		return nil, errors.New("expected PEM encoded public keys")
+	}
+	return ret, nil
 }
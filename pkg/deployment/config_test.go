@@ -17,14 +17,23 @@ limitations under the License.
 package deployment
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	"knative.dev/pkg/system"
@@ -83,8 +92,14 @@ func TestControllerConfiguration(t *testing.T) {
 	}{{
 		name: "controller configuration with bad registries",
 		wantConfig: &Config{
+			QueueSidecarProbePeriod:        queueSidecarProbePeriodDefault,
+			QueueSidecarProbeTimeout:       queueSidecarProbeTimeoutDefault,
+			QueueSidecarProbeBackoffFactor: queueSidecarProbeBackoffFactorDefault,
+			QueueSidecarProbeMaxPeriod:     queueSidecarProbeMaxPeriodDefault,
 			RegistriesSkippingTagResolving: sets.NewString("ko.local", ""),
 			DigestResolutionTimeout:        digestResolutionTimeoutDefault,
+			DigestResolutionRetries:        digestResolutionRetriesDefault,
+			DigestResolutionBackoff:        digestResolutionBackoffDefault,
 			QueueSidecarImage:              defaultSidecarImage,
 			QueueSidecarCPURequest:         &QueueSidecarCPURequestDefault,
 			ProgressDeadline:               ProgressDeadlineDefault,
@@ -96,8 +111,14 @@ func TestControllerConfiguration(t *testing.T) {
 	}, {
 		name: "controller configuration good progress deadline",
 		wantConfig: &Config{
+			QueueSidecarProbePeriod:        queueSidecarProbePeriodDefault,
+			QueueSidecarProbeTimeout:       queueSidecarProbeTimeoutDefault,
+			QueueSidecarProbeBackoffFactor: queueSidecarProbeBackoffFactorDefault,
+			QueueSidecarProbeMaxPeriod:     queueSidecarProbeMaxPeriodDefault,
 			RegistriesSkippingTagResolving: sets.NewString("kind.local", "ko.local", "dev.local"),
 			DigestResolutionTimeout:        digestResolutionTimeoutDefault,
+			DigestResolutionRetries:        digestResolutionRetriesDefault,
+			DigestResolutionBackoff:        digestResolutionBackoffDefault,
 			QueueSidecarImage:              defaultSidecarImage,
 			QueueSidecarCPURequest:         &QueueSidecarCPURequestDefault,
 			ProgressDeadline:               444 * time.Second,
@@ -109,8 +130,14 @@ func TestControllerConfiguration(t *testing.T) {
 	}, {
 		name: "controller configuration good digest resolution timeout",
 		wantConfig: &Config{
+			QueueSidecarProbePeriod:        queueSidecarProbePeriodDefault,
+			QueueSidecarProbeTimeout:       queueSidecarProbeTimeoutDefault,
+			QueueSidecarProbeBackoffFactor: queueSidecarProbeBackoffFactorDefault,
+			QueueSidecarProbeMaxPeriod:     queueSidecarProbeMaxPeriodDefault,
 			RegistriesSkippingTagResolving: sets.NewString("kind.local", "ko.local", "dev.local"),
 			DigestResolutionTimeout:        60 * time.Second,
+			DigestResolutionRetries:        digestResolutionRetriesDefault,
+			DigestResolutionBackoff:        digestResolutionBackoffDefault,
 			QueueSidecarImage:              defaultSidecarImage,
 			QueueSidecarCPURequest:         &QueueSidecarCPURequestDefault,
 			ProgressDeadline:               ProgressDeadlineDefault,
@@ -119,11 +146,85 @@ func TestControllerConfiguration(t *testing.T) {
 			QueueSidecarImageKey:       defaultSidecarImage,
 			digestResolutionTimeoutKey: "60s",
 		},
+	}, {
+		name: "controller configuration good digest resolution transport",
+		wantConfig: &Config{
+			QueueSidecarProbePeriod:        queueSidecarProbePeriodDefault,
+			QueueSidecarProbeTimeout:       queueSidecarProbeTimeoutDefault,
+			QueueSidecarProbeBackoffFactor: queueSidecarProbeBackoffFactorDefault,
+			QueueSidecarProbeMaxPeriod:     queueSidecarProbeMaxPeriodDefault,
+			RegistriesSkippingTagResolving: sets.NewString("kind.local", "ko.local", "dev.local"),
+			DigestResolutionTimeout:        digestResolutionTimeoutDefault,
+			DigestResolutionCABundlePath:   "/etc/registry-certs/ca.crt",
+			DigestResolutionProxy:          "http://proxy.corp.example.com:3128",
+			DigestResolutionRetries:        5,
+			DigestResolutionBackoff:        time.Second,
+			DigestResolutionCacheTTL:       time.Minute,
+			QueueSidecarImage:              defaultSidecarImage,
+			QueueSidecarCPURequest:         &QueueSidecarCPURequestDefault,
+			ProgressDeadline:               ProgressDeadlineDefault,
+		},
+		data: map[string]string{
+			QueueSidecarImageKey:            defaultSidecarImage,
+			digestResolutionCABundlePathKey: "/etc/registry-certs/ca.crt",
+			digestResolutionProxyKey:        "http://proxy.corp.example.com:3128",
+			digestResolutionRetriesKey:      "5",
+			digestResolutionBackoffKey:      "1s",
+			digestResolutionCacheTTLKey:     "1m",
+		},
+	}, {
+		name:    "controller configuration relative digest resolution CA bundle path",
+		wantErr: true,
+		data: map[string]string{
+			QueueSidecarImageKey:            defaultSidecarImage,
+			digestResolutionCABundlePathKey: "ca.crt",
+		},
+	}, {
+		name:    "controller configuration invalid digest resolution proxy",
+		wantErr: true,
+		data: map[string]string{
+			QueueSidecarImageKey:     defaultSidecarImage,
+			digestResolutionProxyKey: "proxy.corp.example.com:3128",
+		},
+	}, {
+		name:    "controller configuration negative digest resolution retries",
+		wantErr: true,
+		data: map[string]string{
+			QueueSidecarImageKey:       defaultSidecarImage,
+			digestResolutionRetriesKey: "-1",
+		},
+	}, {
+		name:    "controller configuration zero digest resolution backoff",
+		wantErr: true,
+		data: map[string]string{
+			QueueSidecarImageKey:       defaultSidecarImage,
+			digestResolutionBackoffKey: "0s",
+		},
+	}, {
+		name:    "controller configuration invalid digest resolution signature public keys",
+		wantErr: true,
+		data: map[string]string{
+			QueueSidecarImageKey:                   defaultSidecarImage,
+			digestResolutionSignaturePublicKeysKey: "not a key",
+		},
+	}, {
+		name:    "controller configuration negative digest resolution cache TTL",
+		wantErr: true,
+		data: map[string]string{
+			QueueSidecarImageKey:        defaultSidecarImage,
+			digestResolutionCacheTTLKey: "-1m",
+		},
 	}, {
 		name: "controller configuration with registries",
 		wantConfig: &Config{
+			QueueSidecarProbePeriod:        queueSidecarProbePeriodDefault,
+			QueueSidecarProbeTimeout:       queueSidecarProbeTimeoutDefault,
+			QueueSidecarProbeBackoffFactor: queueSidecarProbeBackoffFactorDefault,
+			QueueSidecarProbeMaxPeriod:     queueSidecarProbeMaxPeriodDefault,
 			RegistriesSkippingTagResolving: sets.NewString("ko.local", "ko.dev"),
 			DigestResolutionTimeout:        digestResolutionTimeoutDefault,
+			DigestResolutionRetries:        digestResolutionRetriesDefault,
+			DigestResolutionBackoff:        digestResolutionBackoffDefault,
 			QueueSidecarImage:              defaultSidecarImage,
 			QueueSidecarCPURequest:         &QueueSidecarCPURequestDefault,
 			ProgressDeadline:               ProgressDeadlineDefault,
@@ -135,8 +236,14 @@ func TestControllerConfiguration(t *testing.T) {
 	}, {
 		name: "controller configuration with custom queue sidecar resource request/limits",
 		wantConfig: &Config{
+			QueueSidecarProbePeriod:             queueSidecarProbePeriodDefault,
+			QueueSidecarProbeTimeout:            queueSidecarProbeTimeoutDefault,
+			QueueSidecarProbeBackoffFactor:      queueSidecarProbeBackoffFactorDefault,
+			QueueSidecarProbeMaxPeriod:          queueSidecarProbeMaxPeriodDefault,
 			RegistriesSkippingTagResolving:      sets.NewString("kind.local", "ko.local", "dev.local"),
 			DigestResolutionTimeout:             digestResolutionTimeoutDefault,
+			DigestResolutionRetries:             digestResolutionRetriesDefault,
+			DigestResolutionBackoff:             digestResolutionBackoffDefault,
 			QueueSidecarImage:                   defaultSidecarImage,
 			ProgressDeadline:                    ProgressDeadlineDefault,
 			QueueSidecarCPURequest:              resourcePtr(resource.MustParse("123m")),
@@ -155,6 +262,284 @@ func TestControllerConfiguration(t *testing.T) {
 			queueSidecarMemoryLimitKey:             "654m",
 			queueSidecarEphemeralStorageLimitKey:   "321M",
 		},
+	}, {
+		name: "controller configuration with custom queue sidecar probe timing",
+		wantConfig: &Config{
+			RegistriesSkippingTagResolving: sets.NewString("kind.local", "ko.local", "dev.local"),
+			DigestResolutionTimeout:        digestResolutionTimeoutDefault,
+			DigestResolutionRetries:        digestResolutionRetriesDefault,
+			DigestResolutionBackoff:        digestResolutionBackoffDefault,
+			QueueSidecarImage:              defaultSidecarImage,
+			QueueSidecarCPURequest:         &QueueSidecarCPURequestDefault,
+			ProgressDeadline:               ProgressDeadlineDefault,
+			QueueSidecarProbePeriod:        200 * time.Millisecond,
+			QueueSidecarProbeTimeout:       time.Second,
+			QueueSidecarProbeBackoffFactor: 1.5,
+			QueueSidecarProbeMaxPeriod:     3 * time.Second,
+		},
+		data: map[string]string{
+			QueueSidecarImageKey:              defaultSidecarImage,
+			queueSidecarProbePeriodKey:        "200ms",
+			queueSidecarProbeTimeoutKey:       "1s",
+			queueSidecarProbeBackoffFactorKey: "1.5",
+			queueSidecarProbeMaxPeriodKey:     "3s",
+		},
+	}, {
+		name: "controller configuration with custom queue sidecar transport",
+		wantConfig: &Config{
+			RegistriesSkippingTagResolving:  sets.NewString("kind.local", "ko.local", "dev.local"),
+			DigestResolutionTimeout:         digestResolutionTimeoutDefault,
+			DigestResolutionRetries:         digestResolutionRetriesDefault,
+			DigestResolutionBackoff:         digestResolutionBackoffDefault,
+			QueueSidecarImage:               defaultSidecarImage,
+			QueueSidecarCPURequest:          &QueueSidecarCPURequestDefault,
+			ProgressDeadline:                ProgressDeadlineDefault,
+			QueueSidecarProbePeriod:         queueSidecarProbePeriodDefault,
+			QueueSidecarProbeTimeout:        queueSidecarProbeTimeoutDefault,
+			QueueSidecarProbeBackoffFactor:  queueSidecarProbeBackoffFactorDefault,
+			QueueSidecarProbeMaxPeriod:      queueSidecarProbeMaxPeriodDefault,
+			QueueSidecarMaxIdleConnsPerHost: 5000,
+			QueueSidecarTLSHandshakeTimeout: 3 * time.Second,
+			QueueSidecarDisableKeepAlives:   true,
+		},
+		data: map[string]string{
+			QueueSidecarImageKey:               defaultSidecarImage,
+			queueSidecarMaxIdleConnsPerHostKey: "5000",
+			queueSidecarTLSHandshakeTimeoutKey: "3s",
+			queueSidecarDisableKeepAlivesKey:   "true",
+		},
+	}, {
+		name:    "controller configuration negative queue sidecar max idle conns",
+		wantErr: true,
+		data: map[string]string{
+			QueueSidecarImageKey:               defaultSidecarImage,
+			queueSidecarMaxIdleConnsPerHostKey: "-1",
+		},
+	}, {
+		name:    "controller configuration negative queue sidecar TLS handshake timeout",
+		wantErr: true,
+		data: map[string]string{
+			QueueSidecarImageKey:               defaultSidecarImage,
+			queueSidecarTLSHandshakeTimeoutKey: "-1s",
+		},
+	}, {
+		name: "controller configuration with queue sidecar env and labels",
+		wantConfig: &Config{
+			RegistriesSkippingTagResolving: sets.NewString("kind.local", "ko.local", "dev.local"),
+			DigestResolutionTimeout:        digestResolutionTimeoutDefault,
+			DigestResolutionRetries:        digestResolutionRetriesDefault,
+			DigestResolutionBackoff:        digestResolutionBackoffDefault,
+			QueueSidecarImage:              defaultSidecarImage,
+			QueueSidecarCPURequest:         &QueueSidecarCPURequestDefault,
+			ProgressDeadline:               ProgressDeadlineDefault,
+			QueueSidecarProbePeriod:        queueSidecarProbePeriodDefault,
+			QueueSidecarProbeTimeout:       queueSidecarProbeTimeoutDefault,
+			QueueSidecarProbeBackoffFactor: queueSidecarProbeBackoffFactorDefault,
+			QueueSidecarProbeMaxPeriod:     queueSidecarProbeMaxPeriodDefault,
+			QueueSidecarEnv: map[string]string{
+				"APM_SERVICE_NAME": "{{.Service}}",
+			},
+			QueueSidecarLabels: map[string]string{
+				"logs.example.com/source": "{{.Namespace}}.{{.Revision}}",
+			},
+		},
+		data: map[string]string{
+			QueueSidecarImageKey:  defaultSidecarImage,
+			queueSidecarEnvKey:    `{"APM_SERVICE_NAME": "{{.Service}}"}`,
+			queueSidecarLabelsKey: `{"logs.example.com/source": "{{.Namespace}}.{{.Revision}}"}`,
+		},
+	}, {
+		name: "controller configuration with topology spread constraints",
+		wantConfig: &Config{
+			RegistriesSkippingTagResolving: sets.NewString("kind.local", "ko.local", "dev.local"),
+			DigestResolutionTimeout:        digestResolutionTimeoutDefault,
+			DigestResolutionRetries:        digestResolutionRetriesDefault,
+			DigestResolutionBackoff:        digestResolutionBackoffDefault,
+			QueueSidecarImage:              defaultSidecarImage,
+			QueueSidecarCPURequest:         &QueueSidecarCPURequestDefault,
+			ProgressDeadline:               ProgressDeadlineDefault,
+			QueueSidecarProbePeriod:        queueSidecarProbePeriodDefault,
+			QueueSidecarProbeTimeout:       queueSidecarProbeTimeoutDefault,
+			QueueSidecarProbeBackoffFactor: queueSidecarProbeBackoffFactorDefault,
+			QueueSidecarProbeMaxPeriod:     queueSidecarProbeMaxPeriodDefault,
+			TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{
+				MaxSkew:           1,
+				TopologyKey:       "topology.kubernetes.io/zone",
+				WhenUnsatisfiable: corev1.ScheduleAnyway,
+			}},
+		},
+		data: map[string]string{
+			QueueSidecarImageKey: defaultSidecarImage,
+			topologySpreadConstraintsKey: `[{"maxSkew": 1, "topologyKey": "topology.kubernetes.io/zone",
+				"whenUnsatisfiable": "ScheduleAnyway"}]`,
+		},
+	}, {
+		name:    "controller configuration invalid topology spread constraints",
+		wantErr: true,
+		data: map[string]string{
+			QueueSidecarImageKey:         defaultSidecarImage,
+			topologySpreadConstraintsKey: `[{"maxSkew": 0, "topologyKey": "topology.kubernetes.io/zone"}]`,
+		},
+	}, {
+		name: "controller configuration with priority class name",
+		wantConfig: &Config{
+			RegistriesSkippingTagResolving: sets.NewString("kind.local", "ko.local", "dev.local"),
+			DigestResolutionTimeout:        digestResolutionTimeoutDefault,
+			DigestResolutionRetries:        digestResolutionRetriesDefault,
+			DigestResolutionBackoff:        digestResolutionBackoffDefault,
+			QueueSidecarImage:              defaultSidecarImage,
+			QueueSidecarCPURequest:         &QueueSidecarCPURequestDefault,
+			ProgressDeadline:               ProgressDeadlineDefault,
+			QueueSidecarProbePeriod:        queueSidecarProbePeriodDefault,
+			QueueSidecarProbeTimeout:       queueSidecarProbeTimeoutDefault,
+			QueueSidecarProbeBackoffFactor: queueSidecarProbeBackoffFactorDefault,
+			QueueSidecarProbeMaxPeriod:     queueSidecarProbeMaxPeriodDefault,
+			PriorityClassName:              "serving-critical",
+		},
+		data: map[string]string{
+			QueueSidecarImageKey: defaultSidecarImage,
+			priorityClassNameKey: "serving-critical",
+		},
+	}, {
+		name:    "controller configuration invalid priority class name",
+		wantErr: true,
+		data: map[string]string{
+			QueueSidecarImageKey: defaultSidecarImage,
+			priorityClassNameKey: "Serving_Critical",
+		},
+	}, {
+		name: "controller configuration with deployment strategy",
+		wantConfig: &Config{
+			RegistriesSkippingTagResolving: sets.NewString("kind.local", "ko.local", "dev.local"),
+			DigestResolutionTimeout:        digestResolutionTimeoutDefault,
+			DigestResolutionRetries:        digestResolutionRetriesDefault,
+			DigestResolutionBackoff:        digestResolutionBackoffDefault,
+			QueueSidecarImage:              defaultSidecarImage,
+			QueueSidecarCPURequest:         &QueueSidecarCPURequestDefault,
+			ProgressDeadline:               ProgressDeadlineDefault,
+			QueueSidecarProbePeriod:        queueSidecarProbePeriodDefault,
+			QueueSidecarProbeTimeout:       queueSidecarProbeTimeoutDefault,
+			QueueSidecarProbeBackoffFactor: queueSidecarProbeBackoffFactorDefault,
+			QueueSidecarProbeMaxPeriod:     queueSidecarProbeMaxPeriodDefault,
+			DeploymentStrategy: &appsv1.DeploymentStrategy{
+				Type: appsv1.RecreateDeploymentStrategyType,
+			},
+		},
+		data: map[string]string{
+			QueueSidecarImageKey:  defaultSidecarImage,
+			deploymentStrategyKey: `{"type": "Recreate"}`,
+		},
+	}, {
+		name:    "controller configuration invalid deployment strategy",
+		wantErr: true,
+		data: map[string]string{
+			QueueSidecarImageKey:  defaultSidecarImage,
+			deploymentStrategyKey: `{"type": "BlueGreen"}`,
+		},
+	}, {
+		name: "controller configuration with pod disruption budget",
+		wantConfig: &Config{
+			RegistriesSkippingTagResolving:    sets.NewString("kind.local", "ko.local", "dev.local"),
+			DigestResolutionTimeout:           digestResolutionTimeoutDefault,
+			DigestResolutionRetries:           digestResolutionRetriesDefault,
+			DigestResolutionBackoff:           digestResolutionBackoffDefault,
+			QueueSidecarImage:                 defaultSidecarImage,
+			QueueSidecarCPURequest:            &QueueSidecarCPURequestDefault,
+			ProgressDeadline:                  ProgressDeadlineDefault,
+			QueueSidecarProbePeriod:           queueSidecarProbePeriodDefault,
+			QueueSidecarProbeTimeout:          queueSidecarProbeTimeoutDefault,
+			QueueSidecarProbeBackoffFactor:    queueSidecarProbeBackoffFactorDefault,
+			QueueSidecarProbeMaxPeriod:        queueSidecarProbeMaxPeriodDefault,
+			PodDisruptionBudgetMaxUnavailable: intstrPtr(intstr.FromInt(1)),
+		},
+		data: map[string]string{
+			QueueSidecarImageKey:                 defaultSidecarImage,
+			podDisruptionBudgetMaxUnavailableKey: "1",
+		},
+	}, {
+		name: "controller configuration with pod disruption budget percentage",
+		wantConfig: &Config{
+			RegistriesSkippingTagResolving:    sets.NewString("kind.local", "ko.local", "dev.local"),
+			DigestResolutionTimeout:           digestResolutionTimeoutDefault,
+			DigestResolutionRetries:           digestResolutionRetriesDefault,
+			DigestResolutionBackoff:           digestResolutionBackoffDefault,
+			QueueSidecarImage:                 defaultSidecarImage,
+			QueueSidecarCPURequest:            &QueueSidecarCPURequestDefault,
+			ProgressDeadline:                  ProgressDeadlineDefault,
+			QueueSidecarProbePeriod:           queueSidecarProbePeriodDefault,
+			QueueSidecarProbeTimeout:          queueSidecarProbeTimeoutDefault,
+			QueueSidecarProbeBackoffFactor:    queueSidecarProbeBackoffFactorDefault,
+			QueueSidecarProbeMaxPeriod:        queueSidecarProbeMaxPeriodDefault,
+			PodDisruptionBudgetMaxUnavailable: intstrPtr(intstr.FromString("25%")),
+		},
+		data: map[string]string{
+			QueueSidecarImageKey:                 defaultSidecarImage,
+			podDisruptionBudgetMaxUnavailableKey: "25%",
+		},
+	}, {
+		name:    "controller configuration pod disruption budget not positive",
+		wantErr: true,
+		data: map[string]string{
+			QueueSidecarImageKey:                 defaultSidecarImage,
+			podDisruptionBudgetMaxUnavailableKey: "0",
+		},
+	}, {
+		name:    "controller configuration pod disruption budget all pods",
+		wantErr: true,
+		data: map[string]string{
+			QueueSidecarImageKey:                 defaultSidecarImage,
+			podDisruptionBudgetMaxUnavailableKey: "100%",
+		},
+	}, {
+		name:    "controller configuration pod disruption budget not a number",
+		wantErr: true,
+		data: map[string]string{
+			QueueSidecarImageKey:                 defaultSidecarImage,
+			podDisruptionBudgetMaxUnavailableKey: "one",
+		},
+	}, {
+		name:    "controller configuration queue sidecar env not JSON",
+		wantErr: true,
+		data: map[string]string{
+			QueueSidecarImageKey: defaultSidecarImage,
+			queueSidecarEnvKey:   "APM_SERVICE_NAME={{.Service}}",
+		},
+	}, {
+		name:    "controller configuration queue sidecar env invalid name",
+		wantErr: true,
+		data: map[string]string{
+			QueueSidecarImageKey: defaultSidecarImage,
+			queueSidecarEnvKey:   `{"1NVALID": "x"}`,
+		},
+	}, {
+		name:    "controller configuration queue sidecar labels invalid key",
+		wantErr: true,
+		data: map[string]string{
+			QueueSidecarImageKey:  defaultSidecarImage,
+			queueSidecarLabelsKey: `{"not a key": "x"}`,
+		},
+	}, {
+		name:    "controller configuration queue sidecar labels unknown field",
+		wantErr: true,
+		data: map[string]string{
+			QueueSidecarImageKey:  defaultSidecarImage,
+			queueSidecarLabelsKey: `{"source": "{{.Pod}}"}`,
+		},
+	}, {
+		name:    "controller configuration invalid probe backoff factor",
+		wantErr: true,
+		data: map[string]string{
+			QueueSidecarImageKey:              defaultSidecarImage,
+			queueSidecarProbeBackoffFactorKey: "0.5",
+		},
+	}, {
+		name:    "controller configuration probe max period less than period",
+		wantErr: true,
+		data: map[string]string{
+			QueueSidecarImageKey:          defaultSidecarImage,
+			queueSidecarProbePeriodKey:    "2s",
+			queueSidecarProbeMaxPeriodKey: "1s",
+		},
 	}, {
 		name:    "controller with no side car image",
 		wantErr: true,
@@ -228,3 +613,58 @@ func TestControllerConfiguration(t *testing.T) {
 func resourcePtr(q resource.Quantity) *resource.Quantity {
 	return &q
 }
+
+func intstrPtr(v intstr.IntOrString) *intstr.IntOrString {
+	return &v
+}
+
+func TestExecuteQueueSidecarTemplate(t *testing.T) {
+	got, err := ExecuteQueueSidecarTemplate("{{.Namespace}}/{{.Service}}/{{.Configuration}}/{{.Revision}}", QueueSidecarTemplateData{
+		Namespace:     "ns",
+		Revision:      "rev",
+		Configuration: "cfg",
+		Service:       "svc",
+	})
+	if err != nil {
+		t.Fatal("ExecuteQueueSidecarTemplate() =", err)
+	}
+	if want := "ns/svc/cfg/rev"; got != want {
+		t.Errorf("ExecuteQueueSidecarTemplate() = %q, want: %q", got, want)
+	}
+}
+
+func TestParseSignaturePublicKeys(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey() =", err)
+	}
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey() =", err)
+	}
+	encode := func(key crypto.PublicKey) string {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			t.Fatal("MarshalPKIXPublicKey() =", err)
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	}
+
+	keys, err := ParseSignaturePublicKeys(encode(&ecKey.PublicKey) + encode(edKey))
+	if err != nil {
+		t.Fatal("ParseSignaturePublicKeys() =", err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("len(ParseSignaturePublicKeys()) = %d, want: 2", len(keys))
+	}
+
+	for _, keys := range []string{
+		"",
+		encode(&ecKey.PublicKey) + "trailing garbage",
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("garbage")})),
+	} {
+		if _, err := ParseSignaturePublicKeys(keys); err == nil {
+			t.Errorf("ParseSignaturePublicKeys(%q) succeeded, want error", keys)
+		}
+	}
+}
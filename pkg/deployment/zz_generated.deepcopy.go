@@ -21,6 +21,9 @@ limitations under the License.
 package deployment
 
 import (
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	intstr "k8s.io/apimachinery/pkg/util/intstr"
 	sets "k8s.io/apimachinery/pkg/util/sets"
 )
 
@@ -64,6 +67,37 @@ func (in *Config) DeepCopyInto(out *Config) {
 		x := (*in).DeepCopy()
 		*out = &x
 	}
+	if in.QueueSidecarEnv != nil {
+		in, out := &in.QueueSidecarEnv, &out.QueueSidecarEnv
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.QueueSidecarLabels != nil {
+		in, out := &in.QueueSidecarLabels, &out.QueueSidecarLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]v1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DeploymentStrategy != nil {
+		in, out := &in.DeploymentStrategy, &out.DeploymentStrategy
+		*out = new(appsv1.DeploymentStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodDisruptionBudgetMaxUnavailable != nil {
+		in, out := &in.PodDisruptionBudgetMaxUnavailable, &out.PodDisruptionBudgetMaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
 	return
 }
 
@@ -76,3 +110,19 @@ func (in *Config) DeepCopy() *Config {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QueueSidecarTemplateData) DeepCopyInto(out *QueueSidecarTemplateData) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QueueSidecarTemplateData.
+func (in *QueueSidecarTemplateData) DeepCopy() *QueueSidecarTemplateData {
+	if in == nil {
+		return nil
+	}
+	out := new(QueueSidecarTemplateData)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,295 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package diag collects support bundles for Knative Services: the Service
+// itself, its child resources, the autoscaling state, including the decider
+// snapshots of the autoscaler, and the recent events.
+package diag
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+
+	networkingclientset "knative.dev/networking/pkg/client/clientset/versioned"
+	"knative.dev/serving/pkg/apis/serving"
+	servingclientset "knative.dev/serving/pkg/client/clientset/versioned"
+)
+
+// Clients are the API clients used to collect the support bundle.
+type Clients struct {
+	Kube       kubernetes.Interface
+	Serving    servingclientset.Interface
+	Networking networkingclientset.Interface
+	// Deciders provides the decider snapshots of the PodAutoscalers,
+	// they are not collected if it is nil.
+	Deciders DeciderSource
+}
+
+// Bundle is a support bundle for a single Knative Service.
+type Bundle struct {
+	// Files maps the path within the bundle to the file contents.
+	Files map[string][]byte
+
+	// uids are the UIDs of the collected objects, used to pick the events.
+	uids sets.String
+	// errs are the errors encountered during the collection.
+	errs []string
+}
+
+// Collect gathers the Knative Service `name` in `namespace`, its child
+// resources and the events about them that happened after `since`.
+// The collection is best effort: failures to list particular resources
+// are recorded in the bundle, only the failure to get the Service itself
+// is returned as an error.
+func Collect(ctx context.Context, c Clients, namespace, name string, since time.Time) (*Bundle, error) {
+	b := &Bundle{
+		Files: make(map[string][]byte),
+		uids:  sets.NewString(),
+	}
+
+	svc, err := c.Serving.ServingV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Service %s/%s: %w", namespace, name, err)
+	}
+	b.add("services.serving.knative.dev", svc)
+
+	opts := metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{serving.ServiceLabelKey: name}).String(),
+	}
+	// The Route of the Service has the same name.
+	routeOpts := metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{serving.RouteLabelKey: name}).String(),
+	}
+	var pas []string
+	for _, l := range []struct {
+		resource string
+		list     func() (runtime.Object, error)
+	}{{
+		resource: "configurations.serving.knative.dev",
+		list: func() (runtime.Object, error) {
+			return c.Serving.ServingV1().Configurations(namespace).List(ctx, opts)
+		},
+	}, {
+		resource: "routes.serving.knative.dev",
+		list: func() (runtime.Object, error) {
+			return c.Serving.ServingV1().Routes(namespace).List(ctx, opts)
+		},
+	}, {
+		resource: "revisions.serving.knative.dev",
+		list: func() (runtime.Object, error) {
+			return c.Serving.ServingV1().Revisions(namespace).List(ctx, opts)
+		},
+	}, {
+		// The PodAutoscaler status carries the latest decisions of the autoscaler.
+		resource: "podautoscalers.autoscaling.internal.knative.dev",
+		list: func() (runtime.Object, error) {
+			l, err := c.Serving.AutoscalingV1alpha1().PodAutoscalers(namespace).List(ctx, opts)
+			if err == nil {
+				for i := range l.Items {
+					pas = append(pas, l.Items[i].Name)
+				}
+			}
+			return l, err
+		},
+	}, {
+		resource: "metrics.autoscaling.internal.knative.dev",
+		list: func() (runtime.Object, error) {
+			return c.Serving.AutoscalingV1alpha1().Metrics(namespace).List(ctx, opts)
+		},
+	}, {
+		resource: "serverlessservices.networking.internal.knative.dev",
+		list: func() (runtime.Object, error) {
+			return c.Networking.NetworkingV1alpha1().ServerlessServices(namespace).List(ctx, opts)
+		},
+	}, {
+		resource: "ingresses.networking.internal.knative.dev",
+		list: func() (runtime.Object, error) {
+			return c.Networking.NetworkingV1alpha1().Ingresses(namespace).List(ctx, opts)
+		},
+	}, {
+		resource: "certificates.networking.internal.knative.dev",
+		list: func() (runtime.Object, error) {
+			return c.Networking.NetworkingV1alpha1().Certificates(namespace).List(ctx, routeOpts)
+		},
+	}, {
+		resource: "deployments.apps",
+		list: func() (runtime.Object, error) {
+			return c.Kube.AppsV1().Deployments(namespace).List(ctx, opts)
+		},
+	}, {
+		resource: "pods",
+		list: func() (runtime.Object, error) {
+			return c.Kube.CoreV1().Pods(namespace).List(ctx, opts)
+		},
+	}, {
+		resource: "services",
+		list: func() (runtime.Object, error) {
+			return c.Kube.CoreV1().Services(namespace).List(ctx, opts)
+		},
+	}, {
+		resource: "endpoints",
+		list: func() (runtime.Object, error) {
+			return c.Kube.CoreV1().Endpoints(namespace).List(ctx, opts)
+		},
+	}} {
+		list, err := l.list()
+		if err != nil {
+			b.errorf("failed to list %s: %v", l.resource, err)
+			continue
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			b.errorf("failed to extract %s: %v", l.resource, err)
+			continue
+		}
+		for _, item := range items {
+			b.add(l.resource, item)
+		}
+	}
+
+	if c.Deciders != nil {
+		b.addDeciders(ctx, c.Deciders, namespace, pas)
+	}
+	b.addEvents(ctx, c.Kube, namespace, since)
+
+	if len(b.errs) > 0 {
+		b.Files["errors.txt"] = []byte(strings.Join(b.errs, "\n") + "\n")
+	}
+	return b, nil
+}
+
+// add serializes the object into the bundle under the resource directory.
+func (b *Bundle) add(resource string, obj runtime.Object) {
+	acc, err := meta.Accessor(obj)
+	if err != nil {
+		b.errorf("failed to access %s: %v", resource, err)
+		return
+	}
+	// Managed fields are of no use for debugging and bloat the bundle.
+	acc.SetManagedFields(nil)
+	y, err := yaml.Marshal(obj)
+	if err != nil {
+		b.errorf("failed to serialize %s %s: %v", resource, acc.GetName(), err)
+		return
+	}
+	b.Files[path.Join(resource, acc.GetName()+".yaml")] = y
+	b.uids.Insert(string(acc.GetUID()))
+}
+
+// addDeciders adds the snapshots of the deciders of the PodAutoscalers,
+// the parameters and the latest decisions of the autoscaler.
+func (b *Bundle) addDeciders(ctx context.Context, src DeciderSource, namespace string, pas []string) {
+	for _, pa := range pas {
+		d, err := src(ctx, namespace, pa)
+		if err != nil {
+			b.errorf("failed to get the decider of %s: %v", pa, err)
+			continue
+		}
+		b.Files[path.Join("deciders", pa+".json")] = d
+	}
+}
+
+// addEvents adds the events about the collected objects that
+// happened after `since`, sorted by time.
+func (b *Bundle) addEvents(ctx context.Context, kc kubernetes.Interface, namespace string, since time.Time) {
+	events, err := kc.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		b.errorf("failed to list events: %v", err)
+		return
+	}
+	ret := &corev1.EventList{}
+	for i := range events.Items {
+		ev := &events.Items[i]
+		if b.uids.Has(string(ev.InvolvedObject.UID)) && !eventTime(ev).Before(since) {
+			ev.SetManagedFields(nil)
+			ret.Items = append(ret.Items, *ev)
+		}
+	}
+	sort.SliceStable(ret.Items, func(i, j int) bool {
+		return eventTime(&ret.Items[i]).Before(eventTime(&ret.Items[j]))
+	})
+	y, err := yaml.Marshal(ret)
+	if err != nil {
+		b.errorf("failed to serialize events: %v", err)
+		return
+	}
+	b.Files["events.yaml"] = y
+}
+
+// eventTime returns the time the event was last observed.
+func eventTime(ev *corev1.Event) time.Time {
+	switch {
+	case !ev.LastTimestamp.IsZero():
+		return ev.LastTimestamp.Time
+	case !ev.EventTime.IsZero():
+		return ev.EventTime.Time
+	default:
+		return ev.FirstTimestamp.Time
+	}
+}
+
+func (b *Bundle) errorf(format string, args ...interface{}) {
+	b.errs = append(b.errs, fmt.Sprintf(format, args...))
+}
+
+// Write writes the bundle as a gzipped tarball, with all the files placed
+// under the `root` directory.
+func (b *Bundle) Write(w io.Writer, root string, now time.Time) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	names := make([]string, 0, len(b.Files))
+	for n := range b.Files {
+		names = append(names, n)
+	}
+	// Sort for reproducible bundles.
+	sort.Strings(names)
+	for _, n := range names {
+		data := b.Files[n]
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    path.Join(root, n),
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: now,
+		}); err != nil {
+			return fmt.Errorf("failed to write header for %s: %w", n, err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", n, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diag
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
+
+	netv1alpha1 "knative.dev/networking/pkg/apis/networking/v1alpha1"
+	fakenetworking "knative.dev/networking/pkg/client/clientset/versioned/fake"
+	asv1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/apis/serving"
+	v1 "knative.dev/serving/pkg/apis/serving/v1"
+	fakeserving "knative.dev/serving/pkg/client/clientset/versioned/fake"
+)
+
+const (
+	testNamespace = "test-ns"
+	testService   = "test-svc"
+)
+
+func objectMeta(name, uid string, lbls map[string]string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Namespace: testNamespace,
+		Name:      name,
+		UID:       types.UID(uid),
+		Labels:    lbls,
+		ManagedFields: []metav1.ManagedFieldsEntry{{
+			Manager: "test",
+		}},
+	}
+}
+
+func event(name, uid string, t time.Time) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testNamespace,
+			Name:      name,
+		},
+		InvolvedObject: corev1.ObjectReference{UID: types.UID(uid)},
+		LastTimestamp:  metav1.NewTime(t),
+		Message:        name,
+	}
+}
+
+func testClients(now time.Time) Clients {
+	svcLabels := map[string]string{serving.ServiceLabelKey: testService}
+	otherLabels := map[string]string{serving.ServiceLabelKey: "other"}
+	return Clients{
+		Serving: fakeserving.NewSimpleClientset(
+			&v1.Service{ObjectMeta: objectMeta(testService, "svc", nil)},
+			&v1.Configuration{ObjectMeta: objectMeta(testService, "cfg", svcLabels)},
+			&v1.Route{ObjectMeta: objectMeta(testService, "route", svcLabels)},
+			&v1.Revision{ObjectMeta: objectMeta(testService+"-00001", "rev", svcLabels)},
+			&v1.Revision{ObjectMeta: objectMeta("other-00001", "other-rev", otherLabels)},
+			&asv1alpha1.PodAutoscaler{ObjectMeta: objectMeta(testService+"-00001", "pa", svcLabels)},
+		),
+		Networking: fakenetworking.NewSimpleClientset(
+			&netv1alpha1.ServerlessService{ObjectMeta: objectMeta(testService+"-00001", "sks", svcLabels)},
+			&netv1alpha1.Ingress{ObjectMeta: objectMeta(testService, "ing", svcLabels)},
+		),
+		Kube: fakekube.NewSimpleClientset(
+			&appsv1.Deployment{ObjectMeta: objectMeta(testService+"-00001-deployment", "deploy", svcLabels)},
+			&corev1.Pod{ObjectMeta: objectMeta(testService+"-00001-deployment-abcde", "pod", svcLabels)},
+			&corev1.Pod{ObjectMeta: objectMeta("other-00001-deployment-abcde", "other-pod", otherLabels)},
+			event("recent-rev", "rev", now.Add(-time.Minute)),
+			event("recent-svc", "svc", now.Add(-2*time.Minute)),
+			event("old-rev", "rev", now.Add(-time.Hour)),
+			event("recent-other", "other-rev", now.Add(-time.Minute)),
+		),
+		Deciders: func(_ context.Context, namespace, name string) ([]byte, error) {
+			return []byte(`{"namespace":"` + namespace + `","name":"` + name + `"}`), nil
+		},
+	}
+}
+
+func TestCollect(t *testing.T) {
+	now := time.Now()
+	b, err := Collect(context.Background(), testClients(now), testNamespace, testService, now.Add(-10*time.Minute))
+	if err != nil {
+		t.Fatal("Collect() =", err)
+	}
+
+	got := sets.NewString()
+	for n := range b.Files {
+		got.Insert(n)
+	}
+	want := sets.NewString(
+		"services.serving.knative.dev/test-svc.yaml",
+		"configurations.serving.knative.dev/test-svc.yaml",
+		"routes.serving.knative.dev/test-svc.yaml",
+		"revisions.serving.knative.dev/test-svc-00001.yaml",
+		"podautoscalers.autoscaling.internal.knative.dev/test-svc-00001.yaml",
+		"serverlessservices.networking.internal.knative.dev/test-svc-00001.yaml",
+		"ingresses.networking.internal.knative.dev/test-svc.yaml",
+		"deployments.apps/test-svc-00001-deployment.yaml",
+		"pods/test-svc-00001-deployment-abcde.yaml",
+		"deciders/test-svc-00001.json",
+		"events.yaml",
+	)
+	if !got.Equal(want) {
+		t.Errorf("Files = %v, want: %v, diff(-want,+got): %s", got.List(), want.List(), cmp.Diff(want.List(), got.List()))
+	}
+
+	if rev := string(b.Files["revisions.serving.knative.dev/test-svc-00001.yaml"]); strings.Contains(rev, "managedFields") {
+		t.Error("Managed fields were not dropped:\n", rev)
+	}
+
+	// Only the recent events about the collected objects, oldest first.
+	events := string(b.Files["events.yaml"])
+	svcIdx, revIdx := strings.Index(events, "message: recent-svc"), strings.Index(events, "message: recent-rev")
+	if svcIdx == -1 || revIdx == -1 || svcIdx > revIdx {
+		t.Error("Expected recent-svc and recent-rev events in order, got:\n", events)
+	}
+	for _, n := range []string{"old-rev", "recent-other"} {
+		if strings.Contains(events, "message: "+n) {
+			t.Errorf("Unexpected event %s in:\n%s", n, events)
+		}
+	}
+}
+
+func TestCollectNoService(t *testing.T) {
+	now := time.Now()
+	if _, err := Collect(context.Background(), testClients(now), testNamespace, "missing", now); err == nil {
+		t.Error("Collect() = nil, wanted an error")
+	}
+}
+
+func TestCollectListError(t *testing.T) {
+	now := time.Now()
+	c := testClients(now)
+	c.Kube.(*fakekube.Clientset).PrependReactor("list", "pods",
+		func(clientgotesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New("forbidden")
+		})
+	b, err := Collect(context.Background(), c, testNamespace, testService, now)
+	if err != nil {
+		t.Fatal("Collect() =", err)
+	}
+	if got, want := string(b.Files["errors.txt"]), "failed to list pods: forbidden\n"; got != want {
+		t.Errorf("errors.txt = %q, want: %q", got, want)
+	}
+	if _, ok := b.Files["revisions.serving.knative.dev/test-svc-00001.yaml"]; !ok {
+		t.Error("The rest of the bundle was not collected")
+	}
+}
+
+func TestCollectDeciderError(t *testing.T) {
+	now := time.Now()
+	c := testClients(now)
+	c.Deciders = func(context.Context, string, string) ([]byte, error) {
+		return nil, errors.New("unexpected status 404: not found")
+	}
+	b, err := Collect(context.Background(), c, testNamespace, testService, now)
+	if err != nil {
+		t.Fatal("Collect() =", err)
+	}
+	if got, want := string(b.Files["errors.txt"]),
+		"failed to get the decider of test-svc-00001: unexpected status 404: not found\n"; got != want {
+		t.Errorf("errors.txt = %q, want: %q", got, want)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	b := &Bundle{
+		Files: map[string][]byte{
+			"pods/b.yaml":   []byte("b"),
+			"events.yaml":   []byte("events"),
+			"pods/a.yaml":   []byte("a"),
+			"services.yaml": []byte("services"),
+		},
+	}
+	var buf bytes.Buffer
+	if err := b.Write(&buf, "root", time.Now()); err != nil {
+		t.Fatal("Write() =", err)
+	}
+
+	gr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal("Failed to open gzip stream:", err)
+	}
+	tr := tar.NewReader(gr)
+	var names []string
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("Failed to read tar stream:", err)
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal("Failed to read file:", err)
+		}
+		if want := b.Files[strings.TrimPrefix(h.Name, "root/")]; !bytes.Equal(data, want) {
+			t.Errorf("%s = %q, want: %q", h.Name, data, want)
+		}
+		names = append(names, h.Name)
+	}
+	want := []string{"root/events.yaml", "root/pods/a.yaml", "root/pods/b.yaml", "root/services.yaml"}
+	if !cmp.Equal(names, want) {
+		t.Errorf("Names = %v, want: %v", names, want)
+	}
+}
+
+func TestMainFlags(t *testing.T) {
+	var out bytes.Buffer
+	if err := Main(context.Background(), []string{"-namespace", "foo"}, &out); err == nil {
+		t.Error("Main() = nil, wanted an error for missing -service")
+	}
+	if err := Main(context.Background(), []string{"-unknown"}, &out); err == nil {
+		t.Error("Main() = nil, wanted an error for an unknown flag")
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diag
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"knative.dev/pkg/leaderelection"
+	pkgnet "knative.dev/pkg/network"
+	"knative.dev/serving/pkg/autoscaler/bucket"
+	"knative.dev/serving/pkg/autoscaler/statserver"
+)
+
+const (
+	// autoscalerComponent is the name of the autoscaler in the leader
+	// election config.
+	autoscalerComponent = "autoscaler"
+	// autoscalerPort is the port of the stats server of the autoscaler,
+	// which also serves the decider snapshots.
+	autoscalerPort = 8080
+)
+
+// DeciderSource returns the JSON snapshot of the decider of the
+// PodAutoscaler `name` in `namespace`.
+type DeciderSource func(ctx context.Context, namespace, name string) ([]byte, error)
+
+// AutoscalerDeciders returns a DeciderSource fetching the snapshots from
+// the autoscaler bucket running the decider, through the bucket Services
+// in the system namespace. The requests are authenticated by client.
+func AutoscalerDeciders(ctx context.Context, kc kubernetes.Interface, client *http.Client, systemNamespace string) DeciderSource {
+	buckets, err := autoscalerBuckets(ctx, kc, systemNamespace)
+	if err != nil {
+		err = fmt.Errorf("failed to get the autoscaler buckets: %w", err)
+	}
+	return func(ctx context.Context, namespace, name string) ([]byte, error) {
+		if err != nil {
+			return nil, err
+		}
+		key := types.NamespacedName{Namespace: namespace, Name: name}.String()
+		url := fmt.Sprintf("http://%s:%d%s%s",
+			pkgnet.GetServiceHostname(bucket.AutoscalerBucketSet(buckets).Owner(key), systemNamespace),
+			autoscalerPort, statserver.DecidersPath, key)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, body)
+		}
+		return body, nil
+	}
+}
+
+// autoscalerBuckets returns the number of the autoscaler buckets, as
+// configured in the leader election config.
+func autoscalerBuckets(ctx context.Context, kc kubernetes.Interface, systemNamespace string) (uint32, error) {
+	cm, err := kc.CoreV1().ConfigMaps(systemNamespace).Get(ctx, leaderelection.ConfigMapName(), metav1.GetOptions{})
+	if apierrs.IsNotFound(err) {
+		cm = nil
+	} else if err != nil {
+		return 0, err
+	}
+	cfg, err := leaderelection.NewConfigFromConfigMap(cm)
+	if err != nil {
+		return 0, err
+	}
+	return cfg.GetComponentConfig(autoscalerComponent).Buckets, nil
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diag
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	"knative.dev/pkg/leaderelection"
+	pkgnet "knative.dev/pkg/network"
+	"knative.dev/serving/pkg/autoscaler/bucket"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestAutoscalerDeciders(t *testing.T) {
+	const systemNS = "knative-testing"
+	kc := fakekube.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: systemNS,
+			Name:      leaderelection.ConfigMapName(),
+		},
+		Data: map[string]string{"buckets": "3"},
+	})
+	key := testNamespace + "/" + testService + "-00001"
+	wantURL := "http://" + pkgnet.GetServiceHostname(bucket.AutoscalerBucketSet(3).Owner(key), systemNS) +
+		":8080/deciders/" + key
+
+	tests := []struct {
+		name    string
+		code    int
+		want    string
+		wantErr string
+	}{{
+		name: "ok",
+		code: http.StatusOK,
+		want: `{"name":"test-svc-00001"}`,
+	}, {
+		name:    "another bucket",
+		code:    http.StatusNotFound,
+		wantErr: "unexpected status 404",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				if got := r.URL.String(); got != wantURL {
+					t.Errorf("URL = %s, want: %s", got, wantURL)
+				}
+				return &http.Response{
+					StatusCode: test.code,
+					Body:       ioutil.NopCloser(strings.NewReader(test.want)),
+				}, nil
+			})}
+			src := AutoscalerDeciders(context.Background(), kc, client, systemNS)
+
+			got, err := src(context.Background(), testNamespace, testService+"-00001")
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("Deciders() = %v, wanted an error containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("Deciders() =", err)
+			}
+			if string(got) != test.want {
+				t.Errorf("Deciders() = %s, want: %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diag
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	networkingclientset "knative.dev/networking/pkg/client/clientset/versioned"
+	"knative.dev/pkg/system"
+	servingclientset "knative.dev/serving/pkg/client/clientset/versioned"
+)
+
+// Main parses the flags from args, collects the support bundle for the
+// requested Service and writes it out. It backs the `diag` subcommand of
+// the controller.
+func Main(ctx context.Context, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("diag", flag.ContinueOnError)
+	var (
+		serverURL  = fs.String("server", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
+		kubeconfig = fs.String("kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
+		namespace  = fs.String("namespace", "default", "The namespace of the Knative Service.")
+		service    = fs.String("service", "", "The name of the Knative Service to collect the support bundle for.")
+		since      = fs.Duration("since", time.Hour, "Only collect the events that happened within this duration.")
+		output     = fs.String("output", "", `The file to write the bundle to. Defaults to "<service>-diag.tar.gz", "-" writes to stdout.`)
+		systemNS   = fs.String("system-namespace", systemNamespace(), "The namespace Knative Serving is installed in.")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *service == "" {
+		return errors.New("-service must be specified")
+	}
+
+	cfg, err := clientcmd.BuildConfigFromFlags(*serverURL, *kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error building kubeconfig: %w", err)
+	}
+	var c Clients
+	if c.Kube, err = kubernetes.NewForConfig(cfg); err != nil {
+		return fmt.Errorf("error building kube clientset: %w", err)
+	}
+	if c.Serving, err = servingclientset.NewForConfig(cfg); err != nil {
+		return fmt.Errorf("error building serving clientset: %w", err)
+	}
+	if c.Networking, err = networkingclientset.NewForConfig(cfg); err != nil {
+		return fmt.Errorf("error building networking clientset: %w", err)
+	}
+	// The autoscaler authenticates the requests with the credentials of the
+	// kubeconfig, via the TokenReview API.
+	rt, err := rest.TransportFor(cfg)
+	if err != nil {
+		return fmt.Errorf("error building the autoscaler transport: %w", err)
+	}
+	c.Deciders = AutoscalerDeciders(ctx, c.Kube, &http.Client{Transport: rt}, *systemNS)
+
+	now := time.Now()
+	b, err := Collect(ctx, c, *namespace, *service, now.Add(-*since))
+	if err != nil {
+		return err
+	}
+
+	root := *service + "-diag"
+	if *output == "-" {
+		return b.Write(stdout, root, now)
+	}
+	if *output == "" {
+		*output = root + ".tar.gz"
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create the bundle file: %w", err)
+	}
+	if err := b.Write(f, root, now); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write the bundle: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write the bundle: %w", err)
+	}
+	fmt.Fprintln(stdout, "Wrote the support bundle to", *output)
+	return nil
+}
+
+// systemNamespace returns the namespace of Knative Serving when run in its
+// pods, and the default one otherwise.
+func systemNamespace() string {
+	if ns := os.Getenv(system.NamespaceEnvKey); ns != "" {
+		return ns
+	}
+	return "knative-serving"
+}
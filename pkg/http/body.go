@@ -0,0 +1,47 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import "net/http"
+
+// LimitRequestBody limits the body of the request to max bytes. If the
+// request declares a larger body upfront, it is rejected with a 413 right
+// away and false is returned. The bodies of unknown length fail to be read
+// beyond max bytes instead.
+func LimitRequestBody(w http.ResponseWriter, r *http.Request, max int64) bool {
+	if r.ContentLength > max {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return false
+	}
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = http.MaxBytesReader(w, r.Body, max)
+	}
+	return true
+}
+
+// MaxBodySizeHandler wraps the handler to limit the request bodies to max
+// bytes, see LimitRequestBody. Non-positive max returns the handler as is.
+func MaxBodySizeHandler(max int64, h http.Handler) http.Handler {
+	if max <= 0 {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if LimitRequestBody(w, r, max) {
+			h.ServeHTTP(w, r)
+		}
+	})
+}
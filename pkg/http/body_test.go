@@ -0,0 +1,88 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMaxBodySizeHandler(t *testing.T) {
+	const max = 10
+	tests := []struct {
+		name          string
+		body          string
+		contentLength int64
+		wantCode      int
+		wantBody      string
+	}{{
+		name:          "within limit",
+		body:          "0123456789",
+		contentLength: 10,
+		wantCode:      http.StatusOK,
+		wantBody:      "0123456789",
+	}, {
+		name:          "declared too large",
+		body:          "0123456789a",
+		contentLength: 11,
+		wantCode:      http.StatusRequestEntityTooLarge,
+	}, {
+		name:          "unknown length within limit",
+		body:          "01234",
+		contentLength: -1,
+		wantCode:      http.StatusOK,
+		wantBody:      "01234",
+	}, {
+		name:          "unknown length too large",
+		body:          "0123456789a",
+		contentLength: -1,
+		wantCode:      http.StatusBadRequest,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			called := false
+			h := MaxBodySizeHandler(max, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				b, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.Write(b)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "http://example.com", strings.NewReader(test.body))
+			req.ContentLength = test.contentLength
+			resp := httptest.NewRecorder()
+			h.ServeHTTP(resp, req)
+
+			if got, want := resp.Code, test.wantCode; got != want {
+				t.Errorf("Code = %d, want: %d", got, want)
+			}
+			if called != (test.wantCode != http.StatusRequestEntityTooLarge) {
+				t.Error("Handler called =", called)
+			}
+			if test.wantBody != "" && resp.Body.String() != test.wantBody {
+				t.Errorf("Body = %q, want: %q", resp.Body.String(), test.wantBody)
+			}
+		})
+	}
+}
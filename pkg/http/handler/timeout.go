@@ -39,9 +39,11 @@ func StaticTimeoutFunc(timeout time.Duration) TimeoutFunc {
 }
 
 type timeToFirstByteTimeoutHandler struct {
-	handler     http.Handler
-	timeoutFunc TimeoutFunc
-	body        string
+	handler         http.Handler
+	timeoutFunc     TimeoutFunc
+	idleTimeoutFunc TimeoutFunc
+	maxDurationFunc TimeoutFunc
+	body            string
 }
 
 // NewTimeToFirstByteTimeoutHandler returns a Handler that runs `h` with the
@@ -61,10 +63,22 @@ type timeToFirstByteTimeoutHandler struct {
 //
 // The implementation is largely inspired by http.TimeoutHandler.
 func NewTimeToFirstByteTimeoutHandler(h http.Handler, msg string, timeoutFunc TimeoutFunc) http.Handler {
+	return NewTimeoutHandler(h, msg, timeoutFunc, nil, nil)
+}
+
+// NewTimeoutHandler returns a Handler like NewTimeToFirstByteTimeoutHandler,
+// which additionally terminates the responses, once started, when `h` does
+// not write to them for the idle timeout from the idle timeout function, or
+// when they run for longer than the max duration from the max duration
+// function, however much `h` writes to them.
+// A nil function or a zero duration disables the respective limit.
+func NewTimeoutHandler(h http.Handler, msg string, timeoutFunc, idleTimeoutFunc, maxDurationFunc TimeoutFunc) http.Handler {
 	return &timeToFirstByteTimeoutHandler{
-		handler:     h,
-		body:        msg,
-		timeoutFunc: timeoutFunc,
+		handler:         h,
+		body:            msg,
+		timeoutFunc:     timeoutFunc,
+		idleTimeoutFunc: idleTimeoutFunc,
+		maxDurationFunc: maxDurationFunc,
 	}
 }
 
@@ -88,6 +102,30 @@ func (h *timeToFirstByteTimeoutHandler) ServeHTTP(w http.ResponseWriter, r *http
 
 	timeout := time.NewTimer(h.timeoutFunc(r))
 	defer timeout.Stop()
+
+	var (
+		idleTimeout time.Duration
+		idleTimer   *time.Timer
+		idleCh      <-chan time.Time
+	)
+	if h.idleTimeoutFunc != nil {
+		idleTimeout = h.idleTimeoutFunc(r)
+	}
+	if idleTimeout > 0 {
+		idleTimer = time.NewTimer(idleTimeout)
+		defer idleTimer.Stop()
+		idleCh = idleTimer.C
+	}
+
+	var maxDurationCh <-chan time.Time
+	if h.maxDurationFunc != nil {
+		if maxDuration := h.maxDurationFunc(r); maxDuration > 0 {
+			maxDurationTimer := time.NewTimer(maxDuration)
+			defer maxDurationTimer.Stop()
+			maxDurationCh = maxDurationTimer.C
+		}
+	}
+
 	for {
 		select {
 		case p, ok := <-done:
@@ -99,6 +137,19 @@ func (h *timeToFirstByteTimeoutHandler) ServeHTTP(w http.ResponseWriter, r *http
 			if tw.timeoutAndWriteError(h.body) {
 				return
 			}
+		case now := <-idleCh:
+			left, timedOut := tw.tryIdleTimeout(now, idleTimeout)
+			if timedOut {
+				// Returning cancels the request context, which stops
+				// the handler, and ends the response.
+				return
+			}
+			idleTimer.Reset(left)
+		case <-maxDurationCh:
+			// Returning cancels the request context, which stops
+			// the handler, and ends the response.
+			tw.terminate(h.body)
+			return
 		}
 	}
 }
@@ -116,6 +167,7 @@ type timeoutWriter struct {
 	mu        sync.Mutex
 	timedOut  bool
 	wroteOnce bool
+	lastWrite time.Time
 }
 
 var _ http.Flusher = (*timeoutWriter)(nil)
@@ -151,6 +203,7 @@ func (tw *timeoutWriter) Write(p []byte) (int, error) {
 	}
 
 	tw.wroteOnce = true
+	tw.lastWrite = time.Now()
 	return tw.w.Write(p)
 }
 
@@ -161,6 +214,7 @@ func (tw *timeoutWriter) WriteHeader(code int) {
 		return
 	}
 	tw.wroteOnce = true
+	tw.lastWrite = time.Now()
 	tw.w.WriteHeader(code)
 }
 
@@ -184,3 +238,40 @@ func (tw *timeoutWriter) timeoutAndWriteError(msg string) bool {
 
 	return false
 }
+
+// terminate marks the writer as timed out, writing an error to the response
+// writer if nothing has been written on the writer before.
+//
+// All subsequent calls to Write will result in http.ErrHandlerTimeout.
+func (tw *timeoutWriter) terminate(msg string) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if !tw.wroteOnce {
+		tw.w.WriteHeader(http.StatusGatewayTimeout)
+		io.WriteString(tw.w, msg)
+	}
+	tw.timedOut = true
+}
+
+// tryIdleTimeout marks the writer as timed out, if the response started and
+// was not written to for the idle timeout as of now. Otherwise it returns the
+// time left until the response may become idle for the idle timeout.
+//
+// If this times out, all subsequent calls to Write will result in
+// http.ErrHandlerTimeout.
+func (tw *timeoutWriter) tryIdleTimeout(now time.Time, idleTimeout time.Duration) (time.Duration, bool) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	// Until the response starts, the time to first byte timeout applies.
+	if !tw.wroteOnce {
+		return idleTimeout, false
+	}
+	if idle := now.Sub(tw.lastWrite); idle < idleTimeout {
+		return idleTimeout - idle, false
+	}
+
+	tw.timedOut = true
+	return 0, true
+}
@@ -174,3 +174,115 @@ func TestTimeToFirstByteTimeoutHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestTimeoutHandlerIdleTimeout(t *testing.T) {
+	const (
+		idleTimeout = 100 * time.Millisecond
+		longTimeout = 1 * time.Minute // Super long, not supposed to hit this.
+	)
+
+	t.Run("writes within the idle timeout", func(t *testing.T) {
+		handler := NewTimeoutHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for i := 0; i < 5; i++ {
+				w.Write([]byte("a"))
+				time.Sleep(idleTimeout / 4)
+			}
+		}), "request timeout", StaticTimeoutFunc(longTimeout), StaticTimeoutFunc(idleTimeout), nil)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		if got, want := rr.Body.String(), "aaaaa"; got != want {
+			t.Errorf("Body = %q, want: %q", got, want)
+		}
+	})
+
+	t.Run("idle response", func(t *testing.T) {
+		writeErrors := make(chan error, 1)
+		handler := NewTimeoutHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("a"))
+			// Wait for the request to be cancelled by the idle timeout.
+			<-r.Context().Done()
+			_, err := w.Write([]byte("b"))
+			writeErrors <- err
+		}), "request timeout", StaticTimeoutFunc(longTimeout), StaticTimeoutFunc(idleTimeout), nil)
+
+		rr := httptest.NewRecorder()
+		start := time.Now()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		if elapsed := time.Since(start); elapsed < idleTimeout {
+			t.Errorf("ServeHTTP returned after %v, before the idle timeout %v", elapsed, idleTimeout)
+		}
+		if got, want := rr.Code, http.StatusOK; got != want {
+			t.Errorf("Status = %d, want: %d", got, want)
+		}
+		if got, want := rr.Body.String(), "a"; got != want {
+			t.Errorf("Body = %q, want: %q", got, want)
+		}
+		if err := <-writeErrors; err != http.ErrHandlerTimeout {
+			t.Error("Expected a timeout error, got", err)
+		}
+	})
+
+	t.Run("idle before the response starts", func(t *testing.T) {
+		handler := NewTimeoutHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(2 * idleTimeout)
+			w.Write([]byte("a"))
+		}), "request timeout", StaticTimeoutFunc(longTimeout), StaticTimeoutFunc(idleTimeout), nil)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		if got, want := rr.Body.String(), "a"; got != want {
+			t.Errorf("Body = %q, want: %q", got, want)
+		}
+	})
+}
+
+func TestTimeoutHandlerMaxDuration(t *testing.T) {
+	const (
+		maxDuration = 200 * time.Millisecond
+		idleTimeout = 100 * time.Millisecond
+		longTimeout = 1 * time.Minute // Super long, not supposed to hit this.
+	)
+
+	t.Run("slow but chatty response", func(t *testing.T) {
+		writeErrors := make(chan error, 1)
+		handler := NewTimeoutHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Write well within the idle timeout, until cut off.
+			for {
+				if _, err := w.Write([]byte("a")); err != nil {
+					writeErrors <- err
+					return
+				}
+				time.Sleep(idleTimeout / 4)
+			}
+		}), "request timeout", StaticTimeoutFunc(longTimeout), StaticTimeoutFunc(idleTimeout), StaticTimeoutFunc(maxDuration))
+
+		rr := httptest.NewRecorder()
+		start := time.Now()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		if elapsed := time.Since(start); elapsed < maxDuration || elapsed > longTimeout/2 {
+			t.Errorf("ServeHTTP returned after %v, want: about %v", elapsed, maxDuration)
+		}
+		if got, want := rr.Code, http.StatusOK; got != want {
+			t.Errorf("Status = %d, want: %d", got, want)
+		}
+		if err := <-writeErrors; err != http.ErrHandlerTimeout {
+			t.Error("Expected a timeout error, got", err)
+		}
+	})
+
+	t.Run("response not started", func(t *testing.T) {
+		handler := NewTimeoutHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}), "request timeout", StaticTimeoutFunc(longTimeout), nil, StaticTimeoutFunc(maxDuration))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		if got, want := rr.Code, http.StatusGatewayTimeout; got != want {
+			t.Errorf("Status = %d, want: %d", got, want)
+		}
+		if got, want := rr.Body.String(), "request timeout"; got != want {
+			t.Errorf("Body = %q, want: %q", got, want)
+		}
+	})
+}
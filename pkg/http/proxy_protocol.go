@@ -0,0 +1,211 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// proxyProtocolHeaderLen is the length of the fixed part of the
+	// PROXY protocol v2 header: the signature, the version and command,
+	// the address family and the length of the addresses.
+	proxyProtocolHeaderLen = 16
+
+	// proxyProtocolTimeout bounds the time the client has to send the header.
+	proxyProtocolTimeout = 10 * time.Second
+
+	proxyProtocolCmdLocal = 0x0
+	proxyProtocolCmdProxy = 0x1
+	proxyProtocolTCP4     = 0x11
+	proxyProtocolTCP6     = 0x21
+)
+
+// proxyProtocolSignature starts every PROXY protocol v2 header.
+var proxyProtocolSignature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// errListenerClosed is returned by Accept once the listener is closed.
+var errListenerClosed = errors.New("listener closed")
+
+// NewProxyProtocolListener wraps the listener to read the PROXY protocol v2
+// header off the connections accepted from the addresses trusted returns true
+// for, i.e. the proxies of the ingress. The connections then report the
+// client's address the header carries as their RemoteAddr, so that it ends up
+// in the X-Forwarded-For header of the proxied requests. The connections from
+// the rest of the addresses, e.g. the kubelet probes, are accepted as is, so
+// their clients can't claim another address. The headers are read off the
+// connections concurrently, so that a slow client does not hold up accepting
+// the rest.
+func NewProxyProtocolListener(l net.Listener, trusted func(net.Addr) bool) net.Listener {
+	pl := &proxyProtocolListener{
+		Listener: l,
+		trusted:  trusted,
+		conns:    make(chan net.Conn),
+		errs:     make(chan error),
+		done:     make(chan struct{}),
+	}
+	go pl.acceptLoop()
+	return pl
+}
+
+type proxyProtocolListener struct {
+	net.Listener
+	trusted func(net.Addr) bool
+
+	conns     chan net.Conn
+	errs      chan error
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (pl *proxyProtocolListener) acceptLoop() {
+	for {
+		c, err := pl.Listener.Accept()
+		if err != nil {
+			select {
+			case pl.errs <- err:
+			case <-pl.done:
+				return
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			return
+		}
+		if !pl.trusted(c.RemoteAddr()) {
+			pl.deliver(c)
+			continue
+		}
+		go func() {
+			pc, err := readProxyProtocolHeader(c)
+			if err != nil {
+				c.Close()
+				return
+			}
+			pl.deliver(pc)
+		}()
+	}
+}
+
+func (pl *proxyProtocolListener) deliver(c net.Conn) {
+	select {
+	case pl.conns <- c:
+	case <-pl.done:
+		c.Close()
+	}
+}
+
+// Accept implements net.Listener.
+func (pl *proxyProtocolListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-pl.conns:
+		return c, nil
+	case err := <-pl.errs:
+		return nil, err
+	case <-pl.done:
+		return nil, errListenerClosed
+	}
+}
+
+// Close implements net.Listener.
+func (pl *proxyProtocolListener) Close() error {
+	pl.closeOnce.Do(func() { close(pl.done) })
+	return pl.Listener.Close()
+}
+
+// proxyProtocolConn is a connection whose header was consumed.
+type proxyProtocolConn struct {
+	net.Conn
+	r      *bufio.Reader
+	remote net.Addr
+}
+
+// Read implements net.Conn.
+func (c *proxyProtocolConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// RemoteAddr implements net.Conn.
+func (c *proxyProtocolConn) RemoteAddr() net.Addr {
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// readProxyProtocolHeader reads the PROXY protocol v2 header off the
+// connection, if it starts with one. The header is optional, since the
+// trusted proxies may also connect on their own behalf, e.g. to probe.
+func readProxyProtocolHeader(c net.Conn) (net.Conn, error) {
+	if err := c.SetReadDeadline(time.Now().Add(proxyProtocolTimeout)); err != nil {
+		return nil, err
+	}
+	pc := &proxyProtocolConn{Conn: c, r: bufio.NewReader(c)}
+	// On errors Peek returns what it has, the reads of the connection
+	// then return the same bytes and the error.
+	if sig, _ := pc.r.Peek(len(proxyProtocolSignature)); bytes.Equal(sig, proxyProtocolSignature) {
+		var hdr [proxyProtocolHeaderLen]byte
+		if _, err := io.ReadFull(pc.r, hdr[:]); err != nil {
+			return nil, err
+		}
+		if v := hdr[12] >> 4; v != 2 {
+			return nil, fmt.Errorf("unsupported PROXY protocol version %d", v)
+		}
+		addrs := make([]byte, binary.BigEndian.Uint16(hdr[14:]))
+		if _, err := io.ReadFull(pc.r, addrs); err != nil {
+			return nil, err
+		}
+		switch cmd := hdr[12] & 0xf; cmd {
+		case proxyProtocolCmdLocal:
+			// Health checks of the proxy itself, the address is the proxy's.
+		case proxyProtocolCmdProxy:
+			pc.remote = sourceAddr(hdr[13], addrs)
+		default:
+			return nil, fmt.Errorf("unsupported PROXY protocol command %d", cmd)
+		}
+	}
+	if err := c.SetReadDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	return pc, nil
+}
+
+// sourceAddr returns the source address of the TCP connections, or nil for
+// the rest of the address families, which keeps the proxy's address.
+func sourceAddr(family byte, addrs []byte) net.Addr {
+	switch {
+	case family == proxyProtocolTCP4 && len(addrs) >= 12:
+		return &net.TCPAddr{
+			IP:   net.IP(append([]byte(nil), addrs[:4]...)),
+			Port: int(binary.BigEndian.Uint16(addrs[8:])),
+		}
+	case family == proxyProtocolTCP6 && len(addrs) >= 36:
+		return &net.TCPAddr{
+			IP:   net.IP(append([]byte(nil), addrs[:16]...)),
+			Port: int(binary.BigEndian.Uint16(addrs[32:])),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+// proxyProtocolHeader returns a PROXY protocol v2 header of the command
+// with the TCP4 or TCP6 source address.
+func proxyProtocolHeader(cmd byte, src *net.TCPAddr) []byte {
+	hdr := append([]byte(nil), proxyProtocolSignature...)
+	var addrs []byte
+	family := byte(proxyProtocolTCP4)
+	if ip := src.IP.To4(); ip != nil {
+		addrs = append(append(addrs, ip...), 127, 0, 0, 1)
+	} else {
+		family = proxyProtocolTCP6
+		addrs = append(append(addrs, src.IP...), net.IPv6loopback...)
+	}
+	addrs = append(addrs, byte(src.Port>>8), byte(src.Port), 0x1f, 0x90)
+	var l [2]byte
+	binary.BigEndian.PutUint16(l[:], uint16(len(addrs)))
+	hdr = append(hdr, 0x20|cmd, family, l[0], l[1])
+	return append(hdr, addrs...)
+}
+
+func TestProxyProtocolListener(t *testing.T) {
+	client4 := &net.TCPAddr{IP: net.ParseIP("1.2.3.4").To4(), Port: 4242}
+	client6 := &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 4242}
+	tests := []struct {
+		name       string
+		trusted    bool
+		prefix     []byte
+		wantRemote string
+		wantData   string
+		wantClosed bool
+	}{{
+		name:       "tcp4",
+		trusted:    true,
+		prefix:     proxyProtocolHeader(proxyProtocolCmdProxy, client4),
+		wantRemote: client4.String(),
+		wantData:   "GET / HTTP/1.1\r\n",
+	}, {
+		name:       "tcp6",
+		trusted:    true,
+		prefix:     proxyProtocolHeader(proxyProtocolCmdProxy, client6),
+		wantRemote: client6.String(),
+		wantData:   "GET / HTTP/1.1\r\n",
+	}, {
+		name:     "local command keeps the address",
+		trusted:  true,
+		prefix:   proxyProtocolHeader(proxyProtocolCmdLocal, client4),
+		wantData: "GET / HTTP/1.1\r\n",
+	}, {
+		name:     "no header",
+		trusted:  true,
+		wantData: "GET / HTTP/1.1\r\n",
+	}, {
+		name:     "untrusted",
+		prefix:   []byte("x"),
+		wantData: "xGET / HTTP/1.1\r\n",
+	}, {
+		// The clients outside of the trusted proxies can't claim another address.
+		name:     "untrusted with header",
+		prefix:   proxyProtocolHeader(proxyProtocolCmdProxy, client4),
+		wantData: string(proxyProtocolHeader(proxyProtocolCmdProxy, client4)) + "GET / HTTP/1.1\r\n",
+	}, {
+		name:    "unsupported version",
+		trusted: true,
+		prefix: func() []byte {
+			hdr := proxyProtocolHeader(proxyProtocolCmdProxy, client4)
+			hdr[12] = 0x11
+			return hdr
+		}(),
+		wantClosed: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tl, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal("Listen() =", err)
+			}
+			trusted := test.trusted
+			l := NewProxyProtocolListener(tl, func(addr net.Addr) bool {
+				// The connections come from the loopback address.
+				return trusted && addr.(*net.TCPAddr).IP.IsLoopback()
+			})
+			defer l.Close()
+
+			c, err := net.Dial("tcp", tl.Addr().String())
+			if err != nil {
+				t.Fatal("Dial() =", err)
+			}
+			defer c.Close()
+			if _, err := c.Write(append(test.prefix, "GET / HTTP/1.1\r\n"...)); err != nil {
+				t.Fatal("Write() =", err)
+			}
+
+			if test.wantClosed {
+				// The connection is dropped rather than accepted.
+				c.SetReadDeadline(time.Now().Add(time.Second))
+				if _, err := c.Read(make([]byte, 1)); err == nil {
+					t.Error("Read() succeeded, want the connection closed")
+				}
+				return
+			}
+
+			sc, err := l.Accept()
+			if err != nil {
+				t.Fatal("Accept() =", err)
+			}
+			defer sc.Close()
+
+			wantRemote := test.wantRemote
+			if wantRemote == "" {
+				wantRemote = c.LocalAddr().String()
+			}
+			if got := sc.RemoteAddr().String(); got != wantRemote {
+				t.Errorf("RemoteAddr = %s, want: %s", got, wantRemote)
+			}
+			c.Close()
+			data, err := ioutil.ReadAll(sc)
+			if err != nil {
+				t.Fatal("ReadAll() =", err)
+			}
+			if got := string(data); got != test.wantData {
+				t.Errorf("Data = %q, want: %q", got, test.wantData)
+			}
+		})
+	}
+}
+
+func TestProxyProtocolListenerClose(t *testing.T) {
+	tl, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Listen() =", err)
+	}
+	l := NewProxyProtocolListener(tl, func(net.Addr) bool { return true })
+	l.Close()
+	if _, err := l.Accept(); err == nil {
+		t.Error("Accept() succeeded on a closed listener")
+	}
+}
@@ -61,12 +61,25 @@ type RequestLogResponse struct {
 	Latency float64
 }
 
+// RequestLogProxy provides the information about proxying the request to the
+// revision by the activator for the template execution.
+type RequestLogProxy struct {
+	// Dest is the address of the pod the request was proxied to.
+	Dest string
+	// QueueingDelay is the time the request waited for the capacity, in seconds.
+	QueueingDelay float64
+	// UpstreamLatency is the time it took to proxy the request, in seconds.
+	UpstreamLatency float64
+}
+
 // RequestLogTemplateInput is the wrapper struct that provides all
 // necessary information for the template execution.
 type RequestLogTemplateInput struct {
 	Request  *http.Request
 	Response *RequestLogResponse
 	Revision *RequestLogRevision
+	// Proxy is only provided by the activator.
+	Proxy *RequestLogProxy
 }
 
 // RequestLogTemplateInputGetter defines a function returning the input to pass to a request log writer.
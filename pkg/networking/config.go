@@ -0,0 +1,374 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	network "knative.dev/networking/pkg"
+	"knative.dev/pkg/configmap"
+)
+
+const (
+	// IngressShardSizeKey is the name of the configuration entry that
+	// specifies the maximum number of rules in a single Ingress object.
+	IngressShardSizeKey = "ingress.shard-size"
+
+	// ActivatorRetriesKey is the name of the configuration entry that
+	// specifies how many times the activator retries a request which
+	// failed to reach the revision or got a retriable status code back.
+	ActivatorRetriesKey = "activator.retries"
+
+	// ActivatorRetriableStatusCodesKey is the name of the configuration
+	// entry that specifies the comma separated list of the response status
+	// codes on which the activator retries the request.
+	ActivatorRetriableStatusCodesKey = "activator.retriable-status-codes"
+
+	// ActivatorPerTryTimeoutKey is the name of the configuration entry that
+	// specifies the timeout of each individual attempt to proxy a request.
+	ActivatorPerTryTimeoutKey = "activator.per-try-timeout"
+
+	// ActivatorRetryNonIdempotentKey is the name of the configuration entry
+	// that specifies whether the activator also retries the requests with
+	// non idempotent methods, e.g. POST.
+	ActivatorRetryNonIdempotentKey = "activator.retry-non-idempotent"
+
+	// ActivatorRetryBufferSizeKey is the name of the configuration entry that
+	// specifies the maximum size, in bytes, of the request bodies the
+	// activator buffers to be able to replay them on retries.
+	ActivatorRetryBufferSizeKey = "activator.retry-buffer-size"
+
+	// ActivatorBackendTLSKey is the name of the configuration entry that
+	// specifies whether the activator proxies the requests to queue-proxy
+	// over TLS.
+	ActivatorBackendTLSKey = "activator.backend-tls"
+
+	// ActivatorResponseHintsKey is the name of the configuration entry that
+	// specifies whether the activator stamps its responses with the
+	// activator.HintHeaderName header.
+	ActivatorResponseHintsKey = "activator.response-hints"
+
+	// ActivatorPriorityHeaderKey is the name of the configuration entry that
+	// specifies the header marking the high priority requests.
+	ActivatorPriorityHeaderKey = "activator.priority-header"
+
+	// DataplaneTLSMinVersionKey is the name of the configuration entry that
+	// specifies the minimum TLS version accepted by the data-plane listeners.
+	DataplaneTLSMinVersionKey = "dataplane.tls-min-version"
+
+	// DataplaneTLSCipherSuitesKey is the name of the configuration entry that
+	// specifies the comma separated list of the cipher suites the data-plane
+	// listeners accept for TLS 1.2.
+	DataplaneTLSCipherSuitesKey = "dataplane.tls-cipher-suites"
+
+	// DataplaneTLSFIPSModeKey is the name of the configuration entry that
+	// restricts the data-plane listeners to FIPS 140-2 compatible TLS settings.
+	DataplaneTLSFIPSModeKey = "dataplane.tls-fips-mode"
+
+	// DataplaneProxyProtocolKey is the name of the configuration entry that
+	// specifies whether the data-plane listeners accept the PROXY protocol v2.
+	DataplaneProxyProtocolKey = "dataplane.proxy-protocol"
+
+	// DataplaneProxyProtocolTrustedCIDRsKey is the name of the configuration
+	// entry that specifies the comma separated list of the CIDRs of the
+	// proxies whose PROXY protocol headers the data-plane listeners accept.
+	DataplaneProxyProtocolTrustedCIDRsKey = "dataplane.proxy-protocol-trusted-cidrs"
+)
+
+// Config contains the serving specific networking configuration defined in
+// the network config map. It complements the configuration owned by
+// knative.dev/networking, which is parsed from the same config map.
+// +k8s:deepcopy-gen=true
+type Config struct {
+	// IngressShardSize is the maximum number of rules in a single Ingress.
+	// The rules of the Routes exceeding it, e.g. the ones with hundreds of
+	// tags, are sharded across multiple Ingress objects, to keep each of them
+	// within the etcd object size limits. Zero disables the sharding.
+	IngressShardSize int32
+
+	// ActivatorRetries is the number of times the activator retries a request
+	// which failed to reach the revision, timed out, or got one of the
+	// ActivatorRetriableStatusCodes back. Zero means a single attempt.
+	// Only requests whose body can be replayed are retried.
+	ActivatorRetries int32
+
+	// ActivatorRetryNonIdempotent makes the activator retry the requests with
+	// non idempotent methods too. By default only the requests with the
+	// idempotent methods, or an Idempotency-Key header, are retried.
+	ActivatorRetryNonIdempotent bool
+
+	// ActivatorRetryBufferSize is the maximum size, in bytes, of the request
+	// bodies the activator buffers in memory to replay them on retries.
+	// The requests with larger bodies get a single attempt.
+	ActivatorRetryBufferSize int64
+
+	// ActivatorRetriableStatusCodes are the response status codes on which
+	// the activator retries the request.
+	ActivatorRetriableStatusCodes []int
+
+	// ActivatorPerTryTimeout is the timeout of each individual attempt to
+	// proxy a request, until the response headers are received. Zero means
+	// no per attempt timeout.
+	ActivatorPerTryTimeout time.Duration
+
+	// ActivatorBackendTLS makes queue-proxy serve TLS on the BackendHTTPSPort,
+	// using the certificate from the BackendCertsSecretName secret, and
+	// the activator proxy the requests to it over TLS, validating that the
+	// certificate carries the BackendCertSAN of the revision's namespace.
+	// This encrypts the traffic between the activator and the application
+	// pods without requiring a service mesh.
+	// The secret is expected to be issued into the namespaces of the revisions
+	// by the cluster's certificate issuer.
+	ActivatorBackendTLS bool
+
+	// ActivatorResponseHints makes the activator stamp the responses with
+	// the pod the request was proxied to and the time it was buffered for,
+	// to correlate the tail latency with the activations without tracing.
+	ActivatorResponseHints bool
+
+	// ActivatorPriorityHeader is the header marking the requests as high
+	// priority, with the value "high". While the revision is activating,
+	// e.g. the interactive requests marked so take the capacity ahead of
+	// the buffered batch requests. Empty disables the priorities.
+	ActivatorPriorityHeader string
+
+	// DataplaneTLSMinVersion is the minimum TLS version accepted by the
+	// data-plane listeners.
+	DataplaneTLSMinVersion uint16
+
+	// DataplaneTLSCipherSuites are the cipher suites the data-plane listeners
+	// accept for TLS 1.2. Empty means the Go defaults.
+	DataplaneTLSCipherSuites []uint16
+
+	// DataplaneTLSFIPSMode restricts the data-plane listeners to
+	// FIPS 140-2 compatible TLS settings.
+	DataplaneTLSFIPSMode bool
+
+	// DataplaneProxyProtocol makes the activator and the queue-proxy read
+	// the client's address off the PROXY protocol v2 header the ingress
+	// prepends to the connections. Only enable it if the ingress does, since
+	// the clients could claim any address otherwise.
+	DataplaneProxyProtocol bool
+
+	// DataplaneProxyProtocolTrustedCIDRs are the CIDRs of the proxies the
+	// PROXY protocol headers are read from. The connections from the other
+	// addresses are served as is. They must be set when
+	// DataplaneProxyProtocol is.
+	DataplaneProxyProtocolTrustedCIDRs []string
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		ActivatorRetryBufferSize: 64 * 1024,
+
+		DataplaneTLSMinVersion: tls.VersionTLS12,
+	}
+}
+
+// NewConfigFromConfigMap creates a Config from the supplied ConfigMap.
+func NewConfigFromConfigMap(configMap *corev1.ConfigMap) (*Config, error) {
+	return NewConfigFromMap(configMap.Data)
+}
+
+// NewConfigFromMap creates a Config from the supplied data.
+func NewConfigFromMap(data map[string]string) (*Config, error) {
+	nc := defaultConfig()
+
+	var statusCodes, tlsMinVersion, cipherSuites, trustedCIDRs string
+	if err := configmap.Parse(data,
+		configmap.AsInt32(IngressShardSizeKey, &nc.IngressShardSize),
+		configmap.AsInt32(ActivatorRetriesKey, &nc.ActivatorRetries),
+		configmap.AsString(ActivatorRetriableStatusCodesKey, &statusCodes),
+		configmap.AsDuration(ActivatorPerTryTimeoutKey, &nc.ActivatorPerTryTimeout),
+		configmap.AsBool(ActivatorRetryNonIdempotentKey, &nc.ActivatorRetryNonIdempotent),
+		configmap.AsInt64(ActivatorRetryBufferSizeKey, &nc.ActivatorRetryBufferSize),
+		configmap.AsBool(ActivatorBackendTLSKey, &nc.ActivatorBackendTLS),
+		configmap.AsBool(ActivatorResponseHintsKey, &nc.ActivatorResponseHints),
+		configmap.AsString(ActivatorPriorityHeaderKey, &nc.ActivatorPriorityHeader),
+		configmap.AsString(DataplaneTLSMinVersionKey, &tlsMinVersion),
+		configmap.AsString(DataplaneTLSCipherSuitesKey, &cipherSuites),
+		configmap.AsBool(DataplaneTLSFIPSModeKey, &nc.DataplaneTLSFIPSMode),
+		configmap.AsBool(DataplaneProxyProtocolKey, &nc.DataplaneProxyProtocol),
+		configmap.AsString(DataplaneProxyProtocolTrustedCIDRsKey, &trustedCIDRs),
+	); err != nil {
+		return nil, fmt.Errorf("failed to parse data: %w", err)
+	}
+
+	if nc.IngressShardSize < 0 {
+		return nil, fmt.Errorf("%s must be non-negative, was: %d", IngressShardSizeKey, nc.IngressShardSize)
+	}
+
+	if nc.ActivatorRetries < 0 {
+		return nil, fmt.Errorf("%s must be non-negative, was: %d", ActivatorRetriesKey, nc.ActivatorRetries)
+	}
+	if nc.ActivatorPerTryTimeout < 0 {
+		return nil, fmt.Errorf("%s must be non-negative, was: %v", ActivatorPerTryTimeoutKey, nc.ActivatorPerTryTimeout)
+	}
+	if nc.ActivatorRetryBufferSize < 0 {
+		return nil, fmt.Errorf("%s must be non-negative, was: %d", ActivatorRetryBufferSizeKey, nc.ActivatorRetryBufferSize)
+	}
+	codes, err := parseStatusCodes(statusCodes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ActivatorRetriableStatusCodesKey, err)
+	}
+	nc.ActivatorRetriableStatusCodes = codes
+
+	if tlsMinVersion != "" {
+		if nc.DataplaneTLSMinVersion, err = parseTLSVersion(tlsMinVersion); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", DataplaneTLSMinVersionKey, err)
+		}
+	}
+	if nc.DataplaneTLSCipherSuites, err = parseCipherSuites(cipherSuites); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", DataplaneTLSCipherSuitesKey, err)
+	}
+	if nc.DataplaneTLSFIPSMode {
+		if err := nc.validateFIPS(); err != nil {
+			return nil, err
+		}
+	}
+	if nc.DataplaneProxyProtocolTrustedCIDRs, err = parseCIDRs(trustedCIDRs); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", DataplaneProxyProtocolTrustedCIDRsKey, err)
+	}
+	if nc.DataplaneProxyProtocol && len(nc.DataplaneProxyProtocolTrustedCIDRs) == 0 {
+		return nil, fmt.Errorf("%s must be set when %s is enabled",
+			DataplaneProxyProtocolTrustedCIDRsKey, DataplaneProxyProtocolKey)
+	}
+	return nc, nil
+}
+
+// parseCIDRs parses a comma separated list of CIDRs.
+func parseCIDRs(val string) ([]string, error) {
+	if strings.TrimSpace(val) == "" {
+		return nil, nil
+	}
+	var ret []string
+	for _, cidr := range strings.Split(val, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return nil, err
+		}
+		ret = append(ret, cidr)
+	}
+	return ret, nil
+}
+
+// ProxyProtocolTrusted returns whether the PROXY protocol header is read off
+// the connections from addr, i.e. whether DataplaneProxyProtocol is enabled
+// and addr is in one of DataplaneProxyProtocolTrustedCIDRs.
+func (c *Config) ProxyProtocolTrusted(addr net.Addr) bool {
+	if !c.DataplaneProxyProtocol {
+		return false
+	}
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, cidr := range c.DataplaneProxyProtocolTrustedCIDRs {
+		// The CIDRs are validated when the config is parsed.
+		if _, ipNet, err := net.ParseCIDR(cidr); err == nil && ipNet.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseStatusCodes parses a comma separated list of HTTP status codes.
+func parseStatusCodes(val string) ([]int, error) {
+	if strings.TrimSpace(val) == "" {
+		return nil, nil
+	}
+	var ret []int
+	for _, c := range strings.Split(val, ",") {
+		code, err := strconv.Atoi(strings.TrimSpace(c))
+		if err != nil {
+			return nil, err
+		}
+		if code < 400 || code > 599 {
+			return nil, fmt.Errorf("status code %d is not an error status code", code)
+		}
+		ret = append(ret, code)
+	}
+	return ret, nil
+}
+
+type cfgKey struct{}
+
+// FromContext extracts a Config from the provided context.
+func FromContext(ctx context.Context) *Config {
+	x, ok := ctx.Value(cfgKey{}).(*Config)
+	if ok {
+		return x
+	}
+	return nil
+}
+
+// FromContextOrDefaults is like FromContext, but when no Config is attached it
+// returns the default Config.
+func FromContextOrDefaults(ctx context.Context) *Config {
+	if cfg := FromContext(ctx); cfg != nil {
+		return cfg
+	}
+	return defaultConfig()
+}
+
+// ToContext attaches the provided Config to the provided context, returning the
+// new context with the Config attached.
+func ToContext(ctx context.Context, c *Config) context.Context {
+	return context.WithValue(ctx, cfgKey{}, c)
+}
+
+// Store is a typed wrapper around configmap.UntypedStore to handle the
+// serving specific part of the network config map.
+// +k8s:deepcopy-gen=false
+type Store struct {
+	*configmap.UntypedStore
+}
+
+// NewStore creates a new Store and optionally calls functions when the
+// network ConfigMap is updated.
+func NewStore(logger configmap.Logger, onAfterStore ...func(name string, value interface{})) *Store {
+	return &Store{
+		UntypedStore: configmap.NewUntypedStore(
+			"networking",
+			logger,
+			configmap.Constructors{
+				network.ConfigName: NewConfigFromConfigMap,
+			},
+			onAfterStore...,
+		),
+	}
+}
+
+// ToContext attaches the current Config state to the provided context.
+func (s *Store) ToContext(ctx context.Context) context.Context {
+	return ToContext(ctx, s.Load())
+}
+
+// Load creates a Config from the current config state of the Store.
+func (s *Store) Load() *Config {
+	if cfg, ok := s.UntypedLoad(network.ConfigName).(*Config); ok {
+		return cfg.DeepCopy()
+	}
+	return defaultConfig()
+}
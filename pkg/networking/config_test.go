@@ -0,0 +1,276 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	network "knative.dev/networking/pkg"
+	logtesting "knative.dev/pkg/logging/testing"
+
+	. "knative.dev/pkg/configmap/testing"
+)
+
+func TestOurConfig(t *testing.T) {
+	actual, example := ConfigMapsFromTestFile(t, network.ConfigName)
+	for _, tt := range []struct {
+		name    string
+		data    map[string]string
+		want    *Config
+		wantErr bool
+	}{{
+		name: "actual config",
+		data: actual.Data,
+		want: defaultConfig(),
+	}, {
+		name: "example config",
+		data: example.Data,
+		want: defaultConfig(),
+	}, {
+		name: "activator retry policy",
+		data: map[string]string{
+			ActivatorRetriesKey:              "3",
+			ActivatorRetriableStatusCodesKey: "502, 503",
+			ActivatorPerTryTimeoutKey:        "2s",
+			ActivatorRetryNonIdempotentKey:   "true",
+			ActivatorRetryBufferSizeKey:      "1024",
+		},
+		want: func() *Config {
+			c := defaultConfig()
+			c.ActivatorRetries = 3
+			c.ActivatorRetriableStatusCodes = []int{502, 503}
+			c.ActivatorPerTryTimeout = 2 * time.Second
+			c.ActivatorRetryNonIdempotent = true
+			c.ActivatorRetryBufferSize = 1024
+			return c
+		}(),
+	}, {
+		name: "activator backend tls",
+		data: map[string]string{
+			ActivatorBackendTLSKey: "true",
+		},
+		want: func() *Config {
+			c := defaultConfig()
+			c.ActivatorBackendTLS = true
+			return c
+		}(),
+	}, {
+		name: "malformed activator backend tls",
+		data: map[string]string{
+			ActivatorBackendTLSKey: "sure",
+		},
+		wantErr: true,
+	}, {
+		name: "activator response hints",
+		data: map[string]string{
+			ActivatorResponseHintsKey: "true",
+		},
+		want: func() *Config {
+			c := defaultConfig()
+			c.ActivatorResponseHints = true
+			return c
+		}(),
+	}, {
+		name: "activator priority header",
+		data: map[string]string{
+			ActivatorPriorityHeaderKey: "X-Priority",
+		},
+		want: func() *Config {
+			c := defaultConfig()
+			c.ActivatorPriorityHeader = "X-Priority"
+			return c
+		}(),
+	}, {
+		name: "ingress sharding",
+		data: map[string]string{
+			IngressShardSizeKey: "100",
+		},
+		want: func() *Config {
+			c := defaultConfig()
+			c.IngressShardSize = 100
+			return c
+		}(),
+	}, {
+		name: "negative ingress shard size",
+		data: map[string]string{
+			IngressShardSizeKey: "-1",
+		},
+		wantErr: true,
+	}, {
+		name: "negative retries",
+		data: map[string]string{
+			ActivatorRetriesKey: "-1",
+		},
+		wantErr: true,
+	}, {
+		name: "negative per try timeout",
+		data: map[string]string{
+			ActivatorPerTryTimeoutKey: "-1s",
+		},
+		wantErr: true,
+	}, {
+		name: "negative retry buffer size",
+		data: map[string]string{
+			ActivatorRetryBufferSizeKey: "-1",
+		},
+		wantErr: true,
+	}, {
+		name: "malformed status code",
+		data: map[string]string{
+			ActivatorRetriableStatusCodesKey: "503,five hundred",
+		},
+		wantErr: true,
+	}, {
+		name: "non error status code",
+		data: map[string]string{
+			ActivatorRetriableStatusCodesKey: "200",
+		},
+		wantErr: true,
+	}, {
+		name: "dataplane TLS",
+		data: map[string]string{
+			DataplaneTLSMinVersionKey:   "1.3",
+			DataplaneTLSCipherSuitesKey: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+		},
+		want: func() *Config {
+			c := defaultConfig()
+			c.DataplaneTLSMinVersion = tls.VersionTLS13
+			c.DataplaneTLSCipherSuites = []uint16{
+				tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+				tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+			}
+			return c
+		}(),
+	}, {
+		name: "dataplane TLS FIPS mode",
+		data: map[string]string{
+			DataplaneTLSFIPSModeKey:     "true",
+			DataplaneTLSCipherSuitesKey: "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+		},
+		want: func() *Config {
+			c := defaultConfig()
+			c.DataplaneTLSFIPSMode = true
+			c.DataplaneTLSCipherSuites = []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384}
+			return c
+		}(),
+	}, {
+		name: "dataplane PROXY protocol",
+		data: map[string]string{
+			DataplaneProxyProtocolKey:             "true",
+			DataplaneProxyProtocolTrustedCIDRsKey: "10.0.0.0/8, fd00::/8",
+		},
+		want: func() *Config {
+			c := defaultConfig()
+			c.DataplaneProxyProtocol = true
+			c.DataplaneProxyProtocolTrustedCIDRs = []string{"10.0.0.0/8", "fd00::/8"}
+			return c
+		}(),
+	}, {
+		name: "dataplane PROXY protocol without trusted CIDRs",
+		data: map[string]string{
+			DataplaneProxyProtocolKey: "true",
+		},
+		wantErr: true,
+	}, {
+		name: "invalid PROXY protocol trusted CIDRs",
+		data: map[string]string{
+			DataplaneProxyProtocolKey:             "true",
+			DataplaneProxyProtocolTrustedCIDRsKey: "10.0.0.1",
+		},
+		wantErr: true,
+	}, {
+		name: "unsupported TLS version",
+		data: map[string]string{
+			DataplaneTLSMinVersionKey: "1.0",
+		},
+		wantErr: true,
+	}, {
+		name: "insecure cipher suite",
+		data: map[string]string{
+			DataplaneTLSCipherSuitesKey: "TLS_RSA_WITH_RC4_128_SHA",
+		},
+		wantErr: true,
+	}, {
+		name: "TLS 1.3 in FIPS mode",
+		data: map[string]string{
+			DataplaneTLSFIPSModeKey:   "true",
+			DataplaneTLSMinVersionKey: "1.3",
+		},
+		wantErr: true,
+	}, {
+		name: "non FIPS cipher suite in FIPS mode",
+		data: map[string]string{
+			DataplaneTLSFIPSModeKey:     "true",
+			DataplaneTLSCipherSuitesKey: "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+		},
+		wantErr: true,
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewConfigFromMap(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewConfigFromMap() error = %v, wantErr = %v", err, tt.wantErr)
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Error("Config mismatch (-want, +got):", cmp.Diff(tt.want, got))
+			}
+		})
+	}
+}
+
+func TestStoreLoadWithContext(t *testing.T) {
+	store := NewStore(logtesting.TestLogger(t))
+	store.OnConfigChanged(ConfigMapFromTestFile(t, network.ConfigName))
+
+	got := FromContext(store.ToContext(context.Background()))
+	if !cmp.Equal(got, defaultConfig()) {
+		t.Error("Config mismatch (-want, +got):", cmp.Diff(defaultConfig(), got))
+	}
+}
+
+func TestFromContextOrDefaults(t *testing.T) {
+	if got, want := FromContextOrDefaults(context.Background()), defaultConfig(); !cmp.Equal(got, want) {
+		t.Error("Config mismatch (-want, +got):", cmp.Diff(want, got))
+	}
+}
+
+func TestProxyProtocolTrusted(t *testing.T) {
+	c := &Config{
+		DataplaneProxyProtocol:             true,
+		DataplaneProxyProtocolTrustedCIDRs: []string{"10.0.0.0/8", "fd00::/8"},
+	}
+	for addr, want := range map[net.Addr]bool{
+		&net.TCPAddr{IP: net.ParseIP("10.1.2.3")}:  true,
+		&net.TCPAddr{IP: net.ParseIP("fd00::1")}:   true,
+		&net.TCPAddr{IP: net.ParseIP("1.2.3.4")}:   false,
+		&net.TCPAddr{IP: net.ParseIP("2001::1")}:   false,
+		&net.UnixAddr{Name: "/tmp/s", Net: "unix"}: false,
+	} {
+		if got := c.ProxyProtocolTrusted(addr); got != want {
+			t.Errorf("ProxyProtocolTrusted(%s) = %v, want: %v", addr, got, want)
+		}
+	}
+
+	c.DataplaneProxyProtocol = false
+	if c.ProxyProtocolTrusted(&net.TCPAddr{IP: net.ParseIP("10.1.2.3")}) {
+		t.Error("ProxyProtocolTrusted() = true with the PROXY protocol disabled")
+	}
+}
@@ -34,6 +34,14 @@ const (
 	// by queue-proxy for autoscaler.
 	AutoscalingQueueMetricsPort = 9090
 
+	// BackendHTTPSPort is the port on which queue-proxy serves TLS
+	// to the activator, when the backend TLS is enabled.
+	BackendHTTPSPort = 8112
+
+	// BackendHTTPSPortName is the name of the private service port
+	// targeting the BackendHTTPSPort.
+	BackendHTTPSPortName = "https"
+
 	// UserQueueMetricsPort specifies the port number for metrics emitted
 	// by queue-proxy for end user.
 	UserQueueMetricsPort = 9091
@@ -41,6 +49,16 @@ const (
 	// ActivatorServiceName is the name of the activator Kubernetes service.
 	ActivatorServiceName = "activator-service"
 
+	// BackendCertsSecretName is the name of the secret in the namespace of
+	// the revision, holding the certificate (tls.crt and tls.key) queue-proxy
+	// serves TLS with, when the backend TLS is enabled. The certificate
+	// must carry the BackendCertSAN of the namespace.
+	BackendCertsSecretName = "serving-backend-certs"
+
+	// BackendCertsMountPath is the path in the queue-proxy container,
+	// where the BackendCertsSecretName secret is mounted.
+	BackendCertsMountPath = "/var/lib/knative/backend-certs"
+
 	// SKSLabelKey is the label key that SKS Controller attaches to the
 	// underlying resources it controls.
 	SKSLabelKey = networking.GroupName + "/serverlessservice"
@@ -48,6 +66,12 @@ const (
 	// ServiceTypeKey is the label key attached to a service specifying the type of service.
 	// e.g. Public, Private.
 	ServiceTypeKey = networking.GroupName + "/serviceType"
+
+	// ActivatorsAnnotationKey is the annotation attached to the public
+	// endpoints of a revision, listing the comma separated IP addresses of the
+	// activators assigned to back the revision. When absent, all the
+	// activators back the revision.
+	ActivatorsAnnotationKey = networking.GroupName + "/activators"
 )
 
 // ServiceType is the enumeration type for the Kubernetes services
@@ -0,0 +1,81 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsLongLivedRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{{
+		name: "plain request",
+	}, {
+		name: "websocket",
+		headers: map[string]string{
+			"Connection": "Upgrade",
+			"Upgrade":    "websocket",
+		},
+		want: true,
+	}, {
+		name: "websocket, multiple connection tokens",
+		headers: map[string]string{
+			"Connection": "keep-alive, upgrade",
+			"Upgrade":    "WebSocket",
+		},
+		want: true,
+	}, {
+		name: "h2c upgrade",
+		headers: map[string]string{
+			"Connection": "Upgrade, HTTP2-Settings",
+			"Upgrade":    "h2c",
+		},
+	}, {
+		name: "upgrade header without connection",
+		headers: map[string]string{
+			"Upgrade": "websocket",
+		},
+	}, {
+		name: "server-sent events",
+		headers: map[string]string{
+			"Accept": "text/event-stream",
+		},
+		want: true,
+	}, {
+		name: "json",
+		headers: map[string]string{
+			"Accept": "application/json",
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			for k, v := range test.headers {
+				r.Header.Set(k, v)
+			}
+			if got := IsLongLivedRequest(r); got != test.want {
+				t.Errorf("IsLongLivedRequest() = %v, want: %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// fipsCipherSuites are the cipher suites approved by FIPS 140-2,
+// i.e. the ones BoringCrypto permits for TLS 1.2.
+var fipsCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// fipsCurves are the elliptic curves approved by FIPS 140-2.
+var fipsCurves = []tls.CurveID{tls.CurveP256, tls.CurveP384}
+
+func parseTLSVersion(val string) (uint16, error) {
+	v, ok := tlsVersions[strings.TrimSpace(val)]
+	if !ok {
+		return 0, fmt.Errorf("unsupported TLS version %q, supported versions are 1.2 and 1.3", val)
+	}
+	return v, nil
+}
+
+// FormatTLSVersion returns the name of the TLS version v, as accepted by
+// DataplaneTLSMinVersionKey, or "" if it is not supported.
+func FormatTLSVersion(v uint16) string {
+	for name, tv := range tlsVersions {
+		if tv == v {
+			return name
+		}
+	}
+	return ""
+}
+
+// FormatCipherSuites returns the comma separated names of the cipher suites,
+// as accepted by DataplaneTLSCipherSuitesKey.
+func FormatCipherSuites(ids []uint16) string {
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		names = append(names, tls.CipherSuiteName(id))
+	}
+	return strings.Join(names, ",")
+}
+
+// parseCipherSuites parses a comma separated list of cipher suite names,
+// as named by crypto/tls. Only the suites without known security issues
+// are accepted.
+func parseCipherSuites(val string) ([]uint16, error) {
+	if strings.TrimSpace(val) == "" {
+		return nil, nil
+	}
+	known := make(map[string]uint16)
+	for _, cs := range tls.CipherSuites() {
+		known[cs.Name] = cs.ID
+	}
+	var ret []uint16
+	for _, name := range strings.Split(val, ",") {
+		name = strings.TrimSpace(name)
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported cipher suite %q", name)
+		}
+		ret = append(ret, id)
+	}
+	return ret, nil
+}
+
+// validateFIPS checks that the TLS settings of c are FIPS 140-2 compatible.
+func (c *Config) validateFIPS() error {
+	if c.DataplaneTLSMinVersion > tls.VersionTLS12 {
+		return fmt.Errorf("%s must be 1.2 when %s is enabled", DataplaneTLSMinVersionKey, DataplaneTLSFIPSModeKey)
+	}
+	for _, id := range c.DataplaneTLSCipherSuites {
+		if !isFIPSCipherSuite(id) {
+			return fmt.Errorf("cipher suite %s is not FIPS approved", tls.CipherSuiteName(id))
+		}
+	}
+	return nil
+}
+
+func isFIPSCipherSuite(id uint16) bool {
+	for _, fid := range fipsCipherSuites {
+		if fid == id {
+			return true
+		}
+	}
+	return false
+}
+
+// DataplaneTLSConfig returns the TLS configuration for the data-plane
+// listeners, constrained as per the network config map.
+// In FIPS mode the connections are restricted to TLS 1.2 with FIPS approved
+// cipher suites and curves, which matches what BoringCrypto allows.
+func (c *Config) DataplaneTLSConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion:   c.DataplaneTLSMinVersion,
+		CipherSuites: c.DataplaneTLSCipherSuites,
+	}
+	if c.DataplaneTLSFIPSMode {
+		cfg.MaxVersion = tls.VersionTLS12
+		cfg.CurvePreferences = fipsCurves
+		if len(cfg.CipherSuites) == 0 {
+			cfg.CipherSuites = fipsCipherSuites
+		}
+	}
+	return cfg
+}
+
+// BackendCertSAN returns the subject alternative name the certificates
+// queue-proxy serves TLS with in the namespace must carry. The activator
+// verifies it, when proxying the requests over TLS.
+func BackendCertSAN(namespace string) string {
+	return "kn-user-" + namespace
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDataplaneTLSConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *Config
+		want *tls.Config
+	}{{
+		name: "defaults",
+		cfg:  defaultConfig(),
+		want: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		},
+	}, {
+		name: "cipher suites",
+		cfg: &Config{
+			DataplaneTLSMinVersion:   tls.VersionTLS13,
+			DataplaneTLSCipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		},
+		want: &tls.Config{
+			MinVersion:   tls.VersionTLS13,
+			CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		},
+	}, {
+		name: "FIPS mode",
+		cfg: &Config{
+			DataplaneTLSMinVersion: tls.VersionTLS12,
+			DataplaneTLSFIPSMode:   true,
+		},
+		want: &tls.Config{
+			MinVersion:       tls.VersionTLS12,
+			MaxVersion:       tls.VersionTLS12,
+			CipherSuites:     fipsCipherSuites,
+			CurvePreferences: fipsCurves,
+		},
+	}, {
+		name: "FIPS mode with cipher suites",
+		cfg: &Config{
+			DataplaneTLSMinVersion:   tls.VersionTLS12,
+			DataplaneTLSCipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384},
+			DataplaneTLSFIPSMode:     true,
+		},
+		want: &tls.Config{
+			MinVersion:       tls.VersionTLS12,
+			MaxVersion:       tls.VersionTLS12,
+			CipherSuites:     []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384},
+			CurvePreferences: fipsCurves,
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.cfg.DataplaneTLSConfig()
+			if got.MinVersion != test.want.MinVersion || got.MaxVersion != test.want.MaxVersion ||
+				!cmp.Equal(got.CipherSuites, test.want.CipherSuites) ||
+				!cmp.Equal(got.CurvePreferences, test.want.CurvePreferences) {
+				t.Errorf("DataplaneTLSConfig() = {Min: %x, Max: %x, CipherSuites: %v, Curves: %v}, want: {Min: %x, Max: %x, CipherSuites: %v, Curves: %v}",
+					got.MinVersion, got.MaxVersion, got.CipherSuites, got.CurvePreferences,
+					test.want.MinVersion, test.want.MaxVersion, test.want.CipherSuites, test.want.CurvePreferences)
+			}
+		})
+	}
+}
+
+func TestFormatTLSSettings(t *testing.T) {
+	want := &Config{
+		DataplaneTLSMinVersion: tls.VersionTLS12,
+		DataplaneTLSCipherSuites: []uint16{
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		},
+	}
+	// The formatted settings parse back to the same values.
+	got, err := NewConfigFromMap(map[string]string{
+		DataplaneTLSMinVersionKey:   FormatTLSVersion(want.DataplaneTLSMinVersion),
+		DataplaneTLSCipherSuitesKey: FormatCipherSuites(want.DataplaneTLSCipherSuites),
+	})
+	if err != nil {
+		t.Fatal("NewConfigFromMap() =", err)
+	}
+	if got.DataplaneTLSMinVersion != want.DataplaneTLSMinVersion {
+		t.Errorf("DataplaneTLSMinVersion = %x, want: %x", got.DataplaneTLSMinVersion, want.DataplaneTLSMinVersion)
+	}
+	if !cmp.Equal(got.DataplaneTLSCipherSuites, want.DataplaneTLSCipherSuites) {
+		t.Errorf("DataplaneTLSCipherSuites = %v, want: %v", got.DataplaneTLSCipherSuites, want.DataplaneTLSCipherSuites)
+	}
+
+	if got := FormatTLSVersion(tls.VersionTLS13); got != "1.3" {
+		t.Errorf("FormatTLSVersion(1.3) = %q, want: 1.3", got)
+	}
+	if got := FormatCipherSuites(nil); got != "" {
+		t.Errorf("FormatCipherSuites(nil) = %q, want: empty", got)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	pkgnet "knative.dev/pkg/network"
+)
+
+// BackendTLSConfig returns the TLS config to connect to the user container
+// serving HTTPS with. The certificate chain of the user container is verified
+// against the CA bundle in caFile, if set, and isn't verified otherwise. The
+// host name isn't verified either, since the user container is reached on
+// the pod's localhost.
+func BackendTLSConfig(caFile string) (*tls.Config, error) {
+	//nolint:gosec // The chain is verified below, the host name on purpose not.
+	conf := &tls.Config{InsecureSkipVerify: true}
+	if caFile == "" {
+		return conf, nil
+	}
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CA bundle: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in the CA bundle %s", caFile)
+	}
+	conf.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("no certificate presented by the user container")
+		}
+		certs := make([]*x509.Certificate, 0, len(rawCerts))
+		for _, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			certs = append(certs, cert)
+		}
+		opts := x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range certs[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := certs[0].Verify(opts)
+		return err
+	}
+	return conf, nil
+}
+
+// NewBackendTLSTransport returns a transport speaking TLS, with the given
+// config, to the user container serving HTTPS. HTTP/2 is negotiated when
+// the user container supports it.
+func NewBackendTLSTransport(conf *tls.Config, opts TransportOptions) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = pkgnet.DialWithBackOff
+	t.TLSClientConfig = conf
+	opts.apply(t)
+	return t
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writePEM(t *testing.T, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.crt")
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal("Failed to write the CA bundle:", err)
+	}
+	return path
+}
+
+func otherCA(t *testing.T) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("Failed to generate a key:", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "other"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal("Failed to create a certificate:", err)
+	}
+	return der
+}
+
+func TestBackendTLSTransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		caFile  string
+		wantErr bool
+	}{{
+		name: "no CA bundle",
+	}, {
+		name:   "CA bundle of the server",
+		caFile: writePEM(t, server.Certificate().Raw),
+	}, {
+		name:    "other CA bundle",
+		caFile:  writePEM(t, otherCA(t)),
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := BackendTLSConfig(test.caFile)
+			if err != nil {
+				t.Fatal("BackendTLSConfig() =", err)
+			}
+			client := &http.Client{Transport: NewBackendTLSTransport(conf, TransportOptions{MaxIdleConnsPerHost: 10})}
+			resp, err := client.Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if got := err != nil; got != test.wantErr {
+				t.Errorf("Get() = %v, wantErr: %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestBackendTLSConfigErrors(t *testing.T) {
+	if _, err := BackendTLSConfig(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("BackendTLSConfig() succeeded for a missing file")
+	}
+	path := filepath.Join(t.TempDir(), "ca.crt")
+	if err := ioutil.WriteFile(path, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal("Failed to write the CA bundle:", err)
+	}
+	if _, err := BackendTLSConfig(path); err == nil {
+		t.Error("BackendTLSConfig() succeeded without certificates")
+	}
+}
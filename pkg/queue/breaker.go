@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"time"
 
 	"go.uber.org/atomic"
 )
@@ -30,6 +31,15 @@ var (
 	ErrRelease = errors.New("semaphore release error: returned tokens must be <= acquired tokens")
 	// ErrRequestQueueFull indicates the breaker queue depth was exceeded.
 	ErrRequestQueueFull = errors.New("pending request queue full")
+	// ErrRequestQueueDepthExceeded indicates the configured bound of the
+	// requests waiting for capacity was exceeded.
+	ErrRequestQueueDepthExceeded = errors.New("pending request queue depth exceeded")
+	// ErrRequestQueueTimeout indicates the request waited in the queue for
+	// longer than permitted.
+	ErrRequestQueueTimeout = errors.New("pending request queue timeout")
+	// ErrCapacityExceeded indicates there was no capacity for the request
+	// and it was not permitted to wait for it.
+	ErrCapacityExceeded = errors.New("capacity exceeded")
 )
 
 // MaxBreakerCapacity is the largest valid value for the MaxConcurrency value of BreakerParams.
@@ -41,6 +51,12 @@ type BreakerParams struct {
 	QueueDepth      int
 	MaxConcurrency  int
 	InitialCapacity int
+	// QueueTimeout is the maximum time a request waits for capacity in Maybe.
+	// Zero means no limit, other than the request's own deadline.
+	QueueTimeout time.Duration
+	// RejectOverflow makes Maybe fail with ErrCapacityExceeded, rather than
+	// wait, when there is no capacity for the request right away.
+	RejectOverflow bool
 }
 
 // Breaker is a component that enforces a concurrency limit on the
@@ -48,9 +64,11 @@ type BreakerParams struct {
 // executions in excess of the concurrency limit. Function call attempts
 // beyond the limit of the queue are failed immediately.
 type Breaker struct {
-	inFlight   atomic.Int64
-	totalSlots int64
-	sem        *semaphore
+	inFlight       atomic.Int64
+	totalSlots     int64
+	sem            *semaphore
+	queueTimeout   time.Duration
+	rejectOverflow bool
 
 	// release is the callback function returned to callers by Reserve to
 	// allow the reservation made by Reserve to be released.
@@ -71,8 +89,10 @@ func NewBreaker(params BreakerParams) *Breaker {
 	}
 
 	b := &Breaker{
-		totalSlots: int64(params.QueueDepth + params.MaxConcurrency),
-		sem:        newSemaphore(params.MaxConcurrency, params.InitialCapacity),
+		totalSlots:     int64(params.QueueDepth + params.MaxConcurrency),
+		sem:            newSemaphore(params.MaxConcurrency, params.InitialCapacity),
+		queueTimeout:   params.QueueTimeout,
+		rejectOverflow: params.RejectOverflow,
 	}
 
 	// Allocating the closure returned by Reserve here avoids an allocation in Reserve.
@@ -137,6 +157,16 @@ func (b *Breaker) Reserve(ctx context.Context) (func(), bool) {
 // already consumed, Maybe returns immediately without calling thunk. If
 // the thunk was executed, Maybe returns true, else false.
 func (b *Breaker) Maybe(ctx context.Context, thunk func()) error {
+	if b.rejectOverflow {
+		release, ok := b.Reserve(ctx)
+		if !ok {
+			return ErrCapacityExceeded
+		}
+		defer release()
+		thunk()
+		return nil
+	}
+
 	if !b.tryAcquirePending() {
 		return ErrRequestQueueFull
 	}
@@ -144,7 +174,7 @@ func (b *Breaker) Maybe(ctx context.Context, thunk func()) error {
 	defer b.releasePending()
 
 	// Wait for capacity in the active queue.
-	if err := b.sem.acquire(ctx); err != nil {
+	if err := b.acquire(ctx); err != nil {
 		return err
 	}
 	// Defer releasing capacity in the active.
@@ -159,6 +189,20 @@ func (b *Breaker) Maybe(ctx context.Context, thunk func()) error {
 	return nil
 }
 
+// acquire waits for capacity in the semaphore for up to the queue timeout.
+func (b *Breaker) acquire(ctx context.Context) error {
+	if b.queueTimeout <= 0 {
+		return b.sem.acquire(ctx)
+	}
+	queueCtx, cancel := context.WithTimeout(ctx, b.queueTimeout)
+	defer cancel()
+	err := b.sem.acquire(queueCtx)
+	if err != nil && ctx.Err() == nil {
+		return ErrRequestQueueTimeout
+	}
+	return err
+}
+
 // InFlight returns the number of requests currently in flight in this breaker.
 func (b *Breaker) InFlight() int {
 	return int(b.inFlight.Load())
@@ -174,10 +218,25 @@ func (b *Breaker) Capacity() int {
 	return b.sem.Capacity()
 }
 
+type highPriorityKey struct{}
+
+// WithHighPriority marks the context of a request as high priority: while
+// it waits for the capacity of a Breaker, the capacity freed up is handed
+// to it ahead of the requests of the normal priority.
+func WithHighPriority(ctx context.Context) context.Context {
+	return context.WithValue(ctx, highPriorityKey{}, struct{}{})
+}
+
+func isHighPriority(ctx context.Context) bool {
+	return ctx.Value(highPriorityKey{}) != nil
+}
+
 // newSemaphore creates a semaphore with the desired initial capacity.
 func newSemaphore(maxCapacity, initialCapacity int) *semaphore {
-	queue := make(chan struct{}, maxCapacity)
-	sem := &semaphore{queue: queue}
+	sem := &semaphore{
+		queue:         make(chan struct{}, maxCapacity),
+		priorityQueue: make(chan struct{}, maxCapacity),
+	}
 	sem.updateCapacity(initialCapacity)
 	return sem
 }
@@ -192,9 +251,14 @@ func newSemaphore(maxCapacity, initialCapacity int) *semaphore {
 // if capacity becomes free. It's not consistently used in accordance to actual capacity
 // but is rather a communication vehicle to ensure waiting routines are properly woken
 // up.
+// The high priority waiters are counted in priorityWaiting and woken up through
+// priorityQueue, while there are any the normal ones don't take the capacity.
 type semaphore struct {
 	state atomic.Uint64
 	queue chan struct{}
+
+	priorityWaiting atomic.Int64
+	priorityQueue   chan struct{}
 }
 
 // tryAcquire receives a token from the semaphore if there is one otherwise returns false.
@@ -214,11 +278,14 @@ func (s *semaphore) tryAcquire() bool {
 
 // acquire acquires capacity from the semaphore.
 func (s *semaphore) acquire(ctx context.Context) error {
+	if isHighPriority(ctx) {
+		return s.acquirePriority(ctx)
+	}
 	for {
 		old := s.state.Load()
 		capacity, in := unpack(old)
 
-		if in >= capacity {
+		if in >= capacity || s.priorityWaiting.Load() > 0 {
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
@@ -235,6 +302,73 @@ func (s *semaphore) acquire(ctx context.Context) error {
 	}
 }
 
+// acquirePriority acquires capacity from the semaphore ahead of the
+// normal priority waiters.
+func (s *semaphore) acquirePriority(ctx context.Context) error {
+	s.priorityWaiting.Inc()
+	defer func() {
+		if s.priorityWaiting.Dec() > 0 {
+			return
+		}
+		// The normal priority waiters might be waiting with capacity free,
+		// so the last high priority waiter wakes them up, in place of the
+		// wakeups it did not need.
+		for drained := false; !drained; {
+			select {
+			case <-s.priorityQueue:
+			default:
+				drained = true
+			}
+		}
+		capacity, in := unpack(s.state.Load())
+		for ; in < capacity && len(s.queue) < cap(s.queue); in++ {
+			s.poke(s.queue)
+		}
+	}()
+
+	for {
+		old := s.state.Load()
+		capacity, in := unpack(old)
+
+		if in >= capacity {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-s.priorityQueue:
+			}
+			// Force reload state.
+			continue
+		}
+
+		in++
+		if s.state.CAS(old, pack(capacity, in)) {
+			return nil
+		}
+	}
+}
+
+// wake wakes up a goroutine waiting for capacity, the high priority ones first.
+func (s *semaphore) wake() {
+	if s.priorityWaiting.Load() > 0 {
+		s.poke(s.priorityQueue)
+	} else {
+		s.poke(s.queue)
+	}
+}
+
+// poke sends a wakeup to the queue.
+func (s *semaphore) poke(queue chan struct{}) {
+	select {
+	case queue <- struct{}{}:
+	default:
+		// We generate more wakeups than we might need as we don't know
+		// how many goroutines are waiting here. It is therefore okay
+		// to drop the poke on the floor here as this case would mean we
+		// have enough wakeups to wake up as many goroutines as this semaphore
+		// can take, which is guaranteed to be enough.
+	}
+}
+
 // release releases capacity in the semaphore.
 // If the semaphore capacity was reduced in between and as a result inFlight is greater
 // than capacity, we don't wake up goroutines as they'd not get any capacity anyway.
@@ -250,15 +384,7 @@ func (s *semaphore) release() error {
 		in--
 		if s.state.CAS(old, pack(capacity, in)) {
 			if in < capacity {
-				select {
-				case s.queue <- struct{}{}:
-				default:
-					// We generate more wakeups than we might need as we don't know
-					// how many goroutines are waiting here. It is therefore okay
-					// to drop the poke on the floor here as this case would mean we
-					// have enough wakeups to wake up as many goroutines as this semaphore
-					// can take, which is guaranteed to be enough.
-				}
+				s.wake()
 			}
 			return nil
 		}
@@ -280,11 +406,7 @@ func (s *semaphore) updateCapacity(size int) {
 		if s.state.CAS(old, pack(s64, in)) {
 			if s64 > capacity {
 				for i := uint64(0); i < s64-capacity; i++ {
-					select {
-					case s.queue <- struct{}{}:
-					default:
-						// See comment in `release` for explanation of this case.
-					}
+					s.wake()
 				}
 			}
 			return
@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"testing"
 	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 const (
@@ -208,6 +210,41 @@ func TestBreakerCancel(t *testing.T) {
 	reqs.processSuccessfully(t)
 }
 
+func TestBreakerQueueTimeout(t *testing.T) {
+	b := NewBreaker(BreakerParams{QueueDepth: 1, MaxConcurrency: 1, InitialCapacity: 0, QueueTimeout: 10 * time.Millisecond})
+
+	if err := b.Maybe(context.Background(), func() {}); err != ErrRequestQueueTimeout {
+		t.Errorf("Maybe() = %v, want: %v", err, ErrRequestQueueTimeout)
+	}
+
+	// The request's own deadline takes precedence.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := b.Maybe(ctx, func() {}); err != context.Canceled {
+		t.Errorf("Maybe() = %v, want: %v", err, context.Canceled)
+	}
+
+	b.UpdateConcurrency(1)
+	if err := b.Maybe(context.Background(), func() {}); err != nil {
+		t.Errorf("Maybe() = %v, want: nil", err)
+	}
+}
+
+func TestBreakerRejectOverflow(t *testing.T) {
+	b := NewBreaker(BreakerParams{QueueDepth: 10, MaxConcurrency: 1, InitialCapacity: 1, RejectOverflow: true})
+
+	if err := b.Maybe(context.Background(), func() {
+		if err := b.Maybe(context.Background(), func() {}); err != ErrCapacityExceeded {
+			t.Errorf("Maybe() = %v, want: %v", err, ErrCapacityExceeded)
+		}
+	}); err != nil {
+		t.Errorf("Maybe() = %v, want: nil", err)
+	}
+	if got := b.InFlight(); got != 0 {
+		t.Errorf("InFlight() = %d, want: 0", got)
+	}
+}
+
 func TestBreakerUpdateConcurrency(t *testing.T) {
 	params := BreakerParams{QueueDepth: 1, MaxConcurrency: 1, InitialCapacity: 0}
 	b := NewBreaker(params)
@@ -295,6 +332,59 @@ func TestSemaphoreUpdateCapacity(t *testing.T) {
 	}
 }
 
+func TestSemaphoreAcquirePriority(t *testing.T) {
+	sem := newSemaphore(2, 1)
+	sem.acquire(context.Background())
+
+	acquired := make(chan string, 3)
+	acquire := func(ctx context.Context, name string) {
+		go func() {
+			if err := sem.acquire(ctx); err == nil {
+				acquired <- name
+			}
+		}()
+	}
+	acquire(context.Background(), "normal")
+	// Give the normal priority request the head start.
+	time.Sleep(semNoChangeTimeout)
+	acquire(WithHighPriority(context.Background()), "high")
+	cancelledCtx, cancel := context.WithCancel(WithHighPriority(context.Background()))
+	acquire(cancelledCtx, "cancelled")
+	if err := wait.PollImmediate(time.Millisecond, semAcquireTimeout, func() (bool, error) {
+		return sem.priorityWaiting.Load() == 2, nil
+	}); err != nil {
+		t.Fatal("High priority requests are not waiting:", err)
+	}
+	cancel()
+
+	// The freed up capacity goes to the high priority request.
+	sem.release()
+	select {
+	case got := <-acquired:
+		if got != "high" {
+			t.Errorf("Acquired by %q, want: high", got)
+		}
+	case <-time.After(semAcquireTimeout):
+		t.Fatal("Was not able to acquire token before timeout")
+	}
+
+	// The normal one is served once no high priority requests are waiting.
+	sem.updateCapacity(2)
+	select {
+	case got := <-acquired:
+		if got != "normal" {
+			t.Errorf("Acquired by %q, want: normal", got)
+		}
+	case <-time.After(semAcquireTimeout):
+		t.Fatal("Was not able to acquire token before timeout")
+	}
+	select {
+	case got := <-acquired:
+		t.Errorf("Unexpectedly acquired by %q", got)
+	case <-time.After(semNoChangeTimeout):
+	}
+}
+
 func TestPackUnpack(t *testing.T) {
 	wantL := uint64(256)
 	wantR := uint64(513)
@@ -0,0 +1,91 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"knative.dev/pkg/webhook/certificates/resources"
+)
+
+func writeCert(t *testing.T, dir string, modTime time.Time) {
+	t.Helper()
+	key, cert, _, err := resources.CreateCerts(context.Background(), "kn-user-ns", "backend", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal("CreateCerts() =", err)
+	}
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	if err := ioutil.WriteFile(keyFile, key, 0600); err != nil {
+		t.Fatal("WriteFile() =", err)
+	}
+	if err := ioutil.WriteFile(certFile, cert, 0600); err != nil {
+		t.Fatal("WriteFile() =", err)
+	}
+	if err := os.Chtimes(certFile, modTime, modTime); err != nil {
+		t.Fatal("Chtimes() =", err)
+	}
+}
+
+func TestCertificateReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	if err != nil {
+		t.Fatal("TempDir() =", err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := NewCertificateReloader(dir)
+	if _, err := r.GetCertificate(nil); err == nil {
+		t.Fatal("GetCertificate() succeeded without the certificate files")
+	}
+
+	now := time.Now()
+	writeCert(t, dir, now.Add(-time.Minute))
+	first, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal("GetCertificate() =", err)
+	}
+	if got, err := r.GetCertificate(nil); err != nil || got != first {
+		t.Errorf("GetCertificate() = %p, %v, want the cached certificate %p", got, err, first)
+	}
+
+	writeCert(t, dir, now)
+	second, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal("GetCertificate() =", err)
+	}
+	if bytes.Equal(first.Certificate[0], second.Certificate[0]) {
+		t.Error("GetCertificate() did not reload the updated certificate")
+	}
+
+	// A broken update keeps the previous certificate.
+	if err := ioutil.WriteFile(filepath.Join(dir, "tls.key"), []byte("garbage"), 0600); err != nil {
+		t.Fatal("WriteFile() =", err)
+	}
+	later := now.Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, "tls.crt"), later, later); err != nil {
+		t.Fatal("Chtimes() =", err)
+	}
+	if got, err := r.GetCertificate(nil); err != nil || got != second {
+		t.Errorf("GetCertificate() = %p, %v, want the previous certificate %p", got, err, second)
+	}
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"bufio"
+	"compress/gzip"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"knative.dev/pkg/websocket"
+)
+
+// DefaultCompressionTypes are the media types of the responses the queue
+// proxy compresses, unless the revision specifies its own list.
+var DefaultCompressionTypes = []string{
+	"application/javascript",
+	"application/json",
+	"application/xml",
+	"text/*",
+}
+
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(nil)
+	},
+}
+
+// ParseCompressionTypes parses the comma separated list of the media types
+// to compress, where `type/*` matches all the subtypes of the type.
+// DefaultCompressionTypes are returned if the list is empty.
+func ParseCompressionTypes(s string) []string {
+	var types []string
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			types = append(types, t)
+		}
+	}
+	if len(types) == 0 {
+		return DefaultCompressionTypes
+	}
+	return types
+}
+
+// CompressionHandler gzip-compresses the responses of the next handler of one
+// of the given media types, when the client accepts gzip and the response is
+// not already encoded by the user container.
+func CompressionHandler(types []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" || !acceptsGzip(r.Header) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, types: types}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// acceptsGzip returns true if the Accept-Encoding header permits gzip,
+// either explicitly or via the `*` wildcard.
+func acceptsGzip(h http.Header) bool {
+	gzipQ, starQ := -1.0, -1.0
+	for _, v := range h.Values("Accept-Encoding") {
+		for _, enc := range strings.Split(v, ",") {
+			parts := strings.Split(enc, ";")
+			q := 1.0
+			for _, p := range parts[1:] {
+				if p = strings.TrimSpace(p); strings.HasPrefix(p, "q=") {
+					if f, err := strconv.ParseFloat(p[2:], 64); err == nil {
+						q = f
+					}
+				}
+			}
+			switch strings.TrimSpace(parts[0]) {
+			case "gzip":
+				gzipQ = q
+			case "*":
+				starQ = q
+			}
+		}
+	}
+	if gzipQ >= 0 {
+		return gzipQ > 0
+	}
+	return starQ > 0
+}
+
+// compressWriter compresses the response if its status and headers
+// permit it, deciding right before the header is written.
+type compressWriter struct {
+	http.ResponseWriter
+	types       []string
+	gz          *gzip.Writer
+	wroteHeader bool
+}
+
+var (
+	_ http.Flusher  = (*compressWriter)(nil)
+	_ http.Hijacker = (*compressWriter)(nil)
+)
+
+func (w *compressWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if h := w.ResponseWriter.Header(); w.compressible(code, h) {
+			h.Del("Content-Length")
+			h.Set("Content-Encoding", "gzip")
+			h.Add("Vary", "Accept-Encoding")
+			w.gz = gzipWriterPool.Get().(*gzip.Writer)
+			w.gz.Reset(w.ResponseWriter)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *compressWriter) compressible(code int, h http.Header) bool {
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified {
+		return false
+	}
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	mt, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	for _, t := range w.types {
+		if t == mt || (strings.HasSuffix(t, "/*") && strings.HasPrefix(mt, t[:len(t)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher.
+func (w *compressWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, which is required for the
+// reverse proxy to handle the protocol upgrades.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return websocket.HijackIfPossible(w.ResponseWriter)
+}
+
+// close writes the gzip trailer and returns the writer to the pool.
+func (w *compressWriter) close() {
+	if w.gz == nil {
+		return
+	}
+	w.gz.Close()
+	w.gz.Reset(nil)
+	gzipWriterPool.Put(w.gz)
+	w.gz = nil
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseCompressionTypes(t *testing.T) {
+	if got, want := ParseCompressionTypes(""), DefaultCompressionTypes; !cmp.Equal(got, want) {
+		t.Error("ParseCompressionTypes(\"\") (-want, +got):", cmp.Diff(want, got))
+	}
+	if got, want := ParseCompressionTypes("application/JSON, text/*,"), []string{"application/json", "text/*"}; !cmp.Equal(got, want) {
+		t.Error("ParseCompressionTypes (-want, +got):", cmp.Diff(want, got))
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip;q=0.5", true},
+		{"gzip;q=0", false},
+		{"*", true},
+		{"gzip;q=0, *", false},
+		{"br", false},
+	}
+	for _, test := range tests {
+		h := http.Header{}
+		if test.accept != "" {
+			h.Set("Accept-Encoding", test.accept)
+		}
+		if got := acceptsGzip(h); got != test.want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", test.accept, got, test.want)
+		}
+	}
+}
+
+func TestCompressionHandler(t *testing.T) {
+	const body = `{"message": "hello hello hello hello hello"}`
+	tests := []struct {
+		name         string
+		method       string
+		accept       string
+		contentType  string
+		encoding     string
+		status       int
+		wantCompress bool
+	}{{
+		name:         "json",
+		accept:       "gzip",
+		contentType:  "application/json; charset=utf-8",
+		wantCompress: true,
+	}, {
+		name:         "text wildcard",
+		accept:       "gzip",
+		contentType:  "text/plain",
+		wantCompress: true,
+	}, {
+		name:        "client does not accept gzip",
+		contentType: "application/json",
+	}, {
+		name:        "not allowed content type",
+		accept:      "gzip",
+		contentType: "image/png",
+	}, {
+		name:        "already encoded",
+		accept:      "gzip",
+		contentType: "application/json",
+		encoding:    "br",
+	}, {
+		name:        "no content",
+		accept:      "gzip",
+		contentType: "application/json",
+		status:      http.StatusNoContent,
+	}, {
+		name:        "head",
+		method:      http.MethodHead,
+		accept:      "gzip",
+		contentType: "application/json",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := CompressionHandler(DefaultCompressionTypes, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", test.contentType)
+				if test.encoding != "" {
+					w.Header().Set("Content-Encoding", test.encoding)
+				}
+				if test.status != 0 {
+					w.WriteHeader(test.status)
+					return
+				}
+				w.Write([]byte(body))
+			}))
+
+			method := test.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/", nil)
+			if test.accept != "" {
+				req.Header.Set("Accept-Encoding", test.accept)
+			}
+			resp := httptest.NewRecorder()
+			h.ServeHTTP(resp, req)
+
+			if got := resp.Header().Get("Content-Encoding") == "gzip"; got != test.wantCompress {
+				t.Fatalf("Compressed = %v, want %v", got, test.wantCompress)
+			}
+			if !test.wantCompress {
+				return
+			}
+			if got, want := resp.Header().Get("Vary"), "Accept-Encoding"; got != want {
+				t.Errorf("Vary = %q, want %q", got, want)
+			}
+			gz, err := gzip.NewReader(resp.Body)
+			if err != nil {
+				t.Fatal("gzip.NewReader() =", err)
+			}
+			got, err := ioutil.ReadAll(gz)
+			if err != nil {
+				t.Fatal("ReadAll() =", err)
+			}
+			if string(got) != body {
+				t.Errorf("Body = %q, want %q", got, body)
+			}
+		})
+	}
+}
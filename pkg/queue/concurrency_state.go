@@ -0,0 +1,116 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// ConcurrencyStateHook calls the concurrency state endpoint, when the pod
+// transitions between having zero and non-zero requests in flight.
+type ConcurrencyStateHook struct {
+	// Endpoint is the URL the actions are POSTed to.
+	Endpoint string
+	// TokenPath is the file with the token to authenticate with.
+	TokenPath string
+	// Client is the client to call the endpoint with.
+	Client *http.Client
+}
+
+// Pause notifies the endpoint that the pod has no requests in flight.
+func (h *ConcurrencyStateHook) Pause() error {
+	return h.post("pause")
+}
+
+// Resume notifies the endpoint that the pod is about to serve a request.
+func (h *ConcurrencyStateHook) Resume() error {
+	return h.post("resume")
+}
+
+func (h *ConcurrencyStateHook) post(action string) error {
+	// The token is read every time, since it is rotated.
+	token, err := ioutil.ReadFile(h.TokenPath)
+	if err != nil {
+		return fmt.Errorf("failed to read the token: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, h.Endpoint,
+		bytes.NewBufferString(`{"action":"`+action+`"}`))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("failed to %s, the endpoint returned: %s", action, resp.Status)
+	}
+	return nil
+}
+
+// ConcurrencyStateHandler calls pause once the pod has no requests in flight
+// and resume before passing on the first request after that, e.g. to freeze
+// and thaw the processes of the user container, or to bill for the time the
+// pod is active. The requests wait for the resume to complete. Should it fail,
+// they are rejected with a 503.
+func ConcurrencyStateHandler(logger *zap.SugaredLogger, h http.Handler, pause, resume func() error) http.HandlerFunc {
+	var (
+		mux      sync.Mutex
+		inFlight int
+		paused   bool
+	)
+	return func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		if paused {
+			if err := resume(); err != nil {
+				mux.Unlock()
+				logger.Errorw("Failed to resume the pod", zap.Error(err))
+				http.Error(w, "failed to resume the pod", http.StatusServiceUnavailable)
+				return
+			}
+			paused = false
+		}
+		inFlight++
+		mux.Unlock()
+
+		defer func() {
+			mux.Lock()
+			defer mux.Unlock()
+			inFlight--
+			if inFlight > 0 {
+				return
+			}
+			if err := pause(); err != nil {
+				logger.Errorw("Failed to pause the pod", zap.Error(err))
+				return
+			}
+			paused = true
+		}()
+
+		h.ServeHTTP(w, r)
+	}
+}
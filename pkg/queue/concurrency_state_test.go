@@ -0,0 +1,159 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	ktesting "knative.dev/pkg/logging/testing"
+)
+
+func TestConcurrencyStateHandler(t *testing.T) {
+	logger := ktesting.TestLogger(t)
+	var calls []string
+	var resumeErr error
+	pause := func() error {
+		calls = append(calls, "pause")
+		return nil
+	}
+	resume := func() error {
+		calls = append(calls, "resume")
+		return resumeErr
+	}
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "serve")
+	})
+	h := ConcurrencyStateHandler(logger, inner, pause, resume)
+
+	serve := func() int {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Code
+	}
+
+	if got, want := serve(), http.StatusOK; got != want {
+		t.Errorf("StatusCode = %d, want: %d", got, want)
+	}
+	if got, want := serve(), http.StatusOK; got != want {
+		t.Errorf("StatusCode = %d, want: %d", got, want)
+	}
+	if want := []string{"serve", "pause", "resume", "serve", "pause"}; !cmp.Equal(calls, want) {
+		t.Errorf("Calls = %v, want: %v, diff(-want,+got): %s", calls, want, cmp.Diff(want, calls))
+	}
+
+	calls = nil
+	resumeErr = errors.New("frozen")
+	if got, want := serve(), http.StatusServiceUnavailable; got != want {
+		t.Errorf("StatusCode = %d, want: %d", got, want)
+	}
+	// Still paused, so the next request attempts to resume again.
+	resumeErr = nil
+	if got, want := serve(), http.StatusOK; got != want {
+		t.Errorf("StatusCode = %d, want: %d", got, want)
+	}
+	if want := []string{"resume", "resume", "serve", "pause"}; !cmp.Equal(calls, want) {
+		t.Errorf("Calls = %v, want: %v, diff(-want,+got): %s", calls, want, cmp.Diff(want, calls))
+	}
+}
+
+func TestConcurrencyStateHandlerNested(t *testing.T) {
+	logger := ktesting.TestLogger(t)
+	pauses := 0
+	var h http.HandlerFunc
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/outer" {
+			// A request arriving while another one is in flight must not pause.
+			h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/inner", nil))
+		}
+	})
+	h = ConcurrencyStateHandler(logger, inner, func() error {
+		pauses++
+		return nil
+	}, func() error { return nil })
+	h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/outer", nil))
+	if pauses != 1 {
+		t.Errorf("Pauses = %d, want: 1", pauses)
+	}
+}
+
+func TestConcurrencyStateHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "state-token")
+	if err != nil {
+		t.Fatal("Failed to create the temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+	tokenPath := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(tokenPath, []byte("secret\n"), 0600); err != nil {
+		t.Fatal("Failed to write the token:", err)
+	}
+
+	var (
+		gotAuth   string
+		gotAction string
+		status    = http.StatusOK
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		var body struct {
+			Action string `json:"action"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		gotAction = body.Action
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	hook := &ConcurrencyStateHook{
+		Endpoint:  srv.URL,
+		TokenPath: tokenPath,
+		Client:    srv.Client(),
+	}
+	if err := hook.Pause(); err != nil {
+		t.Fatal("Pause() =", err)
+	}
+	if got, want := gotAction, "pause"; got != want {
+		t.Errorf("Action = %q, want: %q", got, want)
+	}
+	if got, want := gotAuth, "Bearer secret"; got != want {
+		t.Errorf("Authorization = %q, want: %q", got, want)
+	}
+	if err := hook.Resume(); err != nil {
+		t.Fatal("Resume() =", err)
+	}
+	if got, want := gotAction, "resume"; got != want {
+		t.Errorf("Action = %q, want: %q", got, want)
+	}
+
+	status = http.StatusInternalServerError
+	if err := hook.Resume(); err == nil {
+		t.Error("Resume() = nil, wanted an error")
+	}
+
+	os.Remove(tokenPath)
+	status = http.StatusOK
+	if err := hook.Pause(); err == nil {
+		t.Error("Pause() = nil, wanted an error without a token")
+	}
+}
@@ -26,4 +26,25 @@ const (
 	// Main usage is to delay the termination of user-container until all
 	// accepted requests have been processed.
 	RequestQueueDrainPath = "/wait-for-drain"
+
+	// ConcurrencyStateTokenAudience is the audience of the service account
+	// token queue-proxy authenticates to the concurrency state endpoint with.
+	ConcurrencyStateTokenAudience = "concurrency-state-hook"
+
+	// ConcurrencyStateTokenMountPath is the directory the token is mounted into.
+	ConcurrencyStateTokenMountPath = "/var/run/secrets/tokens"
+
+	// ConcurrencyStateTokenFilename is the name of the token file.
+	ConcurrencyStateTokenFilename = "state-token"
+
+	// UnixSocketMountPath is the directory shared by queue-proxy and the user
+	// container, when the latter serves on a unix domain socket.
+	UnixSocketMountPath = "/var/run/knative-socket"
+
+	// UnixSocketPath is the unix domain socket the user container listens on.
+	UnixSocketPath = UnixSocketMountPath + "/user.sock"
+
+	// BackendCAMountPath is the directory the CA bundle of the user container
+	// serving HTTPS is mounted into.
+	BackendCAMountPath = "/var/run/secrets/knative-backend-ca"
 )
@@ -0,0 +1,119 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptrace"
+	"runtime"
+
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+	"knative.dev/pkg/profiling"
+)
+
+// DebugStatePath is the path on the debug server serving the DebugState.
+const DebugStatePath = "/debug/state"
+
+// TransportStats is a http.RoundTripper keeping track of how the
+// connections of the wrapped transport's pool are used.
+type TransportStats struct {
+	next http.RoundTripper
+
+	pending     atomic.Int64
+	newConns    atomic.Int64
+	reusedConns atomic.Int64
+}
+
+var _ http.RoundTripper = (*TransportStats)(nil)
+
+// NewTransportStats returns a TransportStats wrapping the given transport.
+func NewTransportStats(next http.RoundTripper) *TransportStats {
+	return &TransportStats{next: next}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *TransportStats) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.pending.Inc()
+	defer t.pending.Dec()
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				t.reusedConns.Inc()
+			} else {
+				t.newConns.Inc()
+			}
+		},
+	}
+	return t.next.RoundTrip(r.WithContext(httptrace.WithClientTrace(r.Context(), trace)))
+}
+
+// TransportState is the connection usage of the proxy transport.
+type TransportState struct {
+	// Pending is the number of requests waiting for the response headers.
+	Pending int64 `json:"pending"`
+	// NewConnections is the number of connections dialed so far.
+	NewConnections int64 `json:"newConnections"`
+	// ReusedConnections is the number of requests sent on an idle
+	// connection of the pool so far.
+	ReusedConnections int64 `json:"reusedConnections"`
+}
+
+// BreakerState is the state of the breaker.
+type BreakerState struct {
+	InFlight int `json:"inFlight"`
+	Capacity int `json:"capacity"`
+}
+
+// DebugState is the runtime state of the queue-proxy served on DebugStatePath.
+type DebugState struct {
+	Goroutines int             `json:"goroutines"`
+	Breaker    *BreakerState   `json:"breaker,omitempty"`
+	Transport  *TransportState `json:"transport,omitempty"`
+}
+
+// DebugHandler returns the handler of the debug server, serving pprof
+// and the DebugState. The breaker and the transport stats may be nil.
+func DebugHandler(logger *zap.SugaredLogger, breaker *Breaker, transport *TransportStats) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/pprof/", profiling.NewHandler(logger, true))
+	mux.HandleFunc(DebugStatePath, func(w http.ResponseWriter, r *http.Request) {
+		state := DebugState{
+			Goroutines: runtime.NumGoroutine(),
+		}
+		if breaker != nil {
+			state.Breaker = &BreakerState{
+				InFlight: breaker.InFlight(),
+				Capacity: breaker.Capacity(),
+			}
+		}
+		if transport != nil {
+			state.Transport = &TransportState{
+				Pending:           transport.pending.Load(),
+				NewConnections:    transport.newConns.Load(),
+				ReusedConnections: transport.reusedConns.Load(),
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(state); err != nil {
+			logger.Errorw("Failed to write the debug state", zap.Error(err))
+		}
+	})
+	return mux
+}
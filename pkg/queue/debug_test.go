@@ -0,0 +1,96 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	logtesting "knative.dev/pkg/logging/testing"
+)
+
+func TestDebugHandler(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	t.Cleanup(backend.Close)
+
+	transport := NewTransportStats(&http.Transport{})
+	client := &http.Client{Transport: transport}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(backend.URL)
+		if err != nil {
+			t.Fatal("Get() =", err)
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	breaker := NewBreaker(BreakerParams{QueueDepth: 10, MaxConcurrency: 5, InitialCapacity: 5})
+	h := DebugHandler(logtesting.TestLogger(t), breaker, transport)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, DebugStatePath, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("StatusCode = %d, want: %d", rec.Code, http.StatusOK)
+	}
+	var got DebugState
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal("Failed to decode the debug state:", err)
+	}
+	if got.Goroutines < 1 {
+		t.Errorf("Goroutines = %d, want > 0", got.Goroutines)
+	}
+	want := DebugState{
+		Breaker: &BreakerState{
+			Capacity: 5,
+		},
+		Transport: &TransportState{
+			NewConnections:    1,
+			ReusedConnections: 1,
+		},
+	}
+	if !cmp.Equal(want, got, cmpopts.IgnoreFields(DebugState{}, "Goroutines")) {
+		t.Error("Debug state diff (-want,+got):", cmp.Diff(want, got, cmpopts.IgnoreFields(DebugState{}, "Goroutines")))
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("pprof StatusCode = %d, want: %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestDebugHandlerNoBreaker(t *testing.T) {
+	h := DebugHandler(logtesting.TestLogger(t), nil, nil)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, DebugStatePath, nil))
+	var got DebugState
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal("Failed to decode the debug state:", err)
+	}
+	if got.Breaker != nil || got.Transport != nil {
+		t.Errorf("Got breaker %v and transport %v, want none", got.Breaker, got.Transport)
+	}
+}
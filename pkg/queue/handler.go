@@ -24,11 +24,15 @@ import (
 	"go.opencensus.io/trace"
 	network "knative.dev/networking/pkg"
 	"knative.dev/serving/pkg/activator"
+	"knative.dev/serving/pkg/networking"
 )
 
 // ProxyHandler sends requests to the `next` handler at a rate controlled by
-// the passed `breaker`, while recording stats to `stats`.
-func ProxyHandler(breaker *Breaker, stats *network.RequestStats, tracingEnabled bool, next http.Handler) http.HandlerFunc {
+// the passed `breaker`, while recording stats to `stats`. The long-lived
+// connections are tracked in `longLived`, which also determines whether they
+// are recorded to `stats`.
+func ProxyHandler(breaker *Breaker, stats *network.RequestStats, longLived *LongLivedConnections,
+	tracingEnabled bool, next http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if network.IsKubeletProbe(r) {
 			next.ServeHTTP(w, r)
@@ -41,15 +45,24 @@ func ProxyHandler(breaker *Breaker, stats *network.RequestStats, tracingEnabled
 			defer proxySpan.End()
 		}
 
+		countStats := true
+		if longLived != nil && networking.IsLongLivedRequest(r) {
+			longLived.open.Inc()
+			defer longLived.open.Dec()
+			countStats = longLived.Counted
+		}
+
 		// Metrics for autoscaling.
-		in, out := network.ReqIn, network.ReqOut
-		if activator.Name == network.KnativeProxyHeader(r) {
-			in, out = network.ProxiedIn, network.ProxiedOut
+		if countStats {
+			in, out := network.ReqIn, network.ReqOut
+			if activator.Name == network.KnativeProxyHeader(r) {
+				in, out = network.ProxiedIn, network.ProxiedOut
+			}
+			stats.HandleEvent(network.ReqEvent{Time: time.Now(), Type: in})
+			defer func() {
+				stats.HandleEvent(network.ReqEvent{Time: time.Now(), Type: out})
+			}()
 		}
-		stats.HandleEvent(network.ReqEvent{Time: time.Now(), Type: in})
-		defer func() {
-			stats.HandleEvent(network.ReqEvent{Time: time.Now(), Type: out})
-		}()
 		network.RewriteHostOut(r)
 
 		// Enforce queuing and concurrency limits.
@@ -64,8 +77,10 @@ func ProxyHandler(breaker *Breaker, stats *network.RequestStats, tracingEnabled
 			}); err != nil {
 				waitSpan.End()
 				switch err {
-				case context.DeadlineExceeded, ErrRequestQueueFull:
+				case context.DeadlineExceeded, ErrRequestQueueFull, ErrRequestQueueTimeout:
 					http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				case ErrCapacityExceeded:
+					http.Error(w, err.Error(), http.StatusTooManyRequests)
 				default:
 					// This line is most likely untestable :-).
 					w.WriteHeader(http.StatusInternalServerError)
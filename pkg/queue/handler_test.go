@@ -48,7 +48,7 @@ func TestHandlerBreakerQueueFull(t *testing.T) {
 		QueueDepth: 1, MaxConcurrency: 1, InitialCapacity: 1,
 	})
 	stats := network.NewRequestStats(time.Now())
-	h := ProxyHandler(breaker, stats, false /*tracingEnabled*/, blockHandler)
+	h := ProxyHandler(breaker, stats, nil /*longLived*/, false /*tracingEnabled*/, blockHandler)
 
 	req := httptest.NewRequest(http.MethodGet, "http://localhost:8081/time", nil)
 	resps := make(chan *httptest.ResponseRecorder)
@@ -96,7 +96,7 @@ func TestHandlerBreakerTimeout(t *testing.T) {
 		QueueDepth: 1, MaxConcurrency: 1, InitialCapacity: 1,
 	})
 	stats := network.NewRequestStats(time.Now())
-	h := ProxyHandler(breaker, stats, false /*tracingEnabled*/, blockHandler)
+	h := ProxyHandler(breaker, stats, nil /*longLived*/, false /*tracingEnabled*/, blockHandler)
 
 	go func() {
 		h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost:8081/time", nil))
@@ -120,6 +120,34 @@ func TestHandlerBreakerTimeout(t *testing.T) {
 	}
 }
 
+func TestHandlerBreakerRejectOverflow(t *testing.T) {
+	seen := make(chan struct{})
+	resp := make(chan struct{})
+	defer close(resp) // Allow all requests to pass through.
+	blockHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen <- struct{}{}
+		<-resp
+	})
+	breaker := NewBreaker(BreakerParams{
+		QueueDepth: 1, MaxConcurrency: 1, InitialCapacity: 1, RejectOverflow: true,
+	})
+	stats := network.NewRequestStats(time.Now())
+	h := ProxyHandler(breaker, stats, nil /*longLived*/, false /*tracingEnabled*/, blockHandler)
+
+	go func() {
+		h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost:8081/time", nil))
+	}()
+
+	// Wait until the first request has entered the handler.
+	<-seen
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "http://localhost:8081/time", nil))
+	if got, want := rec.Code, http.StatusTooManyRequests; got != want {
+		t.Fatalf("Code = %d, want: %d", got, want)
+	}
+}
+
 func TestHandlerReqEvent(t *testing.T) {
 	params := BreakerParams{QueueDepth: 10, MaxConcurrency: 10, InitialCapacity: 10}
 	breaker := NewBreaker(params)
@@ -154,7 +182,7 @@ func TestHandlerReqEvent(t *testing.T) {
 			proxy := httputil.NewSingleHostReverseProxy(serverURL)
 
 			stats := network.NewRequestStats(time.Now())
-			h := ProxyHandler(br, stats, true /*tracingEnabled*/, proxy)
+			h := ProxyHandler(br, stats, nil /*longLived*/, true /*tracingEnabled*/, proxy)
 
 			writer := httptest.NewRecorder()
 			req := httptest.NewRequest(http.MethodPost, "http://example.com", nil)
@@ -215,7 +243,7 @@ func TestIgnoreProbe(t *testing.T) {
 	// Ensure no more than 1 request can be queued. So we'll send 3.
 	breaker := NewBreaker(BreakerParams{QueueDepth: 1, MaxConcurrency: 1, InitialCapacity: 1})
 	stats := network.NewRequestStats(time.Now())
-	h := ProxyHandler(breaker, stats, false /*tracingEnabled*/, proxy)
+	h := ProxyHandler(breaker, stats, nil /*longLived*/, false /*tracingEnabled*/, proxy)
 
 	req := httptest.NewRequest(http.MethodPost, "http://prob.in", nil)
 	req.Header.Set(network.KubeletProbeHeaderName, "1") // Mark it a probe.
@@ -231,6 +259,38 @@ func TestIgnoreProbe(t *testing.T) {
 	}
 }
 
+func TestProxyHandlerLongLived(t *testing.T) {
+	for _, counted := range []bool{true, false} {
+		t.Run(fmt.Sprint("counted=", counted), func(t *testing.T) {
+			longLived := &LongLivedConnections{Counted: counted}
+			stats := network.NewRequestStats(time.Now())
+			var open int32
+			next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+				open = longLived.Open()
+			})
+			h := ProxyHandler(nil /*breaker*/, stats, longLived, false /*tracingEnabled*/, next)
+
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			req.Header.Set("Accept", "text/event-stream")
+			h(httptest.NewRecorder(), req)
+
+			if open != 1 {
+				t.Errorf("Open long-lived connections while serving = %d, want: 1", open)
+			}
+			if got := longLived.Open(); got != 0 {
+				t.Errorf("Open long-lived connections after serving = %d, want: 0", got)
+			}
+			wantCount := 0.
+			if counted {
+				wantCount = 1
+			}
+			if got := stats.Report(time.Now()).RequestCount; got != wantCount {
+				t.Errorf("RequestCount = %v, want: %v", got, wantCount)
+			}
+		})
+	}
+}
+
 func BenchmarkProxyHandler(b *testing.B) {
 	baseHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 	stats := network.NewRequestStats(time.Now())
@@ -276,7 +336,7 @@ func BenchmarkProxyHandler(b *testing.B) {
 			}
 		}()
 
-		h := ProxyHandler(tc.breaker, stats, true /*tracingEnabled*/, baseHandler)
+		h := ProxyHandler(tc.breaker, stats, nil /*longLived*/, true /*tracingEnabled*/, baseHandler)
 		b.Run("sequential-"+tc.label, func(b *testing.B) {
 			resp := httptest.NewRecorder()
 			for j := 0; j < b.N; j++ {
@@ -0,0 +1,163 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"knative.dev/pkg/websocket"
+	"knative.dev/serving/pkg/activator"
+	"knative.dev/serving/pkg/apis/serving"
+)
+
+// HeaderRules are the header manipulations applied to the requests
+// or to the responses by the queue proxy.
+type HeaderRules struct {
+	// Set are the headers to set, replacing their existing values.
+	Set map[string]string
+	// Add are the headers to add, keeping their existing values.
+	Add map[string]string
+	// Remove are the headers to strip.
+	Remove []string
+}
+
+// ParseHeaderRules parses the JSON object of the headers to set and the
+// comma separated list of the headers to remove into HeaderRules.
+// Nil is returned if there's nothing to do.
+func ParseHeaderRules(set, remove string) (*HeaderRules, error) {
+	if set == "" && remove == "" {
+		return nil, nil
+	}
+	r := &HeaderRules{}
+	if set != "" {
+		if err := json.Unmarshal([]byte(set), &r.Set); err != nil {
+			return nil, fmt.Errorf("failed to parse the headers to set %q: %w", set, err)
+		}
+	}
+	for _, name := range strings.Split(remove, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			r.Remove = append(r.Remove, name)
+		}
+	}
+	return r, nil
+}
+
+func (r *HeaderRules) apply(h http.Header) {
+	for _, name := range r.Remove {
+		h.Del(name)
+	}
+	for name, value := range r.Add {
+		h.Add(name, value)
+	}
+	for name, value := range r.Set {
+		h.Set(name, value)
+	}
+}
+
+// HeaderHandler applies the request header rules to the requests before
+// passing them to the next handler and the response header rules to the
+// responses written by it. Either of the rules can be nil.
+func HeaderHandler(request, response *HeaderRules, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if request != nil {
+			request.apply(r.Header)
+		}
+		if response != nil {
+			w = &headerRulesWriter{ResponseWriter: w, rules: response}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RouteHeaderRulesHandler applies the header rules of the Route, which the
+// requests carry JSON encoded in the activator.HeaderRulesHeaderName header,
+// see serving.HeaderRulesAnnotationKey. The header is never forwarded.
+func RouteHeaderRulesHandler(logger *zap.SugaredLogger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := r.Header.Get(activator.HeaderRulesHeaderName)
+		if v == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		r.Header.Del(activator.HeaderRulesHeaderName)
+		var rules serving.HeaderRules
+		if err := json.Unmarshal([]byte(v), &rules); err != nil {
+			logger.Warnw("Failed to parse the header rules of the route", zap.Error(err))
+			next.ServeHTTP(w, r)
+			return
+		}
+		HeaderHandler(headerRulesFrom(rules.Request), headerRulesFrom(rules.Response), next).ServeHTTP(w, r)
+	})
+}
+
+func headerRulesFrom(ops serving.HeaderOperations) *HeaderRules {
+	if len(ops.Set) == 0 && len(ops.Add) == 0 && len(ops.Remove) == 0 {
+		return nil
+	}
+	return &HeaderRules{Set: ops.Set, Add: ops.Add, Remove: ops.Remove}
+}
+
+// headerRulesWriter applies the header rules to the response
+// right before the header is written.
+type headerRulesWriter struct {
+	http.ResponseWriter
+	rules       *HeaderRules
+	wroteHeader bool
+}
+
+var (
+	_ http.Flusher  = (*headerRulesWriter)(nil)
+	_ http.Hijacker = (*headerRulesWriter)(nil)
+)
+
+func (w *headerRulesWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.rules.apply(w.ResponseWriter.Header())
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *headerRulesWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher.
+func (w *headerRulesWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, which is required for the
+// reverse proxy to handle the protocol upgrades.
+func (w *headerRulesWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return websocket.HijackIfPossible(w.ResponseWriter)
+}
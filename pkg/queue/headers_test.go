@@ -0,0 +1,168 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	logtesting "knative.dev/pkg/logging/testing"
+	"knative.dev/serving/pkg/activator"
+)
+
+func TestParseHeaderRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		set     string
+		remove  string
+		want    *HeaderRules
+		wantErr bool
+	}{{
+		name: "empty",
+	}, {
+		name:   "set and remove",
+		set:    `{"X-Env": "prod"}`,
+		remove: "X-Internal, X-Debug,",
+		want: &HeaderRules{
+			Set:    map[string]string{"X-Env": "prod"},
+			Remove: []string{"X-Internal", "X-Debug"},
+		},
+	}, {
+		name:   "remove only",
+		remove: "Server",
+		want: &HeaderRules{
+			Remove: []string{"Server"},
+		},
+	}, {
+		name:    "malformed set",
+		set:     "X-Env: prod",
+		wantErr: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseHeaderRules(test.set, test.remove)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseHeaderRules() = %v, want error: %v", err, test.wantErr)
+			}
+			if !cmp.Equal(got, test.want) {
+				t.Error("ParseHeaderRules (-want, +got):", cmp.Diff(test.want, got))
+			}
+		})
+	}
+}
+
+func TestHeaderHandler(t *testing.T) {
+	request := &HeaderRules{
+		Set:    map[string]string{"X-Env": "prod"},
+		Remove: []string{"X-Internal"},
+	}
+	response := &HeaderRules{
+		Set:    map[string]string{"Strict-Transport-Security": "max-age=31536000"},
+		Remove: []string{"Server"},
+	}
+	h := HeaderHandler(request, response, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, want := r.Header.Get("X-Env"), "prod"; got != want {
+			t.Errorf("X-Env = %q, want: %q", got, want)
+		}
+		if got := r.Header.Get("X-Internal"); got != "" {
+			t.Errorf("X-Internal = %q, want it removed", got)
+		}
+		// As copied by the reverse proxy from the user container response.
+		w.Header().Add("Server", "secret/1.0")
+		w.Header().Add("Strict-Transport-Security", "max-age=0")
+		w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	req.Header.Set("X-Env", "dev")
+	req.Header.Set("X-Internal", "true")
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+
+	if got := resp.Header().Get("Server"); got != "" {
+		t.Errorf("Server = %q, want it removed", got)
+	}
+	if got, want := resp.Header().Values("Strict-Transport-Security"), []string{"max-age=31536000"}; !cmp.Equal(got, want) {
+		t.Errorf("Strict-Transport-Security = %q, want: %q", got, want)
+	}
+	if got, want := resp.Body.String(), "hello"; got != want {
+		t.Errorf("Body = %q, want: %q", got, want)
+	}
+}
+
+func TestHeaderHandlerNoRules(t *testing.T) {
+	h := HeaderHandler(nil, nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "test")
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "http://example.com", nil))
+	if got, want := resp.Code, http.StatusTeapot; got != want {
+		t.Errorf("Code = %d, want: %d", got, want)
+	}
+	if got, want := resp.Header().Get("Server"), "test"; got != want {
+		t.Errorf("Server = %q, want: %q", got, want)
+	}
+}
+
+func TestRouteHeaderRulesHandler(t *testing.T) {
+	h := RouteHeaderRulesHandler(logtesting.TestLogger(t), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get(activator.HeaderRulesHeaderName); got != "" {
+			t.Errorf("%s = %q, want it removed", activator.HeaderRulesHeaderName, got)
+		}
+		if got, want := r.Header.Values("X-Forwarded-Client"), []string{"a", "b"}; !cmp.Equal(got, want) {
+			t.Errorf("X-Forwarded-Client = %q, want: %q", got, want)
+		}
+		if got := r.Header.Get("X-Internal"); got != "" {
+			t.Errorf("X-Internal = %q, want it removed", got)
+		}
+		w.Header().Set("Server", "secret/1.0")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	req.Header.Set(activator.HeaderRulesHeaderName,
+		`{"request":{"add":{"X-Forwarded-Client":"b"},"remove":["X-Internal"]},"response":{"remove":["Server"]}}`)
+	req.Header.Set("X-Forwarded-Client", "a")
+	req.Header.Set("X-Internal", "true")
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+
+	if got := resp.Header().Get("Server"); got != "" {
+		t.Errorf("Server = %q, want it removed", got)
+	}
+}
+
+func TestRouteHeaderRulesHandlerMalformed(t *testing.T) {
+	h := RouteHeaderRulesHandler(logtesting.TestLogger(t), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get(activator.HeaderRulesHeaderName); got != "" {
+			t.Errorf("%s = %q, want it removed", activator.HeaderRulesHeaderName, got)
+		}
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	req.Header.Set(activator.HeaderRulesHeaderName, "X-Foo: bar")
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, req)
+	if got, want := resp.Code, http.StatusTeapot; got != want {
+		t.Errorf("Code = %d, want: %d", got, want)
+	}
+}
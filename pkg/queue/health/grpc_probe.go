@@ -0,0 +1,157 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	// healthCheckMethod is the method of the standard gRPC health checking
+	// protocol, see https://github.com/grpc/grpc/blob/master/doc/health-checking.md.
+	healthCheckMethod = "/grpc.health.v1.Health/Check"
+
+	// servingStatus is the HealthCheckResponse.ServingStatus of a ready service.
+	servingStatus = 1
+)
+
+// GRPCProbeConfigOptions holds the gRPC probe config options
+type GRPCProbeConfigOptions struct {
+	Timeout time.Duration
+	Address string
+	// Service is the name of the service to check, or empty for the server.
+	Service string
+	// UnixSocket, if set, is the unix domain socket the probe connects to,
+	// instead of the address.
+	UnixSocket string
+}
+
+// GRPCProbe checks that the gRPC server at the address reports the service as
+// serving. Only the few bytes of the health checking messages are needed, so
+// they are encoded by hand rather than pulling in the generated health package.
+func GRPCProbe(config GRPCProbeConfigOptions) error {
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+
+	opts := []grpc.DialOption{grpc.WithInsecure(), grpc.WithBlock()}
+	if config.UnixSocket != "" {
+		opts = append(opts, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", config.UnixSocket)
+		}))
+	}
+	conn, err := grpc.DialContext(ctx, config.Address, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", config.Address, err)
+	}
+	defer conn.Close()
+
+	var resp []byte
+	if err := conn.Invoke(ctx, healthCheckMethod, encodeHealthCheckRequest(config.Service), &resp,
+		grpc.ForceCodec(rawCodec{})); err != nil {
+		return fmt.Errorf("gRPC health check failed: %w", err)
+	}
+	status, err := decodeHealthCheckResponse(resp)
+	if err != nil {
+		return err
+	}
+	if status != servingStatus {
+		return fmt.Errorf("gRPC probe did not respond Serving, got status: %d", status)
+	}
+	return nil
+}
+
+// rawCodec passes the already encoded protocol buffers through. It is named
+// after the proto codec, since that's the content subtype the servers expect.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	return v.([]byte), nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*[]byte)) = append([]byte(nil), data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+// encodeHealthCheckRequest encodes the HealthCheckRequest, whose only field
+// is `string service = 1`.
+func encodeHealthCheckRequest(service string) []byte {
+	if service == "" {
+		return []byte{}
+	}
+	buf := make([]byte, 1+binary.MaxVarintLen64+len(service))
+	buf[0] = 1<<3 | 2 // Field 1, length delimited.
+	n := 1 + binary.PutUvarint(buf[1:], uint64(len(service)))
+	return append(buf[:n], service...)
+}
+
+var errMalformedResponse = errors.New("malformed gRPC health check response")
+
+// decodeHealthCheckResponse decodes the `ServingStatus status = 1` field of
+// the HealthCheckResponse, skipping any other field.
+func decodeHealthCheckResponse(b []byte) (uint64, error) {
+	var status uint64
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return 0, errMalformedResponse
+		}
+		b = b[n:]
+
+		var skip uint64
+		switch key & 7 {
+		case 0: // Varint.
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return 0, errMalformedResponse
+			}
+			if key>>3 == 1 {
+				status = v
+			}
+			skip = uint64(n)
+		case 1: // 64-bit.
+			skip = 8
+		case 2: // Length delimited.
+			l, n := binary.Uvarint(b)
+			if n <= 0 {
+				return 0, errMalformedResponse
+			}
+			skip = uint64(n) + l
+		case 5: // 32-bit.
+			skip = 4
+		default:
+			return 0, errMalformedResponse
+		}
+		if skip > uint64(len(b)) {
+			return 0, errMalformedResponse
+		}
+		b = b[skip:]
+	}
+	return status, nil
+}
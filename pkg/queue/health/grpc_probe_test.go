@@ -0,0 +1,160 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// serverCodec adapts rawCodec to the codec the gRPC server options take.
+type serverCodec struct {
+	rawCodec
+}
+
+func (serverCodec) String() string {
+	return "raw"
+}
+
+// newHealthServer starts a gRPC server answering the health checks with the
+// given status per service, and returns its address.
+func newHealthServer(t *testing.T, statuses map[string]byte) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Failed to listen:", err)
+	}
+	s := grpc.NewServer(grpc.CustomCodec(serverCodec{}),
+		grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+			if m, _ := grpc.MethodFromServerStream(stream); m != healthCheckMethod {
+				return status.Errorf(codes.Unimplemented, "unknown method %s", m)
+			}
+			var req []byte
+			if err := stream.RecvMsg(&req); err != nil {
+				return err
+			}
+			for service, st := range statuses {
+				if bytes.Equal(req, encodeHealthCheckRequest(service)) {
+					return stream.SendMsg([]byte{1 << 3, st})
+				}
+			}
+			return status.Error(codes.NotFound, "unknown service")
+		}))
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+	return l.Addr().String()
+}
+
+func TestGRPCProbe(t *testing.T) {
+	addr := newHealthServer(t, map[string]byte{
+		"":            servingStatus,
+		"ping.Ping":   servingStatus,
+		"ping.Sleepy": 2, // NOT_SERVING
+	})
+
+	tests := []struct {
+		name    string
+		service string
+		wantErr bool
+	}{{
+		name: "server",
+	}, {
+		name:    "serving service",
+		service: "ping.Ping",
+	}, {
+		name:    "not serving service",
+		service: "ping.Sleepy",
+		wantErr: true,
+	}, {
+		name:    "unknown service",
+		service: "ping.Unknown",
+		wantErr: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := GRPCProbe(GRPCProbeConfigOptions{
+				Timeout: 5 * time.Second,
+				Address: addr,
+				Service: test.service,
+			})
+			if got := err != nil; got != test.wantErr {
+				t.Errorf("GRPCProbe() = %v, wantErr: %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestGRPCProbeFailedConnection(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Failed to listen:", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	if err := GRPCProbe(GRPCProbeConfigOptions{
+		Timeout: 100 * time.Millisecond,
+		Address: addr,
+	}); err == nil {
+		t.Error("GRPCProbe() = nil, want an error")
+	}
+}
+
+func TestDecodeHealthCheckResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []byte
+		want    uint64
+		wantErr bool
+	}{{
+		name: "empty",
+		in:   []byte{},
+	}, {
+		name: "serving",
+		in:   []byte{1 << 3, 1},
+		want: 1,
+	}, {
+		name: "unknown fields",
+		in:   []byte{2<<3 | 2, 2, 'h', 'i', 3<<3 | 5, 0, 0, 0, 0, 1 << 3, 3},
+		want: 3,
+	}, {
+		name:    "truncated",
+		in:      []byte{2<<3 | 2, 5, 'h'},
+		wantErr: true,
+	}, {
+		name:    "unsupported wire type",
+		in:      []byte{1<<3 | 3},
+		wantErr: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := decodeHealthCheckResponse(test.in)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("decodeHealthCheckResponse() = %v, wantErr: %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("decodeHealthCheckResponse() = %d, want: %d", got, test.want)
+			}
+		})
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package health
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -35,19 +36,28 @@ type HTTPProbeConfigOptions struct {
 	*corev1.HTTPGetAction
 	KubeMajor string
 	KubeMinor string
+	// UnixSocket, if set, is the unix domain socket the probe connects to,
+	// instead of the host and port of the HTTPGetAction.
+	UnixSocket string
 }
 
 // TCPProbeConfigOptions holds the TCP probe config options
 type TCPProbeConfigOptions struct {
 	SocketTimeout time.Duration
 	Address       string
+	// Network is the network of the address, "tcp" if empty.
+	Network string
 }
 
 // TCPProbe checks that a TCP socket to the address can be opened.
 // Did not reuse k8s.io/kubernetes/pkg/probe/tcp to not create a dependency
 // on klog.
 func TCPProbe(config TCPProbeConfigOptions) error {
-	conn, err := net.DialTimeout("tcp", config.Address, config.SocketTimeout)
+	network := config.Network
+	if network == "" {
+		network = "tcp"
+	}
+	conn, err := net.DialTimeout(network, config.Address, config.SocketTimeout)
 	if err != nil {
 		return err
 	}
@@ -63,12 +73,26 @@ var transport = func() *http.Transport {
 	return t
 }()
 
+// unixSocketTransport returns a transport like the probe transport,
+// which connects to the given unix domain socket whatever the address.
+func unixSocketTransport(path string) *http.Transport {
+	t := transport.Clone()
+	t.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", path)
+	}
+	return t
+}
+
 // HTTPProbe checks that HTTP connection can be established to the address.
 func HTTPProbe(config HTTPProbeConfigOptions) error {
 	httpClient := &http.Client{
 		Transport: transport,
 		Timeout:   config.Timeout,
 	}
+	if config.UnixSocket != "" {
+		httpClient.Transport = unixSocketTransport(config.UnixSocket)
+	}
 
 	url := url.URL{
 		Scheme: string(config.Scheme),
@@ -17,9 +17,11 @@ limitations under the License.
 package health
 
 import (
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -51,6 +53,67 @@ func TestTCPProbe(t *testing.T) {
 	}
 }
 
+func newUnixSocketServer(t *testing.T, h http.Handler) (*httptest.Server, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "user.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal("Failed to listen on the unix socket:", err)
+	}
+	server := httptest.NewUnstartedServer(h)
+	server.Listener = l
+	server.Start()
+	return server, path
+}
+
+func TestTCPProbeUnixSocket(t *testing.T) {
+	server, path := newUnixSocketServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	config := TCPProbeConfigOptions{
+		Address:       path,
+		Network:       "unix",
+		SocketTimeout: time.Second,
+	}
+	if err := TCPProbe(config); err != nil {
+		t.Error("Probe failed with:", err)
+	}
+
+	server.Close()
+	if err := TCPProbe(config); err == nil {
+		t.Error("Expected probe to fail but it didn't")
+	}
+}
+
+func TestHTTPProbeUnixSocket(t *testing.T) {
+	var gotPath string
+	server, path := newUnixSocketServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	action := newHTTPGetAction(t, "http://127.0.0.1:8080")
+	action.Path = "/health"
+	config := HTTPProbeConfigOptions{
+		Timeout:       time.Second,
+		HTTPGetAction: action,
+		UnixSocket:    path,
+	}
+	if err := HTTPProbe(config); err != nil {
+		t.Error("Expected probe to succeed but failed with error", err)
+	}
+	if gotPath != "/health" {
+		t.Errorf("Path = %q, want: %q", gotPath, "/health")
+	}
+
+	server.Close()
+	if err := HTTPProbe(config); err == nil {
+		t.Error("Expected probe to fail but it didn't")
+	}
+}
+
 func TestHTTPProbeSuccess(t *testing.T) {
 	var gotHeader corev1.HTTPHeader
 	var gotKubeletHeader bool
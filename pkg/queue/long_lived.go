@@ -0,0 +1,36 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"go.uber.org/atomic"
+)
+
+// LongLivedConnections tracks the long-lived connections, i.e. websockets
+// and server-sent event streams, handled by the queue proxy.
+type LongLivedConnections struct {
+	// Counted determines whether the long-lived connections count towards
+	// the request concurrency reported to the autoscaler.
+	Counted bool
+
+	open atomic.Int32
+}
+
+// Open returns the number of currently open long-lived connections.
+func (c *LongLivedConnections) Open() int32 {
+	return c.open.Load()
+}
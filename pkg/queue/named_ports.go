@@ -0,0 +1,67 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+
+	"knative.dev/serving/pkg/activator"
+)
+
+// ParseNamedPorts parses the comma separated list of `name=port` pairs
+// of the named user container ports, e.g. `admin=8181,data=8282`.
+func ParseNamedPorts(s string) (map[string]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	ports := make(map[string]int)
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid named port %q", pair)
+		}
+		port, err := strconv.Atoi(kv[1])
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port number in named port %q", pair)
+		}
+		ports[kv[0]] = port
+	}
+	return ports, nil
+}
+
+// SetupNamedPortRouting causes the http.ReverseProxy to forward the
+// requests carrying the activator.PortHeaderName header to the named
+// user container port on localhost, instead of the serving port.
+// Requests naming an unknown port are forwarded to the serving port.
+// The header is never forwarded to the user container.
+func SetupNamedPortRouting(p *httputil.ReverseProxy, ports map[string]int) {
+	// Director is never nil - otherwise ServeHTTP panics.
+	orig := p.Director
+	p.Director = func(r *http.Request) {
+		orig(r)
+
+		if port, ok := ports[r.Header.Get(activator.PortHeaderName)]; ok {
+			r.URL.Host = net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+		}
+		r.Header.Del(activator.PortHeaderName)
+	}
+}
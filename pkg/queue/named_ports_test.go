@@ -0,0 +1,119 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"knative.dev/serving/pkg/activator"
+)
+
+func TestParseNamedPorts(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    map[string]int
+		wantErr bool
+	}{{
+		name: "empty",
+	}, {
+		name: "single",
+		in:   "admin=8181",
+		want: map[string]int{"admin": 8181},
+	}, {
+		name: "multiple",
+		in:   "admin=8181,data=8282",
+		want: map[string]int{"admin": 8181, "data": 8282},
+	}, {
+		name:    "missing port",
+		in:      "admin",
+		wantErr: true,
+	}, {
+		name:    "missing name",
+		in:      "=8181",
+		wantErr: true,
+	}, {
+		name:    "bad port",
+		in:      "admin=http",
+		wantErr: true,
+	}, {
+		name:    "port out of range",
+		in:      "admin=65536",
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseNamedPorts(test.in)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseNamedPorts() = %v, wantErr: %v", err, test.wantErr)
+			}
+			if !cmp.Equal(got, test.want) {
+				t.Error("ParseNamedPorts() diff (-want,+got):", cmp.Diff(test.want, got))
+			}
+		})
+	}
+}
+
+func TestSetupNamedPortRouting(t *testing.T) {
+	ports := map[string]int{"admin": 8181}
+	tests := []struct {
+		name     string
+		port     string
+		wantHost string
+	}{{
+		name:     "no header",
+		wantHost: "127.0.0.1:8080",
+	}, {
+		name:     "named port",
+		port:     "admin",
+		wantHost: "127.0.0.1:8181",
+	}, {
+		name:     "unknown port",
+		port:     "data",
+		wantHost: "127.0.0.1:8080",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			proxy := httputil.NewSingleHostReverseProxy(&url.URL{
+				Scheme: "http",
+				Host:   "127.0.0.1:8080",
+			})
+			SetupNamedPortRouting(proxy, ports)
+
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			if test.port != "" {
+				req.Header.Set(activator.PortHeaderName, test.port)
+			}
+			proxy.Director(req)
+
+			if got := req.URL.Host; got != test.wantHost {
+				t.Errorf("Host = %q, want: %q", got, test.wantHost)
+			}
+			if got := req.Header.Get(activator.PortHeaderName); got != "" {
+				t.Errorf("Header %s = %q, want it removed", activator.PortHeaderName, got)
+			}
+		})
+	}
+}
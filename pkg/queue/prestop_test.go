@@ -0,0 +1,74 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotifyPreStop(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{{
+		name:   "success",
+		status: http.StatusOK,
+	}, {
+		name:    "failure",
+		status:  http.StatusInternalServerError,
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var gotPath string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				w.WriteHeader(test.status)
+			}))
+			defer server.Close()
+
+			err := NotifyPreStop(context.Background(), server.Client(), server.URL+"/shutdown")
+			if (err != nil) != test.wantErr {
+				t.Errorf("NotifyPreStop() = %v, wantErr = %v", err, test.wantErr)
+			}
+			if gotPath != "/shutdown" {
+				t.Errorf("Path = %q, want: %q", gotPath, "/shutdown")
+			}
+		})
+	}
+}
+
+func TestNotifyPreStopContextDone(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := NotifyPreStop(ctx, server.Client(), server.URL); err == nil {
+		t.Error("NotifyPreStop() = nil, wanted an error when the context is done")
+	}
+}
@@ -55,6 +55,9 @@ var (
 	averageProxiedConcurrentRequestsGV = newGV(
 		"queue_average_proxied_concurrent_requests",
 		"Number of proxied requests currently being handled by this pod")
+	longLivedConnectionsGV = newGV(
+		"queue_long_lived_connections",
+		"Number of long-lived connections (websockets, server-sent events) currently open to this pod")
 	processUptimeGV = newGV(
 		"process_uptime",
 		"The number of seconds that the process has been up")
@@ -80,6 +83,7 @@ type PrometheusStatsReporter struct {
 	proxiedRequestsPerSecond         prometheus.Gauge
 	averageConcurrentRequests        prometheus.Gauge
 	averageProxiedConcurrentRequests prometheus.Gauge
+	longLivedConnections             prometheus.Gauge
 	processUptime                    prometheus.Gauge
 }
 
@@ -102,7 +106,7 @@ func NewPrometheusStatsReporter(namespace, config, revision, pod string, reporti
 	for _, gv := range []*prometheus.GaugeVec{
 		requestsPerSecondGV, proxiedRequestsPerSecondGV,
 		averageConcurrentRequestsGV, averageProxiedConcurrentRequestsGV,
-		processUptimeGV} {
+		longLivedConnectionsGV, processUptimeGV} {
 		if err := registry.Register(gv); err != nil {
 			return nil, fmt.Errorf("register metric failed: %w", err)
 		}
@@ -125,6 +129,7 @@ func NewPrometheusStatsReporter(namespace, config, revision, pod string, reporti
 		proxiedRequestsPerSecond:         proxiedRequestsPerSecondGV.With(labels),
 		averageConcurrentRequests:        averageConcurrentRequestsGV.With(labels),
 		averageProxiedConcurrentRequests: averageProxiedConcurrentRequestsGV.With(labels),
+		longLivedConnections:             longLivedConnectionsGV.With(labels),
 		processUptime:                    processUptimeGV.With(labels),
 	}, nil
 }
@@ -139,6 +144,11 @@ func (r *PrometheusStatsReporter) Report(stats network.RequestStatsReport) {
 	r.processUptime.Set(time.Since(r.startTime).Seconds())
 }
 
+// ReportLongLivedConnections captures the number of open long-lived connections.
+func (r *PrometheusStatsReporter) ReportLongLivedConnections(n int32) {
+	r.longLivedConnections.Set(float64(n))
+}
+
 // ServeHTTP serves the stats in prometheus format over HTTP.
 func (r *PrometheusStatsReporter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	r.handler.ServeHTTP(w, req)
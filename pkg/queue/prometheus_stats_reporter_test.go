@@ -180,6 +180,17 @@ func TestPrometheusStatsReporterReport(t *testing.T) {
 	}
 }
 
+func TestPrometheusStatsReporterReportLongLivedConnections(t *testing.T) {
+	reporter, err := NewPrometheusStatsReporter(namespace, config, revision, pod, time.Second)
+	if err != nil {
+		t.Fatal("NewPrometheusStatsReporter() =", err)
+	}
+	reporter.ReportLongLivedConnections(3)
+	if got, want := getData(t, longLivedConnectionsGV), 3.; got != want {
+		t.Errorf("long-lived connections = %v, want: %v", got, want)
+	}
+}
+
 func getData(t *testing.T, gv *prometheus.GaugeVec) float64 {
 	t.Helper()
 	g, err := gv.GetMetricWith(prometheus.Labels{
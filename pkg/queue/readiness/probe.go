@@ -36,6 +36,27 @@ const (
 	retryInterval = 50 * time.Millisecond
 )
 
+// Timing configures the retries of the aggressive probe, see Probe.IsAggressive.
+type Timing struct {
+	// Period is the initial interval between two attempts.
+	Period time.Duration
+	// Timeout is the timeout of a single attempt.
+	Timeout time.Duration
+	// BackoffFactor multiplies the interval after each failed attempt.
+	// 1 keeps the interval constant.
+	BackoffFactor float64
+	// MaxPeriod caps the interval between two attempts.
+	MaxPeriod time.Duration
+}
+
+// DefaultTiming is the Timing of the aggressive probe, unless configured otherwise.
+var DefaultTiming = Timing{
+	Period:        retryInterval,
+	Timeout:       aggressiveProbeTimeout,
+	BackoffFactor: 1,
+	MaxPeriod:     time.Second,
+}
+
 // Probe wraps a corev1.Probe along with a count of consecutive, successful probes
 type Probe struct {
 	*corev1.Probe
@@ -43,6 +64,18 @@ type Probe struct {
 	pollTimeout time.Duration // To make tests not run for 10 seconds.
 	out         io.Writer     // To make tests not log errors in good cases.
 
+	// timing configures the retries of the aggressive probe.
+	timing Timing
+
+	// When unixSocket is set, the probes connect to the unix domain socket
+	// the user container serves on, rather than to its TCP port.
+	unixSocket string
+
+	// When grpc is set, the TCPSocket is checked with the gRPC health
+	// checking protocol for grpcService, rather than just connected to.
+	grpc        bool
+	grpcService string
+
 	// Barrier sync to ensure only one probe is happening at the same time.
 	// When a probe is active `gv` will be non-nil.
 	// When the probe finishes the `gv` will be reset to nil.
@@ -81,10 +114,46 @@ func NewProbe(v1p *corev1.Probe) *Probe {
 	return &Probe{
 		Probe:       v1p,
 		pollTimeout: PollTimeout,
+		timing:      DefaultTiming,
 		out:         os.Stderr,
 	}
 }
 
+// SetTiming sets the Timing of the aggressive probe. The zero fields of t
+// keep their default values.
+func (p *Probe) SetTiming(t Timing) {
+	if t.Period > 0 {
+		p.timing.Period = t.Period
+	}
+	if t.Timeout > 0 {
+		p.timing.Timeout = t.Timeout
+	}
+	if t.BackoffFactor >= 1 {
+		p.timing.BackoffFactor = t.BackoffFactor
+	}
+	if t.MaxPeriod > 0 {
+		p.timing.MaxPeriod = t.MaxPeriod
+	}
+	if p.timing.MaxPeriod < p.timing.Period {
+		p.timing.MaxPeriod = p.timing.Period
+	}
+}
+
+// SetUnixSocket makes the probe connect to the unix domain socket at path,
+// which the user container serves on instead of its TCP port.
+func (p *Probe) SetUnixSocket(path string) {
+	p.unixSocket = path
+}
+
+// NewGRPCProbe returns a pointer to a new Probe, which checks the health of
+// the gRPC service on the TCPSocket of the given probe.
+func NewGRPCProbe(v1p *corev1.Probe, service string) *Probe {
+	p := NewProbe(v1p)
+	p.grpc = true
+	p.grpcService = service
+	return p
+}
+
 // IsAggressive indicates whether the Knative probe with aggressive retries should be used.
 func (p *Probe) IsAggressive() bool {
 	return p.PeriodSeconds == 0
@@ -118,6 +187,8 @@ func (p *Probe) probeContainerImpl() bool {
 	var err error
 
 	switch {
+	case p.grpc && p.TCPSocket != nil:
+		err = p.grpcProbe()
 	case p.HTTPGet != nil:
 		err = p.httpProbe()
 	case p.TCPSocket != nil:
@@ -144,23 +215,41 @@ func (p *Probe) probeContainerImpl() bool {
 
 func (p *Probe) doProbe(probe func(time.Duration) error) error {
 	if p.IsAggressive() {
-		return wait.PollImmediate(retryInterval, p.pollTimeout, func() (bool, error) {
-			if err := probe(aggressiveProbeTimeout); err != nil {
-				fmt.Fprintln(p.out, "aggressive probe error: ", err)
-				// Reset count of consecutive successes to zero.
-				p.count = 0
-				return false, nil
-			}
+		return p.doAggressiveProbe(probe)
+	}
 
-			p.count++
+	return probe(time.Duration(p.TimeoutSeconds) * time.Second)
+}
 
+// doAggressiveProbe retries the probe, backing off after each failed attempt,
+// until it succeeds SuccessThreshold times in a row or pollTimeout elapses.
+func (p *Probe) doAggressiveProbe(probe func(time.Duration) error) error {
+	deadline := time.Now().Add(p.pollTimeout)
+	interval := p.timing.Period
+	for {
+		if err := probe(p.timing.Timeout); err != nil {
+			fmt.Fprintln(p.out, "aggressive probe error: ", err)
+			// Reset count of consecutive successes to zero.
+			p.count = 0
+			interval = time.Duration(float64(interval) * p.timing.BackoffFactor)
+			if interval > p.timing.MaxPeriod {
+				interval = p.timing.MaxPeriod
+			}
+		} else {
+			p.count++
 			// Return success if count of consecutive successes is equal to or greater
 			// than the probe's SuccessThreshold.
-			return p.count >= p.SuccessThreshold, nil
-		})
-	}
+			if p.count >= p.SuccessThreshold {
+				return nil
+			}
+			interval = p.timing.Period
+		}
 
-	return probe(time.Duration(p.TimeoutSeconds) * time.Second)
+		if time.Now().Add(interval).After(deadline) {
+			return wait.ErrWaitTimeout
+		}
+		time.Sleep(interval)
+	}
 }
 
 // tcpProbe function executes TCP probe once if its standard probe
@@ -170,6 +259,10 @@ func (p *Probe) tcpProbe() error {
 	config := health.TCPProbeConfigOptions{
 		Address: p.TCPSocket.Host + ":" + p.TCPSocket.Port.String(),
 	}
+	if p.unixSocket != "" {
+		config.Address = p.unixSocket
+		config.Network = "unix"
+	}
 
 	return p.doProbe(func(to time.Duration) error {
 		config.SocketTimeout = to
@@ -177,12 +270,29 @@ func (p *Probe) tcpProbe() error {
 	})
 }
 
+// grpcProbe function executes gRPC health check once if its standard probe
+// otherwise gRPC probe polls condition function which returns true
+// if the probe count is greater than success threshold and false if gRPC probe fails
+func (p *Probe) grpcProbe() error {
+	config := health.GRPCProbeConfigOptions{
+		Address:    p.TCPSocket.Host + ":" + p.TCPSocket.Port.String(),
+		Service:    p.grpcService,
+		UnixSocket: p.unixSocket,
+	}
+
+	return p.doProbe(func(to time.Duration) error {
+		config.Timeout = to
+		return health.GRPCProbe(config)
+	})
+}
+
 // httpProbe function executes HTTP probe once if its standard probe
 // otherwise HTTP probe polls condition function which returns true
 // if the probe count is greater than success threshold and false if HTTP probe fails
 func (p *Probe) httpProbe() error {
 	config := health.HTTPProbeConfigOptions{
 		HTTPGetAction: p.HTTPGet,
+		UnixSocket:    p.unixSocket,
 	}
 
 	return p.doProbe(func(to time.Duration) error {
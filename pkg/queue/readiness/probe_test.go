@@ -20,10 +20,12 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -141,6 +143,36 @@ func TestTCPSuccess(t *testing.T) {
 	}
 }
 
+func TestGRPCFailureOnPlainHTTP(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL %s: %v", ts.URL, err)
+	}
+
+	// The TCP probe would succeed, but the server does not speak gRPC.
+	pb := NewGRPCProbe(&corev1.Probe{
+		PeriodSeconds:    1,
+		TimeoutSeconds:   1,
+		SuccessThreshold: 1,
+		FailureThreshold: 1,
+		Handler: corev1.Handler{
+			TCPSocket: &corev1.TCPSocketAction{
+				Host: tsURL.Hostname(),
+				Port: intstr.FromString(tsURL.Port()),
+			},
+		},
+	}, "")
+
+	if pb.ProbeContainer() {
+		t.Error("Probe report success. Expected failure.")
+	}
+}
+
 func TestHTTPFailureToConnect(t *testing.T) {
 	pb := NewProbe(&corev1.Probe{
 		PeriodSeconds:    1,
@@ -221,6 +253,51 @@ func TestHTTPSuccess(t *testing.T) {
 	}
 }
 
+func TestUnixSocketSuccess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "user.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal("Failed to listen on the unix socket:", err)
+	}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.Listener = l
+	ts.Start()
+	defer ts.Close()
+
+	for name, handler := range map[string]corev1.Handler{
+		"tcp": {
+			TCPSocket: &corev1.TCPSocketAction{
+				Host: "127.0.0.1",
+				Port: intstr.FromInt(8080),
+			},
+		},
+		"http": {
+			HTTPGet: &corev1.HTTPGetAction{
+				Host:   "127.0.0.1",
+				Port:   intstr.FromInt(8080),
+				Scheme: corev1.URISchemeHTTP,
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pb := NewProbe(&corev1.Probe{
+				PeriodSeconds:    1,
+				TimeoutSeconds:   2,
+				SuccessThreshold: 1,
+				FailureThreshold: 1,
+				Handler:          handler,
+			})
+			pb.SetUnixSocket(path)
+
+			if !pb.ProbeContainer() {
+				t.Error("Probe failed. Expected success.")
+			}
+		})
+	}
+}
+
 func TestHTTPManyParallel(t *testing.T) {
 	var count atomic.Int32
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -643,3 +720,80 @@ func TestKnTCPProbeSuccessThresholdIncludesFailure(t *testing.T) {
 		t.Errorf("Count = %d, want: %d", got, successThreshold)
 	}
 }
+
+func TestSetTiming(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Timing
+		want Timing
+	}{{
+		name: "defaults",
+		want: DefaultTiming,
+	}, {
+		name: "all set",
+		in: Timing{
+			Period:        time.Second,
+			Timeout:       2 * time.Second,
+			BackoffFactor: 2,
+			MaxPeriod:     5 * time.Second,
+		},
+		want: Timing{
+			Period:        time.Second,
+			Timeout:       2 * time.Second,
+			BackoffFactor: 2,
+			MaxPeriod:     5 * time.Second,
+		},
+	}, {
+		name: "max period below period",
+		in: Timing{
+			Period: 2 * time.Second,
+		},
+		want: Timing{
+			Period:        2 * time.Second,
+			Timeout:       DefaultTiming.Timeout,
+			BackoffFactor: DefaultTiming.BackoffFactor,
+			MaxPeriod:     2 * time.Second,
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pb := NewProbe(&corev1.Probe{})
+			pb.SetTiming(test.in)
+			if !cmp.Equal(pb.timing, test.want) {
+				t.Error("Timing diff (-want,+got):", cmp.Diff(test.want, pb.timing))
+			}
+		})
+	}
+}
+
+func TestAggressiveProbeBackoff(t *testing.T) {
+	pb := NewProbe(&corev1.Probe{
+		SuccessThreshold: 1,
+	})
+	pb.pollTimeout = 300 * time.Millisecond
+	pb.out = ioutil.Discard
+	pb.SetTiming(Timing{
+		Period:        10 * time.Millisecond,
+		Timeout:       42 * time.Millisecond,
+		BackoffFactor: 2,
+		MaxPeriod:     80 * time.Millisecond,
+	})
+
+	attempts := 0
+	err := pb.doProbe(func(to time.Duration) error {
+		if to != 42*time.Millisecond {
+			t.Errorf("Probe timeout = %v, want: %v", to, 42*time.Millisecond)
+		}
+		attempts++
+		return errors.New("not ready")
+	})
+	if err == nil {
+		t.Fatal("Probe succeeded, want a timeout")
+	}
+	// The 20ms, 40ms, then 80ms intervals fit 5 attempts into 300ms,
+	// as opposed to 30 attempts without the backoff.
+	if attempts < 4 || attempts > 6 {
+		t.Errorf("Got %d attempts, want ~5", attempts)
+	}
+}
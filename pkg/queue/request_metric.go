@@ -19,11 +19,13 @@ package queue
 import (
 	"context"
 	"net/http"
+	"sync"
 	"time"
 
 	"go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
 	"go.opencensus.io/tag"
+	"go.uber.org/atomic"
 
 	network "knative.dev/networking/pkg"
 	pkgmetrics "knative.dev/pkg/metrics"
@@ -61,26 +63,113 @@ var (
 		stats.UnitDimensionless)
 )
 
+// metricsReportingPeriod is the period at which the batched request counts and
+// the queue depth are recorded.
+const metricsReportingPeriod = time.Second
+
 type requestMetricsHandler struct {
-	next     http.Handler
-	statsCtx context.Context
+	next  http.Handler
+	stats *responseStats
 }
 
 type appRequestMetricsHandler struct {
-	next     http.Handler
+	next    http.Handler
+	stats   *responseStats
+	breaker *Breaker
+}
+
+// responseStats records the request metrics without mutating the tags per
+// request: the tag contexts are allocated once per response code, and the
+// request counts are kept in atomic counters, recorded in batches every
+// metricsReportingPeriod.
+type responseStats struct {
 	statsCtx context.Context
-	breaker  *Breaker
+	countM   *stats.Int64Measure
+	latencyM *stats.Float64Measure
+
+	mux   sync.RWMutex
+	codes map[int]*codeStats
+}
+
+// codeStats are the stats of the requests with a response code.
+type codeStats struct {
+	ctx   context.Context
+	count atomic.Int64
+}
+
+func newResponseStats(statsCtx context.Context, countM *stats.Int64Measure, latencyM *stats.Float64Measure) *responseStats {
+	return &responseStats{
+		statsCtx: statsCtx,
+		countM:   countM,
+		latencyM: latencyM,
+		codes:    make(map[int]*codeStats, 8),
+	}
+}
+
+func (s *responseStats) forCode(code int) *codeStats {
+	s.mux.RLock()
+	cs := s.codes[code]
+	s.mux.RUnlock()
+	if cs != nil {
+		return cs
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if cs = s.codes[code]; cs == nil {
+		// TODO: add the routeTag back after stackdriver adds support for it.
+		// https://github.com/knative/serving/issues/8970
+		cs = &codeStats{ctx: metrics.AugmentWithResponse(s.statsCtx, code)}
+		s.codes[code] = cs
+	}
+	return cs
+}
+
+// record counts the request and records its latency.
+func (s *responseStats) record(code int, latency time.Duration) {
+	cs := s.forCode(code)
+	cs.count.Inc()
+	pkgmetrics.Record(cs.ctx, s.latencyM.M(float64(latency.Milliseconds())))
+}
+
+// flush records the requests counted since the previous flush.
+func (s *responseStats) flush() {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	for _, cs := range s.codes {
+		if n := cs.count.Swap(0); n > 0 {
+			pkgmetrics.Record(cs.ctx, s.countM.M(n))
+		}
+	}
+}
+
+// run flushes the stats and calls report every metricsReportingPeriod, until the
+// context is done.
+func (s *responseStats) run(ctx context.Context, report func()) {
+	ticker := time.NewTicker(metricsReportingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+			report()
+		case <-ctx.Done():
+			s.flush()
+			return
+		}
+	}
 }
 
 // NewRequestMetricsHandler creates an http.Handler that emits request metrics.
-func NewRequestMetricsHandler(next http.Handler,
+// The request counts are recorded in batches, until the context is done.
+func NewRequestMetricsHandler(ctx context.Context, next http.Handler,
 	ns, service, config, rev, pod string) (http.Handler, error) {
 	keys := []tag.Key{metrics.PodTagKey, metrics.ContainerTagKey, metrics.ResponseCodeKey, metrics.ResponseCodeClassKey /*, metrics.RouteTagKey*/}
 	if err := pkgmetrics.RegisterResourceView(
 		&view.View{
 			Description: "The number of requests that are routed to queue-proxy",
 			Measure:     requestCountM,
-			Aggregation: view.Count(),
+			Aggregation: view.Sum(),
 			TagKeys:     keys,
 		},
 		&view.View{
@@ -93,15 +182,17 @@ func NewRequestMetricsHandler(next http.Handler,
 		return nil, err
 	}
 
-	ctx, err := metrics.PodRevisionContext(pod, "queue-proxy", ns, service, config, rev)
+	statsCtx, err := metrics.PodRevisionContext(pod, "queue-proxy", ns, service, config, rev)
 	if err != nil {
 		return nil, err
 	}
 
-	return &requestMetricsHandler{
-		next:     next,
-		statsCtx: ctx,
-	}, nil
+	h := &requestMetricsHandler{
+		next:  next,
+		stats: newResponseStats(statsCtx, requestCountM, responseTimeInMsecM),
+	}
+	go h.stats.run(ctx, func() {})
+	return h, nil
 }
 
 func (h *requestMetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -119,35 +210,25 @@ func (h *requestMetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 		latency := time.Since(startTime)
 		// routeTag := GetRouteTagNameFromRequest(r)
 		if err != nil {
-			ctx := metrics.AugmentWithResponse(h.statsCtx, http.StatusInternalServerError)
-			// TODO: add the routeTag back after stackdriver adds support for it.
-			// https://github.com/knative/serving/issues/8970
-			// ctx := metrics.AugmentWithResponseAndRouteTag(h.statsCtx,
-			// http.StatusInternalServerError, routeTag)
-			pkgmetrics.RecordBatch(ctx, requestCountM.M(1),
-				responseTimeInMsecM.M(float64(latency.Milliseconds())))
+			h.stats.record(http.StatusInternalServerError, latency)
 			panic(err)
 		}
-		ctx := metrics.AugmentWithResponse(h.statsCtx, rr.ResponseCode)
-		// TODO: add the routeTag back after stackdriver adds support for it.
-		// https://github.com/knative/serving/issues/8970
-		// ctx := metrics.AugmentWithResponseAndRouteTag(h.statsCtx,
-		// rr.ResponseCode, routeTag)
-		pkgmetrics.RecordBatch(ctx, requestCountM.M(1),
-			responseTimeInMsecM.M(float64(latency.Milliseconds())))
+		h.stats.record(rr.ResponseCode, latency)
 	}()
 
 	h.next.ServeHTTP(rr, r)
 }
 
 // NewAppRequestMetricsHandler creates an http.Handler that emits request metrics.
-func NewAppRequestMetricsHandler(next http.Handler, b *Breaker,
+// The request counts and the queue depth are recorded in batches, until the
+// context is done.
+func NewAppRequestMetricsHandler(ctx context.Context, next http.Handler, b *Breaker,
 	ns, service, config, rev, pod string) (http.Handler, error) {
 	keys := []tag.Key{metrics.PodTagKey, metrics.ContainerTagKey, metrics.ResponseCodeKey, metrics.ResponseCodeClassKey}
 	if err := pkgmetrics.RegisterResourceView(&view.View{
 		Description: "The number of requests that are routed to user-container",
 		Measure:     appRequestCountM,
-		Aggregation: view.Count(),
+		Aggregation: view.Sum(),
 		TagKeys:     keys,
 	}, &view.View{
 		Description: "The response time in millisecond",
@@ -163,25 +244,31 @@ func NewAppRequestMetricsHandler(next http.Handler, b *Breaker,
 		return nil, err
 	}
 
-	ctx, err := metrics.PodRevisionContext(pod, "queue-proxy", ns, service, config, rev)
+	statsCtx, err := metrics.PodRevisionContext(pod, "queue-proxy", ns, service, config, rev)
 	if err != nil {
 		return nil, err
 	}
 
-	return &appRequestMetricsHandler{
-		next:     next,
-		statsCtx: ctx,
-		breaker:  b,
-	}, nil
+	h := &appRequestMetricsHandler{
+		next:    next,
+		stats:   newResponseStats(statsCtx, appRequestCountM, appResponseTimeInMsecM),
+		breaker: b,
+	}
+	go h.stats.run(ctx, h.reportQueueDepth)
+	return h, nil
+}
+
+// reportQueueDepth records the number of the requests in the breaker.
+func (h *appRequestMetricsHandler) reportQueueDepth() {
+	if h.breaker != nil {
+		pkgmetrics.Record(h.stats.statsCtx, queueDepthM.M(int64(h.breaker.InFlight())))
+	}
 }
 
 func (h *appRequestMetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	rr := pkghttp.NewResponseRecorder(w, http.StatusOK)
 	startTime := time.Now()
 
-	if h.breaker != nil {
-		pkgmetrics.Record(h.statsCtx, queueDepthM.M(int64(h.breaker.InFlight())))
-	}
 	defer func() {
 		// Filter probe requests for revision metrics.
 		if network.IsProbe(r) {
@@ -192,15 +279,10 @@ func (h *appRequestMetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Requ
 		err := recover()
 		latency := time.Since(startTime)
 		if err != nil {
-			ctx := metrics.AugmentWithResponse(h.statsCtx, http.StatusInternalServerError)
-			pkgmetrics.RecordBatch(ctx, appRequestCountM.M(1),
-				appResponseTimeInMsecM.M(float64(latency.Milliseconds())))
+			h.stats.record(http.StatusInternalServerError, latency)
 			panic(err)
 		}
-
-		ctx := metrics.AugmentWithResponse(h.statsCtx, rr.ResponseCode)
-		pkgmetrics.RecordBatch(ctx, appRequestCountM.M(1),
-			appResponseTimeInMsecM.M(float64(latency.Milliseconds())))
+		h.stats.record(rr.ResponseCode, latency)
 	}()
 	h.next.ServeHTTP(rr, r)
 }
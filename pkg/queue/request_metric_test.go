@@ -18,6 +18,7 @@ package queue
 
 import (
 	"bytes"
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -33,7 +34,7 @@ const targetURI = "http://example.com"
 
 func TestNewRequestMetricsHandlerFailure(t *testing.T) {
 	t.Cleanup(reset)
-	if _, err := NewRequestMetricsHandler(nil /*next*/, "a", "b", "c", "d", "shøüld fail"); err == nil {
+	if _, err := NewRequestMetricsHandler(context.Background(), nil /*next*/, "a", "b", "c", "d", "shøüld fail"); err == nil {
 		t.Error("Should get error when tag value is not ascii")
 	}
 }
@@ -41,7 +42,7 @@ func TestNewRequestMetricsHandlerFailure(t *testing.T) {
 func TestRequestMetricsHandler(t *testing.T) {
 	defer reset()
 	baseHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
-	handler, err := NewRequestMetricsHandler(baseHandler, "ns", "svc", "cfg", "rev", "pod")
+	handler, err := NewRequestMetricsHandler(context.Background(), baseHandler, "ns", "svc", "cfg", "rev", "pod")
 	if err != nil {
 		t.Fatal("Failed to create handler:", err)
 	}
@@ -67,12 +68,14 @@ func TestRequestMetricsHandler(t *testing.T) {
 		},
 	}
 
+	handler.(*requestMetricsHandler).stats.flush()
 	metricstest.AssertMetric(t, metricstest.IntMetric("request_count", 1, wantTags).WithResource(wantResource))
 	metricstest.AssertMetric(t, metricstest.DistributionCountOnlyMetric("request_latencies", 1, wantTags).WithResource(wantResource))
 
 	// A probe request should not be recorded.
 	req.Header.Set(network.ProbeHeaderName, "activator")
 	handler.ServeHTTP(resp, req)
+	handler.(*requestMetricsHandler).stats.flush()
 	metricstest.AssertMetric(t, metricstest.IntMetric("request_count", 1, wantTags).WithResource(wantResource))
 	metricstest.AssertMetric(t, metricstest.DistributionCountOnlyMetric("request_latencies", 1, wantTags).WithResource(wantResource))
 }
@@ -80,7 +83,7 @@ func TestRequestMetricsHandler(t *testing.T) {
 /* func TestRequestMetricsHandlerWithEnablingTagOnRequestMetrics(t *testing.T) {
 	defer reset()
 	baseHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
-	handler, err := NewRequestMetricsHandler(baseHandler, "ns", "svc", "cfg", "rev", "pod")
+	handler, err := NewRequestMetricsHandler(context.Background(), baseHandler, "ns", "svc", "cfg", "rev", "pod")
 	if err != nil {
 		t.Fatal("Failed to create handler:", err)
 	}
@@ -112,7 +115,7 @@ func TestRequestMetricsHandler(t *testing.T) {
 
 	// Testing for default route
 	reset()
-	handler, _ = NewRequestMetricsHandler(baseHandler, "ns", "svc", "cfg", "rev", "pod")
+	handler, _ = NewRequestMetricsHandler(context.Background(), baseHandler, "ns", "svc", "cfg", "rev", "pod")
 	req.Header.Del(network.TagHeaderName)
 	req.Header.Set(network.DefaultRouteHeaderName, "true")
 	handler.ServeHTTP(resp, req)
@@ -120,7 +123,7 @@ func TestRequestMetricsHandler(t *testing.T) {
 	metricstest.AssertMetric(t, metricstest.IntMetric("request_count", 1, wantTags).WithResource(wantResource))
 
 	reset()
-	handler, _ = NewRequestMetricsHandler(baseHandler, "ns", "svc", "cfg", "rev", "pod")
+	handler, _ = NewRequestMetricsHandler(context.Background(), baseHandler, "ns", "svc", "cfg", "rev", "pod")
 	req.Header.Set(network.TagHeaderName, "test-tag")
 	req.Header.Set(network.DefaultRouteHeaderName, "true")
 	handler.ServeHTTP(resp, req)
@@ -128,7 +131,7 @@ func TestRequestMetricsHandler(t *testing.T) {
 	metricstest.AssertMetric(t, metricstest.IntMetric("request_count", 1, wantTags).WithResource(wantResource))
 
 	reset()
-	handler, _ = NewRequestMetricsHandler(baseHandler, "ns", "svc", "cfg", "rev", "pod")
+	handler, _ = NewRequestMetricsHandler(context.Background(), baseHandler, "ns", "svc", "cfg", "rev", "pod")
 	req.Header.Set(network.TagHeaderName, "test-tag")
 	req.Header.Set(network.DefaultRouteHeaderName, "false")
 	handler.ServeHTTP(resp, req)
@@ -148,7 +151,7 @@ func TestRequestMetricsHandlerPanickingHandler(t *testing.T) {
 	baseHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		panic("no!")
 	})
-	handler, err := NewRequestMetricsHandler(baseHandler, "ns", "svc", "cfg", "rev", "pod")
+	handler, err := NewRequestMetricsHandler(context.Background(), baseHandler, "ns", "svc", "cfg", "rev", "pod")
 	if err != nil {
 		t.Fatal("Failed to create handler:", err)
 	}
@@ -175,6 +178,7 @@ func TestRequestMetricsHandlerPanickingHandler(t *testing.T) {
 				metricskey.LabelConfigurationName: "cfg",
 			},
 		}
+		handler.(*requestMetricsHandler).stats.flush()
 		metricstest.AssertMetric(t, metricstest.IntMetric("request_count", 1, wantTags).WithResource(wantResource))
 		metricstest.AssertMetric(t, metricstest.DistributionCountOnlyMetric("request_latencies", 1, wantTags).WithResource(wantResource))
 	}()
@@ -186,7 +190,7 @@ func BenchmarkNewRequestMetricsHandler(b *testing.B) {
 		w.WriteHeader(http.StatusOK)
 	})
 	breaker := NewBreaker(BreakerParams{QueueDepth: 10, MaxConcurrency: 10, InitialCapacity: 10})
-	handler, err := NewAppRequestMetricsHandler(baseHandler, breaker, "test-ns",
+	handler, err := NewAppRequestMetricsHandler(context.Background(), baseHandler, breaker, "test-ns",
 		"test-svc", "test-cfg", "test-rev", "test-pod")
 	if err != nil {
 		b.Fatal("failed to create request metric handler:", err)
@@ -215,7 +219,7 @@ func TestAppRequestMetricsHandlerPanickingHandler(t *testing.T) {
 		panic("no!")
 	})
 	breaker := NewBreaker(BreakerParams{QueueDepth: 10, MaxConcurrency: 10, InitialCapacity: 10})
-	handler, err := NewAppRequestMetricsHandler(baseHandler, breaker,
+	handler, err := NewAppRequestMetricsHandler(context.Background(), baseHandler, breaker,
 		"ns", "svc", "cfg", "rev", "pod")
 	if err != nil {
 		t.Fatal("Failed to create handler:", err)
@@ -243,6 +247,7 @@ func TestAppRequestMetricsHandlerPanickingHandler(t *testing.T) {
 			},
 		}
 
+		handler.(*appRequestMetricsHandler).stats.flush()
 		metricstest.AssertMetric(t, metricstest.IntMetric("app_request_count", 1, wantTags).WithResource(wantResource))
 		metricstest.AssertMetric(t, metricstest.DistributionCountOnlyMetric("app_request_latencies", 1, wantTags).WithResource(wantResource))
 	}()
@@ -253,7 +258,7 @@ func TestAppRequestMetricsHandler(t *testing.T) {
 	defer reset()
 	baseHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 	breaker := NewBreaker(BreakerParams{QueueDepth: 10, MaxConcurrency: 10, InitialCapacity: 10})
-	handler, err := NewAppRequestMetricsHandler(baseHandler, breaker,
+	handler, err := NewAppRequestMetricsHandler(context.Background(), baseHandler, breaker,
 		"ns", "svc", "cfg", "rev", "pod")
 	if err != nil {
 		t.Fatal("Failed to create handler:", err)
@@ -279,19 +284,66 @@ func TestAppRequestMetricsHandler(t *testing.T) {
 		},
 	}
 
+	handler.(*appRequestMetricsHandler).stats.flush()
 	metricstest.AssertMetric(t, metricstest.IntMetric("app_request_count", 1, wantTags).WithResource(wantResource))
 	metricstest.AssertMetric(t, metricstest.DistributionCountOnlyMetric("app_request_latencies", 1, wantTags).WithResource(wantResource))
 
 	// A probe request should not be recorded.
 	req.Header.Set(network.ProbeHeaderName, "activator")
 	handler.ServeHTTP(resp, req)
+	handler.(*appRequestMetricsHandler).stats.flush()
 	metricstest.AssertMetric(t, metricstest.IntMetric("app_request_count", 1, wantTags).WithResource(wantResource))
 	metricstest.AssertMetric(t, metricstest.DistributionCountOnlyMetric("app_request_latencies", 1, wantTags).WithResource(wantResource))
 }
 
+func TestRequestMetricsHandlerBatchesCounts(t *testing.T) {
+	defer reset()
+	code := http.StatusOK
+	baseHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	})
+	handler, err := NewRequestMetricsHandler(context.Background(), baseHandler, "ns", "svc", "cfg", "rev", "pod")
+	if err != nil {
+		t.Fatal("Failed to create handler:", err)
+	}
+	stats := handler.(*requestMetricsHandler).stats
+
+	req := httptest.NewRequest(http.MethodPost, targetURI, nil)
+	for i := 0; i < 3; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	code = http.StatusServiceUnavailable
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	wantTags := func(code, class string) map[string]string {
+		return map[string]string{
+			metricskey.PodName:                "pod",
+			metricskey.ContainerName:          "queue-proxy",
+			metricskey.LabelResponseCode:      code,
+			metricskey.LabelResponseCodeClass: class,
+		}
+	}
+	// The latencies are recorded right away, the counts only once flushed.
+	wantLatencies := metricstest.DistributionCountOnlyMetric("request_latencies", 3, wantTags("200", "2xx"))
+	wantLatencies.Values = append(wantLatencies.Values,
+		metricstest.DistributionCountOnlyMetric("request_latencies", 1, wantTags("503", "5xx")).Values...)
+	metricstest.AssertMetric(t, wantLatencies)
+	metricstest.AssertNoMetric(t, "request_count")
+
+	stats.flush()
+	wantCounts := metricstest.IntMetric("request_count", 3, wantTags("200", "2xx"))
+	wantCounts.Values = append(wantCounts.Values,
+		metricstest.IntMetric("request_count", 1, wantTags("503", "5xx")).Values...)
+	metricstest.AssertMetric(t, wantCounts)
+
+	// Nothing new to record.
+	stats.flush()
+	metricstest.AssertMetric(t, wantCounts)
+}
+
 func BenchmarkRequestMetricsHandler(b *testing.B) {
 	baseHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
-	handler, _ := NewRequestMetricsHandler(baseHandler, "ns", "svc", "cfg", "rev", "pod")
+	handler, _ := NewRequestMetricsHandler(context.Background(), baseHandler, "ns", "svc", "cfg", "rev", "pod")
 	req := httptest.NewRequest(http.MethodPost, "http://example.com", nil)
 
 	b.Run("sequential", func(b *testing.B) {
@@ -314,7 +366,7 @@ func BenchmarkRequestMetricsHandler(b *testing.B) {
 func BenchmarkAppRequestMetricsHandler(b *testing.B) {
 	baseHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 	breaker := NewBreaker(BreakerParams{QueueDepth: 10, MaxConcurrency: 10, InitialCapacity: 10})
-	handler, err := NewAppRequestMetricsHandler(baseHandler, breaker,
+	handler, err := NewAppRequestMetricsHandler(context.Background(), baseHandler, breaker,
 		"ns", "svc", "cfg", "rev", "pod")
 	if err != nil {
 		b.Fatal("Failed to create handler:", err)
@@ -0,0 +1,66 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"net/http"
+	"time"
+
+	pkgnet "knative.dev/pkg/network"
+)
+
+// TransportOptions tune the transport proxying the requests to the user
+// container, see the queueSidecar transport keys of config-deployment.
+type TransportOptions struct {
+	// MaxIdleConnsPerHost bounds the idle connections kept to the user
+	// container. As it's the only host, it bounds the idle connections overall.
+	MaxIdleConnsPerHost int
+
+	// TLSHandshakeTimeout bounds the TLS handshakes with a user container
+	// serving HTTPS. Zero keeps the default.
+	TLSHandshakeTimeout time.Duration
+
+	// DisableKeepAlives makes every request use a new connection.
+	DisableKeepAlives bool
+}
+
+// apply sets the options on t.
+func (o TransportOptions) apply(t *http.Transport) {
+	t.MaxIdleConns = o.MaxIdleConnsPerHost
+	t.MaxIdleConnsPerHost = o.MaxIdleConnsPerHost
+	if o.TLSHandshakeTimeout > 0 {
+		t.TLSHandshakeTimeout = o.TLSHandshakeTimeout
+	}
+	t.DisableKeepAlives = o.DisableKeepAlives
+}
+
+// NewTransport returns the transport proxying the requests to the user
+// container over TCP, speaking HTTP/1 or h2c like the incoming requests.
+// The options only apply to HTTP/1, h2c multiplexes over a connection.
+func NewTransport(opts TransportOptions) http.RoundTripper {
+	h1 := http.DefaultTransport.(*http.Transport).Clone()
+	h1.DialContext = pkgnet.DialWithBackOff
+	h1.ForceAttemptHTTP2 = false
+	opts.apply(h1)
+	h2c := pkgnet.NewH2CTransport()
+	return pkgnet.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if r.ProtoMajor == 2 {
+			return h2c.RoundTrip(r)
+		}
+		return h1.RoundTrip(r)
+	})
+}
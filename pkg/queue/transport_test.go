@@ -0,0 +1,96 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTransportOptionsApply(t *testing.T) {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	defaultTimeout := tr.TLSHandshakeTimeout
+
+	TransportOptions{MaxIdleConnsPerHost: 42}.apply(tr)
+	if tr.MaxIdleConns != 42 || tr.MaxIdleConnsPerHost != 42 {
+		t.Errorf("MaxIdleConns, MaxIdleConnsPerHost = %d, %d, want: 42, 42", tr.MaxIdleConns, tr.MaxIdleConnsPerHost)
+	}
+	if tr.TLSHandshakeTimeout != defaultTimeout {
+		t.Errorf("TLSHandshakeTimeout = %v, want: %v", tr.TLSHandshakeTimeout, defaultTimeout)
+	}
+	if tr.DisableKeepAlives {
+		t.Error("DisableKeepAlives = true, want: false")
+	}
+
+	TransportOptions{
+		MaxIdleConnsPerHost: 42,
+		TLSHandshakeTimeout: 3 * time.Second,
+		DisableKeepAlives:   true,
+	}.apply(tr)
+	if got, want := tr.TLSHandshakeTimeout, 3*time.Second; got != want {
+		t.Errorf("TLSHandshakeTimeout = %v, want: %v", got, want)
+	}
+	if !tr.DisableKeepAlives {
+		t.Error("DisableKeepAlives = false, want: true")
+	}
+}
+
+func TestTransportKeepAlives(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      TransportOptions
+		wantConns int32
+	}{{
+		name:      "keep-alives",
+		opts:      TransportOptions{MaxIdleConnsPerHost: 10},
+		wantConns: 1,
+	}, {
+		name:      "keep-alives disabled",
+		opts:      TransportOptions{MaxIdleConnsPerHost: 10, DisableKeepAlives: true},
+		wantConns: 3,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var conns int32
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					atomic.AddInt32(&conns, 1)
+				}
+			}
+			server.Start()
+			defer server.Close()
+
+			client := &http.Client{Transport: NewTransport(test.opts)}
+			for i := 0; i < 3; i++ {
+				resp, err := client.Get(server.URL)
+				if err != nil {
+					t.Fatal("Get() =", err)
+				}
+				resp.Body.Close()
+			}
+			if got := atomic.LoadInt32(&conns); got != test.wantConns {
+				t.Errorf("Connections = %d, want: %d", got, test.wantConns)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	pkgnet "knative.dev/pkg/network"
+)
+
+// NewUnixSocketTransport returns a transport, which connects to the unix
+// domain socket at path, the user container serves HTTP/1 on, for the
+// requests to addr. The requests to any other address, e.g. of the named
+// ports, go over TCP.
+func NewUnixSocketTransport(path, addr string, opts TransportOptions) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		if address == addr {
+			return pkgnet.DialWithBackOff(ctx, "unix", path)
+		}
+		return pkgnet.DialWithBackOff(ctx, network, address)
+	}
+	t.ForceAttemptHTTP2 = false
+	opts.apply(t)
+	return t
+}
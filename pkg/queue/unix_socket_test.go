@@ -0,0 +1,68 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnixSocketTransport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "user.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal("Failed to listen on the unix socket:", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host + r.URL.Path))
+	}))
+	server.Listener = l
+	server.Start()
+	defer server.Close()
+
+	client := &http.Client{Transport: NewUnixSocketTransport(path, "127.0.0.1:8080", TransportOptions{MaxIdleConnsPerHost: 10})}
+	resp, err := client.Get("http://127.0.0.1:8080/foo")
+	if err != nil {
+		t.Fatal("Get() =", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal("Failed to read the body:", err)
+	}
+	if got, want := string(body), "127.0.0.1:8080/foo"; got != want {
+		t.Errorf("Body = %q, want: %q", got, want)
+	}
+
+	// Other addresses aren't served by the socket.
+	tcp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer tcp.Close()
+	resp, err = client.Get(tcp.URL)
+	if err != nil {
+		t.Fatal("Get() =", err)
+	}
+	resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusTeapot; got != want {
+		t.Errorf("StatusCode = %d, want: %d", got, want)
+	}
+}
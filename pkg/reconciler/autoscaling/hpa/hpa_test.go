@@ -36,7 +36,6 @@ import (
 	fakepainformer "knative.dev/serving/pkg/client/injection/informers/autoscaling/v1alpha1/podautoscaler/fake"
 	pareconciler "knative.dev/serving/pkg/client/injection/reconciler/autoscaling/v1alpha1/podautoscaler"
 
-	appsv1 "k8s.io/api/apps/v1"
 	autoscalingv2beta1 "k8s.io/api/autoscaling/v2beta1"
 	corev1 "k8s.io/api/core/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
@@ -45,12 +44,10 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	ktesting "k8s.io/client-go/testing"
 
-	"knative.dev/networking/pkg/apis/networking"
 	nv1a1 "knative.dev/networking/pkg/apis/networking/v1alpha1"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/logging"
-	"knative.dev/pkg/ptr"
 	"knative.dev/pkg/reconciler"
 	"knative.dev/pkg/system"
 	"knative.dev/serving/pkg/apis/autoscaling"
@@ -103,7 +100,7 @@ func TestControllerCanReconcile(t *testing.T) {
 		waitInformers()
 	}()
 
-	podAutoscaler := pa(testNamespace, testRevision, WithHPAClass)
+	podAutoscaler := PodAutoscaler(testNamespace, testRevision, WithHPAClass)
 	fakeservingclient.Get(ctx).AutoscalingV1alpha1().PodAutoscalers(testNamespace).Create(ctx, podAutoscaler, metav1.CreateOptions{})
 	fakepainformer.Get(ctx).Informer().GetIndexer().Add(podAutoscaler)
 
@@ -133,18 +130,18 @@ func TestReconcile(t *testing.T) {
 	table := TableTest{{
 		Name: "no op",
 		Objects: []runtime.Object{
-			hpa(pa(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
-			pa(testNamespace, testRevision, WithHPAClass, WithPASKSReady, WithTraffic, WithScaleTargetInitialized,
-				WithPAStatusService(testRevision), WithPAMetricsService(privateSvc), withScales(0, 0)),
-			deploy(testNamespace, testRevision),
+			hpa(PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+			PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithPASKSReady, WithTraffic, WithScaleTargetInitialized,
+				WithPAStatusService(testRevision), WithPAMetricsService(privateSvc), WithScales(0, 0)),
+			Deployment(testNamespace, testRevision),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithSKSReady),
 		},
 		Key: key(testNamespace, testRevision),
 	}, {
 		Name: "create hpa & sks, with retry",
 		Objects: []runtime.Object{
-			pa(testNamespace, testRevision, WithHPAClass),
-			deploy(testNamespace, testRevision),
+			PodAutoscaler(testNamespace, testRevision, WithHPAClass),
+			Deployment(testNamespace, testRevision),
 		},
 		Key: key(testNamespace, testRevision),
 		WithReactors: []ktesting.ReactionFunc{
@@ -158,27 +155,27 @@ func TestReconcile(t *testing.T) {
 		},
 		WantCreates: []runtime.Object{
 			sks(testNamespace, testRevision, WithDeployRef(deployName)),
-			hpa(pa(testNamespace, testRevision,
+			hpa(PodAutoscaler(testNamespace, testRevision,
 				WithHPAClass, WithMetricAnnotation("cpu"))),
 		},
 		WantStatusUpdates: []ktesting.UpdateActionImpl{{
-			Object: pa(testNamespace, testRevision, WithHPAClass, withScales(0, 0),
+			Object: PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithScales(0, 0),
 				WithTraffic, WithPASKSNotReady("SKS Services are not ready yet")),
 		}, {
-			Object: pa(testNamespace, testRevision, WithHPAClass, withScales(0, 0),
+			Object: PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithScales(0, 0),
 				WithTraffic, WithPASKSNotReady("SKS Services are not ready yet")),
 		}},
 	}, {
 		Name: "reconcile sks is still not ready",
 		Objects: []runtime.Object{
-			hpa(pa(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
-			pa(testNamespace, testRevision, WithHPAClass),
-			deploy(testNamespace, testRevision),
+			hpa(PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+			PodAutoscaler(testNamespace, testRevision, WithHPAClass),
+			Deployment(testNamespace, testRevision),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithPubService,
 				WithPrivateService),
 		},
 		WantStatusUpdates: []ktesting.UpdateActionImpl{{
-			Object: pa(testNamespace, testRevision, WithHPAClass, WithTraffic, withScales(0, 0),
+			Object: PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithTraffic, WithScales(0, 0),
 				WithPASKSNotReady("SKS Services are not ready yet"), WithTraffic,
 				WithPAStatusService(testRevision), WithPAMetricsService(privateSvc)),
 		}},
@@ -186,14 +183,14 @@ func TestReconcile(t *testing.T) {
 	}, {
 		Name: "reconcile sks becomes ready",
 		Objects: []runtime.Object{
-			hpa(pa(testNamespace, testRevision, WithHPAClass, WithPASKSNotReady("I wasn't ready yet :-("),
+			hpa(PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithPASKSNotReady("I wasn't ready yet :-("),
 				WithMetricAnnotation("cpu"))),
-			pa(testNamespace, testRevision, WithHPAClass, WithPAStatusService("the-wrong-one")),
-			deploy(testNamespace, testRevision),
+			PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithPAStatusService("the-wrong-one")),
+			Deployment(testNamespace, testRevision),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithSKSReady),
 		},
 		WantStatusUpdates: []ktesting.UpdateActionImpl{{
-			Object: pa(testNamespace, testRevision, WithHPAClass, withScales(0, 0),
+			Object: PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithScales(0, 0),
 				WithPASKSReady, WithTraffic, WithScaleTargetInitialized,
 				WithPAStatusService(testRevision), WithPAMetricsService(privateSvc)),
 		}},
@@ -201,14 +198,14 @@ func TestReconcile(t *testing.T) {
 	}, {
 		Name: "reconcile sks",
 		Objects: []runtime.Object{
-			hpa(pa(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu")), withHPAScaleStatus(5, 3)),
-			pa(testNamespace, testRevision, WithHPAClass, withScales(1, 4), WithPASKSNotReady("crufty"), WithTraffic),
-			deploy(testNamespace, testRevision),
+			hpa(PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu")), withHPAScaleStatus(5, 3)),
+			PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithScales(1, 4), WithPASKSNotReady("crufty"), WithTraffic),
+			Deployment(testNamespace, testRevision),
 			sks(testNamespace, testRevision, WithDeployRef("bar"), WithSKSReady),
 		},
 		WantStatusUpdates: []ktesting.UpdateActionImpl{{
-			Object: pa(testNamespace, testRevision, WithHPAClass, WithPASKSReady, WithTraffic,
-				WithScaleTargetInitialized, withScales(5, 3),
+			Object: PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithPASKSReady, WithTraffic,
+				WithScaleTargetInitialized, WithScales(5, 3),
 				WithPAStatusService(testRevision), WithPAMetricsService(privateSvc)),
 		}},
 		Key: key(testNamespace, testRevision),
@@ -218,14 +215,14 @@ func TestReconcile(t *testing.T) {
 	}, {
 		Name: "reconcile unhappy sks",
 		Objects: []runtime.Object{
-			hpa(pa(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
-			pa(testNamespace, testRevision, WithHPAClass, WithTraffic),
-			deploy(testNamespace, testRevision),
+			hpa(PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+			PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithTraffic),
+			Deployment(testNamespace, testRevision),
 			sks(testNamespace, testRevision, WithDeployRef(deployName+"-hairy"),
 				WithPubService, WithPrivateService),
 		},
 		WantStatusUpdates: []ktesting.UpdateActionImpl{{
-			Object: pa(testNamespace, testRevision, WithHPAClass, withScales(0, 0),
+			Object: PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithScales(0, 0),
 				WithPASKSNotReady("SKS Services are not ready yet"), WithTraffic,
 				WithPAStatusService(testRevision), WithPAMetricsService(privateSvc)),
 		}},
@@ -237,10 +234,10 @@ func TestReconcile(t *testing.T) {
 	}, {
 		Name: "reconcile sks - update fails",
 		Objects: []runtime.Object{
-			pa(testNamespace, testRevision, WithHPAClass, WithTraffic, withScales(0, 0)),
-			deploy(testNamespace, testRevision),
+			PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithTraffic, WithScales(0, 0)),
+			Deployment(testNamespace, testRevision),
 			sks(testNamespace, testRevision, WithDeployRef("bar"), WithSKSReady),
-			hpa(pa(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+			hpa(PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
 		},
 		Key: key(testNamespace, testRevision),
 		WithReactors: []ktesting.ReactionFunc{
@@ -256,9 +253,9 @@ func TestReconcile(t *testing.T) {
 	}, {
 		Name: "create sks - create fails",
 		Objects: []runtime.Object{
-			pa(testNamespace, testRevision, WithHPAClass, withScales(0, 0), WithTraffic),
-			deploy(testNamespace, testRevision),
-			hpa(pa(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+			PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithScales(0, 0), WithTraffic),
+			Deployment(testNamespace, testRevision),
+			hpa(PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
 		},
 		Key: key(testNamespace, testRevision),
 		WithReactors: []ktesting.ReactionFunc{
@@ -274,15 +271,15 @@ func TestReconcile(t *testing.T) {
 	}, {
 		Name: "sks is disowned",
 		Objects: []runtime.Object{
-			pa(testNamespace, testRevision, WithHPAClass),
-			deploy(testNamespace, testRevision),
+			PodAutoscaler(testNamespace, testRevision, WithHPAClass),
+			Deployment(testNamespace, testRevision),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithSKSOwnersRemoved, WithSKSReady),
-			hpa(pa(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+			hpa(PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
 		},
 		Key:     key(testNamespace, testRevision),
 		WantErr: true,
 		WantStatusUpdates: []ktesting.UpdateActionImpl{{
-			Object: pa(testNamespace, testRevision, WithHPAClass, MarkResourceNotOwnedByPA("ServerlessService", testRevision)),
+			Object: PodAutoscaler(testNamespace, testRevision, WithHPAClass, MarkResourceNotOwnedByPA("ServerlessService", testRevision)),
 		}},
 		WantEvents: []string{
 			Eventf(corev1.EventTypeWarning, "InternalError", `error reconciling SKS: PA: test-revision does not own SKS: test-revision`),
@@ -290,15 +287,15 @@ func TestReconcile(t *testing.T) {
 	}, {
 		Name: "pa is disowned",
 		Objects: []runtime.Object{
-			pa(testNamespace, testRevision, WithHPAClass),
-			deploy(testNamespace, testRevision),
+			PodAutoscaler(testNamespace, testRevision, WithHPAClass),
+			Deployment(testNamespace, testRevision),
 			sks(testNamespace, testRevision, WithDeployRef(deployName)),
-			hpa(pa(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu"), WithPAOwnersRemoved), withHPAOwnersRemoved),
+			hpa(PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu"), WithPAOwnersRemoved), withHPAOwnersRemoved),
 		},
 		Key:     key(testNamespace, testRevision),
 		WantErr: true,
 		WantStatusUpdates: []ktesting.UpdateActionImpl{{
-			Object: pa(testNamespace, testRevision, WithHPAClass, MarkResourceNotOwnedByPA("HorizontalPodAutoscaler", testRevision)),
+			Object: PodAutoscaler(testNamespace, testRevision, WithHPAClass, MarkResourceNotOwnedByPA("HorizontalPodAutoscaler", testRevision)),
 		}},
 		WantEvents: []string{
 			Eventf(corev1.EventTypeWarning, "InternalError",
@@ -308,20 +305,20 @@ func TestReconcile(t *testing.T) {
 		Name: "nop deletion reconcile",
 		// Test that with a DeletionTimestamp we do nothing.
 		Objects: []runtime.Object{
-			pa(testNamespace, testRevision, WithHPAClass, WithPADeletionTimestamp),
-			deploy(testNamespace, testRevision),
+			PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithPADeletionTimestamp),
+			Deployment(testNamespace, testRevision),
 		},
 		Key: key(testNamespace, testRevision),
 	}, {
 		Name: "update pa fails",
 		Objects: []runtime.Object{
-			hpa(pa(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu")), withHPAScaleStatus(19, 18)),
-			pa(testNamespace, testRevision, WithHPAClass, WithPAStatusService("the-wrong-one"), withScales(42, 84)),
-			deploy(testNamespace, testRevision),
+			hpa(PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu")), withHPAScaleStatus(19, 18)),
+			PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithPAStatusService("the-wrong-one"), WithScales(42, 84)),
+			Deployment(testNamespace, testRevision),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithSKSReady),
 		},
 		WantStatusUpdates: []ktesting.UpdateActionImpl{{
-			Object: pa(testNamespace, testRevision, WithHPAClass, withScales(19, 18),
+			Object: PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithScales(19, 18),
 				WithPASKSReady, WithTraffic, WithScaleTargetInitialized,
 				WithPAStatusService(testRevision), WithPAMetricsService(privateSvc)),
 		}},
@@ -336,16 +333,16 @@ func TestReconcile(t *testing.T) {
 	}, {
 		Name: "update hpa fails",
 		Objects: []runtime.Object{
-			pa(testNamespace, testRevision, WithHPAClass, WithPASKSReady, WithScaleTargetInitialized,
-				WithTraffic, withScales(0, 0),
+			PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithPASKSReady, WithScaleTargetInitialized,
+				WithTraffic, WithScales(0, 0),
 				WithPAStatusService(testRevision), WithTargetAnnotation("1")),
-			hpa(pa(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+			hpa(PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithSKSReady, WithNumActivators(0)),
-			deploy(testNamespace, testRevision),
+			Deployment(testNamespace, testRevision),
 		},
 		Key: key(testNamespace, testRevision),
 		WantUpdates: []ktesting.UpdateActionImpl{{
-			Object: hpa(pa(testNamespace, testRevision, WithHPAClass, WithTargetAnnotation("1"), WithMetricAnnotation("cpu"))),
+			Object: hpa(PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithTargetAnnotation("1"), WithMetricAnnotation("cpu"))),
 		}},
 		WantErr: true,
 		WithReactors: []ktesting.ReactionFunc{
@@ -357,39 +354,39 @@ func TestReconcile(t *testing.T) {
 	}, {
 		Name: "update hpa with target usage",
 		Objects: []runtime.Object{
-			pa(testNamespace, testRevision, WithHPAClass, WithPASKSReady,
-				WithTraffic, WithScaleTargetInitialized, withScales(0, 0),
+			PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithPASKSReady,
+				WithTraffic, WithScaleTargetInitialized, WithScales(0, 0),
 				WithPAStatusService(testRevision), WithPAMetricsService(privateSvc), WithTargetAnnotation("1")),
-			hpa(pa(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
-			deploy(testNamespace, testRevision),
+			hpa(PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+			Deployment(testNamespace, testRevision),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithSKSReady, WithNumActivators(0)),
 		},
 		Key: key(testNamespace, testRevision),
 		WantUpdates: []ktesting.UpdateActionImpl{{
-			Object: hpa(pa(testNamespace, testRevision, WithHPAClass, WithPASKSReady,
+			Object: hpa(PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithPASKSReady,
 				WithTargetAnnotation("1"), WithMetricAnnotation("cpu"))),
 		}},
 	}, {
 		Name: "invalid key",
 		Objects: []runtime.Object{
-			pa(testNamespace, testRevision, WithHPAClass),
+			PodAutoscaler(testNamespace, testRevision, WithHPAClass),
 		},
 		Key: "sandwich///",
 	}, {
 		Name: "failure to create HPA",
 		Objects: []runtime.Object{
-			pa(testNamespace, testRevision, withScales(0, 0), WithHPAClass),
-			deploy(testNamespace, testRevision),
+			PodAutoscaler(testNamespace, testRevision, WithScales(0, 0), WithHPAClass),
+			Deployment(testNamespace, testRevision),
 		},
 		Key: key(testNamespace, testRevision),
 		WantCreates: []runtime.Object{
-			hpa(pa(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
+			hpa(PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithMetricAnnotation("cpu"))),
 		},
 		WithReactors: []ktesting.ReactionFunc{
 			InduceFailure("create", "horizontalpodautoscalers"),
 		},
 		WantStatusUpdates: []ktesting.UpdateActionImpl{{
-			Object: pa(testNamespace, testRevision, WithHPAClass, withScales(0, 0),
+			Object: PodAutoscaler(testNamespace, testRevision, WithHPAClass, WithScales(0, 0),
 				WithNoTraffic(
 					"FailedCreate", `Failed to create HorizontalPodAutoscaler "test-revision".`)),
 		}},
@@ -422,7 +419,7 @@ func TestReconcile(t *testing.T) {
 }
 
 func sks(ns, n string, so ...SKSOption) *nv1a1.ServerlessService {
-	hpa := pa(ns, n, WithHPAClass)
+	hpa := PodAutoscaler(ns, n, WithHPAClass)
 	s := aresources.MakeSKS(hpa, nv1a1.SKSOperationModeServe, 0)
 	for _, opt := range so {
 		opt(s)
@@ -434,39 +431,12 @@ func key(namespace, name string) string {
 	return namespace + "/" + name
 }
 
-func pa(namespace, name string, options ...PodAutoscalerOption) *asv1a1.PodAutoscaler {
-	pa := &asv1a1.PodAutoscaler{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      name,
-			Namespace: namespace,
-		},
-		Spec: asv1a1.PodAutoscalerSpec{
-			ScaleTargetRef: corev1.ObjectReference{
-				APIVersion: "apps/v1",
-				Kind:       "Deployment",
-				Name:       name + "-deployment",
-			},
-			ProtocolType: networking.ProtocolHTTP1,
-		},
-	}
-	pa.Status.InitializeConditions()
-	for _, opt := range options {
-		opt(pa)
-	}
-	return pa
-}
-
 type hpaOption func(*autoscalingv2beta1.HorizontalPodAutoscaler)
 
 func withHPAOwnersRemoved(hpa *autoscalingv2beta1.HorizontalPodAutoscaler) {
 	hpa.OwnerReferences = nil
 }
 
-func withScales(d, a int32) PodAutoscalerOption {
-	return func(pa *asv1a1.PodAutoscaler) {
-		pa.Status.DesiredScale, pa.Status.ActualScale = ptr.Int32(d), ptr.Int32(a)
-	}
-}
 func withHPAScaleStatus(d, a int32) hpaOption {
 	return func(hpa *autoscalingv2beta1.HorizontalPodAutoscaler) {
 		hpa.Status.DesiredReplicas, hpa.Status.CurrentReplicas = d, a
@@ -481,31 +451,6 @@ func hpa(pa *asv1a1.PodAutoscaler, options ...hpaOption) *autoscalingv2beta1.Hor
 	return h
 }
 
-type deploymentOption func(*appsv1.Deployment)
-
-func deploy(namespace, name string, opts ...deploymentOption) *appsv1.Deployment {
-	s := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      name + "-deployment",
-			Namespace: namespace,
-		},
-		Spec: appsv1.DeploymentSpec{
-			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"a": "b",
-				},
-			},
-		},
-		Status: appsv1.DeploymentStatus{
-			Replicas: 42,
-		},
-	}
-	for _, opt := range opts {
-		opt(s)
-	}
-	return s
-}
-
 func defaultConfig() *config.Config {
 	autoscalerConfig, _ := autoscalerconfig.NewConfigFromMap(nil)
 	return &config.Config{
@@ -91,6 +91,7 @@ func NewController(
 		return controller.Options{ConfigStore: configStore}
 	})
 	c.scaler = newScaler(ctx, psInformerFactory, impl.EnqueueAfter)
+	c.requeuer = servingreconciler.NewRequeuer(impl.EnqueueAfter)
 
 	logger.Info("Setting up KPA-Class event handlers")
 
@@ -33,6 +33,7 @@ import (
 	"knative.dev/serving/pkg/autoscaler/scaling"
 	pareconciler "knative.dev/serving/pkg/client/injection/reconciler/autoscaling/v1alpha1/podautoscaler"
 	"knative.dev/serving/pkg/metrics"
+	servingreconciler "knative.dev/serving/pkg/reconciler"
 	areconciler "knative.dev/serving/pkg/reconciler/autoscaling"
 	"knative.dev/serving/pkg/reconciler/autoscaling/config"
 	"knative.dev/serving/pkg/reconciler/autoscaling/kpa/resources"
@@ -67,13 +68,15 @@ type Reconciler struct {
 	podsLister corev1listers.PodLister
 	deciders   resources.Deciders
 	scaler     *scaler
+	requeuer   *servingreconciler.Requeuer
 }
 
 // Check that our Reconciler implements pareconciler.Interface
 var _ pareconciler.Interface = (*Reconciler)(nil)
 
 // ReconcileKind implements Interface.ReconcileKind.
-func (c *Reconciler) ReconcileKind(ctx context.Context, pa *pav1alpha1.PodAutoscaler) pkgreconciler.Event {
+func (c *Reconciler) ReconcileKind(ctx context.Context, pa *pav1alpha1.PodAutoscaler) (ev pkgreconciler.Event) {
+	defer func() { ev = c.requeuer.Handle(pa, ev) }()
 	logger := logging.FromContext(ctx)
 
 	// We need the SKS object in order to optimize scale to zero
@@ -120,8 +123,13 @@ func (c *Reconciler) ReconcileKind(ctx context.Context, pa *pav1alpha1.PodAutosc
 	//   b. want == -1 && PA is inactive (Autoscaler has no previous knowledge of
 	//			this revision, e.g. after a restart) but PA status is inactive (it was
 	//			already scaled to 0).
-	// 2. The excess burst capacity is negative.
-	if want == 0 || decider.Status.ExcessBurstCapacity < 0 || want == scaleUnknown && pa.Status.IsInactive() {
+	// 2. The excess burst capacity is negative, unless the revision asked to
+	//    bypass the activator, in which case the Serve mode is sticky until
+	//    the revision scales to 0.
+	// 3. The revision has warm standby pods, which only the activator keeps out of
+	//    load balancing until the rest of the pods are saturated.
+	if want == 0 || decider.Status.ExcessBurstCapacity < 0 && !pa.ActivatorBypass() ||
+		want == scaleUnknown && pa.Status.IsInactive() || hasStandby(pa) {
 		mode = nv1alpha1.SKSOperationModeProxy
 	}
 	logger.Infof("SKS should be in %s mode: want = %d, ebc = %d, #act's = %d PA Inactive? = %v",
@@ -168,6 +176,13 @@ func (c *Reconciler) ReconcileKind(ctx context.Context, pa *pav1alpha1.PodAutosc
 	return nil
 }
 
+// hasStandby returns true if the revision backing the PA requested
+// warm standby pods.
+func hasStandby(pa *pav1alpha1.PodAutoscaler) bool {
+	standby, _ := pa.StandbyScale()
+	return standby > 0
+}
+
 func (c *Reconciler) reconcileDecider(ctx context.Context, pa *pav1alpha1.PodAutoscaler) (*scaling.Decider, error) {
 	desiredDecider := resources.MakeDecider(ctx, pa, config.FromContext(ctx).Autoscaler)
 	decider, err := c.deciders.Get(ctx, desiredDecider.Namespace, desiredDecider.Name)
@@ -195,6 +210,7 @@ func computeStatus(ctx context.Context, pa *pav1alpha1.PodAutoscaler, pc podCoun
 	pa.Status.DesiredScale, pa.Status.ActualScale = ptr.Int32(int32(pc.want)), ptr.Int32(int32(pc.ready))
 
 	reportMetrics(pa, pc)
+	areconciler.ReportSLOTargets(pa)
 	computeActiveCondition(ctx, pa, pc)
 	logger.Debugf("PA Status after reconcile: %#v", pa.Status.Status)
 }
@@ -296,6 +312,9 @@ func resolveScrapeTarget(ctx context.Context, pa *pav1alpha1.PodAutoscaler) stri
 }
 
 func resolveTBC(ctx context.Context, pa *pav1alpha1.PodAutoscaler) float64 {
+	if pa.ActivatorBypass() {
+		return 0
+	}
 	if v, ok := pa.TargetBC(); ok {
 		return v
 	}
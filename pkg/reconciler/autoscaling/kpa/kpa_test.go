@@ -44,7 +44,6 @@ import (
 	fakerevisioninformer "knative.dev/serving/pkg/client/injection/informers/serving/v1/revision/fake"
 	pareconciler "knative.dev/serving/pkg/client/injection/reconciler/autoscaling/v1alpha1/podautoscaler"
 
-	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -67,7 +66,6 @@ import (
 	"knative.dev/pkg/metrics/metricskey"
 	"knative.dev/pkg/metrics/metricstest"
 	_ "knative.dev/pkg/metrics/testing"
-	"knative.dev/pkg/ptr"
 	"knative.dev/pkg/reconciler"
 	"knative.dev/pkg/system"
 	_ "knative.dev/pkg/system/testing"
@@ -149,12 +147,6 @@ func newConfigWatcher() configmap.Watcher {
 	})
 }
 
-func withScales(g, w int32) PodAutoscalerOption {
-	return func(pa *asv1a1.PodAutoscaler) {
-		pa.Status.DesiredScale, pa.Status.ActualScale = ptr.Int32(w), ptr.Int32(g)
-	}
-}
-
 func metricWithDiffSvc(ns, n string) *asv1a1.Metric {
 	m := metric(ns, n)
 	m.Spec.ScrapeTarget = "something-else"
@@ -198,8 +190,7 @@ func kpa(ns, n string, opts ...PodAutoscalerOption) *asv1a1.PodAutoscaler {
 	rev := newTestRevision(ns, n)
 	kpa := revisionresources.MakePA(rev)
 	kpa.Generation = 1
-	kpa.Annotations["autoscaling.knative.dev/class"] = "kpa.autoscaling.knative.dev"
-	kpa.Annotations["autoscaling.knative.dev/metric"] = "concurrency"
+	WithKPAClass(kpa)
 	kpa.Status.InitializeConditions()
 	for _, opt := range opts {
 		opt(kpa)
@@ -207,6 +198,14 @@ func kpa(ns, n string, opts ...PodAutoscalerOption) *asv1a1.PodAutoscaler {
 	return kpa
 }
 
+func withSessionAffinity(pa *asv1a1.PodAutoscaler) {
+	pa.Annotations[serving.SessionAffinityHeaderAnnotationKey] = "X-Session-Id"
+}
+
+func withActivatorBypass(pa *asv1a1.PodAutoscaler) {
+	pa.Annotations[autoscaling.ActivatorBypassAnnotationKey] = "true"
+}
+
 func markResourceNotOwned(rType, name string) PodAutoscalerOption {
 	return func(pa *asv1a1.PodAutoscaler) {
 		pa.Status.MarkResourceNotOwned(rType, name)
@@ -225,17 +224,11 @@ func TestReconcile(t *testing.T) {
 
 	// Set up a default deployment with the appropriate scale so that we don't
 	// see patches to correct that scale.
-	defaultDeployment := deploy(testNamespace, testRevision, func(d *appsv1.Deployment) {
-		d.Spec.Replicas = ptr.Int32(defaultScale)
-	})
+	defaultDeployment := Deployment(testNamespace, testRevision, WithDeploymentReplicas(defaultScale))
 
 	// Setup underscaled and overscaled deployment
-	underscaledDeployment := deploy(testNamespace, testRevision, func(d *appsv1.Deployment) {
-		d.Spec.Replicas = ptr.Int32(underscale)
-	})
-	overscaledDeployment := deploy(testNamespace, testRevision, func(d *appsv1.Deployment) {
-		d.Spec.Replicas = ptr.Int32(overscale)
-	})
+	underscaledDeployment := Deployment(testNamespace, testRevision, WithDeploymentReplicas(underscale))
+	overscaledDeployment := Deployment(testNamespace, testRevision, WithDeploymentReplicas(overscale))
 
 	minScalePatch := clientgotesting.PatchActionImpl{
 		ActionImpl: clientgotesting.ActionImpl{Namespace: testNamespace},
@@ -247,7 +240,7 @@ func TestReconcile(t *testing.T) {
 		return kpa(
 			testNamespace, testRevision, WithPASKSNotReady(""), WithScaleTargetInitialized,
 			WithNoTraffic(noTrafficReason, "The target is not receiving traffic."),
-			withScales(g, unknownScale), WithReachabilityReachable,
+			WithScales(unknownScale, g), WithReachabilityReachable,
 			withMinScale(defaultScale), WithPAStatusService(testRevision),
 			WithPAMetricsService(privateSvc), WithObservedGeneration(1),
 		)
@@ -255,7 +248,7 @@ func TestReconcile(t *testing.T) {
 	activatingKPAMinScale := func(g int32, opts ...PodAutoscalerOption) *asv1a1.PodAutoscaler {
 		kpa := kpa(
 			testNamespace, testRevision, WithPASKSNotReady(""),
-			WithBufferedTraffic, withScales(g, defaultScale), WithReachabilityReachable,
+			WithBufferedTraffic, WithScales(defaultScale, g), WithReachabilityReachable,
 			withMinScale(defaultScale), WithPAStatusService(testRevision), WithPAMetricsService(privateSvc),
 			WithObservedGeneration(1),
 		)
@@ -267,7 +260,7 @@ func TestReconcile(t *testing.T) {
 	activeKPAMinScale := func(g, w int32) *asv1a1.PodAutoscaler {
 		return kpa(
 			testNamespace, testRevision, WithPASKSReady, WithTraffic, markScaleTargetInitialized,
-			withScales(g, w), WithReachabilityReachable,
+			WithScales(w, g), WithReachabilityReachable,
 			withMinScale(defaultScale), WithPAStatusService(testRevision), WithPAMetricsService(privateSvc),
 			WithObservedGeneration(1),
 		)
@@ -304,7 +297,18 @@ func TestReconcile(t *testing.T) {
 		Objects: []runtime.Object{
 			kpa(testNamespace, testRevision, WithPASKSReady, WithTraffic,
 				markScaleTargetInitialized, WithPAMetricsService(privateSvc),
-				withScales(1, defaultScale), WithPAStatusService(testRevision), WithObservedGeneration(1)),
+				WithScales(defaultScale, 1), WithPAStatusService(testRevision), WithObservedGeneration(1)),
+			sks(testNamespace, testRevision, WithDeployRef(deployName), WithSKSReady),
+			metric(testNamespace, testRevision),
+			defaultDeployment, defaultReady},
+	}, {
+		Name: "session affinity does not keep activator in path",
+		Key:  key,
+		Objects: []runtime.Object{
+			kpa(testNamespace, testRevision, WithPASKSReady, WithTraffic,
+				markScaleTargetInitialized, WithPAMetricsService(privateSvc),
+				WithScales(defaultScale, 1), WithPAStatusService(testRevision), WithObservedGeneration(1),
+				withSessionAffinity),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithSKSReady),
 			metric(testNamespace, testRevision),
 			defaultDeployment, defaultReady},
@@ -313,7 +317,7 @@ func TestReconcile(t *testing.T) {
 		Key:  key,
 		Objects: []runtime.Object{
 			kpa(testNamespace, testRevision, WithPAMetricsService(privateSvc), WithPAStatusService(testRevision),
-				withScales(0, defaultScale)),
+				WithScales(defaultScale, 0)),
 			defaultSKS,
 			metric(testNamespace, testRevision),
 			defaultDeployment, defaultReady},
@@ -329,12 +333,12 @@ func TestReconcile(t *testing.T) {
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: kpa(testNamespace, testRevision, WithTraffic,
 				markScaleTargetInitialized, WithPASKSReady,
-				WithPAMetricsService(privateSvc), withScales(1, defaultScale),
+				WithPAMetricsService(privateSvc), WithScales(defaultScale, 1),
 				WithPAStatusService(testRevision), WithObservedGeneration(1)),
 		}, {
 			Object: kpa(testNamespace, testRevision, WithTraffic,
 				markScaleTargetInitialized, WithPASKSReady,
-				WithPAMetricsService(privateSvc), withScales(1, defaultScale),
+				WithPAMetricsService(privateSvc), WithScales(defaultScale, 1),
 				WithPAStatusService(testRevision), WithObservedGeneration(1)),
 		}},
 	}, {
@@ -342,7 +346,7 @@ func TestReconcile(t *testing.T) {
 		Key:  key,
 		Objects: []runtime.Object{
 			kpa(testNamespace, testRevision, WithTraffic, WithPAMetricsService(privateSvc),
-				withScales(1, defaultScale), WithPAStatusService(testRevision), WithObservedGeneration(1)),
+				WithScales(defaultScale, 1), WithPAStatusService(testRevision), WithObservedGeneration(1)),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithSKSReady),
 			defaultDeployment, defaultReady},
 		WithReactors: []clientgotesting.ReactionFunc{
@@ -361,7 +365,7 @@ func TestReconcile(t *testing.T) {
 		Key:  key,
 		Objects: []runtime.Object{
 			kpa(testNamespace, testRevision, WithTraffic, WithPAMetricsService(privateSvc),
-				withScales(1, defaultScale), WithPAStatusService(testRevision), WithObservedGeneration(1)),
+				WithScales(defaultScale, 1), WithPAStatusService(testRevision), WithObservedGeneration(1)),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithSKSReady),
 			defaultDeployment,
 			metricWithDiffSvc(testNamespace, testRevision), defaultReady},
@@ -381,11 +385,11 @@ func TestReconcile(t *testing.T) {
 		Key:  key,
 		Objects: []runtime.Object{
 			kpa(testNamespace, testRevision, WithTraffic, markScaleTargetInitialized,
-				withScales(1, defaultScale), WithPAStatusService(testRevision)),
+				WithScales(defaultScale, 1), WithPAStatusService(testRevision)),
 			defaultSKS, defaultDeployment, defaultReady},
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: kpa(testNamespace, testRevision, WithTraffic, markScaleTargetInitialized,
-				withScales(1, defaultScale), WithPASKSReady, WithPAStatusService(testRevision),
+				WithScales(defaultScale, 1), WithPASKSReady, WithPAStatusService(testRevision),
 				WithPAMetricsService(privateSvc), WithObservedGeneration(1)),
 		}},
 		WantCreates: []runtime.Object{
@@ -396,11 +400,11 @@ func TestReconcile(t *testing.T) {
 		Key:  key,
 		Objects: []runtime.Object{
 			kpa(testNamespace, testRevision, WithPASKSReady, WithTraffic, markScaleTargetInitialized,
-				WithPAMetricsService(privateSvc), withScales(1, defaultScale), WithPAStatusService(testRevision),
+				WithPAMetricsService(privateSvc), WithScales(defaultScale, 1), WithPAStatusService(testRevision),
 				WithObservedGeneration(1)),
 			defaultSKS,
 			metric(testNamespace, testRevision),
-			deploy(testNamespace, testRevision), defaultReady},
+			Deployment(testNamespace, testRevision), defaultReady},
 		WantPatches: []clientgotesting.PatchActionImpl{{
 			ActionImpl: clientgotesting.ActionImpl{
 				Namespace: testNamespace,
@@ -417,10 +421,10 @@ func TestReconcile(t *testing.T) {
 		WantErr: true,
 		Objects: []runtime.Object{
 			kpa(testNamespace, testRevision, WithTraffic, WithPAMetricsService(privateSvc),
-				withScales(1, defaultScale), WithPAStatusService(testRevision), WithObservedGeneration(1)),
+				WithScales(defaultScale, 1), WithPAStatusService(testRevision), WithObservedGeneration(1)),
 			defaultSKS,
 			metric(testNamespace, testRevision),
-			deploy(testNamespace, testRevision), defaultReady},
+			Deployment(testNamespace, testRevision), defaultReady},
 		WantPatches: []clientgotesting.PatchActionImpl{{
 			ActionImpl: clientgotesting.ActionImpl{
 				Namespace: testNamespace,
@@ -437,7 +441,7 @@ func TestReconcile(t *testing.T) {
 		Key:  key,
 		Objects: []runtime.Object{
 			kpa(testNamespace, testRevision, WithScaleTargetInitialized, WithNoTraffic(noTrafficReason, "The target is not receiving traffic."),
-				withScales(0, defaultScale), WithPAStatusService(testRevision), WithPAMetricsService(privateSvc)),
+				WithScales(defaultScale, 0), WithPAStatusService(testRevision), WithPAMetricsService(privateSvc)),
 			// SKS is ready here, since its endpoints are populated with Activator endpoints.
 			sks(testNamespace, testRevision, WithProxyMode, WithDeployRef(deployName), WithSKSReady),
 			metric(testNamespace, testRevision),
@@ -448,7 +452,7 @@ func TestReconcile(t *testing.T) {
 				WithDeployRef(deployName)),
 		}},
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
-			Object: kpa(testNamespace, testRevision, WithScaleTargetInitialized, WithBufferedTraffic, withScales(0, defaultScale),
+			Object: kpa(testNamespace, testRevision, WithScaleTargetInitialized, WithBufferedTraffic, WithScales(defaultScale, 0),
 				WithPASKSReady, WithPAMetricsService(privateSvc),
 				WithPAStatusService(testRevision), WithObservedGeneration(1)),
 		}},
@@ -457,14 +461,14 @@ func TestReconcile(t *testing.T) {
 		Key:  key,
 		Objects: append([]runtime.Object{
 			kpa(testNamespace, testRevision, WithTraffic, WithPAMetricsService(privateSvc),
-				withScales(0, defaultScale), WithPAStatusService(testRevision)),
+				WithScales(defaultScale, 0), WithPAStatusService(testRevision)),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithPubService,
 				WithPrivateService),
 			metric(testNamespace, testRevision),
 			defaultDeployment},
 			preciseReady...),
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
-			Object: kpa(testNamespace, testRevision, withScales(defaultScale, defaultScale),
+			Object: kpa(testNamespace, testRevision, WithScales(defaultScale, defaultScale),
 				WithPASKSNotReady(""), WithTraffic, markScaleTargetInitialized,
 				WithPAMetricsService(privateSvc), WithPAStatusService(testRevision), WithObservedGeneration(1)),
 		}},
@@ -473,20 +477,20 @@ func TestReconcile(t *testing.T) {
 		Key:  key,
 		Objects: []runtime.Object{
 			kpa(testNamespace, testRevision, WithPASKSNotReady(""),
-				WithBufferedTraffic, WithPAMetricsService(privateSvc), withScales(0, unknownScale),
+				WithBufferedTraffic, WithPAMetricsService(privateSvc), WithScales(unknownScale, 0),
 				WithObservedGeneration(1)),
 			defaultSKS, defaultDeployment, metric(testNamespace, testRevision),
 		},
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: kpa(testNamespace, testRevision, WithPASKSReady, WithPAStatusService(testRevision),
-				WithBufferedTraffic, WithPAMetricsService(privateSvc), withScales(0, defaultScale),
+				WithBufferedTraffic, WithPAMetricsService(privateSvc), WithScales(defaultScale, 0),
 				WithObservedGeneration(1)),
 		}},
 	}, {
 		Name: "kpa does not become ready without minScale endpoints when reachable",
 		Key:  key,
 		Objects: []runtime.Object{
-			kpa(testNamespace, testRevision, withMinScale(2), withScales(1, defaultScale),
+			kpa(testNamespace, testRevision, withMinScale(2), WithScales(defaultScale, 1),
 				WithReachabilityReachable, WithPAMetricsService(privateSvc)),
 			defaultSKS,
 			metric(testNamespace, testRevision),
@@ -494,14 +498,14 @@ func TestReconcile(t *testing.T) {
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: kpa(testNamespace, testRevision, WithPASKSReady,
 				WithBufferedTraffic, withMinScale(2), WithPAMetricsService(privateSvc),
-				withScales(1, defaultScale), WithPAStatusService(testRevision), WithReachabilityReachable,
+				WithScales(defaultScale, 1), WithPAStatusService(testRevision), WithReachabilityReachable,
 				WithObservedGeneration(1)),
 		}},
 	}, {
 		Name: "kpa does not become ready without minScale endpoints when reachability is unknown",
 		Key:  key,
 		Objects: []runtime.Object{
-			kpa(testNamespace, testRevision, withMinScale(2), withScales(1, defaultScale),
+			kpa(testNamespace, testRevision, withMinScale(2), WithScales(defaultScale, 1),
 				WithPAMetricsService(privateSvc), WithReachabilityUnknown),
 			defaultSKS,
 			metric(testNamespace, testRevision),
@@ -510,14 +514,14 @@ func TestReconcile(t *testing.T) {
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: kpa(testNamespace, testRevision, WithPASKSReady,
 				WithBufferedTraffic, withMinScale(2), WithPAMetricsService(privateSvc),
-				withScales(1, defaultScale), WithPAStatusService(testRevision), WithReachabilityUnknown,
+				WithScales(defaultScale, 1), WithPAStatusService(testRevision), WithReachabilityUnknown,
 				WithObservedGeneration(1)),
 		}},
 	}, {
 		Name: "kpa becomes ready without minScale endpoints when unreachable",
 		Key:  key,
 		Objects: []runtime.Object{
-			kpa(testNamespace, testRevision, withMinScale(2), withScales(1, defaultScale),
+			kpa(testNamespace, testRevision, withMinScale(2), WithScales(defaultScale, 1),
 				WithPAMetricsService(privateSvc), WithReachabilityUnreachable),
 			defaultSKS,
 			metric(testNamespace, testRevision),
@@ -525,7 +529,7 @@ func TestReconcile(t *testing.T) {
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: kpa(testNamespace, testRevision, WithPASKSReady,
 				WithTraffic, markScaleTargetInitialized, withMinScale(2), WithPAMetricsService(privateSvc),
-				withScales(1, defaultScale), WithPAStatusService(testRevision), WithReachabilityUnreachable,
+				WithScales(defaultScale, 1), WithPAStatusService(testRevision), WithReachabilityUnreachable,
 				WithObservedGeneration(1)),
 		}},
 	}, {
@@ -533,7 +537,7 @@ func TestReconcile(t *testing.T) {
 		Key:  key,
 		Objects: append([]runtime.Object{
 			kpa(testNamespace, testRevision, WithBufferedTraffic, withMinScale(2), WithPAStatusService(testRevision),
-				WithPAMetricsService(privateSvc), withScales(1, defaultScale), WithReachabilityReachable),
+				WithPAMetricsService(privateSvc), WithScales(defaultScale, 1), WithReachabilityReachable),
 			defaultSKS,
 			metric(testNamespace, testRevision),
 			defaultDeployment,
@@ -541,7 +545,7 @@ func TestReconcile(t *testing.T) {
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: kpa(testNamespace, testRevision, WithPASKSReady,
 				WithTraffic, markScaleTargetInitialized, withMinScale(2), WithPAMetricsService(privateSvc),
-				withScales(2, defaultScale), WithPAStatusService(testRevision), WithReachabilityReachable,
+				WithScales(defaultScale, 2), WithPAStatusService(testRevision), WithReachabilityReachable,
 				WithObservedGeneration(1)),
 		}},
 	}, {
@@ -549,7 +553,7 @@ func TestReconcile(t *testing.T) {
 		Key:  key,
 		Objects: append([]runtime.Object{
 			kpa(testNamespace, testRevision, WithBufferedTraffic, withMinScale(2), WithPAStatusService(testRevision),
-				WithPAMetricsService(privateSvc), withScales(1, defaultScale), WithReachabilityUnknown),
+				WithPAMetricsService(privateSvc), WithScales(defaultScale, 1), WithReachabilityUnknown),
 			defaultSKS,
 			metric(testNamespace, testRevision),
 			defaultDeployment,
@@ -557,20 +561,20 @@ func TestReconcile(t *testing.T) {
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: kpa(testNamespace, testRevision, WithPASKSReady,
 				WithTraffic, markScaleTargetInitialized, withMinScale(2), WithPAMetricsService(privateSvc),
-				withScales(2, defaultScale), WithPAStatusService(testRevision), WithReachabilityUnknown,
+				WithScales(defaultScale, 2), WithPAStatusService(testRevision), WithReachabilityUnknown,
 				WithObservedGeneration(1)),
 		}},
 	}, {
 		Name: "sks does not exist",
 		Key:  key,
 		Objects: []runtime.Object{
-			kpa(testNamespace, testRevision, WithTraffic, WithPAMetricsService(privateSvc), withScales(1, defaultScale)),
+			kpa(testNamespace, testRevision, WithTraffic, WithPAMetricsService(privateSvc), WithScales(defaultScale, 1)),
 			defaultDeployment,
 		},
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
 			// SKS does not exist, so we're just creating and have no status.
 			Object: kpa(testNamespace, testRevision, WithPASKSNotReady("No Private Service Name"),
-				WithBufferedTraffic, WithPAMetricsService(privateSvc), withScales(0, unknownScale),
+				WithBufferedTraffic, WithPAMetricsService(privateSvc), WithScales(unknownScale, 0),
 				WithObservedGeneration(1)),
 		}},
 		WantCreates: []runtime.Object{
@@ -581,7 +585,7 @@ func TestReconcile(t *testing.T) {
 		Key:  key,
 		Objects: []runtime.Object{
 			kpa(testNamespace, testRevision, WithPASKSReady,
-				withScales(0, defaultScale), WithPAMetricsService(privateSvc), WithTraffic),
+				WithScales(defaultScale, 0), WithPAMetricsService(privateSvc), WithTraffic),
 			sks(testNamespace, testRevision, WithDeployRef("bar"), WithPubService, WithPrivateService),
 			metric(testNamespace, testRevision),
 			defaultDeployment,
@@ -589,7 +593,7 @@ func TestReconcile(t *testing.T) {
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
 			// SKS just got updated and we don't have up to date status.
 			Object: kpa(testNamespace, testRevision, WithPASKSNotReady(""),
-				WithBufferedTraffic, withScales(0, defaultScale), WithPAStatusService(testRevision),
+				WithBufferedTraffic, WithScales(defaultScale, 0), WithPAStatusService(testRevision),
 				WithPAMetricsService(privateSvc), WithObservedGeneration(1)),
 		}},
 		WantUpdates: []clientgotesting.UpdateActionImpl{{
@@ -600,7 +604,7 @@ func TestReconcile(t *testing.T) {
 		Name: "sks cannot be created",
 		Key:  key,
 		Objects: []runtime.Object{
-			kpa(testNamespace, testRevision, WithTraffic, WithPAMetricsService(privateSvc), withScales(1, defaultScale), WithObservedGeneration(1)),
+			kpa(testNamespace, testRevision, WithTraffic, WithPAMetricsService(privateSvc), WithScales(defaultScale, 1), WithObservedGeneration(1)),
 			metric(testNamespace, testRevision),
 			defaultDeployment,
 		},
@@ -619,7 +623,7 @@ func TestReconcile(t *testing.T) {
 		Name: "sks cannot be updated",
 		Key:  key,
 		Objects: []runtime.Object{
-			kpa(testNamespace, testRevision, withScales(1, defaultScale), WithPAMetricsService(privateSvc), WithTraffic, WithObservedGeneration(1)),
+			kpa(testNamespace, testRevision, WithScales(defaultScale, 1), WithPAMetricsService(privateSvc), WithTraffic, WithObservedGeneration(1)),
 			sks(testNamespace, testRevision, WithDeployRef("bar")),
 			metric(testNamespace, testRevision),
 			defaultDeployment,
@@ -638,7 +642,7 @@ func TestReconcile(t *testing.T) {
 		Name: "sks is disowned",
 		Key:  key,
 		Objects: []runtime.Object{
-			kpa(testNamespace, testRevision, withScales(1, defaultScale), WithPAMetricsService(privateSvc), WithTraffic),
+			kpa(testNamespace, testRevision, WithScales(defaultScale, 1), WithPAMetricsService(privateSvc), WithTraffic),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithSKSReady,
 				WithSKSOwnersRemoved),
 			metric(testNamespace, testRevision),
@@ -646,7 +650,7 @@ func TestReconcile(t *testing.T) {
 		},
 		WantErr: true,
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
-			Object: kpa(testNamespace, testRevision, withScales(1, defaultScale),
+			Object: kpa(testNamespace, testRevision, WithScales(defaultScale, 1),
 				WithPAMetricsService(privateSvc), markResourceNotOwned("ServerlessService", testRevision), WithObservedGeneration(1)),
 		}},
 		WantEvents: []string{
@@ -656,14 +660,14 @@ func TestReconcile(t *testing.T) {
 		Name: "metric is disowned",
 		Key:  key,
 		Objects: []runtime.Object{
-			kpa(testNamespace, testRevision, withScales(1, defaultScale), WithPAMetricsService(privateSvc), WithTraffic),
+			kpa(testNamespace, testRevision, WithScales(defaultScale, 1), WithPAMetricsService(privateSvc), WithTraffic),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithSKSReady),
 			metric(testNamespace, testRevision, WithMetricOwnersRemoved),
 			defaultDeployment,
 		},
 		WantErr: true,
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
-			Object: kpa(testNamespace, testRevision, withScales(1, defaultScale),
+			Object: kpa(testNamespace, testRevision, WithScales(defaultScale, 1),
 				WithPAMetricsService(privateSvc), markResourceNotOwned("Metric", testRevision), WithObservedGeneration(1)),
 		}},
 		WantEvents: []string{
@@ -675,15 +679,13 @@ func TestReconcile(t *testing.T) {
 		Ctx: context.WithValue(context.Background(), deciderKey{},
 			decider(testNamespace, testRevision, 0 /* desiredScale */, 0 /* ebc */, scaling.MinActivators)),
 		Objects: []runtime.Object{
-			kpa(testNamespace, testRevision, WithScaleTargetInitialized, withScales(0, 0),
+			kpa(testNamespace, testRevision, WithScaleTargetInitialized, WithScales(0, 0),
 				WithNoTraffic(noTrafficReason, "The target is not receiving traffic."),
 				WithPASKSReady, markOld, WithPAStatusService(testRevision),
 				WithPAMetricsService(privateSvc), WithObservedGeneration(1)),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithProxyMode, WithSKSReady),
 			metric(testNamespace, testRevision),
-			deploy(testNamespace, testRevision, func(d *appsv1.Deployment) {
-				d.Spec.Replicas = ptr.Int32(0)
-			}),
+			Deployment(testNamespace, testRevision, WithDeploymentReplicas(0)),
 		},
 	}, {
 		Name: "steady not serving (scale to zero)",
@@ -691,13 +693,13 @@ func TestReconcile(t *testing.T) {
 		Ctx: context.WithValue(context.Background(), deciderKey{},
 			decider(testNamespace, testRevision, 0 /* desiredScale */, 0 /* ebc */, scaling.MinActivators)),
 		Objects: []runtime.Object{
-			kpa(testNamespace, testRevision, WithScaleTargetInitialized, withScales(0, 0),
+			kpa(testNamespace, testRevision, WithScaleTargetInitialized, WithScales(0, 0),
 				WithNoTraffic(noTrafficReason, "The target is not receiving traffic."),
 				WithPASKSReady, markOld, WithPAStatusService(testRevision),
 				WithPAMetricsService(privateSvc), WithObservedGeneration(1)),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithProxyMode, WithSKSReady),
 			metric(testNamespace, testRevision),
-			deploy(testNamespace, testRevision),
+			Deployment(testNamespace, testRevision),
 		},
 		WantPatches: []clientgotesting.PatchActionImpl{{
 			ActionImpl: clientgotesting.ActionImpl{
@@ -713,12 +715,12 @@ func TestReconcile(t *testing.T) {
 			decider(testNamespace, testRevision, 0 /* desiredScale */, 0 /* ebc */, scaling.MinActivators)),
 		Objects: []runtime.Object{
 			kpa(testNamespace, testRevision, WithPASKSReady, WithTraffic, markOld,
-				withScales(0, 0), WithPAStatusService(testRevision), WithPAMetricsService(privateSvc)),
+				WithScales(0, 0), WithPAStatusService(testRevision), WithPAMetricsService(privateSvc)),
 			defaultSKS,
 			metric(testNamespace, testRevision),
-			deploy(testNamespace, testRevision), defaultReady},
+			Deployment(testNamespace, testRevision), defaultReady},
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
-			Object: kpa(testNamespace, testRevision, markScaleTargetInitialized, withScales(1, 0),
+			Object: kpa(testNamespace, testRevision, markScaleTargetInitialized, WithScales(0, 1),
 				WithPASKSReady, WithPAMetricsService(privateSvc),
 				WithNoTraffic(noTrafficReason, "The target is not receiving traffic."),
 				WithPAStatusService(testRevision), WithPAMetricsService(privateSvc),
@@ -735,11 +737,11 @@ func TestReconcile(t *testing.T) {
 			decider(testNamespace, testRevision, 0 /* desiredScale */, 0 /* ebc */, scaling.MinActivators)),
 		Objects: []runtime.Object{
 			kpa(testNamespace, testRevision, WithPASKSReady, WithTraffic,
-				markScaleTargetInitialized, withScales(1, 1),
+				markScaleTargetInitialized, WithScales(1, 1),
 				WithPAStatusService(testRevision), WithPAMetricsService(privateSvc), WithObservedGeneration(1)),
 			defaultSKS,
 			metric(testNamespace, testRevision),
-			deploy(testNamespace, testRevision), defaultReady},
+			Deployment(testNamespace, testRevision), defaultReady},
 	}, {
 		Name: "activation failure",
 		Key:  key,
@@ -747,14 +749,14 @@ func TestReconcile(t *testing.T) {
 			decider(testNamespace, testRevision, 0 /* desiredScale */, 0 /* ebc */, scaling.MinActivators)),
 		Objects: []runtime.Object{
 			kpa(testNamespace, testRevision, WithPASKSReady, WithBufferedTraffic, markOld,
-				WithPAStatusService(testRevision), withScales(0, 0),
+				WithPAStatusService(testRevision), WithScales(0, 0),
 				WithPAMetricsService(privateSvc)),
 			defaultSKS,
 			metric(testNamespace, testRevision),
-			deploy(testNamespace, testRevision), defaultReady},
+			Deployment(testNamespace, testRevision), defaultReady},
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: kpa(testNamespace, testRevision, markScaleTargetInitialized, WithPASKSReady, WithPAMetricsService(privateSvc),
-				WithNoTraffic("TimedOut", "The target could not be activated."), withScales(1, 0),
+				WithNoTraffic("TimedOut", "The target could not be activated."), WithScales(0, 1),
 				WithPAStatusService(testRevision), WithPAMetricsService(privateSvc),
 				WithObservedGeneration(1)),
 		}},
@@ -909,7 +911,7 @@ func TestReconcile(t *testing.T) {
 		Objects: []runtime.Object{
 			kpa(testNamespace, testRevision, WithScaleTargetInitialized, WithPASKSReady,
 				WithNoTraffic(noTrafficReason, "The target is not receiving traffic."), WithPAMetricsService(privateSvc),
-				withScales(0, -1), WithPAStatusService(testRevision), WithObservedGeneration(1)),
+				WithScales(-1, 0), WithPAStatusService(testRevision), WithObservedGeneration(1)),
 			sks(testNamespace, testRevision, WithDeployRef(deployName),
 				WithProxyMode, WithSKSReady),
 			metric(testNamespace, testRevision),
@@ -924,7 +926,7 @@ func TestReconcile(t *testing.T) {
 		Objects: []runtime.Object{
 			kpa(testNamespace, testRevision, WithPASKSReady, WithTraffic,
 				markScaleTargetInitialized, WithPAMetricsService(privateSvc),
-				withScales(1, defaultScale), WithPAStatusService(testRevision), WithObservedGeneration(1)),
+				WithScales(defaultScale, 1), WithPAStatusService(testRevision), WithObservedGeneration(1)),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithProxyMode, WithSKSReady),
 			metric(testNamespace, testRevision),
 			defaultDeployment, defaultReady},
@@ -937,7 +939,7 @@ func TestReconcile(t *testing.T) {
 		Objects: []runtime.Object{
 			kpa(testNamespace, testRevision, WithPASKSReady, WithTraffic,
 				markScaleTargetInitialized, WithPAMetricsService(privateSvc),
-				withScales(1, defaultScale), WithPAStatusService(testRevision), WithObservedGeneration(1)),
+				WithScales(defaultScale, 1), WithPAStatusService(testRevision), WithObservedGeneration(1)),
 			sks(testNamespace, testRevision, WithDeployRef(deployName),
 				WithProxyMode, WithSKSReady, WithNumActivators(4)),
 			metric(testNamespace, testRevision),
@@ -946,6 +948,19 @@ func TestReconcile(t *testing.T) {
 			Object: sks(testNamespace, testRevision, WithDeployRef(deployName),
 				WithProxyMode, WithSKSReady, WithNumActivators(1982)),
 		}},
+	}, {
+		Name: "traffic increased, activator bypass keeps serve mode",
+		Key:  key,
+		Ctx: context.WithValue(context.Background(), deciderKey{},
+			decider(testNamespace, testRevision, defaultScale, /* desiredScale */
+				-18 /* ebc */, scaling.MinActivators)),
+		Objects: []runtime.Object{
+			kpa(testNamespace, testRevision, WithPASKSReady, WithTraffic, markScaleTargetInitialized,
+				WithPAMetricsService(privateSvc), WithScales(defaultScale, 1),
+				WithPAStatusService(testRevision), WithObservedGeneration(1), withActivatorBypass),
+			sks(testNamespace, testRevision, WithDeployRef(deployName), WithSKSReady),
+			metric(testNamespace, testRevision),
+			defaultDeployment, defaultReady},
 	}, {
 		Name: "traffic increased, no longer enough burst capacity",
 		Key:  key,
@@ -954,7 +969,7 @@ func TestReconcile(t *testing.T) {
 				-18 /* ebc */, scaling.MinActivators+1)),
 		Objects: []runtime.Object{
 			kpa(testNamespace, testRevision, WithPASKSReady, WithTraffic, markScaleTargetInitialized,
-				WithPAMetricsService(privateSvc), withScales(1, defaultScale),
+				WithPAMetricsService(privateSvc), WithScales(defaultScale, 1),
 				WithPAStatusService(testRevision), WithObservedGeneration(1)),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithSKSReady,
 				WithNumActivators(2)),
@@ -972,7 +987,7 @@ func TestReconcile(t *testing.T) {
 				1 /* ebc */, scaling.MinActivators)),
 		Objects: []runtime.Object{
 			kpa(testNamespace, testRevision, WithPASKSReady, WithTraffic, markScaleTargetInitialized,
-				WithPAMetricsService(privateSvc), withScales(1, defaultScale),
+				WithPAMetricsService(privateSvc), WithScales(defaultScale, 1),
 				WithPAStatusService(testRevision), WithObservedGeneration(1)),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithSKSReady, WithProxyMode,
 				WithNumActivators(3)),
@@ -991,14 +1006,14 @@ func TestReconcile(t *testing.T) {
 				-42 /* ebc */, scaling.MinActivators)),
 		Objects: append([]runtime.Object{
 			kpa(testNamespace, testRevision, WithPASKSReady, WithBufferedTraffic,
-				withScales(defaultScale, defaultScale), WithReachabilityReachable,
+				WithScales(defaultScale, defaultScale), WithReachabilityReachable,
 				withMinScale(defaultScale), withInitialScale(20), WithPAStatusService(testRevision), WithPAMetricsService(privateSvc)),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithProxyMode, WithSKSReady),
 			defaultMetric, defaultDeployment,
 		}, makeReadyPods(defaultScale, testNamespace, testRevision)...),
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: kpa(testNamespace, testRevision, WithPASKSReady, WithBufferedTraffic,
-				withScales(defaultScale, 20), WithReachabilityReachable,
+				WithScales(20, defaultScale), WithReachabilityReachable,
 				withMinScale(defaultScale), withInitialScale(20), WithPAStatusService(testRevision), WithPAMetricsService(privateSvc),
 				WithObservedGeneration(1),
 			),
@@ -1016,18 +1031,16 @@ func TestReconcile(t *testing.T) {
 				-42 /* ebc */, scaling.MinActivators)),
 		Objects: append([]runtime.Object{
 			kpa(testNamespace, testRevision, WithPASKSReady, WithBufferedTraffic,
-				withScales(20, defaultScale), withInitialScale(20), WithReachabilityReachable,
+				WithScales(defaultScale, 20), withInitialScale(20), WithReachabilityReachable,
 				WithPAStatusService(testRevision), WithPAMetricsService(privateSvc),
 			),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithProxyMode, WithSKSReady),
 			defaultMetric,
-			deploy(testNamespace, testRevision, func(d *appsv1.Deployment) {
-				d.Spec.Replicas = ptr.Int32(defaultScale)
-			}),
+			Deployment(testNamespace, testRevision, WithDeploymentReplicas(defaultScale)),
 		}, makeReadyPods(20, testNamespace, testRevision)...),
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: kpa(testNamespace, testRevision, WithPASKSReady, WithTraffic,
-				markScaleTargetInitialized, withScales(20, 20), withInitialScale(20), WithReachabilityReachable,
+				markScaleTargetInitialized, WithScales(20, 20), withInitialScale(20), WithReachabilityReachable,
 				WithPAStatusService(testRevision), WithPAMetricsService(privateSvc), WithObservedGeneration(1),
 			),
 		}},
@@ -1043,19 +1056,17 @@ func TestReconcile(t *testing.T) {
 			decider(testNamespace, testRevision, -1, /* desiredScale */
 				0 /* ebc */, scaling.MinActivators)),
 		Objects: []runtime.Object{
-			kpa(testNamespace, testRevision, withScales(0, -1), WithReachabilityReachable,
+			kpa(testNamespace, testRevision, WithScales(-1, 0), WithReachabilityReachable,
 				WithPAMetricsService(privateSvc), WithPASKSNotReady(noPrivateServiceName)),
 			// SKS won't be ready bc no ready endpoints, but private service name will be populated.
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithPrivateService, WithPubService),
 			metric(testNamespace, testRevision),
-			deploy(testNamespace, testRevision, func(d *appsv1.Deployment) {
-				d.Spec.Replicas = ptr.Int32(0)
-			}),
+			Deployment(testNamespace, testRevision, WithDeploymentReplicas(0)),
 		},
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: kpa(testNamespace, testRevision, markScaleTargetInitialized,
 				WithNoTraffic(noTrafficReason, "The target is not receiving traffic."),
-				withScales(0, -1), WithReachabilityReachable,
+				WithScales(-1, 0), WithReachabilityReachable,
 				WithPAMetricsService(privateSvc), WithObservedGeneration(1),
 				WithPASKSNotReady(""), WithPAStatusService(testRevision),
 			),
@@ -1067,19 +1078,17 @@ func TestReconcile(t *testing.T) {
 			decider(testNamespace, testRevision, -1, /* desiredScale */
 				0 /* ebc */, scaling.MinActivators)),
 		Objects: []runtime.Object{
-			kpa(testNamespace, testRevision, withScales(0, -1), WithReachabilityReachable,
+			kpa(testNamespace, testRevision, WithScales(-1, 0), WithReachabilityReachable,
 				WithPAMetricsService(privateSvc), WithPASKSNotReady(noPrivateServiceName)),
 			// SKS won't be ready bc no ready endpoints, but private service name will be populated.
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithPrivateService),
 			metric(testNamespace, testRevision),
-			deploy(testNamespace, testRevision, func(d *appsv1.Deployment) {
-				d.Spec.Replicas = ptr.Int32(0)
-			}),
+			Deployment(testNamespace, testRevision, WithDeploymentReplicas(0)),
 		},
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: kpa(testNamespace, testRevision,
 				WithNoTraffic(noTrafficReason, "The target is not receiving traffic."),
-				withScales(0, -1), WithReachabilityReachable,
+				WithScales(-1, 0), WithReachabilityReachable,
 				WithPAMetricsService(privateSvc), WithObservedGeneration(1),
 				WithPASKSNotReady(""),
 			),
@@ -1091,18 +1100,16 @@ func TestReconcile(t *testing.T) {
 			decider(testNamespace, testRevision, -1, /* desiredScale */
 				0 /* ebc */, scaling.MinActivators)),
 		Objects: []runtime.Object{
-			kpa(testNamespace, testRevision, markScaleTargetInitialized, withScales(0, scaleUnknown),
+			kpa(testNamespace, testRevision, markScaleTargetInitialized, WithScales(scaleUnknown, 0),
 				WithReachabilityReachable, WithPAMetricsService(privateSvc), WithPASKSNotReady(""),
 			),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithPrivateService),
 			metric(testNamespace, testRevision),
-			deploy(testNamespace, testRevision, func(d *appsv1.Deployment) {
-				d.Spec.Replicas = ptr.Int32(0)
-			}),
+			Deployment(testNamespace, testRevision, WithDeploymentReplicas(0)),
 		},
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: kpa(testNamespace, testRevision, WithPASKSNotReady(""), WithBufferedTraffic, markScaleTargetInitialized,
-				withScales(0, scaleUnknown), WithReachabilityReachable,
+				WithScales(scaleUnknown, 0), WithReachabilityReachable,
 				WithPAMetricsService(privateSvc), WithObservedGeneration(1),
 			),
 		}},
@@ -1113,19 +1120,17 @@ func TestReconcile(t *testing.T) {
 			decider(testNamespace, testRevision, 2, /* desiredScale */
 				-42 /* ebc */, scaling.MinActivators)),
 		Objects: append([]runtime.Object{
-			kpa(testNamespace, testRevision, markScaleTargetInitialized, withScales(2, 2),
+			kpa(testNamespace, testRevision, markScaleTargetInitialized, WithScales(2, 2),
 				WithReachabilityReachable, WithPAStatusService(testRevision), WithPAMetricsService(privateSvc),
 				WithPASKSReady,
 			),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithProxyMode, WithSKSReady),
 			metric(testNamespace, testRevision),
-			deploy(testNamespace, testRevision, func(d *appsv1.Deployment) {
-				d.Spec.Replicas = ptr.Int32(2)
-			}),
+			Deployment(testNamespace, testRevision, WithDeploymentReplicas(2)),
 		}, makeReadyPods(2, testNamespace, testRevision)...),
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: kpa(testNamespace, testRevision, WithTraffic, WithPASKSReady, markScaleTargetInitialized,
-				withScales(2, 2), WithReachabilityReachable, WithPAStatusService(testRevision),
+				WithScales(2, 2), WithReachabilityReachable, WithPAStatusService(testRevision),
 				WithPAMetricsService(privateSvc), WithObservedGeneration(1),
 			),
 		}},
@@ -1137,19 +1142,17 @@ func TestReconcile(t *testing.T) {
 				-42 /* ebc */, scaling.MinActivators)),
 		Objects: []runtime.Object{
 			kpa(testNamespace, testRevision, WithNoTraffic(noTrafficReason, "The target is not receiving traffic."),
-				withScales(0, -1), WithReachabilityReachable, WithPAStatusService(testRevision), WithPAMetricsService(privateSvc),
+				WithScales(-1, 0), WithReachabilityReachable, WithPAStatusService(testRevision), WithPAMetricsService(privateSvc),
 				WithPASKSReady,
 			),
 			sks(testNamespace, testRevision, WithDeployRef(deployName), WithProxyMode, WithSKSReady),
 			metric(testNamespace, testRevision),
-			deploy(testNamespace, testRevision, func(d *appsv1.Deployment) {
-				d.Spec.Replicas = ptr.Int32(2)
-			}),
+			Deployment(testNamespace, testRevision, WithDeploymentReplicas(2)),
 		},
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: kpa(testNamespace, testRevision, WithNoTraffic(noTrafficReason, "The target is not receiving traffic."),
 				WithPASKSReady, markScaleTargetInitialized,
-				withScales(0, -1), WithReachabilityReachable, WithPAStatusService(testRevision),
+				WithScales(-1, 0), WithReachabilityReachable, WithPAStatusService(testRevision),
 				WithPAMetricsService(privateSvc), WithObservedGeneration(1),
 			),
 		}},
@@ -1202,31 +1205,6 @@ func TestReconcile(t *testing.T) {
 	}))
 }
 
-type deploymentOption func(*appsv1.Deployment)
-
-func deploy(namespace, name string, opts ...deploymentOption) *appsv1.Deployment {
-	s := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      name + "-deployment",
-			Namespace: namespace,
-		},
-		Spec: appsv1.DeploymentSpec{
-			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"a": "b",
-				},
-			},
-		},
-		Status: appsv1.DeploymentStatus{
-			Replicas: 42,
-		},
-	}
-	for _, opt := range opts {
-		opt(s)
-	}
-	return s
-}
-
 func TestGlobalResyncOnUpdateAutoscalerConfigMap(t *testing.T) {
 	ctx, cancel, informers := SetupFakeContextWithCancel(t)
 	watcher := &configmap.ManualWatcher{Namespace: system.Namespace()}
@@ -60,6 +60,10 @@ func MakeDecider(ctx context.Context, pa *asv1a1.PodAutoscaler, config *autoscal
 	if x, ok := pa.TargetBC(); ok {
 		tbc = x
 	}
+	if pa.ActivatorBypass() {
+		// The activator does not back the scaled up revision, so there is no burst capacity to keep.
+		tbc = 0
+	}
 
 	scaleDownDelay := config.ScaleDownDelay
 	if sdd, ok := pa.ScaleDownDelay(); ok {
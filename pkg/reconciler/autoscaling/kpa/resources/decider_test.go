@@ -118,6 +118,16 @@ func TestMakeDecider(t *testing.T) {
 			c.ContainerConcurrencyTargetFraction = 0.8
 			return &c
 		},
+	}, {
+		name: "with activator bypass",
+		pa:   pa(WithPAContainerConcurrency(120), withActivatorBypassAnnotation),
+		want: decider(withTarget(96), withTotal(120), withPanicThreshold(2.0),
+			withDeciderActivatorBypassAnnotation, withTargetBurstCapacity(0)),
+		cfgOpt: func(c autoscalerconfig.Config) *autoscalerconfig.Config {
+			c.TargetBurstCapacity = 63
+			c.ContainerConcurrencyTargetFraction = 0.8
+			return &c
+		},
 	}, {
 		name: "with container concurrency greater than target annotation (ok)",
 		pa:   pa(WithPAContainerConcurrency(10), WithTargetAnnotation("1")),
@@ -275,6 +285,14 @@ func withDeciderTBCAnnotation(tbc string) deciderOption {
 	}
 }
 
+func withActivatorBypassAnnotation(pa *v1alpha1.PodAutoscaler) {
+	pa.Annotations[autoscaling.ActivatorBypassAnnotationKey] = "true"
+}
+
+func withDeciderActivatorBypassAnnotation(d *scaling.Decider) {
+	d.Annotations[autoscaling.ActivatorBypassAnnotationKey] = "true"
+}
+
 type deciderOption func(*scaling.Decider)
 
 func decider(options ...deciderOption) *scaling.Decider {
@@ -177,8 +177,11 @@ func (ks *scaler) handleScaleToZero(ctx context.Context, pa *pav1alpha1.PodAutos
 	if !cfgAS.EnableScaleToZero {
 		return 1, true
 	}
-	cfgD := cfgs.Deployment
-	activationTimeout := cfgD.ProgressDeadline + activationTimeoutBuffer
+	progressDeadline := cfgs.Deployment.ProgressDeadline
+	if pd, ok := pa.ProgressDeadline(); ok {
+		progressDeadline = pd
+	}
+	activationTimeout := progressDeadline + activationTimeoutBuffer
 
 	now := time.Now()
 	logger := logging.FromContext(ctx)
@@ -334,6 +337,16 @@ func (ks *scaler) scale(ctx context.Context, pa *pav1alpha1.PodAutoscaler, sks *
 		logger.Debugf("Adjusting desiredScale to meet the min and max bounds before applying: %d -> %d", desiredScale, newScale)
 		desiredScale = newScale
 	}
+	// Standby pods are provisioned only while the revision is serving,
+	// they don't keep a revision from scaling to zero.
+	if standby, ok := pa.StandbyScale(); ok && standby > 0 && desiredScale > 0 {
+		newScale := desiredScale + standby
+		if max != 0 && newScale > max {
+			newScale = max
+		}
+		logger.Debugf("Adding %d standby pods: %d -> %d", standby, desiredScale, newScale)
+		desiredScale = newScale
+	}
 
 	desiredScale, shouldApplyScale := ks.handleScaleToZero(ctx, pa, sks, desiredScale)
 	if !shouldApplyScale {
@@ -321,6 +321,17 @@ func TestScaler(t *testing.T) {
 		paMutation: func(k *pav1alpha1.PodAutoscaler) {
 			paMarkActivating(k, time.Now().Add(-(activationTimeout + time.Second)))
 		},
+	}, {
+		label:         "waits to scale to zero while activating within the revision's progress deadline",
+		startReplicas: 1,
+		scaleTo:       0,
+		wantReplicas:  -1,
+		wantScaling:   false,
+		paMutation: func(k *pav1alpha1.PodAutoscaler) {
+			k.Annotations[serving.ProgressDeadlineAnnotationKey] = (2 * progressDeadline).String()
+			paMarkActivating(k, time.Now().Add(-(activationTimeout + time.Second)))
+		},
+		wantCBCount: 1,
 	}, {
 		label:         "scale down to minScale before grace period",
 		startReplicas: 10,
@@ -371,6 +382,35 @@ func TestScaler(t *testing.T) {
 		scaleTo:       10,
 		wantReplicas:  10,
 		wantScaling:   true,
+	}, {
+		label:         "scales up with standby pods",
+		startReplicas: 1,
+		scaleTo:       5,
+		wantReplicas:  7,
+		wantScaling:   true,
+		paMutation: func(k *pav1alpha1.PodAutoscaler) {
+			k.Annotations[autoscaling.StandbyScaleAnnotationKey] = "2"
+		},
+	}, {
+		label:         "standby pods capped by maxScale",
+		startReplicas: 1,
+		scaleTo:       5,
+		maxScale:      6,
+		wantReplicas:  6,
+		wantScaling:   true,
+		paMutation: func(k *pav1alpha1.PodAutoscaler) {
+			k.Annotations[autoscaling.StandbyScaleAnnotationKey] = "2"
+		},
+	}, {
+		label:         "standby pods don't prevent scale to zero",
+		startReplicas: 1,
+		scaleTo:       0,
+		wantReplicas:  0,
+		wantScaling:   true,
+		paMutation: func(k *pav1alpha1.PodAutoscaler) {
+			paMarkActivating(k, time.Now().Add(-(activationTimeout + time.Second)))
+			k.Annotations[autoscaling.StandbyScaleAnnotationKey] = "2"
+		},
 	}, {
 		label:         "scales up to maxScale",
 		startReplicas: 1,
@@ -0,0 +1,81 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscaling
+
+import (
+	"time"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+
+	pkgmetrics "knative.dev/pkg/metrics"
+	pav1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/apis/serving"
+	"knative.dev/serving/pkg/metrics"
+)
+
+var (
+	sloLatencyP99TargetM = stats.Float64(
+		"slo_latency_p99_target",
+		"Target 99th percentile request latency declared for the revision",
+		stats.UnitMilliseconds)
+	sloMaxErrorRateTargetM = stats.Float64(
+		"slo_max_error_rate_target",
+		"Maximum percentage of failed requests declared for the revision",
+		stats.UnitDimensionless)
+)
+
+func init() {
+	register()
+}
+
+func register() {
+	if err := pkgmetrics.RegisterResourceView(
+		&view.View{
+			Description: "Target 99th percentile request latency declared for the revision",
+			Measure:     sloLatencyP99TargetM,
+			Aggregation: view.LastValue(),
+		},
+		&view.View{
+			Description: "Maximum percentage of failed requests declared for the revision",
+			Measure:     sloMaxErrorRateTargetM,
+			Aggregation: view.LastValue(),
+		},
+	); err != nil {
+		panic(err)
+	}
+}
+
+// ReportSLOTargets exports the SLO targets declared on the PA, if any,
+// so that they can be compared to the observed request metrics.
+func ReportSLOTargets(pa *pav1alpha1.PodAutoscaler) {
+	var ms []stats.Measurement
+	if l, ok := pa.SLOLatencyP99(); ok {
+		ms = append(ms, sloLatencyP99TargetM.M(float64(l)/float64(time.Millisecond)))
+	}
+	if r, ok := pa.SLOMaxErrorRatePercentage(); ok {
+		ms = append(ms, sloMaxErrorRateTargetM.M(r))
+	}
+	if len(ms) == 0 {
+		return
+	}
+
+	serviceLabel := pa.Labels[serving.ServiceLabelKey] // This might be empty.
+	configLabel := pa.Labels[serving.ConfigurationLabelKey]
+	ctx := metrics.RevisionContext(pa.Namespace, serviceLabel, configLabel, pa.Name)
+	pkgmetrics.RecordBatch(ctx, ms...)
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package autoscaling
+
+import (
+	"testing"
+
+	"go.opencensus.io/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/metrics/metricskey"
+	"knative.dev/pkg/metrics/metricstest"
+	"knative.dev/serving/pkg/apis/autoscaling"
+	pav1alpha1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+	"knative.dev/serving/pkg/apis/serving"
+
+	_ "knative.dev/pkg/metrics/testing"
+)
+
+func TestReportSLOTargets(t *testing.T) {
+	pa := &pav1alpha1.PodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "test-namespace",
+			Name:      "test-revision",
+			Labels: map[string]string{
+				serving.ServiceLabelKey:       "test-service",
+				serving.ConfigurationLabelKey: "test-config",
+			},
+		},
+	}
+
+	// Nothing is reported without the annotations.
+	ReportSLOTargets(pa)
+	metricstest.AssertNoMetric(t, "slo_latency_p99_target", "slo_max_error_rate_target")
+
+	pa.Annotations = map[string]string{
+		autoscaling.SLOLatencyP99AnnotationKey:             "1.5s",
+		autoscaling.SLOMaxErrorRatePercentageAnnotationKey: "0.1",
+	}
+	ReportSLOTargets(pa)
+	wantResource := &resource.Resource{
+		Type: "knative_revision",
+		Labels: map[string]string{
+			metricskey.LabelRevisionName:      "test-revision",
+			metricskey.LabelNamespaceName:     "test-namespace",
+			metricskey.LabelServiceName:       "test-service",
+			metricskey.LabelConfigurationName: "test-config",
+		},
+	}
+	metricstest.AssertMetric(t,
+		metricstest.FloatMetric("slo_latency_p99_target", 1500, nil).WithResource(wantResource),
+		metricstest.FloatMetric("slo_max_error_rate_target", 0.1, nil).WithResource(wantResource),
+	)
+}
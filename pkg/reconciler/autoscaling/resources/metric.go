@@ -62,6 +62,9 @@ func MakeMetric(pa *v1alpha1.PodAutoscaler, metricSvc string, config *autoscaler
 			StableWindow: stableWindow,
 			PanicWindow:  panicWindow,
 			ScrapeTarget: metricSvc,
+
+			ActivatorMergePolicy:      config.ActivatorMetricMergePolicy,
+			ActivatorWeightPercentage: config.ActivatorMetricWeightPercentage,
 		},
 	}
 }
@@ -83,6 +83,20 @@ func TestMakeMetric(t *testing.T) {
 	}
 }
 
+func TestMakeMetricActivatorMergePolicy(t *testing.T) {
+	cfg := *config
+	cfg.ActivatorMetricMergePolicy = autoscaling.ActivatorMetricMergeWeighted
+	cfg.ActivatorMetricWeightPercentage = 30
+
+	want := metric(withScrapeTarget("ik"), func(m *v1alpha1.Metric) {
+		m.Spec.ActivatorMergePolicy = autoscaling.ActivatorMetricMergeWeighted
+		m.Spec.ActivatorWeightPercentage = 30
+	})
+	if diff := cmp.Diff(want, MakeMetric(pa(), "ik", &cfg)); diff != "" {
+		t.Errorf("MakeMetric (-want, +got):\n%v", diff)
+	}
+}
+
 func TestStableWindow(t *testing.T) {
 	// Not set on PA.
 	thePa := pa()
@@ -96,8 +110,6 @@ func TestStableWindow(t *testing.T) {
 	}
 }
 
-type MetricOption func(*v1alpha1.Metric)
-
 func withStableWindow(window time.Duration) MetricOption {
 	return func(metric *v1alpha1.Metric) {
 		metric.Spec.StableWindow = window
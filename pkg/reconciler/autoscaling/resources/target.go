@@ -35,6 +35,10 @@ func ResolveMetricTarget(pa *v1alpha1.PodAutoscaler, config *autoscalerconfig.Co
 	case autoscaling.RPS:
 		total = config.RPSTargetDefault
 		tu = config.TargetUtilization
+	case autoscaling.CPU, autoscaling.Memory:
+		// The resource usage target is always provided via annotation
+		// and it is aimed for as is.
+		tu = 1
 	default:
 		// Concurrency is used by default
 		total = float64(pa.Spec.ContainerConcurrency)
@@ -170,6 +170,16 @@ func TestResolveMetricTarget(t *testing.T) {
 		pa:         pa(WithMetricAnnotation(autoscaling.RPS), WithTargetAnnotation("300")),
 		wantTarget: 210,
 		wantTotal:  300,
+	}, {
+		name:       "CPU: with target annotation 500",
+		pa:         pa(WithMetricAnnotation(autoscaling.CPU), WithTargetAnnotation("500"), WithPAContainerConcurrency(10)),
+		wantTarget: 500,
+		wantTotal:  500,
+	}, {
+		name:       "Memory: with target annotation 256 and TU annotation 50%",
+		pa:         pa(WithMetricAnnotation(autoscaling.Memory), WithTargetAnnotation("256"), WithTUAnnotation("50")),
+		wantTarget: 128,
+		wantTotal:  256,
 	}}
 
 	for _, tc := range cases {
@@ -48,6 +48,7 @@ func MakeRevision(ctx context.Context, configuration *v1.Configuration, clock cl
 		rev.SetRoutingState(v1.RoutingStatePending, clock)
 	}
 
+	propagateMetadata(ctx, rev, configuration)
 	updateRevisionLabels(rev, configuration)
 	updateRevisionAnnotations(rev, configuration)
 
@@ -57,6 +58,18 @@ func MakeRevision(ctx context.Context, configuration *v1.Configuration, clock cl
 	return rev
 }
 
+// propagateMetadata copies the labels and annotations of the Configuration
+// configured to propagate onto the revision, unless its template sets them.
+func propagateMetadata(ctx context.Context, rev *v1.Revision, configuration *v1.Configuration) {
+	defaults := config.FromContextOrDefaults(ctx).Defaults
+	rev.SetLabels(kmeta.UnionMaps(
+		kmeta.FilterMap(configuration.GetLabels(), func(key string) bool { return !defaults.PropagatesLabel(key) }),
+		rev.GetLabels()))
+	rev.SetAnnotations(kmeta.UnionMaps(
+		kmeta.FilterMap(configuration.GetAnnotations(), func(key string) bool { return !defaults.PropagatesAnnotation(key) }),
+		rev.GetAnnotations()))
+}
+
 // updateRevisionLabels sets the revisions labels given a Configuration.
 func updateRevisionLabels(rev, config metav1.Object) {
 	labels := rev.GetLabels()
@@ -349,3 +349,64 @@ func enableResponsiveGC(ctx context.Context, enabled bool) context.Context {
 	}
 	return config.ToContext(ctx, c)
 }
+
+func TestMakeRevisionPropagatesMetadata(t *testing.T) {
+	defaults, err := cfgmap.NewDefaultsConfigFromMap(map[string]string{
+		"propagate-labels":      "example.com/*",
+		"propagate-annotations": "team",
+	})
+	if err != nil {
+		t.Fatal("NewDefaultsConfigFromMap() =", err)
+	}
+	ctx := config.ToContext(context.Background(), &config.Config{
+		Features: &cfgmap.Features{ResponsiveRevisionGC: cfgmap.Disabled},
+		Defaults: defaults,
+	})
+
+	configuration := &v1.Configuration{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "propagate",
+			Name:      "config",
+			Labels: map[string]string{
+				"example.com/app":       "app",
+				"example.com/tier":      "config",
+				"other.com/app":         "app",
+				serving.ServiceLabelKey: "svc",
+			},
+			Annotations: map[string]string{
+				"team":                             "blue",
+				"other":                            "value",
+				"serving.knative.dev/lastModifier": "someone",
+			},
+			Generation: 1,
+		},
+		Spec: v1.ConfigurationSpec{
+			Template: v1.RevisionTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"example.com/tier": "template",
+					},
+				},
+			},
+		},
+	}
+
+	got := MakeRevision(ctx, configuration, clock.NewFakeClock(fakeCurTime))
+	wantLabels := map[string]string{
+		"example.com/app":                       "app",
+		"example.com/tier":                      "template",
+		serving.ConfigurationLabelKey:           "config",
+		serving.ConfigurationGenerationLabelKey: "1",
+		serving.ServiceLabelKey:                 "svc",
+	}
+	if diff := cmp.Diff(wantLabels, got.Labels); diff != "" {
+		t.Error("Labels (-want, +got) =", diff)
+	}
+	wantAnnotations := map[string]string{
+		"team":                        "blue",
+		"serving.knative.dev/creator": "someone",
+	}
+	if diff := cmp.Diff(wantAnnotations, got.Annotations); diff != "" {
+		t.Error("Annotations (-want, +got) =", diff)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leaderstatus
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+	"k8s.io/client-go/tools/cache"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	leaseinformer "knative.dev/pkg/client/injection/kube/informers/coordination/v1/lease"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/injection"
+	"knative.dev/pkg/kmeta"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/system"
+	servingreconciler "knative.dev/serving/pkg/reconciler"
+)
+
+const controllerAgentName = "leaderstatus-controller"
+
+// NewController returns the constructor of the controller reporting which of
+// the buckets of the component this replica holds, out of the Leases in the
+// system namespace whose names start with one of the prefixes.
+func NewController(component string, prefixes ...string) injection.ControllerConstructor {
+	return func(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+		ctx = servingreconciler.AnnotateLoggerWithName(ctx, controllerAgentName)
+		logger := logging.FromContext(ctx)
+		leaseInformer := leaseinformer.Get(ctx)
+
+		// The leader election identities start with the pod name, which is
+		// also the host name.
+		pod, err := os.Hostname()
+		if err != nil {
+			logger.Fatalw("Failed to get the pod name", zap.Error(err))
+		}
+
+		c := &Reconciler{
+			kubeclient:  kubeclient.Get(ctx),
+			leaseLister: leaseInformer.Lister(),
+			component:   component,
+			pod:         pod,
+			held:        make(map[string]string),
+		}
+		impl := controller.NewImpl(c, logger, controllerAgentName)
+
+		logger.Info("Setting up event handlers")
+		leaseInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+			FilterFunc: IsBucketLease(prefixes...),
+			Handler:    controller.HandleAll(impl.Enqueue),
+		})
+
+		return impl
+	}
+}
+
+// IsBucketLease returns a filter accepting the Leases in the system namespace
+// whose names start with one of the prefixes.
+func IsBucketLease(prefixes ...string) func(obj interface{}) bool {
+	hasPrefix := HasPrefix(prefixes...)
+	return func(obj interface{}) bool {
+		l, err := kmeta.DeletionHandlingAccessor(obj)
+		if err != nil || l.GetNamespace() != system.Namespace() {
+			return false
+		}
+		return hasPrefix(l.GetName())
+	}
+}
+
+// HasPrefix returns a filter accepting the names starting with one of the
+// prefixes.
+func HasPrefix(prefixes ...string) func(name string) bool {
+	return func(name string) bool {
+		for _, p := range prefixes {
+			if strings.HasPrefix(name, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
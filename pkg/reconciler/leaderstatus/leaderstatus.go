@@ -0,0 +1,160 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leaderstatus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	coordinationlisters "k8s.io/client-go/listers/coordination/v1"
+	"k8s.io/client-go/tools/cache"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/reconciler"
+)
+
+// StatusConfigMapName returns the name of the ConfigMap mapping the buckets
+// of the component to the leader election identities of their holders.
+func StatusConfigMapName(component string) string {
+	return component + "-leader-status"
+}
+
+// Reconciler reports the buckets held by this replica via the metrics and the
+// status ConfigMap of its component.
+type Reconciler struct {
+	// LeaderAwareFuncs lets the Reconciler run with leader election enabled,
+	// though every replica reconciles all the Leases, regardless of the
+	// buckets of this controller it leads, to report its own.
+	reconciler.LeaderAwareFuncs
+
+	kubeclient  kubernetes.Interface
+	leaseLister coordinationlisters.LeaseLister
+	component   string
+	pod         string
+
+	mu sync.Mutex
+	// held maps the buckets held by this replica to its identity.
+	held map[string]string
+}
+
+// Check that our Reconciler implements controller.Reconciler.
+var _ controller.Reconciler = (*Reconciler)(nil)
+
+// Reconcile implements controller.Reconciler.
+func (r *Reconciler) Reconcile(ctx context.Context, key string) error {
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	var holder string
+	lease, err := r.leaseLister.Leases(ns).Get(name)
+	if err != nil && !apierrs.IsNotFound(err) {
+		return err
+	} else if err == nil && lease.Spec.HolderIdentity != nil {
+		holder = *lease.Spec.HolderIdentity
+	}
+	if podFromIdentity(holder) != r.pod {
+		holder = ""
+	}
+
+	r.mu.Lock()
+	prev := r.held[name]
+	r.mu.Unlock()
+	if prev == holder {
+		return nil
+	}
+
+	if holder != "" {
+		if err := r.setStatus(ctx, ns, name, holder); err != nil {
+			return err
+		}
+		logging.FromContext(ctx).Infof("Holding bucket %s as %s", name, holder)
+	} else {
+		r.clearStatus(ctx, ns, name, prev)
+		logging.FromContext(ctx).Infof("Released bucket %s", name)
+	}
+
+	r.mu.Lock()
+	if holder != "" {
+		r.held[name] = holder
+	} else {
+		delete(r.held, name)
+	}
+	r.mu.Unlock()
+	reportBucketHeld(ctx, r.component, name, holder != "")
+	return nil
+}
+
+// setStatus records the holder of the bucket in the status ConfigMap.
+// The replicas only ever write the entries of the buckets they hold, so the
+// merge patches need no coordination between them.
+func (r *Reconciler) setStatus(ctx context.Context, ns, bucket, holder string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"data": map[string]string{bucket: holder},
+	})
+	if err != nil {
+		return err
+	}
+	name := StatusConfigMapName(r.component)
+	_, err = r.kubeclient.CoreV1().ConfigMaps(ns).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if apierrs.IsNotFound(err) {
+		_, err = r.kubeclient.CoreV1().ConfigMaps(ns).Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: ns,
+			},
+			Data: map[string]string{bucket: holder},
+		}, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record the holder of bucket %s: %w", bucket, err)
+	}
+	return nil
+}
+
+// clearStatus removes the entry of the bucket from the status ConfigMap,
+// unless the new holder already replaced it. This is best effort, as the new
+// holder overwrites the entry anyway.
+func (r *Reconciler) clearStatus(ctx context.Context, ns, bucket, holder string) {
+	path := "/data/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(bucket)
+	patch, err := json.Marshal([]map[string]string{
+		{"op": "test", "path": path, "value": holder},
+		{"op": "remove", "path": path},
+	})
+	if err != nil {
+		return
+	}
+	if _, err := r.kubeclient.CoreV1().ConfigMaps(ns).Patch(ctx, StatusConfigMapName(r.component),
+		types.JSONPatchType, patch, metav1.PatchOptions{}); err != nil {
+		logging.FromContext(ctx).Debugf("Did not clear the holder of bucket %s: %v", bucket, err)
+	}
+}
+
+// podFromIdentity returns the pod name the leader election identities
+// start with.
+func podFromIdentity(id string) string {
+	return strings.SplitN(id, "_", 2)[0]
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leaderstatus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakek8s "k8s.io/client-go/kubernetes/fake"
+	coordinationlisters "k8s.io/client-go/listers/coordination/v1"
+	"k8s.io/client-go/tools/cache"
+	"knative.dev/pkg/ptr"
+	"knative.dev/pkg/system"
+
+	_ "knative.dev/pkg/system/testing"
+)
+
+const (
+	bucket1 = "autoscaler.knative.dev.serving.pkg.reconciler.autoscaling.kpa.reconciler.00-of-02"
+	bucket2 = "autoscaler.knative.dev.serving.pkg.reconciler.autoscaling.kpa.reconciler.01-of-02"
+)
+
+func lease(name, holder string) *coordinationv1.Lease {
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: system.Namespace(),
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity: ptr.String(holder),
+		},
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	kc := fakek8s.NewSimpleClientset()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	r := &Reconciler{
+		kubeclient:  kc,
+		leaseLister: coordinationlisters.NewLeaseLister(indexer),
+		component:   "autoscaler",
+		pod:         "autoscaler-1",
+		held:        make(map[string]string),
+	}
+
+	reconcile := func(l *coordinationv1.Lease) {
+		t.Helper()
+		indexer.Add(l)
+		if err := r.Reconcile(ctx, system.Namespace()+"/"+l.Name); err != nil {
+			t.Fatal("Reconcile() =", err)
+		}
+	}
+	checkStatus := func(want map[string]string) {
+		t.Helper()
+		cm, err := kc.CoreV1().ConfigMaps(system.Namespace()).Get(ctx, StatusConfigMapName("autoscaler"), metav1.GetOptions{})
+		if err != nil {
+			t.Fatal("Failed to get the status ConfigMap:", err)
+		}
+		if !cmp.Equal(cm.Data, want) {
+			t.Errorf("Status (-want, +got) =\n%s", cmp.Diff(want, cm.Data))
+		}
+	}
+
+	// Acquiring the first bucket creates the status.
+	reconcile(lease(bucket1, "autoscaler-1_10.0.0.1"))
+	checkStatus(map[string]string{bucket1: "autoscaler-1_10.0.0.1"})
+
+	// The buckets held by the other replicas are recorded by them.
+	reconcile(lease(bucket2, "autoscaler-2_10.0.0.2"))
+	checkStatus(map[string]string{bucket1: "autoscaler-1_10.0.0.1"})
+
+	// Acquiring another bucket adds it to the status.
+	reconcile(lease(bucket2, "autoscaler-1_10.0.0.1"))
+	checkStatus(map[string]string{
+		bucket1: "autoscaler-1_10.0.0.1",
+		bucket2: "autoscaler-1_10.0.0.1",
+	})
+
+	// Losing a bucket clears its entry.
+	reconcile(lease(bucket1, ""))
+	checkStatus(map[string]string{bucket2: "autoscaler-1_10.0.0.1"})
+
+	// Unless the new holder already replaced it.
+	if _, err := kc.CoreV1().ConfigMaps(system.Namespace()).Update(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      StatusConfigMapName("autoscaler"),
+			Namespace: system.Namespace(),
+		},
+		Data: map[string]string{bucket2: "autoscaler-2_10.0.0.2"},
+	}, metav1.UpdateOptions{}); err != nil {
+		t.Fatal("Failed to update the status ConfigMap:", err)
+	}
+	reconcile(lease(bucket2, "autoscaler-2_10.0.0.2"))
+	checkStatus(map[string]string{bucket2: "autoscaler-2_10.0.0.2"})
+
+	if len(r.held) != 0 {
+		t.Errorf("held = %v, want empty", r.held)
+	}
+}
+
+func TestIsBucketLease(t *testing.T) {
+	filter := IsBucketLease("autoscaler.", "autoscaler-bucket")
+	tests := []struct {
+		name string
+		obj  interface{}
+		want bool
+	}{{
+		name: "reconciler bucket",
+		obj:  lease(bucket1, ""),
+		want: true,
+	}, {
+		name: "autoscaler bucket",
+		obj:  lease("autoscaler-bucket-00-of-01", ""),
+		want: true,
+	}, {
+		name: "other component",
+		obj:  lease("autoscaler-hpa.knative.dev.serving.pkg.reconciler.autoscaling.hpa.reconciler.00-of-01", ""),
+	}, {
+		name: "other namespace",
+		obj: &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      bucket1,
+				Namespace: "default",
+			},
+		},
+	}, {
+		name: "tombstone",
+		obj: cache.DeletedFinalStateUnknown{
+			Key: system.Namespace() + "/" + bucket1,
+			Obj: lease(bucket1, ""),
+		},
+		want: true,
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := filter(test.obj); got != test.want {
+				t.Errorf("IsBucketLease() = %v, want: %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leaderstatus
+
+import (
+	"context"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	pkgmetrics "knative.dev/pkg/metrics"
+)
+
+var (
+	bucketHeldM = stats.Int64(
+		"leader_bucket_held",
+		"1 if this replica holds the bucket, 0 otherwise",
+		stats.UnitDimensionless)
+
+	componentKey = tag.MustNewKey("component")
+	bucketKey    = tag.MustNewKey("bucket")
+)
+
+func init() {
+	if err := view.Register(&view.View{
+		Description: "1 if this replica holds the bucket, 0 otherwise",
+		Measure:     bucketHeldM,
+		Aggregation: view.LastValue(),
+		TagKeys:     []tag.Key{componentKey, bucketKey},
+	}); err != nil {
+		panic(err)
+	}
+}
+
+func reportBucketHeld(ctx context.Context, component, bucket string, held bool) {
+	ctx, err := tag.New(ctx, tag.Upsert(componentKey, component), tag.Upsert(bucketKey, bucket))
+	if err != nil {
+		return
+	}
+	var v int64
+	if held {
+		v = 1
+	}
+	pkgmetrics.Record(ctx, bucketHeldM.M(v))
+}
@@ -90,8 +90,8 @@ func (c *reconciler) ReconcileKind(ctx context.Context, ns *corev1.Namespace) pk
 		return c.deleteNamespaceCerts(ctx, ns, existingCerts)
 	}
 
-	// Only create wildcard certs for the default domain
-	defaultDomain := cfg.Domain.LookupDomainForLabels(nil /* labels */)
+	// Only create wildcard certs for the default domain of the namespace
+	defaultDomain := cfg.Domain.LookupDomainForNamespace(nil /* labels */, ns.Labels)
 
 	dnsName, err := wildcardDomain(cfg.Network.DomainTemplate, defaultDomain, ns.Name)
 	if err != nil {
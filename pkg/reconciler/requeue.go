@@ -0,0 +1,145 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/kmeta"
+)
+
+// APIErrorClass is the class of an API error, which determines how soon
+// the reconciliation should be retried.
+type APIErrorClass int
+
+const (
+	// APIErrorOther are the errors retried as per the controller's defaults.
+	APIErrorOther APIErrorClass = iota
+	// APIErrorConflict are the optimistic concurrency conflicts, which get
+	// resolved once the informer caches catch up with the latest changes.
+	APIErrorConflict
+	// APIErrorThrottled are the errors returned by the API server under
+	// pressure, e.g. the 429s and the server timeouts.
+	APIErrorThrottled
+	// APIErrorWebhookTimeout are the timeouts calling the admission webhooks.
+	APIErrorWebhookTimeout
+)
+
+// ClassifyAPIError returns the class of the API error err, which can be wrapped.
+func ClassifyAPIError(err error) APIErrorClass {
+	var status apierrs.APIStatus
+	if !errors.As(err, &status) {
+		return APIErrorOther
+	}
+	s := status.Status()
+	switch {
+	case s.Reason == metav1.StatusReasonConflict:
+		return APIErrorConflict
+	case s.Reason == metav1.StatusReasonTooManyRequests || s.Code == http.StatusTooManyRequests,
+		s.Reason == metav1.StatusReasonServerTimeout, s.Reason == metav1.StatusReasonTimeout:
+		return APIErrorThrottled
+	case s.Reason == metav1.StatusReasonInternalError && isWebhookTimeout(s.Message):
+		return APIErrorWebhookTimeout
+	default:
+		return APIErrorOther
+	}
+}
+
+// isWebhookTimeout returns whether the message is the one of a timed out
+// admission webhook call, e.g.
+// `failed calling webhook "foo": Post https://...: context deadline exceeded`.
+func isWebhookTimeout(msg string) bool {
+	return strings.Contains(msg, "failed calling webhook") &&
+		(strings.Contains(msg, "deadline exceeded") || strings.Contains(msg, "Client.Timeout exceeded") ||
+			strings.Contains(msg, "i/o timeout"))
+}
+
+// Requeuer requeues the keys failing to reconcile due to API errors with
+// a backoff tailored to the error class, rather than the controller's
+// default exponential backoff, which starts in milliseconds and makes
+// the reconcilers hot-loop under API server pressure.
+type Requeuer struct {
+	enqueueAfter func(interface{}, time.Duration)
+	limiters     map[APIErrorClass]workqueue.RateLimiter
+}
+
+// NewRequeuer creates a Requeuer, which uses enqueueAfter, typically
+// the controller's EnqueueAfter, to requeue the keys.
+func NewRequeuer(enqueueAfter func(interface{}, time.Duration)) *Requeuer {
+	return &Requeuer{
+		enqueueAfter: enqueueAfter,
+		limiters: map[APIErrorClass]workqueue.RateLimiter{
+			APIErrorConflict:       workqueue.NewItemExponentialFailureRateLimiter(100*time.Millisecond, 10*time.Second),
+			APIErrorThrottled:      workqueue.NewItemExponentialFailureRateLimiter(time.Second, time.Minute),
+			APIErrorWebhookTimeout: workqueue.NewItemExponentialFailureRateLimiter(5*time.Second, 5*time.Minute),
+		},
+	}
+}
+
+// Handle returns the error to be returned by the reconciler, which failed to
+// reconcile obj with err. For the classified API errors obj is requeued after
+// the class' backoff and err is returned as a permanent error, so that the
+// controller does not requeue it too. Otherwise err is returned as is.
+// The backoffs for obj are reset once it reconciles successfully.
+// A nil Requeuer returns the errors as is.
+func (r *Requeuer) Handle(obj kmeta.Accessor, err error) error {
+	if r == nil {
+		return err
+	}
+	key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	if err == nil {
+		for _, l := range r.limiters {
+			l.Forget(key)
+		}
+		return nil
+	}
+	if controller.IsPermanentError(err) {
+		return err
+	}
+	l, ok := r.limiters[ClassifyAPIError(err)]
+	if !ok {
+		return err
+	}
+	delay := l.When(key)
+	// Respect the delay suggested by the API server, if longer.
+	if secs, ok := apierrs.SuggestsClientDelay(unwrapAPIStatus(err)); ok {
+		if d := time.Duration(secs) * time.Second; d > delay {
+			delay = d
+		}
+	}
+	r.enqueueAfter(obj, delay)
+	return controller.NewPermanentError(err)
+}
+
+// unwrapAPIStatus returns the API status error wrapped in err, or err itself.
+func unwrapAPIStatus(err error) error {
+	var status apierrs.APIStatus
+	if errors.As(err, &status) {
+		if e, ok := status.(error); ok {
+			return e
+		}
+	}
+	return err
+}
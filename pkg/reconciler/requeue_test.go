@@ -0,0 +1,134 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"knative.dev/pkg/controller"
+	v1 "knative.dev/serving/pkg/apis/serving/v1"
+)
+
+var revisionsGR = schema.GroupResource{Group: "serving.knative.dev", Resource: "revisions"}
+
+func TestClassifyAPIError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want APIErrorClass
+	}{{
+		name: "not an API error",
+		err:  errors.New("boom"),
+		want: APIErrorOther,
+	}, {
+		name: "not found",
+		err:  apierrs.NewNotFound(revisionsGR, "foo"),
+		want: APIErrorOther,
+	}, {
+		name: "conflict",
+		err:  apierrs.NewConflict(revisionsGR, "foo", errors.New("modified")),
+		want: APIErrorConflict,
+	}, {
+		name: "wrapped conflict",
+		err:  fmt.Errorf("failed to update: %w", apierrs.NewConflict(revisionsGR, "foo", errors.New("modified"))),
+		want: APIErrorConflict,
+	}, {
+		name: "too many requests",
+		err:  apierrs.NewTooManyRequests("slow down", 2),
+		want: APIErrorThrottled,
+	}, {
+		name: "server timeout",
+		err:  apierrs.NewServerTimeout(revisionsGR, "update", 1),
+		want: APIErrorThrottled,
+	}, {
+		name: "timeout",
+		err:  apierrs.NewTimeoutError("timed out", 1),
+		want: APIErrorThrottled,
+	}, {
+		name: "webhook timeout",
+		err: apierrs.NewInternalError(errors.New(`failed calling webhook "webhook.serving.knative.dev": ` +
+			`Post https://webhook.knative-serving.svc:443/?timeout=10s: context deadline exceeded`)),
+		want: APIErrorWebhookTimeout,
+	}, {
+		name: "webhook rejection",
+		err: apierrs.NewInternalError(errors.New(`failed calling webhook "webhook.serving.knative.dev": ` +
+			`Post https://webhook.knative-serving.svc:443/?timeout=10s: x509: certificate signed by unknown authority`)),
+		want: APIErrorOther,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ClassifyAPIError(tc.err); got != tc.want {
+				t.Errorf("ClassifyAPIError() = %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRequeuer(t *testing.T) {
+	var delays []time.Duration
+	r := NewRequeuer(func(_ interface{}, d time.Duration) {
+		delays = append(delays, d)
+	})
+	rev := &v1.Revision{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "rev"}}
+
+	// The errors that are not classified are returned as is.
+	other := errors.New("boom")
+	if got := r.Handle(rev, other); got != other {
+		t.Errorf("Handle() = %v, want: %v", got, other)
+	}
+	if len(delays) != 0 {
+		t.Errorf("Requeued %v for an unclassified error", delays)
+	}
+
+	// The classified errors back off exponentially.
+	conflict := apierrs.NewConflict(revisionsGR, "rev", errors.New("modified"))
+	for i := 0; i < 3; i++ {
+		got := r.Handle(rev, conflict)
+		if !controller.IsPermanentError(got) || !errors.Is(got, conflict) {
+			t.Errorf("Handle() = %v, want permanent %v", got, conflict)
+		}
+	}
+	// The delay suggested by the API server is respected.
+	r.Handle(rev, apierrs.NewTooManyRequests("slow down", 5))
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 5 * time.Second}
+	if !cmp.Equal(delays, want) {
+		t.Errorf("Delays = %v, want: %v", delays, want)
+	}
+
+	// The success resets the backoff.
+	delays = nil
+	if got := r.Handle(rev, nil); got != nil {
+		t.Errorf("Handle() = %v, want: nil", got)
+	}
+	r.Handle(rev, conflict)
+	if want := []time.Duration{100 * time.Millisecond}; !cmp.Equal(delays, want) {
+		t.Errorf("Delays = %v, want: %v", delays, want)
+	}
+
+	// A nil Requeuer returns the errors as is.
+	if got := (*Requeuer)(nil).Handle(rev, conflict); got != conflict {
+		t.Errorf("Handle() = %v, want: %v", got, conflict)
+	}
+}
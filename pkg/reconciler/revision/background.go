@@ -24,6 +24,8 @@ import (
 
 	"github.com/google/go-containerregistry/pkg/authn/k8schain"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/util/workqueue"
@@ -32,7 +34,9 @@ import (
 
 // imageResolver is an interface used mostly to mock digestResolver for tests.
 type imageResolver interface {
-	Resolve(ctx context.Context, image string, opt k8schain.Options, registriesToSkip sets.String) (string, error)
+	Resolve(ctx context.Context, image string, opt k8schain.Options, registriesToSkip sets.String, maxImageSize int64) (string, error)
+	Metadata(ctx context.Context, image string, opt k8schain.Options) (imageMetadata, error)
+	VerifiesSignatures() bool
 }
 
 // backgroundResolver performs background downloads of image digests.
@@ -54,13 +58,16 @@ type resolveResult struct {
 	// these fields are immutable afer creation, so can be accessed without a lock.
 	opt                k8schain.Options
 	registriesToSkip   sets.String
+	maxImageSize       int64
+	imageMetadata      bool
 	completionCallback func()
 
 	// these fields can be written concurrently, so should only be accessed while
 	// holding the backgroundResolver mutex.
-	statuses  []v1.ContainerStatus
-	err       error
-	remaining int
+	statuses     []v1.ContainerStatus
+	initStatuses []v1.ContainerStatus
+	err          error
+	remaining    int
 }
 
 // workItem is a single task submitted to the queue, to resolve a single image
@@ -72,6 +79,11 @@ type workItem struct {
 	name  string
 	image string
 	index int
+	// init is true if the image is of an init container.
+	init bool
+	// local is true if the image is never pulled, so it is used as is
+	// rather than resolved against a registry it may not be in.
+	local bool
 }
 
 func newBackgroundResolver(logger *zap.SugaredLogger, resolver imageResolver, enqueue func(types.NamespacedName)) *backgroundResolver {
@@ -136,10 +148,13 @@ func (r *backgroundResolver) Start(stop <-chan struct{}, maxInFlight int) (done
 // the resolver already has the digest in cache it is returned immediately, if
 // it does not and no resolution is already in flight a resolution is triggered
 // in the background.
-// If this method returns `nil, nil` this implies a resolve was triggered or is
+// The statuses of the containers are returned before those of the init containers.
+// If imageMetadata is true, the statuses also report the size and the creation
+// time of the resolved images.
+// If this method returns `nil, nil, nil` this implies a resolve was triggered or is
 // already in progress, so the reconciler should exit and wait for the revision
 // to be re-enqueued when the result is ready.
-func (r *backgroundResolver) Resolve(rev *v1.Revision, opt k8schain.Options, registriesToSkip sets.String, timeout time.Duration) ([]v1.ContainerStatus, error) {
+func (r *backgroundResolver) Resolve(rev *v1.Revision, opt k8schain.Options, registriesToSkip sets.String, maxImageSize int64, imageMetadata bool, timeout time.Duration) ([]v1.ContainerStatus, []v1.ContainerStatus, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -150,40 +165,59 @@ func (r *backgroundResolver) Resolve(rev *v1.Revision, opt k8schain.Options, reg
 
 	result, inFlight := r.results[name]
 	if !inFlight {
-		r.addWorkItems(rev, name, opt, registriesToSkip, timeout)
-		return nil, nil
+		r.addWorkItems(rev, name, opt, registriesToSkip, maxImageSize, imageMetadata, timeout)
+		return nil, nil, nil
 	}
 
 	if !result.ready() {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	ret := r.results[name]
-	return ret.statuses, ret.err
+	return ret.statuses, ret.initStatuses, ret.err
 }
 
-// addWorkItems adds a digest resolve item to the queue for each container in the revision.
+// addWorkItems adds a digest resolve item to the queue for each container and
+// init container in the revision.
 // This is expected to be called with the mutex locked.
-func (r *backgroundResolver) addWorkItems(rev *v1.Revision, name types.NamespacedName, opt k8schain.Options, registriesToSkip sets.String, timeout time.Duration) {
+func (r *backgroundResolver) addWorkItems(rev *v1.Revision, name types.NamespacedName, opt k8schain.Options, registriesToSkip sets.String, maxImageSize int64, imageMetadata bool, timeout time.Duration) {
 	r.results[name] = &resolveResult{
 		opt:              opt,
 		registriesToSkip: registriesToSkip,
+		maxImageSize:     maxImageSize,
+		imageMetadata:    imageMetadata,
 		statuses:         make([]v1.ContainerStatus, len(rev.Spec.Containers)),
-		remaining:        len(rev.Spec.Containers),
+		remaining:        len(rev.Spec.Containers) + len(rev.Spec.InitContainers),
 		completionCallback: func() {
 			r.enqueue(name)
 		},
 	}
 
 	for i := range rev.Spec.Containers {
-		image := rev.Spec.Containers[i].Image
+		c := &rev.Spec.Containers[i]
+		r.queue.Add(&workItem{
+			result:  r.results[name],
+			timeout: timeout,
+			name:    c.Name,
+			image:   c.Image,
+			index:   i,
+			local:   c.ImagePullPolicy == corev1.PullNever,
+		})
+	}
 
+	if len(rev.Spec.InitContainers) > 0 {
+		r.results[name].initStatuses = make([]v1.ContainerStatus, len(rev.Spec.InitContainers))
+	}
+	for i := range rev.Spec.InitContainers {
+		c := &rev.Spec.InitContainers[i]
 		r.queue.Add(&workItem{
 			result:  r.results[name],
 			timeout: timeout,
-			name:    rev.Spec.Containers[i].Name,
-			image:   image,
+			name:    c.Name,
+			image:   c.Image,
 			index:   i,
+			init:    true,
+			local:   c.ImagePullPolicy == corev1.PullNever,
 		})
 	}
 }
@@ -194,10 +228,30 @@ func (r *backgroundResolver) addWorkItems(rev *v1.Revision, name types.Namespace
 func (r *backgroundResolver) processWorkItem(item *workItem) {
 	defer r.queue.Done(item)
 
-	ctx, cancel := context.WithTimeout(context.Background(), item.timeout)
-	defer cancel()
-
-	resolvedDigest, resolveErr := r.resolver.Resolve(ctx, item.image, item.result.opt, item.result.registriesToSkip)
+	var (
+		resolvedDigest string
+		metadata       imageMetadata
+		resolveErr     error
+	)
+	// An empty digest deploys the image as is, like for the registries skipping
+	// tag resolution. The images that are never pulled cannot be verified to
+	// be the signed ones though.
+	if item.local && r.resolver.VerifiesSignatures() {
+		resolveErr = &signatureError{message: "images with imagePullPolicy Never cannot be verified"}
+	} else if !item.local {
+		ctx, cancel := context.WithTimeout(context.Background(), item.timeout)
+		defer cancel()
+
+		resolvedDigest, resolveErr = r.resolver.Resolve(ctx, item.image, item.result.opt, item.result.registriesToSkip, item.result.maxImageSize)
+		if resolveErr == nil && resolvedDigest != "" && item.result.imageMetadata {
+			// The metadata is informational, so failing to fetch it does not
+			// fail the revision.
+			var err error
+			if metadata, err = r.resolver.Metadata(ctx, resolvedDigest, item.result.opt); err != nil {
+				r.logger.Warnw("Failed to fetch the metadata of image "+resolvedDigest, zap.Error(err))
+			}
+		}
+	}
 
 	// lock after the resolve because we don't want to block parallel resolves,
 	// just storing the result.
@@ -213,15 +267,29 @@ func (r *backgroundResolver) processWorkItem(item *workItem) {
 
 	if resolveErr != nil {
 		item.result.statuses = nil
-		item.result.err = fmt.Errorf("%s: %w", v1.RevisionContainerMissingMessage(item.image, "failed to resolve image to digest"), resolveErr)
+		item.result.initStatuses = nil
+		if isSignatureError(resolveErr) {
+			item.result.err = &signatureError{message: v1.RevisionImageSignatureInvalidMessage(item.image, resolveErr.Error())}
+		} else {
+			item.result.err = fmt.Errorf("%s: %w", v1.RevisionContainerMissingMessage(item.image, "failed to resolve image to digest"), resolveErr)
+		}
 		item.result.completionCallback()
 		return
 	}
 
 	item.result.remaining--
-	item.result.statuses[item.index] = v1.ContainerStatus{
-		Name:        item.name,
-		ImageDigest: resolvedDigest,
+	statuses := item.result.statuses
+	if item.init {
+		statuses = item.result.initStatuses
+	}
+	statuses[item.index] = v1.ContainerStatus{
+		Name:                 item.name,
+		ImageDigest:          resolvedDigest,
+		TagResolutionSkipped: resolvedDigest == "",
+		ImageSize:            metadata.size,
+	}
+	if !metadata.created.IsZero() {
+		statuses[item.index].ImageCreated = &metav1.Time{Time: metadata.created}
 	}
 
 	if item.result.ready() {
@@ -37,6 +37,7 @@ import (
 
 var (
 	errDigest    = errors.New("digest error")
+	imageCreated = time.Date(2020, time.October, 15, 0, 0, 0, 0, time.UTC)
 	fakeRevision = &v1.Revision{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "rev",
@@ -51,6 +52,10 @@ var (
 					Name:  "second",
 					Image: "second-image",
 				}},
+				InitContainers: []corev1.Container{{
+					Name:  "init",
+					Image: "init-image",
+				}},
 			},
 		},
 	}
@@ -58,11 +63,17 @@ var (
 
 func TestResolveInBackground(t *testing.T) {
 	tests := []struct {
-		name         string
-		resolver     resolveFunc
-		timeout      *time.Duration
-		wantStatuses []v1.ContainerStatus
-		wantError    error
+		name             string
+		rev              *v1.Revision
+		resolver         resolveFunc
+		verifySignatures bool
+		imageMetadata    bool
+		timeout          *time.Duration
+		wantStatuses     []v1.ContainerStatus
+		wantInitStatuses []v1.ContainerStatus
+		wantError        error
+		// wantSignatureError is whether the error is a signatureError.
+		wantSignatureError bool
 	}{{
 		name: "success",
 		resolver: func(_ context.Context, img string, _ k8schain.Options, _ sets.String) (string, error) {
@@ -75,6 +86,73 @@ func TestResolveInBackground(t *testing.T) {
 			Name:        "second",
 			ImageDigest: "second-image-digest",
 		}},
+		wantInitStatuses: []v1.ContainerStatus{{
+			Name:        "init",
+			ImageDigest: "init-image-digest",
+		}},
+	}, {
+		name: "never pulled images",
+		rev: func() *v1.Revision {
+			rev := fakeRevision.DeepCopy()
+			rev.Spec.Containers[1].ImagePullPolicy = corev1.PullNever
+			rev.Spec.InitContainers[0].ImagePullPolicy = corev1.PullNever
+			return rev
+		}(),
+		resolver: func(_ context.Context, img string, _ k8schain.Options, _ sets.String) (string, error) {
+			if img != "first-image" {
+				return "", errDigest
+			}
+			return img + "-digest", nil
+		},
+		wantStatuses: []v1.ContainerStatus{{
+			Name:        "first",
+			ImageDigest: "first-image-digest",
+		}, {
+			Name:                 "second",
+			TagResolutionSkipped: true,
+		}},
+		wantInitStatuses: []v1.ContainerStatus{{
+			Name:                 "init",
+			TagResolutionSkipped: true,
+		}},
+	}, {
+		name: "never pulled images with signature verification",
+		rev: func() *v1.Revision {
+			rev := fakeRevision.DeepCopy()
+			rev.Spec.Containers[1].ImagePullPolicy = corev1.PullNever
+			return rev
+		}(),
+		resolver: func(_ context.Context, img string, _ k8schain.Options, _ sets.String) (string, error) {
+			return img + "-digest", nil
+		},
+		verifySignatures:   true,
+		wantSignatureError: true,
+	}, {
+		name: "image metadata",
+		rev: func() *v1.Revision {
+			rev := fakeRevision.DeepCopy()
+			rev.Spec.Containers[1].ImagePullPolicy = corev1.PullNever
+			return rev
+		}(),
+		resolver: func(_ context.Context, img string, _ k8schain.Options, _ sets.String) (string, error) {
+			return img + "-digest", nil
+		},
+		imageMetadata: true,
+		wantStatuses: []v1.ContainerStatus{{
+			Name:         "first",
+			ImageDigest:  "first-image-digest",
+			ImageSize:    int64(len("first-image-digest")),
+			ImageCreated: &metav1.Time{Time: imageCreated},
+		}, {
+			Name:                 "second",
+			TagResolutionSkipped: true,
+		}},
+		wantInitStatuses: []v1.ContainerStatus{{
+			Name:         "init",
+			ImageDigest:  "init-image-digest",
+			ImageSize:    int64(len("init-image-digest")),
+			ImageCreated: &metav1.Time{Time: imageCreated},
+		}},
 	}, {
 		name: "passing params",
 		resolver: func(_ context.Context, img string, opt k8schain.Options, skip sets.String) (string, error) {
@@ -87,6 +165,10 @@ func TestResolveInBackground(t *testing.T) {
 			Name:        "second",
 			ImageDigest: "second-image-san-skip",
 		}},
+		wantInitStatuses: []v1.ContainerStatus{{
+			Name:        "init",
+			ImageDigest: "init-image-san-skip",
+		}},
 	}, {
 		name: "one slow resolve",
 		resolver: func(_ context.Context, img string, _ k8schain.Options, _ sets.String) (string, error) {
@@ -103,6 +185,10 @@ func TestResolveInBackground(t *testing.T) {
 			Name:        "second",
 			ImageDigest: "second-image-digest",
 		}},
+		wantInitStatuses: []v1.ContainerStatus{{
+			Name:        "init",
+			ImageDigest: "init-image-digest",
+		}},
 	}, {
 		name: "resolver entirely fails",
 		resolver: func(_ context.Context, img string, _ k8schain.Options, _ sets.String) (string, error) {
@@ -138,6 +224,10 @@ func TestResolveInBackground(t *testing.T) {
 			if tt.timeout != nil {
 				timeout = *tt.timeout
 			}
+			rev := fakeRevision
+			if tt.rev != nil {
+				rev = tt.rev
+			}
 
 			ready := make(chan types.NamespacedName)
 			cb := func(rev types.NamespacedName) {
@@ -146,7 +236,11 @@ func TestResolveInBackground(t *testing.T) {
 			}
 
 			logger := logtesting.TestLogger(t)
-			subject := newBackgroundResolver(logger, tt.resolver, cb)
+			var resolver imageResolver = tt.resolver
+			if tt.verifySignatures {
+				resolver = verifyingResolver{tt.resolver}
+			}
+			subject := newBackgroundResolver(logger, resolver, cb)
 
 			stop := make(chan struct{})
 			done := subject.Start(stop, 10)
@@ -158,10 +252,10 @@ func TestResolveInBackground(t *testing.T) {
 
 			for i := 0; i < 2; i++ {
 				t.Run(fmt.Sprint("iteration", i), func(t *testing.T) {
-					statuses, err := subject.Resolve(fakeRevision, k8schain.Options{ServiceAccountName: "san"}, sets.NewString("skip"), timeout)
-					if err != nil || statuses != nil {
-						// Initial result should be nil, nil since we have nothing in cache.
-						t.Errorf("Resolve() = %v, %v, wanted nil, nil", statuses, err)
+					statuses, initStatuses, err := subject.Resolve(rev, k8schain.Options{ServiceAccountName: "san"}, sets.NewString("skip"), 0, tt.imageMetadata, timeout)
+					if err != nil || statuses != nil || initStatuses != nil {
+						// Initial result should be nil, nil, nil since we have nothing in cache.
+						t.Errorf("Resolve() = %v, %v, %v, wanted nil, nil, nil", statuses, initStatuses, err)
 					}
 
 					select {
@@ -173,16 +267,23 @@ func TestResolveInBackground(t *testing.T) {
 						t.Fatalf("Resolver did not report ready")
 					}
 
-					statuses, err = subject.Resolve(fakeRevision, k8schain.Options{}, nil, timeout)
-					if got, want := err, tt.wantError; !errors.Is(got, want) {
+					statuses, initStatuses, err = subject.Resolve(rev, k8schain.Options{}, nil, 0, false, timeout)
+					if tt.wantSignatureError {
+						if !isSignatureError(err) {
+							t.Errorf("Resolve() = _, %q, wanted a signature error", err)
+						}
+					} else if got, want := err, tt.wantError; !errors.Is(got, want) {
 						t.Errorf("Resolve() = _, %q, wanted %q", got, want)
 					}
 					if got, want := statuses, tt.wantStatuses; !reflect.DeepEqual(got, want) {
 						t.Errorf("Resolve() = %v, wanted %v", got, want)
 					}
+					if got, want := initStatuses, tt.wantInitStatuses; !reflect.DeepEqual(got, want) {
+						t.Errorf("Resolve() = _, %v, wanted %v", got, want)
+					}
 
 					// Clear, then we'll loop and make sure that we look everything up from scratch.
-					subject.Clear(types.NamespacedName{Namespace: fakeRevision.Namespace, Name: fakeRevision.Name})
+					subject.Clear(types.NamespacedName{Namespace: rev.Namespace, Name: rev.Name})
 					ready = make(chan types.NamespacedName)
 				})
 			}
@@ -192,6 +293,24 @@ func TestResolveInBackground(t *testing.T) {
 
 type resolveFunc func(context.Context, string, k8schain.Options, sets.String) (string, error)
 
-func (r resolveFunc) Resolve(c context.Context, s string, o k8schain.Options, t sets.String) (string, error) {
+func (r resolveFunc) Resolve(c context.Context, s string, o k8schain.Options, t sets.String, _ int64) (string, error) {
 	return r(c, s, o, t)
 }
+
+// Metadata reports the length of the image as its size.
+func (r resolveFunc) Metadata(_ context.Context, s string, _ k8schain.Options) (imageMetadata, error) {
+	return imageMetadata{size: int64(len(s)), created: imageCreated}, nil
+}
+
+func (r resolveFunc) VerifiesSignatures() bool {
+	return false
+}
+
+// verifyingResolver is a resolveFunc verifying the signatures of the images.
+type verifyingResolver struct {
+	resolveFunc
+}
+
+func (verifyingResolver) VerifiesSignatures() bool {
+	return true
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// LogSidecarKey is the config-observability entry defining the container,
+// in YAML, injected into the pods of the revisions opting into it to ship
+// the logs they write under /var/log.
+const LogSidecarKey = "logging.sidecar-template"
+
+// NewLogSidecarFromConfigMap extracts the log sidecar template from the
+// config-observability ConfigMap, nil if it isn't defined.
+func NewLogSidecarFromConfigMap(configMap *corev1.ConfigMap) (*corev1.Container, error) {
+	v, ok := configMap.Data[LogSidecarKey]
+	if !ok || v == "" {
+		return nil, nil
+	}
+	j, err := yaml.ToJSON([]byte(v))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", LogSidecarKey, err)
+	}
+	sidecar := &corev1.Container{}
+	decoder := json.NewDecoder(bytes.NewReader(j))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(sidecar); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", LogSidecarKey, err)
+	}
+	if sidecar.Name == "" || sidecar.Image == "" {
+		return nil, errors.New(LogSidecarKey + " must define the name and image of the container")
+	}
+	return sidecar, nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNewLogSidecarFromConfigMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]string
+		want    *corev1.Container
+		wantErr bool
+	}{{
+		name: "no template",
+		data: map[string]string{},
+	}, {
+		name: "empty template",
+		data: map[string]string{LogSidecarKey: ""},
+	}, {
+		name: "template",
+		data: map[string]string{
+			LogSidecarKey: "name: fluent-bit\nimage: fluent/fluent-bit\nargs: [\"-i\", \"tail\"]",
+		},
+		want: &corev1.Container{
+			Name:  "fluent-bit",
+			Image: "fluent/fluent-bit",
+			Args:  []string{"-i", "tail"},
+		},
+	}, {
+		name:    "no image",
+		data:    map[string]string{LogSidecarKey: "name: fluent-bit"},
+		wantErr: true,
+	}, {
+		name:    "unknown field",
+		data:    map[string]string{LogSidecarKey: "name: fluent-bit\nimage: fluent/fluent-bit\nimages: []"},
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NewLogSidecarFromConfigMap(&corev1.ConfigMap{Data: test.data})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("NewLogSidecarFromConfigMap() = %v, wantErr %v", err, test.wantErr)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Error("NewLogSidecarFromConfigMap() (-want, +got):", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// RequestLogTemplateKeyPrefix is the prefix of the config-observability entries
+// defining the named request log templates, which the revisions can select
+// instead of logging.request-log-template, e.g.
+// `logging.request-log-template.minimal`.
+const RequestLogTemplateKeyPrefix = "logging.request-log-template."
+
+// RequestLogTemplates maps the names of the request log templates to the templates.
+type RequestLogTemplates map[string]string
+
+// NewRequestLogTemplatesFromConfigMap extracts the named request log templates
+// from the config-observability ConfigMap.
+func NewRequestLogTemplatesFromConfigMap(configMap *corev1.ConfigMap) (RequestLogTemplates, error) {
+	templates := make(RequestLogTemplates)
+	for k, v := range configMap.Data {
+		name := strings.TrimPrefix(k, RequestLogTemplateKeyPrefix)
+		if name == k || name == "" {
+			continue
+		}
+		if _, err := template.New("requestLog").Parse(v); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", k, err)
+		}
+		templates[name] = v
+	}
+	return templates, nil
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNewRequestLogTemplatesFromConfigMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]string
+		want    RequestLogTemplates
+		wantErr bool
+	}{{
+		name: "no templates",
+		data: map[string]string{
+			"logging.request-log-template": "{{.Request.Method}}",
+		},
+		want: RequestLogTemplates{},
+	}, {
+		name: "named templates",
+		data: map[string]string{
+			"logging.request-log-template":         "{{.Request.Method}}",
+			"logging.request-log-template.minimal": "{{.Response.Code}}",
+			"logging.request-log-template.":        "ignored",
+		},
+		want: RequestLogTemplates{
+			"minimal": "{{.Response.Code}}",
+		},
+	}, {
+		name: "invalid template",
+		data: map[string]string{
+			"logging.request-log-template.broken": "{{.Request.Method",
+		},
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NewRequestLogTemplatesFromConfigMap(&corev1.ConfigMap{Data: test.data})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("NewRequestLogTemplatesFromConfigMap() = %v, wantErr %v", err, test.wantErr)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Error("NewRequestLogTemplatesFromConfigMap() (-want, +got):", diff)
+			}
+		})
+	}
+}
@@ -19,13 +19,17 @@ package config
 import (
 	"context"
 
+	corev1 "k8s.io/api/core/v1"
+
 	network "knative.dev/networking/pkg"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/metrics"
-	pkgtracing "knative.dev/pkg/tracing/config"
+	tracingconfig "knative.dev/pkg/tracing/config"
 	apiconfig "knative.dev/serving/pkg/apis/config"
 	"knative.dev/serving/pkg/deployment"
+	"knative.dev/serving/pkg/networking"
+	"knative.dev/serving/pkg/tracing"
 )
 
 type cfgKey struct{}
@@ -36,8 +40,12 @@ type Config struct {
 	Deployment    *deployment.Config
 	Logging       *logging.Config
 	Network       *network.Config
+	Networking    *networking.Config
 	Observability *metrics.ObservabilityConfig
-	Tracing       *pkgtracing.Config
+	Tracing       *tracing.Config
+
+	RequestLogTemplates RequestLogTemplates
+	LogSidecar          *corev1.Container
 }
 
 // FromContext loads the configuration from the context.
@@ -54,7 +62,14 @@ func ToContext(ctx context.Context, c *Config) context.Context {
 // +k8s:deepcopy-gen=false
 type Store struct {
 	*configmap.UntypedStore
-	apiStore *apiconfig.Store
+	apiStore        *apiconfig.Store
+	networkingStore *networking.Store
+	// requestLogStore keeps the named request log templates, which are
+	// parsed from the same ConfigMap as Observability.
+	requestLogStore *configmap.UntypedStore
+	// logSidecarStore keeps the log sidecar template, which is also parsed
+	// from the same ConfigMap as Observability.
+	logSidecarStore *configmap.UntypedStore
 }
 
 // NewStore creates a new store of Configs and optionally calls functions when ConfigMaps are updated for Revisions
@@ -64,15 +79,32 @@ func NewStore(logger configmap.Logger, onAfterStore ...func(name string, value i
 			"revision",
 			logger,
 			configmap.Constructors{
-				deployment.ConfigName:   deployment.NewConfigFromConfigMap,
-				logging.ConfigMapName(): logging.NewConfigFromConfigMap,
-				metrics.ConfigMapName(): metrics.NewObservabilityConfigFromConfigMap,
-				network.ConfigName:      network.NewConfigFromConfigMap,
-				pkgtracing.ConfigName:   pkgtracing.NewTracingConfigFromConfigMap,
+				deployment.ConfigName:    deployment.NewConfigFromConfigMap,
+				logging.ConfigMapName():  logging.NewConfigFromConfigMap,
+				metrics.ConfigMapName():  metrics.NewObservabilityConfigFromConfigMap,
+				network.ConfigName:       network.NewConfigFromConfigMap,
+				tracingconfig.ConfigName: tracing.NewConfigFromConfigMap,
+			},
+			onAfterStore...,
+		),
+		apiStore:        apiconfig.NewStore(logger),
+		networkingStore: networking.NewStore(logger, onAfterStore...),
+		requestLogStore: configmap.NewUntypedStore(
+			"request-log-templates",
+			logger,
+			configmap.Constructors{
+				metrics.ConfigMapName(): NewRequestLogTemplatesFromConfigMap,
+			},
+			onAfterStore...,
+		),
+		logSidecarStore: configmap.NewUntypedStore(
+			"log-sidecar",
+			logger,
+			configmap.Constructors{
+				metrics.ConfigMapName(): NewLogSidecarFromConfigMap,
 			},
 			onAfterStore...,
 		),
-		apiStore: apiconfig.NewStore(logger),
 	}
 	return store
 }
@@ -83,6 +115,9 @@ func NewStore(logger configmap.Logger, onAfterStore ...func(name string, value i
 func (s *Store) WatchConfigs(cmw configmap.Watcher) {
 	s.UntypedStore.WatchConfigs(cmw)
 	s.apiStore.WatchConfigs(cmw)
+	s.networkingStore.WatchConfigs(cmw)
+	s.requestLogStore.WatchConfigs(cmw)
+	s.logSidecarStore.WatchConfigs(cmw)
 }
 
 // ToContext persists the config on the context.
@@ -93,7 +128,8 @@ func (s *Store) ToContext(ctx context.Context) context.Context {
 // Load returns the config from the store.
 func (s *Store) Load() *Config {
 	cfg := &Config{
-		Config: s.apiStore.Load(),
+		Config:     s.apiStore.Load(),
+		Networking: s.networkingStore.Load(),
 	}
 
 	if dep, ok := s.UntypedLoad(deployment.ConfigName).(*deployment.Config); ok {
@@ -108,9 +144,15 @@ func (s *Store) Load() *Config {
 	if obs, ok := s.UntypedLoad(metrics.ConfigMapName()).(*metrics.ObservabilityConfig); ok {
 		cfg.Observability = obs.DeepCopy()
 	}
-	if tr, ok := s.UntypedLoad(pkgtracing.ConfigName).(*pkgtracing.Config); ok {
+	if tr, ok := s.UntypedLoad(tracingconfig.ConfigName).(*tracing.Config); ok {
 		cfg.Tracing = tr.DeepCopy()
 	}
+	if rlt, ok := s.requestLogStore.UntypedLoad(metrics.ConfigMapName()).(RequestLogTemplates); ok {
+		cfg.RequestLogTemplates = rlt.DeepCopy()
+	}
+	if ls, ok := s.logSidecarStore.UntypedLoad(metrics.ConfigMapName()).(*corev1.Container); ok {
+		cfg.LogSidecar = ls.DeepCopy()
+	}
 
 	return cfg
 }
@@ -33,6 +33,8 @@ import (
 	apiconfig "knative.dev/serving/pkg/apis/config"
 	autoscalerconfig "knative.dev/serving/pkg/autoscaler/config"
 	"knative.dev/serving/pkg/deployment"
+	"knative.dev/serving/pkg/networking"
+	"knative.dev/serving/pkg/tracing"
 
 	. "knative.dev/pkg/configmap/testing"
 )
@@ -77,6 +79,13 @@ func TestStoreLoadWithContext(t *testing.T) {
 		}
 	})
 
+	t.Run("networking", func(t *testing.T) {
+		expected, _ := networking.NewConfigFromConfigMap(networkConfig)
+		if diff := cmp.Diff(expected, config.Networking); diff != "" {
+			t.Error("Unexpected networking config (-want, +got):", diff)
+		}
+	})
+
 	t.Run("observability", func(t *testing.T) {
 		expected, _ := metrics.NewObservabilityConfigFromConfigMap(observabilityConfig)
 		if diff := cmp.Diff(expected, config.Observability); diff != "" {
@@ -97,6 +106,20 @@ func TestStoreLoadWithContext(t *testing.T) {
 		}
 	})
 
+	t.Run("request log templates", func(t *testing.T) {
+		expected, _ := NewRequestLogTemplatesFromConfigMap(observabilityConfig)
+		if diff := cmp.Diff(expected, config.RequestLogTemplates); diff != "" {
+			t.Error("Unexpected request log templates (-want, +got):", diff)
+		}
+	})
+
+	t.Run("log sidecar", func(t *testing.T) {
+		expected, _ := NewLogSidecarFromConfigMap(observabilityConfig)
+		if diff := cmp.Diff(expected, config.LogSidecar); diff != "" {
+			t.Error("Unexpected log sidecar (-want, +got):", diff)
+		}
+	})
+
 	t.Run("logging", func(t *testing.T) {
 		expected, _ := logging.NewConfigFromConfigMap(loggingConfig)
 		if diff := cmp.Diff(expected, config.Logging); diff != "" {
@@ -115,14 +138,14 @@ func TestStoreLoadWithContext(t *testing.T) {
 	})
 
 	t.Run("tracing", func(t *testing.T) {
-		expected, _ := pkgtracing.NewTracingConfigFromConfigMap(tracingConfig)
+		expected, _ := tracing.NewConfigFromConfigMap(tracingConfig)
 		if diff := cmp.Diff(expected, config.Tracing); diff != "" {
 			t.Error("Unexpected tracing config (-want, +got):", diff)
 		}
 
 		// Default config.
-		want, _ := pkgtracing.NewTracingConfigFromConfigMap(&corev1.ConfigMap{Data: map[string]string{}})
-		got, err := pkgtracing.NewTracingConfigFromConfigMap(tracingConfigExample)
+		want, _ := tracing.NewConfigFromConfigMap(&corev1.ConfigMap{Data: map[string]string{}})
+		got, err := tracing.NewConfigFromConfigMap(tracingConfigExample)
 		if err != nil {
 			t.Fatal("Error parsing example tracing config:", err)
 		}
@@ -21,12 +21,14 @@ limitations under the License.
 package config
 
 import (
+	v1 "k8s.io/api/core/v1"
 	pkg "knative.dev/networking/pkg"
 	logging "knative.dev/pkg/logging"
 	metrics "knative.dev/pkg/metrics"
-	tracingconfig "knative.dev/pkg/tracing/config"
 	apisconfig "knative.dev/serving/pkg/apis/config"
 	deployment "knative.dev/serving/pkg/deployment"
+	networking "knative.dev/serving/pkg/networking"
+	tracing "knative.dev/serving/pkg/tracing"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -52,6 +54,11 @@ func (in *Config) DeepCopyInto(out *Config) {
 		*out = new(pkg.Config)
 		**out = **in
 	}
+	if in.Networking != nil {
+		in, out := &in.Networking, &out.Networking
+		*out = new(networking.Config)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Observability != nil {
 		in, out := &in.Observability, &out.Observability
 		*out = new(metrics.ObservabilityConfig)
@@ -59,9 +66,21 @@ func (in *Config) DeepCopyInto(out *Config) {
 	}
 	if in.Tracing != nil {
 		in, out := &in.Tracing, &out.Tracing
-		*out = new(tracingconfig.Config)
+		*out = new(tracing.Config)
 		**out = **in
 	}
+	if in.RequestLogTemplates != nil {
+		in, out := &in.RequestLogTemplates, &out.RequestLogTemplates
+		*out = make(RequestLogTemplates, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LogSidecar != nil {
+		in, out := &in.LogSidecar, &out.LogSidecar
+		*out = new(v1.Container)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -74,3 +93,25 @@ func (in *Config) DeepCopy() *Config {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in RequestLogTemplates) DeepCopyInto(out *RequestLogTemplates) {
+	{
+		in := &in
+		*out = make(RequestLogTemplates, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+		return
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RequestLogTemplates.
+func (in RequestLogTemplates) DeepCopy() RequestLogTemplates {
+	if in == nil {
+		return nil
+	}
+	out := new(RequestLogTemplates)
+	in.DeepCopyInto(out)
+	return *out
+}
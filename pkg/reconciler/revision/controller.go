@@ -29,8 +29,11 @@ import (
 	revisioninformer "knative.dev/serving/pkg/client/injection/informers/serving/v1/revision"
 	revisionreconciler "knative.dev/serving/pkg/client/injection/reconciler/serving/v1/revision"
 
+	"go.uber.org/zap"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/apimachinery/pkg/util/clock"
 	"k8s.io/client-go/tools/cache"
 	network "knative.dev/networking/pkg"
 	"knative.dev/pkg/configmap"
@@ -51,6 +54,10 @@ const controllerAgentName = "revision-controller"
 // resolution's Transport will also be set to this value.
 const digestResolutionWorkers = 100
 
+// digestResolutionCacheSize is the number of resolved image digests cached
+// for deployment.Config.DigestResolutionCacheTTL.
+const digestResolutionCacheSize = 1000
+
 // NewController initializes the controller and is called by the generated code
 // Registers eventhandlers to enqueue events
 func NewController(
@@ -74,6 +81,18 @@ func newControllerWithOptions(
 	imageInformer := imageinformer.Get(ctx)
 	paInformer := painformer.Get(ctx)
 
+	transport := http.DefaultTransport
+	if rt, err := newResolverTransport([]string{k8sCertPath}, digestResolutionWorkers, digestResolutionWorkers); err != nil {
+		logging.FromContext(ctx).Error("Failed to create resolver transport: ", err)
+	} else {
+		transport = rt
+	}
+	digestResolver := &digestResolver{
+		client:    kubeclient.Get(ctx),
+		cache:     utilcache.NewLRUExpireCache(digestResolutionCacheSize),
+		transport: transport,
+	}
+
 	c := &Reconciler{
 		kubeclient:    kubeclient.Get(ctx),
 		client:        servingclient.Get(ctx),
@@ -90,6 +109,7 @@ func newControllerWithOptions(
 			&metrics.ObservabilityConfig{},
 			&deployment.Config{},
 			&apisconfig.Defaults{},
+			&apisconfig.Features{},
 		}
 
 		resync := configmap.TypeFilter(configsToResync...)(func(string, interface{}) {
@@ -98,21 +118,25 @@ func newControllerWithOptions(
 			impl.GlobalResync(revisionInformer.Informer())
 		})
 
-		configStore := config.NewStore(logger.Named("config-store"), resync)
+		configureResolver := configmap.TypeFilter(&deployment.Config{})(func(_ string, value interface{}) {
+			if err := digestResolver.configure(value.(*deployment.Config), k8sCertPath); err != nil {
+				logger.Errorw("Failed to configure the digest resolution", zap.Error(err))
+			}
+		})
+
+		configStore := config.NewStore(logger.Named("config-store"), resync, configureResolver)
 		configStore.WatchConfigs(cmw)
 		return controller.Options{ConfigStore: configStore}
 	})
 
-	transport := http.DefaultTransport
-	if rt, err := newResolverTransport(k8sCertPath, digestResolutionWorkers, digestResolutionWorkers); err != nil {
-		logging.FromContext(ctx).Error("Failed to create resolver transport: ", err)
-	} else {
-		transport = rt
-	}
-
-	resolver := newBackgroundResolver(logger, &digestResolver{client: kubeclient.Get(ctx), transport: transport}, impl.EnqueueKey)
+	resolver := newBackgroundResolver(logger, digestResolver, impl.EnqueueKey)
 	resolver.Start(ctx.Done(), digestResolutionWorkers)
 	c.resolver = resolver
+	c.imageResolver = digestResolver
+	c.requeuer = servingreconciler.NewRequeuer(impl.EnqueueAfter)
+	recommender := newRecommender(&metricsServerLister{client: kubeclient.Get(ctx).Discovery().RESTClient()}, clock.RealClock{})
+	c.recommender = recommender
+	c.enqueueAfter = impl.EnqueueAfter
 
 	// Set up an event handler for when the resource types of interest change
 	logger.Info("Setting up event handlers")
@@ -120,7 +144,9 @@ func newControllerWithOptions(
 	revisionInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		DeleteFunc: func(obj interface{}) {
 			if om, ok := obj.(metav1.Object); ok {
-				resolver.Clear(types.NamespacedName{Namespace: om.GetNamespace(), Name: om.GetName()})
+				name := types.NamespacedName{Namespace: om.GetNamespace(), Name: om.GetName()}
+				resolver.Clear(name)
+				recommender.Clear(name)
 			}
 		},
 	})
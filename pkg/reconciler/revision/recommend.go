@@ -0,0 +1,208 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package revision
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/rest"
+	"knative.dev/pkg/logging"
+	apiconfig "knative.dev/serving/pkg/apis/config"
+	"knative.dev/serving/pkg/apis/serving"
+	v1 "knative.dev/serving/pkg/apis/serving/v1"
+	"knative.dev/serving/pkg/reconciler/revision/config"
+)
+
+const (
+	// recommendationInterval is how often the usage of the pods of a revision
+	// is sampled for its resource recommendations.
+	recommendationInterval = 5 * time.Minute
+
+	// recommendationHeadroomPercent is added on top of the peak usage to
+	// recommend the requests.
+	recommendationHeadroomPercent = 15
+)
+
+// usageLister lists the resource usage of the containers of pods.
+type usageLister interface {
+	// List returns the peak usage of the containers of the pods of the
+	// namespace matching the selector, keyed by container name.
+	List(ctx context.Context, namespace string, selector labels.Selector) (map[string]corev1.ResourceList, error)
+}
+
+// podMetricsList is the subset of the metrics.k8s.io PodMetricsList
+// the recommendations need.
+type podMetricsList struct {
+	Items []struct {
+		Containers []struct {
+			Name  string              `json:"name"`
+			Usage corev1.ResourceList `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+// metricsServerLister reads the resource usage of the pods from the metrics
+// API, as served by the metrics-server.
+type metricsServerLister struct {
+	// client must talk to the API server, e.g. the discovery REST client.
+	client rest.Interface
+}
+
+// List implements usageLister.
+func (l *metricsServerLister) List(ctx context.Context, namespace string, selector labels.Selector) (map[string]corev1.ResourceList, error) {
+	raw, err := l.client.Get().
+		AbsPath("/apis/metrics.k8s.io/v1beta1/namespaces", namespace, "pods").
+		Param("labelSelector", selector.String()).
+		DoRaw(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the pod metrics: %w", err)
+	}
+	var list podMetricsList
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse the pod metrics: %w", err)
+	}
+	ret := make(map[string]corev1.ResourceList)
+	for _, pod := range list.Items {
+		for _, c := range pod.Containers {
+			ret[c.Name] = maxResources(ret[c.Name], c.Usage)
+		}
+	}
+	return ret, nil
+}
+
+// recommender samples the usage of the pods of the revisions at most every
+// recommendationInterval, to recommend the requests of their containers.
+type recommender struct {
+	lister usageLister
+	clock  clock.Clock
+
+	mu      sync.Mutex
+	sampled map[types.NamespacedName]time.Time
+}
+
+func newRecommender(lister usageLister, clock clock.Clock) *recommender {
+	return &recommender{
+		lister:  lister,
+		clock:   clock,
+		sampled: make(map[types.NamespacedName]time.Time),
+	}
+}
+
+// due returns how long until the revision is due for its next sample, if
+// not already.
+func (r *recommender) due(name types.NamespacedName) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	last, ok := r.sampled[name]
+	if !ok {
+		return 0
+	}
+	return last.Add(recommendationInterval).Sub(r.clock.Now())
+}
+
+func (r *recommender) markSampled(name types.NamespacedName) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sampled[name] = r.clock.Now()
+}
+
+// Clear forgets the revision, once deleted.
+func (r *recommender) Clear(name types.NamespacedName) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sampled, name)
+}
+
+// reconcileRecommendations updates the recommended requests of the containers
+// of the revision with the usage of its pods, when the resource-recommendations
+// feature is enabled, and schedules the next sample while the revision has pods.
+// The recommendations are informational, so the failures to sample the usage
+// do not fail the reconciliation.
+func (c *Reconciler) reconcileRecommendations(ctx context.Context, rev *v1.Revision) {
+	cfgs := config.FromContext(ctx)
+	if c.recommender == nil || cfgs.Features == nil || cfgs.Features.ResourceRecommendations != apiconfig.Enabled {
+		return
+	}
+	name := types.NamespacedName{Namespace: rev.Namespace, Name: rev.Name}
+	if wait := c.recommender.due(name); wait > 0 {
+		return
+	}
+
+	selector := labels.SelectorFromSet(labels.Set{serving.RevisionUID: string(rev.UID)})
+	usage, err := c.recommender.lister.List(ctx, rev.Namespace, selector)
+	if err != nil {
+		logging.FromContext(ctx).Warnw("Failed to sample the resource usage", zap.Error(err))
+		return
+	}
+	c.recommender.markSampled(name)
+	if len(usage) == 0 {
+		// Scaled to zero, the revision is enqueued again once it has pods.
+		return
+	}
+	for i := range rev.Status.ContainerStatuses {
+		cs := &rev.Status.ContainerStatuses[i]
+		if u, ok := usage[cs.Name]; ok {
+			cs.RecommendedRequests = maxResources(cs.RecommendedRequests, withHeadroom(u))
+		}
+	}
+	if c.enqueueAfter != nil {
+		c.enqueueAfter(rev, recommendationInterval)
+	}
+}
+
+// withHeadroom returns the cpu and memory of usage, increased by
+// recommendationHeadroomPercent and rounded up to whole millicores and MiB.
+func withHeadroom(usage corev1.ResourceList) corev1.ResourceList {
+	ret := corev1.ResourceList{}
+	if q, ok := usage[corev1.ResourceCPU]; ok {
+		m := ceilDiv(q.MilliValue()*(100+recommendationHeadroomPercent), 100)
+		ret[corev1.ResourceCPU] = *resource.NewMilliQuantity(m, resource.DecimalSI)
+	}
+	if q, ok := usage[corev1.ResourceMemory]; ok {
+		mi := ceilDiv(ceilDiv(q.Value()*(100+recommendationHeadroomPercent), 100), 1<<20)
+		ret[corev1.ResourceMemory] = *resource.NewQuantity(mi<<20, resource.BinarySI)
+	}
+	return ret
+}
+
+// maxResources returns the highest quantity of each resource of a and b.
+func maxResources(a, b corev1.ResourceList) corev1.ResourceList {
+	ret := make(corev1.ResourceList, len(a))
+	for k, v := range a {
+		ret[k] = v
+	}
+	for k, v := range b {
+		if q, ok := ret[k]; !ok || v.Cmp(q) > 0 {
+			ret[k] = v
+		}
+	}
+	return ret
+}
+
+func ceilDiv(a, b int64) int64 {
+	return (a + b - 1) / b
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package revision
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/client-go/rest"
+	apiconfig "knative.dev/serving/pkg/apis/config"
+	"knative.dev/serving/pkg/apis/serving"
+	v1 "knative.dev/serving/pkg/apis/serving/v1"
+	"knative.dev/serving/pkg/reconciler/revision/config"
+)
+
+const testPodMetrics = `{
+  "kind": "PodMetricsList",
+  "apiVersion": "metrics.k8s.io/v1beta1",
+  "items": [{
+    "metadata": {"name": "pod-1"},
+    "containers": [
+      {"name": "user-container", "usage": {"cpu": "100m", "memory": "64Mi"}},
+      {"name": "queue-proxy", "usage": {"cpu": "10m", "memory": "20Mi"}}
+    ]
+  }, {
+    "metadata": {"name": "pod-2"},
+    "containers": [
+      {"name": "user-container", "usage": {"cpu": "50m", "memory": "128Mi"}},
+      {"name": "queue-proxy", "usage": {"cpu": "5m", "memory": "20Mi"}}
+    ]
+  }]
+}`
+
+func TestMetricsServerLister(t *testing.T) {
+	var gotPath, gotSelector string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotSelector = r.URL.Query().Get("labelSelector")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(testPodMetrics))
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	client, err := rest.NewRESTClient(u, "", rest.ClientContentConfig{}, nil, srv.Client())
+	if err != nil {
+		t.Fatal("NewRESTClient() =", err)
+	}
+
+	l := &metricsServerLister{client: client}
+	got, err := l.List(context.Background(), "ns", labels.SelectorFromSet(labels.Set{serving.RevisionUID: "uid"}))
+	if err != nil {
+		t.Fatal("List() =", err)
+	}
+	if want := "/apis/metrics.k8s.io/v1beta1/namespaces/ns/pods"; gotPath != want {
+		t.Errorf("Path = %q, want: %q", gotPath, want)
+	}
+	if want := serving.RevisionUID + "=uid"; gotSelector != want {
+		t.Errorf("labelSelector = %q, want: %q", gotSelector, want)
+	}
+	want := map[string]corev1.ResourceList{
+		"user-container": {
+			corev1.ResourceCPU:    resource.MustParse("100m"),
+			corev1.ResourceMemory: resource.MustParse("128Mi"),
+		},
+		"queue-proxy": {
+			corev1.ResourceCPU:    resource.MustParse("10m"),
+			corev1.ResourceMemory: resource.MustParse("20Mi"),
+		},
+	}
+	if !cmp.Equal(got, want, cmp.Comparer(func(a, b resource.Quantity) bool { return a.Cmp(b) == 0 })) {
+		t.Errorf("List() = %v, want: %v", got, want)
+	}
+}
+
+type usageFunc func() (map[string]corev1.ResourceList, error)
+
+func (f usageFunc) List(context.Context, string, labels.Selector) (map[string]corev1.ResourceList, error) {
+	return f()
+}
+
+func TestReconcileRecommendations(t *testing.T) {
+	var (
+		usage   map[string]corev1.ResourceList
+		listErr error
+		lists   int
+		enqueue []time.Duration
+	)
+	clk := clock.NewFakeClock(time.Now())
+	c := &Reconciler{
+		recommender: newRecommender(usageFunc(func() (map[string]corev1.ResourceList, error) {
+			lists++
+			return usage, listErr
+		}), clk),
+		enqueueAfter: func(_ interface{}, d time.Duration) {
+			enqueue = append(enqueue, d)
+		},
+	}
+	cfg := ReconcilerTestConfig()
+	cfg.Features = &apiconfig.Features{ResourceRecommendations: apiconfig.Enabled}
+	ctx := config.ToContext(context.Background(), cfg)
+
+	rev := &v1.Revision{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "rev", UID: "uid"},
+		Status: v1.RevisionStatus{
+			ContainerStatuses: []v1.ContainerStatus{{Name: "user-container"}},
+		},
+	}
+	recommended := func() corev1.ResourceList {
+		return rev.Status.ContainerStatuses[0].RecommendedRequests
+	}
+	equal := func(a, b corev1.ResourceList) bool {
+		return cmp.Equal(a, b, cmp.Comparer(func(a, b resource.Quantity) bool { return a.Cmp(b) == 0 }))
+	}
+
+	usage = map[string]corev1.ResourceList{
+		"user-container": {
+			corev1.ResourceCPU:    resource.MustParse("100m"),
+			corev1.ResourceMemory: resource.MustParse("100Mi"),
+		},
+		"queue-proxy": {
+			corev1.ResourceCPU: resource.MustParse("10m"),
+		},
+	}
+	c.reconcileRecommendations(ctx, rev)
+	want := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("115m"),
+		corev1.ResourceMemory: resource.MustParse("115Mi"),
+	}
+	if got := recommended(); !equal(got, want) {
+		t.Errorf("RecommendedRequests = %v, want: %v", got, want)
+	}
+	if !cmp.Equal(enqueue, []time.Duration{recommendationInterval}) {
+		t.Errorf("enqueueAfter = %v, want: %v", enqueue, recommendationInterval)
+	}
+
+	// Not due yet.
+	c.reconcileRecommendations(ctx, rev)
+	if lists != 1 {
+		t.Errorf("List() calls = %d, want: 1", lists)
+	}
+
+	// The peak usage is kept.
+	clk.Step(recommendationInterval)
+	usage = map[string]corev1.ResourceList{
+		"user-container": {
+			corev1.ResourceCPU:    resource.MustParse("1"),
+			corev1.ResourceMemory: resource.MustParse("10Mi"),
+		},
+	}
+	c.reconcileRecommendations(ctx, rev)
+	want = corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("1150m"),
+		corev1.ResourceMemory: resource.MustParse("115Mi"),
+	}
+	if got := recommended(); !equal(got, want) {
+		t.Errorf("RecommendedRequests = %v, want: %v", got, want)
+	}
+
+	// The failures are retried on the next reconciliation.
+	clk.Step(recommendationInterval)
+	listErr = errors.New("metrics-server unavailable")
+	c.reconcileRecommendations(ctx, rev)
+	listErr = nil
+	c.reconcileRecommendations(ctx, rev)
+	if lists != 4 {
+		t.Errorf("List() calls = %d, want: 4", lists)
+	}
+	if got := recommended(); !equal(got, want) {
+		t.Errorf("RecommendedRequests = %v, want: %v", got, want)
+	}
+
+	// Disabled.
+	clk.Step(recommendationInterval)
+	cfg.Features.ResourceRecommendations = apiconfig.Disabled
+	c.reconcileRecommendations(ctx, rev)
+	if lists != 4 {
+		t.Errorf("List() calls = %d, want: 4", lists)
+	}
+}
@@ -19,7 +19,10 @@ package revision
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"strings"
 
+	"github.com/google/go-containerregistry/pkg/name"
 	"go.uber.org/zap"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -28,11 +31,15 @@ import (
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"knative.dev/pkg/controller"
 	"knative.dev/pkg/kmeta"
 	"knative.dev/pkg/kmp"
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/logging/logkey"
+	apiconfig "knative.dev/serving/pkg/apis/config"
+	"knative.dev/serving/pkg/apis/serving"
 	v1 "knative.dev/serving/pkg/apis/serving/v1"
+	"knative.dev/serving/pkg/reconciler/revision/config"
 	"knative.dev/serving/pkg/reconciler/revision/resources"
 	resourcenames "knative.dev/serving/pkg/reconciler/revision/resources/names"
 )
@@ -74,6 +81,11 @@ func (c *Reconciler) reconcileDeployment(ctx context.Context, rev *v1.Revision)
 		// or down.
 		if !rev.Status.IsActivationRequired() {
 			rev.Status.PropagateDeploymentStatus(&deployment.Status)
+			if cond := replicaFailure(deployment); cond != nil {
+				if reason, message, ok := classifyFailure(cond.Message); ok {
+					rev.Status.MarkResourcesAvailableFalse(reason, message)
+				}
+			}
 		}
 	}
 
@@ -92,21 +104,35 @@ func (c *Reconciler) reconcileDeployment(ctx context.Context, rev *v1.Revision)
 			// If pod cannot be scheduled then we expect the container status to be empty.
 			for _, cond := range pod.Status.Conditions {
 				if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse {
-					rev.Status.MarkResourcesAvailableFalse(cond.Reason, cond.Message)
+					if reason, message, ok := classifyFailure(cond.Message); ok {
+						rev.Status.MarkResourcesAvailableFalse(reason, message)
+					} else {
+						rev.Status.MarkResourcesAvailableFalse(cond.Reason, cond.Message)
+					}
 					break
 				}
 			}
 
-			for _, status := range pod.Status.ContainerStatuses {
-				if status.Name == rev.Spec.GetContainer().Name {
-					if t := status.LastTerminationState.Terminated; t != nil {
-						logger.Infof("marking exiting with: %d/%s", t.ExitCode, t.Message)
-						rev.Status.MarkContainerHealthyFalse(v1.ExitCodeReason(t.ExitCode), v1.RevisionContainerExitingMessage(t.Message))
-					} else if w := status.State.Waiting; w != nil && hasDeploymentTimedOut(deployment) {
-						logger.Infof("marking resources unavailable with: %s: %s", w.Reason, w.Message)
-						rev.Status.MarkResourcesAvailableFalse(w.Reason, w.Message)
+			terminations := lastTerminations(pods.Items)
+			if reason, message := terminationsMessage(rev, terminations); message != "" {
+				logger.Info("marking exiting with: ", message)
+				rev.Status.MarkContainerHealthyFalse(reason, message)
+			} else {
+				for _, status := range pod.Status.ContainerStatuses {
+					if status.Name == rev.Spec.GetContainer().Name {
+						if w := status.State.Waiting; w != nil && hasDeploymentTimedOut(deployment) {
+							logger.Infof("marking resources unavailable with: %s: %s", w.Reason, w.Message)
+							rev.Status.MarkResourcesAvailableFalse(w.Reason, w.Message)
+						}
+						break
+					}
+				}
+			}
+			for _, statuses := range [][]v1.ContainerStatus{rev.Status.ContainerStatuses, rev.Status.InitContainerStatuses} {
+				for i := range statuses {
+					if t, ok := terminations[statuses[i].Name]; ok {
+						statuses[i].LastTerminationReason = t.Reason
 					}
-					break
 				}
 			}
 		}
@@ -121,7 +147,10 @@ func (c *Reconciler) reconcileImageCache(ctx context.Context, rev *v1.Revision)
 	ns := rev.Namespace
 	// Revisions are immutable.
 	// Updating image results to new revision so there won't be any chance of resource leak.
-	for _, container := range rev.Status.ContainerStatuses {
+	statuses := make([]v1.ContainerStatus, 0, len(rev.Status.ContainerStatuses)+len(rev.Status.InitContainerStatuses))
+	statuses = append(statuses, rev.Status.ContainerStatuses...)
+	statuses = append(statuses, rev.Status.InitContainerStatuses...)
+	for _, container := range statuses {
 		imageName := kmeta.ChildName(resourcenames.ImageCache(rev), "-"+container.Name)
 		if _, err := c.imageLister.Images(ns).Get(imageName); apierrs.IsNotFound(err) {
 			if _, err := c.createImageCache(ctx, rev, container.Name, container.ImageDigest); err != nil {
@@ -177,6 +206,268 @@ func (c *Reconciler) reconcilePA(ctx context.Context, rev *v1.Revision) error {
 	return nil
 }
 
+func (c *Reconciler) reconcilePodDisruptionBudget(ctx context.Context, rev *v1.Revision) error {
+	cfgs := config.FromContext(ctx)
+	// Without a configured budget we don't look for the existing ones, which
+	// are garbage collected with their revisions.
+	if cfgs.Deployment.PodDisruptionBudgetMaxUnavailable == nil {
+		return nil
+	}
+
+	ns := rev.Namespace
+	pdbName := resourcenames.PodDisruptionBudget(rev)
+	logger := logging.FromContext(ctx)
+
+	want := resources.MakePodDisruptionBudget(rev, cfgs)
+	pdb, err := c.kubeclient.PolicyV1beta1().PodDisruptionBudgets(ns).Get(ctx, pdbName, metav1.GetOptions{})
+	switch {
+	case apierrs.IsNotFound(err):
+		if want == nil {
+			return nil
+		}
+		if _, err := c.kubeclient.PolicyV1beta1().PodDisruptionBudgets(ns).Create(ctx, want, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create PodDisruptionBudget %q: %w", pdbName, err)
+		}
+		logger.Info("Created PodDisruptionBudget: ", pdbName)
+	case err != nil:
+		return fmt.Errorf("failed to get PodDisruptionBudget %q: %w", pdbName, err)
+	case !metav1.IsControlledBy(pdb, rev):
+		if want == nil {
+			return nil
+		}
+		// Surface an error in the revision's status, and return an error.
+		rev.Status.MarkResourcesAvailableFalse(v1.ReasonNotOwned, v1.ResourceNotOwnedMessage("PodDisruptionBudget", pdbName))
+		return fmt.Errorf("revision: %q does not own PodDisruptionBudget: %q", rev.Name, pdbName)
+	case want == nil:
+		// The minScale of the revision dropped below 2.
+		if err := c.kubeclient.PolicyV1beta1().PodDisruptionBudgets(ns).Delete(ctx, pdbName, metav1.DeleteOptions{}); err != nil && !apierrs.IsNotFound(err) {
+			return fmt.Errorf("failed to delete PodDisruptionBudget %q: %w", pdbName, err)
+		}
+		logger.Info("Deleted PodDisruptionBudget: ", pdbName)
+	case !equality.Semantic.DeepEqual(want.Spec, pdb.Spec):
+		desired := pdb.DeepCopy()
+		desired.Spec = want.Spec
+		if _, err := c.kubeclient.PolicyV1beta1().PodDisruptionBudgets(ns).Update(ctx, desired, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update PodDisruptionBudget %q: %w", pdbName, err)
+		}
+		logger.Info("Updated PodDisruptionBudget: ", pdbName)
+	}
+	return nil
+}
+
+// debugContainerName is the name of the ephemeral debug container attached
+// to the pods of the revisions requesting one.
+const debugContainerName = "knative-debugger"
+
+func (c *Reconciler) reconcileDebugContainer(ctx context.Context, rev *v1.Revision) error {
+	podName, image, ok := rev.DebugContainer()
+	if !ok || config.FromContext(ctx).Features.DebugContainers != apiconfig.Enabled {
+		return nil
+	}
+
+	ns := rev.Namespace
+	logger := logging.FromContext(ctx)
+
+	pod, err := c.kubeclient.CoreV1().Pods(ns).Get(ctx, podName, metav1.GetOptions{})
+	if apierrs.IsNotFound(err) {
+		// The pod may have been scaled down or replaced since.
+		logger.Infof("Pod %q to debug does not exist", podName)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to get pod %q: %w", podName, err)
+	}
+	if pod.Labels[serving.RevisionLabelKey] != rev.Name {
+		logger.Warnf("Pod %q to debug does not belong to the revision", podName)
+		return nil
+	}
+	for _, ec := range pod.Spec.EphemeralContainers {
+		if ec.Name == debugContainerName {
+			// Ephemeral containers can't be changed or removed once attached.
+			return nil
+		}
+	}
+
+	image, err = c.resolveDebugImage(ctx, rev, image)
+	if err != nil {
+		return err
+	}
+
+	// Containers can only be added to a running pod through its
+	// ephemeralcontainers subresource.
+	ecs := &corev1.EphemeralContainers{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            pod.Name,
+			Namespace:       pod.Namespace,
+			ResourceVersion: pod.ResourceVersion,
+		},
+		EphemeralContainers: append(pod.Spec.EphemeralContainers, corev1.EphemeralContainer{
+			EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+				Name:                     debugContainerName,
+				Image:                    image,
+				ImagePullPolicy:          corev1.PullIfNotPresent,
+				TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+				Stdin:                    true,
+				TTY:                      true,
+			},
+			TargetContainerName: rev.Spec.GetContainer().Name,
+		}),
+	}
+	if _, err := c.kubeclient.CoreV1().Pods(ns).UpdateEphemeralContainers(ctx, podName, ecs, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to attach debug container to pod %q: %w", podName, err)
+	}
+	logger.Infof("Attached debug container to pod %q", podName)
+	return nil
+}
+
+// resolveDebugImage returns the debug image resolved to a digest, once it is
+// allowed and its signature is verified like those of the containers of the
+// revision. The debug container is not attached otherwise.
+func (c *Reconciler) resolveDebugImage(ctx context.Context, rev *v1.Revision, image string) (string, error) {
+	cfgs := config.FromContext(ctx)
+	ref, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return "", controller.NewPermanentError(fmt.Errorf("failed to parse debug image %q: %w", image, err))
+	}
+	if !serving.IsImageRepositoryAllowed(cfgs.Features.ImageRegistriesAllowlist, ref) {
+		return "", controller.NewPermanentError(fmt.Errorf("debug image repository %q is not allowed", ref.Context().Name()))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfgs.Deployment.DigestResolutionTimeout)
+	defer cancel()
+	digest, err := c.imageResolver.Resolve(ctx, image, pullOptions(rev),
+		registriesSkippingTagResolving(rev, cfgs.Deployment.RegistriesSkippingTagResolving), maxImageSize(cfgs))
+	if isSignatureError(err) {
+		return "", controller.NewPermanentError(fmt.Errorf("failed to verify debug image %q: %w", image, err))
+	} else if err != nil {
+		return "", fmt.Errorf("failed to resolve debug image %q: %w", image, err)
+	}
+	if digest == "" {
+		// Like for the containers of the revision, the image is used as is.
+		return image, nil
+	}
+	return digest, nil
+}
+
+// containerTermination is the latest termination of a container across the
+// pods of a revision.
+type containerTermination struct {
+	corev1.ContainerStateTerminated
+	// Restarts is the highest restart count of the container across the pods.
+	Restarts int32
+}
+
+// lastTerminations returns the latest termination of each container, init
+// containers included, across the pods.
+func lastTerminations(pods []corev1.Pod) map[string]containerTermination {
+	ret := make(map[string]containerTermination)
+	for i := range pods {
+		for _, statuses := range [][]corev1.ContainerStatus{pods[i].Status.InitContainerStatuses, pods[i].Status.ContainerStatuses} {
+			for _, status := range statuses {
+				t := status.LastTerminationState.Terminated
+				if t == nil {
+					continue
+				}
+				prev, ok := ret[status.Name]
+				if !ok || prev.FinishedAt.Before(&t.FinishedAt) {
+					prev.ContainerStateTerminated = *t
+				}
+				if status.RestartCount > prev.Restarts {
+					prev.Restarts = status.RestartCount
+				}
+				ret[status.Name] = prev
+			}
+		}
+	}
+	return ret
+}
+
+// terminationsMessage returns the reason and the message of the terminations
+// of the containers of the revision, reporting the serving container first,
+// or an empty message if none of them terminated.
+func terminationsMessage(rev *v1.Revision, terminations map[string]containerTermination) (string, string) {
+	names := []string{rev.Spec.GetContainer().Name}
+	for _, c := range rev.Spec.Containers {
+		if c.Name != names[0] {
+			names = append(names, c.Name)
+		}
+	}
+	for _, c := range rev.Spec.InitContainers {
+		names = append(names, c.Name)
+	}
+
+	var reason string
+	var messages []string
+	for _, name := range names {
+		t, ok := terminations[name]
+		if !ok {
+			continue
+		}
+		if reason == "" {
+			reason = v1.ExitCodeReason(t.ExitCode)
+		}
+		messages = append(messages, v1.RevisionContainerTerminatedMessage(name, t.Reason, t.ExitCode, t.Restarts, t.Message))
+	}
+	return reason, strings.Join(messages, "; ")
+}
+
+var (
+	// quotaExceededRegexp matches the errors of the ResourceQuota admission,
+	// e.g. `exceeded quota: compute, requested: limits.cpu=2,limits.memory=1Gi, used: ...`.
+	quotaExceededRegexp = regexp.MustCompile(`exceeded quota: ([^,]+), requested: (\S+),`)
+
+	// insufficientRegexp matches the resources in the scheduling errors,
+	// e.g. `0/3 nodes are available: 3 Insufficient cpu, 1 Insufficient nvidia.com/gpu.`.
+	insufficientRegexp = regexp.MustCompile(`Insufficient ([^\s,]+?)[,.]?(\s|$)`)
+
+	// policyErrors are the fragments of the errors of the pods rejected by the
+	// PodSecurityPolicy and LimitRange admissions, or by admission webhooks.
+	policyErrors = []string{
+		"unable to validate against any pod security policy",
+		"admission webhook",
+		"usage per Container is",
+		"usage per Pod is",
+		"ratio per Container is",
+		"ratio per Pod is",
+	}
+)
+
+// replicaFailure returns the ReplicaFailure condition of the deployment, if
+// it failed to create pods.
+func replicaFailure(deployment *appsv1.Deployment) *appsv1.DeploymentCondition {
+	for i, cond := range deployment.Status.Conditions {
+		if cond.Type == appsv1.DeploymentReplicaFailure && cond.Status == corev1.ConditionTrue {
+			return &deployment.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// classifyFailure maps the error of the creation or the scheduling of the
+// revision pods to an actionable reason and message, naming the resources
+// which were short, if it recognizes it.
+func classifyFailure(message string) (string, string, bool) {
+	if m := quotaExceededRegexp.FindStringSubmatch(message); m != nil {
+		var resources []string
+		for _, kv := range strings.Split(m[2], ",") {
+			resources = append(resources, strings.SplitN(kv, "=", 2)[0])
+		}
+		return v1.ReasonQuotaExceeded, v1.RevisionQuotaExceededMessage(m[1], resources, message), true
+	}
+	if ms := insufficientRegexp.FindAllStringSubmatch(message, -1); ms != nil {
+		resources := make([]string, 0, len(ms))
+		for _, m := range ms {
+			resources = append(resources, m[1])
+		}
+		return v1.ReasonExceedsNodeAllocatable, v1.RevisionExceedsNodeAllocatableMessage(resources, message), true
+	}
+	for _, fragment := range policyErrors {
+		if strings.Contains(message, fragment) {
+			return v1.ReasonBlockedByPolicy, v1.RevisionBlockedByPolicyMessage(message), true
+		}
+	}
+	return "", "", false
+}
+
 func hasDeploymentTimedOut(deployment *appsv1.Deployment) bool {
 	// as per https://kubernetes.io/docs/concepts/workloads/controllers/deployment
 	for _, cond := range deployment.Status.Conditions {
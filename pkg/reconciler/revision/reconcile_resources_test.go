@@ -0,0 +1,326 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package revision
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/authn/k8schain"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	fakek8s "k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
+
+	"knative.dev/serving/pkg/apis/autoscaling"
+	apiconfig "knative.dev/serving/pkg/apis/config"
+	"knative.dev/serving/pkg/apis/serving"
+	v1 "knative.dev/serving/pkg/apis/serving/v1"
+	"knative.dev/serving/pkg/reconciler/revision/config"
+	"knative.dev/serving/pkg/reconciler/revision/resources"
+	"knative.dev/serving/pkg/reconciler/revision/resources/names"
+)
+
+func TestReconcilePodDisruptionBudget(t *testing.T) {
+	one, two := intstr.FromInt(1), intstr.FromInt(2)
+	rev := func(minScale string) *v1.Revision {
+		r := testRevision(testPodSpec())
+		r.Annotations = map[string]string{autoscaling.MinScaleAnnotationKey: minScale}
+		return r
+	}
+	budget := func(r *v1.Revision, maxUnavailable intstr.IntOrString) *policyv1beta1.PodDisruptionBudget {
+		cfg := ReconcilerTestConfig()
+		cfg.Deployment.PodDisruptionBudgetMaxUnavailable = &maxUnavailable
+		return resources.MakePodDisruptionBudget(r, cfg)
+	}
+
+	tests := []struct {
+		name           string
+		rev            *v1.Revision
+		maxUnavailable *intstr.IntOrString
+		existing       *policyv1beta1.PodDisruptionBudget
+		want           *policyv1beta1.PodDisruptionBudget
+		wantErr        bool
+	}{{
+		name: "no budget configured",
+		rev:  rev("3"),
+	}, {
+		name:           "minScale too low",
+		rev:            rev("1"),
+		maxUnavailable: &one,
+	}, {
+		name:           "create",
+		rev:            rev("3"),
+		maxUnavailable: &one,
+		want:           budget(rev("3"), one),
+	}, {
+		name:           "update",
+		rev:            rev("3"),
+		maxUnavailable: &two,
+		existing:       budget(rev("3"), one),
+		want:           budget(rev("3"), two),
+	}, {
+		name:           "delete when minScale dropped",
+		rev:            rev("1"),
+		maxUnavailable: &one,
+		existing:       budget(rev("3"), one),
+	}, {
+		name:           "not owned",
+		rev:            rev("3"),
+		maxUnavailable: &one,
+		existing: func() *policyv1beta1.PodDisruptionBudget {
+			pdb := budget(rev("3"), one)
+			pdb.OwnerReferences = nil
+			return pdb
+		}(),
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kubeClient := fakek8s.NewSimpleClientset()
+			if test.existing != nil {
+				kubeClient = fakek8s.NewSimpleClientset(test.existing)
+			}
+			cfg := ReconcilerTestConfig()
+			cfg.Deployment.PodDisruptionBudgetMaxUnavailable = test.maxUnavailable
+			ctx := config.ToContext(context.Background(), cfg)
+			c := &Reconciler{kubeclient: kubeClient}
+
+			err := c.reconcilePodDisruptionBudget(ctx, test.rev)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("reconcilePodDisruptionBudget() = %v, wantErr %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				if got := test.rev.Status.GetCondition(v1.RevisionConditionResourcesAvailable); got == nil || got.Reason != v1.ReasonNotOwned {
+					t.Errorf("ResourcesAvailable = %v, want reason %s", got, v1.ReasonNotOwned)
+				}
+				return
+			}
+
+			got, err := kubeClient.PolicyV1beta1().PodDisruptionBudgets(test.rev.Namespace).Get(ctx,
+				names.PodDisruptionBudget(test.rev), metav1.GetOptions{})
+			if test.want == nil {
+				if !apierrs.IsNotFound(err) {
+					t.Errorf("PodDisruptionBudget = %v, %v, want none", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("Failed to get PodDisruptionBudget:", err)
+			}
+			if !cmp.Equal(got.Spec, test.want.Spec) {
+				t.Error("PodDisruptionBudget spec (-want, +got) =", cmp.Diff(test.want.Spec, got.Spec))
+			}
+		})
+	}
+}
+
+func TestReconcileDebugContainer(t *testing.T) {
+	rev := func(annotations map[string]string) *v1.Revision {
+		r := testRevision(testPodSpec())
+		r.Annotations = annotations
+		return r
+	}
+	debug := map[string]string{
+		serving.DebugPodAnnotationKey:   "pod",
+		serving.DebugImageAnnotationKey: "busybox",
+	}
+	pod := func(revision string, ecs ...corev1.EphemeralContainer) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testNamespace,
+				Name:      "pod",
+				Labels:    map[string]string{serving.RevisionLabelKey: revision},
+			},
+			Spec: corev1.PodSpec{EphemeralContainers: ecs},
+		}
+	}
+	attached := corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: debugContainerName},
+	}
+
+	const digest = "busybox@sha256:deadbeef"
+	resolved := func(context.Context, string, k8schain.Options, sets.String) (string, error) {
+		return digest, nil
+	}
+
+	tests := []struct {
+		name      string
+		rev       *v1.Revision
+		disabled  bool
+		allowlist sets.String
+		resolver  resolveFunc
+		existing  *corev1.Pod
+		want      []string
+		wantImage string
+		wantErr   bool
+	}{{
+		name:     "no debug container requested",
+		rev:      rev(nil),
+		existing: pod(testRevision(testPodSpec()).Name),
+	}, {
+		name:     "feature disabled",
+		rev:      rev(debug),
+		disabled: true,
+		existing: pod(testRevision(testPodSpec()).Name),
+	}, {
+		name: "pod gone",
+		rev:  rev(debug),
+	}, {
+		name:     "pod of another revision",
+		rev:      rev(debug),
+		existing: pod("another"),
+	}, {
+		name:     "already attached",
+		rev:      rev(debug),
+		existing: pod(testRevision(testPodSpec()).Name, attached),
+	}, {
+		name:      "attach",
+		rev:       rev(debug),
+		resolver:  resolved,
+		existing:  pod(testRevision(testPodSpec()).Name),
+		want:      []string{debugContainerName},
+		wantImage: digest,
+	}, {
+		name: "attach skipping tag resolution",
+		rev:  rev(debug),
+		resolver: func(context.Context, string, k8schain.Options, sets.String) (string, error) {
+			return "", nil
+		},
+		existing:  pod(testRevision(testPodSpec()).Name),
+		want:      []string{debugContainerName},
+		wantImage: "busybox",
+	}, {
+		name:      "image not allowed",
+		rev:       rev(debug),
+		allowlist: sets.NewString("registry.example.com/"),
+		resolver:  resolved,
+		existing:  pod(testRevision(testPodSpec()).Name),
+		wantErr:   true,
+	}, {
+		name: "signature invalid",
+		rev:  rev(debug),
+		resolver: func(context.Context, string, k8schain.Options, sets.String) (string, error) {
+			return "", &signatureError{message: "no signature found"}
+		},
+		existing: pod(testRevision(testPodSpec()).Name),
+		wantErr:  true,
+	}, {
+		name: "resolution fails",
+		rev:  rev(debug),
+		resolver: func(context.Context, string, k8schain.Options, sets.String) (string, error) {
+			return "", errDigest
+		},
+		existing: pod(testRevision(testPodSpec()).Name),
+		wantErr:  true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kubeClient := fakek8s.NewSimpleClientset()
+			if test.existing != nil {
+				kubeClient = fakek8s.NewSimpleClientset(test.existing)
+			}
+			var got []string
+			kubeClient.PrependReactor("update", "pods", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "ephemeralcontainers" {
+					return false, nil, nil
+				}
+				ecs := action.(clientgotesting.UpdateAction).GetObject().(*corev1.EphemeralContainers)
+				for _, ec := range ecs.EphemeralContainers {
+					got = append(got, ec.Name)
+					if ec.Image != test.wantImage || ec.TargetContainerName != test.rev.Spec.GetContainer().Name {
+						t.Errorf("Ephemeral container = %#v, want %s targeting %q", ec, test.wantImage, test.rev.Spec.GetContainer().Name)
+					}
+				}
+				return true, ecs, nil
+			})
+			cfg := ReconcilerTestConfig()
+			cfg.Features = &apiconfig.Features{
+				DebugContainers:          apiconfig.Enabled,
+				ImageRegistriesAllowlist: test.allowlist,
+			}
+			if test.disabled {
+				cfg.Features.DebugContainers = apiconfig.Disabled
+			}
+			ctx := config.ToContext(context.Background(), cfg)
+			c := &Reconciler{kubeclient: kubeClient, imageResolver: test.resolver}
+
+			if err := c.reconcileDebugContainer(ctx, test.rev); (err != nil) != test.wantErr {
+				t.Fatalf("reconcileDebugContainer() = %v, wantErr: %v", err, test.wantErr)
+			}
+			if !cmp.Equal(got, test.want) {
+				t.Error("Attached containers (-want, +got) =", cmp.Diff(test.want, got))
+			}
+		})
+	}
+}
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name        string
+		message     string
+		wantReason  string
+		wantMessage string
+	}{{
+		name:    "unknown",
+		message: "I replica failed!",
+	}, {
+		name:       "quota",
+		message:    `pods "foo-1" is forbidden: exceeded quota: compute, requested: limits.cpu=2,limits.memory=1Gi, used: limits.cpu=3,limits.memory=2Gi, limited: limits.cpu=4,limits.memory=2Gi`,
+		wantReason: v1.ReasonQuotaExceeded,
+		wantMessage: v1.RevisionQuotaExceededMessage("compute", []string{"limits.cpu", "limits.memory"},
+			`pods "foo-1" is forbidden: exceeded quota: compute, requested: limits.cpu=2,limits.memory=1Gi, used: limits.cpu=3,limits.memory=2Gi, limited: limits.cpu=4,limits.memory=2Gi`),
+	}, {
+		name:        "node allocatable",
+		message:     "0/3 nodes are available: 3 Insufficient cpu, 1 Insufficient nvidia.com/gpu.",
+		wantReason:  v1.ReasonExceedsNodeAllocatable,
+		wantMessage: v1.RevisionExceedsNodeAllocatableMessage([]string{"cpu", "nvidia.com/gpu"}, "0/3 nodes are available: 3 Insufficient cpu, 1 Insufficient nvidia.com/gpu."),
+	}, {
+		name:        "pod security policy",
+		message:     `pods "foo-1" is forbidden: unable to validate against any pod security policy: []`,
+		wantReason:  v1.ReasonBlockedByPolicy,
+		wantMessage: v1.RevisionBlockedByPolicyMessage(`pods "foo-1" is forbidden: unable to validate against any pod security policy: []`),
+	}, {
+		name:        "limit range",
+		message:     `pods "foo-1" is forbidden: maximum cpu usage per Container is 1, but limit is 2`,
+		wantReason:  v1.ReasonBlockedByPolicy,
+		wantMessage: v1.RevisionBlockedByPolicyMessage(`pods "foo-1" is forbidden: maximum cpu usage per Container is 1, but limit is 2`),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reason, message, ok := classifyFailure(test.message)
+			if ok != (test.wantReason != "") {
+				t.Fatalf("classifyFailure() = %v, want: %v", ok, test.wantReason != "")
+			}
+			if reason != test.wantReason {
+				t.Errorf("Reason = %q, want: %q", reason, test.wantReason)
+			}
+			if message != test.wantMessage {
+				t.Errorf("Message = %q, want: %q", message, test.wantMessage)
+			}
+		})
+	}
+}
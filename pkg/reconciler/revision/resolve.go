@@ -18,23 +18,82 @@ package revision
 
 import (
 	"context"
+	"crypto"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
+	"sync"
+	"time"
 
 	"github.com/google/go-containerregistry/pkg/authn/k8schain"
 	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"k8s.io/apimachinery/pkg/util/cache"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/kubernetes"
+	"knative.dev/serving/pkg/apis/serving"
+	"knative.dev/serving/pkg/deployment"
 )
 
 type digestResolver struct {
-	client    kubernetes.Interface
+	client kubernetes.Interface
+
+	// cache holds the digests resolved in the last cacheTTL, if set, keyed by
+	// resolveKey.
+	cache *cache.LRUExpireCache
+
+	// mu guards the fields below, which are updated from the config-deployment
+	// ConfigMap, see configure.
+	mu        sync.RWMutex
 	transport http.RoundTripper
+	retries   int
+	backoff   time.Duration
+	cacheTTL  time.Duration
+	// signatureKeys are the public keys the cosign signatures of the resolved
+	// images are verified with, if any, and signatureKeysID identifies them
+	// in the resolveKeys.
+	signatureKeys   []crypto.PublicKey
+	signatureKeysID string
+}
+
+// resolveKey identifies the resolutions of the same image tag with the same
+// credentials, which share their digest.
+type resolveKey struct {
+	image         string
+	credentials   string
+	maxImageSize  int64
+	signatureKeys string
+}
+
+// newResolveKey returns the key of the resolution, hashing the credentials
+// the keychain of the options is made of.
+func newResolveKey(image string, opt k8schain.Options, maxImageSize int64) resolveKey {
+	h := sha256.New()
+	for _, s := range append([]string{opt.Namespace, opt.ServiceAccountName}, opt.ImagePullSecrets...) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return resolveKey{
+		image:        image,
+		credentials:  hex.EncodeToString(h.Sum(nil)),
+		maxImageSize: maxImageSize,
+	}
+}
+
+// imageMetadata is the registry information about a resolved image.
+type imageMetadata struct {
+	// size is the total size in bytes of the config and the layers of the image.
+	size    int64
+	created time.Time
 }
 
 const (
@@ -43,20 +102,22 @@ const (
 	k8sCertPath = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
 )
 
-// newResolverTransport returns an http.Transport that appends the certs bundle
-// at path to the system cert pool.
+// newResolverTransport returns an http.Transport that appends the certs bundles
+// at paths to the system cert pool.
 //
 // Use this with k8sCertPath to trust the same certs as the cluster.
-func newResolverTransport(path string, maxIdleConns, maxIdleConnsPerHost int) (*http.Transport, error) {
+func newResolverTransport(paths []string, maxIdleConns, maxIdleConnsPerHost int) (*http.Transport, error) {
 	pool, err := x509.SystemCertPool()
 	if err != nil {
 		pool = x509.NewCertPool()
 	}
 
-	if crt, err := ioutil.ReadFile(path); err != nil {
-		return nil, err
-	} else if ok := pool.AppendCertsFromPEM(crt); !ok {
-		return nil, errors.New("failed to append k8s cert bundle to cert pool")
+	for _, path := range paths {
+		if crt, err := ioutil.ReadFile(path); err != nil {
+			return nil, err
+		} else if ok := pool.AppendCertsFromPEM(crt); !ok {
+			return nil, fmt.Errorf("failed to append cert bundle %s to cert pool", path)
+		}
 	}
 
 	transport := http.DefaultTransport.(*http.Transport).Clone()
@@ -70,19 +131,70 @@ func newResolverTransport(path string, maxIdleConns, maxIdleConnsPerHost int) (*
 	return transport, nil
 }
 
+// configure updates the transport and the retries of the resolver from the
+// config-deployment ConfigMap. The CA bundle is read at that time, on top of
+// the one at clusterCertPath, and the previous transport is kept if that fails.
+func (r *digestResolver) configure(cfg *deployment.Config, clusterCertPath string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retries = int(cfg.DigestResolutionRetries)
+	r.backoff = cfg.DigestResolutionBackoff
+	r.cacheTTL = cfg.DigestResolutionCacheTTL
+
+	// Validated along with the ConfigMap.
+	r.signatureKeys, _ = deployment.ParseSignaturePublicKeys(cfg.DigestResolutionSignaturePublicKeys)
+	r.signatureKeysID = ""
+	if len(r.signatureKeys) > 0 {
+		sum := sha256.Sum256([]byte(cfg.DigestResolutionSignaturePublicKeys))
+		r.signatureKeysID = hex.EncodeToString(sum[:])
+	}
+
+	paths := []string{clusterCertPath}
+	if cfg.DigestResolutionCABundlePath != "" {
+		paths = append(paths, cfg.DigestResolutionCABundlePath)
+	}
+	transport, err := newResolverTransport(paths, digestResolutionWorkers, digestResolutionWorkers)
+	if err != nil {
+		return err
+	}
+	if cfg.DigestResolutionProxy != "" {
+		// Validated along with the ConfigMap.
+		u, _ := url.Parse(cfg.DigestResolutionProxy)
+		transport.Proxy = http.ProxyURL(u)
+	}
+	r.transport = transport
+	return nil
+}
+
 // Resolve resolves the image references that use tags to digests.
+// If maxImageSize is positive, the resolved image is also checked to not
+// exceed that many bytes. If the resolver has signature public keys, the
+// cosign signature of the resolved image is verified with them, and the
+// registriesToSkip are resolved anyway so that the verified digest is the
+// one deployed.
 func (r *digestResolver) Resolve(
 	ctx context.Context,
 	image string,
 	opt k8schain.Options,
-	registriesToSkip sets.String) (string, error) {
+	registriesToSkip sets.String,
+	maxImageSize int64) (string, error) {
 	kc, err := k8schain.New(ctx, r.client, opt)
 	if err != nil {
 		return "", fmt.Errorf("failed to initialize authentication: %w", err)
 	}
+	r.mu.RLock()
+	rt, retries, backoff, cacheTTL := r.transport, r.retries, r.backoff, r.cacheTTL
+	keys, keysID := r.signatureKeys, r.signatureKeysID
+	r.mu.RUnlock()
+	opts := []remote.Option{remote.WithContext(ctx), remote.WithTransport(rt), remote.WithAuthFromKeychain(kc)}
 
-	if _, err := name.NewDigest(image, name.WeakValidation); err == nil {
+	if digest, err := name.NewDigest(image, name.WeakValidation); err == nil {
 		// Already a digest
+		if err := withRetries(ctx, retries, backoff, func() error {
+			return checkImage(digest, maxImageSize, keys, opts)
+		}); err != nil {
+			return "", err
+		}
 		return image, nil
 	}
 
@@ -91,13 +203,152 @@ func (r *digestResolver) Resolve(
 		return "", fmt.Errorf("failed to parse image name %q into a tag: %w", image, err)
 	}
 
-	if registriesToSkip.Has(tag.Registry.RegistryStr()) {
+	if len(keys) == 0 && (registriesToSkip.Has(tag.Registry.RegistryStr()) || registriesToSkip.Has(serving.AllRegistries)) {
 		return "", nil
 	}
 
-	desc, err := remote.Head(tag, remote.WithContext(ctx), remote.WithTransport(r.transport), remote.WithAuthFromKeychain(kc))
-	if err != nil {
+	key := newResolveKey(image, opt, maxImageSize)
+	key.signatureKeys = keysID
+	if cacheTTL > 0 && r.cache != nil {
+		if digest, ok := r.cache.Get(key); ok {
+			return digest.(string), nil
+		}
+	}
+
+	var resolved string
+	if err := withRetries(ctx, retries, backoff, func() error {
+		desc, err := remote.Head(tag, opts...)
+		if err != nil {
+			return err
+		}
+		if err := checkImage(tag.Repository.Digest(desc.Digest.String()), maxImageSize, keys, opts); err != nil {
+			return err
+		}
+		resolved = fmt.Sprintf("%s@%s", tag.Repository.String(), desc.Digest)
+		return nil
+	}); err != nil {
 		return "", err
 	}
-	return fmt.Sprintf("%s@%s", tag.Repository.String(), desc.Digest), nil
+	if cacheTTL > 0 && r.cache != nil {
+		r.cache.Add(key, resolved, cacheTTL)
+	}
+	return resolved, nil
+}
+
+// VerifiesSignatures returns whether the resolver verifies the signatures of
+// the images, i.e. has signature public keys.
+func (r *digestResolver) VerifiesSignatures() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.signatureKeys) > 0
+}
+
+// Metadata fetches the manifest and the config of the image, which must be
+// a digest, to report its size and creation time.
+func (r *digestResolver) Metadata(ctx context.Context, image string, opt k8schain.Options) (imageMetadata, error) {
+	digest, err := name.NewDigest(image, name.WeakValidation)
+	if err != nil {
+		return imageMetadata{}, fmt.Errorf("failed to parse image name %q into a digest: %w", image, err)
+	}
+	kc, err := k8schain.New(ctx, r.client, opt)
+	if err != nil {
+		return imageMetadata{}, fmt.Errorf("failed to initialize authentication: %w", err)
+	}
+	r.mu.RLock()
+	rt, retries, backoff := r.transport, r.retries, r.backoff
+	r.mu.RUnlock()
+	opts := []remote.Option{remote.WithContext(ctx), remote.WithTransport(rt), remote.WithAuthFromKeychain(kc)}
+
+	var md imageMetadata
+	err = withRetries(ctx, retries, backoff, func() error {
+		img, err := remote.Image(digest, opts...)
+		if err != nil {
+			return err
+		}
+		m, err := img.Manifest()
+		if err != nil {
+			return err
+		}
+		cfg, err := img.ConfigFile()
+		if err != nil {
+			return err
+		}
+		md = imageMetadata{
+			size:    manifestSize(m),
+			created: cfg.Created.Time,
+		}
+		return nil
+	})
+	return md, err
+}
+
+// withRetries calls f until it succeeds, fails with a permanent error or runs
+// out of retries, doubling the backoff between the calls.
+func withRetries(ctx context.Context, retries int, backoff time.Duration, f func() error) error {
+	for i := 0; ; i++ {
+		err := f()
+		if err == nil || i >= retries || !isTransient(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff << i):
+		}
+	}
+}
+
+// isTransient returns whether the registry error may not happen on retry,
+// unlike e.g. missing credentials or images.
+func isTransient(err error) bool {
+	var terr *transport.Error
+	if errors.As(err, &terr) {
+		return terr.Temporary() || terr.StatusCode == http.StatusTooManyRequests ||
+			terr.StatusCode >= http.StatusInternalServerError
+	}
+	var nerr net.Error
+	return errors.As(err, &nerr)
+}
+
+// checkImage checks the size of the image, and verifies its signature with
+// the keys, if any.
+func checkImage(digest name.Digest, maxImageSize int64, keys []crypto.PublicKey, opts []remote.Option) error {
+	if err := checkImageSize(digest, maxImageSize, opts); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return verifySignature(digest, keys, opts)
+}
+
+// checkImageSize fetches the manifest of the image and verifies that the
+// total size of its config and layers does not exceed maxImageSize.
+// Non-positive maxImageSize disables the check.
+func checkImageSize(digest name.Digest, maxImageSize int64, opts []remote.Option) error {
+	if maxImageSize <= 0 {
+		return nil
+	}
+	img, err := remote.Image(digest, opts...)
+	if err != nil {
+		return err
+	}
+	m, err := img.Manifest()
+	if err != nil {
+		return err
+	}
+	if size := manifestSize(m); size > maxImageSize {
+		return fmt.Errorf("image size %d bytes exceeds the maximum of %d bytes", size, maxImageSize)
+	}
+	return nil
+}
+
+// manifestSize returns the total size of the config and the layers of the
+// image of the manifest.
+func manifestSize(m *v1.Manifest) int64 {
+	size := m.Config.Size
+	for _, l := range m.Layers {
+		size += l.Size
+	}
+	return size
 }
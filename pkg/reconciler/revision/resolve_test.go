@@ -31,6 +31,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -38,11 +39,16 @@ import (
 	"github.com/google/go-containerregistry/pkg/authn/k8schain"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
 	"github.com/google/go-containerregistry/pkg/v1/random"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/cache"
 	"k8s.io/apimachinery/pkg/util/sets"
 	fakeclient "k8s.io/client-go/kubernetes/fake"
+	"knative.dev/serving/pkg/apis/serving"
+	"knative.dev/serving/pkg/deployment"
 )
 
 var emptyRegistrySet = sets.NewString()
@@ -124,6 +130,44 @@ func fakeRegistryManifestFailure(t *testing.T, repo string) *httptest.Server {
 	}))
 }
 
+// anonymousClient returns a client with service accounts without pull secrets.
+func anonymousClient(ns string, svcaccts ...string) *fakeclient.Clientset {
+	objs := make([]runtime.Object, 0, len(svcaccts))
+	for _, svcacct := range svcaccts {
+		objs = append(objs, &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      svcacct,
+				Namespace: ns,
+			},
+		})
+	}
+	return fakeclient.NewSimpleClientset(objs...)
+}
+
+// fakeRegistryFlaky serves the image anonymously, once the manifest requests
+// failed the given number of times.
+func fakeRegistryFlaky(t *testing.T, repo string, img v1.Image, failures int32) http.Handler {
+	manifestPath := fmt.Sprintf("/v2/%s/manifests/latest", repo)
+	var requests int32
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/":
+		case manifestPath:
+			if atomic.AddInt32(&requests, 1) <= failures {
+				http.Error(w, "Try again", http.StatusServiceUnavailable)
+				return
+			}
+			mt, _ := img.MediaType()
+			sz, _ := img.Size()
+			w.Header().Set("Content-Type", string(mt))
+			w.Header().Set("Content-Length", fmt.Sprint(sz))
+			w.Header().Set("Docker-Content-Digest", mustDigest(t, img).String())
+		default:
+			t.Error("Unexpected path:", r.URL.Path)
+		}
+	})
+}
+
 func fakeRegistryBlocking(t *testing.T) (ts *httptest.Server, cancel func()) {
 	ch := make(chan struct{})
 	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -200,7 +244,7 @@ func TestResolve(t *testing.T) {
 		Namespace:          ns,
 		ServiceAccountName: svcacct,
 	}
-	resolvedDigest, err := dr.Resolve(context.Background(), tag.String(), opt, emptyRegistrySet)
+	resolvedDigest, err := dr.Resolve(context.Background(), tag.String(), opt, emptyRegistrySet, 0)
 	if err != nil {
 		t.Fatal("Resolve() =", err)
 	}
@@ -232,7 +276,7 @@ func TestResolveWithDigest(t *testing.T) {
 		Namespace:          ns,
 		ServiceAccountName: svcacct,
 	}
-	resolvedDigest, err := dr.Resolve(context.Background(), originalDigest, opt, emptyRegistrySet)
+	resolvedDigest, err := dr.Resolve(context.Background(), originalDigest, opt, emptyRegistrySet, 0)
 	if err != nil {
 		t.Fatal("Resolve() =", err)
 	}
@@ -242,6 +286,224 @@ func TestResolveWithDigest(t *testing.T) {
 	}
 }
 
+func TestResolveRetries(t *testing.T) {
+	const repo = "booger/nose"
+	img, err := random.Image(3, 1024)
+	if err != nil {
+		t.Fatal("random.Image() =", err)
+	}
+
+	tests := []struct {
+		name     string
+		retries  int
+		failures int32
+		wantErr  bool
+	}{{
+		name:     "no retries",
+		failures: 1,
+		wantErr:  true,
+	}, {
+		name:     "enough retries",
+		retries:  2,
+		failures: 2,
+	}, {
+		name:     "too many failures",
+		retries:  2,
+		failures: 3,
+		wantErr:  true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(fakeRegistryFlaky(t, repo, img, test.failures))
+			defer server.Close()
+			u, err := url.Parse(server.URL)
+			if err != nil {
+				t.Fatal("url.Parse() =", err)
+			}
+
+			dr := &digestResolver{
+				client:    anonymousClient("ns", "default"),
+				transport: http.DefaultTransport,
+				retries:   test.retries,
+				backoff:   time.Millisecond,
+			}
+			image := fmt.Sprintf("%s/%s:latest", u.Host, repo)
+			opt := k8schain.Options{Namespace: "ns", ServiceAccountName: "default"}
+			resolvedDigest, err := dr.Resolve(context.Background(), image, opt, emptyRegistrySet, 0)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("Resolve() = %v, want error", resolvedDigest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("Resolve() =", err)
+			}
+			if want := fmt.Sprintf("%s/%s@%s", u.Host, repo, mustDigest(t, img)); resolvedDigest != want {
+				t.Errorf("Resolve() = %s, want: %s", resolvedDigest, want)
+			}
+		})
+	}
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestResolveCache(t *testing.T) {
+	const repo = "booger/nose"
+	img, err := random.Image(3, 1024)
+	if err != nil {
+		t.Fatal("random.Image() =", err)
+	}
+
+	var requests int32
+	registry := fakeRegistryFlaky(t, repo, img, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/" {
+			atomic.AddInt32(&requests, 1)
+		}
+		registry.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal("url.Parse() =", err)
+	}
+	image := fmt.Sprintf("%s/%s:latest", u.Host, repo)
+
+	clock := &fakeClock{now: time.Now()}
+	dr := &digestResolver{
+		client:    anonymousClient("ns", "default", "builder"),
+		cache:     cache.NewLRUExpireCacheWithClock(10, clock),
+		transport: http.DefaultTransport,
+		cacheTTL:  time.Minute,
+	}
+	opt := k8schain.Options{Namespace: "ns", ServiceAccountName: "default"}
+	want := fmt.Sprintf("%s/%s@%s", u.Host, repo, mustDigest(t, img))
+
+	for _, step := range []struct {
+		name         string
+		opt          k8schain.Options
+		advance      time.Duration
+		wantRequests int32
+	}{{
+		name:         "first resolution",
+		opt:          opt,
+		wantRequests: 1,
+	}, {
+		name:         "cached",
+		opt:          opt,
+		wantRequests: 1,
+	}, {
+		name: "other credentials",
+		opt: k8schain.Options{
+			Namespace:          "ns",
+			ServiceAccountName: "builder",
+		},
+		wantRequests: 2,
+	}, {
+		name:         "expired",
+		opt:          opt,
+		advance:      2 * time.Minute,
+		wantRequests: 3,
+	}} {
+		clock.now = clock.now.Add(step.advance)
+		got, err := dr.Resolve(context.Background(), image, step.opt, emptyRegistrySet, 0)
+		if err != nil {
+			t.Fatalf("%s: Resolve() = %v", step.name, err)
+		}
+		if got != want {
+			t.Errorf("%s: Resolve() = %s, want: %s", step.name, got, want)
+		}
+		if got := atomic.LoadInt32(&requests); got != step.wantRequests {
+			t.Errorf("%s: registry requests = %d, want: %d", step.name, got, step.wantRequests)
+		}
+	}
+}
+
+func TestDigestResolverConfigure(t *testing.T) {
+	const repo = "booger/nose"
+	img, err := random.Image(3, 1024)
+	if err != nil {
+		t.Fatal("random.Image() =", err)
+	}
+
+	// A registry with a private CA.
+	server := httptest.NewTLSServer(fakeRegistryFlaky(t, repo, img, 0))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal("url.Parse() =", err)
+	}
+	image := fmt.Sprintf("%s/%s:latest", u.Host, repo)
+
+	tmpDir, err := ioutil.TempDir("", "TestDigestResolverConfigure-")
+	if err != nil {
+		t.Fatal("Failed to create tempdir for certs:", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	caPath, err := writeCertFile(tmpDir, "ca.crt", pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: server.Certificate().Raw,
+	}))
+	if err != nil {
+		t.Fatal("Failed to write cert bundle file:", err)
+	}
+	// Any valid bundle stands in for the one of the cluster.
+	clusterCertPath := caPath
+
+	dr := &digestResolver{client: anonymousClient("ns", "default"), transport: http.DefaultTransport}
+	opt := k8schain.Options{Namespace: "ns", ServiceAccountName: "default"}
+	if _, err := dr.Resolve(context.Background(), image, opt, emptyRegistrySet, 0); err == nil {
+		t.Fatal("Resolve() succeeded without the CA bundle")
+	}
+
+	cfg := &deployment.Config{
+		DigestResolutionCABundlePath: caPath,
+		DigestResolutionRetries:      1,
+		DigestResolutionBackoff:      time.Second,
+		DigestResolutionCacheTTL:     time.Minute,
+	}
+	if err := dr.configure(cfg, clusterCertPath); err != nil {
+		t.Fatal("configure() =", err)
+	}
+	if dr.retries != 1 || dr.backoff != time.Second || dr.cacheTTL != time.Minute {
+		t.Errorf("retries, backoff, cacheTTL = %d, %v, %v, want: 1, 1s, 1m", dr.retries, dr.backoff, dr.cacheTTL)
+	}
+	if _, err := dr.Resolve(context.Background(), image, opt, emptyRegistrySet, 0); err != nil {
+		t.Fatal("Resolve() =", err)
+	}
+
+	// The proxy is used for all the registries.
+	cfg.DigestResolutionProxy = "http://proxy.corp.example.com:3128"
+	if err := dr.configure(cfg, clusterCertPath); err != nil {
+		t.Fatal("configure() =", err)
+	}
+	proxy, err := dr.transport.(*http.Transport).Proxy(&http.Request{URL: u})
+	if err != nil {
+		t.Fatal("Proxy() =", err)
+	}
+	if got, want := proxy.String(), cfg.DigestResolutionProxy; got != want {
+		t.Errorf("Proxy() = %s, want: %s", got, want)
+	}
+
+	// The previous transport is kept when the CA bundle can't be read.
+	transport := dr.transport
+	cfg.DigestResolutionCABundlePath = filepath.Join(tmpDir, "missing.crt")
+	if err := dr.configure(cfg, clusterCertPath); err == nil {
+		t.Error("configure() succeeded with a missing CA bundle")
+	}
+	if dr.transport != transport {
+		t.Error("The transport was replaced by a failed configuration")
+	}
+}
+
 func TestResolveWithBadTag(t *testing.T) {
 	const (
 		ns      = "foo"
@@ -262,7 +524,7 @@ func TestResolveWithBadTag(t *testing.T) {
 
 	// Invalid character.
 	invalidImage := "ubuntu%latest"
-	if resolvedDigest, err := dr.Resolve(context.Background(), invalidImage, opt, emptyRegistrySet); err == nil {
+	if resolvedDigest, err := dr.Resolve(context.Background(), invalidImage, opt, emptyRegistrySet, 0); err == nil {
 		t.Fatalf("Resolve() succeeded with %q, want error", resolvedDigest)
 	}
 }
@@ -300,7 +562,7 @@ func TestResolveWithPingFailure(t *testing.T) {
 		Namespace:          ns,
 		ServiceAccountName: svcacct,
 	}
-	if resolvedDigest, err := dr.Resolve(context.Background(), tag.String(), opt, emptyRegistrySet); err == nil {
+	if resolvedDigest, err := dr.Resolve(context.Background(), tag.String(), opt, emptyRegistrySet, 0); err == nil {
 		t.Fatalf("Resolve() = %v, want error", resolvedDigest)
 	}
 }
@@ -340,7 +602,7 @@ func TestResolveWithManifestFailure(t *testing.T) {
 		Namespace:          ns,
 		ServiceAccountName: svcacct,
 	}
-	if resolvedDigest, err := dr.Resolve(context.Background(), tag.String(), opt, emptyRegistrySet); err == nil {
+	if resolvedDigest, err := dr.Resolve(context.Background(), tag.String(), opt, emptyRegistrySet, 0); err == nil {
 		t.Fatalf("Resolve() = %v, want error", resolvedDigest)
 	}
 }
@@ -357,7 +619,7 @@ func TestResolveNoAccess(t *testing.T) {
 		ServiceAccountName: svcacct,
 	}
 	// If there is a failure accessing the ServiceAccount for this Pod, then we should see an error.
-	if resolvedDigest, err := dr.Resolve(context.Background(), "ubuntu:latest", opt, emptyRegistrySet); err == nil {
+	if resolvedDigest, err := dr.Resolve(context.Background(), "ubuntu:latest", opt, emptyRegistrySet, 0); err == nil {
 		t.Fatalf("Resolve() = %v, want error", resolvedDigest)
 	}
 }
@@ -403,7 +665,7 @@ func TestResolveTimeout(t *testing.T) {
 		ServiceAccountName: svcacct,
 	}
 
-	_, err = dr.Resolve(ctx, tag.String(), opt, emptyRegistrySet)
+	_, err = dr.Resolve(ctx, tag.String(), opt, emptyRegistrySet, 0)
 	if !errors.Is(err, context.DeadlineExceeded) {
 		t.Fatal("Expected Resolve() to fail via timeout, but failed with", err)
 	}
@@ -452,7 +714,7 @@ func TestResolveSkippingRegistry(t *testing.T) {
 		ServiceAccountName: svcacct,
 	}
 
-	resolvedDigest, err := dr.Resolve(context.Background(), "localhost:5000/ubuntu:latest", opt, registriesToSkip)
+	resolvedDigest, err := dr.Resolve(context.Background(), "localhost:5000/ubuntu:latest", opt, registriesToSkip, 0)
 	if err != nil {
 		t.Fatal("Resolve() =", err)
 	}
@@ -460,6 +722,185 @@ func TestResolveSkippingRegistry(t *testing.T) {
 	if got, want := resolvedDigest, ""; got != want {
 		t.Fatalf("Resolve() got %q want of %q", got, want)
 	}
+
+	// All the registries are skipped with the wildcard.
+	resolvedDigest, err = dr.Resolve(context.Background(), "gcr.io/ubuntu:latest", opt, sets.NewString(serving.AllRegistries), 0)
+	if err != nil {
+		t.Fatal("Resolve() =", err)
+	}
+	if got, want := resolvedDigest, ""; got != want {
+		t.Fatalf("Resolve() got %q want of %q", got, want)
+	}
+}
+
+func TestResolveMaxImageSize(t *testing.T) {
+	const (
+		ns           = "user-project"
+		svcacct      = "user-robot"
+		expectedRepo = "booger/nose"
+	)
+
+	img, err := random.Image(3, 1024)
+	if err != nil {
+		t.Fatal("random.Image() =", err)
+	}
+	m, err := img.Manifest()
+	if err != nil {
+		t.Fatal("Manifest() =", err)
+	}
+	size := m.Config.Size
+	for _, l := range m.Layers {
+		size += l.Size
+	}
+
+	// Stand up a fake anonymous registry serving the image manifest
+	// both by tag and by digest.
+	digest := mustDigest(t, img)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/":
+		case "/v2/" + expectedRepo + "/manifests/latest", "/v2/" + expectedRepo + "/manifests/" + digest.String():
+			mt, _ := img.MediaType()
+			raw, _ := img.RawManifest()
+			w.Header().Set("Content-Type", string(mt))
+			w.Header().Set("Content-Length", fmt.Sprint(len(raw)))
+			w.Header().Set("Docker-Content-Digest", digest.String())
+			if r.Method == http.MethodGet {
+				w.Write(raw)
+			}
+		default:
+			t.Error("Unexpected path:", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal("url.Parse() =", err)
+	}
+
+	client := fakeclient.NewSimpleClientset(&corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      svcacct,
+			Namespace: ns,
+		},
+	})
+	dr := &digestResolver{client: client, transport: http.DefaultTransport}
+	opt := k8schain.Options{
+		Namespace:          ns,
+		ServiceAccountName: svcacct,
+	}
+
+	tag := fmt.Sprintf("%s/%s:latest", u.Host, expectedRepo)
+	byDigest := fmt.Sprintf("%s/%s@%s", u.Host, expectedRepo, digest)
+	tests := []struct {
+		name    string
+		image   string
+		max     int64
+		wantErr bool
+	}{{
+		name:  "tag at the limit",
+		image: tag,
+		max:   size,
+	}, {
+		name:    "tag above the limit",
+		image:   tag,
+		max:     size - 1,
+		wantErr: true,
+	}, {
+		name:  "digest at the limit",
+		image: byDigest,
+		max:   size,
+	}, {
+		name:    "digest above the limit",
+		image:   byDigest,
+		max:     size - 1,
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resolvedDigest, err := dr.Resolve(context.Background(), test.image, opt, emptyRegistrySet, test.max)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("Resolve() = %v, expected error", resolvedDigest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("Resolve() =", err)
+			}
+			if got, want := resolvedDigest, byDigest; got != want {
+				t.Errorf("Resolve() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestResolveMetadata(t *testing.T) {
+	const repo = "booger/nose"
+
+	img, err := random.Image(3, 1024)
+	if err != nil {
+		t.Fatal("random.Image() =", err)
+	}
+	created := time.Date(2020, time.October, 15, 12, 0, 0, 0, time.UTC)
+	if img, err = mutate.CreatedAt(img, v1.Time{Time: created}); err != nil {
+		t.Fatal("CreatedAt() =", err)
+	}
+	m, err := img.Manifest()
+	if err != nil {
+		t.Fatal("Manifest() =", err)
+	}
+	size := m.Config.Size
+	for _, l := range m.Layers {
+		size += l.Size
+	}
+
+	// Stand up a fake anonymous registry serving the image manifest and config.
+	digest := mustDigest(t, img)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/":
+		case "/v2/" + repo + "/manifests/" + digest.String():
+			mt, _ := img.MediaType()
+			raw, _ := img.RawManifest()
+			w.Header().Set("Content-Type", string(mt))
+			w.Header().Set("Docker-Content-Digest", digest.String())
+			w.Write(raw)
+		case "/v2/" + repo + "/blobs/" + m.Config.Digest.String():
+			raw, _ := img.RawConfigFile()
+			w.Write(raw)
+		default:
+			t.Error("Unexpected path:", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal("url.Parse() =", err)
+	}
+
+	const (
+		ns      = "user-project"
+		svcacct = "user-robot"
+	)
+	dr := &digestResolver{client: anonymousClient(ns, svcacct), transport: http.DefaultTransport}
+	opt := k8schain.Options{
+		Namespace:          ns,
+		ServiceAccountName: svcacct,
+	}
+
+	got, err := dr.Metadata(context.Background(), fmt.Sprintf("%s/%s@%s", u.Host, repo, digest), opt)
+	if err != nil {
+		t.Fatal("Metadata() =", err)
+	}
+	if want := (imageMetadata{size: size, created: created}); !got.created.Equal(want.created) || got.size != want.size {
+		t.Errorf("Metadata() = %+v, want: %+v", got, want)
+	}
+
+	if _, err := dr.Metadata(context.Background(), fmt.Sprintf("%s/%s:latest", u.Host, repo), opt); err == nil {
+		t.Error("Metadata() succeeded with a tag, want error")
+	}
 }
 
 func TestNewResolverTransport(t *testing.T) {
@@ -529,7 +970,7 @@ yE+vPxsiUkvQHdO2fojCkY8jg70jxM+gu59tPDNbw3Uh/2Ij310FgTHsnGQMyA==
 			}
 
 			// The actual test.
-			if tr, err := newResolverTransport(path, 100, 100); err != nil && !tc.wantErr {
+			if tr, err := newResolverTransport([]string{path}, 100, 100); err != nil && !tc.wantErr {
 				t.Error("Got unexpected err:", err)
 			} else if tc.wantErr && err == nil {
 				t.Error("Didn't get an error when we wanted it")
@@ -19,12 +19,17 @@ package resources
 import (
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	network "knative.dev/networking/pkg"
 	"knative.dev/pkg/kmeta"
 	"knative.dev/pkg/ptr"
 	"knative.dev/serving/pkg/apis/autoscaling"
+	apiconfig "knative.dev/serving/pkg/apis/config"
+	"knative.dev/serving/pkg/apis/serving"
 	v1 "knative.dev/serving/pkg/apis/serving/v1"
+	"knative.dev/serving/pkg/deployment"
 	"knative.dev/serving/pkg/networking"
 	"knative.dev/serving/pkg/queue"
 	"knative.dev/serving/pkg/reconciler/revision/config"
@@ -34,6 +39,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 var (
@@ -50,10 +56,76 @@ var (
 		SubPathExpr: "$(K_INTERNAL_POD_NAMESPACE)_$(K_INTERNAL_POD_NAME)_",
 	}
 
+	logSidecarVolume = corev1.Volume{
+		Name: "knative-log-sidecar",
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	}
+
+	backendCertsVolume = corev1.Volume{
+		Name: "knative-backend-certs",
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: networking.BackendCertsSecretName,
+			},
+		},
+	}
+
+	backendCertsVolumeMount = corev1.VolumeMount{
+		Name:      backendCertsVolume.Name,
+		MountPath: networking.BackendCertsMountPath,
+		ReadOnly:  true,
+	}
+
+	concurrencyStateTokenVolume = corev1.Volume{
+		Name: "knative-concurrency-state-token",
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{{
+					ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+						Audience:          queue.ConcurrencyStateTokenAudience,
+						ExpirationSeconds: ptr.Int64(600),
+						Path:              queue.ConcurrencyStateTokenFilename,
+					},
+				}},
+			},
+		},
+	}
+
+	concurrencyStateTokenVolumeMount = corev1.VolumeMount{
+		Name:      concurrencyStateTokenVolume.Name,
+		MountPath: queue.ConcurrencyStateTokenMountPath,
+		ReadOnly:  true,
+	}
+
+	unixSocketVolume = corev1.Volume{
+		Name: "knative-user-socket",
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{
+				Medium: corev1.StorageMediumMemory,
+			},
+		},
+	}
+
+	unixSocketVolumeMount = corev1.VolumeMount{
+		Name:      unixSocketVolume.Name,
+		MountPath: queue.UnixSocketMountPath,
+	}
+
+	backendCAVolumeMount = corev1.VolumeMount{
+		Name:      "knative-backend-ca",
+		MountPath: queue.BackendCAMountPath,
+		ReadOnly:  true,
+	}
+
 	// This PreStop hook is actually calling an endpoint on the queue-proxy
 	// because of the way PreStop hooks are called by kubelet. We use this
 	// to block the user-container from exiting before the queue-proxy is ready
 	// to exit so we can guarantee that there are no more requests in flight.
+	// The queue-proxy also notifies the optional pre-stop path of the user
+	// container, see serving.PreStopPathAnnotationKey and userPreStopPath,
+	// before unblocking it.
 	userLifecycle = &corev1.Lifecycle{
 		PreStop: &corev1.Handler{
 			HTTPGet: &corev1.HTTPGetAction{
@@ -86,6 +158,102 @@ func rewriteUserProbe(p *corev1.Probe, userPort int) {
 	}
 }
 
+// probePassthrough returns true if the TCP and HTTPS probes of the serving
+// container are kept as declared, see apiconfig.Features.ProbePassthrough.
+func probePassthrough(rev *v1.Revision, cfg *config.Config) bool {
+	if cfg == nil || cfg.Config == nil || cfg.Features == nil {
+		return false
+	}
+	switch cfg.Features.ProbePassthrough {
+	case apiconfig.Enabled:
+		return true
+	case apiconfig.Allowed:
+		return strings.EqualFold(rev.Annotations[serving.ProbePassthroughAnnotationKey], string(apiconfig.Enabled))
+	}
+	return false
+}
+
+// securePodDefaults returns true if the containers of the revision pods get
+// a restricted securityContext, see apiconfig.Features.SecurePodDefaults.
+func securePodDefaults(cfg *config.Config) bool {
+	return cfg != nil && cfg.Config != nil && cfg.Features != nil &&
+		cfg.Features.SecurePodDefaults == apiconfig.Enabled
+}
+
+// withSecureDefaults fills in the fields of the restricted securityContext the
+// user container left unset. Those it set opt out of the secure defaults.
+func withSecureDefaults(sc *corev1.SecurityContext) *corev1.SecurityContext {
+	if sc == nil {
+		sc = &corev1.SecurityContext{}
+	}
+	if sc.AllowPrivilegeEscalation == nil {
+		sc.AllowPrivilegeEscalation = ptr.Bool(false)
+	}
+	// The containers explicitly running as root would fail to start.
+	if sc.RunAsNonRoot == nil && (sc.RunAsUser == nil || *sc.RunAsUser != 0) {
+		sc.RunAsNonRoot = ptr.Bool(true)
+	}
+	if sc.ReadOnlyRootFilesystem == nil {
+		sc.ReadOnlyRootFilesystem = ptr.Bool(true)
+	}
+	if sc.Capabilities == nil {
+		sc.Capabilities = &corev1.Capabilities{
+			Drop: []corev1.Capability{"ALL"},
+		}
+	}
+	return sc
+}
+
+// makePodAnnotations adds the runtime/default seccomp profile to the
+// annotations of the revision pods with the secure pod defaults, unless the
+// revision sets another.
+func makePodAnnotations(anns map[string]string, cfg *config.Config) map[string]string {
+	if !securePodDefaults(cfg) {
+		return anns
+	}
+	if _, ok := anns[corev1.SeccompPodAnnotationKey]; ok {
+		return anns
+	}
+	podAnns := kmeta.CopyMap(anns)
+	podAnns[corev1.SeccompPodAnnotationKey] = corev1.SeccompProfileRuntimeDefault
+	return podAnns
+}
+
+// isPassthroughProbe returns true if the probe is kept as declared when
+// probePassthrough is on. The plain HTTP probes are still routed through
+// queue-proxy, which can run them just the same.
+func isPassthroughProbe(p *corev1.Probe) bool {
+	return p != nil && (p.TCPSocket != nil || (p.HTTPGet != nil && p.HTTPGet.Scheme == corev1.URISchemeHTTPS))
+}
+
+// defaultProbePort sets the port of the probe to the user port,
+// if it was left out, since the kubelet needs one.
+func defaultProbePort(p *corev1.Probe, userPort int) {
+	switch {
+	case p.TCPSocket != nil && p.TCPSocket.Port == intstr.IntOrString{}:
+		p.TCPSocket.Port = intstr.FromInt(userPort)
+	case p.HTTPGet != nil && p.HTTPGet.Port == intstr.IntOrString{}:
+		p.HTTPGet.Port = intstr.FromInt(userPort)
+	}
+}
+
+// backendCAVolume returns the volume of the CA bundle from the given Secret,
+// see serving.QueueSidecarBackendCASecretAnnotationKey.
+func backendCAVolume(secretName string) corev1.Volume {
+	return corev1.Volume{
+		Name: backendCAVolumeMount.Name,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: secretName,
+				Items: []corev1.KeyToPath{{
+					Key:  serving.BackendCASecretKey,
+					Path: serving.BackendCASecretKey,
+				}},
+			},
+		},
+	}
+}
+
 func makePodSpec(rev *v1.Revision, cfg *config.Config) (*corev1.PodSpec, error) {
 	queueContainer, err := makeQueueContainer(rev, cfg)
 
@@ -93,9 +261,13 @@ func makePodSpec(rev *v1.Revision, cfg *config.Config) (*corev1.PodSpec, error)
 		return nil, fmt.Errorf("failed to create queue-proxy container: %w", err)
 	}
 
-	podSpec := BuildPodSpec(rev, append(BuildUserContainers(rev), *queueContainer), cfg)
+	podSpec := BuildPodSpec(rev, append(BuildUserContainers(rev, cfg), *queueContainer), cfg)
+	podSpec.TopologySpreadConstraints = topologySpreadConstraints(rev, cfg)
+	podSpec.PriorityClassName = priorityClassName(rev, cfg)
 
-	if cfg.Observability.EnableVarLogCollection {
+	if rev.LogSidecarEnabled() && cfg.LogSidecar != nil {
+		injectLogSidecar(rev, podSpec, cfg.LogSidecar)
+	} else if cfg.Observability.EnableVarLogCollection {
 		podSpec.Volumes = append(podSpec.Volumes, varLogVolume)
 
 		for i, container := range podSpec.Containers {
@@ -112,16 +284,132 @@ func makePodSpec(rev *v1.Revision, cfg *config.Config) (*corev1.PodSpec, error)
 		}
 	}
 
+	if backendTLSEnabled(cfg) {
+		podSpec.Volumes = append(podSpec.Volumes, backendCertsVolume)
+	}
+
+	if _, ok := rev.Annotations[serving.ConcurrencyStateEndpointAnnotationKey]; ok {
+		podSpec.Volumes = append(podSpec.Volumes, concurrencyStateTokenVolume)
+	}
+
+	if rev.UsesUnixSocket() {
+		podSpec.Volumes = append(podSpec.Volumes, unixSocketVolume)
+	}
+
+	if secretName, ok := backendCASecret(rev); ok {
+		podSpec.Volumes = append(podSpec.Volumes, backendCAVolume(secretName))
+	}
+
 	return podSpec, nil
 }
 
+// injectLogSidecar adds the log sidecar to the pod, sharing an emptyDir with
+// the containers of the revision. Each of them writes under /var/log to its
+// own directory, which the sidecar reads under /var/log/<container name>.
+func injectLogSidecar(rev *v1.Revision, podSpec *corev1.PodSpec, template *corev1.Container) {
+	podSpec.Volumes = append(podSpec.Volumes, logSidecarVolume)
+
+	for i, container := range podSpec.Containers {
+		if container.Name == QueueContainerName {
+			continue
+		}
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      logSidecarVolume.Name,
+			MountPath: "/var/log",
+			SubPath:   container.Name,
+		})
+		podSpec.Containers[i] = container
+	}
+
+	sidecar := template.DeepCopy()
+	sidecar.VolumeMounts = append(sidecar.VolumeMounts, corev1.VolumeMount{
+		Name:      logSidecarVolume.Name,
+		MountPath: "/var/log",
+		ReadOnly:  true,
+	})
+	sidecar.Env = append(sidecar.Env, corev1.EnvVar{
+		Name:  "K_REVISION",
+		Value: rev.Name,
+	})
+	sidecar.Env = append(sidecar.Env, buildVarLogSubpathEnvs()...)
+	podSpec.Containers = append(podSpec.Containers, *sidecar)
+}
+
+// topologySpreadConstraints returns the topology spread constraints of the
+// revision pods, from the revision's annotation, or else config-deployment.
+// Those without a labelSelector spread the pods of the revision.
+func topologySpreadConstraints(rev *v1.Revision, cfg *config.Config) []corev1.TopologySpreadConstraint {
+	tscs := cfg.Deployment.TopologySpreadConstraints
+	if v, ok := rev.Annotations[serving.TopologySpreadConstraintsAnnotationKey]; ok {
+		// The annotation has been validated by the webhook.
+		tscs, _ = serving.ParseTopologySpreadConstraints(v)
+	}
+	if len(tscs) == 0 {
+		return nil
+	}
+	ret := make([]corev1.TopologySpreadConstraint, 0, len(tscs))
+	for _, c := range tscs {
+		if c.LabelSelector == nil {
+			c.LabelSelector = makeSelector(rev)
+		}
+		ret = append(ret, c)
+	}
+	return ret
+}
+
+// priorityClassName returns the priorityClassName of the revision pods, from
+// the revision's annotation, or else config-deployment, if the
+// kubernetes.podspec-priorityclassname feature is on.
+func priorityClassName(rev *v1.Revision, cfg *config.Config) string {
+	if cfg.Config == nil || cfg.Features == nil || cfg.Features.PodSpecPriorityClassName == apiconfig.Disabled {
+		return ""
+	}
+	if v, ok := rev.Annotations[serving.PriorityClassNameAnnotationKey]; ok {
+		return v
+	}
+	return cfg.Deployment.PriorityClassName
+}
+
+// progressDeadline returns the progress deadline of the revision deployment,
+// from the revision's annotation, or else config-deployment.
+func progressDeadline(rev *v1.Revision, cfg *config.Config) time.Duration {
+	if pd, ok := rev.ProgressDeadline(); ok {
+		return pd
+	}
+	return cfg.Deployment.ProgressDeadline
+}
+
+// deploymentStrategy returns the update strategy of the revision deployment,
+// from the revision's annotation, or else config-deployment, or else the
+// Kubernetes default one.
+func deploymentStrategy(rev *v1.Revision, cfg *config.Config) appsv1.DeploymentStrategy {
+	ds := cfg.Deployment.DeploymentStrategy
+	if v, ok := rev.Annotations[serving.DeploymentStrategyAnnotationKey]; ok {
+		// The annotation has been validated by the webhook.
+		ds, _ = serving.ParseDeploymentStrategy(v)
+	}
+	if ds == nil {
+		return appsv1.DeploymentStrategy{}
+	}
+	return *ds
+}
+
+// backendTLSEnabled returns true if queue-proxy must serve TLS to the activator.
+func backendTLSEnabled(cfg *config.Config) bool {
+	return cfg.Networking != nil && cfg.Networking.ActivatorBackendTLS
+}
+
 // BuildUserContainers makes an array of containers from the Revision template.
-func BuildUserContainers(rev *v1.Revision) []corev1.Container {
+// cfg can be passed as nil if not within revision reconciliation context.
+func BuildUserContainers(rev *v1.Revision, cfg *config.Config) []corev1.Container {
 	containers := make([]corev1.Container, 0, len(rev.Spec.PodSpec.Containers))
+	servingIdx := serving.ServingContainerIndex(rev.Spec.PodSpec.Containers)
+	passthrough := probePassthrough(rev, cfg)
+	secure := securePodDefaults(cfg)
 	for i := range rev.Spec.PodSpec.Containers {
 		var container corev1.Container
-		if len(rev.Spec.PodSpec.Containers[i].Ports) != 0 || len(rev.Spec.PodSpec.Containers) == 1 {
-			container = makeServingContainer(*rev.Spec.PodSpec.Containers[i].DeepCopy(), rev)
+		if i == servingIdx {
+			container = makeServingContainer(*rev.Spec.PodSpec.Containers[i].DeepCopy(), rev, passthrough)
 		} else {
 			container = makeContainer(*rev.Spec.PodSpec.Containers[i].DeepCopy(), rev)
 		}
@@ -133,6 +421,9 @@ func BuildUserContainers(rev *v1.Revision) []corev1.Container {
 				container.Image = rev.Status.ContainerStatuses[i].ImageDigest
 			}
 		}
+		if secure {
+			container.SecurityContext = withSecureDefaults(container.SecurityContext)
+		}
 		containers = append(containers, container)
 	}
 	return containers
@@ -141,7 +432,7 @@ func BuildUserContainers(rev *v1.Revision) []corev1.Container {
 func makeContainer(container corev1.Container, rev *v1.Revision) corev1.Container {
 	// Adding or removing an overwritten corev1.Container field here? Don't forget to
 	// update the fieldmasks / validations in pkg/apis/serving
-	container.Lifecycle = userLifecycle
+	container.Lifecycle = makeLifecycle(container.Lifecycle)
 	container.Env = append(container.Env, getKnativeEnvVar(rev)...)
 
 	// Explicitly disable stdin and tty allocation
@@ -153,37 +444,122 @@ func makeContainer(container corev1.Container, rev *v1.Revision) corev1.Containe
 	return container
 }
 
-func makeServingContainer(servingContainer corev1.Container, rev *v1.Revision) corev1.Container {
+// makeLifecycle keeps the postStart hook of the container, while its preStop
+// hook, if any, is sent by queue-proxy once drained, see userPreStopPath.
+func makeLifecycle(lc *corev1.Lifecycle) *corev1.Lifecycle {
+	if lc == nil || lc.PostStart == nil {
+		return userLifecycle
+	}
+	return &corev1.Lifecycle{
+		PostStart: lc.PostStart,
+		PreStop:   userLifecycle.PreStop,
+	}
+}
+
+func makeServingContainer(servingContainer corev1.Container, rev *v1.Revision, probePassthrough bool) corev1.Container {
 	userPort := getUserPort(rev)
 	userPortStr := strconv.Itoa(int(userPort))
-	// Replacement is safe as only up to a single port is allowed on the Revision
-	servingContainer.Ports = buildContainerPorts(userPort)
+	// Replacement is safe as only the first port is served through the queue-proxy,
+	// any further ports are named ports reachable via tagged traffic targets.
+	servingContainer.Ports = append(buildContainerPorts(userPort), getNamedPorts(rev)...)
 	servingContainer.Env = append(servingContainer.Env, buildUserPortEnv(userPortStr))
 	container := makeContainer(servingContainer, rev)
+	if rev.UsesUnixSocket() {
+		// The user container listens on the socket in the shared volume
+		// instead of the user port, queue-proxy proxies to it.
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  "K_UNIX_SOCKET",
+			Value: queue.UnixSocketPath,
+		})
+		container.VolumeMounts = append(container.VolumeMounts, unixSocketVolumeMount)
+	}
 	if container.ReadinessProbe != nil {
-		if container.ReadinessProbe.HTTPGet != nil || container.ReadinessProbe.TCPSocket != nil {
+		if probePassthrough && isPassthroughProbe(container.ReadinessProbe) {
+			// The probe is run by the kubelet as declared, while queue-proxy
+			// only checks that the user port accepts connections.
+			defaultProbePort(container.ReadinessProbe, int(userPort))
+		} else if container.ReadinessProbe.HTTPGet != nil || container.ReadinessProbe.TCPSocket != nil {
 			// HTTP and TCP ReadinessProbes are executed by the queue-proxy directly against the
 			// user-container instead of via kubelet.
 			container.ReadinessProbe = nil
 		}
 	}
-	// If the client provides probes, we should fill in the port for them.
-	rewriteUserProbe(container.LivenessProbe, int(userPort))
+	// The startup probe is run by the kubelet, just like the liveness probe,
+	// which only starts once the startup probe succeeded. The user container
+	// isn't ready, and so the pod isn't routable, until then either.
+	for _, p := range []*corev1.Probe{container.LivenessProbe, container.StartupProbe} {
+		if probePassthrough && isPassthroughProbe(p) {
+			defaultProbePort(p, int(userPort))
+		} else {
+			// If the client provides probes, we should fill in the port for them.
+			rewriteUserProbe(p, int(userPort))
+		}
+	}
 	return container
 }
 
+// makePodLabels adds the operator's extra labels of the pods running the
+// queue sidecar to the given labels. The given labels take precedence and the
+// extra labels, whose templates produce invalid values, are left out.
+func makePodLabels(rev *v1.Revision, labels map[string]string, cfg *config.Config) (map[string]string, error) {
+	if len(cfg.Deployment.QueueSidecarLabels) == 0 {
+		return labels, nil
+	}
+	data := queueSidecarTemplateData(rev)
+	podLabels := kmeta.CopyMap(labels)
+	for k, tmpl := range cfg.Deployment.QueueSidecarLabels {
+		if _, ok := podLabels[k]; ok {
+			continue
+		}
+		value, err := deployment.ExecuteQueueSidecarTemplate(tmpl, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute the template of the label %s: %w", k, err)
+		}
+		if len(validation.IsValidLabelValue(value)) == 0 {
+			podLabels[k] = value
+		}
+	}
+	return podLabels, nil
+}
+
 // BuildPodSpec creates a PodSpec from the given revision and containers.
 // cfg can be passed as nil if not within revision reconciliation context.
 func BuildPodSpec(rev *v1.Revision, containers []corev1.Container, cfg *config.Config) *corev1.PodSpec {
 	pod := rev.Spec.PodSpec.DeepCopy()
 	pod.Containers = containers
-	pod.TerminationGracePeriodSeconds = rev.Spec.TimeoutSeconds
+	// Like those of the containers, the init containers' image digests will
+	// have been resolved, unless within a DryRun.
+	for i := range pod.InitContainers {
+		if i < len(rev.Status.InitContainerStatuses) && rev.Status.InitContainerStatuses[i].ImageDigest != "" {
+			pod.InitContainers[i].Image = rev.Status.InitContainerStatuses[i].ImageDigest
+		}
+		if securePodDefaults(cfg) {
+			pod.InitContainers[i].SecurityContext = withSecureDefaults(pod.InitContainers[i].SecurityContext)
+		}
+	}
+	if pod.TerminationGracePeriodSeconds == nil {
+		pod.TerminationGracePeriodSeconds = terminationGracePeriodSeconds(rev)
+	}
 	if cfg != nil && pod.EnableServiceLinks == nil {
 		pod.EnableServiceLinks = cfg.Defaults.EnableServiceLinks
 	}
+	if cfg != nil && pod.AutomountServiceAccountToken == nil {
+		pod.AutomountServiceAccountToken = cfg.Defaults.AutomountServiceAccountToken
+	}
 	return pod
 }
 
+// terminationGracePeriodSeconds derives the termination grace period of the
+// revision pods, which didn't specify one, from the revision's timeouts.
+func terminationGracePeriodSeconds(rev *v1.Revision) *int64 {
+	tgps := rev.Spec.TimeoutSeconds
+	// The pods must be given the time to drain the requests in flight.
+	if dts := rev.Spec.DrainTimeoutSeconds; dts != nil && (tgps == nil || *dts > *tgps) {
+		tgps = dts
+	}
+	return tgps
+}
+
 func getUserPort(rev *v1.Revision) int32 {
 	ports := rev.Spec.GetContainer().Ports
 
@@ -194,6 +570,23 @@ func getUserPort(rev *v1.Revision) int32 {
 	return v1.DefaultUserPort
 }
 
+// getNamedPorts returns the additional named ports declared on the user
+// container, which are only present with the multi-port feature enabled.
+func getNamedPorts(rev *v1.Revision) []corev1.ContainerPort {
+	ports := rev.Spec.GetContainer().Ports
+	if len(ports) < 2 {
+		return nil
+	}
+	named := make([]corev1.ContainerPort, 0, len(ports)-1)
+	for _, p := range ports[1:] {
+		named = append(named, corev1.ContainerPort{
+			Name:          p.Name,
+			ContainerPort: p.ContainerPort,
+		})
+	}
+	return named
+}
+
 func buildContainerPorts(userPort int32) []corev1.ContainerPort {
 	return []corev1.ContainerPort{{
 		Name:          v1.UserPortName,
@@ -243,6 +636,10 @@ func MakeDeployment(rev *v1.Revision, cfg *config.Config) (*appsv1.Deployment, e
 
 	labels := makeLabels(rev)
 	anns := makeAnnotations(rev)
+	podLabels, err := makePodLabels(rev, labels, cfg)
+	if err != nil {
+		return nil, err
+	}
 
 	return &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
@@ -255,11 +652,12 @@ func MakeDeployment(rev *v1.Revision, cfg *config.Config) (*appsv1.Deployment, e
 		Spec: appsv1.DeploymentSpec{
 			Replicas:                ptr.Int32(replicaCount),
 			Selector:                makeSelector(rev),
-			ProgressDeadlineSeconds: ptr.Int32(int32(cfg.Deployment.ProgressDeadline.Seconds())),
+			ProgressDeadlineSeconds: ptr.Int32(int32(progressDeadline(rev, cfg).Seconds())),
+			Strategy:                deploymentStrategy(rev, cfg),
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels:      labels,
-					Annotations: anns,
+					Labels:      podLabels,
+					Annotations: makePodAnnotations(anns, cfg),
 				},
 				Spec: *podSpec,
 			},
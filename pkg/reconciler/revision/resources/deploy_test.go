@@ -17,6 +17,7 @@ limitations under the License.
 package resources
 
 import (
+	"crypto/tls"
 	"fmt"
 	"testing"
 	"time"
@@ -104,9 +105,21 @@ var (
 		}, {
 			Name:  "CONTAINER_CONCURRENCY",
 			Value: "0",
+		}, {
+			Name:  "COUNT_LONG_LIVED_CONNECTIONS",
+			Value: "true",
 		}, {
 			Name:  "REVISION_TIMEOUT_SECONDS",
 			Value: "45",
+		}, {
+			Name:  "REVISION_RESPONSE_START_TIMEOUT_SECONDS",
+			Value: "0",
+		}, {
+			Name:  "REVISION_IDLE_TIMEOUT_SECONDS",
+			Value: "0",
+		}, {
+			Name:  "REVISION_DRAIN_TIMEOUT_SECONDS",
+			Value: "0",
 		}, {
 			Name: "SERVING_POD",
 			ValueFrom: &corev1.EnvVarSource{
@@ -147,6 +160,12 @@ var (
 		}, {
 			Name:  "TRACING_CONFIG_SAMPLE_RATE",
 			Value: "0",
+		}, {
+			Name:  "TRACING_CONFIG_OTLP_ENDPOINT",
+			Value: "",
+		}, {
+			Name:  "TRACING_CONFIG_OTLP_INSECURE",
+			Value: "false",
 		}, {
 			Name:  "USER_PORT",
 			Value: "8080",
@@ -456,6 +475,9 @@ func TestMakePodSpec(t *testing.T) {
 		rev  *v1.Revision
 		oc   metrics.ObservabilityConfig
 		dc   *apicfg.Defaults
+		nc   *networking.Config
+		fc   *apicfg.Features
+		ls   *corev1.Container
 		want *corev1.PodSpec
 	}{{
 		name: "user-defined user port, queue proxy have PORT env",
@@ -486,67 +508,35 @@ func TestMakePodSpec(t *testing.T) {
 					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8888,"host":"127.0.0.1"}}`),
 				)}),
 	}, {
-		name: "volumes passed through",
-		rev: revision("bar", "foo",
-			withContainers([]corev1.Container{{
-				Name:  servingContainerName,
-				Image: "busybox",
-				Ports: []corev1.ContainerPort{{
-					ContainerPort: 8888,
-				}},
-				VolumeMounts: []corev1.VolumeMount{{
-					Name:      "asdf",
-					MountPath: "/asdf",
-				}},
-				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
-			}}),
-			WithContainerStatuses([]v1.ContainerStatus{{
-				ImageDigest: "busybox@sha256:deadbeef",
-			}}),
-			func(revision *v1.Revision) {
-				revision.Spec.Volumes = []corev1.Volume{{
-					Name: "asdf",
-					VolumeSource: corev1.VolumeSource{
-						Secret: &corev1.SecretVolumeSource{
-							SecretName: "asdf",
-						},
-					},
-				}}
-			},
-		),
-		want: podSpec(
-			[]corev1.Container{
-				servingContainer(
-					func(container *corev1.Container) {
-						container.Ports[0].ContainerPort = 8888
-						container.Image = "busybox@sha256:deadbeef"
-					},
-					withEnvVar("PORT", "8888"),
-					withPrependedVolumeMounts(corev1.VolumeMount{
-						Name:      "asdf",
-						MountPath: "/asdf",
-					}),
-				),
-				queueContainer(
-					withEnvVar("USER_PORT", "8888"),
-					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8888,"host":"127.0.0.1"}}`),
-				),
-			}, withAppendedVolumes(corev1.Volume{
-				Name: "asdf",
-				VolumeSource: corev1.VolumeSource{
-					Secret: &corev1.SecretVolumeSource{
-						SecretName: "asdf",
-					},
-				},
-			})),
-	}, {
-		name: "explicit true service links",
+		name: "node placement",
 		rev: revision("bar", "foo",
 			withContainers([]corev1.Container{{
 				Name:           servingContainerName,
 				Image:          "busybox",
 				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
 			}}),
+			func(r *v1.Revision) {
+				r.Spec.NodeSelector = map[string]string{"cloud.google.com/gke-spot": "true"}
+				r.Spec.Tolerations = []corev1.Toleration{{
+					Key:      "nvidia.com/gpu",
+					Operator: corev1.TolerationOpExists,
+					Effect:   corev1.TaintEffectNoSchedule,
+				}}
+				r.Spec.Affinity = &corev1.Affinity{
+					NodeAffinity: &corev1.NodeAffinity{
+						PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{{
+							Weight: 1,
+							Preference: corev1.NodeSelectorTerm{
+								MatchExpressions: []corev1.NodeSelectorRequirement{{
+									Key:      "topology.kubernetes.io/zone",
+									Operator: corev1.NodeSelectorOpIn,
+									Values:   []string{"us-east1-b"},
+								}},
+							},
+						}},
+					},
+				}
+			},
 			WithContainerStatuses([]v1.ContainerStatus{{
 				ImageDigest: "busybox@sha256:deadbeef",
 			}}),
@@ -556,24 +546,46 @@ func TestMakePodSpec(t *testing.T) {
 				servingContainer(func(container *corev1.Container) {
 					container.Image = "busybox@sha256:deadbeef"
 				}),
-				queueContainer(),
-			}, func(p *corev1.PodSpec) {
-				p.EnableServiceLinks = ptr.Bool(true)
+				queueContainer(
+					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8080,"host":"127.0.0.1"}}`),
+				),
+			},
+			func(ps *corev1.PodSpec) {
+				ps.NodeSelector = map[string]string{"cloud.google.com/gke-spot": "true"}
+				ps.Tolerations = []corev1.Toleration{{
+					Key:      "nvidia.com/gpu",
+					Operator: corev1.TolerationOpExists,
+					Effect:   corev1.TaintEffectNoSchedule,
+				}}
+				ps.Affinity = &corev1.Affinity{
+					NodeAffinity: &corev1.NodeAffinity{
+						PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{{
+							Weight: 1,
+							Preference: corev1.NodeSelectorTerm{
+								MatchExpressions: []corev1.NodeSelectorRequirement{{
+									Key:      "topology.kubernetes.io/zone",
+									Operator: corev1.NodeSelectorOpIn,
+									Values:   []string{"us-east1-b"},
+								}},
+							},
+						}},
+					},
+				}
 			}),
-		dc: func() *apicfg.Defaults {
-			d, _ := apicfg.NewDefaultsConfigFromMap(map[string]string{
-				"enable-service-links": "true",
-			})
-			return d
-		}(),
 	}, {
-		name: "explicit default service links",
+		name: "host aliases",
 		rev: revision("bar", "foo",
 			withContainers([]corev1.Container{{
 				Name:           servingContainerName,
 				Image:          "busybox",
 				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
 			}}),
+			func(r *v1.Revision) {
+				r.Spec.HostAliases = []corev1.HostAlias{{
+					IP:        "10.1.2.3",
+					Hostnames: []string{"legacy.example.com"},
+				}}
+			},
 			WithContainerStatuses([]v1.ContainerStatus{{
 				ImageDigest: "busybox@sha256:deadbeef",
 			}}),
@@ -583,25 +595,32 @@ func TestMakePodSpec(t *testing.T) {
 				servingContainer(func(container *corev1.Container) {
 					container.Image = "busybox@sha256:deadbeef"
 				}),
-				queueContainer(),
-			}, func(p *corev1.PodSpec) {
-				p.EnableServiceLinks = nil
+				queueContainer(
+					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8080,"host":"127.0.0.1"}}`),
+				),
+			},
+			func(ps *corev1.PodSpec) {
+				ps.HostAliases = []corev1.HostAlias{{
+					IP:        "10.1.2.3",
+					Hostnames: []string{"legacy.example.com"},
+				}}
 			}),
-		dc: func() *apicfg.Defaults {
-			d, _ := apicfg.NewDefaultsConfigFromMap(map[string]string{
-				"enable-service-links": "default",
-			})
-			return d
-		}(),
 	}, {
-		name: "concurrency=1 no owner",
+		name: "lifecycle hooks",
 		rev: revision("bar", "foo",
 			withContainers([]corev1.Container{{
 				Name:           servingContainerName,
 				Image:          "busybox",
 				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+				Lifecycle: &corev1.Lifecycle{
+					PostStart: &corev1.Handler{
+						Exec: &corev1.ExecAction{Command: []string{"warmup"}},
+					},
+					PreStop: &corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{Path: "/quit"},
+					},
+				},
 			}}),
-			withContainerConcurrency(1),
 			WithContainerStatuses([]v1.ContainerStatus{{
 				ImageDigest: "busybox@sha256:deadbeef",
 			}}),
@@ -610,49 +629,92 @@ func TestMakePodSpec(t *testing.T) {
 			[]corev1.Container{
 				servingContainer(func(container *corev1.Container) {
 					container.Image = "busybox@sha256:deadbeef"
+					// The preStop hook is sent by queue-proxy once drained.
+					container.Lifecycle = &corev1.Lifecycle{
+						PostStart: &corev1.Handler{
+							Exec: &corev1.ExecAction{Command: []string{"warmup"}},
+						},
+						PreStop: userLifecycle.PreStop,
+					}
 				}),
 				queueContainer(
-					withEnvVar("CONTAINER_CONCURRENCY", "1"),
+					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8080,"host":"127.0.0.1"}}`),
+					withEnvVar("USER_PRESTOP_PATH", "/quit"),
 				),
 			}),
 	}, {
-		name: "metrics collector address",
+		name: "emptyDir volume",
 		rev: revision("bar", "foo",
 			withContainers([]corev1.Container{{
 				Name:           servingContainerName,
 				Image:          "busybox",
 				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+				VolumeMounts: []corev1.VolumeMount{{
+					Name:      "scratch",
+					MountPath: "/scratch",
+				}},
 			}}),
+			func(r *v1.Revision) {
+				r.Spec.Volumes = []corev1.Volume{{
+					Name: "scratch",
+					VolumeSource: corev1.VolumeSource{
+						EmptyDir: &corev1.EmptyDirVolumeSource{
+							SizeLimit: resourcePtr(resource.MustParse("64Mi")),
+						},
+					},
+				}}
+			},
 			WithContainerStatuses([]v1.ContainerStatus{{
 				ImageDigest: "busybox@sha256:deadbeef",
 			}}),
 		),
-		oc: metrics.ObservabilityConfig{
-			RequestMetricsBackend:   "opencensus",
-			MetricsCollectorAddress: "otel:55678",
-		},
 		want: podSpec(
 			[]corev1.Container{
 				servingContainer(func(container *corev1.Container) {
 					container.Image = "busybox@sha256:deadbeef"
+					container.VolumeMounts = []corev1.VolumeMount{{
+						Name:      "scratch",
+						MountPath: "/scratch",
+					}}
 				}),
 				queueContainer(
-					withEnvVar("METRICS_COLLECTOR_ADDRESS", "otel:55678"),
-					withEnvVar("SERVING_REQUEST_METRICS_BACKEND", "opencensus"),
+					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8080,"host":"127.0.0.1"}}`),
 				),
-			}),
+			},
+			withAppendedVolumes(corev1.Volume{
+				Name: "scratch",
+				VolumeSource: corev1.VolumeSource{
+					EmptyDir: &corev1.EmptyDirVolumeSource{
+						SizeLimit: resourcePtr(resource.MustParse("64Mi")),
+					},
+				},
+			})),
 	}, {
-		name: "concurrency=121 no owner digest resolved",
+		name: "init containers",
 		rev: revision("bar", "foo",
 			withContainers([]corev1.Container{{
 				Name:           servingContainerName,
 				Image:          "busybox",
 				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
 			}}),
-			withContainerConcurrency(121),
+			func(r *v1.Revision) {
+				r.Spec.InitContainers = []corev1.Container{{
+					Name:  "migrate",
+					Image: "migrate",
+				}, {
+					Name:  "skipped",
+					Image: "ko.local/skipped",
+				}}
+			},
 			WithContainerStatuses([]v1.ContainerStatus{{
 				ImageDigest: "busybox@sha256:deadbeef",
 			}}),
+			WithInitContainerStatuses([]v1.ContainerStatus{{
+				Name:        "migrate",
+				ImageDigest: "migrate@sha256:cafe",
+			}, {
+				Name: "skipped",
+			}}),
 		),
 		want: podSpec(
 			[]corev1.Container{
@@ -660,163 +722,916 @@ func TestMakePodSpec(t *testing.T) {
 					container.Image = "busybox@sha256:deadbeef"
 				}),
 				queueContainer(
-					withEnvVar("CONTAINER_CONCURRENCY", "121"),
+					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8080,"host":"127.0.0.1"}}`),
 				),
+			},
+			func(ps *corev1.PodSpec) {
+				ps.InitContainers = []corev1.Container{{
+					Name:  "migrate",
+					Image: "migrate@sha256:cafe",
+				}, {
+					Name:  "skipped",
+					Image: "ko.local/skipped",
+				}}
 			}),
 	}, {
-		name: "concurrency=1 with owner",
+		name: "named ports",
 		rev: revision("bar", "foo",
 			withContainers([]corev1.Container{{
-				Name:           servingContainerName,
-				Image:          "busybox",
+				Name:  servingContainerName,
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 8888,
+				}, {
+					Name:          "admin",
+					ContainerPort: 8181,
+				}},
 				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
 			}}),
-			withContainerConcurrency(42),
-			withOwnerReference("parent-config"),
 			WithContainerStatuses([]v1.ContainerStatus{{
 				ImageDigest: "busybox@sha256:deadbeef",
 			}}),
 		),
 		want: podSpec(
 			[]corev1.Container{
-				servingContainer(func(container *corev1.Container) {
-					container.Image = "busybox@sha256:deadbeef"
-				}),
-				queueContainer(
-					withEnvVar("SERVING_CONFIGURATION", "parent-config"),
-					withEnvVar("CONTAINER_CONCURRENCY", "42"),
+				servingContainer(
+					func(container *corev1.Container) {
+						container.Ports[0].ContainerPort = 8888
+						container.Ports = append(container.Ports, corev1.ContainerPort{
+							Name:          "admin",
+							ContainerPort: 8181,
+						})
+						container.Image = "busybox@sha256:deadbeef"
+					},
+					withEnvVar("PORT", "8888"),
 				),
-			}),
+				queueContainer(
+					withEnvVar("USER_PORT", "8888"),
+					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8888,"host":"127.0.0.1"}}`),
+					func(container *corev1.Container) {
+						container.Env = append(container.Env, corev1.EnvVar{
+							Name:  "USER_NAMED_PORTS",
+							Value: "admin=8181",
+						})
+					},
+				)}),
 	}, {
-		name: "with http readiness probe",
+		name: "backend tls",
 		rev: revision("bar", "foo",
 			withContainers([]corev1.Container{{
-				Name:           servingContainerName,
-				Image:          "busybox",
-				ReadinessProbe: withHTTPReadinessProbe(v1.DefaultUserPort),
+				Name:  servingContainerName,
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 8888,
+				}},
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
 			}}),
 			WithContainerStatuses([]v1.ContainerStatus{{
 				ImageDigest: "busybox@sha256:deadbeef",
 			}}),
 		),
+		nc: &networking.Config{ActivatorBackendTLS: true},
 		want: podSpec(
 			[]corev1.Container{
-				servingContainer(func(container *corev1.Container) {
-					container.Image = "busybox@sha256:deadbeef"
-				}),
-				queueContainer(
-					withEnvVar("SERVING_READINESS_PROBE", `{"httpGet":{"path":"/","port":8080,"host":"127.0.0.1","scheme":"HTTP","httpHeaders":[{"name":"K-Kubelet-Probe","value":"queue"}]}}`),
+				servingContainer(
+					func(container *corev1.Container) {
+						container.Ports[0].ContainerPort = 8888
+						container.Image = "busybox@sha256:deadbeef"
+					},
+					withEnvVar("PORT", "8888"),
 				),
-			}),
+				queueContainer(
+					withEnvVar("USER_PORT", "8888"),
+					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8888,"host":"127.0.0.1"}}`),
+					func(container *corev1.Container) {
+						container.Ports = append(container.Ports, queueHTTPSPort)
+						container.Env = append(container.Env, corev1.EnvVar{
+							Name:  "BACKEND_TLS_CERTS_DIR",
+							Value: networking.BackendCertsMountPath,
+						})
+						container.VolumeMounts = []corev1.VolumeMount{backendCertsVolumeMount}
+					},
+				)},
+			withAppendedVolumes(backendCertsVolume),
+		),
 	}, {
-		name: "with tcp readiness probe",
+		name: "backend tls settings",
 		rev: revision("bar", "foo",
 			withContainers([]corev1.Container{{
-				Name:           servingContainerName,
-				Image:          "busybox",
-				ReadinessProbe: withTCPReadinessProbe(12345),
+				Name:  servingContainerName,
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 8888,
+				}},
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
 			}}),
 			WithContainerStatuses([]v1.ContainerStatus{{
 				ImageDigest: "busybox@sha256:deadbeef",
 			}}),
 		),
+		nc: &networking.Config{
+			ActivatorBackendTLS:      true,
+			DataplaneTLSMinVersion:   tls.VersionTLS12,
+			DataplaneTLSCipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+			DataplaneTLSFIPSMode:     true,
+		},
 		want: podSpec(
 			[]corev1.Container{
-				servingContainer(func(container *corev1.Container) {
-					container.Image = "busybox@sha256:deadbeef"
-				}),
-				queueContainer(
-					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8080,"host":"127.0.0.1"}}`),
+				servingContainer(
+					func(container *corev1.Container) {
+						container.Ports[0].ContainerPort = 8888
+						container.Image = "busybox@sha256:deadbeef"
+					},
+					withEnvVar("PORT", "8888"),
 				),
-			}),
+				queueContainer(
+					withEnvVar("USER_PORT", "8888"),
+					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8888,"host":"127.0.0.1"}}`),
+					func(container *corev1.Container) {
+						container.Ports = append(container.Ports, queueHTTPSPort)
+						container.Env = append(container.Env, corev1.EnvVar{
+							Name:  "BACKEND_TLS_CERTS_DIR",
+							Value: networking.BackendCertsMountPath,
+						}, corev1.EnvVar{
+							Name:  "BACKEND_TLS_MIN_VERSION",
+							Value: "1.2",
+						}, corev1.EnvVar{
+							Name:  "BACKEND_TLS_CIPHER_SUITES",
+							Value: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+						}, corev1.EnvVar{
+							Name:  "BACKEND_TLS_FIPS_MODE",
+							Value: "true",
+						})
+						container.VolumeMounts = []corev1.VolumeMount{backendCertsVolumeMount}
+					},
+				)},
+			withAppendedVolumes(backendCertsVolume),
+		),
 	}, {
-		name: "with shell readiness probe",
+		name: "proxy protocol",
 		rev: revision("bar", "foo",
 			withContainers([]corev1.Container{{
-				Name:           servingContainerName,
-				Image:          "busybox",
-				ReadinessProbe: withExecReadinessProbe([]string{"echo", "hello"}),
+				Name:  servingContainerName,
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 8888,
+				}},
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
 			}}),
 			WithContainerStatuses([]v1.ContainerStatus{{
 				ImageDigest: "busybox@sha256:deadbeef",
 			}}),
 		),
+		nc: &networking.Config{
+			DataplaneProxyProtocol:             true,
+			DataplaneProxyProtocolTrustedCIDRs: []string{"10.0.0.0/8", "fd00::/8"},
+		},
 		want: podSpec(
 			[]corev1.Container{
 				servingContainer(
 					func(container *corev1.Container) {
+						container.Ports[0].ContainerPort = 8888
 						container.Image = "busybox@sha256:deadbeef"
-						container.ReadinessProbe = withExecReadinessProbe([]string{"echo", "hello"})
-					}),
-				queueContainer(
-					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8080,"host":"127.0.0.1"}}`),
+					},
+					withEnvVar("PORT", "8888"),
 				),
+				queueContainer(
+					withEnvVar("USER_PORT", "8888"),
+					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8888,"host":"127.0.0.1"}}`),
+					withEnvVar("SERVING_PROXY_PROTOCOL", "true"),
+					withEnvVar("SERVING_PROXY_PROTOCOL_TRUSTED_CIDRS", "10.0.0.0/8,fd00::/8"),
+				)}),
+	}, {
+		name: "grpc probe",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:  servingContainerName,
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 8888,
+				}},
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+			}}),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+			func(revision *v1.Revision) {
+				revision.Annotations = map[string]string{
+					serving.GRPCProbeAnnotationKey: "ping.Ping",
+				}
+			},
+		),
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(
+					func(container *corev1.Container) {
+						container.Ports[0].ContainerPort = 8888
+						container.Image = "busybox@sha256:deadbeef"
+					},
+					withEnvVar("PORT", "8888"),
+				),
+				queueContainer(
+					withEnvVar("USER_PORT", "8888"),
+					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8888,"host":"127.0.0.1"}}`),
+					withEnvVar("SERVING_GRPC_PROBE", "true"),
+					withEnvVar("SERVING_GRPC_PROBE_SERVICE", "ping.Ping"),
+				)}),
+	}, {
+		name: "concurrency state endpoint",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:  servingContainerName,
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 8888,
+				}},
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+			}}),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+			func(revision *v1.Revision) {
+				revision.Annotations = map[string]string{
+					serving.ConcurrencyStateEndpointAnnotationKey: "http://$HOST_IP:9696",
+				}
+			},
+		),
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(
+					func(container *corev1.Container) {
+						container.Ports[0].ContainerPort = 8888
+						container.Image = "busybox@sha256:deadbeef"
+					},
+					withEnvVar("PORT", "8888"),
+				),
+				queueContainer(
+					withEnvVar("USER_PORT", "8888"),
+					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8888,"host":"127.0.0.1"}}`),
+					func(container *corev1.Container) {
+						container.Env = append(container.Env, corev1.EnvVar{
+							Name:  "CONCURRENCY_STATE_ENDPOINT",
+							Value: "http://$HOST_IP:9696",
+						}, corev1.EnvVar{
+							Name:  "CONCURRENCY_STATE_TOKEN_PATH",
+							Value: "/var/run/secrets/tokens/state-token",
+						}, corev1.EnvVar{
+							Name: "HOST_IP",
+							ValueFrom: &corev1.EnvVarSource{
+								FieldRef: &corev1.ObjectFieldSelector{
+									FieldPath: "status.hostIP",
+								},
+							},
+						})
+						container.VolumeMounts = []corev1.VolumeMount{concurrencyStateTokenVolumeMount}
+					},
+				)},
+			withAppendedVolumes(concurrencyStateTokenVolume),
+		),
+	}, {
+		name: "unix socket",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:  servingContainerName,
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{
+					Name:          "unix",
+					ContainerPort: 8888,
+				}},
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+			}}),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+		),
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(
+					func(container *corev1.Container) {
+						container.Ports[0].ContainerPort = 8888
+						container.Image = "busybox@sha256:deadbeef"
+						container.VolumeMounts = []corev1.VolumeMount{unixSocketVolumeMount}
+					},
+					withEnvVar("PORT", "8888"),
+					withEnvVar("K_UNIX_SOCKET", "/var/run/knative-socket/user.sock"),
+				),
+				queueContainer(
+					withEnvVar("USER_PORT", "8888"),
+					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8888,"host":"127.0.0.1"}}`),
+					func(container *corev1.Container) {
+						container.Env = append(container.Env, corev1.EnvVar{
+							Name:  "USER_UNIX_SOCKET",
+							Value: "/var/run/knative-socket/user.sock",
+						})
+						container.VolumeMounts = []corev1.VolumeMount{unixSocketVolumeMount}
+					},
+				)},
+			withAppendedVolumes(unixSocketVolume),
+		),
+	}, {
+		name: "volumes passed through",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:  servingContainerName,
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 8888,
+				}},
+				VolumeMounts: []corev1.VolumeMount{{
+					Name:      "asdf",
+					MountPath: "/asdf",
+				}},
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+			}}),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+			func(revision *v1.Revision) {
+				revision.Spec.Volumes = []corev1.Volume{{
+					Name: "asdf",
+					VolumeSource: corev1.VolumeSource{
+						Secret: &corev1.SecretVolumeSource{
+							SecretName: "asdf",
+						},
+					},
+				}}
+			},
+		),
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(
+					func(container *corev1.Container) {
+						container.Ports[0].ContainerPort = 8888
+						container.Image = "busybox@sha256:deadbeef"
+					},
+					withEnvVar("PORT", "8888"),
+					withPrependedVolumeMounts(corev1.VolumeMount{
+						Name:      "asdf",
+						MountPath: "/asdf",
+					}),
+				),
+				queueContainer(
+					withEnvVar("USER_PORT", "8888"),
+					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8888,"host":"127.0.0.1"}}`),
+				),
+			}, withAppendedVolumes(corev1.Volume{
+				Name: "asdf",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: "asdf",
+					},
+				},
+			})),
+	}, {
+		name: "explicit true service links",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				Image:          "busybox",
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+			}}),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+		),
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(func(container *corev1.Container) {
+					container.Image = "busybox@sha256:deadbeef"
+				}),
+				queueContainer(),
+			}, func(p *corev1.PodSpec) {
+				p.EnableServiceLinks = ptr.Bool(true)
+			}),
+		dc: func() *apicfg.Defaults {
+			d, _ := apicfg.NewDefaultsConfigFromMap(map[string]string{
+				"enable-service-links": "true",
+			})
+			return d
+		}(),
+	}, {
+		name: "explicit default service links",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				Image:          "busybox",
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+			}}),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+		),
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(func(container *corev1.Container) {
+					container.Image = "busybox@sha256:deadbeef"
+				}),
+				queueContainer(),
+			}, func(p *corev1.PodSpec) {
+				p.EnableServiceLinks = nil
+			}),
+		dc: func() *apicfg.Defaults {
+			d, _ := apicfg.NewDefaultsConfigFromMap(map[string]string{
+				"enable-service-links": "default",
+			})
+			return d
+		}(),
+	}, {
+		name: "default automount service account token",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				Image:          "busybox",
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+			}}),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+		),
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(func(container *corev1.Container) {
+					container.Image = "busybox@sha256:deadbeef"
+				}),
+				queueContainer(),
+			}, func(p *corev1.PodSpec) {
+				p.AutomountServiceAccountToken = ptr.Bool(false)
+			}),
+		dc: func() *apicfg.Defaults {
+			d, _ := apicfg.NewDefaultsConfigFromMap(map[string]string{
+				"automount-service-account-token": "false",
+			})
+			return d
+		}(),
+	}, {
+		name: "drain timeout exceeds timeout",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				Image:          "busybox",
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+			}}),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+			func(revision *v1.Revision) {
+				revision.Spec.DrainTimeoutSeconds = ptr.Int64(90)
+			},
+		),
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(func(container *corev1.Container) {
+					container.Image = "busybox@sha256:deadbeef"
+				}),
+				queueContainer(
+					withEnvVar("REVISION_DRAIN_TIMEOUT_SECONDS", "90"),
+				),
+			}, func(p *corev1.PodSpec) {
+				p.TerminationGracePeriodSeconds = ptr.Int64(90)
+			}),
+	}, {
+		name: "explicit termination grace period",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				Image:          "busybox",
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+			}}),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+			func(revision *v1.Revision) {
+				revision.Spec.TerminationGracePeriodSeconds = ptr.Int64(900)
+			},
+		),
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(func(container *corev1.Container) {
+					container.Image = "busybox@sha256:deadbeef"
+				}),
+				queueContainer(),
+			}, func(p *corev1.PodSpec) {
+				p.TerminationGracePeriodSeconds = ptr.Int64(900)
+			}),
+	}, {
+		name: "concurrency=1 no owner",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				Image:          "busybox",
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+			}}),
+			withContainerConcurrency(1),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+		),
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(func(container *corev1.Container) {
+					container.Image = "busybox@sha256:deadbeef"
+				}),
+				queueContainer(
+					withEnvVar("CONTAINER_CONCURRENCY", "1"),
+				),
+			}),
+	}, {
+		name: "metrics collector address",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				Image:          "busybox",
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+			}}),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+		),
+		oc: metrics.ObservabilityConfig{
+			RequestMetricsBackend:   "opencensus",
+			MetricsCollectorAddress: "otel:55678",
+		},
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(func(container *corev1.Container) {
+					container.Image = "busybox@sha256:deadbeef"
+				}),
+				queueContainer(
+					withEnvVar("METRICS_COLLECTOR_ADDRESS", "otel:55678"),
+					withEnvVar("SERVING_REQUEST_METRICS_BACKEND", "opencensus"),
+				),
+			}),
+	}, {
+		name: "concurrency=121 no owner digest resolved",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				Image:          "busybox",
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+			}}),
+			withContainerConcurrency(121),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+		),
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(func(container *corev1.Container) {
+					container.Image = "busybox@sha256:deadbeef"
+				}),
+				queueContainer(
+					withEnvVar("CONTAINER_CONCURRENCY", "121"),
+				),
+			}),
+	}, {
+		name: "concurrency=1 with owner",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				Image:          "busybox",
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+			}}),
+			withContainerConcurrency(42),
+			withOwnerReference("parent-config"),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+		),
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(func(container *corev1.Container) {
+					container.Image = "busybox@sha256:deadbeef"
+				}),
+				queueContainer(
+					withEnvVar("SERVING_CONFIGURATION", "parent-config"),
+					withEnvVar("CONTAINER_CONCURRENCY", "42"),
+				),
+			}),
+	}, {
+		name: "with http readiness probe",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				Image:          "busybox",
+				ReadinessProbe: withHTTPReadinessProbe(v1.DefaultUserPort),
+			}}),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+		),
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(func(container *corev1.Container) {
+					container.Image = "busybox@sha256:deadbeef"
+				}),
+				queueContainer(
+					withEnvVar("SERVING_READINESS_PROBE", `{"httpGet":{"path":"/","port":8080,"host":"127.0.0.1","scheme":"HTTP","httpHeaders":[{"name":"K-Kubelet-Probe","value":"queue"}]}}`),
+				),
+			}),
+	}, {
+		name: "with https backend",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				Image:          "busybox",
+				ReadinessProbe: withHTTPReadinessProbe(v1.DefaultUserPort),
+			}}),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+			func(revision *v1.Revision) {
+				revision.Annotations = map[string]string{
+					serving.QueueSidecarBackendSchemeAnnotationKey:   "https",
+					serving.QueueSidecarBackendCASecretAnnotationKey: "my-ca",
+				}
+			},
+		),
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(func(container *corev1.Container) {
+					container.Image = "busybox@sha256:deadbeef"
+				}),
+				queueContainer(
+					withEnvVar("SERVING_READINESS_PROBE", `{"httpGet":{"path":"/","port":8080,"host":"127.0.0.1","scheme":"HTTPS","httpHeaders":[{"name":"K-Kubelet-Probe","value":"queue"}]}}`),
+					withEnvVar("USER_SCHEME", "https"),
+					withEnvVar("USER_CA_BUNDLE_PATH", "/var/run/secrets/knative-backend-ca/ca.crt"),
+					func(container *corev1.Container) {
+						container.VolumeMounts = []corev1.VolumeMount{backendCAVolumeMount}
+					},
+				),
+			},
+			withAppendedVolumes(corev1.Volume{
+				Name: "knative-backend-ca",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: "my-ca",
+						Items: []corev1.KeyToPath{{
+							Key:  "ca.crt",
+							Path: "ca.crt",
+						}},
+					},
+				},
+			}),
+		),
+	}, {
+		name: "with tcp readiness probe",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				Image:          "busybox",
+				ReadinessProbe: withTCPReadinessProbe(12345),
+			}}),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+		),
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(func(container *corev1.Container) {
+					container.Image = "busybox@sha256:deadbeef"
+				}),
+				queueContainer(
+					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8080,"host":"127.0.0.1"}}`),
+				),
+			}),
+	}, {
+		name: "with shell readiness probe",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				Image:          "busybox",
+				ReadinessProbe: withExecReadinessProbe([]string{"echo", "hello"}),
+			}}),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+		),
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(
+					func(container *corev1.Container) {
+						container.Image = "busybox@sha256:deadbeef"
+						container.ReadinessProbe = withExecReadinessProbe([]string{"echo", "hello"})
+					}),
+				queueContainer(
+					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8080,"host":"127.0.0.1"}}`),
+				),
+			}),
+	}, {
+		name: "with HTTP liveness probe",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				Image:          "busybox",
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+				LivenessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{
+							Path: "/",
+						},
+					},
+				},
+			}}),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+		),
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(
+					func(container *corev1.Container) {
+						container.Image = "busybox@sha256:deadbeef"
+					},
+					withLivenessProbe(corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{
+							Path: "/",
+							Port: intstr.FromInt(networking.BackendHTTPPort),
+							HTTPHeaders: []corev1.HTTPHeader{{
+								Name:  network.KubeletProbeHeaderName,
+								Value: queue.Name,
+							}},
+						},
+					}),
+				),
+				queueContainer(),
+			}),
+	}, {
+		name: "with HTTP startup probe",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				Image:          "busybox",
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+				StartupProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{
+							Path: "/started",
+						},
+					},
+					FailureThreshold: 30,
+				},
+			}}),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+		),
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(
+					func(container *corev1.Container) {
+						container.Image = "busybox@sha256:deadbeef"
+						container.StartupProbe = &corev1.Probe{
+							Handler: corev1.Handler{
+								HTTPGet: &corev1.HTTPGetAction{
+									Path: "/started",
+									Port: intstr.FromInt(networking.BackendHTTPPort),
+									HTTPHeaders: []corev1.HTTPHeader{{
+										Name:  network.KubeletProbeHeaderName,
+										Value: queue.Name,
+									}},
+								},
+							},
+							FailureThreshold: 30,
+						}
+					},
+				),
+				queueContainer(),
+			}),
+	}, {
+		name: "with tcp liveness probe",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				Image:          "busybox",
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+				LivenessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						TCPSocket: &corev1.TCPSocketAction{},
+					}}}},
+			),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+		),
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(
+					func(container *corev1.Container) {
+						container.Image = "busybox@sha256:deadbeef"
+					},
+					withLivenessProbe(corev1.Handler{
+						TCPSocket: &corev1.TCPSocketAction{
+							Port: intstr.FromInt(v1.DefaultUserPort),
+						},
+					}),
+				),
+				queueContainer(),
 			}),
 	}, {
-		name: "with HTTP liveness probe",
+		name: "probe passthrough enabled",
 		rev: revision("bar", "foo",
 			withContainers([]corev1.Container{{
-				Name:           servingContainerName,
-				Image:          "busybox",
-				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
-				LivenessProbe: &corev1.Probe{
+				Name:  servingContainerName,
+				Image: "busybox",
+				ReadinessProbe: &corev1.Probe{
 					Handler: corev1.Handler{
-						HTTPGet: &corev1.HTTPGetAction{
-							Path: "/",
-						},
+						TCPSocket: &corev1.TCPSocketAction{},
 					},
+					PeriodSeconds: 5,
 				},
-			}}),
+				LivenessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						TCPSocket: &corev1.TCPSocketAction{},
+					},
+				}}},
+			),
 			WithContainerStatuses([]v1.ContainerStatus{{
 				ImageDigest: "busybox@sha256:deadbeef",
 			}}),
 		),
+		fc: &apicfg.Features{ProbePassthrough: apicfg.Enabled},
 		want: podSpec(
 			[]corev1.Container{
 				servingContainer(
 					func(container *corev1.Container) {
 						container.Image = "busybox@sha256:deadbeef"
+						container.ReadinessProbe = &corev1.Probe{
+							Handler: corev1.Handler{
+								TCPSocket: &corev1.TCPSocketAction{
+									Port: intstr.FromInt(v1.DefaultUserPort),
+								},
+							},
+							PeriodSeconds: 5,
+						}
 					},
 					withLivenessProbe(corev1.Handler{
-						HTTPGet: &corev1.HTTPGetAction{
-							Path: "/",
-							Port: intstr.FromInt(networking.BackendHTTPPort),
-							HTTPHeaders: []corev1.HTTPHeader{{
-								Name:  network.KubeletProbeHeaderName,
-								Value: queue.Name,
-							}},
+						TCPSocket: &corev1.TCPSocketAction{
+							Port: intstr.FromInt(v1.DefaultUserPort),
 						},
 					}),
 				),
 				queueContainer(),
 			}),
 	}, {
-		name: "with tcp liveness probe",
+		name: "probe passthrough allowed, https probe",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:  servingContainerName,
+				Image: "busybox",
+				ReadinessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{
+							Path:   "/ready",
+							Scheme: corev1.URISchemeHTTPS,
+						},
+					},
+				}}},
+			),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+			func(r *v1.Revision) {
+				r.Annotations = map[string]string{serving.ProbePassthroughAnnotationKey: "enabled"}
+			},
+		),
+		fc: &apicfg.Features{ProbePassthrough: apicfg.Allowed},
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(
+					func(container *corev1.Container) {
+						container.Image = "busybox@sha256:deadbeef"
+						container.ReadinessProbe = &corev1.Probe{
+							Handler: corev1.Handler{
+								HTTPGet: &corev1.HTTPGetAction{
+									Path:   "/ready",
+									Port:   intstr.FromInt(v1.DefaultUserPort),
+									Scheme: corev1.URISchemeHTTPS,
+								},
+							},
+						}
+					},
+				),
+				queueContainer(),
+			}),
+	}, {
+		name: "probe passthrough allowed, not annotated",
 		rev: revision("bar", "foo",
 			withContainers([]corev1.Container{{
 				Name:           servingContainerName,
 				Image:          "busybox",
 				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
-				LivenessProbe: &corev1.Probe{
-					Handler: corev1.Handler{
-						TCPSocket: &corev1.TCPSocketAction{},
-					}}}},
-			),
+			}}),
 			WithContainerStatuses([]v1.ContainerStatus{{
 				ImageDigest: "busybox@sha256:deadbeef",
 			}}),
 		),
+		fc: &apicfg.Features{ProbePassthrough: apicfg.Allowed},
 		want: podSpec(
 			[]corev1.Container{
 				servingContainer(
 					func(container *corev1.Container) {
 						container.Image = "busybox@sha256:deadbeef"
 					},
-					withLivenessProbe(corev1.Handler{
-						TCPSocket: &corev1.TCPSocketAction{
-							Port: intstr.FromInt(v1.DefaultUserPort),
-						},
-					}),
 				),
 				queueContainer(),
 			}),
@@ -971,6 +1786,85 @@ func TestMakePodSpec(t *testing.T) {
 					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8888,"host":"127.0.0.1"}}`),
 				),
 			}),
+	}, {
+		name: "sidecar probes and ports are passed through",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:  sidecarContainerName,
+				Image: "ubuntu",
+				Ports: []corev1.ContainerPort{{
+					Name:          "admin",
+					ContainerPort: 9901,
+				}},
+				ReadinessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(9901)},
+					},
+				},
+				LivenessProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: intstr.FromString("admin")},
+					},
+				},
+				StartupProbe: &corev1.Probe{
+					Handler: corev1.Handler{
+						TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(9901)},
+					},
+					FailureThreshold: 30,
+				},
+			}, {
+				Name:  servingContainerName,
+				Image: "busybox",
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: 8888,
+				}},
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+			}}),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "ubuntu@sha256:deadbffe",
+			}, {
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+		),
+		want: podSpec(
+			[]corev1.Container{
+				sidecarContainer(sidecarContainerName,
+					func(container *corev1.Container) {
+						container.Image = "ubuntu@sha256:deadbffe"
+						container.Ports = []corev1.ContainerPort{{
+							Name:          "admin",
+							ContainerPort: 9901,
+						}}
+						container.ReadinessProbe = &corev1.Probe{
+							Handler: corev1.Handler{
+								TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(9901)},
+							},
+						}
+						container.LivenessProbe = &corev1.Probe{
+							Handler: corev1.Handler{
+								HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: intstr.FromString("admin")},
+							},
+						}
+						container.StartupProbe = &corev1.Probe{
+							Handler: corev1.Handler{
+								TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(9901)},
+							},
+							FailureThreshold: 30,
+						}
+					},
+				),
+				servingContainer(
+					func(container *corev1.Container) {
+						container.Image = "busybox@sha256:deadbeef"
+						container.Ports[0].ContainerPort = 8888
+					},
+					withEnvVar("PORT", "8888"),
+				),
+				queueContainer(
+					withEnvVar("USER_PORT", "8888"),
+					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8888,"host":"127.0.0.1"}}`),
+				),
+			}),
 	}, {
 		name: "properties allowed by the webhook are passed through",
 		rev: revision("bar", "foo",
@@ -1064,6 +1958,66 @@ func TestMakePodSpec(t *testing.T) {
 			},
 			withAppendedVolumes(varLogVolume),
 		),
+	}, {
+		name: "log sidecar injected instead of var-log collection",
+		oc: metrics.ObservabilityConfig{
+			EnableVarLogCollection: true,
+		},
+		ls: &corev1.Container{
+			Name:  "fluent-bit",
+			Image: "fluent/fluent-bit",
+		},
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				Image:          "busybox",
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+				Ports:          buildContainerPorts(v1.DefaultUserPort),
+			}}),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}),
+			func(revision *v1.Revision) {
+				revision.Annotations = map[string]string{
+					serving.LogSidecarAnnotationKey: "true",
+				}
+			},
+		),
+		want: podSpec(
+			[]corev1.Container{
+				servingContainer(func(container *corev1.Container) {
+					container.Image = "busybox@sha256:deadbeef"
+					container.VolumeMounts = []corev1.VolumeMount{{
+						Name:      logSidecarVolume.Name,
+						MountPath: "/var/log",
+						SubPath:   servingContainerName,
+					}}
+				}),
+				queueContainer(
+					withEnvVar("SERVING_READINESS_PROBE", `{"tcpSocket":{"port":8080,"host":"127.0.0.1"}}`),
+				),
+				{
+					Name:  "fluent-bit",
+					Image: "fluent/fluent-bit",
+					VolumeMounts: []corev1.VolumeMount{{
+						Name:      logSidecarVolume.Name,
+						MountPath: "/var/log",
+						ReadOnly:  true,
+					}},
+					Env: []corev1.EnvVar{{
+						Name:  "K_REVISION",
+						Value: "bar",
+					}, {
+						Name:      "K_INTERNAL_POD_NAME",
+						ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}},
+					}, {
+						Name:      "K_INTERNAL_POD_NAMESPACE",
+						ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"}},
+					}},
+				},
+			},
+			withAppendedVolumes(logSidecarVolume),
+		),
 	}}
 
 	for _, test := range tests {
@@ -1073,6 +2027,13 @@ func TestMakePodSpec(t *testing.T) {
 			if test.dc != nil {
 				cfg.Defaults = test.dc
 			}
+			if test.nc != nil {
+				cfg.Networking = test.nc
+			}
+			if test.fc != nil {
+				cfg.Features = test.fc
+			}
+			cfg.LogSidecar = test.ls
 			got, err := makePodSpec(test.rev, cfg)
 			if err != nil {
 				t.Fatal("makePodSpec returned error:", err)
@@ -1176,6 +2137,28 @@ func TestMakeDeployment(t *testing.T) {
 		want: appsv1deployment(func(deploy *appsv1.Deployment) {
 			deploy.Spec.ProgressDeadlineSeconds = ptr.Int32(42)
 		}),
+	}, {
+		name: "with operator labels",
+		dc: deployment.Config{
+			QueueSidecarLabels: map[string]string{
+				"logs.example.com/source": "{{.Namespace}}.{{.Revision}}",
+				AppLabelKey:               "not-overridden",
+				"invalid-value":           "{{.Namespace}}/{{.Revision}}",
+			},
+		},
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				Image:          "ubuntu",
+				ReadinessProbe: withTCPReadinessProbe(12345),
+			}}),
+			WithContainerStatuses([]v1.ContainerStatus{{
+				ImageDigest: "busybox@sha256:deadbeef",
+			}}), withoutLabels),
+		want: appsv1deployment(func(deploy *appsv1.Deployment) {
+			deploy.Spec.Template.Labels = kmeta.UnionMaps(deploy.Spec.Template.Labels,
+				map[string]string{"logs.example.com/source": "foo.bar"})
+		}),
 	}, {
 		name: "cluster initial scale",
 		acMutator: func(ac *autoscalerconfig.Config) {
@@ -1218,8 +2201,9 @@ func TestMakeDeployment(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			ac := &autoscalerconfig.Config{
-				InitialScale:          1,
-				AllowZeroInitialScale: false,
+				InitialScale:              1,
+				AllowZeroInitialScale:     false,
+				CountLongLivedConnections: true,
 			}
 			if test.acMutator != nil {
 				test.acMutator(ac)
@@ -1246,3 +2230,280 @@ func TestMakeDeployment(t *testing.T) {
 		})
 	}
 }
+
+func TestTopologySpreadConstraints(t *testing.T) {
+	zones := corev1.TopologySpreadConstraint{
+		MaxSkew:           1,
+		TopologyKey:       "topology.kubernetes.io/zone",
+		WhenUnsatisfiable: corev1.ScheduleAnyway,
+	}
+	selector := &metav1.LabelSelector{
+		MatchLabels: map[string]string{"app": "foo"},
+	}
+	tests := []struct {
+		name       string
+		defaults   []corev1.TopologySpreadConstraint
+		annotation *string
+		want       []corev1.TopologySpreadConstraint
+	}{{
+		name: "none",
+	}, {
+		name:     "defaults spread the revision",
+		defaults: []corev1.TopologySpreadConstraint{zones},
+		want: []corev1.TopologySpreadConstraint{{
+			MaxSkew:           1,
+			TopologyKey:       "topology.kubernetes.io/zone",
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector:     makeSelector(revision("bar", "foo")),
+		}},
+	}, {
+		name: "defaults with selector",
+		defaults: []corev1.TopologySpreadConstraint{{
+			MaxSkew:           2,
+			TopologyKey:       "kubernetes.io/hostname",
+			WhenUnsatisfiable: corev1.DoNotSchedule,
+			LabelSelector:     selector,
+		}},
+		want: []corev1.TopologySpreadConstraint{{
+			MaxSkew:           2,
+			TopologyKey:       "kubernetes.io/hostname",
+			WhenUnsatisfiable: corev1.DoNotSchedule,
+			LabelSelector:     selector,
+		}},
+	}, {
+		name:     "annotation overrides",
+		defaults: []corev1.TopologySpreadConstraint{zones},
+		annotation: ptr.String(`[{"maxSkew": 3, "topologyKey": "kubernetes.io/hostname",
+			"whenUnsatisfiable": "DoNotSchedule"}]`),
+		want: []corev1.TopologySpreadConstraint{{
+			MaxSkew:           3,
+			TopologyKey:       "kubernetes.io/hostname",
+			WhenUnsatisfiable: corev1.DoNotSchedule,
+			LabelSelector:     makeSelector(revision("bar", "foo")),
+		}},
+	}, {
+		name:       "annotation packs",
+		defaults:   []corev1.TopologySpreadConstraint{zones},
+		annotation: ptr.String("[]"),
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rev := revision("bar", "foo")
+			if test.annotation != nil {
+				rev.Annotations = map[string]string{
+					serving.TopologySpreadConstraintsAnnotationKey: *test.annotation,
+				}
+			}
+			cfg := (&revCfg).DeepCopy()
+			cfg.Deployment = &deployment.Config{TopologySpreadConstraints: test.defaults}
+			got := topologySpreadConstraints(rev, cfg)
+			if !cmp.Equal(got, test.want) {
+				t.Error("topologySpreadConstraints (-want, +got):", cmp.Diff(test.want, got))
+			}
+		})
+	}
+}
+
+func TestPriorityClassName(t *testing.T) {
+	tests := []struct {
+		name       string
+		feature    apicfg.Flag
+		defaultPCN string
+		annotation *string
+		want       string
+	}{{
+		name:    "none",
+		feature: apicfg.Enabled,
+	}, {
+		name:       "disabled",
+		feature:    apicfg.Disabled,
+		defaultPCN: "serving",
+		annotation: ptr.String("critical"),
+	}, {
+		name:       "default",
+		feature:    apicfg.Enabled,
+		defaultPCN: "serving",
+		want:       "serving",
+	}, {
+		name:       "annotation overrides",
+		feature:    apicfg.Allowed,
+		defaultPCN: "serving",
+		annotation: ptr.String("critical"),
+		want:       "critical",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rev := revision("bar", "foo")
+			if test.annotation != nil {
+				rev.Annotations = map[string]string{
+					serving.PriorityClassNameAnnotationKey: *test.annotation,
+				}
+			}
+			cfg := (&revCfg).DeepCopy()
+			cfg.Features = &apicfg.Features{PodSpecPriorityClassName: test.feature}
+			cfg.Deployment = &deployment.Config{PriorityClassName: test.defaultPCN}
+			if got := priorityClassName(rev, cfg); got != test.want {
+				t.Errorf("priorityClassName = %q, want: %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestProgressDeadline(t *testing.T) {
+	rev := revision("bar", "foo")
+	cfg := (&revCfg).DeepCopy()
+	cfg.Deployment = &deployment.Config{ProgressDeadline: 2 * time.Minute}
+	if got, want := progressDeadline(rev, cfg), 2*time.Minute; got != want {
+		t.Errorf("progressDeadline = %v, want: %v", got, want)
+	}
+
+	rev.Annotations = map[string]string{serving.ProgressDeadlineAnnotationKey: "10m"}
+	if got, want := progressDeadline(rev, cfg), 10*time.Minute; got != want {
+		t.Errorf("progressDeadline = %v, want: %v", got, want)
+	}
+}
+
+func TestDeploymentStrategy(t *testing.T) {
+	recreate := &appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+	tests := []struct {
+		name       string
+		defaults   *appsv1.DeploymentStrategy
+		annotation *string
+		want       appsv1.DeploymentStrategy
+	}{{
+		name: "none",
+	}, {
+		name:     "default",
+		defaults: recreate,
+		want:     *recreate,
+	}, {
+		name:       "annotation overrides",
+		defaults:   recreate,
+		annotation: ptr.String(`{"type": "RollingUpdate", "rollingUpdate": {"maxSurge": 0}}`),
+		want: appsv1.DeploymentStrategy{
+			Type: appsv1.RollingUpdateDeploymentStrategyType,
+			RollingUpdate: &appsv1.RollingUpdateDeployment{
+				MaxSurge: &intstr.IntOrString{},
+			},
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rev := revision("bar", "foo")
+			if test.annotation != nil {
+				rev.Annotations = map[string]string{
+					serving.DeploymentStrategyAnnotationKey: *test.annotation,
+				}
+			}
+			cfg := (&revCfg).DeepCopy()
+			cfg.Deployment = &deployment.Config{DeploymentStrategy: test.defaults}
+			if got := deploymentStrategy(rev, cfg); !cmp.Equal(got, test.want) {
+				t.Error("deploymentStrategy (-want, +got):", cmp.Diff(test.want, got))
+			}
+		})
+	}
+}
+
+func TestSecurePodDefaults(t *testing.T) {
+	restricted := &corev1.SecurityContext{
+		AllowPrivilegeEscalation: ptr.Bool(false),
+		ReadOnlyRootFilesystem:   ptr.Bool(true),
+		RunAsNonRoot:             ptr.Bool(true),
+		Capabilities: &corev1.Capabilities{
+			Drop: []corev1.Capability{"ALL"},
+		},
+	}
+	tests := []struct {
+		name        string
+		feature     apicfg.Flag
+		annotations map[string]string
+		user        *corev1.SecurityContext
+		wantUser    *corev1.SecurityContext
+		wantQueue   *corev1.SecurityContext
+		wantSeccomp string
+	}{{
+		name:      "disabled",
+		feature:   apicfg.Disabled,
+		wantQueue: queueSecurityContext,
+	}, {
+		name:      "allowed",
+		feature:   apicfg.Allowed,
+		user:      &corev1.SecurityContext{ReadOnlyRootFilesystem: ptr.Bool(false)},
+		wantUser:  &corev1.SecurityContext{ReadOnlyRootFilesystem: ptr.Bool(false)},
+		wantQueue: queueSecurityContext,
+	}, {
+		name:        "enabled",
+		feature:     apicfg.Enabled,
+		wantUser:    restricted,
+		wantQueue:   restricted,
+		wantSeccomp: corev1.SeccompProfileRuntimeDefault,
+	}, {
+		name:    "opt-out",
+		feature: apicfg.Enabled,
+		annotations: map[string]string{
+			corev1.SeccompPodAnnotationKey: "unconfined",
+		},
+		user: &corev1.SecurityContext{
+			RunAsUser:              ptr.Int64(0),
+			ReadOnlyRootFilesystem: ptr.Bool(false),
+			Capabilities: &corev1.Capabilities{
+				Add: []corev1.Capability{"NET_ADMIN"},
+			},
+		},
+		wantUser: &corev1.SecurityContext{
+			AllowPrivilegeEscalation: ptr.Bool(false),
+			RunAsUser:                ptr.Int64(0),
+			ReadOnlyRootFilesystem:   ptr.Bool(false),
+			Capabilities: &corev1.Capabilities{
+				Add: []corev1.Capability{"NET_ADMIN"},
+			},
+		},
+		wantQueue:   restricted,
+		wantSeccomp: "unconfined",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rev := revision("bar", "foo", withContainers([]corev1.Container{{
+				Name:            servingContainerName,
+				Image:           "busybox",
+				ReadinessProbe:  withTCPReadinessProbe(v1.DefaultUserPort),
+				SecurityContext: test.user,
+			}}))
+			rev.Annotations = test.annotations
+			rev.Spec.InitContainers = []corev1.Container{{
+				Name:            "init",
+				Image:           "busybox",
+				SecurityContext: test.user.DeepCopy(),
+			}}
+			cfg := (&revCfg).DeepCopy()
+			cfg.Features = &apicfg.Features{SecurePodDefaults: test.feature}
+			got, err := MakeDeployment(rev, cfg)
+			if err != nil {
+				t.Fatal("MakeDeployment() =", err)
+			}
+			podSpec := got.Spec.Template.Spec
+			if !cmp.Equal(podSpec.Containers[0].SecurityContext, test.wantUser) {
+				t.Error("user container securityContext (-want, +got):",
+					cmp.Diff(test.wantUser, podSpec.Containers[0].SecurityContext))
+			}
+			if !cmp.Equal(podSpec.InitContainers[0].SecurityContext, test.wantUser) {
+				t.Error("init container securityContext (-want, +got):",
+					cmp.Diff(test.wantUser, podSpec.InitContainers[0].SecurityContext))
+			}
+			if !cmp.Equal(podSpec.Containers[1].SecurityContext, test.wantQueue) {
+				t.Error("queue-proxy securityContext (-want, +got):",
+					cmp.Diff(test.wantQueue, podSpec.Containers[1].SecurityContext))
+			}
+			if got := got.Spec.Template.Annotations[corev1.SeccompPodAnnotationKey]; got != test.wantSeccomp {
+				t.Errorf("seccomp annotation = %q, want: %q", got, test.wantSeccomp)
+			}
+			if _, ok := got.Annotations[corev1.SeccompPodAnnotationKey]; ok != (test.annotations != nil) {
+				t.Error("Deployment annotations =", got.Annotations)
+			}
+		})
+	}
+}
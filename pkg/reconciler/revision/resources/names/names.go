@@ -28,6 +28,12 @@ func ImageCache(rev kmeta.Accessor) string {
 	return kmeta.ChildName(rev.GetName(), "-cache")
 }
 
+// PodDisruptionBudget returns the precomputed name for the revision pod
+// disruption budget.
+func PodDisruptionBudget(rev kmeta.Accessor) string {
+	return kmeta.ChildName(rev.GetName(), "-pdb")
+}
+
 // PA returns the PA name for the revision.
 func PA(rev kmeta.Accessor) string {
 	return rev.GetName()
@@ -77,6 +77,15 @@ func TestNamer(t *testing.T) {
 		},
 		f:    ImageCache,
 		want: "uuuuuuuuuuuuuuuuuuuuuuuuuca47ad1ce8479df271ec0d23653ce256-cache",
+	}, {
+		name: "PodDisruptionBudget",
+		rev: &v1.Revision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "foo",
+			},
+		},
+		f:    PodDisruptionBudget,
+		want: "foo-pdb",
 	}, {
 		name: "ImageCache",
 		rev: &v1.Revision{
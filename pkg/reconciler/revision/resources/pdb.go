@@ -0,0 +1,62 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"strconv"
+
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/pkg/kmeta"
+	"knative.dev/serving/pkg/apis/autoscaling"
+	v1 "knative.dev/serving/pkg/apis/serving/v1"
+	"knative.dev/serving/pkg/reconciler/revision/config"
+	"knative.dev/serving/pkg/reconciler/revision/resources/names"
+)
+
+// minScaleForPodDisruptionBudget is the minScale from which the revisions get
+// a pod disruption budget. A budget for a single pod would block node drains.
+const minScaleForPodDisruptionBudget = 2
+
+// MakePodDisruptionBudget makes the pod disruption budget of a revision, or
+// returns nil if it should have none.
+func MakePodDisruptionBudget(rev *v1.Revision, cfg *config.Config) *policyv1beta1.PodDisruptionBudget {
+	maxUnavailable := cfg.Deployment.PodDisruptionBudgetMaxUnavailable
+	if maxUnavailable == nil {
+		return nil
+	}
+	// The annotation has been validated by the webhook.
+	if min, err := strconv.Atoi(rev.Annotations[autoscaling.MinScaleAnnotationKey]); err != nil || min < minScaleForPodDisruptionBudget {
+		return nil
+	}
+
+	mu := *maxUnavailable
+	return &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            names.PodDisruptionBudget(rev),
+			Namespace:       rev.Namespace,
+			Labels:          makeLabels(rev),
+			Annotations:     makeAnnotations(rev),
+			OwnerReferences: []metav1.OwnerReference{*kmeta.NewControllerRef(rev)},
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			MaxUnavailable: &mu,
+			Selector:       makeSelector(rev),
+		},
+	}
+}
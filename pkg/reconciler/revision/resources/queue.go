@@ -19,13 +19,17 @@ package resources
 import (
 	"fmt"
 	"math"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
 	network "knative.dev/networking/pkg"
 	pkgnet "knative.dev/networking/pkg/apis/networking"
 	"knative.dev/pkg/metrics"
@@ -44,6 +48,7 @@ import (
 const (
 	localAddress             = "127.0.0.1"
 	requestQueueHTTPPortName = "queue-port"
+	queueHTTPSPortName       = "queue-https"
 	profilingPortName        = "profiling-port"
 )
 
@@ -56,6 +61,10 @@ var (
 		Name:          requestQueueHTTPPortName,
 		ContainerPort: networking.BackendHTTP2Port,
 	}
+	queueHTTPSPort = corev1.ContainerPort{
+		Name:          queueHTTPSPortName,
+		ContainerPort: networking.BackendHTTPSPort,
+	}
 	queueNonServingPorts = []corev1.ContainerPort{{
 		// Provides health checks and lifecycle hooks.
 		Name:          v1.QueueAdminPortName,
@@ -76,8 +85,34 @@ var (
 	queueSecurityContext = &corev1.SecurityContext{
 		AllowPrivilegeEscalation: ptr.Bool(false),
 	}
+
+	// queueRestrictedSecurityContext is the securityContext of queue-proxy
+	// with the secure pod defaults, see apiconfig.Features.SecurePodDefaults.
+	queueRestrictedSecurityContext = &corev1.SecurityContext{
+		AllowPrivilegeEscalation: ptr.Bool(false),
+		ReadOnlyRootFilesystem:   ptr.Bool(true),
+		RunAsNonRoot:             ptr.Bool(true),
+		Capabilities: &corev1.Capabilities{
+			Drop: []corev1.Capability{"ALL"},
+		},
+	}
 )
 
+// queueSidecarResourceAnnotations are the annotations overriding the
+// queue-proxy resources per revision.
+var queueSidecarResourceAnnotations = []struct {
+	key   string
+	name  corev1.ResourceName
+	limit bool
+}{
+	{key: serving.QueueSidecarCPUResourceRequestAnnotationKey, name: corev1.ResourceCPU},
+	{key: serving.QueueSidecarCPUResourceLimitAnnotationKey, name: corev1.ResourceCPU, limit: true},
+	{key: serving.QueueSidecarMemoryResourceRequestAnnotationKey, name: corev1.ResourceMemory},
+	{key: serving.QueueSidecarMemoryResourceLimitAnnotationKey, name: corev1.ResourceMemory, limit: true},
+	{key: serving.QueueSidecarEphemeralStorageResourceRequestAnnotationKey, name: corev1.ResourceEphemeralStorage},
+	{key: serving.QueueSidecarEphemeralStorageResourceLimitAnnotationKey, name: corev1.ResourceEphemeralStorage, limit: true},
+}
+
 func createQueueResources(cfg *deployment.Config, annotations map[string]string, userContainer *corev1.Container) corev1.ResourceRequirements {
 	resourceRequests := corev1.ResourceList{}
 	resourceLimits := corev1.ResourceList{}
@@ -110,7 +145,7 @@ func createQueueResources(cfg *deployment.Config, annotations map[string]string,
 	var requestCPU, limitCPU, requestMemory, limitMemory resource.Quantity
 
 	if resourceFraction, ok := fractionFromPercentage(annotations, serving.QueueSideCarResourcePercentageAnnotation); ok {
-		if ok, requestCPU = computeResourceRequirements(userContainer.Resources.Requests.Cpu(), resourceFraction, queueContainerRequestCPU); ok {
+		if ok, requestCPU = computeResourceRequirements(requestOrLimit(userContainer, corev1.ResourceCPU), resourceFraction, queueContainerRequestCPU); ok {
 			resourceRequests[corev1.ResourceCPU] = requestCPU
 		}
 
@@ -118,7 +153,7 @@ func createQueueResources(cfg *deployment.Config, annotations map[string]string,
 			resourceLimits[corev1.ResourceCPU] = limitCPU
 		}
 
-		if ok, requestMemory = computeResourceRequirements(userContainer.Resources.Requests.Memory(), resourceFraction, queueContainerRequestMemory); ok {
+		if ok, requestMemory = computeResourceRequirements(requestOrLimit(userContainer, corev1.ResourceMemory), resourceFraction, queueContainerRequestMemory); ok {
 			resourceRequests[corev1.ResourceMemory] = requestMemory
 		}
 
@@ -127,6 +162,19 @@ func createQueueResources(cfg *deployment.Config, annotations map[string]string,
 		}
 	}
 
+	for _, r := range queueSidecarResourceAnnotations {
+		// The values are validated in the webhook.
+		q, err := resource.ParseQuantity(annotations[r.key])
+		if err != nil {
+			continue
+		}
+		if r.limit {
+			resourceLimits[r.name] = q
+		} else {
+			resourceRequests[r.name] = q
+		}
+	}
+
 	resources := corev1.ResourceRequirements{
 		Requests: resourceRequests,
 	}
@@ -137,6 +185,17 @@ func createQueueResources(cfg *deployment.Config, annotations map[string]string,
 	return resources
 }
 
+// requestOrLimit returns the request of the resource of the container, or its
+// limit if the request is not set, like Kubernetes defaults it, e.g. for the
+// GPU containers setting only their limits.
+func requestOrLimit(container *corev1.Container, name corev1.ResourceName) *resource.Quantity {
+	if q, ok := container.Resources.Requests[name]; ok {
+		return &q
+	}
+	q := container.Resources.Limits[name]
+	return &q
+}
+
 func computeResourceRequirements(resourceQuantity *resource.Quantity, fraction float64, boundary resourceBoundary) (bool, resource.Quantity) {
 	if resourceQuantity.IsZero() {
 		return false, resource.Quantity{}
@@ -218,13 +277,25 @@ func makeQueueProbe(in *corev1.Probe) *corev1.Probe {
 	}
 }
 
+// requestLogSettings returns whether queue-proxy writes the request logs of
+// the revision and the template to use, taking the revision's annotations into
+// account. An unknown template name falls back to the default template.
+func requestLogSettings(rev *v1.Revision, cfg *config.Config) (bool, string) {
+	enabled, tmpl := cfg.Observability.EnableRequestLog, cfg.Observability.RequestLogTemplate
+	if t, ok := cfg.RequestLogTemplates[rev.RequestLogTemplateName()]; ok {
+		tmpl = t
+	}
+	if e, ok := rev.RequestLogEnabled(); ok {
+		enabled = e
+	}
+	return enabled && tmpl != "", tmpl
+}
+
 // makeQueueContainer creates the container spec for the queue sidecar.
 func makeQueueContainer(rev *v1.Revision, cfg *config.Config) (*corev1.Container, error) {
-	configName := ""
-	if owner := metav1.GetControllerOf(rev); owner != nil && owner.Kind == "Configuration" {
-		configName = owner.Name
-	}
-	serviceName := rev.Labels[serving.ServiceLabelKey]
+	templateData := queueSidecarTemplateData(rev)
+	configName := templateData.Configuration
+	serviceName := templateData.Service
 
 	userPort := getUserPort(rev)
 
@@ -237,6 +308,18 @@ func makeQueueContainer(rev *v1.Revision, cfg *config.Config) (*corev1.Container
 	if rev.Spec.TimeoutSeconds != nil {
 		ts = *rev.Spec.TimeoutSeconds
 	}
+	responseStartTS := int64(0)
+	if rev.Spec.ResponseStartTimeoutSeconds != nil {
+		responseStartTS = *rev.Spec.ResponseStartTimeoutSeconds
+	}
+	idleTS := int64(0)
+	if rev.Spec.IdleTimeoutSeconds != nil {
+		idleTS = *rev.Spec.IdleTimeoutSeconds
+	}
+	drainTS := int64(0)
+	if rev.Spec.DrainTimeoutSeconds != nil {
+		drainTS = *rev.Spec.DrainTimeoutSeconds
+	}
 
 	ports := queueNonServingPorts
 	if cfg.Observability.EnableProfiling {
@@ -252,21 +335,41 @@ func makeQueueContainer(rev *v1.Revision, cfg *config.Config) (*corev1.Container
 
 	container := rev.Spec.GetContainer()
 	rp := container.ReadinessProbe.DeepCopy()
+	if probePassthrough(rev, cfg) && isPassthroughProbe(rp) {
+		// The user's probe stays on the user container, so only
+		// check that the user port accepts connections.
+		rp = &corev1.Probe{
+			Handler: corev1.Handler{
+				TCPSocket: &corev1.TCPSocketAction{},
+			},
+		}
+	}
 
 	applyReadinessProbeDefaults(rp, userPort)
+	if backendHTTPS(rev) && rp != nil && rp.HTTPGet != nil {
+		// The user container only serves HTTPS.
+		rp.HTTPGet.Scheme = corev1.URISchemeHTTPS
+	}
 
 	probeJSON, err := readiness.EncodeProbe(rp)
 	if err != nil {
 		return nil, fmt.Errorf("failed to serialize readiness probe: %w", err)
 	}
 
-	return &corev1.Container{
+	enableRequestLog, requestLogTemplate := requestLogSettings(rev, cfg)
+
+	securityContext := queueSecurityContext
+	if securePodDefaults(cfg) {
+		securityContext = queueRestrictedSecurityContext
+	}
+
+	c := &corev1.Container{
 		Name:            QueueContainerName,
 		Image:           cfg.Deployment.QueueSidecarImage,
 		Resources:       createQueueResources(cfg.Deployment, rev.GetAnnotations(), container),
 		Ports:           ports,
 		ReadinessProbe:  makeQueueProbe(rp),
-		SecurityContext: queueSecurityContext,
+		SecurityContext: securityContext,
 		Env: []corev1.EnvVar{{
 			Name:  "SERVING_NAMESPACE",
 			Value: rev.Namespace,
@@ -285,9 +388,21 @@ func makeQueueContainer(rev *v1.Revision, cfg *config.Config) (*corev1.Container
 		}, {
 			Name:  "CONTAINER_CONCURRENCY",
 			Value: strconv.Itoa(int(rev.Spec.GetContainerConcurrency())),
+		}, {
+			Name:  "COUNT_LONG_LIVED_CONNECTIONS",
+			Value: strconv.FormatBool(cfg.Autoscaler.CountLongLivedConnections),
 		}, {
 			Name:  "REVISION_TIMEOUT_SECONDS",
 			Value: strconv.Itoa(int(ts)),
+		}, {
+			Name:  "REVISION_RESPONSE_START_TIMEOUT_SECONDS",
+			Value: strconv.Itoa(int(responseStartTS)),
+		}, {
+			Name:  "REVISION_IDLE_TIMEOUT_SECONDS",
+			Value: strconv.Itoa(int(idleTS)),
+		}, {
+			Name:  "REVISION_DRAIN_TIMEOUT_SECONDS",
+			Value: strconv.Itoa(int(drainTS)),
 		}, {
 			Name: "SERVING_POD",
 			ValueFrom: &corev1.EnvVarSource{
@@ -310,10 +425,10 @@ func makeQueueContainer(rev *v1.Revision, cfg *config.Config) (*corev1.Container
 			Value: loggingLevel,
 		}, {
 			Name:  "SERVING_REQUEST_LOG_TEMPLATE",
-			Value: cfg.Observability.RequestLogTemplate,
+			Value: requestLogTemplate,
 		}, {
 			Name:  "SERVING_ENABLE_REQUEST_LOG",
-			Value: strconv.FormatBool(cfg.Observability.EnableRequestLog),
+			Value: strconv.FormatBool(enableRequestLog),
 		}, {
 			Name:  "SERVING_REQUEST_METRICS_BACKEND",
 			Value: cfg.Observability.RequestMetricsBackend,
@@ -332,6 +447,12 @@ func makeQueueContainer(rev *v1.Revision, cfg *config.Config) (*corev1.Container
 		}, {
 			Name:  "TRACING_CONFIG_SAMPLE_RATE",
 			Value: fmt.Sprint(cfg.Tracing.SampleRate),
+		}, {
+			Name:  "TRACING_CONFIG_OTLP_ENDPOINT",
+			Value: cfg.Tracing.OTLPEndpoint,
+		}, {
+			Name:  "TRACING_CONFIG_OTLP_INSECURE",
+			Value: strconv.FormatBool(cfg.Tracing.OTLPInsecure),
 		}, {
 			Name:  "USER_PORT",
 			Value: strconv.Itoa(int(userPort)),
@@ -354,7 +475,226 @@ func makeQueueContainer(rev *v1.Revision, cfg *config.Config) (*corev1.Container
 			Name:  "METRICS_COLLECTOR_ADDRESS",
 			Value: cfg.Observability.MetricsCollectorAddress,
 		}},
-	}, nil
+	}
+
+	// Pass through the header manipulations, only when requested.
+	for _, h := range []struct {
+		annotation, env string
+	}{
+		{serving.RequestHeadersSetAnnotationKey, "SERVING_REQUEST_HEADERS_SET"},
+		{serving.RequestHeadersRemoveAnnotationKey, "SERVING_REQUEST_HEADERS_REMOVE"},
+		{serving.ResponseHeadersSetAnnotationKey, "SERVING_RESPONSE_HEADERS_SET"},
+		{serving.ResponseHeadersRemoveAnnotationKey, "SERVING_RESPONSE_HEADERS_REMOVE"},
+	} {
+		if v, ok := rev.Annotations[h.annotation]; ok {
+			c.Env = append(c.Env, corev1.EnvVar{Name: h.env, Value: v})
+		}
+	}
+
+	if max := rev.MaxRequestBodySize(); max > 0 {
+		c.Env = append(c.Env, corev1.EnvVar{
+			Name:  "SERVING_MAX_REQUEST_BODY_SIZE",
+			Value: strconv.FormatInt(max, 10),
+		})
+	}
+
+	if mode := rev.Annotations[serving.ConcurrencyModeAnnotationKey]; mode != "" {
+		c.Env = append(c.Env, corev1.EnvVar{
+			Name:  "SERVING_CONCURRENCY_MODE",
+			Value: mode,
+		})
+		if depth, ok := rev.Annotations[serving.ConcurrencyQueueDepthAnnotationKey]; ok {
+			c.Env = append(c.Env, corev1.EnvVar{
+				Name:  "SERVING_CONCURRENCY_QUEUE_DEPTH",
+				Value: depth,
+			})
+		}
+		if timeout, ok := rev.Annotations[serving.ConcurrencyQueueTimeoutAnnotationKey]; ok {
+			c.Env = append(c.Env, corev1.EnvVar{
+				Name:  "SERVING_CONCURRENCY_QUEUE_TIMEOUT",
+				Value: timeout,
+			})
+		}
+	}
+
+	if compression := rev.Annotations[serving.ResponseCompressionAnnotationKey]; compression != "" {
+		c.Env = append(c.Env, corev1.EnvVar{
+			Name:  "SERVING_RESPONSE_COMPRESSION",
+			Value: compression,
+		}, corev1.EnvVar{
+			Name:  "SERVING_RESPONSE_COMPRESSION_TYPES",
+			Value: rev.Annotations[serving.ResponseCompressionTypesAnnotationKey],
+		})
+	}
+
+	if service, ok := rev.GRPCProbeService(); ok {
+		c.Env = append(c.Env, corev1.EnvVar{
+			Name:  "SERVING_GRPC_PROBE",
+			Value: "true",
+		}, corev1.EnvVar{
+			Name:  "SERVING_GRPC_PROBE_SERVICE",
+			Value: service,
+		})
+	}
+
+	if cfg.Networking != nil && cfg.Networking.DataplaneProxyProtocol {
+		c.Env = append(c.Env, corev1.EnvVar{
+			Name:  "SERVING_PROXY_PROTOCOL",
+			Value: "true",
+		}, corev1.EnvVar{
+			Name:  "SERVING_PROXY_PROTOCOL_TRUSTED_CIDRS",
+			Value: strings.Join(cfg.Networking.DataplaneProxyProtocolTrustedCIDRs, ","),
+		})
+	}
+
+	if endpoint, ok := rev.Annotations[serving.ConcurrencyStateEndpointAnnotationKey]; ok {
+		c.Env = append(c.Env, corev1.EnvVar{
+			Name:  "CONCURRENCY_STATE_ENDPOINT",
+			Value: endpoint,
+		}, corev1.EnvVar{
+			Name:  "CONCURRENCY_STATE_TOKEN_PATH",
+			Value: filepath.Join(queue.ConcurrencyStateTokenMountPath, queue.ConcurrencyStateTokenFilename),
+		}, corev1.EnvVar{
+			Name: "HOST_IP",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "status.hostIP",
+				},
+			},
+		})
+		c.VolumeMounts = append(c.VolumeMounts, concurrencyStateTokenVolumeMount)
+	}
+
+	if named := getNamedPorts(rev); len(named) > 0 {
+		pairs := make([]string, 0, len(named))
+		for _, p := range named {
+			pairs = append(pairs, p.Name+"="+strconv.Itoa(int(p.ContainerPort)))
+		}
+		c.Env = append(c.Env, corev1.EnvVar{
+			Name:  "USER_NAMED_PORTS",
+			Value: strings.Join(pairs, ","),
+		})
+	}
+
+	if rev.UsesUnixSocket() {
+		c.Env = append(c.Env, corev1.EnvVar{
+			Name:  "USER_UNIX_SOCKET",
+			Value: queue.UnixSocketPath,
+		})
+		c.VolumeMounts = append(c.VolumeMounts, unixSocketVolumeMount)
+	}
+
+	if backendHTTPS(rev) {
+		c.Env = append(c.Env, corev1.EnvVar{
+			Name:  "USER_SCHEME",
+			Value: serving.BackendSchemeHTTPS,
+		})
+		if _, ok := backendCASecret(rev); ok {
+			c.Env = append(c.Env, corev1.EnvVar{
+				Name:  "USER_CA_BUNDLE_PATH",
+				Value: queue.BackendCAMountPath + "/" + serving.BackendCASecretKey,
+			})
+			c.VolumeMounts = append(c.VolumeMounts, backendCAVolumeMount)
+		}
+	}
+
+	c.Env = append(c.Env, readinessProbeTimingEnv(rev, cfg)...)
+	c.Env = append(c.Env, transportEnv(cfg)...)
+
+	if port, ok := rev.Annotations[serving.QueueSidecarDebugPortAnnotationKey]; ok {
+		c.Env = append(c.Env, corev1.EnvVar{
+			Name:  "SERVING_DEBUG_PORT",
+			Value: port,
+		})
+	}
+
+	if path, ok := userPreStopPath(rev); ok {
+		c.Env = append(c.Env, corev1.EnvVar{
+			Name:  "USER_PRESTOP_PATH",
+			Value: path,
+		})
+	}
+
+	if backendTLSEnabled(cfg) {
+		// Serve TLS to the activator on the side, the ingress still
+		// talks to the plain serving port.
+		c.Ports = append(c.Ports, queueHTTPSPort)
+		c.Env = append(c.Env, corev1.EnvVar{
+			Name:  "BACKEND_TLS_CERTS_DIR",
+			Value: networking.BackendCertsMountPath,
+		})
+		if v := networking.FormatTLSVersion(cfg.Networking.DataplaneTLSMinVersion); v != "" {
+			c.Env = append(c.Env, corev1.EnvVar{
+				Name:  "BACKEND_TLS_MIN_VERSION",
+				Value: v,
+			})
+		}
+		if suites := cfg.Networking.DataplaneTLSCipherSuites; len(suites) > 0 {
+			c.Env = append(c.Env, corev1.EnvVar{
+				Name:  "BACKEND_TLS_CIPHER_SUITES",
+				Value: networking.FormatCipherSuites(suites),
+			})
+		}
+		if cfg.Networking.DataplaneTLSFIPSMode {
+			c.Env = append(c.Env, corev1.EnvVar{
+				Name:  "BACKEND_TLS_FIPS_MODE",
+				Value: "true",
+			})
+		}
+		c.VolumeMounts = append(c.VolumeMounts, backendCertsVolumeMount)
+	}
+
+	extraEnv, err := queueSidecarExtraEnv(cfg.Deployment.QueueSidecarEnv, templateData, c.Env)
+	if err != nil {
+		return nil, err
+	}
+	c.Env = append(c.Env, extraEnv...)
+	return c, nil
+}
+
+// queueSidecarTemplateData returns the data the operator's templates of the
+// queue sidecar's extra environment variables and labels are executed with.
+func queueSidecarTemplateData(rev *v1.Revision) deployment.QueueSidecarTemplateData {
+	configName := ""
+	if owner := metav1.GetControllerOf(rev); owner != nil && owner.Kind == "Configuration" {
+		configName = owner.Name
+	}
+	return deployment.QueueSidecarTemplateData{
+		Namespace:     rev.Namespace,
+		Revision:      rev.Name,
+		Configuration: configName,
+		Service:       rev.Labels[serving.ServiceLabelKey],
+	}
+}
+
+// queueSidecarExtraEnv executes the templates of the operator's extra
+// environment variables, leaving out the ones already in env.
+func queueSidecarExtraEnv(templates map[string]string, data deployment.QueueSidecarTemplateData, env []corev1.EnvVar) ([]corev1.EnvVar, error) {
+	if len(templates) == 0 {
+		return nil, nil
+	}
+	existing := make(sets.String, len(env))
+	for _, e := range env {
+		existing.Insert(e.Name)
+	}
+	// Sort the names to keep the container spec stable.
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		if !existing.Has(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	extra := make([]corev1.EnvVar, 0, len(names))
+	for _, name := range names {
+		value, err := deployment.ExecuteQueueSidecarTemplate(templates[name], data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute the template of %s: %w", name, err)
+		}
+		extra = append(extra, corev1.EnvVar{Name: name, Value: value})
+	}
+	return extra, nil
 }
 
 func applyReadinessProbeDefaults(p *corev1.Probe, port int32) {
@@ -390,3 +730,84 @@ func applyReadinessProbeDefaults(p *corev1.Probe, port int32) {
 		p.TimeoutSeconds = 1
 	}
 }
+
+// backendHTTPS returns true if queue-proxy speaks TLS to the user container,
+// see serving.QueueSidecarBackendSchemeAnnotationKey.
+func backendHTTPS(rev *v1.Revision) bool {
+	return rev.Annotations[serving.QueueSidecarBackendSchemeAnnotationKey] == serving.BackendSchemeHTTPS
+}
+
+// backendCASecret returns the name of the Secret with the CA bundle of the
+// user container serving HTTPS, if any.
+func backendCASecret(rev *v1.Revision) (string, bool) {
+	name, ok := rev.Annotations[serving.QueueSidecarBackendCASecretAnnotationKey]
+	return name, ok && backendHTTPS(rev)
+}
+
+// readinessProbeTimingEnv returns the environment variables configuring the
+// timing of the queue-proxy's aggressive readiness probe, from the deployment
+// config overridden by the revision's annotations. Unset values are left out,
+// so the queue-proxy falls back to its defaults.
+func readinessProbeTimingEnv(rev *v1.Revision, cfg *config.Config) []corev1.EnvVar {
+	var env []corev1.EnvVar
+	add := func(name, annotation, value string, set bool) {
+		if v, ok := rev.Annotations[annotation]; ok {
+			value, set = v, true
+		}
+		if set {
+			env = append(env, corev1.EnvVar{Name: name, Value: value})
+		}
+	}
+
+	d := cfg.Deployment
+	add("SERVING_READINESS_PROBE_PERIOD", serving.QueueSidecarProbePeriodAnnotationKey,
+		d.QueueSidecarProbePeriod.String(), d.QueueSidecarProbePeriod > 0)
+	add("SERVING_READINESS_PROBE_TIMEOUT", serving.QueueSidecarProbeTimeoutAnnotationKey,
+		d.QueueSidecarProbeTimeout.String(), d.QueueSidecarProbeTimeout > 0)
+	add("SERVING_READINESS_PROBE_BACKOFF_FACTOR", serving.QueueSidecarProbeBackoffFactorAnnotationKey,
+		strconv.FormatFloat(d.QueueSidecarProbeBackoffFactor, 'f', -1, 64), d.QueueSidecarProbeBackoffFactor > 0)
+	add("SERVING_READINESS_PROBE_MAX_PERIOD", serving.QueueSidecarProbeMaxPeriodAnnotationKey,
+		d.QueueSidecarProbeMaxPeriod.String(), d.QueueSidecarProbeMaxPeriod > 0)
+	return env
+}
+
+// transportEnv returns the environment variables tuning the queue-proxy's
+// transport to the user container, from the deployment config. Unset values
+// are left out, so the queue-proxy falls back to its defaults.
+func transportEnv(cfg *config.Config) []corev1.EnvVar {
+	var env []corev1.EnvVar
+	d := cfg.Deployment
+	if d.QueueSidecarMaxIdleConnsPerHost > 0 {
+		env = append(env, corev1.EnvVar{
+			Name:  "SERVING_MAX_IDLE_CONNS_PER_HOST",
+			Value: strconv.Itoa(int(d.QueueSidecarMaxIdleConnsPerHost)),
+		})
+	}
+	if d.QueueSidecarTLSHandshakeTimeout > 0 {
+		env = append(env, corev1.EnvVar{
+			Name:  "SERVING_TLS_HANDSHAKE_TIMEOUT",
+			Value: d.QueueSidecarTLSHandshakeTimeout.String(),
+		})
+	}
+	if d.QueueSidecarDisableKeepAlives {
+		env = append(env, corev1.EnvVar{
+			Name:  "SERVING_DISABLE_KEEP_ALIVES",
+			Value: "true",
+		})
+	}
+	return env
+}
+
+// userPreStopPath returns the path on the user port queue-proxy sends a GET
+// request to once drained, set either by serving.PreStopPathAnnotationKey
+// or by the preStop hook of the serving container, which the kubelet can't
+// run along with the drain hook of queue-proxy.
+func userPreStopPath(rev *v1.Revision) (string, bool) {
+	if path, ok := rev.Annotations[serving.PreStopPathAnnotationKey]; ok {
+		return path, true
+	}
+	if lc := rev.Spec.GetContainer().Lifecycle; lc != nil && lc.PreStop != nil && lc.PreStop.HTTPGet != nil {
+		return lc.PreStop.HTTPGet.Path, true
+	}
+	return "", false
+}
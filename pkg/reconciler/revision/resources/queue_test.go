@@ -22,6 +22,7 @@ import (
 	"sort"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"go.uber.org/zap/zapcore"
@@ -46,6 +47,7 @@ import (
 	"knative.dev/serving/pkg/deployment"
 	"knative.dev/serving/pkg/queue"
 	"knative.dev/serving/pkg/reconciler/revision/config"
+	"knative.dev/serving/pkg/tracing"
 
 	_ "knative.dev/pkg/metrics/testing"
 	_ "knative.dev/pkg/system/testing"
@@ -68,13 +70,14 @@ var (
 
 	// The default CM values.
 	asConfig = autoscalerconfig.Config{
-		InitialScale:          1,
-		AllowZeroInitialScale: false,
+		InitialScale:              1,
+		AllowZeroInitialScale:     false,
+		CountLongLivedConnections: true,
 	}
 	deploymentConfig deployment.Config
 	logConfig        logging.Config
 	obsConfig        metrics.ObservabilityConfig
-	traceConfig      tracingconfig.Config
+	traceConfig      tracing.Config
 	defaults, _      = apicfg.NewDefaultsConfigFromMap(nil)
 	revCfg           = config.Config{
 		Config: &apicfg.Config{
@@ -98,7 +101,9 @@ func TestMakeQueueContainer(t *testing.T) {
 		lc   logging.Config
 		nc   network.Config
 		oc   metrics.ObservabilityConfig
+		rlt  config.RequestLogTemplates
 		dc   deployment.Config
+		tc   tracing.Config
 		want corev1.Container
 	}{{
 		name: "autoscaler single",
@@ -186,6 +191,36 @@ func TestMakeQueueContainer(t *testing.T) {
 				"SERVING_REVISION":       "this",
 			})
 		}),
+	}, {
+		name: "header annotations as env vars",
+		rev: revision("bar", "foo",
+			withContainers(containers),
+			func(revision *v1.Revision) {
+				revision.Annotations = map[string]string{
+					serving.RequestHeadersRemoveAnnotationKey: "X-Internal",
+					serving.ResponseHeadersSetAnnotationKey:   `{"Strict-Transport-Security":"max-age=31536000"}`,
+				}
+			}),
+		want: queueContainer(func(c *corev1.Container) {
+			c.Env = env(map[string]string{
+				"SERVING_REQUEST_HEADERS_REMOVE": "X-Internal",
+				"SERVING_RESPONSE_HEADERS_SET":   `{"Strict-Transport-Security":"max-age=31536000"}`,
+			})
+		}),
+	}, {
+		name: "max request body size as env var",
+		rev: revision("bar", "foo",
+			withContainers(containers),
+			func(revision *v1.Revision) {
+				revision.Annotations = map[string]string{
+					serving.MaxRequestBodySizeAnnotationKey: "1Mi",
+				}
+			}),
+		want: queueContainer(func(c *corev1.Container) {
+			c.Env = env(map[string]string{
+				"SERVING_MAX_REQUEST_BODY_SIZE": "1048576",
+			})
+		}),
 	}, {
 		name: "container concurrency 10",
 		rev: revision("bar", "foo",
@@ -226,6 +261,81 @@ func TestMakeQueueContainer(t *testing.T) {
 				"SERVING_ENABLE_PROBE_REQUEST_LOG": "false",
 			})
 		}),
+	}, {
+		name: "response compression annotations as env vars",
+		rev: revision("bar", "foo",
+			withContainers(containers),
+			func(revision *v1.Revision) {
+				revision.Annotations = map[string]string{
+					serving.ResponseCompressionAnnotationKey:      "gzip",
+					serving.ResponseCompressionTypesAnnotationKey: "application/json",
+				}
+			}),
+		want: queueContainer(func(c *corev1.Container) {
+			c.Env = env(map[string]string{
+				"SERVING_RESPONSE_COMPRESSION":       "gzip",
+				"SERVING_RESPONSE_COMPRESSION_TYPES": "application/json",
+			})
+		}),
+	}, {
+		name: "request log disabled by annotation",
+		rev: revision("bar", "foo",
+			withContainers(containers),
+			func(revision *v1.Revision) {
+				revision.Annotations = map[string]string{
+					serving.RequestLogAnnotationKey: "false",
+				}
+			}),
+		oc: metrics.ObservabilityConfig{
+			RequestLogTemplate: "test template",
+			EnableRequestLog:   true,
+		},
+		want: queueContainer(func(c *corev1.Container) {
+			c.Env = env(map[string]string{
+				"SERVING_REQUEST_LOG_TEMPLATE": "test template",
+				"SERVING_ENABLE_REQUEST_LOG":   "false",
+			})
+		}),
+	}, {
+		name: "request log enabled by annotation with named template",
+		rev: revision("bar", "foo",
+			withContainers(containers),
+			func(revision *v1.Revision) {
+				revision.Annotations = map[string]string{
+					serving.RequestLogAnnotationKey:         "true",
+					serving.RequestLogTemplateAnnotationKey: "minimal",
+				}
+			}),
+		oc: metrics.ObservabilityConfig{
+			RequestLogTemplate: "test template",
+		},
+		rlt: config.RequestLogTemplates{"minimal": "minimal template"},
+		want: queueContainer(func(c *corev1.Container) {
+			c.Env = env(map[string]string{
+				"SERVING_REQUEST_LOG_TEMPLATE": "minimal template",
+				"SERVING_ENABLE_REQUEST_LOG":   "true",
+			})
+		}),
+	}, {
+		name: "unknown request log template falls back to default",
+		rev: revision("bar", "foo",
+			withContainers(containers),
+			func(revision *v1.Revision) {
+				revision.Annotations = map[string]string{
+					serving.RequestLogTemplateAnnotationKey: "missing",
+				}
+			}),
+		oc: metrics.ObservabilityConfig{
+			RequestLogTemplate: "test template",
+			EnableRequestLog:   true,
+		},
+		rlt: config.RequestLogTemplates{"minimal": "minimal template"},
+		want: queueContainer(func(c *corev1.Container) {
+			c.Env = env(map[string]string{
+				"SERVING_REQUEST_LOG_TEMPLATE": "test template",
+				"SERVING_ENABLE_REQUEST_LOG":   "true",
+			})
+		}),
 	}, {
 		name: "request metrics backend as env var",
 		rev: revision("bar", "foo",
@@ -276,6 +386,61 @@ func TestMakeQueueContainer(t *testing.T) {
 			}
 			c.Resources.Limits = nil
 		}),
+	}, {
+		name: "readiness probe timing",
+		rev: revision("bar", "foo",
+			withContainers(containers),
+			func(revision *v1.Revision) {
+				revision.Annotations = map[string]string{
+					serving.QueueSidecarProbeBackoffFactorAnnotationKey: "2",
+					serving.QueueSidecarProbeMaxPeriodAnnotationKey:     "5s",
+				}
+			}),
+		dc: deployment.Config{
+			QueueSidecarProbePeriod:        50 * time.Millisecond,
+			QueueSidecarProbeTimeout:       100 * time.Millisecond,
+			QueueSidecarProbeBackoffFactor: 1,
+			QueueSidecarProbeMaxPeriod:     time.Second,
+		},
+		want: queueContainer(func(c *corev1.Container) {
+			c.Env = env(map[string]string{
+				"SERVING_READINESS_PROBE_PERIOD":         "50ms",
+				"SERVING_READINESS_PROBE_TIMEOUT":        "100ms",
+				"SERVING_READINESS_PROBE_BACKOFF_FACTOR": "2",
+				"SERVING_READINESS_PROBE_MAX_PERIOD":     "5s",
+			})
+		}),
+	}, {
+		name: "transport tuning",
+		rev: revision("bar", "foo",
+			withContainers(containers)),
+		dc: deployment.Config{
+			QueueSidecarMaxIdleConnsPerHost: 5000,
+			QueueSidecarTLSHandshakeTimeout: 3 * time.Second,
+			QueueSidecarDisableKeepAlives:   true,
+		},
+		want: queueContainer(func(c *corev1.Container) {
+			c.Env = env(map[string]string{
+				"SERVING_MAX_IDLE_CONNS_PER_HOST": "5000",
+				"SERVING_TLS_HANDSHAKE_TIMEOUT":   "3s",
+				"SERVING_DISABLE_KEEP_ALIVES":     "true",
+			})
+		}),
+	}, {
+		name: "operator env",
+		rev: revision("bar", "foo",
+			withContainers(containers)),
+		dc: deployment.Config{
+			QueueSidecarEnv: map[string]string{
+				"APM_SERVICE_NAME":  "{{.Namespace}}-{{.Revision}}",
+				"SERVING_NAMESPACE": "not-overridden",
+			},
+		},
+		want: queueContainer(func(c *corev1.Container) {
+			c.Env = env(map[string]string{
+				"APM_SERVICE_NAME": "foo-bar",
+			})
+		}),
 	}, {
 		name: "overridden resources",
 		rev: revision("bar", "foo",
@@ -309,6 +474,145 @@ func TestMakeQueueContainer(t *testing.T) {
 				"METRICS_COLLECTOR_ADDRESS":       "otel:55678",
 			})
 		}),
+	}, {
+		name: "response start and idle timeouts",
+		rev: revision("bar", "foo",
+			withContainers(containers),
+			func(revision *v1.Revision) {
+				revision.Spec.ResponseStartTimeoutSeconds = ptr.Int64(30)
+				revision.Spec.IdleTimeoutSeconds = ptr.Int64(600)
+			}),
+		want: queueContainer(func(c *corev1.Container) {
+			c.Env = env(map[string]string{
+				"REVISION_RESPONSE_START_TIMEOUT_SECONDS": "30",
+				"REVISION_IDLE_TIMEOUT_SECONDS":           "600",
+			})
+		}),
+	}, {
+		name: "soft concurrency mode",
+		rev: revision("bar", "foo",
+			withContainers(containers),
+			func(revision *v1.Revision) {
+				revision.Annotations = map[string]string{
+					serving.ConcurrencyModeAnnotationKey:         serving.ConcurrencyModeSoft,
+					serving.ConcurrencyQueueDepthAnnotationKey:   "50",
+					serving.ConcurrencyQueueTimeoutAnnotationKey: "5s",
+				}
+			}),
+		want: queueContainer(func(c *corev1.Container) {
+			c.Env = env(map[string]string{
+				"SERVING_CONCURRENCY_MODE":          "soft",
+				"SERVING_CONCURRENCY_QUEUE_DEPTH":   "50",
+				"SERVING_CONCURRENCY_QUEUE_TIMEOUT": "5s",
+			})
+		}),
+	}, {
+		name: "named ports",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				Image:          "busybox",
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+				Ports: []corev1.ContainerPort{{
+					ContainerPort: v1.DefaultUserPort,
+				}, {
+					Name:          "admin",
+					ContainerPort: 8181,
+				}, {
+					Name:          "data",
+					ContainerPort: 8282,
+				}},
+			}})),
+		want: queueContainer(func(c *corev1.Container) {
+			c.Env = env(map[string]string{
+				"USER_NAMED_PORTS": "admin=8181,data=8282",
+			})
+		}),
+	}, {
+		name: "unix socket",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				Image:          "busybox",
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+				Ports: []corev1.ContainerPort{{
+					Name:          "unix",
+					ContainerPort: v1.DefaultUserPort,
+				}},
+			}})),
+		want: queueContainer(func(c *corev1.Container) {
+			c.Env = env(map[string]string{
+				"USER_UNIX_SOCKET": "/var/run/knative-socket/user.sock",
+			})
+			c.VolumeMounts = []corev1.VolumeMount{unixSocketVolumeMount}
+		}),
+	}, {
+		name: "debug port",
+		rev: revision("bar", "foo",
+			withContainers(containers),
+			func(revision *v1.Revision) {
+				revision.Annotations = map[string]string{
+					serving.QueueSidecarDebugPortAnnotationKey: "8090",
+				}
+			}),
+		want: queueContainer(func(c *corev1.Container) {
+			c.Env = env(map[string]string{
+				"SERVING_DEBUG_PORT": "8090",
+			})
+		}),
+	}, {
+		name: "drain timeout and pre-stop path",
+		rev: revision("bar", "foo",
+			withContainers(containers),
+			func(revision *v1.Revision) {
+				revision.Annotations = map[string]string{
+					serving.PreStopPathAnnotationKey: "/shutdown",
+				}
+				revision.Spec.DrainTimeoutSeconds = ptr.Int64(90)
+			}),
+		want: queueContainer(func(c *corev1.Container) {
+			c.Env = env(map[string]string{
+				"REVISION_DRAIN_TIMEOUT_SECONDS": "90",
+				"USER_PRESTOP_PATH":              "/shutdown",
+			})
+		}),
+	}, {
+		name: "pre-stop hook",
+		rev: revision("bar", "foo",
+			withContainers([]corev1.Container{{
+				Name:           servingContainerName,
+				ReadinessProbe: withTCPReadinessProbe(v1.DefaultUserPort),
+				Lifecycle: &corev1.Lifecycle{
+					PreStop: &corev1.Handler{
+						HTTPGet: &corev1.HTTPGetAction{Path: "/quit"},
+					},
+				},
+			}})),
+		want: queueContainer(func(c *corev1.Container) {
+			c.Env = env(map[string]string{
+				"USER_PRESTOP_PATH": "/quit",
+			})
+		}),
+	}, {
+		name: "otlp tracing",
+		rev: revision("bar", "foo",
+			withContainers(containers)),
+		tc: tracing.Config{
+			Config: tracingconfig.Config{
+				Backend:    tracing.OTLP,
+				SampleRate: 0.5,
+			},
+			OTLPEndpoint: "otel-collector.observability:4317",
+			OTLPInsecure: true,
+		},
+		want: queueContainer(func(c *corev1.Container) {
+			c.Env = env(map[string]string{
+				"TRACING_CONFIG_BACKEND":       "otlp",
+				"TRACING_CONFIG_SAMPLE_RATE":   "0.5",
+				"TRACING_CONFIG_OTLP_ENDPOINT": "otel-collector.observability:4317",
+				"TRACING_CONFIG_OTLP_INSECURE": "true",
+			})
+		}),
 	}}
 
 	for _, test := range tests {
@@ -322,10 +626,13 @@ func TestMakeQueueContainer(t *testing.T) {
 				}
 			}
 			cfg := &config.Config{
-				Tracing:       &traceConfig,
+				Config:        &apicfg.Config{Autoscaler: &asConfig},
+				Tracing:       &test.tc,
 				Logging:       &test.lc,
 				Observability: &test.oc,
 				Deployment:    &test.dc,
+
+				RequestLogTemplates: test.rlt,
 			}
 			got, err := makeQueueContainer(test.rev, cfg)
 			if err != nil {
@@ -371,11 +678,47 @@ func TestMakeQueueContainerWithPercentageAnnotation(t *testing.T) {
 			}),
 		want: queueContainer(func(c *corev1.Container) {
 			c.Env = env(map[string]string{})
+			// The requests are derived from the limits, like Kubernetes defaults them.
+			c.Resources.Requests = corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("200Mi"), // clamped to boundary in resourceboundary.go
+				corev1.ResourceCPU:    resource.MustParse("100m"),
+			}
 			c.Resources.Limits = corev1.ResourceList{
 				corev1.ResourceMemory: resource.MustParse("0.4Gi"),
 				corev1.ResourceCPU:    resource.MustParse("0.4"),
 			}
 		}),
+	}, {
+		name: "resources percentage in annotations of a gpu container",
+		rev: revision("bar", "foo",
+			func(revision *v1.Revision) {
+				revision.Annotations = map[string]string{
+					serving.QueueSideCarResourcePercentageAnnotation: "10",
+				}
+				revision.Spec.PodSpec.Containers = []corev1.Container{{
+					Name:           servingContainerName,
+					ReadinessProbe: testProbe,
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceMemory: resource.MustParse("1Gi"),
+						},
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU: resource.MustParse("500m"),
+							"nvidia.com/gpu":   resource.MustParse("1"),
+						},
+					}},
+				}
+			}),
+		want: queueContainer(func(c *corev1.Container) {
+			c.Env = env(map[string]string{})
+			c.Resources.Requests = corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("102.4Mi"),
+				corev1.ResourceCPU:    resource.MustParse("50m"),
+			}
+			c.Resources.Limits = corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("50m"),
+			}
+		}),
 	}, {
 		name: "resources percentage in annotations smaller than min allowed",
 		rev: revision("bar", "foo",
@@ -455,6 +798,43 @@ func TestMakeQueueContainerWithPercentageAnnotation(t *testing.T) {
 				corev1.ResourceMemory: resource.MustParse("200Mi"),
 			}
 		}),
+	}, {
+		name: "resources in annotations override the defaults and percentage",
+		rev: revision("bar", "foo",
+			func(revision *v1.Revision) {
+				revision.Annotations = map[string]string{
+					serving.QueueSideCarResourcePercentageAnnotation:               "20",
+					serving.QueueSidecarCPUResourceRequestAnnotationKey:            "500m",
+					serving.QueueSidecarCPUResourceLimitAnnotationKey:              "1",
+					serving.QueueSidecarEphemeralStorageResourceLimitAnnotationKey: "1Gi",
+				}
+				revision.Spec.PodSpec.Containers = []corev1.Container{{
+					Name:           servingContainerName,
+					ReadinessProbe: testProbe,
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{
+							corev1.ResourceMemory: resource.MustParse("2Gi"),
+							corev1.ResourceCPU:    resource.MustParse("2"),
+						},
+					}},
+				}
+			}),
+		dc: deployment.Config{
+			QueueSidecarCPURequest:    resourcePtr(resource.MustParse("25m")),
+			QueueSidecarMemoryRequest: resourcePtr(resource.MustParse("50Mi")),
+		},
+		want: queueContainer(func(c *corev1.Container) {
+			c.Env = env(map[string]string{})
+			c.Resources.Requests = corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("500m"),
+				corev1.ResourceMemory: resource.MustParse("200Mi"), // derived from the limit, clamped
+			}
+			c.Resources.Limits = corev1.ResourceList{
+				corev1.ResourceMemory:           resource.MustParse("0.4Gi"),
+				corev1.ResourceCPU:              resource.MustParse("1"),
+				corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+			}
+		}),
 	}}
 
 	for _, test := range tests {
@@ -781,29 +1161,35 @@ func TestTCPProbeGeneration(t *testing.T) {
 }
 
 var defaultEnv = map[string]string{
-	"CONTAINER_CONCURRENCY":                 "0",
-	"ENABLE_PROFILING":                      "false",
-	"METRICS_DOMAIN":                        metrics.Domain(),
-	"METRICS_COLLECTOR_ADDRESS":             "",
-	"QUEUE_SERVING_PORT":                    "8012",
-	"REVISION_TIMEOUT_SECONDS":              "45",
-	"SERVING_CONFIGURATION":                 "",
-	"SERVING_ENABLE_PROBE_REQUEST_LOG":      "false",
-	"SERVING_ENABLE_REQUEST_LOG":            "false",
-	"SERVING_LOGGING_CONFIG":                "",
-	"SERVING_LOGGING_LEVEL":                 "",
-	"SERVING_NAMESPACE":                     "foo",
-	"SERVING_REQUEST_LOG_TEMPLATE":          "",
-	"SERVING_REQUEST_METRICS_BACKEND":       "",
-	"SERVING_REVISION":                      "bar",
-	"SERVING_SERVICE":                       "",
-	"SYSTEM_NAMESPACE":                      system.Namespace(),
-	"TRACING_CONFIG_BACKEND":                "",
-	"TRACING_CONFIG_DEBUG":                  "false",
-	"TRACING_CONFIG_SAMPLE_RATE":            "0",
-	"TRACING_CONFIG_STACKDRIVER_PROJECT_ID": "",
-	"TRACING_CONFIG_ZIPKIN_ENDPOINT":        "",
-	"USER_PORT":                             strconv.Itoa(v1.DefaultUserPort),
+	"CONTAINER_CONCURRENCY":                   "0",
+	"COUNT_LONG_LIVED_CONNECTIONS":            "true",
+	"ENABLE_PROFILING":                        "false",
+	"METRICS_DOMAIN":                          metrics.Domain(),
+	"METRICS_COLLECTOR_ADDRESS":               "",
+	"QUEUE_SERVING_PORT":                      "8012",
+	"REVISION_TIMEOUT_SECONDS":                "45",
+	"SERVING_CONFIGURATION":                   "",
+	"SERVING_ENABLE_PROBE_REQUEST_LOG":        "false",
+	"SERVING_ENABLE_REQUEST_LOG":              "false",
+	"SERVING_LOGGING_CONFIG":                  "",
+	"SERVING_LOGGING_LEVEL":                   "",
+	"SERVING_NAMESPACE":                       "foo",
+	"SERVING_REQUEST_LOG_TEMPLATE":            "",
+	"SERVING_REQUEST_METRICS_BACKEND":         "",
+	"SERVING_REVISION":                        "bar",
+	"SERVING_SERVICE":                         "",
+	"SYSTEM_NAMESPACE":                        system.Namespace(),
+	"TRACING_CONFIG_BACKEND":                  "",
+	"REVISION_RESPONSE_START_TIMEOUT_SECONDS": "0",
+	"REVISION_IDLE_TIMEOUT_SECONDS":           "0",
+	"REVISION_DRAIN_TIMEOUT_SECONDS":          "0",
+	"TRACING_CONFIG_DEBUG":                    "false",
+	"TRACING_CONFIG_OTLP_ENDPOINT":            "",
+	"TRACING_CONFIG_OTLP_INSECURE":            "false",
+	"TRACING_CONFIG_SAMPLE_RATE":              "0",
+	"TRACING_CONFIG_STACKDRIVER_PROJECT_ID":   "",
+	"TRACING_CONFIG_ZIPKIN_ENDPOINT":          "",
+	"USER_PORT":                               strconv.Itoa(v1.DefaultUserPort),
 }
 
 func probeJSON(container *corev1.Container) string {
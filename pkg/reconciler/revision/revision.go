@@ -36,13 +36,16 @@ import (
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/logging"
 	pkgreconciler "knative.dev/pkg/reconciler"
+	apiconfig "knative.dev/serving/pkg/apis/config"
+	"knative.dev/serving/pkg/apis/serving"
 	v1 "knative.dev/serving/pkg/apis/serving/v1"
 	palisters "knative.dev/serving/pkg/client/listers/autoscaling/v1alpha1"
+	servingreconciler "knative.dev/serving/pkg/reconciler"
 	"knative.dev/serving/pkg/reconciler/revision/config"
 )
 
 type resolver interface {
-	Resolve(*v1.Revision, k8schain.Options, sets.String, time.Duration) ([]v1.ContainerStatus, error)
+	Resolve(*v1.Revision, k8schain.Options, sets.String, int64, bool, time.Duration) ([]v1.ContainerStatus, []v1.ContainerStatus, error)
 	Clear(types.NamespacedName)
 }
 
@@ -58,6 +61,15 @@ type Reconciler struct {
 	deploymentLister    appsv1listers.DeploymentLister
 
 	resolver resolver
+	requeuer *servingreconciler.Requeuer
+	// imageResolver resolves the images outside of the spec of the
+	// revisions, i.e. the debug image, inline.
+	imageResolver imageResolver
+
+	// recommender, if set, samples the resource usage of the revisions, which
+	// are then enqueued again after the next sample is due with enqueueAfter.
+	recommender  *recommender
+	enqueueAfter func(interface{}, time.Duration)
 }
 
 // Check that our Reconciler implements revisionreconciler.Interface
@@ -79,39 +91,36 @@ func (c *Reconciler) reconcileDigest(ctx context.Context, rev *v1.Revision) (boo
 		}
 	}
 
-	// The image digest has already been resolved.
-	if len(rev.Status.ContainerStatuses) == len(rev.Spec.Containers) {
+	// The image digests have already been resolved.
+	if len(rev.Status.ContainerStatuses) == len(rev.Spec.Containers) &&
+		len(rev.Status.InitContainerStatuses) == len(rev.Spec.InitContainers) {
 		c.resolver.Clear(types.NamespacedName{Namespace: rev.Namespace, Name: rev.Name})
 		return true, nil
 	}
 
-	imagePullSecrets := make([]string, 0, len(rev.Spec.ImagePullSecrets))
-	for _, s := range rev.Spec.ImagePullSecrets {
-		imagePullSecrets = append(imagePullSecrets, s.Name)
-	}
 	cfgs := config.FromContext(ctx)
-	opt := k8schain.Options{
-		Namespace:          rev.Namespace,
-		ServiceAccountName: rev.Spec.ServiceAccountName,
-		ImagePullSecrets:   imagePullSecrets,
-	}
-
-	statuses, err := c.resolver.Resolve(rev, opt, cfgs.Deployment.RegistriesSkippingTagResolving, cfgs.Deployment.DigestResolutionTimeout)
+	registriesToSkip := registriesSkippingTagResolving(rev, cfgs.Deployment.RegistriesSkippingTagResolving)
+	imageMetadata := cfgs.Features != nil && cfgs.Features.ImageMetadata == apiconfig.Enabled
+	statuses, initStatuses, err := c.resolver.Resolve(rev, pullOptions(rev), registriesToSkip, maxImageSize(cfgs),
+		imageMetadata, cfgs.Deployment.DigestResolutionTimeout)
 	if err != nil {
 		// Clear the resolver so we can retry the digest resolution rather than
 		// being stuck with this error.
 		c.resolver.Clear(types.NamespacedName{Namespace: rev.Namespace, Name: rev.Name})
-		rev.Status.MarkContainerHealthyFalse(v1.ReasonContainerMissing, err.Error())
+		if isSignatureError(err) {
+			rev.Status.MarkContainerHealthyFalse(v1.ReasonImageSignatureInvalid, err.Error())
+		} else {
+			rev.Status.MarkContainerHealthyFalse(v1.ReasonContainerMissing, err.Error())
+		}
 		return true, err
 	}
 	if len(statuses) > 0 {
 		rev.Status.ContainerStatuses = statuses
+		rev.Status.InitContainerStatuses = initStatuses
 
 		// For backwards-compatibility we need to continue to set the DeprecatedImageDigest field.
-		for i := range rev.Spec.Containers {
-			if len(rev.Spec.Containers) == 1 || len(rev.Spec.Containers[i].Ports) != 0 {
-				rev.Status.DeprecatedImageDigest = statuses[i].ImageDigest
-			}
+		if i := serving.ServingContainerIndex(rev.Spec.Containers); i >= 0 {
+			rev.Status.DeprecatedImageDigest = statuses[i].ImageDigest
 		}
 
 		return true, nil
@@ -121,8 +130,44 @@ func (c *Reconciler) reconcileDigest(ctx context.Context, rev *v1.Revision) (boo
 	return false, nil
 }
 
+// pullOptions returns the options to pull the images of the revision with.
+func pullOptions(rev *v1.Revision) k8schain.Options {
+	imagePullSecrets := make([]string, 0, len(rev.Spec.ImagePullSecrets))
+	for _, s := range rev.Spec.ImagePullSecrets {
+		imagePullSecrets = append(imagePullSecrets, s.Name)
+	}
+	return k8schain.Options{
+		Namespace:          rev.Namespace,
+		ServiceAccountName: rev.Spec.ServiceAccountName,
+		ImagePullSecrets:   imagePullSecrets,
+	}
+}
+
+// maxImageSize returns the maximum size of the images in bytes, if positive.
+func maxImageSize(cfgs *config.Config) int64 {
+	if q := cfgs.Defaults.MaxImageSize; q != nil {
+		return q.Value()
+	}
+	return 0
+}
+
+// registriesSkippingTagResolving returns the registries of the configuration
+// along with those of serving.RegistriesSkippingTagResolvingAnnotationKey.
+func registriesSkippingTagResolving(rev *v1.Revision, registries sets.String) sets.String {
+	v, ok := rev.Annotations[serving.RegistriesSkippingTagResolvingAnnotationKey]
+	if !ok {
+		return registries
+	}
+	ret := sets.NewString(registries.UnsortedList()...)
+	for _, r := range strings.Split(v, ",") {
+		ret.Insert(strings.TrimSpace(r))
+	}
+	return ret
+}
+
 // ReconcileKind implements Interface.ReconcileKind.
-func (c *Reconciler) ReconcileKind(ctx context.Context, rev *v1.Revision) pkgreconciler.Event {
+func (c *Reconciler) ReconcileKind(ctx context.Context, rev *v1.Revision) (ev pkgreconciler.Event) {
+	defer func() { ev = c.requeuer.Handle(rev, ev) }()
 	readyBeforeReconcile := rev.IsReady()
 	c.updateRevisionLoggingURL(ctx, rev)
 
@@ -140,11 +185,14 @@ func (c *Reconciler) ReconcileKind(ctx context.Context, rev *v1.Revision) pkgrec
 		c.reconcileDeployment,
 		c.reconcileImageCache,
 		c.reconcilePA,
+		c.reconcilePodDisruptionBudget,
+		c.reconcileDebugContainer,
 	} {
 		if err := phase(ctx, rev); err != nil {
 			return err
 		}
 	}
+	c.reconcileRecommendations(ctx, rev)
 	readyAfterReconcile := rev.Status.GetCondition(v1.RevisionConditionReady).IsTrue()
 	if !readyBeforeReconcile && readyAfterReconcile {
 		logging.FromContext(ctx).Info("Revision became ready")
@@ -228,10 +228,14 @@ func addResourcesToInformers(t *testing.T, ctx context.Context, rev *v1.Revision
 
 type nopResolver struct{}
 
-func (r *nopResolver) Resolve(rev *v1.Revision, _ k8schain.Options, _ sets.String, _ time.Duration) ([]v1.ContainerStatus, error) {
+func (r *nopResolver) Resolve(rev *v1.Revision, _ k8schain.Options, _ sets.String, _ int64, _ bool, _ time.Duration) ([]v1.ContainerStatus, []v1.ContainerStatus, error) {
+	var initStatuses []v1.ContainerStatus
+	for _, c := range rev.Spec.InitContainers {
+		initStatuses = append(initStatuses, v1.ContainerStatus{Name: c.Name})
+	}
 	return []v1.ContainerStatus{{
 		Name: rev.Spec.Containers[0].Name,
-	}}, nil
+	}}, initStatuses, nil
 }
 
 func (r *nopResolver) Clear(types.NamespacedName) {}
@@ -324,8 +328,8 @@ func testDefaultsCM() *corev1.ConfigMap {
 
 type notResolvedYetResolver struct{}
 
-func (r *notResolvedYetResolver) Resolve(_ *v1.Revision, _ k8schain.Options, _ sets.String, _ time.Duration) ([]v1.ContainerStatus, error) {
-	return nil, nil
+func (r *notResolvedYetResolver) Resolve(_ *v1.Revision, _ k8schain.Options, _ sets.String, _ int64, _ bool, _ time.Duration) ([]v1.ContainerStatus, []v1.ContainerStatus, error) {
+	return nil, nil, nil
 }
 
 func (r *notResolvedYetResolver) Clear(types.NamespacedName) {}
@@ -335,8 +339,8 @@ type errorResolver struct {
 	cleared bool
 }
 
-func (r *errorResolver) Resolve(_ *v1.Revision, _ k8schain.Options, _ sets.String, _ time.Duration) ([]v1.ContainerStatus, error) {
-	return nil, r.err
+func (r *errorResolver) Resolve(_ *v1.Revision, _ k8schain.Options, _ sets.String, _ int64, _ bool, _ time.Duration) ([]v1.ContainerStatus, []v1.ContainerStatus, error) {
+	return nil, nil, r.err
 }
 
 func (r *errorResolver) Clear(types.NamespacedName) {
@@ -380,6 +384,35 @@ func TestResolutionFailed(t *testing.T) {
 	}
 }
 
+func TestResolutionSignatureInvalid(t *testing.T) {
+	innerError := &signatureError{message: "no signature found"}
+	resolver := &errorResolver{err: innerError}
+	ctx, _, _, controller, _ := newTestController(t, nil /*additional CMs*/, func(r *Reconciler) {
+		r.resolver = resolver
+	})
+
+	rev := testRevision(testPodSpec())
+	createRevision(t, ctx, controller, rev)
+
+	rev, err := fakeservingclient.Get(ctx).ServingV1().Revisions(testNamespace).Get(ctx, rev.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal("Couldn't get revision:", err)
+	}
+
+	got := rev.Status.GetCondition(v1.RevisionConditionContainerHealthy)
+	want := &apis.Condition{
+		Type:               v1.RevisionConditionContainerHealthy,
+		Status:             corev1.ConditionFalse,
+		Reason:             v1.ReasonImageSignatureInvalid,
+		Message:            innerError.Error(),
+		LastTransitionTime: got.LastTransitionTime,
+		Severity:           apis.ConditionSeverityError,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected revision conditions diff (-want +got):\n%s", diff)
+	}
+}
+
 func TestUpdateRevWithWithUpdatedLoggingURL(t *testing.T) {
 	ctx, _, _, controller, watcher := newTestController(t, []*corev1.ConfigMap{{
 		ObjectMeta: metav1.ObjectMeta{
@@ -421,6 +454,25 @@ func TestUpdateRevWithWithUpdatedLoggingURL(t *testing.T) {
 	}
 }
 
+func TestInitContainersImageCache(t *testing.T) {
+	ctx, _, _, controller, _ := newTestController(t, nil /*additional CMs*/)
+
+	ps := testPodSpec()
+	ps.InitContainers = []corev1.Container{{
+		Name:  "migrate",
+		Image: "gcr.io/repo/migrate",
+	}}
+	rev := createRevision(t, ctx, controller, testRevision(ps))
+
+	if got, want := rev.Status.InitContainerStatuses, []v1.ContainerStatus{{Name: "migrate"}}; !cmp.Equal(got, want) {
+		t.Errorf("InitContainerStatuses = %v, want: %v", got, want)
+	}
+	imageName := kmeta.ChildName(names.ImageCache(rev), "-migrate")
+	if _, err := fakecachingclient.Get(ctx).CachingV1alpha1().Images(rev.Namespace).Get(ctx, imageName, metav1.GetOptions{}); err != nil {
+		t.Errorf("Caching.Images.Get(%v) = %v", imageName, err)
+	}
+}
+
 func TestStatusUnknownWhenDigestsNotResolvedYet(t *testing.T) {
 	ctx, _, _, controller, _ := newTestController(t, nil /*additional CMs*/, func(r *Reconciler) {
 		r.resolver = &notResolvedYetResolver{}
@@ -715,3 +767,24 @@ func TestNewRevisionCallsSyncHandler(t *testing.T) {
 		t.Error("Failed to see deployment creation:", err)
 	}
 }
+
+func TestRegistriesSkippingTagResolving(t *testing.T) {
+	registries := sets.NewString("ko.local")
+	rev := testRevision(testPodSpec())
+
+	if got := registriesSkippingTagResolving(rev, registries); !got.Equal(registries) {
+		t.Errorf("registriesSkippingTagResolving = %v, want: %v", got.List(), registries.List())
+	}
+
+	rev.Annotations = map[string]string{
+		serving.RegistriesSkippingTagResolvingAnnotationKey: "registry.internal, mirror.internal",
+	}
+	want := sets.NewString("ko.local", "registry.internal", "mirror.internal")
+	if got := registriesSkippingTagResolving(rev, registries); !got.Equal(want) {
+		t.Errorf("registriesSkippingTagResolving = %v, want: %v", got.List(), want.List())
+	}
+	// The configured registries are left as is.
+	if got, want := registries.List(), []string{"ko.local"}; !cmp.Equal(got, want) {
+		t.Errorf("registries = %v, want: %v", got, want)
+	}
+}
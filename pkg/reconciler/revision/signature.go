@@ -0,0 +1,141 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package revision
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// cosignSignatureAnnotation is the annotation of the layers of the cosign
+// signature images with the base64 signature of the layer, the payload.
+const cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+// signatureError is returned when the signature of an image is missing or
+// invalid, as opposed to failing to fetch it.
+type signatureError struct {
+	message string
+}
+
+func (e *signatureError) Error() string {
+	return e.message
+}
+
+// isSignatureError returns whether the image resolution failed because of the
+// signature of the image.
+func isSignatureError(err error) bool {
+	var serr *signatureError
+	return errors.As(err, &serr)
+}
+
+// simpleSigning is the part of the cosign payload identifying the signed image.
+type simpleSigning struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// verifySignature verifies that the cosign signature image of the digest, i.e.
+// the `sha256-<hex>.sig` tag of its repository, has a payload for the digest
+// signed by one of the keys.
+func verifySignature(digest name.Digest, keys []crypto.PublicKey, opts []remote.Option) error {
+	h, err := v1.NewHash(digest.DigestStr())
+	if err != nil {
+		return err
+	}
+	img, err := remote.Image(digest.Context().Tag(fmt.Sprintf("%s-%s.sig", h.Algorithm, h.Hex)), opts...)
+	if err != nil {
+		var terr *transport.Error
+		if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+			return &signatureError{message: "no signature found"}
+		}
+		return err
+	}
+	m, err := img.Manifest()
+	if err != nil {
+		return err
+	}
+	for _, l := range m.Layers {
+		sig, err := base64.StdEncoding.DecodeString(l.Annotations[cosignSignatureAnnotation])
+		if err != nil || len(sig) == 0 {
+			continue
+		}
+		layer, err := img.LayerByDigest(l.Digest)
+		if err != nil {
+			return err
+		}
+		rc, err := layer.Compressed()
+		if err != nil {
+			return err
+		}
+		payload, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		if !verifiesWithAny(keys, payload, sig) {
+			continue
+		}
+		var ss simpleSigning
+		if err := json.Unmarshal(payload, &ss); err == nil && ss.Critical.Image.DockerManifestDigest == h.String() {
+			return nil
+		}
+	}
+	return &signatureError{message: "no signature of the image matches the configured public keys"}
+}
+
+// verifiesWithAny returns whether the signature of the payload verifies with
+// one of the keys.
+func verifiesWithAny(keys []crypto.PublicKey, payload, sig []byte) bool {
+	sum := sha256.Sum256(payload)
+	for _, key := range keys {
+		switch key := key.(type) {
+		case *ecdsa.PublicKey:
+			var esig struct{ R, S *big.Int }
+			if _, err := asn1.Unmarshal(sig, &esig); err == nil && ecdsa.Verify(key, sum[:], esig.R, esig.S) {
+				return true
+			}
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig) == nil {
+				return true
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(key, payload, sig) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,269 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package revision
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn/k8schain"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/serving/pkg/apis/serving"
+)
+
+// fakeSignedRegistry stands up an anonymous registry serving the image and,
+// unless payload is nil, its cosign signature image with the payload and sig.
+// The image is also tagged latest.
+func fakeSignedRegistry(t *testing.T, repo string, img v1.Image, payload, sig []byte) *httptest.Server {
+	digest := mustDigest(t, img)
+	payloadDigest := v1.Hash{Algorithm: "sha256", Hex: fmt.Sprintf("%x", sha256.Sum256(payload))}
+	config := []byte("{}")
+	sigManifest, err := json.Marshal(v1.Manifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		Config: v1.Descriptor{
+			MediaType: types.OCIConfigJSON,
+			Size:      int64(len(config)),
+			Digest:    v1.Hash{Algorithm: "sha256", Hex: fmt.Sprintf("%x", sha256.Sum256(config))},
+		},
+		Layers: []v1.Descriptor{{
+			MediaType: "application/vnd.dev.cosign.simplesigning.v1+json",
+			Size:      int64(len(payload)),
+			Digest:    payloadDigest,
+			Annotations: map[string]string{
+				cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(sig),
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatal("json.Marshal() =", err)
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/":
+		case "/v2/" + repo + "/manifests/" + digest.String(), "/v2/" + repo + "/manifests/latest":
+			mt, _ := img.MediaType()
+			raw, _ := img.RawManifest()
+			w.Header().Set("Content-Type", string(mt))
+			w.Header().Set("Content-Length", fmt.Sprint(len(raw)))
+			w.Header().Set("Docker-Content-Digest", digest.String())
+			w.Write(raw)
+		case fmt.Sprintf("/v2/%s/manifests/sha256-%s.sig", repo, digest.Hex):
+			if payload == nil {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", string(types.OCIManifestSchema1))
+			w.Write(sigManifest)
+		case "/v2/" + repo + "/blobs/" + payloadDigest.String():
+			w.Write(payload)
+		default:
+			t.Error("Unexpected path:", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func cosignPayload(t *testing.T, digest v1.Hash) []byte {
+	payload, err := json.Marshal(map[string]interface{}{
+		"critical": map[string]interface{}{
+			"image": map[string]string{"docker-manifest-digest": digest.String()},
+			"type":  "cosign container image signature",
+		},
+	})
+	if err != nil {
+		t.Fatal("json.Marshal() =", err)
+	}
+	return payload
+}
+
+func ecdsaSign(t *testing.T, key *ecdsa.PrivateKey, payload []byte) []byte {
+	sum := sha256.Sum256(payload)
+	r, s, err := ecdsa.Sign(rand.Reader, key, sum[:])
+	if err != nil {
+		t.Fatal("ecdsa.Sign() =", err)
+	}
+	sig, err := asn1.Marshal(struct{ R, S interface{} }{r, s})
+	if err != nil {
+		t.Fatal("asn1.Marshal() =", err)
+	}
+	return sig
+}
+
+func TestResolveVerifiesSignature(t *testing.T) {
+	const repo = "booger/nose"
+	img, err := random.Image(3, 1024)
+	if err != nil {
+		t.Fatal("random.Image() =", err)
+	}
+	other, err := random.Image(3, 1024)
+	if err != nil {
+		t.Fatal("random.Image() =", err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey() =", err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey() =", err)
+	}
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey() =", err)
+	}
+	payload := cosignPayload(t, mustDigest(t, img))
+	otherPayload := cosignPayload(t, mustDigest(t, other))
+
+	tests := []struct {
+		name         string
+		keys         []crypto.PublicKey
+		payload, sig []byte
+		wantErr      bool
+	}{{
+		name: "no keys",
+	}, {
+		name:    "signed",
+		keys:    []crypto.PublicKey{&otherKey.PublicKey, &key.PublicKey},
+		payload: payload,
+		sig:     ecdsaSign(t, key, payload),
+	}, {
+		name:    "signed with ed25519",
+		keys:    []crypto.PublicKey{edPub},
+		payload: payload,
+		sig:     ed25519.Sign(edKey, payload),
+	}, {
+		name:    "no signature",
+		keys:    []crypto.PublicKey{&key.PublicKey},
+		wantErr: true,
+	}, {
+		name:    "signed with another key",
+		keys:    []crypto.PublicKey{&key.PublicKey},
+		payload: payload,
+		sig:     ecdsaSign(t, otherKey, payload),
+		wantErr: true,
+	}, {
+		name:    "signature of another image",
+		keys:    []crypto.PublicKey{&key.PublicKey},
+		payload: otherPayload,
+		sig:     ecdsaSign(t, key, otherPayload),
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := fakeSignedRegistry(t, repo, img, test.payload, test.sig)
+			defer server.Close()
+			u, err := url.Parse(server.URL)
+			if err != nil {
+				t.Fatal("url.Parse() =", err)
+			}
+
+			dr := &digestResolver{
+				client:        anonymousClient("ns", "default"),
+				transport:     http.DefaultTransport,
+				signatureKeys: test.keys,
+			}
+			image := fmt.Sprintf("%s/%s@%s", u.Host, repo, mustDigest(t, img))
+			_, err = dr.Resolve(context.Background(), image, k8schain.Options{Namespace: "ns", ServiceAccountName: "default"}, emptyRegistrySet, 0)
+			if test.wantErr {
+				if !isSignatureError(err) {
+					t.Errorf("Resolve() = %v, want a signature error", err)
+				}
+			} else if err != nil {
+				t.Error("Resolve() =", err)
+			}
+		})
+	}
+}
+
+func TestResolveSkippingRegistryVerifiesSignature(t *testing.T) {
+	const repo = "booger/nose"
+	img, err := random.Image(3, 1024)
+	if err != nil {
+		t.Fatal("random.Image() =", err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("GenerateKey() =", err)
+	}
+	payload := cosignPayload(t, mustDigest(t, img))
+
+	tests := []struct {
+		name         string
+		payload, sig []byte
+		wantErr      bool
+	}{{
+		name:    "signed",
+		payload: payload,
+		sig:     ecdsaSign(t, key, payload),
+	}, {
+		name:    "no signature",
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := fakeSignedRegistry(t, repo, img, test.payload, test.sig)
+			defer server.Close()
+			u, err := url.Parse(server.URL)
+			if err != nil {
+				t.Fatal("url.Parse() =", err)
+			}
+
+			dr := &digestResolver{
+				client:        anonymousClient("ns", "default"),
+				transport:     http.DefaultTransport,
+				signatureKeys: []crypto.PublicKey{&key.PublicKey},
+			}
+			image := fmt.Sprintf("%s/%s:latest", u.Host, repo)
+			for _, skip := range []sets.String{sets.NewString(u.Host), sets.NewString(serving.AllRegistries)} {
+				got, err := dr.Resolve(context.Background(), image, k8schain.Options{Namespace: "ns", ServiceAccountName: "default"}, skip, 0)
+				if test.wantErr {
+					if !isSignatureError(err) {
+						t.Errorf("Resolve() = %v, want a signature error", err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatal("Resolve() =", err)
+				}
+				// The verified digest is deployed rather than the tag.
+				if want := fmt.Sprintf("%s/%s@%s", u.Host, repo, mustDigest(t, img)); got != want {
+					t.Errorf("Resolve() = %q, want: %q", got, want)
+				}
+			}
+		})
+	}
+}
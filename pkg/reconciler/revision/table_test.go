@@ -19,6 +19,7 @@ package revision
 import (
 	"context"
 	"testing"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -35,7 +36,6 @@ import (
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/metrics"
 	pkgreconciler "knative.dev/pkg/reconciler"
-	tracingconfig "knative.dev/pkg/tracing/config"
 	asv1a1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
 	defaultconfig "knative.dev/serving/pkg/apis/config"
 	"knative.dev/serving/pkg/apis/serving"
@@ -45,6 +45,7 @@ import (
 	revisionreconciler "knative.dev/serving/pkg/client/injection/reconciler/serving/v1/revision"
 	"knative.dev/serving/pkg/reconciler/revision/config"
 	"knative.dev/serving/pkg/reconciler/revision/resources"
+	"knative.dev/serving/pkg/tracing"
 
 	. "knative.dev/pkg/reconciler/testing"
 	. "knative.dev/serving/pkg/reconciler/testing/v1"
@@ -53,6 +54,8 @@ import (
 )
 
 // This is heavily based on the way the OpenShift Ingress controller tests its reconciliation method.
+const quotaFailure = `pods "foo" is forbidden: exceeded quota: compute, requested: requests.cpu=1, used: requests.cpu=4, limited: requests.cpu=4`
+
 func TestReconcile(t *testing.T) {
 	table := TableTest{{
 		Name: "bad workqueue key",
@@ -482,6 +485,28 @@ func TestReconcile(t *testing.T) {
 			Object: pa("foo", "deploy-replica-failure", WithReachabilityUnreachable),
 		}},
 		Key: "foo/deploy-replica-failure",
+	}, {
+		Name: "surface replica failure exceeding quota",
+		// Test the mapping of the FailedCreate of a Deployment exceeding a
+		// ResourceQuota to an actionable reason.
+		Objects: []runtime.Object{
+			Revision("foo", "deploy-quota-failure",
+				WithK8sServiceName("the-taxman"), WithLogURL, MarkActive),
+			pa("foo", "deploy-quota-failure"),
+			replicaFailureDeploy(deploy(t, "foo", "deploy-quota-failure"), quotaFailure),
+			image("foo", "deploy-quota-failure"),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: Revision("foo", "deploy-quota-failure",
+				WithLogURL, allUnknownConditions,
+				MarkResourcesUnavailable(v1.ReasonQuotaExceeded,
+					v1.RevisionQuotaExceededMessage("compute", []string{"requests.cpu"}, quotaFailure)),
+				withDefaultContainerStatuses(), WithRevisionObservedGeneration(1)),
+		}},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: pa("foo", "deploy-quota-failure", WithReachabilityUnreachable),
+		}},
+		Key: "foo/deploy-quota-failure",
 	}, {
 		Name: "surface ImagePullBackoff",
 		// Test the propagation of ImagePullBackoff from user container.
@@ -519,12 +544,36 @@ func TestReconcile(t *testing.T) {
 		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: Revision("foo", "pod-error",
 				WithLogURL, allUnknownConditions, MarkContainerExiting(5,
-					v1.RevisionContainerExitingMessage("I failed man!")), withDefaultContainerStatuses(), WithRevisionObservedGeneration(1)),
+					v1.RevisionContainerTerminatedMessage("pod-error", "", 5, 0, "I failed man!")), withDefaultContainerStatuses(), WithRevisionObservedGeneration(1)),
 		}},
 		WantUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: pa("foo", "pod-error", WithReachabilityUnreachable),
 		}},
 		Key: "foo/pod-error",
+	}, {
+		Name: "surface the latest pod errors across pods",
+		// Test the aggregation of the termination states of the Pods of the
+		// revision, reporting the latest one and the most restarts.
+		Objects: []runtime.Object{
+			Revision("foo", "pods-error",
+				WithK8sServiceName("a-pods-error"), WithLogURL, allUnknownConditions, MarkActive),
+			pa("foo", "pods-error"), // PA can't be ready, since no traffic.
+			pod(t, "foo", "pods-error", withTerminatedContainer("pods-error", "Error", 1, 4, time.Unix(100, 0), "panic")),
+			pod(t, "foo", "pods-error", withPodName("pods-error-2"),
+				withTerminatedContainer("pods-error", "OOMKilled", 137, 2, time.Unix(200, 0), "")),
+			deploy(t, "foo", "pods-error"),
+			image("foo", "pods-error"),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: Revision("foo", "pods-error",
+				WithLogURL, allUnknownConditions, MarkContainerExiting(137,
+					v1.RevisionContainerTerminatedMessage("pods-error", "OOMKilled", 137, 4, "")),
+				withDefaultContainerStatuses(), withLastTerminationReason("OOMKilled"), WithRevisionObservedGeneration(1)),
+		}},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: pa("foo", "pods-error", WithReachabilityUnreachable),
+		}},
+		Key: "foo/pods-error",
 	}, {
 		Name: "surface pod schedule errors",
 		// Test the propagation of the scheduling errors of Pod into the revision.
@@ -716,6 +765,35 @@ func withDefaultContainerStatuses() RevisionOption {
 	}
 }
 
+func withLastTerminationReason(reason string) RevisionOption {
+	return func(r *v1.Revision) {
+		r.Status.ContainerStatuses[0].LastTerminationReason = reason
+	}
+}
+
+func withPodName(name string) PodOption {
+	return func(pod *corev1.Pod) {
+		pod.Name = name
+	}
+}
+
+func withTerminatedContainer(name, reason string, exitCode, restarts int32, finishedAt time.Time, message string) PodOption {
+	return func(pod *corev1.Pod) {
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:         name,
+			RestartCount: restarts,
+			LastTerminationState: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{
+					Reason:     reason,
+					ExitCode:   exitCode,
+					FinishedAt: metav1.NewTime(finishedAt),
+					Message:    message,
+				},
+			},
+		}}
+	}
+}
+
 // TODO(mattmoor): Come up with a better name for this.
 func allUnknownConditions(r *v1.Revision) {
 	WithInitRevConditions(r)
@@ -814,6 +892,6 @@ func ReconcilerTestConfig() *config.Config {
 			LoggingURLTemplate: "http://logger.io/${REVISION_UID}",
 		},
 		Logging: &logging.Config{},
-		Tracing: &tracingconfig.Config{},
+		Tracing: &tracing.Config{},
 	}
 }
@@ -34,16 +34,24 @@ const (
 	// DefaultDomain holds the domain that Route's live under by default
 	// when no label selector-based options apply.
 	DefaultDomain = "example.com"
+
+	// CustomDomainsAllowlistKey is the config-domain key listing, comma
+	// separated, the domains under which the Routes may request custom hosts.
+	CustomDomainsAllowlistKey = "custom-domains-allowlist"
 )
 
 // LabelSelector represents map of {key,value} pairs. A single {key,value} in the
 // map is equivalent to a requirement key == value. The requirements are ANDed.
 type LabelSelector struct {
 	Selector map[string]string `json:"selector,omitempty"`
+
+	// NamespaceSelector is matched, the same way, against the labels of the
+	// namespace of the route.
+	NamespaceSelector map[string]string `json:"namespaceSelector,omitempty"`
 }
 
 func (s *LabelSelector) specificity() int {
-	return len(s.Selector)
+	return len(s.Selector) + len(s.NamespaceSelector)
 }
 
 // Matches returns whether the given labels meet the requirement of the selector.
@@ -57,6 +65,18 @@ func (s *LabelSelector) Matches(labels map[string]string) bool {
 	return true
 }
 
+// MatchesNamespace returns whether the given namespace labels meet the
+// requirement of the namespace selector.
+func (s *LabelSelector) MatchesNamespace(labels map[string]string) bool {
+	for label, expectedValue := range s.NamespaceSelector {
+		value, ok := labels[label]
+		if !ok || expectedValue != value {
+			return false
+		}
+	}
+	return true
+}
+
 // Domain maps domains to routes by matching the domain's
 // label selectors to the route's labels.
 type Domain struct {
@@ -65,6 +85,10 @@ type Domain struct {
 	// corresponding domain.  If multiple selectors match, we choose
 	// the most specific selector.
 	Domains map[string]*LabelSelector
+
+	// CustomDomainsAllowlist are the domains the custom hosts of the routes
+	// must be, or be a subdomain of. No custom host is allowed if empty.
+	CustomDomainsAllowlist []string
 }
 
 // NewDomainFromConfigMap creates a Domain from the supplied ConfigMap
@@ -75,13 +99,21 @@ func NewDomainFromConfigMap(configMap *corev1.ConfigMap) (*Domain, error) {
 		if k == configmap.ExampleKey {
 			continue
 		}
+		if k == CustomDomainsAllowlistKey {
+			for _, d := range strings.Split(v, ",") {
+				if d = strings.TrimSpace(d); d != "" {
+					c.CustomDomainsAllowlist = append(c.CustomDomainsAllowlist, d)
+				}
+			}
+			continue
+		}
 		labelSelector := &LabelSelector{}
 		err := yaml.Unmarshal([]byte(v), labelSelector)
 		if err != nil {
 			return nil, err
 		}
 		c.Domains[k] = labelSelector
-		if len(labelSelector.Selector) == 0 {
+		if labelSelector.specificity() == 0 {
 			hasDefault = true
 		}
 	}
@@ -91,10 +123,27 @@ func NewDomainFromConfigMap(configMap *corev1.ConfigMap) (*Domain, error) {
 	return &c, nil
 }
 
+// AllowsCustomHost returns whether the custom host is one of the allowed
+// domains, or a subdomain of one of them.
+func (c *Domain) AllowsCustomHost(host string) bool {
+	for _, d := range c.CustomDomainsAllowlist {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
 // LookupDomainForLabels returns a domain given a set of labels.
 // Since we reject configuration without a default domain, this should
 // always return a value.
 func (c *Domain) LookupDomainForLabels(labels map[string]string) string {
+	return c.LookupDomainForNamespace(labels, nil /* namespaceLabels */)
+}
+
+// LookupDomainForNamespace is like LookupDomainForLabels, but also matches the
+// namespace selectors against the labels of the namespace of the route.
+func (c *Domain) LookupDomainForNamespace(labels, namespaceLabels map[string]string) string {
 	domain := ""
 	specificity := -1
 	// If we see VisibilityLabelKey sets with VisibilityClusterLocal, that
@@ -105,7 +154,8 @@ func (c *Domain) LookupDomainForLabels(labels map[string]string) string {
 	}
 	for k, selector := range c.Domains {
 		// Ignore if selector doesn't match, or decrease the specificity.
-		if !selector.Matches(labels) || selector.specificity() < specificity {
+		if !selector.Matches(labels) || !selector.MatchesNamespace(namespaceLabels) ||
+			selector.specificity() < specificity {
 			continue
 		}
 		if selector.specificity() > specificity || strings.Compare(k, domain) < 0 {
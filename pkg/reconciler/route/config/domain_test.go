@@ -126,6 +126,41 @@ func TestNewConfig(t *testing.T) {
 	}
 }
 
+func TestCustomDomainsAllowlist(t *testing.T) {
+	c, err := NewDomainFromConfigMap(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      DomainConfigName,
+		},
+		Data: map[string]string{
+			"default.com":             "",
+			CustomDomainsAllowlistKey: "example.org, shop.example.net",
+		},
+	})
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if got, want := c.CustomDomainsAllowlist, []string{"example.org", "shop.example.net"}; !cmp.Equal(got, want) {
+		t.Errorf("CustomDomainsAllowlist = %v, want: %v", got, want)
+	}
+	// The allowlist is not a domain of the routes.
+	if _, ok := c.Domains[CustomDomainsAllowlistKey]; ok {
+		t.Errorf("Domains = %v, want no %s", c.Domains, CustomDomainsAllowlistKey)
+	}
+
+	for host, want := range map[string]bool{
+		"example.org":          true,
+		"www.example.org":      true,
+		"notexample.org":       false,
+		"example.net":          false,
+		"www.shop.example.net": true,
+	} {
+		if got := c.AllowsCustomHost(host); got != want {
+			t.Errorf("AllowsCustomHost(%q) = %t, want: %t", host, got, want)
+		}
+	}
+}
+
 func TestLookupDomainForLabels(t *testing.T) {
 	config := Domain{
 		Domains: map[string]*LabelSelector{
@@ -184,6 +219,66 @@ func TestLookupDomainForLabels(t *testing.T) {
 	}
 }
 
+func TestLookupDomainForNamespace(t *testing.T) {
+	config := Domain{
+		Domains: map[string]*LabelSelector{
+			"a.example.com": {
+				NamespaceSelector: map[string]string{
+					"tenant": "a",
+				},
+			},
+			"b.example.com": {
+				NamespaceSelector: map[string]string{
+					"tenant": "b",
+				},
+			},
+			"prod.b.example.com": {
+				Selector: map[string]string{
+					"version": "prod",
+				},
+				NamespaceSelector: map[string]string{
+					"tenant": "b",
+				},
+			},
+			"default.com": {},
+		},
+	}
+
+	expectations := []struct {
+		labels          map[string]string
+		namespaceLabels map[string]string
+		domain          string
+	}{{
+		namespaceLabels: map[string]string{"tenant": "a"},
+		domain:          "a.example.com",
+	}, {
+		labels:          map[string]string{"version": "prod"},
+		namespaceLabels: map[string]string{"tenant": "a"},
+		domain:          "a.example.com",
+	}, {
+		namespaceLabels: map[string]string{"tenant": "b"},
+		domain:          "b.example.com",
+	}, {
+		// This should match two selector, but the one with version=prod is more specific.
+		labels:          map[string]string{"version": "prod"},
+		namespaceLabels: map[string]string{"tenant": "b"},
+		domain:          "prod.b.example.com",
+	}, {
+		namespaceLabels: map[string]string{"tenant": "c"},
+		domain:          "default.com",
+	}, {
+		labels: map[string]string{"version": "prod"},
+		domain: "default.com",
+	}}
+
+	for _, expected := range expectations {
+		domain := config.LookupDomainForNamespace(expected.labels, expected.namespaceLabels)
+		if expected.domain != domain {
+			t.Errorf("Expected domain %q got %q", expected.domain, domain)
+		}
+	}
+}
+
 func TestOurDomain(t *testing.T) {
 	cm, example := ConfigMapsFromTestFile(t, DomainConfigName)
 	if _, err := NewDomainFromConfigMap(cm); err != nil {
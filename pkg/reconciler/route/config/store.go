@@ -24,6 +24,7 @@ import (
 	"knative.dev/pkg/logging"
 	cfgmap "knative.dev/serving/pkg/apis/config"
 	"knative.dev/serving/pkg/gc"
+	"knative.dev/serving/pkg/networking"
 )
 
 type cfgKey struct{}
@@ -62,12 +63,28 @@ func ToContext(ctx context.Context, c *Config) context.Context {
 	return context.WithValue(ctx, cfgKey{}, c)
 }
 
+type nsLabelsKey struct{}
+
+// WithNamespaceLabels stores the labels of the namespace of the route in the
+// passed context, for the namespace selectors of config-domain to match.
+func WithNamespaceLabels(ctx context.Context, labels map[string]string) context.Context {
+	return context.WithValue(ctx, nsLabelsKey{}, labels)
+}
+
+// NamespaceLabelsFromContext obtains the namespace labels injected into the
+// passed context, or nil if there are none.
+func NamespaceLabelsFromContext(ctx context.Context) map[string]string {
+	labels, _ := ctx.Value(nsLabelsKey{}).(map[string]string)
+	return labels
+}
+
 // Store is based on configmap.UntypedStore and is used to store and watch for
 // updates to configuration related to routes (currently only config-domain).
 //
 // +k8s:deepcopy-gen=false
 type Store struct {
 	*configmap.UntypedStore
+	networkingStore *networking.Store
 }
 
 // NewStore creates a configmap.UntypedStore based config store.
@@ -94,14 +111,23 @@ func NewStore(ctx context.Context, onAfterStore ...func(name string, value inter
 			},
 			onAfterStore...,
 		),
+		networkingStore: networking.NewStore(logger, onAfterStore...),
 	}
 
 	return store
 }
 
+// WatchConfigs uses the provided configmap.Watcher
+// to setup watches for the config names provided in the
+// Constructors map
+func (s *Store) WatchConfigs(cmw configmap.Watcher) {
+	s.UntypedStore.WatchConfigs(cmw)
+	s.networkingStore.WatchConfigs(cmw)
+}
+
 // ToContext stores the configuration Store in the passed context.
 func (s *Store) ToContext(ctx context.Context) context.Context {
-	return ToContext(ctx, s.Load())
+	return s.networkingStore.ToContext(ToContext(ctx, s.Load()))
 }
 
 // Load creates a Config for this store.
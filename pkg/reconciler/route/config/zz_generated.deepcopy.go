@@ -38,6 +38,11 @@ func (in *Domain) DeepCopyInto(out *Domain) {
 			(*out)[key] = outVal
 		}
 	}
+	if in.CustomDomainsAllowlist != nil {
+		in, out := &in.CustomDomainsAllowlist, &out.CustomDomainsAllowlist
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -61,6 +66,13 @@ func (in *LabelSelector) DeepCopyInto(out *LabelSelector) {
 			(*out)[key] = val
 		}
 	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -23,6 +23,7 @@ import (
 	certificateinformer "knative.dev/networking/pkg/client/injection/informers/networking/v1alpha1/certificate"
 	ingressinformer "knative.dev/networking/pkg/client/injection/informers/networking/v1alpha1/ingress"
 	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	namespaceinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/namespace"
 	serviceinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/service"
 	servingclient "knative.dev/serving/pkg/client/injection/client"
 	configurationinformer "knative.dev/serving/pkg/client/injection/informers/serving/v1/configuration"
@@ -30,6 +31,10 @@ import (
 	routeinformer "knative.dev/serving/pkg/client/injection/informers/serving/v1/route"
 	routereconciler "knative.dev/serving/pkg/client/injection/reconciler/serving/v1/route"
 
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/cache"
 	network "knative.dev/networking/pkg"
 	"knative.dev/pkg/configmap"
@@ -37,7 +42,9 @@ import (
 	"knative.dev/pkg/logging"
 	"knative.dev/pkg/system"
 	"knative.dev/pkg/tracker"
+	"knative.dev/serving/pkg/apis/serving"
 	v1 "knative.dev/serving/pkg/apis/serving/v1"
+	"knative.dev/serving/pkg/networking"
 	servingreconciler "knative.dev/serving/pkg/reconciler"
 	"knative.dev/serving/pkg/reconciler/route/config"
 )
@@ -69,6 +76,13 @@ func newControllerWithClock(
 	revisionInformer := revisioninformer.Get(ctx)
 	ingressInformer := ingressinformer.Get(ctx)
 	certificateInformer := certificateinformer.Get(ctx)
+	namespaceInformer := namespaceinformer.Get(ctx)
+
+	// The Routes are indexed by their custom hosts, to find the other Routes
+	// sharing them.
+	if err := routeInformer.Informer().AddIndexers(cache.Indexers{hostsIndex: indexHosts}); err != nil {
+		logger.Fatalw("Failed to add the custom hosts index", zap.Error(err))
+	}
 
 	c := &Reconciler{
 		kubeclient:          kubeclient.Get(ctx),
@@ -76,14 +90,17 @@ func newControllerWithClock(
 		netclient:           netclient.Get(ctx),
 		configurationLister: configInformer.Lister(),
 		revisionLister:      revisionInformer.Lister(),
+		routeIndexer:        routeInformer.Informer().GetIndexer(),
 		serviceLister:       serviceInformer.Lister(),
 		ingressLister:       ingressInformer.Lister(),
 		certificateLister:   certificateInformer.Lister(),
+		namespaceLister:     namespaceInformer.Lister(),
 		clock:               clock,
 	}
 	impl := routereconciler.NewImpl(ctx, c, func(impl *controller.Impl) controller.Options {
 		configsToResync := []interface{}{
 			&network.Config{},
+			&networking.Config{},
 			&config.Domain{},
 		}
 		resync := configmap.TypeFilter(configsToResync...)(func(string, interface{}) {
@@ -105,7 +122,53 @@ func newControllerWithClock(
 	certificateInformer.Informer().AddEventHandler(handleControllerOf)
 	ingressInformer.Informer().AddEventHandler(handleControllerOf)
 
+	// The custom hosts a Route lets go of may be claimed by the others
+	// requesting them.
+	enqueueSharingHosts := func(obj interface{}) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		route, ok := obj.(*v1.Route)
+		if !ok {
+			return
+		}
+		for _, host := range routeHosts(route) {
+			others, err := c.routeIndexer.ByIndex(hostsIndex, host)
+			if err != nil {
+				logger.Errorw("Failed to get the routes of the custom host "+host, zap.Error(err))
+				continue
+			}
+			for _, other := range others {
+				if other := other.(*v1.Route); other.Namespace != route.Namespace || other.Name != route.Name {
+					impl.Enqueue(other)
+				}
+			}
+		}
+	}
+	routeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if !equality.Semantic.DeepEqual(routeHosts(oldObj.(*v1.Route)), routeHosts(newObj.(*v1.Route))) {
+				enqueueSharingHosts(oldObj)
+				enqueueSharingHosts(newObj)
+			}
+		},
+		DeleteFunc: enqueueSharingHosts,
+	})
+
+	// The namespace labels may select another domain for its routes.
+	namespaceInformer.Informer().AddEventHandler(controller.HandleAll(func(obj interface{}) {
+		ns, ok := obj.(*corev1.Namespace)
+		if !ok {
+			return
+		}
+		impl.FilteredGlobalResync(func(obj interface{}) bool {
+			route, ok := obj.(*v1.Route)
+			return ok && route.Namespace == ns.Name
+		}, routeInformer.Informer())
+	}))
+
 	c.tracker = tracker.New(impl.EnqueueKey, controller.GetTrackerLease(ctx))
+	c.requeuer = servingreconciler.NewRequeuer(impl.EnqueueAfter)
 
 	// Make sure trackers are deleted once the observers are removed.
 	routeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -137,3 +200,24 @@ func newControllerWithClock(
 	}
 	return impl
 }
+
+// hostsIndex is the name of the index of the Routes by custom host.
+const hostsIndex = "hosts"
+
+// indexHosts indexes the Routes by the custom hosts they request or claim.
+func indexHosts(obj interface{}) ([]string, error) {
+	route, ok := obj.(*v1.Route)
+	if !ok {
+		return nil, nil
+	}
+	return routeHosts(route), nil
+}
+
+// routeHosts returns the custom hosts the Route requests or claims.
+func routeHosts(r *v1.Route) []string {
+	hosts := sets.NewString(serving.ParseHostsAnnotation(r.Annotations)...)
+	for host := range hostClaims(r) {
+		hosts.Insert(host)
+	}
+	return hosts.List()
+}
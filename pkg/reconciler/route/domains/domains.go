@@ -57,15 +57,28 @@ func GetAllDomainsAndTags(ctx context.Context, r *v1.Route, names []string, visi
 		}
 		domainTagMap[subDomain] = name
 	}
+	// The custom hosts get certificates of their own, named after them.
+	for _, host := range CustomHosts(r, visibility) {
+		domainTagMap[host] = host
+	}
 	return domainTagMap, nil
 }
 
+// CustomHosts returns the custom hosts of the Route, served by its default
+// traffic target, keyed by the empty name, unless that's cluster-local.
+func CustomHosts(r *v1.Route, visibility map[string]netv1alpha1.IngressVisibility) []string {
+	if visibility[""] == netv1alpha1.IngressVisibilityClusterLocal {
+		return nil
+	}
+	return serving.ParseHostsAnnotation(r.Annotations)
+}
+
 // DomainNameFromTemplate generates domain name base on the template specified in the `config-network` ConfigMap.
 // name is the "subdomain" which will be referred as the "name" in the template
 func DomainNameFromTemplate(ctx context.Context, r metav1.ObjectMeta, name string) (string, error) {
 	domainConfig := config.FromContext(ctx).Domain
 	rLabels := r.Labels
-	domain := domainConfig.LookupDomainForLabels(rLabels)
+	domain := domainConfig.LookupDomainForNamespace(rLabels, config.NamespaceLabelsFromContext(ctx))
 	annotations := r.Annotations
 	// These are the available properties they can choose from.
 	// We could add more over time - e.g. RevisionName if we thought that
@@ -28,6 +28,7 @@ import (
 	pkgnet "knative.dev/pkg/network"
 
 	network "knative.dev/networking/pkg"
+	netv1alpha1 "knative.dev/networking/pkg/apis/networking/v1alpha1"
 	"knative.dev/serving/pkg/apis/serving"
 	v1 "knative.dev/serving/pkg/apis/serving/v1"
 	"knative.dev/serving/pkg/gc"
@@ -154,6 +155,35 @@ func TestDomainNameFromTemplate(t *testing.T) {
 	}
 }
 
+func TestDomainNameFromTemplateNamespaceSelector(t *testing.T) {
+	cfg := testConfig()
+	cfg.Domain.Domains["a.example.com"] = &config.LabelSelector{
+		NamespaceSelector: map[string]string{"tenant": "a"},
+	}
+	ctx := config.ToContext(context.Background(), cfg)
+	meta := metav1.ObjectMeta{
+		Name:      "myroute",
+		Namespace: "default",
+	}
+
+	got, err := DomainNameFromTemplate(ctx, meta, "myroute")
+	if err != nil {
+		t.Fatal("DomainNameFromTemplate() =", err)
+	}
+	if want := "myroute.default.example.com"; got != want {
+		t.Errorf("DomainNameFromTemplate() = %s, want: %s", got, want)
+	}
+
+	ctx = config.WithNamespaceLabels(ctx, map[string]string{"tenant": "a"})
+	got, err = DomainNameFromTemplate(ctx, meta, "myroute")
+	if err != nil {
+		t.Fatal("DomainNameFromTemplate() =", err)
+	}
+	if want := "myroute.default.a.example.com"; got != want {
+		t.Errorf("DomainNameFromTemplate() = %s, want: %s", got, want)
+	}
+}
+
 func TestURL(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -264,6 +294,44 @@ func TestGetAllDomainsAndTags(t *testing.T) {
 		})
 	}
 }
+
+func TestGetAllDomainsAndTagsCustomHosts(t *testing.T) {
+	route := &v1.Route{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "myroute",
+			Namespace: "default",
+			Annotations: map[string]string{
+				serving.HostsAnnotationKey: "www.example.org,example.org",
+			},
+		},
+	}
+	ctx := config.ToContext(context.Background(), testConfig())
+
+	got, err := GetAllDomainsAndTags(ctx, route, []string{""}, nil /* visibility */)
+	if err != nil {
+		t.Fatal("GetAllDomainsAndTags() =", err)
+	}
+	want := map[string]string{
+		"myroute.default.example.com": "",
+		"www.example.org":             "www.example.org",
+		"example.org":                 "example.org",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("GetAllDomainsAndTags() diff (-want +got):", diff)
+	}
+
+	// The custom hosts aren't served by cluster-local routes.
+	got, err = GetAllDomainsAndTags(ctx, route, []string{""}, map[string]netv1alpha1.IngressVisibility{
+		"": netv1alpha1.IngressVisibilityClusterLocal,
+	})
+	if err != nil {
+		t.Fatal("GetAllDomainsAndTags() =", err)
+	}
+	if _, ok := got["example.org"]; ok {
+		t.Errorf("GetAllDomainsAndTags() = %v, want no custom host", got)
+	}
+}
+
 func TestIsClusterLocal(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -98,6 +98,9 @@ func TestNewRouteCallsSyncHandler(t *testing.T) {
 		return HookComplete
 	})
 
+	// The controller indexes the informers, before they are started.
+	ctrl := NewController(ctx, configMapWatcher)
+
 	waitInformers, err := controller.RunInformers(ctx.Done(), informers...)
 	if err != nil {
 		t.Fatal("Failed to start informers:", err)
@@ -106,7 +109,6 @@ func TestNewRouteCallsSyncHandler(t *testing.T) {
 	// Run the controller.
 	eg := errgroup.Group{}
 	eg.Go(func() error {
-		ctrl := NewController(ctx, configMapWatcher)
 		return ctrl.Run(2, ctx.Done())
 	})
 
@@ -77,6 +77,38 @@ func (c *Reconciler) reconcileIngress(ctx context.Context, r *v1.Route, desired
 	return ingress, err
 }
 
+// reconcileIngresses reconciles the Ingress shards of the Route and deletes
+// the shards which are no longer desired, e.g. after the Route dropped tags.
+func (c *Reconciler) reconcileIngresses(ctx context.Context, r *v1.Route, desired []*netv1alpha1.Ingress) ([]*netv1alpha1.Ingress, error) {
+	recorder := controller.GetEventRecorder(ctx)
+	ingresses := make([]*netv1alpha1.Ingress, 0, len(desired))
+	desiredNames := make(sets.String, len(desired))
+	for _, d := range desired {
+		ingress, err := c.reconcileIngress(ctx, r, d)
+		if err != nil {
+			return nil, err
+		}
+		ingresses = append(ingresses, ingress)
+		desiredNames.Insert(d.Name)
+	}
+
+	existing, err := c.ingressLister.Ingresses(r.Namespace).List(resources.SelectorFromRoute(r))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch existing Ingresses: %w", err)
+	}
+	for _, ingress := range existing {
+		if desiredNames.Has(ingress.Name) || !metav1.IsControlledBy(ingress, r) {
+			continue
+		}
+		if err := c.netclient.NetworkingV1alpha1().Ingresses(ingress.Namespace).Delete(
+			ctx, ingress.Name, metav1.DeleteOptions{}); err != nil && !apierrs.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to delete Ingress: %w", err)
+		}
+		recorder.Eventf(r, corev1.EventTypeNormal, "Deleted", "Deleted Ingress %q", ingress.Name)
+	}
+	return ingresses, nil
+}
+
 func (c *Reconciler) deleteServices(ctx context.Context, namespace string, serviceNames sets.String) error {
 	for _, serviceName := range serviceNames.List() {
 		if err := c.kubeclient.CoreV1().Services(namespace).Delete(ctx, serviceName, metav1.DeleteOptions{}); err != nil {
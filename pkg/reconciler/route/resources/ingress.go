@@ -19,7 +19,10 @@ package resources
 import (
 	"context"
 	"encoding/json"
+	"regexp"
 	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/davecgh/go-spew/spew"
 	"go.uber.org/zap"
@@ -38,6 +41,7 @@ import (
 	apicfg "knative.dev/serving/pkg/apis/config"
 	"knative.dev/serving/pkg/apis/serving"
 	servingv1 "knative.dev/serving/pkg/apis/serving/v1"
+	servingnetworking "knative.dev/serving/pkg/networking"
 	"knative.dev/serving/pkg/reconciler/route/config"
 	"knative.dev/serving/pkg/reconciler/route/domains"
 	"knative.dev/serving/pkg/reconciler/route/resources/labels"
@@ -88,6 +92,65 @@ func MakeIngress(
 	}, nil
 }
 
+// MakeIngresses creates the Ingresses to set up routing rules for the Route.
+// Normally this is a single Ingress, but when the number of rules exceeds the
+// ingress shard size configured in the network config map, the rules are
+// sharded across multiple Ingresses, each carrying the TLS configuration
+// only for the hosts of its own rules.
+func MakeIngresses(
+	ctx context.Context,
+	r *servingv1.Route,
+	tc *traffic.Config,
+	tls []netv1alpha1.IngressTLS,
+	ingressClass string,
+	acmeChallenges ...netv1alpha1.HTTP01Challenge,
+) ([]*netv1alpha1.Ingress, error) {
+	ing, err := MakeIngress(ctx, r, tc, tls, ingressClass, acmeChallenges...)
+	if err != nil {
+		return nil, err
+	}
+	size := int(servingnetworking.FromContextOrDefaults(ctx).IngressShardSize)
+	rules := ing.Spec.Rules
+	if size == 0 || len(rules) <= size {
+		return []*netv1alpha1.Ingress{ing}, nil
+	}
+
+	shards := make([]*netv1alpha1.Ingress, 0, (len(rules)+size-1)/size)
+	for i := 0; i*size < len(rules); i++ {
+		end := (i + 1) * size
+		if end > len(rules) {
+			end = len(rules)
+		}
+		shard := &netv1alpha1.Ingress{
+			ObjectMeta: *ing.ObjectMeta.DeepCopy(),
+			Spec: netv1alpha1.IngressSpec{
+				Rules: rules[i*size : end : end],
+			},
+		}
+		shard.Name = names.IngressShard(r, i)
+		shard.Spec.TLS = tlsForRules(ing.Spec.TLS, shard.Spec.Rules)
+		shards = append(shards, shard)
+	}
+	return shards, nil
+}
+
+// tlsForRules returns the subset of the TLS configuration that applies to
+// the hosts of the given rules.
+func tlsForRules(tls []netv1alpha1.IngressTLS, rules []netv1alpha1.IngressRule) []netv1alpha1.IngressTLS {
+	hosts := sets.NewString()
+	for _, rule := range rules {
+		hosts.Insert(rule.Hosts...)
+	}
+	var ret []netv1alpha1.IngressTLS
+	for _, t := range tls {
+		if shardHosts := hosts.Intersection(sets.NewString(t.Hosts...)); shardHosts.Len() > 0 {
+			t.Hosts = shardHosts.List()
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
 func serializeRollout(ctx context.Context, r *traffic.Rollout) string {
 	sr, err := json.Marshal(r)
 	if err != nil {
@@ -120,19 +183,52 @@ func makeIngressSpec(
 	challengeHosts := getChallengeHosts(acmeChallenges)
 
 	featuresConfig := config.FromContextOrDefaults(ctx).Features
+	// The annotation has been validated by the webhook.
+	mirrors, _ := serving.ParseMirrorAnnotation(r.Annotations)
+	headerRules, _ := serving.ParseHeaderRulesAnnotation(r.Annotations)
+
+	// The cluster-local tags may not be reached through the public rule of
+	// the default target either, by header or match.
+	publicNames := make([]string, 0, len(names))
+	publicMatchTargets := make(traffic.RevisionTargets, 0, len(tc.MatchTargets))
+	for _, name := range names {
+		if isPublic(tc, name) {
+			publicNames = append(publicNames, name)
+		}
+	}
+	for _, t := range tc.MatchTargets {
+		if isPublic(tc, t.Tag) {
+			publicMatchTargets = append(publicMatchTargets, t)
+		}
+	}
+
+	customHosts := domains.CustomHosts(r, tc.Visibility)
 
 	for _, name := range names {
 		visibilities := []netv1alpha1.IngressVisibility{netv1alpha1.IngressVisibilityClusterLocal}
 		// If this is a public target (or not being marked as cluster-local), we also make public rule.
-		if v, ok := tc.Visibility[name]; !ok || v == netv1alpha1.IngressVisibilityExternalIP {
+		if isPublic(tc, name) {
 			visibilities = append(visibilities, netv1alpha1.IngressVisibilityExternalIP)
 		}
 		for _, visibility := range visibilities {
+			tagNames, matchTargets := names, tc.MatchTargets
+			if visibility == netv1alpha1.IngressVisibilityExternalIP {
+				tagNames, matchTargets = publicNames, publicMatchTargets
+			}
 			domains, err := routeDomain(ctx, name, r, visibility)
 			if err != nil {
 				return netv1alpha1.IngressSpec{}, err
 			}
+			if name == traffic.DefaultTarget && visibility == netv1alpha1.IngressVisibilityExternalIP {
+				domains = append(domains, customHosts...)
+			}
 			rule := makeIngressRule(domains, r.Namespace, visibility, tc.Targets[name])
+			if m, ok := mirrors[name]; ok {
+				appendMirrorHeaders(&rule.HTTP.Paths[0], m)
+			}
+			if err := appendHeaderRules(&rule.HTTP.Paths[0], headerRules, name); err != nil {
+				return netv1alpha1.IngressSpec{}, err
+			}
 			if featuresConfig.TagHeaderBasedRouting == apicfg.Enabled {
 				if rule.HTTP.Paths[0].AppendHeaders == nil {
 					rule.HTTP.Paths[0].AppendHeaders = make(map[string]string)
@@ -147,8 +243,11 @@ func makeIngressSpec(
 					// Add ingress paths for a request with the tag header.
 					// If a request has one of the `names`(tag name) except the default path,
 					// the request will be routed via one of the ingress paths, corresponding to the tag name.
-					rule.HTTP.Paths = append(
-						makeTagBasedRoutingIngressPaths(r.Namespace, tc, names), rule.HTTP.Paths...)
+					tagPaths, err := makeTagBasedRoutingIngressPaths(r.Namespace, tc, tagNames, mirrors, headerRules)
+					if err != nil {
+						return netv1alpha1.IngressSpec{}, err
+					}
+					rule.HTTP.Paths = append(tagPaths, rule.HTTP.Paths...)
 				} else {
 					// If a request is routed by a tag-attached hostname instead of the tag header,
 					// the request may not have the tag header "Knative-Serving-Tag",
@@ -160,6 +259,14 @@ func makeIngressSpec(
 					rule.HTTP.Paths[0].AppendHeaders[network.TagHeaderName] = name
 				}
 			}
+			if name == traffic.DefaultTarget && len(matchTargets) > 0 {
+				// The matching paths take precedence over the percentage split.
+				matchPaths, err := makeMatchingIngressPaths(r.Namespace, matchTargets, headerRules)
+				if err != nil {
+					return netv1alpha1.IngressSpec{}, err
+				}
+				rule.HTTP.Paths = append(matchPaths, rule.HTTP.Paths...)
+			}
 			// If this is a public rule, we need to configure ACME challenge paths.
 			if visibility == netv1alpha1.IngressVisibilityExternalIP {
 				rule.HTTP.Paths = append(
@@ -175,6 +282,13 @@ func makeIngressSpec(
 	}, nil
 }
 
+// isPublic returns whether the traffic target of the name is exposed outside
+// of the cluster, i.e. it's not marked as cluster-local.
+func isPublic(tc *traffic.Config, name string) bool {
+	v, ok := tc.Visibility[name]
+	return !ok || v == netv1alpha1.IngressVisibilityExternalIP
+}
+
 func getChallengeHosts(challenges []netv1alpha1.HTTP01Challenge) map[string]netv1alpha1.HTTP01Challenge {
 	c := make(map[string]netv1alpha1.HTTP01Challenge, len(challenges))
 
@@ -242,18 +356,102 @@ func makeIngressRule(domains []string, ns string,
 	}
 }
 
-func makeTagBasedRoutingIngressPaths(ns string, tc *traffic.Config, names []string) []netv1alpha1.HTTPIngressPath {
+func makeTagBasedRoutingIngressPaths(ns string, tc *traffic.Config, names []string,
+	mirrors map[string]serving.MirrorTarget, headerRules map[string]serving.HeaderRules) ([]netv1alpha1.HTTPIngressPath, error) {
 	paths := make([]netv1alpha1.HTTPIngressPath, 0, len(names))
 
 	for _, name := range names {
 		if name != traffic.DefaultTarget {
 			path := makeBaseIngressPath(ns, tc.Targets[name])
 			path.Headers = map[string]netv1alpha1.HeaderMatch{network.TagHeaderName: {Exact: name}}
+			if m, ok := mirrors[name]; ok {
+				appendMirrorHeaders(path, m)
+			}
+			if err := appendHeaderRules(path, headerRules, name); err != nil {
+				return nil, err
+			}
 			paths = append(paths, *path)
 		}
 	}
 
-	return paths
+	return paths, nil
+}
+
+// pathPrefixRegex returns the regular expression of the KIngress path matching
+// the paths under the prefix, which is matched literally. The trailing slash
+// of the prefix is optional, like in the Route validation.
+func pathPrefixRegex(prefix string) string {
+	return regexp.QuoteMeta(strings.TrimSuffix(prefix, "/")) + "(/.*)?"
+}
+
+// makeMatchingIngressPaths makes a path for each of the match targets,
+// matching the requests on its headers and path prefix. Its split carries the
+// revision headers like any other, so that the activator, while in the request
+// path, forwards the matching requests to the revision of the target too.
+func makeMatchingIngressPaths(ns string, targets traffic.RevisionTargets,
+	headerRules map[string]serving.HeaderRules) ([]netv1alpha1.HTTPIngressPath, error) {
+	paths := make([]netv1alpha1.HTTPIngressPath, 0, len(targets))
+
+	for _, t := range targets {
+		path := makeBaseIngressPath(ns, traffic.RevisionTargets{t})
+		if t.PathPrefix != "" {
+			path.Path = pathPrefixRegex(t.PathPrefix)
+		}
+		if len(t.Headers) > 0 {
+			path.Headers = make(map[string]netv1alpha1.HeaderMatch, len(t.Headers))
+			for k, v := range t.Headers {
+				path.Headers[k] = netv1alpha1.HeaderMatch{Exact: v}
+			}
+		}
+		if err := appendHeaderRules(path, headerRules, t.Tag); err != nil {
+			return nil, err
+		}
+		paths = append(paths, *path)
+	}
+
+	return paths, nil
+}
+
+// appendMirrorHeaders instructs the activator to mirror the given percentage
+// of the requests routed via the path to the mirror revision.
+// Mirroring happens only while the activator is in the request path.
+func appendMirrorHeaders(path *netv1alpha1.HTTPIngressPath, m serving.MirrorTarget) {
+	for i := range path.Splits {
+		path.Splits[i].AppendHeaders[activator.MirrorRevisionHeaderName] = m.RevisionName
+		path.Splits[i].AppendHeaders[activator.MirrorPercentHeaderName] = strconv.Itoa(m.Percent)
+	}
+}
+
+// appendHeaderRules programs the header rules of the named traffic target,
+// or of all the targets, into the path. The Ingress sets the request headers,
+// while the other rules are passed along to the queue-proxy.
+func appendHeaderRules(path *netv1alpha1.HTTPIngressPath, rules map[string]serving.HeaderRules, name string) error {
+	r, ok := rules[name]
+	if !ok {
+		if r, ok = rules[serving.HeaderRulesAllTargets]; !ok {
+			return nil
+		}
+	}
+	if path.AppendHeaders == nil {
+		path.AppendHeaders = make(map[string]string, len(r.Request.Set)+1)
+	}
+	for k, v := range r.Request.Set {
+		path.AppendHeaders[k] = v
+	}
+	r.Request.Set = nil
+	if isEmptyHeaderOperations(r.Request) && isEmptyHeaderOperations(r.Response) {
+		return nil
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	path.AppendHeaders[activator.HeaderRulesHeaderName] = string(b)
+	return nil
+}
+
+func isEmptyHeaderOperations(o serving.HeaderOperations) bool {
+	return len(o.Set) == 0 && len(o.Add) == 0 && len(o.Remove) == 0
 }
 
 func makeBaseIngressPath(ns string, targets traffic.RevisionTargets) *netv1alpha1.HTTPIngressPath {
@@ -264,6 +462,17 @@ func makeBaseIngressPath(ns string, targets traffic.RevisionTargets) *netv1alpha
 			continue
 		}
 
+		headers := map[string]string{
+			activator.RevisionHeaderName:      t.TrafficTarget.RevisionName,
+			activator.RevisionHeaderNamespace: ns,
+		}
+		if t.Port != "" {
+			headers[activator.PortHeaderName] = t.Port
+		}
+		if t.Mirror != nil {
+			headers[activator.MirrorRevisionHeaderName] = t.Mirror.RevisionName
+			headers[activator.MirrorPercentHeaderName] = strconv.FormatInt(t.Mirror.Percent, 10)
+		}
 		splits = append(splits, netv1alpha1.IngressBackendSplit{
 			IngressBackend: netv1alpha1.IngressBackend{
 				ServiceNamespace: ns,
@@ -272,11 +481,8 @@ func makeBaseIngressPath(ns string, targets traffic.RevisionTargets) *netv1alpha
 				// Otherwise, the serverless services can't guarantee seamless positive handoff.
 				ServicePort: intstr.FromInt(networking.ServicePort(t.Protocol)),
 			},
-			Percent: int(*t.Percent),
-			AppendHeaders: map[string]string{
-				activator.RevisionHeaderName:      t.TrafficTarget.RevisionName,
-				activator.RevisionHeaderNamespace: ns,
-			},
+			Percent:       int(*t.Percent),
+			AppendHeaders: headers,
 		})
 	}
 
@@ -18,6 +18,7 @@ package resources
 
 import (
 	"context"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -35,9 +36,11 @@ import (
 	pkgnet "knative.dev/pkg/network"
 	"knative.dev/pkg/ptr"
 	"knative.dev/pkg/system"
+	"knative.dev/serving/pkg/activator"
 	apicfg "knative.dev/serving/pkg/apis/config"
 	"knative.dev/serving/pkg/apis/serving"
 	v1 "knative.dev/serving/pkg/apis/serving/v1"
+	servingnetworking "knative.dev/serving/pkg/networking"
 	"knative.dev/serving/pkg/reconciler/route/config"
 	"knative.dev/serving/pkg/reconciler/route/traffic"
 
@@ -286,6 +289,90 @@ func TestMakeIngressSpecCorrectRules(t *testing.T) {
 	}
 }
 
+func TestMakeIngressSpecCustomHosts(t *testing.T) {
+	targets := map[string]traffic.RevisionTargets{
+		traffic.DefaultTarget: {{
+			TrafficTarget: v1.TrafficTarget{
+				ConfigurationName: "config",
+				RevisionName:      "v2",
+				Percent:           ptr.Int64(100),
+			},
+			ServiceName: "gilberto",
+			Active:      true,
+		}},
+		"v1": {{
+			TrafficTarget: v1.TrafficTarget{
+				ConfigurationName: "config",
+				RevisionName:      "v1",
+				Percent:           ptr.Int64(100),
+			},
+			ServiceName: "jobim",
+			Active:      true,
+		}},
+	}
+	r := Route(ns, "test-route", WithURL, WithRouteAnnotation(map[string]string{
+		serving.HostsAnnotationKey: "www.example.org, example.org",
+	}))
+
+	tests := []struct {
+		name       string
+		visibility map[string]netv1alpha1.IngressVisibility
+		want       [][]string
+	}{{
+		name: "public",
+		want: [][]string{{
+			"test-route." + ns,
+			"test-route." + ns + ".svc",
+			pkgnet.GetServiceHostname("test-route", ns),
+		}, {
+			// Only the public rule of the default target serves the custom hosts.
+			"test-route." + ns + ".example.com",
+			"www.example.org",
+			"example.org",
+		}, {
+			"v1-test-route." + ns,
+			"v1-test-route." + ns + ".svc",
+			pkgnet.GetServiceHostname("v1-test-route", ns),
+		}, {
+			"v1-test-route." + ns + ".example.com",
+		}},
+	}, {
+		name: "cluster local",
+		visibility: map[string]netv1alpha1.IngressVisibility{
+			traffic.DefaultTarget: netv1alpha1.IngressVisibilityClusterLocal,
+			"v1":                  netv1alpha1.IngressVisibilityClusterLocal,
+		},
+		want: [][]string{{
+			"test-route." + ns,
+			"test-route." + ns + ".svc",
+			pkgnet.GetServiceHostname("test-route", ns),
+		}, {
+			"v1-test-route." + ns,
+			"v1-test-route." + ns + ".svc",
+			pkgnet.GetServiceHostname("v1-test-route", ns),
+		}},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ci, err := makeIngressSpec(testContext(), r, nil, &traffic.Config{
+				Targets:    targets,
+				Visibility: test.visibility,
+			})
+			if err != nil {
+				t.Fatal("Unexpected error", err)
+			}
+			got := make([][]string, 0, len(ci.Rules))
+			for _, rule := range ci.Rules {
+				got = append(got, rule.Hosts)
+			}
+			if !cmp.Equal(got, test.want) {
+				t.Error("Unexpected rule hosts (-want, +got):", cmp.Diff(test.want, got))
+			}
+		})
+	}
+}
+
 func TestMakeIngressSpecCorrectRuleVisibility(t *testing.T) {
 	cases := []struct {
 		name               string
@@ -547,6 +634,386 @@ func TestMakeIngressSpecCorrectRulesWithTagBasedRouting(t *testing.T) {
 	}
 }
 
+func TestMakeIngressSpecClusterLocalTag(t *testing.T) {
+	targets := map[string]traffic.RevisionTargets{
+		traffic.DefaultTarget: {{
+			TrafficTarget: v1.TrafficTarget{
+				ConfigurationName: "config",
+				RevisionName:      "v2",
+				Percent:           ptr.Int64(100),
+			},
+			ServiceName: "gilberto",
+			Active:      true,
+		}},
+		"v1": {{
+			TrafficTarget: v1.TrafficTarget{
+				ConfigurationName: "config",
+				RevisionName:      "v1",
+				Percent:           ptr.Int64(100),
+			},
+			ServiceName: "jobim",
+			Active:      true,
+		}},
+	}
+	matchTargets := traffic.RevisionTargets{{
+		TrafficTarget: v1.TrafficTarget{
+			Tag:               "v1",
+			ConfigurationName: "config",
+			RevisionName:      "v1",
+			Percent:           ptr.Int64(100),
+			PathPrefix:        "/debug",
+		},
+		ServiceName: "jobim",
+		Active:      true,
+	}}
+
+	r := Route(ns, "test-route", WithURL)
+	ctx := testContext()
+	config.FromContext(ctx).Features.TagHeaderBasedRouting = apicfg.Enabled
+
+	ci, err := makeIngressSpec(ctx, r, nil, &traffic.Config{
+		Targets:      targets,
+		MatchTargets: matchTargets,
+		Visibility: map[string]netv1alpha1.IngressVisibility{
+			traffic.DefaultTarget: netv1alpha1.IngressVisibilityExternalIP,
+			"v1":                  netv1alpha1.IngressVisibilityClusterLocal,
+		},
+	})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	// The cluster-local rules of both targets and the public rule of the default one.
+	if got, want := len(ci.Rules), 3; got != want {
+		t.Fatalf("Rules = %d, want: %d", got, want)
+	}
+	for _, rule := range ci.Rules {
+		var tagged int
+		for _, path := range rule.HTTP.Paths {
+			if path.Splits[0].AppendHeaders[activator.RevisionHeaderName] == "v1" {
+				tagged++
+			}
+		}
+		// The default target's cluster-local rule routes the tag by header and path.
+		want := 1
+		if rule.Visibility == netv1alpha1.IngressVisibilityClusterLocal && len(rule.HTTP.Paths) > 1 {
+			want = 2
+		} else if rule.Visibility == netv1alpha1.IngressVisibilityExternalIP {
+			want = 0
+		}
+		if tagged != want {
+			t.Errorf("Paths to the tag of %v = %d, want: %d", rule.Hosts, tagged, want)
+		}
+	}
+}
+
+func TestMakeIngressSpecMirror(t *testing.T) {
+	targets := map[string]traffic.RevisionTargets{
+		traffic.DefaultTarget: {{
+			TrafficTarget: v1.TrafficTarget{
+				ConfigurationName: "config",
+				RevisionName:      "v2",
+				Percent:           ptr.Int64(100),
+			},
+			ServiceName: "gilberto",
+			Active:      true,
+		}},
+		"v1": {{
+			TrafficTarget: v1.TrafficTarget{
+				ConfigurationName: "config",
+				RevisionName:      "v1",
+				Percent:           ptr.Int64(100),
+			},
+			ServiceName: "jobim",
+			Active:      true,
+		}},
+	}
+
+	r := Route(ns, "test-route", WithURL, WithRouteAnnotation(map[string]string{
+		serving.MirrorAnnotationKey: `{"v1": {"revisionName": "v3", "percent": 10}}`,
+	}))
+
+	ctx := testContext()
+	config.FromContext(ctx).Features.TagHeaderBasedRouting = apicfg.Enabled
+
+	ci, err := makeIngressSpec(ctx, r, nil, &traffic.Config{Targets: targets})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	var mirrored int
+	for _, rule := range ci.Rules {
+		for _, path := range rule.HTTP.Paths {
+			for _, split := range path.Splits {
+				// Only the requests routed to the tag are mirrored.
+				want := map[string]string{}
+				if split.AppendHeaders[activator.RevisionHeaderName] == "v1" {
+					want = map[string]string{
+						activator.MirrorRevisionHeaderName: "v3",
+						activator.MirrorPercentHeaderName:  "10",
+					}
+					mirrored++
+				}
+				got := map[string]string{}
+				for _, h := range []string{activator.MirrorRevisionHeaderName, activator.MirrorPercentHeaderName} {
+					if v, ok := split.AppendHeaders[h]; ok {
+						got[h] = v
+					}
+				}
+				if !cmp.Equal(want, got) {
+					t.Errorf("Mirror headers of %v (-want, +got): %s", rule.Hosts, cmp.Diff(want, got))
+				}
+			}
+		}
+	}
+	// The tag header paths and the tag hosts, for both visibilities.
+	if got, want := mirrored, 4; got != want {
+		t.Errorf("Mirrored splits = %d, want: %d", got, want)
+	}
+}
+
+func TestMakeIngressSpecTargetMirror(t *testing.T) {
+	mirror := &v1.TrafficMirror{RevisionName: "v3", Percent: 20}
+	targets := map[string]traffic.RevisionTargets{
+		traffic.DefaultTarget: {{
+			TrafficTarget: v1.TrafficTarget{
+				ConfigurationName: "config",
+				RevisionName:      "v2",
+				Percent:           ptr.Int64(50),
+				Mirror:            mirror,
+			},
+			ServiceName: "gilberto",
+			Active:      true,
+		}, {
+			TrafficTarget: v1.TrafficTarget{
+				ConfigurationName: "config",
+				RevisionName:      "v1",
+				Percent:           ptr.Int64(50),
+			},
+			ServiceName: "jobim",
+			Active:      true,
+		}},
+		"v1": {{
+			TrafficTarget: v1.TrafficTarget{
+				ConfigurationName: "config",
+				RevisionName:      "v1",
+				Percent:           ptr.Int64(100),
+			},
+			ServiceName: "jobim",
+			Active:      true,
+		}},
+	}
+
+	ci, err := makeIngressSpec(testContext(), Route(ns, "test-route", WithURL), nil, &traffic.Config{Targets: targets})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	var mirrored int
+	for _, rule := range ci.Rules {
+		for _, split := range rule.HTTP.Paths[0].Splits {
+			// Only the requests routed to the mirroring target are mirrored.
+			want := map[string]string{}
+			if split.AppendHeaders[activator.RevisionHeaderName] == "v2" {
+				want = map[string]string{
+					activator.MirrorRevisionHeaderName: "v3",
+					activator.MirrorPercentHeaderName:  "20",
+				}
+				mirrored++
+			}
+			got := map[string]string{}
+			for _, h := range []string{activator.MirrorRevisionHeaderName, activator.MirrorPercentHeaderName} {
+				if v, ok := split.AppendHeaders[h]; ok {
+					got[h] = v
+				}
+			}
+			if !cmp.Equal(want, got) {
+				t.Errorf("Mirror headers of %v (-want, +got): %s", rule.Hosts, cmp.Diff(want, got))
+			}
+		}
+	}
+	// The default target splits, for both visibilities.
+	if got, want := mirrored, 2; got != want {
+		t.Errorf("Mirrored splits = %d, want: %d", got, want)
+	}
+}
+
+func TestMakeIngressSpecHeaderRules(t *testing.T) {
+	targets := map[string]traffic.RevisionTargets{
+		traffic.DefaultTarget: {{
+			TrafficTarget: v1.TrafficTarget{
+				ConfigurationName: "config",
+				RevisionName:      "v2",
+				Percent:           ptr.Int64(100),
+			},
+			ServiceName: "gilberto",
+			Active:      true,
+		}},
+		"v1": {{
+			TrafficTarget: v1.TrafficTarget{
+				ConfigurationName: "config",
+				RevisionName:      "v1",
+				Percent:           ptr.Int64(100),
+			},
+			ServiceName: "jobim",
+			Active:      true,
+		}},
+	}
+
+	r := Route(ns, "test-route", WithURL, WithRouteAnnotation(map[string]string{
+		serving.HeaderRulesAnnotationKey: `{
+			"*": {"response": {"set": {"Strict-Transport-Security": "max-age=31536000"}}},
+			"v1": {"request": {"set": {"X-Candidate": "true"}}}
+		}`,
+	}))
+
+	ctx := testContext()
+	config.FromContext(ctx).Features.TagHeaderBasedRouting = apicfg.Enabled
+
+	ci, err := makeIngressSpec(ctx, r, nil, &traffic.Config{Targets: targets})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	for _, rule := range ci.Rules {
+		for _, path := range rule.HTTP.Paths {
+			// The tag has only request headers to set, which the Ingress sets,
+			// the other traffic the response headers for queue-proxy to set.
+			want := map[string]string{
+				activator.HeaderRulesHeaderName: `{"request":{},"response":{"set":{"Strict-Transport-Security":"max-age=31536000"}}}`,
+			}
+			if path.Splits[0].AppendHeaders[activator.RevisionHeaderName] == "v1" {
+				want = map[string]string{"X-Candidate": "true"}
+			}
+			got := map[string]string{}
+			for _, h := range []string{activator.HeaderRulesHeaderName, "X-Candidate"} {
+				if v, ok := path.AppendHeaders[h]; ok {
+					got[h] = v
+				}
+			}
+			if !cmp.Equal(want, got) {
+				t.Errorf("Header rules of %v (-want, +got): %s", rule.Hosts, cmp.Diff(want, got))
+			}
+		}
+	}
+}
+
+func TestPathPrefixRegex(t *testing.T) {
+	for prefix, want := range map[string]string{
+		"/api":       "/api(/.*)?",
+		"/api/":      "/api(/.*)?",
+		"/v1.0/a+b":  `/v1\.0/a\+b(/.*)?`,
+		"/(x)/[y]/*": `/\(x\)/\[y\]/\*(/.*)?`,
+	} {
+		got := pathPrefixRegex(prefix)
+		if got != want {
+			t.Errorf("pathPrefixRegex(%q) = %q, want: %q", prefix, got, want)
+		}
+		base := strings.TrimSuffix(prefix, "/")
+		re := regexp.MustCompile("^(?:" + got + ")$")
+		for _, p := range []string{base, base + "/", base + "/x/y"} {
+			if !re.MatchString(p) {
+				t.Errorf("%q does not match %q under the prefix %q", got, p, prefix)
+			}
+		}
+		if sibling := base + "x"; re.MatchString(sibling) {
+			t.Errorf("%q matches the sibling path %q of the prefix %q", got, sibling, prefix)
+		}
+	}
+}
+
+func TestMakeIngressSpecMatchTargets(t *testing.T) {
+	targets := map[string]traffic.RevisionTargets{
+		traffic.DefaultTarget: {{
+			TrafficTarget: v1.TrafficTarget{
+				ConfigurationName: "config",
+				RevisionName:      "v1",
+				Percent:           ptr.Int64(100),
+			},
+			ServiceName: "jobim",
+			Active:      true,
+		}},
+	}
+	matchTargets := traffic.RevisionTargets{{
+		TrafficTarget: v1.TrafficTarget{
+			ConfigurationName: "config",
+			RevisionName:      "v3",
+			Percent:           ptr.Int64(100),
+			PathPrefix:        "/api",
+		},
+		ServiceName: "caetano",
+		Active:      true,
+	}, {
+		TrafficTarget: v1.TrafficTarget{
+			ConfigurationName: "config",
+			RevisionName:      "v2",
+			Percent:           ptr.Int64(100),
+			Headers:           map[string]string{"X-Canary": "true"},
+		},
+		ServiceName: "gilberto",
+		Active:      true,
+	}}
+
+	r := Route(ns, "test-route", WithURL)
+	ci, err := makeIngressSpec(testContext(), r, nil, &traffic.Config{
+		Targets:      targets,
+		MatchTargets: matchTargets,
+	})
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	want := []netv1alpha1.HTTPIngressPath{{
+		Path: "/api(/.*)?",
+		Splits: []netv1alpha1.IngressBackendSplit{{
+			IngressBackend: netv1alpha1.IngressBackend{
+				ServiceNamespace: ns,
+				ServiceName:      "caetano",
+				ServicePort:      intstr.FromInt(80),
+			},
+			Percent: 100,
+			AppendHeaders: map[string]string{
+				"Knative-Serving-Revision":  "v3",
+				"Knative-Serving-Namespace": ns,
+			},
+		}},
+	}, {
+		Headers: map[string]netv1alpha1.HeaderMatch{
+			"X-Canary": {Exact: "true"},
+		},
+		Splits: []netv1alpha1.IngressBackendSplit{{
+			IngressBackend: netv1alpha1.IngressBackend{
+				ServiceNamespace: ns,
+				ServiceName:      "gilberto",
+				ServicePort:      intstr.FromInt(80),
+			},
+			Percent: 100,
+			AppendHeaders: map[string]string{
+				"Knative-Serving-Revision":  "v2",
+				"Knative-Serving-Namespace": ns,
+			},
+		}},
+	}, {
+		Splits: []netv1alpha1.IngressBackendSplit{{
+			IngressBackend: netv1alpha1.IngressBackend{
+				ServiceNamespace: ns,
+				ServiceName:      "jobim",
+				ServicePort:      intstr.FromInt(80),
+			},
+			Percent: 100,
+			AppendHeaders: map[string]string{
+				"Knative-Serving-Revision":  "v1",
+				"Knative-Serving-Namespace": ns,
+			},
+		}},
+	}}
+	for _, rule := range ci.Rules {
+		if !cmp.Equal(want, rule.HTTP.Paths) {
+			t.Errorf("Paths of %v (-want, +got): %s", rule.Hosts, cmp.Diff(want, rule.HTTP.Paths))
+		}
+	}
+}
+
 // One active target.
 func TestMakeIngressRuleVanilla(t *testing.T) {
 	targets := []traffic.RevisionTarget{{
@@ -589,6 +1056,46 @@ func TestMakeIngressRuleVanilla(t *testing.T) {
 	}
 }
 
+func TestMakeIngressRuleNamedPort(t *testing.T) {
+	targets := []traffic.RevisionTarget{{
+		TrafficTarget: v1.TrafficTarget{
+			Tag:          "admin",
+			RevisionName: "revision",
+			Percent:      ptr.Int64(100),
+			Port:         "admin",
+		},
+		ServiceName: "chocolate",
+		Active:      true,
+	}}
+	domains := []string{"admin-a.com"}
+	rule := makeIngressRule(domains, ns, netv1alpha1.IngressVisibilityExternalIP, targets)
+	expected := netv1alpha1.IngressRule{
+		Hosts: domains,
+		HTTP: &netv1alpha1.HTTPIngressRuleValue{
+			Paths: []netv1alpha1.HTTPIngressPath{{
+				Splits: []netv1alpha1.IngressBackendSplit{{
+					IngressBackend: netv1alpha1.IngressBackend{
+						ServiceNamespace: ns,
+						ServiceName:      "chocolate",
+						ServicePort:      intstr.FromInt(80),
+					},
+					Percent: 100,
+					AppendHeaders: map[string]string{
+						"Knative-Serving-Revision":  "revision",
+						"Knative-Serving-Namespace": ns,
+						"Knative-Serving-Port":      "admin",
+					},
+				}},
+			}},
+		},
+		Visibility: netv1alpha1.IngressVisibilityExternalIP,
+	}
+
+	if !cmp.Equal(expected, rule) {
+		t.Error("Unexpected rule (-want, +got):", cmp.Diff(expected, rule))
+	}
+}
+
 // One active target and a target of zero percent.
 func TestMakeIngressRuleZeroPercentTarget(t *testing.T) {
 	targets := []traffic.RevisionTarget{{
@@ -967,6 +1474,82 @@ func TestMakeIngressWithTLS(t *testing.T) {
 	}
 }
 
+func TestMakeIngresses(t *testing.T) {
+	target := func(rev string) traffic.RevisionTargets {
+		return traffic.RevisionTargets{{
+			TrafficTarget: v1.TrafficTarget{
+				ConfigurationName: "config",
+				RevisionName:      rev,
+				Percent:           ptr.Int64(100),
+			},
+			ServiceName: rev,
+			Active:      true,
+		}}
+	}
+	tc := &traffic.Config{Targets: map[string]traffic.RevisionTargets{
+		traffic.DefaultTarget: target("v2"),
+		"v1":                  target("v1"),
+	}}
+	r := Route(ns, "test-route", WithRouteUID("1234-5678"), WithURL)
+	defaultHost := "test-route." + ns + ".example.com"
+	tagHost := "v1-test-route." + ns + ".example.com"
+	tls := []netv1alpha1.IngressTLS{{
+		Hosts:      []string{defaultHost, tagHost},
+		SecretName: "secret",
+	}}
+
+	// Not sharded by default.
+	ings, err := MakeIngresses(testContext(), r, tc, tls, testIngressClass)
+	if err != nil {
+		t.Fatal("MakeIngresses() =", err)
+	}
+	want, err := MakeIngress(testContext(), r, tc, tls, testIngressClass)
+	if err != nil {
+		t.Fatal("MakeIngress() =", err)
+	}
+	if !cmp.Equal([]*netv1alpha1.Ingress{want}, ings) {
+		t.Error("Unexpected Ingresses (-want, +got):", cmp.Diff([]*netv1alpha1.Ingress{want}, ings))
+	}
+
+	// Four rules: cluster local and public ones for each of the targets.
+	ctx := servingnetworking.ToContext(testContext(), &servingnetworking.Config{
+		IngressShardSize: 3,
+	})
+	ings, err = MakeIngresses(ctx, r, tc, tls, testIngressClass)
+	if err != nil {
+		t.Fatal("MakeIngresses() =", err)
+	}
+	if got, want := len(ings), 2; got != want {
+		t.Fatalf("#Ingresses = %d, want: %d", got, want)
+	}
+	for i, wantName := range []string{"test-route", "test-route-shard-1"} {
+		if got := ings[i].Name; got != wantName {
+			t.Errorf("Ingresses[%d].Name = %s, want: %s", i, got, wantName)
+		}
+		if !cmp.Equal(want.Labels, ings[i].Labels) || !cmp.Equal(want.OwnerReferences, ings[i].OwnerReferences) {
+			t.Errorf("Ingresses[%d] metadata = %#v, want the same as the unsharded one", i, ings[i].ObjectMeta)
+		}
+	}
+	if got, want := append(ings[0].Spec.Rules, ings[1].Spec.Rules...), want.Spec.Rules; !cmp.Equal(got, want) {
+		t.Error("Unexpected sharded rules (-want, +got):", cmp.Diff(want, got))
+	}
+	if got, want := len(ings[0].Spec.Rules), 3; got != want {
+		t.Errorf("#Rules in the first shard = %d, want: %d", got, want)
+	}
+	wantTLS := [][]netv1alpha1.IngressTLS{{{
+		Hosts:      []string{defaultHost},
+		SecretName: "secret",
+	}}, {{
+		Hosts:      []string{tagHost},
+		SecretName: "secret",
+	}}}
+	for i, ing := range ings {
+		if !cmp.Equal(wantTLS[i], ing.Spec.TLS) {
+			t.Errorf("Ingresses[%d] TLS (-want, +got): %s", i, cmp.Diff(wantTLS[i], ing.Spec.TLS))
+		}
+	}
+}
+
 func TestMakeIngressTLS(t *testing.T) {
 	cert := &netv1alpha1.Certificate{
 		ObjectMeta: metav1.ObjectMeta{
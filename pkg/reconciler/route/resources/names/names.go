@@ -17,6 +17,8 @@ limitations under the License.
 package names
 
 import (
+	"strconv"
+
 	"knative.dev/pkg/kmeta"
 	"knative.dev/pkg/network"
 )
@@ -37,6 +39,15 @@ func Ingress(route kmeta.Accessor) string {
 	return kmeta.ChildName(route.GetName(), "")
 }
 
+// IngressShard returns the name for the given shard of the Ingress
+// child resource for the given Route. The first shard is the Ingress itself.
+func IngressShard(route kmeta.Accessor, shard int) string {
+	if shard == 0 {
+		return Ingress(route)
+	}
+	return kmeta.ChildName(route.GetName(), "-shard-"+strconv.Itoa(shard))
+}
+
 // Certificate returns the name for the Certificate
 // child resource for the given Route.
 func Certificate(route kmeta.Accessor) string {
@@ -65,6 +65,16 @@ func TestNamer(t *testing.T) {
 	}
 }
 
+func TestIngressShard(t *testing.T) {
+	route := getRoute("bar", "default", "1234-5678-910")
+	if got, want := IngressShard(route, 0), Ingress(route); got != want {
+		t.Errorf("IngressShard(0) = %s, want: %s", got, want)
+	}
+	if got, want := IngressShard(route, 2), "bar-shard-2"; got != want {
+		t.Errorf("IngressShard(2) = %s, want: %s", got, want)
+	}
+}
+
 func getRoute(name, ns string, uid types.UID) *v1.Route {
 	return &v1.Route{
 		ObjectMeta: metav1.ObjectMeta{
@@ -18,16 +18,21 @@ package route
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"sort"
 	"strconv"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kubelabels "k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/kubernetes"
 	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 
 	network "knative.dev/networking/pkg"
 	"knative.dev/networking/pkg/apis/networking"
@@ -36,15 +41,18 @@ import (
 	networkinglisters "knative.dev/networking/pkg/client/listers/networking/v1alpha1"
 	"knative.dev/pkg/apis"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/controller"
 	"knative.dev/pkg/logging"
 	pkgreconciler "knative.dev/pkg/reconciler"
 	"knative.dev/pkg/system"
 	"knative.dev/pkg/tracker"
 	cfgmap "knative.dev/serving/pkg/apis/config"
+	"knative.dev/serving/pkg/apis/serving"
 	v1 "knative.dev/serving/pkg/apis/serving/v1"
 	clientset "knative.dev/serving/pkg/client/clientset/versioned"
 	routereconciler "knative.dev/serving/pkg/client/injection/reconciler/serving/v1/route"
 	listers "knative.dev/serving/pkg/client/listers/serving/v1"
+	servingreconciler "knative.dev/serving/pkg/reconciler"
 	kaccessor "knative.dev/serving/pkg/reconciler/accessor"
 	networkaccessor "knative.dev/serving/pkg/reconciler/accessor/networking"
 	"knative.dev/serving/pkg/reconciler/route/config"
@@ -65,10 +73,13 @@ type Reconciler struct {
 	// Listers index properties about resources
 	configurationLister listers.ConfigurationLister
 	revisionLister      listers.RevisionLister
+	routeIndexer        cache.Indexer
 	serviceLister       corev1listers.ServiceLister
+	namespaceLister     corev1listers.NamespaceLister
 	ingressLister       networkinglisters.IngressLister
 	certificateLister   networkinglisters.CertificateLister
 	tracker             tracker.Interface
+	requeuer            *servingreconciler.Requeuer
 
 	clock system.Clock
 }
@@ -105,10 +116,19 @@ func (c *Reconciler) getServices(route *v1.Route) ([]*corev1.Service, error) {
 }
 
 // ReconcileKind implements Interface.ReconcileKind.
-func (c *Reconciler) ReconcileKind(ctx context.Context, r *v1.Route) pkgreconciler.Event {
+func (c *Reconciler) ReconcileKind(ctx context.Context, r *v1.Route) (ev pkgreconciler.Event) {
+	defer func() { ev = c.requeuer.Handle(r, ev) }()
 	logger := logging.FromContext(ctx)
 	logger.Debugf("Reconciling route: %#v", r.Spec)
 
+	// The namespace labels select the domain of the route in config-domain.
+	ns, err := c.namespaceLister.Get(r.Namespace)
+	if err != nil && !apierrs.IsNotFound(err) {
+		return err
+	} else if err == nil {
+		ctx = config.WithNamespaceLabels(ctx, ns.Labels)
+	}
+
 	// When a new generation is observed for the first time, we need to make sure that we
 	// do not report ourselves as being ready prematurely due to an error during
 	// reconciliation.  For instance, if we were to hit an error creating new placeholder
@@ -134,6 +154,18 @@ func (c *Reconciler) ReconcileKind(ctx context.Context, r *v1.Route) pkgreconcil
 		}
 	}
 
+	if host := disallowedCustomHost(ctx, r); host != "" {
+		// The Ingress keeps serving the previously allowed hosts.
+		r.Status.MarkCustomHostNotAllowed(host)
+		return nil
+	}
+	if host, owner, err := c.claimCustomHosts(r); err != nil {
+		return err
+	} else if owner != nil {
+		r.Status.MarkCustomHostClaimed(host, owner.Namespace+"/"+owner.Name)
+		return nil
+	}
+
 	r.Status.Address = &duckv1.Addressable{
 		URL: &apis.URL{
 			Scheme: "http",
@@ -153,20 +185,17 @@ func (c *Reconciler) ReconcileKind(ctx context.Context, r *v1.Route) pkgreconcil
 	}
 
 	// Reconcile ingress and its children resources.
-	ingress, err := c.reconcileIngressResources(ctx, r, traffic, tls, ingressClassForRoute(ctx, r), acmeChallenges...)
+	ingresses, err := c.reconcileIngressResources(ctx, r, traffic, tls, ingressClassForRoute(ctx, r), acmeChallenges...)
 
 	if err != nil {
 		return err
 	}
 
-	if ingress.GetObjectMeta().GetGeneration() != ingress.Status.ObservedGeneration {
-		r.Status.MarkIngressNotConfigured()
-	} else {
-		r.Status.PropagateIngressStatus(ingress.Status)
-	}
+	propagateIngressStatus(r, ingresses)
 
 	logger.Info("Updating placeholder k8s services with ingress information")
-	if err := c.updatePlaceholderServices(ctx, r, services, ingress); err != nil {
+	// All the shards are served by the same load balancers.
+	if err := c.updatePlaceholderServices(ctx, r, services, ingresses[0]); err != nil {
 		return err
 	}
 
@@ -174,20 +203,147 @@ func (c *Reconciler) ReconcileKind(ctx context.Context, r *v1.Route) pkgreconcil
 	return nil
 }
 
+// disallowedCustomHost returns the first custom host of the Route not allowed
+// by config-domain, if any.
+func disallowedCustomHost(ctx context.Context, r *v1.Route) string {
+	domainConfig := config.FromContext(ctx).Domain
+	for _, host := range serving.ParseHostsAnnotation(r.Annotations) {
+		if !domainConfig.AllowsCustomHost(host) {
+			return host
+		}
+	}
+	return ""
+}
+
+// claimCustomHosts records in the Route status the claims of the custom hosts
+// of the Route not claimed by another Route, in any namespace, and returns the
+// first host already claimed by another Route, along with that Route.
+// A host is kept by the Route claiming it first.
+func (c *Reconciler) claimCustomHosts(r *v1.Route) (string, *v1.Route, error) {
+	var (
+		host   string
+		owner  *v1.Route
+		claims = hostClaims(r)
+		now    = metav1.NewTime(c.clock.Now())
+		kept   = make(map[string]metav1.Time, len(claims))
+	)
+	for _, h := range serving.ParseHostsAnnotation(r.Annotations) {
+		claim, ok := claims[h]
+		if !ok {
+			claim = now
+		}
+		o, err := c.hostOwner(h, r, claim)
+		if err != nil {
+			return "", nil, err
+		}
+		if o == nil {
+			kept[h] = claim
+		} else if owner == nil {
+			host, owner = h, o
+		}
+	}
+	if err := setHostClaims(r, kept); err != nil {
+		return "", nil, err
+	}
+	return host, owner, nil
+}
+
+// hostOwner returns the Route, other than r, holding the earliest claim of the
+// host, if it was claimed before the claim of r.
+func (c *Reconciler) hostOwner(host string, r *v1.Route, claim metav1.Time) (*v1.Route, error) {
+	objs, err := c.routeIndexer.ByIndex(hostsIndex, host)
+	if err != nil {
+		return nil, err
+	}
+	var owner *v1.Route
+	for _, obj := range objs {
+		other := obj.(*v1.Route)
+		if other.Namespace == r.Namespace && other.Name == r.Name {
+			continue
+		}
+		otherClaim, ok := hostClaims(other)[host]
+		if !ok || !claimedBefore(otherClaim, other, claim, r) {
+			continue
+		}
+		if owner == nil || claimedBefore(otherClaim, other, hostClaims(owner)[host], owner) {
+			owner = other
+		}
+	}
+	return owner, nil
+}
+
+// claimedBefore returns whether the claim a of the Route ra takes precedence
+// over the claim b of the Route rb, i.e. it was made first. The ties are broken
+// by the namespace and the name.
+func claimedBefore(a metav1.Time, ra *v1.Route, b metav1.Time, rb *v1.Route) bool {
+	if !a.Equal(&b) {
+		return a.Before(&b)
+	}
+	if ra.Namespace != rb.Namespace {
+		return ra.Namespace < rb.Namespace
+	}
+	return ra.Name < rb.Name
+}
+
+// hostClaims returns the claims of the custom hosts recorded in the Route status.
+func hostClaims(r *v1.Route) map[string]metav1.Time {
+	claims := map[string]metav1.Time{}
+	if v := r.Status.Annotations[serving.HostsClaimedAnnotationKey]; v != "" {
+		// The annotation is only written by us, and is claimed anew if mangled.
+		json.Unmarshal([]byte(v), &claims)
+	}
+	return claims
+}
+
+// setHostClaims records the claims of the custom hosts in the Route status.
+func setHostClaims(r *v1.Route, claims map[string]metav1.Time) error {
+	if len(claims) == 0 {
+		delete(r.Status.Annotations, serving.HostsClaimedAnnotationKey)
+		return nil
+	}
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return err
+	}
+	if r.Status.Annotations == nil {
+		r.Status.Annotations = make(map[string]string, 1)
+	}
+	r.Status.Annotations[serving.HostsClaimedAnnotationKey] = string(b)
+	return nil
+}
+
 func (c *Reconciler) reconcileIngressResources(ctx context.Context, r *v1.Route, tc *traffic.Config, tls []netv1alpha1.IngressTLS,
-	ingressClass string, acmeChallenges ...netv1alpha1.HTTP01Challenge) (*netv1alpha1.Ingress, error) {
+	ingressClass string, acmeChallenges ...netv1alpha1.HTTP01Challenge) ([]*netv1alpha1.Ingress, error) {
 
-	desired, err := resources.MakeIngress(ctx, r, tc, tls, ingressClass, acmeChallenges...)
+	desired, err := resources.MakeIngresses(ctx, r, tc, tls, ingressClass, acmeChallenges...)
 	if err != nil {
 		return nil, err
 	}
 
-	ingress, err := c.reconcileIngress(ctx, r, desired)
+	ingresses, err := c.reconcileIngresses(ctx, r, desired)
 	if err != nil {
 		return nil, err
 	}
 
-	return ingress, nil
+	return ingresses, nil
+}
+
+// propagateIngressStatus reflects the status of the Ingress shards in the Route.
+// The Route's ingress is ready only once all of the shards are, otherwise the
+// status of the first shard which is not ready is reported.
+func propagateIngressStatus(r *v1.Route, ingresses []*netv1alpha1.Ingress) {
+	for _, ing := range ingresses {
+		if ing.GetObjectMeta().GetGeneration() != ing.Status.ObservedGeneration {
+			r.Status.MarkIngressNotConfigured()
+			return
+		}
+	}
+	for _, ing := range ingresses {
+		r.Status.PropagateIngressStatus(ing.Status)
+		if !ing.Status.GetCondition(netv1alpha1.IngressConditionReady).IsTrue() {
+			return
+		}
+	}
 }
 
 func (c *Reconciler) tls(ctx context.Context, host string, r *v1.Route, traffic *traffic.Config) ([]netv1alpha1.IngressTLS, []netv1alpha1.HTTP01Challenge, error) {
@@ -209,7 +365,7 @@ func (c *Reconciler) tls(ctx context.Context, host string, r *v1.Route, traffic
 		}
 	}
 
-	routeDomain := config.FromContext(ctx).Domain.LookupDomainForLabels(r.Labels)
+	routeDomain := config.FromContext(ctx).Domain.LookupDomainForNamespace(r.Labels, config.NamespaceLabelsFromContext(ctx))
 	labelSelector := kubelabels.SelectorFromSet(kubelabels.Set{
 		networking.WildcardCertDomainLabelKey: routeDomain,
 	})
@@ -349,10 +505,31 @@ func (c *Reconciler) configureTraffic(ctx context.Context, r *v1.Route) (*traffi
 	}
 
 	r.Status.MarkTrafficAssigned()
+	markRollbacks(ctx, r, t.Rollbacks)
 
 	return t, nil
 }
 
+// markRollbacks reflects the configuration targets rolled back to their
+// previous ready Revision in the Route status, emitting an event when they
+// change.
+func markRollbacks(ctx context.Context, r *v1.Route, rollbacks []traffic.Rollback) {
+	if len(rollbacks) == 0 {
+		r.Status.ClearRolloutRolledBack()
+		return
+	}
+	msgs := make([]string, 0, len(rollbacks))
+	for _, rb := range rollbacks {
+		msgs = append(msgs, fmt.Sprintf("Configuration %q rolled back from failed Revision %q to Revision %q.",
+			rb.ConfigurationName, rb.FailedRevision, rb.RevisionName))
+	}
+	msg := strings.Join(msgs, " ")
+	if c := r.Status.GetCondition(v1.RouteConditionRolloutRolledBack); c == nil || c.Message != msg {
+		controller.GetEventRecorder(ctx).Event(r, corev1.EventTypeWarning, "RolloutRolledBack", msg)
+	}
+	r.Status.MarkRolloutRolledBack(msg)
+}
+
 func (c *Reconciler) updateRouteStatusURL(ctx context.Context, route *v1.Route, visibility map[string]netv1alpha1.IngressVisibility) error {
 	isClusterLocal := visibility[traffic.DefaultTarget] == netv1alpha1.IngressVisibilityClusterLocal
 
@@ -27,6 +27,7 @@ import (
 	fakenetworkingclient "knative.dev/networking/pkg/client/injection/client/fake"
 	_ "knative.dev/networking/pkg/client/injection/informers/networking/v1alpha1/certificate/fake"
 	fakeingressinformer "knative.dev/networking/pkg/client/injection/informers/networking/v1alpha1/ingress/fake"
+	_ "knative.dev/pkg/client/injection/kube/informers/core/v1/namespace/fake"
 	_ "knative.dev/pkg/client/injection/kube/informers/core/v1/service/fake"
 	fakeservingclient "knative.dev/serving/pkg/client/injection/client/fake"
 	fakecfginformer "knative.dev/serving/pkg/client/injection/informers/serving/v1/configuration/fake"
@@ -42,6 +43,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 
 	network "knative.dev/networking/pkg"
@@ -59,6 +61,7 @@ import (
 	"knative.dev/serving/pkg/gc"
 	"knative.dev/serving/pkg/reconciler/route/config"
 	"knative.dev/serving/pkg/reconciler/route/domains"
+	"knative.dev/serving/pkg/reconciler/route/traffic"
 
 	_ "knative.dev/pkg/metrics/testing"
 	. "knative.dev/pkg/reconciler/testing"
@@ -1650,3 +1653,182 @@ func TestAutoTLSEnabled(t *testing.T) {
 		})
 	}
 }
+
+func TestPropagateIngressStatus(t *testing.T) {
+	ready := &v1alpha1.Ingress{}
+	ready.Status.InitializeConditions()
+	ready.Status.MarkNetworkConfigured()
+	ready.Status.MarkLoadBalancerReady(nil, nil)
+
+	notReady := &v1alpha1.Ingress{}
+	notReady.Status.InitializeConditions()
+	notReady.Status.MarkIngressNotReady("Pending", "still probing")
+
+	stale := ready.DeepCopy()
+	stale.Generation = 2
+
+	tests := []struct {
+		name       string
+		ingresses  []*v1alpha1.Ingress
+		wantStatus corev1.ConditionStatus
+		wantReason string
+	}{{
+		name:       "all shards ready",
+		ingresses:  []*v1alpha1.Ingress{ready, ready},
+		wantStatus: corev1.ConditionTrue,
+	}, {
+		name:       "a shard not ready",
+		ingresses:  []*v1alpha1.Ingress{ready, notReady},
+		wantStatus: corev1.ConditionUnknown,
+		wantReason: "Pending",
+	}, {
+		name:       "a shard not reconciled",
+		ingresses:  []*v1alpha1.Ingress{ready, stale},
+		wantStatus: corev1.ConditionUnknown,
+		wantReason: "IngressNotConfigured",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := Route(testNamespace, "test-route")
+			r.Status.InitializeConditions()
+			propagateIngressStatus(r, test.ingresses)
+			cond := r.Status.GetCondition(v1.RouteConditionIngressReady)
+			if cond.Status != test.wantStatus || cond.Reason != test.wantReason {
+				t.Errorf("IngressReady = %s/%s, want: %s/%s", cond.Status, cond.Reason, test.wantStatus, test.wantReason)
+			}
+		})
+	}
+}
+
+func TestMarkRollbacks(t *testing.T) {
+	rollbacks := []traffic.Rollback{{
+		ConfigurationName: "config",
+		FailedRevision:    "config-00002",
+		RevisionName:      "config-00001",
+	}}
+	recorder := record.NewFakeRecorder(10)
+	ctx := controller.WithEventRecorder(context.Background(), recorder)
+
+	r := Route(testNamespace, "test-route")
+	r.Status.InitializeConditions()
+	markRollbacks(ctx, r, rollbacks)
+	cond := r.Status.GetCondition(v1.RouteConditionRolloutRolledBack)
+	if cond == nil || cond.Status != corev1.ConditionTrue {
+		t.Fatalf("RolloutRolledBack = %v, want True", cond)
+	}
+	want := `Warning RolloutRolledBack Configuration "config" rolled back from failed Revision "config-00002" to Revision "config-00001".`
+	if got := <-recorder.Events; got != want {
+		t.Errorf("Event = %q, want: %q", got, want)
+	}
+
+	// The event is only emitted when the rollbacks change.
+	markRollbacks(ctx, r, rollbacks)
+	if len(recorder.Events) != 0 {
+		t.Errorf("Got unexpected event: %s", <-recorder.Events)
+	}
+
+	markRollbacks(ctx, r, nil)
+	if cond := r.Status.GetCondition(v1.RouteConditionRolloutRolledBack); cond != nil {
+		t.Errorf("RolloutRolledBack = %v, want: nil", cond)
+	}
+}
+
+func TestClaimCustomHosts(t *testing.T) {
+	now := time.Unix(1e9, 0)
+	claims := func(c string) RouteOption {
+		return WithRouteStatusAnnotation(map[string]string{serving.HostsClaimedAnnotationKey: c})
+	}
+	hosts := func(h string) RouteOption {
+		return WithRouteAnnotation(map[string]string{serving.HostsAnnotationKey: h})
+	}
+
+	tests := []struct {
+		name       string
+		route      *v1.Route
+		others     []*v1.Route
+		wantHost   string
+		wantOwner  string
+		wantClaims string
+	}{{
+		name:  "no custom hosts",
+		route: Route(testNamespace, "route"),
+	}, {
+		name:       "unclaimed host",
+		route:      Route(testNamespace, "route", hosts("www.example.org")),
+		wantClaims: `{"www.example.org":"2001-09-09T01:46:40Z"}`,
+	}, {
+		name:  "host requested but not claimed by another route",
+		route: Route(testNamespace, "route", hosts("www.example.org")),
+		others: []*v1.Route{
+			Route("other", "route", hosts("www.example.org"), WithRouteCreationTimestamp(now.Add(-time.Hour))),
+		},
+		wantClaims: `{"www.example.org":"2001-09-09T01:46:40Z"}`,
+	}, {
+		name: "host claimed first by a newer route",
+		route: Route(testNamespace, "route", hosts("example.org,www.example.org"),
+			WithRouteCreationTimestamp(now.Add(-time.Hour))),
+		others: []*v1.Route{
+			Route("other", "route", hosts("www.example.org"), WithRouteCreationTimestamp(now),
+				claims(`{"www.example.org":"2001-09-09T00:00:00Z"}`)),
+		},
+		wantHost:   "www.example.org",
+		wantOwner:  "other/route",
+		wantClaims: `{"example.org":"2001-09-09T01:46:40Z"}`,
+	}, {
+		name:  "host claimed first by the route",
+		route: Route(testNamespace, "route", hosts("www.example.org"), claims(`{"www.example.org":"2001-09-09T00:00:00Z"}`)),
+		others: []*v1.Route{
+			Route("other", "route", hosts("www.example.org"), claims(`{"www.example.org":"2001-09-09T01:00:00Z"}`)),
+		},
+		wantClaims: `{"www.example.org":"2001-09-09T00:00:00Z"}`,
+	}, {
+		name:  "simultaneous claims",
+		route: Route("other", "route", hosts("www.example.org"), claims(`{"www.example.org":"2001-09-09T00:00:00Z"}`)),
+		others: []*v1.Route{
+			Route("another", "route", hosts("www.example.org"), claims(`{"www.example.org":"2001-09-09T00:00:00Z"}`)),
+			Route(testNamespace, "route", hosts("www.example.org"), claims(`{"www.example.org":"2001-09-09T00:00:00Z"}`)),
+		},
+		wantHost:  "www.example.org",
+		wantOwner: "another/route",
+	}, {
+		name:  "host no longer requested",
+		route: Route(testNamespace, "route", hosts("example.org"), claims(`{"www.example.org":"2001-09-09T00:00:00Z"}`)),
+		others: []*v1.Route{
+			Route("other", "route", hosts("www.example.org")),
+		},
+		wantClaims: `{"example.org":"2001-09-09T01:46:40Z"}`,
+	}}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{hostsIndex: indexHosts})
+			indexer.Add(tc.route)
+			for _, other := range tc.others {
+				indexer.Add(other)
+			}
+			c := &Reconciler{
+				routeIndexer: indexer,
+				clock:        FakeClock{Time: now},
+			}
+
+			host, owner, err := c.claimCustomHosts(tc.route)
+			if err != nil {
+				t.Fatal("claimCustomHosts() =", err)
+			}
+			if host != tc.wantHost {
+				t.Errorf("host = %q, want: %q", host, tc.wantHost)
+			}
+			var gotOwner string
+			if owner != nil {
+				gotOwner = owner.Namespace + "/" + owner.Name
+			}
+			if gotOwner != tc.wantOwner {
+				t.Errorf("owner = %q, want: %q", gotOwner, tc.wantOwner)
+			}
+			if got := tc.route.Status.Annotations[serving.HostsClaimedAnnotationKey]; got != tc.wantClaims {
+				t.Errorf("claims = %s, want: %s", got, tc.wantClaims)
+			}
+		})
+	}
+}
@@ -24,9 +24,11 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 
 	network "knative.dev/networking/pkg"
 	"knative.dev/networking/pkg/apis/networking"
@@ -528,6 +530,149 @@ func TestReconcile(t *testing.T) {
 				WithExternalName(pkgnet.GetServiceHostname("private-istio-ingressgateway", "istio-system"))),
 		},
 		Key: "default/steady-state",
+	}, {
+		Name: "custom host claimed by another route",
+		Objects: []runtime.Object{
+			Route("default", "custom-host-claimed", WithConfigTarget("config"),
+				WithURL, WithAddress, WithRouteConditionsAutoTLSDisabled,
+				MarkTrafficAssigned, MarkIngressReady, WithRouteGeneration(1), WithRouteObservedGeneration,
+				WithRouteFinalizer, WithRouteAnnotation(map[string]string{
+					serving.HostsAnnotationKey: "www.example.org",
+				}), WithRouteCreationTimestamp(fakeCurTime.Add(-time.Hour)), WithStatusTraffic(
+					v1.TrafficTarget{
+						RevisionName:   "config-00001",
+						Percent:        ptr.Int64(100),
+						LatestRevision: ptr.Bool(true),
+					})),
+			// The newer route claimed the host first, and keeps it.
+			Route("other", "owner", WithConfigTarget("config"), WithRouteAnnotation(map[string]string{
+				serving.HostsAnnotationKey: "example.org,www.example.org",
+			}), WithRouteCreationTimestamp(fakeCurTime), WithRouteStatusAnnotation(map[string]string{
+				serving.HostsClaimedAnnotationKey: `{"example.org":"2001-09-09T00:00:00Z","www.example.org":"2001-09-09T00:00:00Z"}`,
+			})),
+			cfg("default", "config",
+				WithConfigGeneration(1), WithLatestCreated("config-00001"), WithLatestReady("config-00001"),
+				WithConfigLabel("serving.knative.dev/route", "custom-host-claimed"),
+			),
+			rev("default", "config", 1, MarkRevisionReady, WithRevName("config-00001")),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: Route("default", "custom-host-claimed", WithConfigTarget("config"),
+				WithURL, WithAddress, WithRouteConditionsAutoTLSDisabled,
+				MarkTrafficAssigned, MarkIngressReady, WithRouteGeneration(1), WithRouteObservedGeneration,
+				WithRouteFinalizer, WithRouteAnnotation(map[string]string{
+					serving.HostsAnnotationKey: "www.example.org",
+				}), WithRouteCreationTimestamp(fakeCurTime.Add(-time.Hour)), WithStatusTraffic(
+					v1.TrafficTarget{
+						RevisionName:   "config-00001",
+						Percent:        ptr.Int64(100),
+						LatestRevision: ptr.Bool(true),
+					}), WithCustomHostClaimed("www.example.org", "other/owner")),
+		}},
+		Key: "default/custom-host-claimed",
+	}, {
+		Name: "custom host not allowed",
+		Objects: []runtime.Object{
+			Route("default", "custom-host-not-allowed", WithConfigTarget("config"),
+				WithURL, WithAddress, WithRouteConditionsAutoTLSDisabled,
+				MarkTrafficAssigned, MarkIngressReady, WithRouteGeneration(1), WithRouteObservedGeneration,
+				WithRouteFinalizer, WithRouteAnnotation(map[string]string{
+					serving.HostsAnnotationKey: "www.example.net",
+				}), WithRouteCreationTimestamp(fakeCurTime), WithStatusTraffic(
+					v1.TrafficTarget{
+						RevisionName:   "config-00001",
+						Percent:        ptr.Int64(100),
+						LatestRevision: ptr.Bool(true),
+					})),
+			cfg("default", "config",
+				WithConfigGeneration(1), WithLatestCreated("config-00001"), WithLatestReady("config-00001"),
+				WithConfigLabel("serving.knative.dev/route", "custom-host-not-allowed"),
+			),
+			rev("default", "config", 1, MarkRevisionReady, WithRevName("config-00001")),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: Route("default", "custom-host-not-allowed", WithConfigTarget("config"),
+				WithURL, WithAddress, WithRouteConditionsAutoTLSDisabled,
+				MarkTrafficAssigned, MarkIngressReady, WithRouteGeneration(1), WithRouteObservedGeneration,
+				WithRouteFinalizer, WithRouteAnnotation(map[string]string{
+					serving.HostsAnnotationKey: "www.example.net",
+				}), WithRouteCreationTimestamp(fakeCurTime), WithStatusTraffic(
+					v1.TrafficTarget{
+						RevisionName:   "config-00001",
+						Percent:        ptr.Int64(100),
+						LatestRevision: ptr.Bool(true),
+					}), WithCustomHostNotAllowed("www.example.net")),
+		}},
+		Key: "default/custom-host-not-allowed",
+	}, {
+		Name: "deletes stale ingress shard",
+		Objects: []runtime.Object{
+			Route("default", "steady-state", WithConfigTarget("config"),
+				WithURL, WithAddress, WithRouteConditionsAutoTLSDisabled,
+				MarkTrafficAssigned, MarkIngressReady, WithRouteGeneration(1), WithRouteObservedGeneration,
+				WithRouteFinalizer, WithStatusTraffic(
+					v1.TrafficTarget{
+						RevisionName:   "config-00001",
+						Percent:        ptr.Int64(100),
+						LatestRevision: ptr.Bool(true),
+					})),
+			cfg("default", "config",
+				WithConfigGeneration(1), WithLatestCreated("config-00001"), WithLatestReady("config-00001"),
+				// The Route controller attaches our label to this Configuration.
+				WithConfigLabel("serving.knative.dev/route", "steady-state"),
+			),
+			rev("default", "config", 1, MarkRevisionReady, WithRevName("config-00001")),
+			simpleReadyIngress(
+				Route("default", "steady-state", WithConfigTarget("config"), WithURL),
+				&traffic.Config{
+					Targets: map[string]traffic.RevisionTargets{
+						traffic.DefaultTarget: {{
+							TrafficTarget: v1.TrafficTarget{
+								ConfigurationName: "config",
+								LatestRevision:    ptr.Bool(true),
+								RevisionName:      "config-00001",
+								Percent:           ptr.Int64(100),
+							},
+							Active: true,
+						}},
+					},
+				},
+			),
+			// Left over from the times the Route had more tags.
+			simpleReadyIngress(
+				Route("default", "steady-state", WithConfigTarget("config"), WithURL),
+				&traffic.Config{
+					Targets: map[string]traffic.RevisionTargets{
+						traffic.DefaultTarget: {{
+							TrafficTarget: v1.TrafficTarget{
+								ConfigurationName: "config",
+								LatestRevision:    ptr.Bool(true),
+								RevisionName:      "config-00001",
+								Percent:           ptr.Int64(100),
+							},
+							Active: true,
+						}},
+					},
+				},
+				func(ing *netv1alpha1.Ingress) {
+					ing.Name = "steady-state-shard-1"
+				},
+			),
+			simpleK8sService(Route("default", "steady-state", WithConfigTarget("config")),
+				WithExternalName(pkgnet.GetServiceHostname("private-istio-ingressgateway", "istio-system"))),
+		},
+		WantDeletes: []clientgotesting.DeleteActionImpl{{
+			ActionImpl: clientgotesting.ActionImpl{
+				Namespace: "default",
+				Verb:      "delete",
+				Resource:  netv1alpha1.SchemeGroupVersion.WithResource("ingresses"),
+			},
+			Name: "steady-state-shard-1",
+		}},
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, "Deleted", "Deleted Ingress %q", "steady-state-shard-1"),
+		},
+		Key: "default/steady-state",
 	}, {
 		Name:    "unhappy about ownership of placeholder service",
 		WantErr: true,
@@ -1735,8 +1880,10 @@ func TestReconcile(t *testing.T) {
 			netclient:           networkingclient.Get(ctx),
 			configurationLister: listers.GetConfigurationLister(),
 			revisionLister:      listers.GetRevisionLister(),
+			routeIndexer:        hostIndexer(listers),
 			serviceLister:       listers.GetK8sServiceLister(),
 			ingressLister:       listers.GetIngressLister(),
+			namespaceLister:     listers.GetNamespaceLister(),
 			tracker:             ctx.Value(TrackerKey).(tracker.Interface),
 			clock:               FakeClock{Time: fakeCurTime},
 		}
@@ -1843,8 +1990,10 @@ func TestReconcileResponsiveGC(t *testing.T) {
 			netclient:           networkingclient.Get(ctx),
 			configurationLister: listers.GetConfigurationLister(),
 			revisionLister:      listers.GetRevisionLister(),
+			routeIndexer:        hostIndexer(listers),
 			serviceLister:       listers.GetK8sServiceLister(),
 			ingressLister:       listers.GetIngressLister(),
+			namespaceLister:     listers.GetNamespaceLister(),
 			tracker:             ctx.Value(TrackerKey).(tracker.Interface),
 			clock:               FakeClock{Time: fakeCurTime},
 		}
@@ -2406,8 +2555,10 @@ func TestReconcileEnableAutoTLS(t *testing.T) {
 			netclient:           networkingclient.Get(ctx),
 			configurationLister: listers.GetConfigurationLister(),
 			revisionLister:      listers.GetRevisionLister(),
+			routeIndexer:        hostIndexer(listers),
 			serviceLister:       listers.GetK8sServiceLister(),
 			ingressLister:       listers.GetIngressLister(),
+			namespaceLister:     listers.GetNamespaceLister(),
 			certificateLister:   listers.GetCertificateLister(),
 			tracker:             &NullTracker{},
 			clock:               FakeClock{Time: fakeCurTime},
@@ -2527,8 +2678,10 @@ func TestReconcileEnableAutoTLSHTTPDisabled(t *testing.T) {
 			netclient:           networkingclient.Get(ctx),
 			configurationLister: listers.GetConfigurationLister(),
 			revisionLister:      listers.GetRevisionLister(),
+			routeIndexer:        hostIndexer(listers),
 			serviceLister:       listers.GetK8sServiceLister(),
 			ingressLister:       listers.GetIngressLister(),
+			namespaceLister:     listers.GetNamespaceLister(),
 			certificateLister:   listers.GetCertificateLister(),
 			tracker:             &NullTracker{},
 			clock:               FakeClock{Time: fakeCurTime},
@@ -2540,6 +2693,17 @@ func TestReconcileEnableAutoTLSHTTPDisabled(t *testing.T) {
 	}))
 }
 
+// hostIndexer returns the indexer of the Routes by custom host, as set up by
+// the controller.
+func hostIndexer(listers *Listers) cache.Indexer {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{hostsIndex: indexHosts})
+	routes, _ := listers.GetRouteLister().List(labels.Everything())
+	for _, r := range routes {
+		indexer.Add(r)
+	}
+	return indexer
+}
+
 func cfg(namespace, name string, co ...ConfigOption) *v1.Configuration {
 	cfg := &v1.Configuration{
 		ObjectMeta: metav1.ObjectMeta{
@@ -2718,6 +2882,7 @@ func ReconcilerTestConfig(enableAutoTLS bool) *config.Config {
 					Selector: map[string]string{"app": "prod"},
 				},
 			},
+			CustomDomainsAllowlist: []string{"example.org"},
 		},
 		Network: &network.Config{
 			DefaultIngressClass:     TestIngressClass,
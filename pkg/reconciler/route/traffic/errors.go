@@ -108,6 +108,28 @@ func (e *unreadyRevisionError) IsFailure() bool {
 	return e.isFailure
 }
 
+type missingPortError struct {
+	revision string // Name of the revision missing the port.
+	port     string // Name of the port.
+}
+
+var _ TargetError = (*missingPortError)(nil)
+
+// Error implements error.
+func (e *missingPortError) Error() string {
+	return fmt.Sprintf("Revision %q does not declare the port %q", e.revision, e.port)
+}
+
+// MarkBadTrafficTarget implements TargetError.
+func (e *missingPortError) MarkBadTrafficTarget(rs *v1.RouteStatus) {
+	rs.MarkMissingPort(e.revision, e.port)
+}
+
+// IsFailure implements TargetError.
+func (e *missingPortError) IsFailure() bool {
+	return true
+}
+
 // errUnreadyConfiguration returns a TargetError for a Configuration that is not ready.
 func errUnreadyConfiguration(config *v1.Configuration) TargetError {
 	status := corev1.ConditionUnknown
@@ -147,3 +169,12 @@ func errMissingRevision(name string) TargetError {
 		name: name,
 	}
 }
+
+// errMissingPort returns a TargetError for a Revision that does not declare
+// the port referenced in traffic.
+func errMissingPort(rev *v1.Revision, port string) TargetError {
+	return &missingPortError{
+		revision: rev.Name,
+		port:     port,
+	}
+}
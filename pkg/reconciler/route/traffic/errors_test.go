@@ -21,6 +21,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"knative.dev/pkg/apis"
 	v1 "knative.dev/serving/pkg/apis/serving/v1"
 	. "knative.dev/serving/pkg/testing/v1"
@@ -177,3 +178,25 @@ func TestMarkBadTrafficTarget_RevisionNotYetReady(t *testing.T) {
 		}
 	}
 }
+
+func TestMarkBadTrafficTarget_MissingPort(t *testing.T) {
+	err := errMissingPort(&v1.Revision{ObjectMeta: metav1.ObjectMeta{Name: "rev"}}, "admin")
+	if !err.IsFailure() {
+		t.Error("IsFailure() = false, want: true")
+	}
+	r := testRouteWithTrafficTargets(WithSpecTraffic(v1.TrafficTarget{}))
+
+	err.MarkBadTrafficTarget(&r.Status)
+	got := r.Status.GetCondition(v1.RouteConditionAllTrafficAssigned)
+	want := &apis.Condition{
+		Type:               v1.RouteConditionAllTrafficAssigned,
+		Status:             corev1.ConditionFalse,
+		Reason:             "PortMissing",
+		Message:            `Revision "rev" does not declare the port "admin" referenced in traffic.`,
+		LastTransitionTime: got.LastTransitionTime,
+		Severity:           apis.ConditionSeverityError,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("Unexpected condition diff (-want +got):", diff)
+	}
+}
@@ -19,9 +19,11 @@ package traffic
 import (
 	"context"
 	"sort"
+	"strconv"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	k8slabels "k8s.io/apimachinery/pkg/labels"
 
 	net "knative.dev/networking/pkg/apis/networking"
 	netv1alpha1 "knative.dev/networking/pkg/apis/networking/v1alpha1"
@@ -57,6 +59,12 @@ type Config struct {
 	// realize a route's setting.
 	Targets map[string]RevisionTargets
 
+	// MatchTargets are the targets matching the requests on their headers
+	// or path prefix, the longest path prefix first, then in the order of
+	// the Route's traffic. Each receives all the matching requests, ahead
+	// of the percentage split of the default target.
+	MatchTargets RevisionTargets
+
 	// Visibility of the traffic targets.
 	Visibility map[string]netv1alpha1.IngressVisibility
 
@@ -71,6 +79,18 @@ type Config struct {
 	// MissingTargets are references to Configurations or Revisions
 	// that are missing
 	MissingTargets []corev1.ObjectReference
+
+	// Rollbacks are the configuration targets routed to the previous ready
+	// Revision of their Configuration, as its latest ready one failed.
+	Rollbacks []Rollback
+}
+
+// Rollback describes a configuration target routed to the previous ready
+// Revision of the Configuration instead of its failed latest ready one.
+type Rollback struct {
+	ConfigurationName string
+	FailedRevision    string
+	RevisionName      string
 }
 
 // BuildTrafficConfiguration consolidates and flattens the Route.Spec.Traffic to the Revision-level. It also provides a
@@ -104,6 +124,10 @@ func (cfg *Config) GetRevisionTrafficTargets(ctx context.Context, r *v1.Route) (
 			RevisionName:   tt.RevisionName,
 			Percent:        pp,
 			LatestRevision: tt.LatestRevision,
+			Port:           tt.Port,
+			Headers:        tt.Headers,
+			PathPrefix:     tt.PathPrefix,
+			Mirror:         tt.Mirror,
 		}
 		if tt.Tag != "" {
 			meta := r.ObjectMeta.DeepCopy()
@@ -134,6 +158,10 @@ type configBuilder struct {
 	// targets is a grouping of traffic targets serving the same origin.
 	targets map[string]RevisionTargets
 
+	// matchTargets are the targets matching the requests on their headers
+	// or path prefix.
+	matchTargets RevisionTargets
+
 	// revisionTargets is the original list of targets, at the Revision level.
 	revisionTargets RevisionTargets
 
@@ -146,6 +174,10 @@ type configBuilder struct {
 	// in our listers
 	missingTargets []corev1.ObjectReference
 
+	// rollbacks are the configuration targets rolled back, once for each
+	// Configuration.
+	rollbacks []Rollback
+
 	// TargetError are deferred until we got a complete list of all referred targets.
 	deferredTargetErr TargetError
 }
@@ -303,6 +335,23 @@ func (cb *configBuilder) addConfigurationTarget(tt *v1.TrafficTarget) error {
 	if err != nil {
 		return err
 	}
+	if cb.route.RollbackOnFailure() && rev.IsFailed() {
+		prev, err := cb.previousReadyRevision(config, rev)
+		if err != nil {
+			return err
+		}
+		if prev != nil {
+			cb.addRollback(Rollback{
+				ConfigurationName: config.Name,
+				FailedRevision:    rev.Name,
+				RevisionName:      prev.Name,
+			})
+			rev = prev
+		}
+	}
+	if !hasPort(rev, tt.Port) {
+		return errMissingPort(rev, tt.Port)
+	}
 	ntt := tt.DeepCopy()
 	target := RevisionTarget{
 		TrafficTarget: *ntt,
@@ -315,6 +364,48 @@ func (cb *configBuilder) addConfigurationTarget(tt *v1.TrafficTarget) error {
 	return nil
 }
 
+// previousReadyRevision returns the ready Revision of the Configuration of the
+// latest generation before the one of the failed Revision, if any.
+func (cb *configBuilder) previousReadyRevision(config *v1.Configuration, failed *v1.Revision) (*v1.Revision, error) {
+	failedGen, err := strconv.Atoi(failed.Labels[serving.ConfigurationGenerationLabelKey])
+	if err != nil {
+		return nil, nil
+	}
+	revs, err := cb.revLister.List(k8slabels.SelectorFromSet(k8slabels.Set{
+		serving.ConfigurationLabelKey: config.Name,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	var (
+		prev    *v1.Revision
+		prevGen int
+	)
+	for _, rev := range revs {
+		gen, err := strconv.Atoi(rev.Labels[serving.ConfigurationGenerationLabelKey])
+		if err != nil || gen >= failedGen || gen <= prevGen || !rev.IsReady() {
+			continue
+		}
+		prev, prevGen = rev, gen
+	}
+	if prev != nil {
+		// The route is reconciled again once the previous Revision changes.
+		cb.revisions[prev.Name] = prev
+	}
+	return prev, nil
+}
+
+// addRollback records the rollback of the configuration target, unless the
+// Configuration is already rolled back for another target.
+func (cb *configBuilder) addRollback(rb Rollback) {
+	for _, r := range cb.rollbacks {
+		if r.ConfigurationName == rb.ConfigurationName {
+			return
+		}
+	}
+	cb.rollbacks = append(cb.rollbacks, rb)
+}
+
 func (cb *configBuilder) addRevisionTarget(tt *v1.TrafficTarget) error {
 	rev, err := cb.getRevision(tt.RevisionName)
 	if err != nil {
@@ -323,6 +414,9 @@ func (cb *configBuilder) addRevisionTarget(tt *v1.TrafficTarget) error {
 	if !rev.IsReady() {
 		return errUnreadyRevision(rev)
 	}
+	if !hasPort(rev, tt.Port) {
+		return errMissingPort(rev, tt.Port)
+	}
 	ntt := tt.DeepCopy()
 	target := RevisionTarget{
 		TrafficTarget: *ntt,
@@ -340,6 +434,20 @@ func (cb *configBuilder) addRevisionTarget(tt *v1.TrafficTarget) error {
 	return nil
 }
 
+// hasPort returns true if the port is empty, i.e. the serving port,
+// or the revision's container declares a port of that name.
+func hasPort(rev *v1.Revision, port string) bool {
+	if port == "" {
+		return true
+	}
+	for _, p := range rev.Spec.GetContainer().Ports {
+		if p.Name == port {
+			return true
+		}
+	}
+	return false
+}
+
 // valIfNil returns `val` if `ptr==nil`, or `*ptr` otherwise.
 func valIfNil(val int64, ptr *int64) int64 {
 	if ptr == nil {
@@ -353,7 +461,8 @@ func valIfNil(val int64, ptr *int64) int64 {
 func mergeIfNecessary(rts RevisionTargets, rt RevisionTarget) RevisionTargets {
 	for i := range rts {
 		if rts[i].Tag == rt.Tag && rts[i].RevisionName == rt.RevisionName &&
-			*rt.LatestRevision == *rts[i].LatestRevision {
+			*rt.LatestRevision == *rts[i].LatestRevision && sameHeaders(rts[i].Headers, rt.Headers) &&
+			rts[i].PathPrefix == rt.PathPrefix && sameMirror(rts[i].Mirror, rt.Mirror) {
 			rts[i].Percent = ptr.Int64(valIfNil(0, rts[i].Percent) + valIfNil(0, rt.Percent))
 			return rts
 		}
@@ -361,30 +470,72 @@ func mergeIfNecessary(rts RevisionTargets, rt RevisionTarget) RevisionTargets {
 	return append(rts, rt)
 }
 
+// sameHeaders returns true if both targets match the requests on the same headers.
+func sameHeaders(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// sameMirror returns true if both targets mirror the same requests to the
+// same revision.
+func sameMirror(a, b *v1.TrafficMirror) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
 func (cb *configBuilder) addFlattenedTarget(target RevisionTarget) {
 	name := target.TrafficTarget.Tag
 	cb.revisionTargets = mergeIfNecessary(cb.revisionTargets, target)
-	cb.targets[DefaultTarget] = append(cb.targets[DefaultTarget], target)
+	// The port is only selected by the dedicated url of the target.
+	defaultTarget := target
+	defaultTarget.TrafficTarget.Port = ""
+	cb.targets[DefaultTarget] = append(cb.targets[DefaultTarget], defaultTarget)
 	if name != "" {
 		// This should always have just a single entry at most.
 		cb.targets[name] = append(cb.targets[name], target)
 	}
+	if len(target.Headers) > 0 || target.PathPrefix != "" {
+		matchTarget := defaultTarget
+		matchTarget.TrafficTarget.Percent = ptr.Int64(100)
+		cb.matchTargets = append(cb.matchTargets, matchTarget)
+	}
 }
 
 func (cb *configBuilder) build() (*Config, error) {
 	if cb.deferredTargetErr != nil {
 		cb.targets = nil
+		cb.matchTargets = nil
 		cb.revisionTargets = nil
 	}
 	return &Config{
 		Targets:         consolidateAll(cb.targets),
+		MatchTargets:    sortMatchTargets(cb.matchTargets),
 		revisionTargets: cb.revisionTargets,
 		Configurations:  cb.configurations,
 		Revisions:       cb.revisions,
 		MissingTargets:  cb.missingTargets,
+		Rollbacks:       cb.rollbacks,
 	}, cb.deferredTargetErr
 }
 
+// sortMatchTargets orders the targets from the longest path prefix to the
+// shortest, so that the most specific prefix matches first.
+func sortMatchTargets(targets RevisionTargets) RevisionTargets {
+	sort.SliceStable(targets, func(i, j int) bool {
+		return len(targets[i].PathPrefix) > len(targets[j].PathPrefix)
+	})
+	return targets
+}
+
 func consolidateAll(targets map[string]RevisionTargets) map[string]RevisionTargets {
 	consolidated := make(map[string]RevisionTargets, len(targets))
 	for name, tts := range targets {
@@ -81,6 +81,12 @@ var (
 	niceOldRev *v1.Revision
 	niceNewRev *v1.Revision
 
+	// brokenConfig has a good revision brokenOldRev, and its latest ready
+	// revision brokenNewRev failed afterwards.
+	brokenConfig *v1.Configuration
+	brokenOldRev *v1.Revision
+	brokenNewRev *v1.Revision
+
 	configLister listers.ConfigurationLister
 	revLister    listers.RevisionLister
 
@@ -98,6 +104,7 @@ func setUp() {
 	inactiveConfig, inactiveRev = getTestInactiveConfig("inactive")
 	goodConfig, goodOldRev, goodNewRev = getTestReadyConfig("good")
 	niceConfig, niceOldRev, niceNewRev = getTestReadyConfig("nice")
+	brokenConfig, brokenOldRev, brokenNewRev = getTestBrokenConfig("broken")
 	servingClient := fakeclientset.NewSimpleClientset()
 
 	servingInformer := informers.NewSharedInformerFactory(servingClient, 0)
@@ -117,6 +124,7 @@ func setUp() {
 		emptyConfig,
 		goodConfig, goodOldRev, goodNewRev,
 		niceConfig, niceOldRev, niceNewRev,
+		brokenConfig, brokenOldRev, brokenNewRev,
 	}
 
 	for _, obj := range objs {
@@ -1121,6 +1129,150 @@ func TestBuildTrafficConfigurationPreliminary(t *testing.T) {
 	}
 }
 
+func TestBuildTrafficConfigurationMatchTargets(t *testing.T) {
+	headers := map[string]string{"X-Canary": "true"}
+	route := testRouteWithTrafficTargets(WithSpecTraffic(v1.TrafficTarget{
+		ConfigurationName: goodConfig.Name,
+		Percent:           ptr.Int64(100),
+	}, v1.TrafficTarget{
+		RevisionName: niceNewRev.Name,
+		Percent:      ptr.Int64(0),
+		Headers:      headers,
+	}, v1.TrafficTarget{
+		RevisionName: niceOldRev.Name,
+		PathPrefix:   "/api",
+	}))
+	tc, err := BuildTrafficConfiguration(configLister, revLister, route)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	// The path prefixed targets match first.
+	want := RevisionTargets{{
+		TrafficTarget: v1.TrafficTarget{
+			ConfigurationName: niceConfig.Name,
+			RevisionName:      niceOldRev.Name,
+			LatestRevision:    ptr.Bool(false),
+			Percent:           ptr.Int64(100),
+			PathPrefix:        "/api",
+		},
+		Active:   true,
+		Protocol: net.ProtocolHTTP1,
+	}, {
+		TrafficTarget: v1.TrafficTarget{
+			ConfigurationName: niceConfig.Name,
+			RevisionName:      niceNewRev.Name,
+			LatestRevision:    ptr.Bool(false),
+			Percent:           ptr.Int64(100),
+			Headers:           headers,
+		},
+		Active:   true,
+		Protocol: net.ProtocolH2C,
+	}}
+	if got := tc.MatchTargets; !cmp.Equal(want, got) {
+		t.Errorf("MatchTargets (-want +got):\n%s", cmp.Diff(want, got))
+	}
+
+	// The status reflects the matches of the targets.
+	targets, err := tc.GetRevisionTrafficTargets(getContext(), route)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if got := targets[1].Headers; !cmp.Equal(headers, got) {
+		t.Errorf("Status headers (-want +got):\n%s", cmp.Diff(headers, got))
+	}
+	if got, want := targets[2].PathPrefix, "/api"; got != want {
+		t.Errorf("Status pathPrefix = %q, want: %q", got, want)
+	}
+}
+
+func TestBuildTrafficConfigurationMirror(t *testing.T) {
+	mirror := &v1.TrafficMirror{RevisionName: niceNewRev.Name, Percent: 10}
+	route := testRouteWithTrafficTargets(WithSpecTraffic(v1.TrafficTarget{
+		RevisionName: niceOldRev.Name,
+		Percent:      ptr.Int64(50),
+		Mirror:       mirror,
+	}, v1.TrafficTarget{
+		RevisionName: niceOldRev.Name,
+		Percent:      ptr.Int64(50),
+	}))
+	tc, err := BuildTrafficConfiguration(configLister, revLister, route)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	// The targets mirroring differently are not merged.
+	targets, err := tc.GetRevisionTrafficTargets(getContext(), route)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if got, want := len(targets), 2; got != want {
+		t.Fatalf("len(targets) = %d, want: %d", got, want)
+	}
+	if got := targets[0].Mirror; !cmp.Equal(mirror, got) {
+		t.Errorf("Status mirror (-want +got):\n%s", cmp.Diff(mirror, got))
+	}
+	if got := targets[1].Mirror; got != nil {
+		t.Errorf("Status mirror = %v, want: nil", got)
+	}
+}
+
+func TestBuildTrafficConfigurationRollback(t *testing.T) {
+	tts := v1.TrafficTarget{
+		ConfigurationName: brokenConfig.Name,
+		Percent:           ptr.Int64(100),
+	}
+	rt := RevisionTarget{
+		TrafficTarget: v1.TrafficTarget{
+			ConfigurationName: brokenConfig.Name,
+			RevisionName:      brokenOldRev.Name,
+			Percent:           ptr.Int64(100),
+			LatestRevision:    ptr.Bool(true),
+		},
+		Active:   true,
+		Protocol: net.ProtocolHTTP1,
+	}
+	expected := &Config{
+		Targets: map[string]RevisionTargets{
+			DefaultTarget: {rt},
+		},
+		revisionTargets: []RevisionTarget{rt},
+		Configurations: map[string]*v1.Configuration{
+			brokenConfig.Name: brokenConfig,
+		},
+		Revisions: map[string]*v1.Revision{
+			brokenOldRev.Name: brokenOldRev,
+			brokenNewRev.Name: brokenNewRev,
+		},
+		Rollbacks: []Rollback{{
+			ConfigurationName: brokenConfig.Name,
+			FailedRevision:    brokenNewRev.Name,
+			RevisionName:      brokenOldRev.Name,
+		}},
+	}
+	route := testRouteWithTrafficTargets(WithSpecTraffic(tts))
+	route.Annotations = map[string]string{serving.RollbackOnFailureAnnotationKey: "true"}
+	tc, err := BuildTrafficConfiguration(configLister, revLister, route)
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if got, want := tc, expected; !cmp.Equal(want, got, cmpOpts...) {
+		t.Errorf("Unexpected traffic diff (-want +got):\n%s", cmp.Diff(want, got, cmpOpts...))
+	}
+
+	// Without the annotation the failed Revision keeps being routed to.
+	tc, err = BuildTrafficConfiguration(configLister, revLister, testRouteWithTrafficTargets(WithSpecTraffic(tts)))
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+	if got, want := tc.Targets[DefaultTarget][0].RevisionName, brokenNewRev.Name; got != want {
+		t.Errorf("RevisionName = %q, want: %q", got, want)
+	}
+	if len(tc.Rollbacks) != 0 {
+		t.Errorf("Rollbacks = %v, want none", tc.Rollbacks)
+	}
+}
+
 func TestBuildTrafficConfigurationMissingConfig(t *testing.T) {
 	expected := &Config{
 		Targets: map[string]RevisionTargets{},
@@ -1441,6 +1593,14 @@ func getTestReadyConfig(name string) (*v1.Configuration, *v1.Revision, *v1.Revis
 	return config, rev1, rev2
 }
 
+func getTestBrokenConfig(name string) (*v1.Configuration, *v1.Revision, *v1.Revision) {
+	config, rev1, rev2 := getTestReadyConfig(name)
+	rev1.Labels[serving.ConfigurationGenerationLabelKey] = "1"
+	rev2.Labels[serving.ConfigurationGenerationLabelKey] = "2"
+	rev2.Status.MarkContainerHealthyFalse(v1.ReasonContainerMissing, "Image deleted")
+	return config, rev1, rev2
+}
+
 func TestMain(m *testing.M) {
 	setUp()
 	os.Exit(m.Run())
@@ -1472,3 +1632,39 @@ func testNetworkConfig() *config.Config {
 		},
 	}
 }
+
+func TestBuildTrafficConfigurationMissingPort(t *testing.T) {
+	expectedErr := errMissingPort(goodNewRev, "admin")
+	_, err := BuildTrafficConfiguration(configLister, revLister, testRouteWithTrafficTargets(WithSpecTraffic(v1.TrafficTarget{
+		RevisionName: goodNewRev.Name,
+		Percent:      ptr.Int64(100),
+	}, v1.TrafficTarget{
+		Tag:          "admin",
+		RevisionName: goodNewRev.Name,
+		Port:         "admin",
+	})))
+	if err == nil || err.Error() != expectedErr.Error() {
+		t.Errorf("Expected %v, saw %v", expectedErr, err)
+	}
+}
+
+func TestHasPort(t *testing.T) {
+	rev := testRevForConfig(goodConfig, "named-ports")
+	rev.Spec.Containers = []corev1.Container{{
+		Ports: []corev1.ContainerPort{{
+			ContainerPort: 8080,
+		}, {
+			Name:          "admin",
+			ContainerPort: 8181,
+		}},
+	}}
+	for port, want := range map[string]bool{
+		"":      true,
+		"admin": true,
+		"data":  false,
+	} {
+		if got := hasPort(rev, port); got != want {
+			t.Errorf("hasPort(%q) = %v, want: %v", port, got, want)
+		}
+	}
+}
@@ -64,7 +64,7 @@ func (b *Resolver) getServices(route *v1.Route) (map[string]*corev1.Service, err
 
 func (b *Resolver) routeVisibility(ctx context.Context, route *v1.Route) netv1alpha1.IngressVisibility {
 	domainConfig := config.FromContext(ctx).Domain
-	domain := domainConfig.LookupDomainForLabels(route.Labels)
+	domain := domainConfig.LookupDomainForNamespace(route.Labels, config.NamespaceLabelsFromContext(ctx))
 	if domain == "svc."+network.GetClusterDomainName() {
 		return netv1alpha1.IngressVisibilityClusterLocal
 	}
@@ -89,6 +89,8 @@ func (b *Resolver) GetVisibility(ctx context.Context, route *v1.Route) (map[stri
 	if err != nil {
 		return nil, err
 	}
+	// The annotation has been validated by the webhook.
+	tagVisibility, _ := serving.ParseTagVisibilityAnnotation(route.Annotations)
 	trafficNames := trafficNames(route)
 	m := make(map[string]netv1alpha1.IngressVisibility, trafficNames.Len())
 	for tt := range trafficNames {
@@ -103,6 +105,10 @@ func (b *Resolver) GetVisibility(ctx context.Context, route *v1.Route) (map[stri
 				ttVisibility = netv1alpha1.IngressVisibilityClusterLocal
 			}
 		}
+		// Or on the Route for the tag?
+		if tt != traffic.DefaultTarget && tagVisibility[tt] == serving.VisibilityClusterLocal {
+			ttVisibility = netv1alpha1.IngressVisibilityClusterLocal
+		}
 
 		// Now, choose the lowest visibility.
 		m[tt] = minVisibility(ttVisibility, defaultVisibility)
@@ -142,6 +142,28 @@ func TestVisibility(t *testing.T) {
 			traffic.DefaultTarget: netv1alpha1.IngressVisibilityExternalIP,
 			"blue":                netv1alpha1.IngressVisibilityClusterLocal,
 		},
+	}, {
+		name: "two tags, tag marked local on route",
+		route: &v1.Route{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "foo",
+				Annotations: map[string]string{
+					serving.TagVisibilityAnnotationKey: `{"blue": "cluster-local"}`,
+				},
+			},
+			Spec: v1.RouteSpec{
+				Traffic: []v1.TrafficTarget{{
+					Tag: "blue",
+				}, {
+					Tag: "green",
+				}},
+			},
+		},
+		expected: map[string]netv1alpha1.IngressVisibility{
+			traffic.DefaultTarget: netv1alpha1.IngressVisibilityExternalIP,
+			"blue":                netv1alpha1.IngressVisibilityClusterLocal,
+			"green":               netv1alpha1.IngressVisibilityExternalIP,
+		},
 	}, {
 		name: "one tag initial default",
 		route: &v1.Route{
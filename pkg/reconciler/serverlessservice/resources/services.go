@@ -133,6 +133,12 @@ func MakePrivateService(sks *v1alpha1.ServerlessService, selector map[string]str
 				// This one is matching the public one, since this is the
 				// port queue-proxy listens on.
 				TargetPort: targetPort(sks),
+			}, {
+				// The port queue-proxy serves TLS on, when the backend TLS is enabled.
+				Name:       networking.BackendHTTPSPortName,
+				Protocol:   corev1.ProtocolTCP,
+				Port:       networking.BackendHTTPSPort,
+				TargetPort: intstr.FromInt(networking.BackendHTTPSPort),
 			}, {
 				Name:       servingv1.AutoscalingQueueMetricsPortName,
 				Protocol:   corev1.ProtocolTCP,
@@ -130,6 +130,11 @@ func privateSvcMod(s *corev1.Service) {
 	}
 	s.Spec.Ports = append(s.Spec.Ports,
 		[]corev1.ServicePort{{
+			Name:       networking.BackendHTTPSPortName,
+			Protocol:   corev1.ProtocolTCP,
+			Port:       networking.BackendHTTPSPort,
+			TargetPort: intstr.FromInt(networking.BackendHTTPSPort),
+		}, {
 			Name:       servingv1.AutoscalingQueueMetricsPortName,
 			Protocol:   corev1.ProtocolTCP,
 			Port:       networking.AutoscalingQueueMetricsPort,
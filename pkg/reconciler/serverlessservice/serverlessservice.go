@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/google/go-cmp/cmp"
@@ -122,16 +123,15 @@ func (r *reconciler) reconcilePublicService(ctx context.Context, sks *netv1alpha
 	return nil
 }
 
-// subsetEndpoints computes a subset of all endpoints of size `n` using a consistent
-// selection algorithm. For non empty input, subsetEndpoints returns a copy of the
-// input with the irrelevant endpoints and empty subsets filtered out, if the input
-// size is larger than `n`,
-// Otherwise the input is returned as is.
-// `target` is the revision name for which we are computing a subset.
-func subsetEndpoints(eps *corev1.Endpoints, target string, n int) *corev1.Endpoints {
+// activatorSubset computes the subset of the activators of size `n`, that
+// are assigned to back the revision `target`, using a consistent selection
+// algorithm. activatorSubset returns nil, if all the activators are
+// assigned to the revision, i.e. if `n` is 0 or not smaller than the number
+// of the activators.
+func activatorSubset(eps *corev1.Endpoints, target string, n int) sets.String {
 	// n == 0 means all, and if there are no subsets there's no work to do either.
 	if len(eps.Subsets) == 0 || n == 0 {
-		return eps
+		return nil
 	}
 
 	addrs := make(sets.String, len(eps.Subsets[0].Addresses))
@@ -143,10 +143,27 @@ func subsetEndpoints(eps *corev1.Endpoints, target string, n int) *corev1.Endpoi
 
 	// The input is not larger than desired.
 	if len(addrs) <= n {
-		return eps
+		return nil
 	}
+	return hash.ChooseSubset(addrs, n, target)
+}
+
+// subsetEndpoints computes a subset of all endpoints of size `n` using a consistent
+// selection algorithm. For non empty input, subsetEndpoints returns a copy of the
+// input with the irrelevant endpoints and empty subsets filtered out, if the input
+// size is larger than `n`,
+// Otherwise the input is returned as is.
+// `target` is the revision name for which we are computing a subset.
+func subsetEndpoints(eps *corev1.Endpoints, target string, n int) *corev1.Endpoints {
+	return filterEndpoints(eps, activatorSubset(eps, target, n))
+}
 
-	selection := hash.ChooseSubset(addrs, n, target)
+// filterEndpoints returns a copy of the input with only the addresses in
+// `selection` preserved. If selection is nil, the input is returned as is.
+func filterEndpoints(eps *corev1.Endpoints, selection sets.String) *corev1.Endpoints {
+	if selection == nil {
+		return eps
+	}
 
 	// Copy the informer's copy, so we can filter it out.
 	neps := eps.DeepCopy()
@@ -176,7 +193,7 @@ func subsetEndpoints(eps *corev1.Endpoints, target string, n int) *corev1.Endpoi
 	// We are guaranteed here to have w > 0, because
 	// 0. There's at least one subset (checked above).
 	// 1. A subset cannot be empty (k8s validation).
-	// 2. len(addrs) is at least as big as n
+	// 2. selection is a non empty subset of the addresses
 	// Thus there's at least 1 non empty subset (and for all intents and purposes we'll have 1 always).
 	neps.Subsets = neps.Subsets[:w]
 	return neps
@@ -199,6 +216,13 @@ func (r *reconciler) reconcilePublicEndpoints(ctx context.Context, sks *netv1alp
 		logger.Debug("Activator endpoints: ", spew.Sprint(activatorEps))
 	}
 
+	// The activators assigned to this revision. These are the same in
+	// both modes, so that the assigned activators keep tracking the revision
+	// while it is being served directly.
+	// List is sorted, so the value is stable across reconciliations.
+	assigned := activatorSubset(activatorEps, sks.Name, int(sks.Spec.NumActivators))
+	assignedActivators := strings.Join(assigned.List(), ",")
+
 	psn := sks.Status.PrivateServiceName
 	pvtEps, err := r.endpointsLister.Endpoints(sks.Namespace).Get(psn)
 	if err != nil {
@@ -233,13 +257,13 @@ func (r *reconciler) reconcilePublicEndpoints(ctx context.Context, sks *netv1alp
 		// Serving but no ready endpoints.
 		if pvtReady == 0 {
 			logger.Info(psn + " is in mode Serve but has no endpoints, using Activator endpoints for now")
-			srcEps = subsetEndpoints(activatorEps, sks.Name, int(sks.Spec.NumActivators))
+			srcEps = filterEndpoints(activatorEps, assigned)
 		} else {
 			// Serving & have endpoints ready.
 			srcEps = pvtEps
 		}
 	case netv1alpha1.SKSOperationModeProxy:
-		srcEps = subsetEndpoints(activatorEps, sks.Name, int(sks.Spec.NumActivators))
+		srcEps = filterEndpoints(activatorEps, assigned)
 		if dlogger.Core().Enabled(zap.DebugLevel) {
 			// Spew is expensive and there might be a lof of  endpoints.
 			logger.Debugf("Subset of activator endpoints (needed %d): %s",
@@ -253,7 +277,9 @@ func (r *reconciler) reconcilePublicEndpoints(ctx context.Context, sks *netv1alp
 	if apierrs.IsNotFound(err) {
 		logger.Infof("Public endpoints %s does not exist; creating.", sn)
 		sks.Status.MarkEndpointsNotReady("CreatingPublicEndpoints")
-		if _, err = r.kubeclient.CoreV1().Endpoints(sks.Namespace).Create(ctx, resources.MakePublicEndpoints(sks, srcEps), metav1.CreateOptions{}); err != nil {
+		want := resources.MakePublicEndpoints(sks, srcEps)
+		setAssignedActivators(want, assignedActivators)
+		if _, err = r.kubeclient.CoreV1().Endpoints(sks.Namespace).Create(ctx, want, metav1.CreateOptions{}); err != nil {
 			return fmt.Errorf("failed to create public K8s Endpoints: %w", err)
 		}
 		logger.Info("Created K8s Endpoints: ", sn)
@@ -264,9 +290,11 @@ func (r *reconciler) reconcilePublicEndpoints(ctx context.Context, sks *netv1alp
 		return fmt.Errorf("SKS: %s does not own Endpoints: %s", sks.Name, sn)
 	} else {
 		wantSubsets := resources.FilterSubsetPorts(sks, srcEps.Subsets)
-		if !equality.Semantic.DeepEqual(wantSubsets, eps.Subsets) {
+		if !equality.Semantic.DeepEqual(wantSubsets, eps.Subsets) ||
+			eps.Annotations[networking.ActivatorsAnnotationKey] != assignedActivators {
 			want := eps.DeepCopy()
 			want.Subsets = wantSubsets
+			setAssignedActivators(want, assignedActivators)
 			logger.Info("Public K8s Endpoints changed; reconciling: ", sn)
 			if _, err = r.kubeclient.CoreV1().Endpoints(sks.Namespace).Update(ctx, want, metav1.UpdateOptions{}); err != nil {
 				return fmt.Errorf("failed to update public K8s Endpoints: %w", err)
@@ -291,6 +319,20 @@ func (r *reconciler) reconcilePublicEndpoints(ctx context.Context, sks *netv1alp
 	return nil
 }
 
+// setAssignedActivators records the activators assigned to the revision on
+// the public endpoints, so that the activators don't have to track
+// the revisions they do not back. Empty value means all the activators.
+func setAssignedActivators(eps *corev1.Endpoints, activators string) {
+	if activators == "" {
+		delete(eps.Annotations, networking.ActivatorsAnnotationKey)
+		return
+	}
+	if eps.Annotations == nil {
+		eps.Annotations = make(map[string]string, 1)
+	}
+	eps.Annotations[networking.ActivatorsAnnotationKey] = activators
+}
+
 func (r *reconciler) reconcilePrivateService(ctx context.Context, sks *netv1alpha1.ServerlessService) error {
 	logger := logging.FromContext(ctx)
 
@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -143,6 +144,44 @@ func TestReconcile(t *testing.T) {
 		WantUpdates: []clientgotesting.UpdateActionImpl{{
 			Object: endpointspub("steady", "to-proxy-with-subset",
 				withPickedSubset(2, 4, 5, "to-proxy-with-subset"),
+				withAssignedActivators(2, 4, 5, "to-proxy-with-subset"),
+				withFilteredPorts(networking.BackendHTTPPort)),
+		}},
+	}, {
+		Name: "steady serve mode, subset assignment",
+		Key:  "steady/serve-with-subset",
+		Objects: []runtime.Object{
+			SKS("steady", "serve-with-subset", markHappy, WithPubService, WithPrivateService,
+				WithDeployRef("bar"), WithNumActivators(3)),
+			deploy("steady", "bar"),
+			svcpub("steady", "serve-with-subset"),
+			svcpriv("steady", "serve-with-subset"),
+			endpointspub("steady", "serve-with-subset", WithSubsets, withFilteredPorts(networking.BackendHTTPPort)),
+			endpointspriv("steady", "serve-with-subset", WithSubsets),
+			activatorEndpoints(withNSubsets(2, 4 /*8 in total*/)),
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: endpointspub("steady", "serve-with-subset", WithSubsets,
+				withAssignedActivators(2, 4, 3, "serve-with-subset"),
+				withFilteredPorts(networking.BackendHTTPPort)),
+		}},
+	}, {
+		Name: "steady serve mode, subset assignment removed",
+		Key:  "steady/serve-with-subset",
+		Objects: []runtime.Object{
+			SKS("steady", "serve-with-subset", markHappy, WithPubService, WithPrivateService,
+				WithDeployRef("bar"), WithNumActivators(8)),
+			deploy("steady", "bar"),
+			svcpub("steady", "serve-with-subset"),
+			svcpriv("steady", "serve-with-subset"),
+			endpointspub("steady", "serve-with-subset", WithSubsets,
+				withAssignedActivators(2, 4, 3, "serve-with-subset"),
+				withFilteredPorts(networking.BackendHTTPPort)),
+			endpointspriv("steady", "serve-with-subset", WithSubsets),
+			activatorEndpoints(withNSubsets(2, 4 /*8 in total*/)),
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: endpointspub("steady", "serve-with-subset", WithSubsets,
 				withFilteredPorts(networking.BackendHTTPPort)),
 		}},
 	}, {
@@ -702,6 +741,15 @@ func withPickedSubset(numSS, numAddrs, pickN int, target string) EndpointsOption
 	}
 }
 
+// withAssignedActivators simulates the recording of the activators
+// assigned to the revision.
+func withAssignedActivators(numSS, numAddrs, pickN int, target string) EndpointsOption {
+	return func(ep *corev1.Endpoints) {
+		aeps := activatorEndpoints(withNSubsets(numSS, numAddrs))
+		setAssignedActivators(ep, strings.Join(activatorSubset(aeps, target, pickN).List(), ","))
+	}
+}
+
 // withOtherSubsets uses different IP set than functional::withSubsets.
 func withOtherSubsets(ep *corev1.Endpoints) {
 	ep.Subsets = []corev1.EndpointSubset{{
@@ -892,6 +940,9 @@ func TestSubsetEndpoints(t *testing.T) {
 				if got, want := subsetEndpoints(aeps, "rev", tc.req), aeps; got != want {
 					t.Errorf("Select all: EPS = %p, want: %p", got, want)
 				}
+				if got := activatorSubset(aeps, "rev", tc.req); got != nil {
+					t.Errorf("Select all: subset = %v, want: nil", got)
+				}
 			})
 		}
 	})
@@ -27,10 +27,13 @@ import (
 	kserviceinformer "knative.dev/serving/pkg/client/injection/informers/serving/v1/service"
 	ksvcreconciler "knative.dev/serving/pkg/client/injection/reconciler/serving/v1/service"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/cache"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/logging"
+	pkgnet "knative.dev/pkg/network"
 	v1 "knative.dev/serving/pkg/apis/serving/v1"
 	servingreconciler "knative.dev/serving/pkg/reconciler"
 )
@@ -64,9 +67,17 @@ func NewController(
 		return controller.Options{ConfigStore: configStore}
 	}
 	impl := ksvcreconciler.NewImpl(ctx, c, opts)
+	c.endToEnd = newEndToEndReadiness(logger, pkgnet.NewProberTransport(), impl.EnqueueKey)
 
 	logger.Info("Setting up event handlers")
 	serviceInformer.Informer().AddEventHandler(controller.HandleAll(impl.Enqueue))
+	serviceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: func(obj interface{}) {
+			if om, ok := obj.(metav1.Object); ok {
+				c.endToEnd.Clear(types.NamespacedName{Namespace: om.GetNamespace(), Name: om.GetName()})
+			}
+		},
+	})
 
 	handleControllerOf := cache.FilteringResourceEventHandler{
 		FilterFunc: controller.FilterControllerGK(v1.Kind("Service")),
@@ -0,0 +1,138 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/logging"
+	"knative.dev/pkg/network/prober"
+	v1 "knative.dev/serving/pkg/apis/serving/v1"
+)
+
+const (
+	endToEndProbePeriod  = 1 * time.Second
+	endToEndProbeTimeout = 30 * time.Second
+)
+
+// endToEndProbeOptions accept any non-error response, since e.g. the
+// HTTP to HTTPS redirects are not followed by the prober.
+var endToEndProbeOptions = []interface{}{
+	prober.Verifier(func(r *http.Response, _ []byte) (bool, error) {
+		return r.StatusCode < http.StatusBadRequest, nil
+	}),
+}
+
+// asyncProber is an interface used mostly to mock prober.Manager for tests.
+type asyncProber interface {
+	Offer(context.Context, string, interface{}, time.Duration, time.Duration, ...interface{}) bool
+}
+
+// endToEndProbe is the argument of the end-to-end readiness probes.
+type endToEndProbe struct {
+	key         types.NamespacedName
+	fingerprint string
+}
+
+// endToEndReadiness probes the URLs of the Services opting into the
+// end-to-end readiness and remembers the state of their Routes that
+// was verified to respond.
+type endToEndReadiness struct {
+	prober asyncProber
+
+	mu       sync.Mutex
+	verified map[types.NamespacedName]string
+}
+
+func newEndToEndReadiness(logger *zap.SugaredLogger, transport http.RoundTripper, enqueue func(types.NamespacedName)) *endToEndReadiness {
+	e := &endToEndReadiness{
+		verified: make(map[types.NamespacedName]string),
+	}
+	e.prober = prober.New(func(arg interface{}, success bool, err error) {
+		p := arg.(endToEndProbe)
+		logger.Infof("End-to-end probe is done for %v: success?: %v error: %v", p.key, success, err)
+		if success {
+			e.mu.Lock()
+			e.verified[p.key] = p.fingerprint
+			e.mu.Unlock()
+		}
+		// Re-enqueue the Service in any case, either to become ready,
+		// or to retry the probe.
+		enqueue(p.key)
+	}, transport)
+	return e
+}
+
+// Clear forgets the verified state of the Service.
+func (e *endToEndReadiness) Clear(key types.NamespacedName) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.verified, key)
+}
+
+func (e *endToEndReadiness) isVerified(key types.NamespacedName, fingerprint string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.verified[key] == fingerprint
+}
+
+// routeFingerprint captures the state of the Route the end-to-end probe verifies.
+func routeFingerprint(route *v1.Route) string {
+	var sb strings.Builder
+	sb.WriteString(route.Status.URL.String())
+	for _, t := range route.Status.Traffic {
+		var percent int64
+		if t.Percent != nil {
+			percent = *t.Percent
+		}
+		fmt.Fprintf(&sb, " %s:%s:%d", t.Tag, t.RevisionName, percent)
+	}
+	return sb.String()
+}
+
+// checkEndToEndReady keeps the RoutesReady condition of the Services opting
+// into the end-to-end readiness Unknown, until their URL actually responds.
+func (c *Reconciler) checkEndToEndReady(ctx context.Context, route *v1.Route, service *v1.Service) {
+	key := types.NamespacedName{Namespace: service.Namespace, Name: service.Name}
+	if !service.EndToEndReadiness() {
+		c.endToEnd.Clear(key)
+		return
+	}
+	rc := service.Status.GetCondition(v1.ServiceConditionRoutesReady)
+	if rc == nil || rc.Status != corev1.ConditionTrue || route.Status.URL == nil {
+		return
+	}
+
+	fingerprint := routeFingerprint(route)
+	if c.endToEnd.isVerified(key, fingerprint) {
+		return
+	}
+	target := route.Status.URL.String()
+	service.Status.MarkRouteNotYetResponding(target)
+	// The probe outlives the reconciliation, so don't inherit its context.
+	probeCtx := logging.WithLogger(context.Background(), logging.FromContext(ctx))
+	c.endToEnd.prober.Offer(probeCtx, target, endToEndProbe{key: key, fingerprint: fingerprint},
+		endToEndProbePeriod, endToEndProbeTimeout, endToEndProbeOptions...)
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	logtesting "knative.dev/pkg/logging/testing"
+	"knative.dev/pkg/ptr"
+	v1 "knative.dev/serving/pkg/apis/serving/v1"
+
+	. "knative.dev/serving/pkg/testing/v1"
+)
+
+type fakeProber struct {
+	targets []string
+}
+
+func (p *fakeProber) Offer(_ context.Context, target string, _ interface{}, _, _ time.Duration, _ ...interface{}) bool {
+	p.targets = append(p.targets, target)
+	return true
+}
+
+func TestEndToEndReadinessProbe(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		want   bool
+	}{{
+		name:   "ok",
+		status: http.StatusOK,
+		want:   true,
+	}, {
+		name:   "redirect",
+		status: http.StatusMovedPermanently,
+		want:   true,
+	}, {
+		name:   "unavailable",
+		status: http.StatusServiceUnavailable,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+			}))
+			defer ts.Close()
+
+			key := types.NamespacedName{Namespace: "foo", Name: "bar"}
+			enqueued := make(chan types.NamespacedName, 1)
+			e := newEndToEndReadiness(logtesting.TestLogger(t), http.DefaultTransport, func(k types.NamespacedName) {
+				enqueued <- k
+			})
+			if !e.prober.Offer(context.Background(), ts.URL, endToEndProbe{key: key, fingerprint: "fp"},
+				10*time.Millisecond, 100*time.Millisecond, endToEndProbeOptions...) {
+				t.Fatal("Offer() = false, want true")
+			}
+
+			select {
+			case got := <-enqueued:
+				if got != key {
+					t.Errorf("Enqueued %v, want %v", got, key)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timed out waiting for the probe to finish")
+			}
+			if got := e.isVerified(key, "fp"); got != test.want {
+				t.Errorf("isVerified() = %v, want %v", got, test.want)
+			}
+
+			e.Clear(key)
+			if e.isVerified(key, "fp") {
+				t.Error("isVerified() = true after Clear()")
+			}
+		})
+	}
+}
+
+func TestRouteFingerprint(t *testing.T) {
+	r1 := route("fp", "foo", WithRunLatestRollout, WithURL,
+		WithStatusTraffic(v1.TrafficTarget{RevisionName: "fp-00001", Percent: ptr.Int64(100)}))
+	r2 := route("fp", "foo", WithRunLatestRollout, WithURL,
+		WithStatusTraffic(v1.TrafficTarget{RevisionName: "fp-00002", Percent: ptr.Int64(100)}))
+
+	if routeFingerprint(r1) != routeFingerprint(r1.DeepCopy()) {
+		t.Error("Fingerprints of the same Route differ")
+	}
+	if routeFingerprint(r1) == routeFingerprint(r2) {
+		t.Error("Fingerprints of the Routes with different traffic are the same")
+	}
+}
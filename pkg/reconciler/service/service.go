@@ -51,6 +51,9 @@ type Reconciler struct {
 	configurationLister listers.ConfigurationLister
 	revisionLister      listers.RevisionLister
 	routeLister         listers.RouteLister
+
+	// endToEnd probes the Services opting into the end-to-end readiness.
+	endToEnd *endToEndReadiness
 }
 
 // Check that our Reconciler implements ksvcreconciler.Interface
@@ -108,6 +111,7 @@ func (c *Reconciler) ReconcileKind(ctx context.Context, service *v1.Service) pkg
 	}
 
 	c.checkRoutesNotReady(config, logger, route, service)
+	c.checkEndToEndReady(ctx, route, service)
 	return nil
 }
 
@@ -48,6 +48,7 @@ import (
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/clock"
 	clientgotesting "k8s.io/client-go/testing"
 
@@ -58,6 +59,17 @@ import (
 
 func TestReconcile(t *testing.T) {
 	retryAttempted := false
+	withEndToEndReadiness := func(s *v1.Service) {
+		WithRunLatestRollout(s)
+		WithServiceAnnotation(serving.EndToEndReadinessAnnotationKey, "true")(s)
+	}
+	e2eVerifiedRoute := route("e2e-verified", "foo", withEndToEndReadiness, RouteReady,
+		WithURL, WithAddress, WithInitRouteConditions,
+		WithStatusTraffic(
+			v1.TrafficTarget{
+				RevisionName: "e2e-verified-00001",
+				Percent:      ptr.Int64(100),
+			}), MarkTrafficAssigned, MarkIngressReady)
 	table := TableTest{{
 		Name: "bad workqueue key",
 		Key:  "too/many/parts",
@@ -538,6 +550,55 @@ func TestReconcile(t *testing.T) {
 					Percent:      ptr.Int64(100),
 				})),
 		}},
+	}, {
+		Name: "end-to-end readiness, wait for the domain to respond",
+		Objects: []runtime.Object{
+			DefaultService("e2e", "foo", WithRunLatestRollout, WithInitSvcConditions, WithServiceGeneration(1),
+				WithServiceAnnotation(serving.EndToEndReadinessAnnotationKey, "true")),
+			route("e2e", "foo", withEndToEndReadiness, RouteReady,
+				WithURL, WithAddress, WithInitRouteConditions,
+				WithStatusTraffic(
+					v1.TrafficTarget{
+						RevisionName: "e2e-00001",
+						Percent:      ptr.Int64(100),
+					}), MarkTrafficAssigned, MarkIngressReady),
+			config("e2e", "foo", withEndToEndReadiness,
+				WithConfigGeneration(1), WithConfigObservedGen,
+				WithLatestCreated("e2e-00001"), WithLatestReady("e2e-00001")),
+		},
+		Key: "foo/e2e",
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: DefaultService("e2e", "foo", WithRunLatestRollout,
+				WithServiceAnnotation(serving.EndToEndReadinessAnnotationKey, "true"),
+				WithReadyConfig("e2e-00001"),
+				WithReadyRoute, WithSvcStatusDomain, WithSvcStatusAddress,
+				WithSvcStatusTraffic(v1.TrafficTarget{
+					RevisionName: "e2e-00001",
+					Percent:      ptr.Int64(100),
+				}),
+				WithServiceStatusRouteNotResponding),
+		}},
+	}, {
+		Name: "end-to-end readiness, domain responded",
+		Objects: []runtime.Object{
+			DefaultService("e2e-verified", "foo", WithRunLatestRollout, WithInitSvcConditions, WithServiceGeneration(1),
+				WithServiceAnnotation(serving.EndToEndReadinessAnnotationKey, "true")),
+			e2eVerifiedRoute,
+			config("e2e-verified", "foo", withEndToEndReadiness,
+				WithConfigGeneration(1), WithConfigObservedGen,
+				WithLatestCreated("e2e-verified-00001"), WithLatestReady("e2e-verified-00001")),
+		},
+		Key: "foo/e2e-verified",
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: DefaultService("e2e-verified", "foo", WithRunLatestRollout,
+				WithServiceAnnotation(serving.EndToEndReadinessAnnotationKey, "true"),
+				WithReadyConfig("e2e-verified-00001"),
+				WithReadyRoute, WithSvcStatusDomain, WithSvcStatusAddress,
+				WithSvcStatusTraffic(v1.TrafficTarget{
+					RevisionName: "e2e-verified-00001",
+					Percent:      ptr.Int64(100),
+				})),
+		}},
 	}, {
 		Name: "configuration lagging",
 		// When both route and config are ready, the service should become ready.
@@ -781,6 +842,12 @@ func TestReconcile(t *testing.T) {
 			configurationLister: listers.GetConfigurationLister(),
 			revisionLister:      listers.GetRevisionLister(),
 			routeLister:         listers.GetRouteLister(),
+			endToEnd: &endToEndReadiness{
+				prober: &fakeProber{},
+				verified: map[types.NamespacedName]string{
+					{Namespace: "foo", Name: "e2e-verified"}: routeFingerprint(e2eVerifiedRoute),
+				},
+			},
 		}
 
 		return ksvcreconciler.NewReconciler(ctx, logging.FromContext(ctx), servingclient.Get(ctx),
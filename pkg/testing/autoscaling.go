@@ -0,0 +1,143 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"knative.dev/networking/pkg/apis/networking"
+	"knative.dev/pkg/kmeta"
+	"knative.dev/pkg/ptr"
+	"knative.dev/serving/pkg/apis/autoscaling"
+	asv1a1 "knative.dev/serving/pkg/apis/autoscaling/v1alpha1"
+)
+
+// PodAutoscaler creates a generic PodAutoscaler object targeting the
+// deployment created by Deployment(ns, name).
+func PodAutoscaler(ns, name string, opts ...PodAutoscalerOption) *asv1a1.PodAutoscaler {
+	pa := &asv1a1.PodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+		},
+		Spec: asv1a1.PodAutoscalerSpec{
+			ScaleTargetRef: corev1.ObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       name + "-deployment",
+			},
+			ProtocolType: networking.ProtocolHTTP1,
+		},
+	}
+	pa.Status.InitializeConditions()
+	for _, opt := range opts {
+		opt(pa)
+	}
+	return pa
+}
+
+// WithKPAClass updates the PA to add the KPA class and concurrency metric annotations.
+func WithKPAClass(pa *asv1a1.PodAutoscaler) {
+	if pa.Annotations == nil {
+		pa.Annotations = make(map[string]string, 2)
+	}
+	pa.Annotations[autoscaling.ClassAnnotationKey] = autoscaling.KPA
+	pa.Annotations[autoscaling.MetricAnnotationKey] = autoscaling.Concurrency
+}
+
+// WithScales sets the desired and actual scale in the PA status.
+func WithScales(desired, actual int32) PodAutoscalerOption {
+	return func(pa *asv1a1.PodAutoscaler) {
+		pa.Status.DesiredScale, pa.Status.ActualScale = ptr.Int32(desired), ptr.Int32(actual)
+	}
+}
+
+// MetricOption is an option that can be applied to a Metric.
+type MetricOption func(*asv1a1.Metric)
+
+// WithMetricScrapeTarget sets the service the Metric scrapes.
+func WithMetricScrapeTarget(target string) MetricOption {
+	return func(m *asv1a1.Metric) {
+		m.Spec.ScrapeTarget = target
+	}
+}
+
+// WithMetricWindows sets the stable and panic windows of the Metric.
+func WithMetricWindows(stable, panicWindow time.Duration) MetricOption {
+	return func(m *asv1a1.Metric) {
+		m.Spec.StableWindow, m.Spec.PanicWindow = stable, panicWindow
+	}
+}
+
+// Metric creates a generic Metric object scraping the private service
+// of the given revision.
+func Metric(ns, name string, opts ...MetricOption) *asv1a1.Metric {
+	m := &asv1a1.Metric{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+		},
+		Spec: asv1a1.MetricSpec{
+			ScrapeTarget: kmeta.ChildName(name, "-private"),
+			StableWindow: 60 * time.Second,
+			PanicWindow:  6 * time.Second,
+		},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// DeploymentOption is an option that can be applied to a Deployment.
+type DeploymentOption func(*appsv1.Deployment)
+
+// WithDeploymentReplicas sets the desired number of replicas on the Deployment.
+func WithDeploymentReplicas(replicas int32) DeploymentOption {
+	return func(d *appsv1.Deployment) {
+		d.Spec.Replicas = ptr.Int32(replicas)
+	}
+}
+
+// Deployment creates a generic Deployment object named after the
+// revision, as targeted by PodAutoscaler(ns, name).
+func Deployment(ns, name string, opts ...DeploymentOption) *appsv1.Deployment {
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name + "-deployment",
+			Namespace: ns,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"a": "b",
+				},
+			},
+		},
+		Status: appsv1.DeploymentStatus{
+			Replicas: 42,
+		},
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
@@ -233,6 +233,13 @@ func WithContainerStatuses(containerStatus []v1.ContainerStatus) RevisionOption
 	}
 }
 
+// WithInitContainerStatuses sets the .Status.InitContainerStatuses to the Revision.
+func WithInitContainerStatuses(containerStatus []v1.ContainerStatus) RevisionOption {
+	return func(r *v1.Revision) {
+		r.Status.InitContainerStatuses = containerStatus
+	}
+}
+
 // WithRevisionObservedGeneration sets the observed generation on the
 // revision status.
 func WithRevisionObservedGeneration(gen int64) RevisionOption {
@@ -19,6 +19,7 @@ package v1
 import (
 	"context"
 	"fmt"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -226,6 +227,21 @@ func MarkIngressNotConfigured(r *v1.Route) {
 	r.Status.MarkIngressNotConfigured()
 }
 
+// WithCustomHostNotAllowed marks the custom host of the route not allowed.
+func WithCustomHostNotAllowed(host string) RouteOption {
+	return func(r *v1.Route) {
+		r.Status.MarkCustomHostNotAllowed(host)
+	}
+}
+
+// WithCustomHostClaimed marks the custom host of the route claimed by
+// the other route.
+func WithCustomHostClaimed(host, route string) RouteOption {
+	return func(r *v1.Route) {
+		r.Status.MarkCustomHostClaimed(host, route)
+	}
+}
+
 // WithPropagatedStatus propagates the given IngressStatus into the routes status.
 func WithPropagatedStatus(status netv1alpha1.IngressStatus) RouteOption {
 	return func(r *v1.Route) {
@@ -278,6 +294,20 @@ func WithRouteAnnotation(annotations map[string]string) RouteOption {
 	}
 }
 
+// WithRouteStatusAnnotation sets the specified annotations on the Route status.
+func WithRouteStatusAnnotation(annotations map[string]string) RouteOption {
+	return func(r *v1.Route) {
+		r.Status.Annotations = annotations
+	}
+}
+
+// WithRouteCreationTimestamp sets the creation timestamp of the Route.
+func WithRouteCreationTimestamp(t time.Time) RouteOption {
+	return func(r *v1.Route) {
+		r.CreationTimestamp = metav1.NewTime(t)
+	}
+}
+
 // Route creates a route with RouteOptions
 func Route(namespace, name string, ro ...RouteOption) *v1.Route {
 	r := &v1.Route{
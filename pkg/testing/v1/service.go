@@ -417,6 +417,12 @@ func WithServiceStatusRouteNotReady(s *v1.Service) {
 	s.Status.MarkRouteNotYetReady()
 }
 
+// WithServiceStatusRouteNotResponding sets the `RoutesReady` condition on the service
+// to `Unknown`, waiting for its domain to respond.
+func WithServiceStatusRouteNotResponding(s *v1.Service) {
+	s.Status.MarkRouteNotYetResponding(fmt.Sprintf("http://%s.%s.example.com", s.Name, s.Namespace))
+}
+
 // MarkConfigurationNotOwned calls the function of the same name on the Service's status.
 func MarkConfigurationNotOwned(service *v1.Service) {
 	service.Status.MarkConfigurationNotOwned(servicenames.Configuration(service))
@@ -0,0 +1,101 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	cm "knative.dev/pkg/configmap"
+	tracingconfig "knative.dev/pkg/tracing/config"
+)
+
+const (
+	// OTLP is used for the OpenTelemetry collector backend, which receives
+	// the traces via OTLP/gRPC.
+	OTLP tracingconfig.BackendType = "otlp"
+
+	// ServiceNameAttribute and ServiceInstanceAttribute are the resource
+	// attributes identifying the process exporting the traces.
+	ServiceNameAttribute     = "service.name"
+	ServiceInstanceAttribute = "service.instance.id"
+	// NamespaceAttribute and PodAttribute are the resource attributes
+	// identifying the pod the process runs in.
+	NamespaceAttribute = "k8s.namespace.name"
+	PodAttribute       = "k8s.pod.name"
+
+	backendKey      = "backend"
+	otlpEndpointKey = "otlp-endpoint"
+	otlpInsecureKey = "otlp-insecure"
+)
+
+// Config holds the configuration for tracers, as read from config-tracing.
+// +k8s:deepcopy-gen=true
+type Config struct {
+	tracingconfig.Config
+
+	// OTLPEndpoint is the host:port of the OTLP/gRPC collector the traces
+	// are exported to, when the backend is OTLP.
+	OTLPEndpoint string
+	// OTLPInsecure disables the transport security of the connection to
+	// the OTLP collector.
+	OTLPInsecure bool
+}
+
+// NewConfigFromMap returns a Config given a map corresponding to a ConfigMap.
+func NewConfigFromMap(data map[string]string) (*Config, error) {
+	otlp := tracingconfig.BackendType(data[backendKey]) == OTLP
+	if otlp {
+		// The knative.dev/pkg parser does not know the OTLP backend,
+		// so it parses the common settings as if tracing was disabled.
+		common := make(map[string]string, len(data))
+		for k, v := range data {
+			common[k] = v
+		}
+		common[backendKey] = string(tracingconfig.None)
+		data = common
+	}
+
+	base, err := tracingconfig.NewTracingConfigFromMap(data)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{Config: *base}
+
+	if err := cm.Parse(data,
+		cm.AsString(otlpEndpointKey, &cfg.OTLPEndpoint),
+		cm.AsBool(otlpInsecureKey, &cfg.OTLPInsecure),
+	); err != nil {
+		return nil, err
+	}
+
+	if otlp {
+		if cfg.OTLPEndpoint == "" {
+			return nil, fmt.Errorf("otlp tracing enabled without an %s specified", otlpEndpointKey)
+		}
+		cfg.Backend = OTLP
+	}
+	return cfg, nil
+}
+
+// NewConfigFromConfigMap returns a Config for the given ConfigMap.
+func NewConfigFromConfigMap(config *corev1.ConfigMap) (*Config, error) {
+	if config == nil {
+		return NewConfigFromMap(nil)
+	}
+	return NewConfigFromMap(config.Data)
+}
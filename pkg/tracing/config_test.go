@@ -0,0 +1,106 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	tracingconfig "knative.dev/pkg/tracing/config"
+)
+
+func TestNewConfigFromMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   map[string]string
+		want    *Config
+		wantErr bool
+	}{{
+		name:  "default",
+		input: map[string]string{},
+		want:  &Config{Config: *tracingconfig.NoopConfig()},
+	}, {
+		name: "zipkin",
+		input: map[string]string{
+			"backend":         "zipkin",
+			"zipkin-endpoint": "http://zipkin:9411/api/v2/spans",
+			"sample-rate":     "0.5",
+		},
+		want: &Config{Config: tracingconfig.Config{
+			Backend:        tracingconfig.Zipkin,
+			ZipkinEndpoint: "http://zipkin:9411/api/v2/spans",
+			SampleRate:     0.5,
+		}},
+	}, {
+		name: "otlp",
+		input: map[string]string{
+			"backend":       "otlp",
+			"otlp-endpoint": "otel-collector:4317",
+			"otlp-insecure": "true",
+			"debug":         "true",
+		},
+		want: &Config{
+			Config: tracingconfig.Config{
+				Backend:    OTLP,
+				Debug:      true,
+				SampleRate: 0.1,
+			},
+			OTLPEndpoint: "otel-collector:4317",
+			OTLPInsecure: true,
+		},
+	}, {
+		name: "otlp without endpoint",
+		input: map[string]string{
+			"backend": "otlp",
+		},
+		wantErr: true,
+	}, {
+		name: "otlp with invalid sample rate",
+		input: map[string]string{
+			"backend":       "otlp",
+			"otlp-endpoint": "otel-collector:4317",
+			"sample-rate":   "2",
+		},
+		wantErr: true,
+	}, {
+		name: "malformed otlp-insecure",
+		input: map[string]string{
+			"backend":       "otlp",
+			"otlp-endpoint": "otel-collector:4317",
+			"otlp-insecure": "maybe",
+		},
+		wantErr: true,
+	}, {
+		name: "unknown backend",
+		input: map[string]string{
+			"backend": "jaeger",
+		},
+		wantErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := NewConfigFromMap(test.input)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("NewConfigFromMap() = %v, want error: %v", err, test.wantErr)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Error("NewConfigFromMap (-want, +got):", diff)
+			}
+		})
+	}
+}
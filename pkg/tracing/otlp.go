@@ -0,0 +1,178 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	// otlpExportMethod is the full name of the OTLP/gRPC trace export method.
+	otlpExportMethod = "/opentelemetry.proto.collector.trace.v1.TraceService/Export"
+
+	// otlpExportInterval is how often the buffered spans are exported.
+	otlpExportInterval = 5 * time.Second
+	// otlpExportTimeout is how long a single export may take.
+	otlpExportTimeout = 10 * time.Second
+	// otlpMaxBatchSize is the number of buffered spans that triggers
+	// an export before the interval elapses.
+	otlpMaxBatchSize = 512
+	// otlpMaxBufferSize is the number of buffered spans, above which
+	// the spans are dropped, e.g. while the collector is unreachable.
+	otlpMaxBufferSize = 4 * otlpMaxBatchSize
+)
+
+// otlpExporter is an OpenCensus trace.Exporter, which exports the spans to an
+// OTLP/gRPC collector in batches.
+type otlpExporter struct {
+	logger   *zap.SugaredLogger
+	conn     *grpc.ClientConn
+	resource []byte
+
+	mu    sync.Mutex
+	spans []*trace.SpanData
+
+	flushCh chan struct{}
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+}
+
+var _ trace.Exporter = (*otlpExporter)(nil)
+
+// newOTLPExporter creates an exporter sending the spans to the given endpoint,
+// as originating from a resource with the given attributes.
+func newOTLPExporter(endpoint string, insecure bool, attrs map[string]string, logger *zap.SugaredLogger) (*otlpExporter, error) {
+	creds := grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{}))
+	if insecure {
+		creds = grpc.WithInsecure()
+	}
+	// The dial does not block, the connection is established on the first export.
+	conn, err := grpc.Dial(endpoint, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &otlpExporter{
+		logger:   logger,
+		conn:     conn,
+		resource: encodeResource(attrs),
+		flushCh:  make(chan struct{}, 1),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+	go e.run()
+	return e, nil
+}
+
+// ExportSpan implements trace.Exporter.
+func (e *otlpExporter) ExportSpan(sd *trace.SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.spans) >= otlpMaxBufferSize {
+		return
+	}
+	e.spans = append(e.spans, sd)
+	if len(e.spans) == otlpMaxBatchSize {
+		select {
+		case e.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (e *otlpExporter) run() {
+	defer close(e.doneCh)
+
+	ticker := time.NewTicker(otlpExportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stopCh:
+			e.flush()
+			return
+		case <-ticker.C:
+			e.flush()
+		case <-e.flushCh:
+			e.flush()
+		}
+	}
+}
+
+// flush exports all the buffered spans, in batches of at most otlpMaxBatchSize.
+func (e *otlpExporter) flush() {
+	e.mu.Lock()
+	spans := e.spans
+	e.spans = nil
+	e.mu.Unlock()
+
+	for len(spans) > 0 {
+		n := len(spans)
+		if n > otlpMaxBatchSize {
+			n = otlpMaxBatchSize
+		}
+		if err := e.export(spans[:n]); err != nil {
+			e.logger.Warnw("Failed to export spans", zap.Int("count", n), zap.Error(err))
+		}
+		spans = spans[n:]
+	}
+}
+
+func (e *otlpExporter) export(spans []*trace.SpanData) error {
+	ctx, cancel := context.WithTimeout(context.Background(), otlpExportTimeout)
+	defer cancel()
+
+	var resp rawMessage
+	req := rawMessage(encodeExportRequest(e.resource, spans))
+	return e.conn.Invoke(ctx, otlpExportMethod, &req, &resp, grpc.ForceCodec(rawCodec{}))
+}
+
+// Close exports the buffered spans and closes the connection to the collector.
+func (e *otlpExporter) Close() error {
+	close(e.stopCh)
+	<-e.doneCh
+	return e.conn.Close()
+}
+
+// rawMessage is a protobuf message, which is already serialized.
+type rawMessage []byte
+
+// rawCodec is a gRPC codec passing the serialized protobuf messages through.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	return *v.(*rawMessage), nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	*v.(*rawMessage) = append((*v.(*rawMessage))[:0], data...)
+	return nil
+}
+
+// Name returns the name of the protobuf codec, which selects the
+// content-subtype the collectors expect.
+func (rawCodec) Name() string {
+	return "proto"
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"go.opencensus.io/trace"
+)
+
+// The OTLP messages are encoded by hand, following
+// https://github.com/open-telemetry/opentelemetry-proto/tree/main/opentelemetry/proto/trace/v1.
+
+const (
+	// The OTLP span kinds.
+	otlpSpanKindUnspecified = 0
+	otlpSpanKindServer      = 2
+	otlpSpanKindClient      = 3
+
+	// otlpStatusCodeError is the OTLP status code of a failed span.
+	otlpStatusCodeError = 2
+
+	// instrumentationName is the name of the instrumentation scope of the spans.
+	instrumentationName = "knative.dev/serving"
+
+	// The protobuf wire types.
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// encodeExportRequest encodes an ExportTraceServiceRequest with the spans of
+// the given, already encoded, resource.
+func encodeExportRequest(resource []byte, spans []*trace.SpanData) []byte {
+	scope := appendString(nil, 1, instrumentationName)
+	scopeSpans := appendMessage(nil, 1, scope)
+	for _, sd := range spans {
+		scopeSpans = appendMessage(scopeSpans, 2, encodeSpan(sd))
+	}
+
+	resourceSpans := appendMessage(nil, 1, resource)
+	resourceSpans = appendMessage(resourceSpans, 2, scopeSpans)
+	return appendMessage(nil, 1, resourceSpans)
+}
+
+// encodeResource encodes a Resource with the given attributes.
+func encodeResource(attrs map[string]string) []byte {
+	m := make(map[string]interface{}, len(attrs))
+	for k, v := range attrs {
+		m[k] = v
+	}
+	return appendAttributes(nil, 1, m)
+}
+
+func encodeSpan(sd *trace.SpanData) []byte {
+	b := appendBytes(nil, 1, sd.TraceID[:])
+	b = appendBytes(b, 2, sd.SpanID[:])
+	if entries := sd.Tracestate.Entries(); len(entries) > 0 {
+		kvs := make([]string, 0, len(entries))
+		for _, e := range entries {
+			kvs = append(kvs, e.Key+"="+e.Value)
+		}
+		b = appendString(b, 3, strings.Join(kvs, ","))
+	}
+	if sd.ParentSpanID != (trace.SpanID{}) {
+		b = appendBytes(b, 4, sd.ParentSpanID[:])
+	}
+	b = appendString(b, 5, sd.Name)
+	if kind := otlpSpanKind(sd.SpanKind); kind != otlpSpanKindUnspecified {
+		b = appendVarint(b, 6, kind)
+	}
+	b = appendFixed64(b, 7, uint64(sd.StartTime.UnixNano()))
+	b = appendFixed64(b, 8, uint64(sd.EndTime.UnixNano()))
+	b = appendAttributes(b, 9, sd.Attributes)
+	b = appendVarint(b, 10, uint64(sd.DroppedAttributeCount))
+
+	for _, a := range sd.Annotations {
+		ev := appendFixed64(nil, 1, uint64(a.Time.UnixNano()))
+		ev = appendString(ev, 2, a.Message)
+		ev = appendAttributes(ev, 3, a.Attributes)
+		b = appendMessage(b, 11, ev)
+	}
+	for _, me := range sd.MessageEvents {
+		ev := appendFixed64(nil, 1, uint64(me.Time.UnixNano()))
+		ev = appendString(ev, 2, "message")
+		ev = appendAttributes(ev, 3, map[string]interface{}{
+			"message.type":              messageEventType(me.EventType),
+			"message.id":                me.MessageID,
+			"message.uncompressed_size": me.UncompressedByteSize,
+			"message.compressed_size":   me.CompressedByteSize,
+		})
+		b = appendMessage(b, 11, ev)
+	}
+	b = appendVarint(b, 12, uint64(sd.DroppedAnnotationCount+sd.DroppedMessageEventCount))
+
+	for _, l := range sd.Links {
+		lb := appendBytes(nil, 1, l.TraceID[:])
+		lb = appendBytes(lb, 2, l.SpanID[:])
+		lb = appendAttributes(lb, 4, l.Attributes)
+		b = appendMessage(b, 13, lb)
+	}
+	b = appendVarint(b, 14, uint64(sd.DroppedLinkCount))
+
+	// OpenCensus reports the successful spans with the zero code,
+	// which OTLP leaves unset.
+	if sd.Code != 0 {
+		st := appendString(nil, 2, sd.Message)
+		st = appendVarint(st, 3, otlpStatusCodeError)
+		b = appendMessage(b, 15, st)
+	}
+	return b
+}
+
+func otlpSpanKind(kind int) uint64 {
+	switch kind {
+	case trace.SpanKindServer:
+		return otlpSpanKindServer
+	case trace.SpanKindClient:
+		return otlpSpanKindClient
+	default:
+		return otlpSpanKindUnspecified
+	}
+}
+
+func messageEventType(t trace.MessageEventType) string {
+	switch t {
+	case trace.MessageEventTypeSent:
+		return "SENT"
+	case trace.MessageEventTypeRecv:
+		return "RECEIVED"
+	default:
+		return "UNSPECIFIED"
+	}
+}
+
+// appendAttributes appends the attributes as repeated KeyValue messages,
+// sorted by the key.
+func appendAttributes(b []byte, num int, attrs map[string]interface{}) []byte {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		var v []byte
+		switch val := attrs[k].(type) {
+		case string:
+			v = appendString(nil, 1, val)
+		case bool:
+			v = appendTag(nil, 2, wireVarint)
+			if val {
+				v = append(v, 1)
+			} else {
+				v = append(v, 0)
+			}
+		case int64:
+			v = appendTag(nil, 3, wireVarint)
+			v = appendUvarint(v, uint64(val))
+		case float64:
+			v = appendFixed64(nil, 4, math.Float64bits(val))
+		default:
+			v = appendString(nil, 1, fmt.Sprint(val))
+		}
+		kv := appendString(nil, 1, k)
+		kv = appendMessage(kv, 2, v)
+		b = appendMessage(b, num, kv)
+	}
+	return b
+}
+
+// appendMessage appends the embedded message, which is already encoded.
+func appendMessage(b []byte, num int, msg []byte) []byte {
+	b = appendTag(b, num, wireBytes)
+	b = appendUvarint(b, uint64(len(msg)))
+	return append(b, msg...)
+}
+
+// appendBytes appends the bytes field, unless it is empty.
+func appendBytes(b []byte, num int, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	return appendMessage(b, num, v)
+}
+
+// appendString appends the string field, unless it is empty.
+func appendString(b []byte, num int, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = appendTag(b, num, wireBytes)
+	b = appendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// appendVarint appends the varint field, unless it is zero.
+func appendVarint(b []byte, num int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendTag(b, num, wireVarint)
+	return appendUvarint(b, v)
+}
+
+func appendFixed64(b []byte, num int, v uint64) []byte {
+	b = appendTag(b, num, wireFixed64)
+	return append(b, byte(v), byte(v>>8), byte(v>>16), byte(v>>24),
+		byte(v>>32), byte(v>>40), byte(v>>48), byte(v>>56))
+}
+
+func appendTag(b []byte, num int, wireType uint64) []byte {
+	return appendUvarint(b, uint64(num)<<3|wireType)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
@@ -0,0 +1,264 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc"
+	tracingconfig "knative.dev/pkg/tracing/config"
+
+	. "knative.dev/pkg/logging/testing"
+)
+
+type exportRequest struct {
+	method string
+	body   []byte
+}
+
+// serverCodec adapts rawCodec to the codec interface of the gRPC servers.
+type serverCodec struct {
+	rawCodec
+}
+
+func (serverCodec) String() string {
+	return "proto"
+}
+
+// fakeCollector starts a gRPC server, which sends the received export
+// requests to the returned channel.
+func fakeCollector(t *testing.T) (string, chan exportRequest) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("Failed to listen:", err)
+	}
+	reqCh := make(chan exportRequest, 10)
+	srv := grpc.NewServer(grpc.CustomCodec(serverCodec{}),
+		grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+			method, _ := grpc.MethodFromServerStream(stream)
+			var req rawMessage
+			if err := stream.RecvMsg(&req); err != nil {
+				return err
+			}
+			reqCh <- exportRequest{method: method, body: req}
+			resp := rawMessage{}
+			return stream.SendMsg(&resp)
+		}))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String(), reqCh
+}
+
+// field is a decoded protobuf field.
+type field struct {
+	varint uint64
+	bytes  []byte
+}
+
+// decode decodes the protobuf message into its fields, keyed by the number.
+func decode(t *testing.T, b []byte) map[uint64][]field {
+	t.Helper()
+	ret := map[uint64][]field{}
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatal("Failed to decode tag")
+		}
+		b = b[n:]
+		var f field
+		switch tag & 7 {
+		case wireVarint:
+			f.varint, n = binary.Uvarint(b)
+		case wireFixed64:
+			if len(b) >= 8 {
+				f.varint, n = binary.LittleEndian.Uint64(b), 8
+			}
+		case wireBytes:
+			var l uint64
+			l, n = binary.Uvarint(b)
+			if n > 0 && uint64(len(b)-n) >= l {
+				f.bytes = b[n : n+int(l)]
+				n += int(l)
+			} else {
+				n = 0
+			}
+		default:
+			n = 0
+		}
+		if n <= 0 {
+			t.Fatalf("Failed to decode field %d", tag>>3)
+		}
+		b = b[n:]
+		ret[tag>>3] = append(ret[tag>>3], f)
+	}
+	return ret
+}
+
+// attributes decodes the KeyValue messages with string values.
+func attributes(t *testing.T, fields []field) map[string]string {
+	t.Helper()
+	ret := make(map[string]string, len(fields))
+	for _, f := range fields {
+		kv := decode(t, f.bytes)
+		v := decode(t, kv[2][0].bytes)
+		ret[string(kv[1][0].bytes)] = string(v[1][0].bytes)
+	}
+	return ret
+}
+
+func TestOTLPExporter(t *testing.T) {
+	addr, reqCh := fakeCollector(t)
+
+	exporter, err := newOTLPExporter(addr, true /*insecure*/, map[string]string{
+		ServiceNameAttribute:           "queue-proxy",
+		"serving.knative.dev/revision": "hello-00001",
+	}, TestLogger(t))
+	if err != nil {
+		t.Fatal("newOTLPExporter() =", err)
+	}
+
+	start := time.Unix(1600000000, 0)
+	sd := &trace.SpanData{
+		SpanContext: trace.SpanContext{
+			TraceID: trace.TraceID{1, 2, 3},
+			SpanID:  trace.SpanID{4, 5, 6},
+		},
+		ParentSpanID: trace.SpanID{7, 8, 9},
+		SpanKind:     trace.SpanKindServer,
+		Name:         "queue_proxy",
+		StartTime:    start,
+		EndTime:      start.Add(time.Second),
+		Attributes: map[string]interface{}{
+			"http.path": "/hello",
+		},
+		Annotations: []trace.Annotation{{
+			Time:    start,
+			Message: "proxied",
+		}},
+		Status: trace.Status{
+			Code:    2,
+			Message: "unknown",
+		},
+	}
+	exporter.ExportSpan(sd)
+	// Closing flushes the buffered spans.
+	if err := exporter.Close(); err != nil {
+		t.Fatal("Close() =", err)
+	}
+
+	var req exportRequest
+	select {
+	case req = <-reqCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the export request")
+	}
+	if req.method != otlpExportMethod {
+		t.Errorf("Method = %s, want: %s", req.method, otlpExportMethod)
+	}
+
+	resourceSpans := decode(t, decode(t, req.body)[1][0].bytes)
+	resource := decode(t, resourceSpans[1][0].bytes)
+	if got, want := attributes(t, resource[1]), map[string]string{
+		ServiceNameAttribute:           "queue-proxy",
+		"serving.knative.dev/revision": "hello-00001",
+	}; len(got) != len(want) || got[ServiceNameAttribute] != want[ServiceNameAttribute] ||
+		got["serving.knative.dev/revision"] != want["serving.knative.dev/revision"] {
+		t.Errorf("Resource attributes = %v, want: %v", got, want)
+	}
+
+	scopeSpans := decode(t, resourceSpans[2][0].bytes)
+	if got, want := len(scopeSpans[2]), 1; got != want {
+		t.Fatalf("#spans = %d, want: %d", got, want)
+	}
+	span := decode(t, scopeSpans[2][0].bytes)
+	if got, want := string(span[1][0].bytes), string(sd.TraceID[:]); got != want {
+		t.Errorf("TraceID = %x, want: %x", got, want)
+	}
+	if got, want := string(span[4][0].bytes), string(sd.ParentSpanID[:]); got != want {
+		t.Errorf("ParentSpanID = %x, want: %x", got, want)
+	}
+	if got, want := string(span[5][0].bytes), sd.Name; got != want {
+		t.Errorf("Name = %s, want: %s", got, want)
+	}
+	if got, want := span[6][0].varint, uint64(otlpSpanKindServer); got != want {
+		t.Errorf("Kind = %d, want: %d", got, want)
+	}
+	if got, want := span[8][0].varint-span[7][0].varint, uint64(time.Second); got != want {
+		t.Errorf("Duration = %d, want: %d", got, want)
+	}
+	if got, want := attributes(t, span[9])["http.path"], "/hello"; got != want {
+		t.Errorf("Attribute http.path = %s, want: %s", got, want)
+	}
+	if got, want := string(decode(t, span[11][0].bytes)[2][0].bytes), "proxied"; got != want {
+		t.Errorf("Event = %s, want: %s", got, want)
+	}
+	status := decode(t, span[15][0].bytes)
+	if got, want := status[3][0].varint, uint64(otlpStatusCodeError); got != want {
+		t.Errorf("Status code = %d, want: %d", got, want)
+	}
+}
+
+func TestTracerApplyConfig(t *testing.T) {
+	addr, reqCh := fakeCollector(t)
+
+	tracer := NewTracer("activator-service", "10.0.0.1", nil, TestLogger(t))
+	cfg, err := NewConfigFromMap(map[string]string{
+		"backend":       "otlp",
+		"otlp-endpoint": addr,
+		"otlp-insecure": "true",
+		"debug":         "true",
+	})
+	if err != nil {
+		t.Fatal("NewConfigFromMap() =", err)
+	}
+	if err := tracer.ApplyConfig(cfg); err != nil {
+		t.Fatal("ApplyConfig() =", err)
+	}
+	if tracer.exporter == nil {
+		t.Fatal("No OTLP exporter was created")
+	}
+
+	_, span := trace.StartSpan(context.Background(), "test")
+	span.End()
+
+	// Disabling the tracing flushes the spans.
+	if err := tracer.ApplyConfig(&Config{Config: *tracingconfig.NoopConfig()}); err != nil {
+		t.Fatal("ApplyConfig() =", err)
+	}
+	if tracer.exporter != nil {
+		t.Error("The OTLP exporter was not stopped")
+	}
+
+	select {
+	case req := <-reqCh:
+		resource := decode(t, decode(t, decode(t, req.body)[1][0].bytes)[1][0].bytes)
+		attrs := attributes(t, resource[1])
+		if got, want := attrs[ServiceNameAttribute], "activator-service"; got != want {
+			t.Errorf("Service name = %s, want: %s", got, want)
+		}
+		if got, want := attrs[ServiceInstanceAttribute], "10.0.0.1"; got != want {
+			t.Errorf("Service instance = %s, want: %s", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the export request")
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"sync"
+
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+	pkgtracing "knative.dev/pkg/tracing"
+)
+
+// Tracer manages the OpenCensus tracing of the process, like the
+// knative.dev/pkg OpenCensusTracer it wraps, and additionally exports the
+// traces to OTLP collectors when the backend is OTLP.
+type Tracer struct {
+	oct    *pkgtracing.OpenCensusTracer
+	attrs  map[string]string
+	logger *zap.SugaredLogger
+
+	mu       sync.Mutex
+	otlpCfg  Config
+	exporter *otlpExporter
+}
+
+// NewTracer creates a Tracer for the process with the given name and host,
+// see pkgtracing.WithExporterFull. The given resource attributes are attached
+// to the traces exported via OTLP.
+func NewTracer(name, host string, attrs map[string]string, logger *zap.SugaredLogger) *Tracer {
+	attrs = withDefaultAttributes(attrs, name, host)
+	return &Tracer{
+		oct:    pkgtracing.NewOpenCensusTracer(pkgtracing.WithExporterFull(name, host, logger)),
+		attrs:  attrs,
+		logger: logger,
+	}
+}
+
+// withDefaultAttributes returns the attributes, with the service name and
+// instance defaulted to the process name and host.
+func withDefaultAttributes(attrs map[string]string, name, host string) map[string]string {
+	ret := make(map[string]string, len(attrs)+2)
+	ret[ServiceNameAttribute] = name
+	ret[ServiceInstanceAttribute] = host
+	for k, v := range attrs {
+		if v != "" {
+			ret[k] = v
+		}
+	}
+	return ret
+}
+
+// ApplyConfig applies the tracing configuration.
+func (t *Tracer) ApplyConfig(cfg *Config) error {
+	if err := t.oct.ApplyConfig(&cfg.Config); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if cfg.Backend != OTLP {
+		t.stopExporter()
+		return nil
+	}
+	if t.exporter != nil && t.otlpCfg.OTLPEndpoint == cfg.OTLPEndpoint &&
+		t.otlpCfg.OTLPInsecure == cfg.OTLPInsecure {
+		return nil
+	}
+
+	// Create the new exporter before stopping the current one, to minimize
+	// the time no spans are exported.
+	exporter, err := newOTLPExporter(cfg.OTLPEndpoint, cfg.OTLPInsecure, t.attrs, t.logger)
+	if err != nil {
+		return err
+	}
+	trace.RegisterExporter(exporter)
+	t.stopExporter()
+	t.exporter = exporter
+	t.otlpCfg = *cfg
+	return nil
+}
+
+// stopExporter unregisters and closes the current OTLP exporter, if any.
+// The caller must hold t.mu.
+func (t *Tracer) stopExporter() {
+	if t.exporter == nil {
+		return
+	}
+	trace.UnregisterExporter(t.exporter)
+	if err := t.exporter.Close(); err != nil {
+		t.logger.Warnw("Failed to close the OTLP exporter", zap.Error(err))
+	}
+	t.exporter = nil
+}
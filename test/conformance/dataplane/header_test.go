@@ -0,0 +1,130 @@
+// +build e2e
+
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dataplane
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	network "knative.dev/networking/pkg"
+	"knative.dev/pkg/test/spoof"
+	"knative.dev/serving/pkg/activator"
+	"knative.dev/serving/test"
+	"knative.dev/serving/test/types"
+)
+
+// fetchRequestInfo sends a request with the given headers to the runtime
+// image and returns the request as observed by the user container.
+func fetchRequestInfo(t *testing.T, client *spoof.SpoofingClient, u *url.URL, header http.Header) *types.RequestInfo {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		t.Fatal("Failed to create new HTTP request:", err)
+	}
+	for k, vs := range header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal("Failed roundtripping:", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Status = %d, want: %d, response: %v", resp.StatusCode, http.StatusOK, resp)
+	}
+
+	var ri types.RuntimeInfo
+	if err := json.Unmarshal(resp.Body, &ri); err != nil {
+		t.Fatal("Failed to unmarshal the runtime info:", err)
+	}
+	return ri.Request
+}
+
+// TestHeadersPreserved verifies that the request headers, including the
+// repeated and the large ones, reach the user container unchanged on every
+// data-plane path.
+func TestHeadersPreserved(t *testing.T) {
+	t.Parallel()
+	clients := test.Setup(t)
+
+	header := http.Header{
+		"Dataplane-Single":   {"a value"},
+		"Dataplane-Repeated": {"first", "second", "third"},
+		"Dataplane-Large":    {strings.Repeat("x", 4096)},
+	}
+
+	for _, path := range dataplanePaths {
+		path := path
+		t.Run(path.name, func(t *testing.T) {
+			t.Parallel()
+			u, client := createService(t, clients, test.Runtime, path)
+
+			ri := fetchRequestInfo(t, client, u, header)
+			if ri.Host != u.Host {
+				t.Errorf("Host = %q, want: %q", ri.Host, u.Host)
+			}
+			for k, want := range header {
+				got := ri.Headers[k]
+				if len(got) != len(want) {
+					t.Errorf("Header %s has %d values, want: %d", k, len(got), len(want))
+					continue
+				}
+				for i := range want {
+					if got[i] != want[i] {
+						t.Errorf("Header %s[%d] = %q, want: %q", k, i, got[i], want[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestInternalHeadersFiltered verifies that the headers the data plane uses
+// for routing and probing never reach the user container.
+func TestInternalHeadersFiltered(t *testing.T) {
+	t.Parallel()
+	clients := test.Setup(t)
+
+	internalHeaders := []string{
+		activator.RevisionHeaderName,
+		activator.RevisionHeaderNamespace,
+		network.ProbeHeaderName,
+		network.HashHeaderName,
+	}
+
+	for _, path := range dataplanePaths {
+		path := path
+		t.Run(path.name, func(t *testing.T) {
+			t.Parallel()
+			u, client := createService(t, clients, test.Runtime, path)
+
+			ri := fetchRequestInfo(t, client, u, nil)
+			for _, h := range internalHeaders {
+				if v, ok := ri.Headers[http.CanonicalHeaderKey(h)]; ok {
+					t.Errorf("Header %s = %v was not filtered by the data plane", h, v)
+				}
+			}
+		})
+	}
+}
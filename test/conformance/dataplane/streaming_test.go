@@ -0,0 +1,77 @@
+// +build e2e
+
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dataplane
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"knative.dev/serving/test"
+)
+
+// TestStreaming verifies that no data-plane component buffers the
+// responses: the headers the user container flushes must reach the client
+// before the rest of the body is written.
+func TestStreaming(t *testing.T) {
+	t.Parallel()
+	clients := test.Setup(t)
+
+	const bodyDelay = 5 * time.Second
+
+	for _, path := range dataplanePaths {
+		path := path
+		t.Run(path.name, func(t *testing.T) {
+			t.Parallel()
+			u, client := createService(t, clients, test.Timeout, path)
+
+			req, err := http.NewRequest(http.MethodGet, sleepURL(u, 0, bodyDelay), nil)
+			if err != nil {
+				t.Fatal("Failed to create new HTTP request:", err)
+			}
+
+			// Use the underlying client, since the spoofing client reads
+			// the whole body before returning.
+			start := time.Now()
+			resp, err := client.Client.Do(req)
+			if err != nil {
+				t.Fatal("Failed roundtripping:", err)
+			}
+			defer resp.Body.Close()
+			headers := time.Since(start)
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("Status = %d, want: %d", resp.StatusCode, http.StatusOK)
+			}
+			if _, err := ioutil.ReadAll(resp.Body); err != nil {
+				t.Fatal("Failed to read the body:", err)
+			}
+			total := time.Since(start)
+
+			t.Logf("Headers arrived after %v, body after %v", headers, total)
+			if headers >= bodyDelay {
+				t.Errorf("Headers arrived after %v, want: less than %v", headers, bodyDelay)
+			}
+			if total < bodyDelay {
+				t.Errorf("Body arrived after %v, want: at least %v", total, bodyDelay)
+			}
+		})
+	}
+}
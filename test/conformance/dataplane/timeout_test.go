@@ -0,0 +1,95 @@
+// +build e2e
+
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dataplane
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"knative.dev/serving/test"
+
+	. "knative.dev/serving/pkg/testing/v1"
+)
+
+// sleepURL returns the URL that makes the timeout image wait for
+// initialSleep before writing the headers and for sleep before writing
+// the body.
+func sleepURL(u *url.URL, initialSleep, sleep time.Duration) string {
+	su := *u
+	q := su.Query()
+	q.Set("initialTimeout", fmt.Sprint(initialSleep.Milliseconds()))
+	q.Set("timeout", fmt.Sprint(sleep.Milliseconds()))
+	su.RawQuery = q.Encode()
+	return su.String()
+}
+
+// TestTimeoutEnforced verifies that every data-plane path enforces the
+// revision timeout on the time to the first byte of the response.
+func TestTimeoutEnforced(t *testing.T) {
+	t.Parallel()
+	clients := test.Setup(t)
+
+	const timeoutSeconds = 5
+	cases := []struct {
+		name         string
+		initialSleep time.Duration
+		sleep        time.Duration
+		wantStatus   int
+	}{{
+		name:         "first byte before timeout",
+		initialSleep: time.Second,
+		wantStatus:   http.StatusOK,
+	}, {
+		name:         "first byte after timeout",
+		initialSleep: 7 * time.Second,
+		wantStatus:   http.StatusGatewayTimeout,
+	}, {
+		name:       "body after timeout",
+		sleep:      7 * time.Second,
+		wantStatus: http.StatusOK,
+	}}
+
+	for _, path := range dataplanePaths {
+		path := path
+		t.Run(path.name, func(t *testing.T) {
+			t.Parallel()
+			u, client := createService(t, clients, test.Timeout, path,
+				WithRevisionTimeoutSeconds(timeoutSeconds))
+
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					req, err := http.NewRequest(http.MethodGet, sleepURL(u, tc.initialSleep, tc.sleep), nil)
+					if err != nil {
+						t.Fatal("Failed to create new HTTP request:", err)
+					}
+					resp, err := client.Do(req)
+					if err != nil {
+						t.Fatal("Failed roundtripping:", err)
+					}
+					if resp.StatusCode != tc.wantStatus {
+						t.Errorf("Status = %d, want: %d, response: %v", resp.StatusCode, tc.wantStatus, resp)
+					}
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2020 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dataplane
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	pkgTest "knative.dev/pkg/test"
+	"knative.dev/pkg/test/spoof"
+	"knative.dev/serving/pkg/apis/autoscaling"
+	"knative.dev/serving/test"
+	v1test "knative.dev/serving/test/v1"
+
+	. "knative.dev/serving/pkg/testing/v1"
+)
+
+// dataplanePath describes one of the ways a request can travel from the
+// ingress to the user container.
+type dataplanePath struct {
+	name        string
+	annotations map[string]string
+}
+
+// dataplanePaths are the request paths every data-plane contract is
+// validated against. A targetBurstCapacity of 0 takes the activator out
+// of the path once the revision is scaled up, while -1 keeps it there
+// permanently.
+var dataplanePaths = []dataplanePath{{
+	name: "direct",
+	annotations: map[string]string{
+		autoscaling.MinScaleAnnotationKey:  "1",
+		autoscaling.TargetBurstCapacityKey: "0",
+	},
+}, {
+	name: "activator",
+	annotations: map[string]string{
+		autoscaling.MinScaleAnnotationKey:  "1",
+		autoscaling.TargetBurstCapacityKey: "-1",
+	},
+}}
+
+// createService creates a Service with the given image that routes its
+// requests along the given path and returns its URL along with a spoofing
+// client that can reach it.
+func createService(t *testing.T, clients *test.Clients, image string, path dataplanePath,
+	opts ...ServiceOption) (*url.URL, *spoof.SpoofingClient) {
+	t.Helper()
+	names := &test.ResourceNames{
+		Service: test.ObjectNameForTest(t),
+		Image:   image,
+	}
+	test.EnsureTearDown(t, clients, names)
+
+	t.Logf("Creating a new Service %s with the %s path", names.Service, path.name)
+	objects, err := v1test.CreateServiceReady(t, clients, names,
+		append([]ServiceOption{WithConfigAnnotations(path.annotations)}, opts...)...)
+	if err != nil {
+		t.Fatal("Failed to create Service:", err)
+	}
+
+	u := objects.Service.Status.URL.URL()
+	if _, err := pkgTest.WaitForEndpointState(
+		context.Background(),
+		clients.KubeClient,
+		t.Logf,
+		u,
+		v1test.RetryingRouteInconsistency(pkgTest.IsStatusOK),
+		"WaitForSuccessfulResponse",
+		test.ServingFlags.ResolvableDomain,
+		test.AddRootCAtoTransport(context.Background(), t.Logf, clients, test.ServingFlags.HTTPS)); err != nil {
+		t.Fatalf("Error probing %s: %v", u, err)
+	}
+
+	client, err := pkgTest.NewSpoofingClient(context.Background(), clients.KubeClient, t.Logf,
+		u.Hostname(), test.ServingFlags.ResolvableDomain,
+		test.AddRootCAtoTransport(context.Background(), t.Logf, clients, test.ServingFlags.HTTPS))
+	if err != nil {
+		t.Fatal("Error creating spoofing client:", err)
+	}
+	return u, client
+}